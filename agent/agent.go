@@ -5,6 +5,10 @@ import (
 	"context"
 	"io"
 	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/transport"
 )
 
 // Config configures a voice agent.
@@ -12,12 +16,57 @@ type Config struct {
 	// Name is a human-readable name for the agent.
 	Name string
 
-	// SystemPrompt is the initial system prompt for the LLM.
+	// SystemPrompt is the initial system prompt for the LLM. It may be a
+	// template over PromptVariables and call details; see
+	// RenderSystemPrompt.
 	SystemPrompt string
 
+	// PromptVariables are the variables of a SystemPrompt template, such
+	// as "caller_name" or "account_status".
+	PromptVariables map[string]any
+
+	// CallDirection is "inbound" or "outbound", as set by the call
+	// system, for SystemPrompt templates.
+	CallDirection string
+
+	// TimeZone is the IANA time zone (e.g. "America/New_York") of the
+	// times in a SystemPrompt template. Defaults to the local zone.
+	TimeZone string
+
+	// Greeting is spoken as the first agent turn when FirstSpeaker lets
+	// the agent speak first.
+	Greeting Greeting
+
+	// FirstSpeaker controls who speaks first. Defaults to FirstSpeakerUser.
+	FirstSpeaker FirstSpeaker
+
+	// ResumeGreeting, if set, is spoken when a paused session resumes
+	// (see Pauser), e.g. "Thanks for holding."
+	ResumeGreeting Greeting
+
+	// RecordingConsent, if set, plays a recording announcement before the
+	// conversation begins, for calls that are recorded; see ConsentConfig.
+	RecordingConsent *ConsentConfig
+
+	// Metadata is call metadata (e.g., "caller_name", "caller_number",
+	// "caller_region") available to Greeting placeholders and SystemPrompt
+	// templates.
+	Metadata map[string]string
+
 	// VoiceID is the TTS voice to use.
 	VoiceID string
 
+	// Style is the initial TTS speaking style (see tts.Style constants).
+	// Sessions can change it per turn through Styler.
+	Style string
+
+	// StyleDegree is the intensity of Style (0.0 to 2.0, 0 = default).
+	StyleDegree float64
+
+	// Prosody, if set, adjusts the TTS settings for each agent turn from
+	// its sentiment and emphasis hints. See ApplyProsody.
+	Prosody ProsodyFunc
+
 	// Language is the primary language (BCP-47 code).
 	Language string
 
@@ -33,20 +82,220 @@ type Config struct {
 	// LLMModel is the specific LLM model to use.
 	LLMModel string
 
-	// MaxTurnDuration is the maximum duration for a single turn.
+	// TextOnly runs the session without audio, for channels such as SMS
+	// and chat: input arrives with SendText, replies are delivered by
+	// EventAgentTranscript, and no STT or TTS is used. Tools, history,
+	// webhooks, the transcript, and metrics work as usual, with the
+	// audio metrics left zero. VoiceID, STTProvider, TTSProvider, and the
+	// audio settings are ignored; SendAudio fails and ReceiveAudio is
+	// closed.
+	TextOnly bool
+
+	// SpeakerLabels prefixes each user message in the LLM prompt with
+	// its turn's speaker ("Alice: ..."), so the agent knows who said what
+	// in a multi-party session such as a meeting. Turns without a speaker
+	// are not labeled. See SpeakerTracker.
+	SpeakerLabels bool
+
+	// PushToTalk runs the session half-duplex, for push-to-talk hardware
+	// and very noisy environments: it listens only between
+	// StartListening and StopListening (see PushToTalker), ignoring
+	// caller audio otherwise, and answers once the user stops. Starting
+	// to listen always cuts the agent off, and nothing else interrupts
+	// it, so InterruptionMode, Interruption, and InterruptionResume do
+	// not apply.
+	PushToTalk bool
+
+	// MaxTurnDuration is the maximum duration of an agent reply, from
+	// the end of the user turn, including tool calls. A reply over it is
+	// cut, EventLimitReached is emitted, and the session keeps listening.
 	MaxTurnDuration time.Duration
 
-	// MaxSessionDuration is the maximum total session duration.
+	// MaxSessionDuration is the maximum total session duration. When it
+	// is reached, EventLimitReached is emitted, ClosingMessage is spoken,
+	// and the session stops. Time paused (see Pauser) does not count.
 	MaxSessionDuration time.Duration
 
+	// Budget caps LLM and TTS spend; reaching it ends the session like
+	// MaxSessionDuration. The zero Budget is unlimited.
+	Budget Budget
+
+	// ClosingMessage is spoken, uninterruptibly, before a session ends
+	// because of MaxSessionDuration or Budget.
+	ClosingMessage string
+
+	// Endpointing decides when the user's utterance is complete and
+	// should be answered. Nil completes it on the first final transcript
+	// after the user stops speaking. See EndpointConfig.
+	Endpointing *EndpointConfig
+
 	// InterruptionMode controls how interruptions are handled.
 	InterruptionMode InterruptionMode
 
+	// Interruption, if set, filters out coughs, backchannels, and noise so
+	// that only genuine caller speech interrupts the agent. See
+	// InterruptionConfig.
+	Interruption *InterruptionConfig
+
+	// InterruptionResume, if set, pauses rather than cuts the agent when
+	// it is interrupted, and resumes if no user utterance follows. It
+	// applies to InterruptImmediate.
+	InterruptionResume *InterruptionResumeConfig
+
+	// InterruptionGracePeriod ignores the user barging in for this long
+	// after the agent starts speaking (EventAgentSpeechStart), when an
+	// interruption more likely means the caller has not yet heard the
+	// agent over the network than that they mean to cut in. Speech and
+	// transcripts within it do not stop TTS under InterruptImmediate, cut
+	// the sentence under InterruptAfterSentence, or pause the agent under
+	// InterruptionResume; a user turn that completes is answered after
+	// the reply, as with InterruptDisabled. Explicit interruptions
+	// (Interrupter, DTMF, SendText) apply at once. Zero disables it.
+	InterruptionGracePeriod time.Duration
+
+	// TurnPolicy decides when the caller may interrupt, is heard, and
+	// gets to finish before the agent speaks. Nil uses DefaultTurnPolicy;
+	// see StrictTurnPolicy and YieldingTurnPolicy.
+	TurnPolicy TurnPolicy
+
+	// StopMode controls whether Stop lets the agent finish the reply it
+	// is speaking. Defaults to StopImmediate; see WithStopMode to choose
+	// per call.
+	StopMode StopMode
+
+	// AudioEncoding is the encoding of audio exchanged through SendAudio
+	// and ReceiveAudio: "pcm" (16-bit linear, the default), "mulaw", or
+	// "alaw". See Session.SendAudio for the framing contract.
+	AudioEncoding string
+
+	// SampleRate is the sample rate of session audio in Hz. Zero uses the
+	// provider default.
+	SampleRate int
+
+	// FrameDuration is the frame length audio is repacketized into before
+	// it reaches the provider. Defaults to audio.DefaultFrameDuration.
+	FrameDuration time.Duration
+
+	// EchoCancellation, if set, removes the agent's own speech from
+	// caller audio before STT, for full-duplex transports where playback
+	// leaks into the microphone; without it the agent can hear and
+	// interrupt itself. Hosted providers that cancel echo themselves
+	// ignore it. Agent speech becomes the reference as the transport
+	// takes it from ReceiveAudio; set the config's Delay to any playback
+	// buffering after that.
+	EchoCancellation *audio.EchoConfig
+
+	// EchoGate, if set, distrusts caller audio while the agent speaks: a
+	// lightweight alternative to EchoCancellation. See EchoGateConfig for
+	// the tradeoff.
+	EchoGate *EchoGateConfig
+
+	// OutputGain adjusts the level of agent speech, in dB: negative is
+	// quieter. See OutputGainSetter to change it during a session.
+	OutputGain float64
+
+	// Ducking, if set, turns a background mixer source down while the
+	// agent speaks.
+	Ducking *DuckingConfig
+
+	// AudioLevelInterval, if positive, emits EventAudioLevel for caller
+	// audio and agent speech at this interval, for level meters and
+	// speaking indicators. It is at least MinAudioLevelInterval.
+	AudioLevelInterval time.Duration
+
+	// InputAudioBuffer caps, in bytes, the caller audio SendAudio has
+	// accepted but STT has not yet taken, so a client sending faster than
+	// transcription keeps up cannot exhaust memory. Defaults to
+	// DefaultInputAudioBuffer.
+	InputAudioBuffer int
+
+	// InputAudioBufferPolicy is what SendAudio does when InputAudioBuffer
+	// is full. BufferBlock, the default, emits an EventError wrapping
+	// ErrInputAudioOverflow and waits for room, pushing back on the
+	// transport; BufferDropOldest discards the oldest audio.
+	InputAudioBufferPolicy BufferPolicy
+
+	// AudioBuffer is the number of chunks ReceiveAudio buffers. Defaults
+	// to DefaultAudioBuffer.
+	AudioBuffer int
+
+	// AudioBufferPolicy applies when ReceiveAudio is full. Defaults to
+	// BufferDropOldest; dropped chunks are counted in
+	// Metrics.DroppedAudioFrames.
+	AudioBufferPolicy BufferPolicy
+
+	// EventBuffer is the number of events Events buffers. Defaults to
+	// DefaultEventBuffer.
+	EventBuffer int
+
+	// EventBufferPolicy applies when Events is full. Defaults to
+	// BufferBlock.
+	EventBufferPolicy BufferPolicy
+
+	// ResumeGracePeriod is how long a session is kept alive after its
+	// transport disconnects, waiting for the caller to reconnect.
+	// Zero ends the session on disconnect.
+	ResumeGracePeriod time.Duration
+
 	// Tools defines functions the agent can call.
 	Tools []Tool
 
+	// DTMFAsInput treats keypad entry as a user turn: digits are collected
+	// until DTMFTerminator, DTMFMaxDigits, or DTMFTimeout and then sent
+	// to the LLM like SendText. See DTMFCollector.
+	DTMFAsInput bool
+
+	// DTMFTerminator ends keypad entry. It may be more than one key, such
+	// as "**", in which case entry ends once the keys are pressed in a
+	// row. Defaults to "#".
+	DTMFTerminator string
+
+	// DTMFTimeout ends keypad entry after this long without a digit.
+	// Defaults to 3 seconds.
+	DTMFTimeout time.Duration
+
+	// DTMFMaxDigits ends keypad entry once this many digits are collected.
+	// Zero means no limit.
+	DTMFMaxDigits int
+
+	// InputModerator, if set, screens user transcripts before they reach
+	// the LLM. See ModerateInput.
+	InputModerator Moderator
+
+	// OutputModerator, if set, screens agent responses before TTS.
+	// See ModerateOutput.
+	OutputModerator Moderator
+
+	// ModerationFallback is spoken in place of a blocked response when
+	// the OutputModerator supplies no replacement. Defaults to
+	// DefaultModerationFallback.
+	ModerationFallback string
+
+	// Retriever, if set, looks up knowledge for each user turn, given to
+	// the LLM for that turn only. See Retrieve.
+	Retriever Retriever
+
+	// RetrievalTimeout bounds Retriever. Defaults to
+	// DefaultRetrievalTimeout.
+	RetrievalTimeout time.Duration
+
+	// TranscriptSink, if set, receives each Turn as it is added to the
+	// transcript, for live dashboards and agent assist.
+	TranscriptSink TranscriptSink
+
+	// TranscriptSinkBuffer is how many turns wait for a slow
+	// TranscriptSink before the oldest are dropped (counted in
+	// Metrics.DroppedTranscriptTurns). Defaults to
+	// DefaultTranscriptSinkBuffer.
+	TranscriptSinkBuffer int
+
 	// Webhooks configures event webhooks.
 	Webhooks WebhookConfig
+
+	// Summary, if set, summarizes the call with the LLM when the session
+	// stops, extracting structured fields for CRM logging; see
+	// SummaryConfig.
+	Summary *SummaryConfig
 }
 
 // InterruptionMode controls how user interruptions are handled.
@@ -76,11 +325,47 @@ type Tool struct {
 
 	// Handler is called when the tool is invoked.
 	Handler ToolHandler
+
+	// StructuredHandler is called instead of Handler when set, for tools
+	// that return structured data or attachments.
+	StructuredHandler StructuredToolHandler
 }
 
 // ToolHandler processes a tool call and returns a result.
 type ToolHandler func(ctx context.Context, args map[string]any) (string, error)
 
+// StructuredToolHandler processes a tool call and returns a structured
+// result, which the session serializes for the LLM.
+type StructuredToolHandler func(ctx context.Context, args map[string]any) (*ToolResult, error)
+
+// ToolResult is a structured tool result.
+type ToolResult struct {
+	// Data is the result value. Strings are sent to the LLM as is; any
+	// other value is JSON-encoded.
+	Data any
+
+	// Attachments are images, audio, or files for multimodal models.
+	Attachments []Attachment
+}
+
+// Attachment references media returned by a tool.
+type Attachment struct {
+	// Type is "image", "audio", or "file".
+	Type string
+
+	// MIMEType is the media type (e.g., "image/png").
+	MIMEType string
+
+	// URL locates the media. Either URL or Data is set.
+	URL string
+
+	// Data is the inline media content.
+	Data []byte
+
+	// Description is alternative text for models or logs.
+	Description string
+}
+
 // WebhookConfig configures event webhooks.
 type WebhookConfig struct {
 	// OnSessionStart is called when a session begins.
@@ -94,6 +379,13 @@ type WebhookConfig struct {
 
 	// OnToolCall is called when a tool is invoked.
 	OnToolCall string
+
+	// OnCallSummary is called with the call summary (Config.Summary).
+	OnCallSummary string
+
+	// Secret, if set, signs each delivery with HMAC-SHA256 so receivers
+	// can verify it. See the agent/webhook package.
+	Secret string
 }
 
 // Session represents an active voice conversation session.
@@ -104,19 +396,42 @@ type Session interface {
 	// Start begins the voice session.
 	Start(ctx context.Context) error
 
-	// Stop ends the voice session gracefully.
+	// Stop ends the voice session gracefully: it stops accepting input,
+	// finishes or cuts the current reply according to StopModeFrom,
+	// flushes final transcripts, emits EventSessionEnded with the final
+	// Metrics, and closes the ReceiveAudio and Events channels. When ctx
+	// ends first, the session is terminated at once and ctx.Err() is
+	// returned.
 	Stop(ctx context.Context) error
 
-	// SendAudio sends audio data to the agent.
+	// SendAudio sends mono audio in Config.AudioEncoding at
+	// Config.SampleRate. Chunks may be any size: the session repacketizes
+	// them into Config.FrameDuration frames, buffering a partial frame
+	// until the next call, AudioFlusher.FlushAudio, or the end of user
+	// speech. Encoded files (WAV, MP3, Ogg) and stray partial samples
+	// return an error wrapping audio.ErrFormatMismatch. The session does
+	// not retain audio after SendAudio returns, so callers may reuse it.
 	SendAudio(audio []byte) error
 
-	// ReceiveAudio returns a channel for receiving agent audio.
+	// ReceiveAudio returns a channel for receiving agent audio. It is
+	// bounded by Config.AudioBuffer, follows Config.AudioBufferPolicy
+	// when full, and is closed by Stop. Each chunk belongs to the
+	// receiver; see AudioReleaser to hand it back for reuse.
 	ReceiveAudio() <-chan []byte
 
-	// SendText sends text input to the agent (bypass STT).
+	// SendText sends text input to the agent, bypassing STT, as for a
+	// web chat with voice output. It is handled like a final transcript:
+	// it interrupts the agent if speaking, becomes a user Turn (emitting
+	// EventUserTranscript), and is answered with the LLM and tools, the
+	// reply spoken on ReceiveAudio and recorded as an agent Turn. Both
+	// turns count in Metrics; text input adds no user speech time or STT
+	// latency. Empty text is ignored.
 	SendText(text string) error
 
-	// Events returns a channel for session events.
+	// Events returns a channel for session events. It is bounded by
+	// Config.EventBuffer, follows Config.EventBufferPolicy when full, and
+	// is closed by Stop. A consumer that stops reading never prevents
+	// Stop from returning.
 	Events() <-chan Event
 
 	// Transcript returns the conversation transcript so far.
@@ -126,24 +441,247 @@ type Session interface {
 	Metrics() Metrics
 }
 
+// QualityRecorder is implemented by sessions that report transport call
+// quality in Metrics.AudioQuality. Whatever connects the session to a
+// transport forwards each transport.EventQuality to it.
+type QualityRecorder interface {
+	RecordQuality(stats transport.QualityStats)
+}
+
+// OutputGainSetter is implemented by sessions whose Config.OutputGain
+// can change mid-session, e.g. for a caller asking the agent to speak up.
+type OutputGainSetter interface {
+	SetOutputGain(db float64)
+}
+
+// AudioFlusher is implemented by sessions that buffer a partial frame of
+// SendAudio.
+type AudioFlusher interface {
+	// FlushAudio sends any buffered partial frame, e.g. at the end of a
+	// recording.
+	FlushAudio() error
+}
+
+// Styler is implemented by sessions whose TTS speaking style can change
+// mid-session.
+type Styler interface {
+	// SetStyle changes the TTS speaking style from the next agent turn,
+	// e.g. to tts.StyleEmpathetic when the caller sounds frustrated.
+	// Providers without style support ignore it.
+	SetStyle(style string, degree float64) error
+}
+
+// DTMFSender is implemented by sessions that take keypad input.
+type DTMFSender interface {
+	// SendDTMF sends keypad digits to the agent. With Config.DTMFAsInput
+	// they are collected into a user turn; a digit pressed while the agent
+	// is speaking interrupts it unless InterruptionMode is
+	// InterruptDisabled.
+	SendDTMF(digits string) error
+}
+
+// Interrupter is implemented by sessions that can be interrupted
+// explicitly, as if the user barged in, following Config.InterruptionMode.
+type Interrupter interface {
+	Interrupt() error
+}
+
+// PushToTalker is implemented by sessions supporting Config.PushToTalk.
+// Transports with a talk signal report it as transport.EventListenStart
+// and EventListenStop, for whatever connects them to forward.
+type PushToTalker interface {
+	// StartListening interrupts the agent and starts taking caller audio.
+	StartListening() error
+
+	// StopListening stops taking caller audio and answers what the user
+	// said since StartListening as one turn.
+	StopListening() error
+}
+
+// PersonaSwitcher is implemented by sessions whose system prompt and
+// tools can change mid-session, as when a triage agent hands off from
+// intake to scheduling. Changes apply from the next user turn; a reply in
+// progress, including its tool rounds, finishes under the old ones. The
+// transcript and conversation history are kept.
+type PersonaSwitcher interface {
+	// SetPrompt replaces the system prompt, rendered like
+	// Config.SystemPrompt (see RenderSystemPrompt).
+	SetPrompt(prompt string) error
+
+	// SetTools replaces the tools, each checked with ValidateTool.
+	SetTools(tools []Tool) error
+}
+
+// PersonaChange is the Data of an EventPersonaChanged event: the system
+// prompt and tool names in effect from the next turn.
+type PersonaChange struct {
+	Prompt string
+	Tools  []string
+}
+
+// SpeakerTracker is implemented by sessions that attribute user turns to
+// speakers (Turn.Speaker). Without it, or until told otherwise, a session
+// takes speakers from STT diarization, when enabled.
+type SpeakerTracker interface {
+	// SetSpeaker attributes the user speech that follows to the speaker
+	// id, for transports that report the active speaker or carry a track
+	// per participant. An empty id leaves attribution to diarization.
+	SetSpeaker(id string)
+
+	// NameSpeaker gives a speaker a display name, for Turn.SpeakerName
+	// and Config.SpeakerLabels.
+	NameSpeaker(id, name string)
+}
+
+// Label returns the turn's text as given to the LLM with
+// Config.SpeakerLabels: prefixed with the speaker's name, or ID.
+func (t Turn) Label() string {
+	switch {
+	case t.SpeakerName != "":
+		return t.SpeakerName + ": " + t.Text
+	case t.Speaker != "":
+		return t.Speaker + ": " + t.Text
+	}
+	return t.Text
+}
+
+// AgentInterrupter is implemented by sessions whose reply can be canceled
+// and replaced by the application, as when an asynchronous event ("your
+// order shipped") or a tool result makes what the agent is saying wrong.
+// Unlike Interrupter, it does not stand for the user barging in.
+type AgentInterrupter interface {
+	// InterruptAgent cancels the reply in progress, discarding audio
+	// queued on ReceiveAudio, emits EventAgentInterrupted, and starts
+	// the replacement, if any.
+	InterruptAgent(replacement AgentInterruption) error
+}
+
+// AudioReleaser is implemented by sessions that draw ReceiveAudio chunks
+// from a buffer pool. A receiver that has finished with a chunk, such as
+// after writing it to a transport (io.Writer implementations do not
+// retain what they are given), may pass it to ReleaseAudio for the session
+// to reuse, sparing an allocation per chunk. It must not use the chunk
+// afterwards. Releasing is optional: chunks never released are garbage
+// collected as usual.
+type AudioReleaser interface {
+	ReleaseAudio(chunk []byte)
+}
+
+// Sayer is implemented by sessions that can speak fixed text on the
+// application's behalf, for announcements such as "This call may be
+// recorded" or asynchronous notifications, without involving the LLM.
+type Sayer interface {
+	// Say speaks text exactly as given and records it as an agent Turn.
+	// If the agent is replying, it waits for the reply to finish; use
+	// AgentInterrupter to cut in instead. It returns once the text has
+	// been spoken or interrupted, or with ctx.Err() if ctx ends first,
+	// cutting it off.
+	Say(ctx context.Context, text string, config SayConfig) error
+}
+
+// SayConfig configures Sayer.Say.
+type SayConfig struct {
+	// Audio is optional pre-synthesized audio of the text in the
+	// session's output format, played instead of synthesizing it.
+	Audio []byte
+
+	// Uninterruptible plays the text to the end even if the user speaks.
+	// By default it follows Config.InterruptionMode.
+	Uninterruptible bool
+}
+
+// AgentInterruption is what replaces a reply canceled with
+// InterruptAgent, and the Data of EventAgentInterrupted. With neither
+// field set the agent just stops.
+type AgentInterruption struct {
+	// Say is spoken as the agent's next turn.
+	Say string
+
+	// Prompt, if Say is empty, is added to the conversation as a system
+	// message for the LLM to answer, e.g. "The caller's order has just
+	// shipped; tell them."
+	Prompt string
+}
+
+// TranscriptUpdate is the Data of an EventUserTranscriptUpdate event.
+type TranscriptUpdate struct {
+	// Text is the current transcript of the utterance.
+	Text string
+
+	// Correction turns the utterance's previous update into Text. Apply
+	// it with stt.TranscriptBuilder to revise captions in place.
+	Correction stt.Correction
+
+	// Final reports whether the utterance is complete. A final update
+	// precedes the utterance's EventUserTranscript.
+	Final bool
+
+	// Stability and StableLength are the provider's stability markers;
+	// see stt.StreamEvent.
+	Stability    float64
+	StableLength int
+}
+
 // Turn represents a single conversation turn.
 type Turn struct {
 	// Role is "user" or "agent".
 	Role string
 
+	// Speaker identifies who spoke a user turn in a multi-party session:
+	// a participant ID, or a diarization speaker ID such as "speaker_1".
+	// Empty when unknown.
+	Speaker string
+
+	// SpeakerName is the speaker's display name, if known.
+	SpeakerName string
+
 	// Text is the transcribed/generated text.
 	Text string
 
 	// Timestamp is when the turn occurred.
 	Timestamp time.Time
 
+	// Offset is when the turn began in session time (see SessionClock):
+	// for a user turn the STT timed, the start of its speech; otherwise
+	// Timestamp in session time.
+	Offset time.Duration
+
+	// Words are the word timings of a user turn, in session time, when
+	// the STT reports them.
+	Words []stt.Word
+
 	// DurationMs is the turn duration in milliseconds.
 	DurationMs int
 
+	// LLMLatencyMs is the time to the first LLM token, for agent turns.
+	LLMLatencyMs int
+
+	// TimeToFirstAudioMs is the time from the end of the user turn to the
+	// first agent audio, for agent turns.
+	TimeToFirstAudioMs int
+
+	// Sentiment is an optional sentiment hint for the turn (e.g.,
+	// "positive", "negative", "frustrated"), set by the LLM or a
+	// classifier.
+	Sentiment string
+
+	// Emphasis is an optional emphasis hint (0.0 to 1.0).
+	Emphasis float64
+
+	// DTMF holds the raw keypad digits for turns entered via DTMF.
+	DTMF string
+
 	// ToolCalls contains any tool calls made during this turn.
 	ToolCalls []ToolCall
 }
 
+// TranscriptSink receives finalized turns in real time, with their
+// speaker and tool calls. Sessions call it in order from a goroutine of
+// its own, never from the audio path, so a slow sink only delays (and at
+// worst drops) its own turns. Turns still queued when the session stops
+// are delivered afterwards.
+type TranscriptSink func(turn Turn)
+
 // ToolCall represents a tool invocation during conversation.
 type ToolCall struct {
 	// Name is the tool name.
@@ -152,9 +690,12 @@ type ToolCall struct {
 	// Arguments is the parsed arguments.
 	Arguments map[string]any
 
-	// Result is the tool result.
+	// Result is the serialized tool result as sent to the LLM.
 	Result string
 
+	// Attachments are media returned alongside the result.
+	Attachments []Attachment
+
 	// Error is any error from the tool call.
 	Error string
 
@@ -170,6 +711,9 @@ type Event struct {
 	// Timestamp is when the event occurred.
 	Timestamp time.Time
 
+	// Offset is Timestamp in session time (see SessionClock).
+	Offset time.Duration
+
 	// Data contains event-specific data.
 	Data any
 
@@ -184,18 +728,32 @@ const (
 	// EventSessionStarted indicates the session has started.
 	EventSessionStarted EventType = "session_started"
 
-	// EventSessionEnded indicates the session has ended.
+	// EventSessionEnded indicates the session has ended. Data is the
+	// final Metrics.
 	EventSessionEnded EventType = "session_ended"
 
 	// EventUserSpeechStart indicates the user started speaking.
 	EventUserSpeechStart EventType = "user_speech_start"
 
-	// EventUserSpeechEnd indicates the user stopped speaking.
+	// EventUserSpeechEnd indicates the user stopped speaking. It is the
+	// raw voice-activity signal: the user may only be pausing. See
+	// EventUtteranceComplete.
 	EventUserSpeechEnd EventType = "user_speech_end"
 
+	// EventUtteranceComplete indicates endpointing decided the user
+	// finished their turn (see Config.Endpointing). Data is the Turn,
+	// with the utterance's final transcripts joined. The session answers
+	// it unless EndpointConfig.Manual is set.
+	EventUtteranceComplete EventType = "utterance_complete"
+
 	// EventUserTranscript contains user speech transcription.
 	EventUserTranscript EventType = "user_transcript"
 
+	// EventUserTranscriptUpdate carries each interim and final
+	// transcript of user speech as it is recognized, for live captions.
+	// Data is a TranscriptUpdate.
+	EventUserTranscriptUpdate EventType = "user_transcript_update"
+
 	// EventAgentThinking indicates the agent is processing.
 	EventAgentThinking EventType = "agent_thinking"
 
@@ -216,6 +774,64 @@ const (
 
 	// EventError indicates an error occurred.
 	EventError EventType = "error"
+
+	// EventSessionSuspended indicates the transport was lost and the
+	// session is waiting to be resumed.
+	EventSessionSuspended EventType = "session_suspended"
+
+	// EventSessionResumed indicates a suspended session was reattached
+	// to a transport, or a paused session unpaused.
+	EventSessionResumed EventType = "session_resumed"
+
+	// EventSessionPaused indicates the session was put on hold with
+	// Pauser.
+	EventSessionPaused EventType = "session_paused"
+
+	// EventDTMFInput indicates keypad entry completed and was sent as a
+	// user turn. Data is a DTMFInput.
+	EventDTMFInput EventType = "dtmf_input"
+
+	// EventModeration indicates a moderator blocked or rewrote text. Data
+	// is a ModerationEvent.
+	EventModeration EventType = "moderation"
+
+	// EventInterruptionConfirmed indicates that, under
+	// Config.InterruptionResume, an interruption was real (the user spoke
+	// or interrupted explicitly) and the interrupted reply was abandoned.
+	EventInterruptionConfirmed EventType = "interruption_confirmed"
+
+	// EventInterruptionResumed indicates an interruption paused under
+	// Config.InterruptionResume was a false alarm and the reply resumed.
+	// Data is an InterruptionResumed.
+	EventInterruptionResumed EventType = "interruption_resumed"
+
+	// EventAgentInterrupted indicates the application canceled the
+	// agent's reply with InterruptAgent. Data is an AgentInterruption.
+	EventAgentInterrupted EventType = "agent_interrupted"
+
+	// EventPersonaChanged indicates the system prompt or tools were
+	// changed with PersonaSwitcher. Data is a PersonaChange.
+	EventPersonaChanged EventType = "persona_changed"
+
+	// EventLimitReached indicates a turn or session limit was reached.
+	// Data is a LimitEvent.
+	EventLimitReached EventType = "limit_reached"
+
+	// EventRecordingConsent indicates the recording announcement played
+	// and consent was settled (Config.RecordingConsent). Data is a
+	// RecordingConsent.
+	EventRecordingConsent EventType = "recording_consent"
+
+	// EventAudioLevel reports the level of caller or agent audio every
+	// Config.AudioLevelInterval while there is audio, then once at zero
+	// when it stops. Data is an AudioLevel. Level events are sent only
+	// if Events has room, and are not audited or sent to webhooks.
+	EventAudioLevel EventType = "audio_level"
+
+	// EventCallSummary delivers the call summary when the session stops
+	// (Config.Summary), just before EventSessionEnded. Data is a
+	// CallSummary; Error is set, and Data nil, if it failed.
+	EventCallSummary EventType = "call_summary"
 )
 
 // Metrics contains session performance metrics.
@@ -238,20 +854,72 @@ type Metrics struct {
 	// AvgLLMLatencyMs is average LLM processing time.
 	AvgLLMLatencyMs int
 
-	// AvgTTSLatencyMs is average TTS processing time.
+	// AvgTTSLatencyMs is the average time from a TTS request to its first
+	// audio chunk.
 	AvgTTSLatencyMs int
 
+	// AvgTTSLatencyByProviderMs is AvgTTSLatencyMs by the TTS provider
+	// that served the request.
+	AvgTTSLatencyByProviderMs map[string]int
+
+	// AvgRetrievalLatencyMs is the average time Config.Retriever took,
+	// including calls that failed or timed out.
+	AvgRetrievalLatencyMs int
+
 	// AvgTotalLatencyMs is average end-to-end latency.
 	AvgTotalLatencyMs int
 
+	// TimeToFirstAudioMs is the time from the end of the last user turn to
+	// the first agent audio of the response.
+	TimeToFirstAudioMs int
+
+	// AvgTimeToFirstAudioMs is the average TimeToFirstAudioMs.
+	AvgTimeToFirstAudioMs int
+
 	// InterruptionCount is number of user interruptions.
 	InterruptionCount int
 
+	// FalseInterruptionCount is the number of interruptions the agent
+	// resumed from under Config.InterruptionResume. They are included in
+	// InterruptionCount.
+	FalseInterruptionCount int
+
+	// VoiceSwitchCount is the number of times TTS fallback moved the
+	// agent's voice to another provider between utterances.
+	VoiceSwitchCount int
+
 	// ToolCallCount is number of tool invocations.
 	ToolCallCount int
 
 	// ErrorCount is number of errors encountered.
 	ErrorCount int
+
+	// DroppedAudioFrames is the number of ReceiveAudio chunks discarded
+	// because the consumer fell behind.
+	DroppedAudioFrames int
+
+	// DroppedEvents is the number of events discarded because the
+	// consumer fell behind.
+	DroppedEvents int
+
+	// DroppedTranscriptTurns is the number of turns discarded because
+	// Config.TranscriptSink fell behind.
+	DroppedTranscriptTurns int
+
+	// InputAudioHighWater is the most caller audio, in bytes, buffered at
+	// once waiting for STT (see Config.InputAudioBuffer).
+	InputAudioHighWater int
+
+	// DroppedInputAudioBytes is the caller audio discarded under
+	// Config.InputAudioBufferPolicy BufferDropOldest.
+	DroppedInputAudioBytes int
+
+	// Usage is the LLM and TTS spend, priced with Config.Budget.
+	Usage Usage
+
+	// AudioQuality is the latest call-quality measurement of the session's
+	// transport (see QualityRecorder), or zero if none was reported.
+	AudioQuality transport.QualityStats
 }
 
 // Provider defines the interface for voice agent providers.
@@ -0,0 +1,25 @@
+package agenttest
+
+import "errors"
+
+var (
+	// ErrScriptExhausted is returned by ScriptedLLM when it is called more
+	// times than it has responses.
+	ErrScriptExhausted = errors.New("agenttest: no scripted response left")
+
+	// ErrInterruptNotSupported is returned when an Action interrupts a
+	// session that does not implement agent.Interrupter.
+	ErrInterruptNotSupported = errors.New("agenttest: session does not support interruption")
+
+	// ErrDTMFNotSupported is returned when an Action sends digits to a
+	// session that does not implement agent.DTMFSender.
+	ErrDTMFNotSupported = errors.New("agenttest: session does not support DTMF")
+
+	// ErrNotSupported is returned by mock provider methods with no
+	// meaningful simulation.
+	ErrNotSupported = errors.New("agenttest: not supported")
+
+	// ErrInvalidAction is returned for an Action that does nothing or does
+	// more than one thing.
+	ErrInvalidAction = errors.New("agenttest: invalid action")
+)
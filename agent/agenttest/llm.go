@@ -0,0 +1,101 @@
+package agenttest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+// Response is one scripted LLM reply.
+type Response struct {
+	// Text is streamed word by word.
+	Text string
+
+	// ToolCalls are sent after Text.
+	ToolCalls []agent.LLMToolCall
+
+	// Usage, if set, is reported on the last chunk.
+	Usage *agent.LLMUsage
+
+	// Err, if set, is returned from Stream instead of a reply.
+	Err error
+}
+
+// ScriptedLLM is an agent.LLM that returns Responses in order and records
+// the messages it was sent.
+type ScriptedLLM struct {
+	mu        sync.Mutex
+	responses []Response
+	calls     [][]agent.Message
+}
+
+// NewScriptedLLM creates an LLM that replies with responses in order.
+func NewScriptedLLM(responses ...Response) *ScriptedLLM {
+	return &ScriptedLLM{responses: responses}
+}
+
+// Stream returns the next scripted response, or ErrScriptExhausted.
+func (l *ScriptedLLM) Stream(ctx context.Context, messages []agent.Message, tools []agent.Tool) (<-chan agent.LLMChunk, error) {
+	l.mu.Lock()
+	n := len(l.calls)
+	l.calls = append(l.calls, append([]agent.Message(nil), messages...))
+	l.mu.Unlock()
+	if n >= len(l.responses) {
+		return nil, fmt.Errorf("%w: call %d", ErrScriptExhausted, n+1)
+	}
+	r := l.responses[n]
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	ch := make(chan agent.LLMChunk)
+	go func() {
+		defer close(ch)
+		words := strings.SplitAfter(r.Text, " ")
+		chunks := make([]agent.LLMChunk, 0, len(words)+1)
+		for _, w := range words {
+			if w != "" {
+				chunks = append(chunks, agent.LLMChunk{Text: w})
+			}
+		}
+		if len(r.ToolCalls) > 0 || r.Usage != nil {
+			chunks = append(chunks, agent.LLMChunk{ToolCalls: r.ToolCalls, Usage: r.Usage})
+		}
+		for _, c := range chunks {
+			select {
+			case ch <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Calls returns the messages sent on each call to Stream.
+func (l *ScriptedLLM) Calls() [][]agent.Message {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([][]agent.Message(nil), l.calls...)
+}
+
+// StubTool returns a tool that always returns result and records the
+// arguments of each call in calls, if non-nil.
+func StubTool(name string, result any, calls *[]map[string]any) agent.Tool {
+	var mu sync.Mutex
+	return agent.Tool{
+		Name:        name,
+		Description: "stub " + name,
+		StructuredHandler: func(ctx context.Context, args map[string]any) (*agent.ToolResult, error) {
+			if calls != nil {
+				mu.Lock()
+				*calls = append(*calls, args)
+				mu.Unlock()
+			}
+			return &agent.ToolResult{Data: result}, nil
+		},
+	}
+}
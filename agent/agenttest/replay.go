@@ -0,0 +1,398 @@
+// Package agenttest replays recorded caller audio through agent sessions
+// for end-to-end regression tests.
+//
+// Run feeds 16-bit mono PCM into Session.SendAudio at real-time or
+// accelerated pace, injects DTMF, text, and interruptions at audio
+// timestamps, and captures every event, the agent audio, and the final
+// transcript and metrics. ScriptedSTT, ScriptedLLM, SilentTTS, and
+// StubTool stand in for real providers so that a custom.Provider session
+// is deterministic.
+//
+// With Speed set, agent audio is read at playback pace. Configure sessions
+// with AudioBufferPolicy agent.BufferBlock so that pace holds the agent
+// back as a phone line would; under the default drop-oldest policy, speech
+// synthesized faster than it plays is dropped and counted in
+// Metrics.DroppedAudioFrames.
+//
+//	llm := agenttest.NewScriptedLLM(agenttest.Response{Text: "Your balance is ten dollars."})
+//	p := custom.New(
+//		stt.NewClient(agenttest.NewScriptedSTT(agenttest.Utterance{Start: time.Second, End: 2 * time.Second, Text: "balance"})),
+//		tts.NewClient(&agenttest.SilentTTS{}),
+//		llm,
+//	)
+//	session, _ := p.CreateSession(ctx, agent.Config{AudioBufferPolicy: agent.BufferBlock})
+//	pcm, rate, _ := agenttest.LoadWAV("testdata/caller.wav")
+//	result, err := agenttest.Run(ctx, session, agenttest.Replay{Audio: pcm, SampleRate: rate, Speed: 10})
+package agenttest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// stopTimeout bounds how long Run waits for a stopped session to close
+// its event channel.
+const stopTimeout = 2 * time.Second
+
+// Replay describes a replay run.
+type Replay struct {
+	// Audio is 16-bit mono PCM caller audio.
+	Audio []byte
+
+	// SampleRate is the sample rate of Audio. Defaults to 16000.
+	SampleRate int
+
+	// Speed is the playback rate: 1 is real time, 10 is ten times faster.
+	// Zero or less sends audio as fast as the session accepts it, which
+	// is fastest but leaves agent timing relative to the audio undefined.
+	Speed float64
+
+	// FrameDuration is the size of each SendAudio call. Defaults to 20ms.
+	FrameDuration time.Duration
+
+	// Actions are injected at audio timestamps. Actions after the end of
+	// Audio are reached by sending silence.
+	Actions []Action
+
+	// Settle is how long to wait with no events after the last audio
+	// before stopping the session. Defaults to 500ms.
+	Settle time.Duration
+}
+
+// Action is something injected at a point in the replay. Exactly one of
+// DTMF, Text, Interrupt, or Do is set.
+type Action struct {
+	// At is the audio position at which the action runs.
+	At time.Duration
+
+	// DTMF is sent with agent.DTMFSender.
+	DTMF string
+
+	// Text is sent with Session.SendText.
+	Text string
+
+	// Interrupt interrupts the agent through agent.Interrupter.
+	Interrupt bool
+
+	// Do runs arbitrary code against the session.
+	Do func(ctx context.Context, session agent.Session) error
+}
+
+func (a Action) run(ctx context.Context, session agent.Session) error {
+	n := 0
+	for _, set := range []bool{a.DTMF != "", a.Text != "", a.Interrupt, a.Do != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return ErrInvalidAction
+	}
+	switch {
+	case a.DTMF != "":
+		d, ok := session.(agent.DTMFSender)
+		if !ok {
+			return ErrDTMFNotSupported
+		}
+		return d.SendDTMF(a.DTMF)
+	case a.Text != "":
+		return session.SendText(a.Text)
+	case a.Interrupt:
+		i, ok := session.(agent.Interrupter)
+		if !ok {
+			return ErrInterruptNotSupported
+		}
+		return i.Interrupt()
+	default:
+		return a.Do(ctx, session)
+	}
+}
+
+// RecordedEvent is a session event with the replay position at which it
+// was received.
+type RecordedEvent struct {
+	agent.Event
+
+	// At is the amount of caller audio sent when the event arrived.
+	At time.Duration
+}
+
+// Result is the outcome of a replay.
+type Result struct {
+	// Events are all session events, in order.
+	Events []RecordedEvent
+
+	// AgentAudio is all audio received from the agent.
+	AgentAudio []byte
+
+	// Transcript is the session transcript just before it stopped.
+	Transcript []agent.Turn
+
+	// Metrics are the session metrics just before it stopped.
+	Metrics agent.Metrics
+}
+
+// Filter returns the events of the given types.
+func (r *Result) Filter(types ...agent.EventType) []RecordedEvent {
+	var out []RecordedEvent
+	for _, ev := range r.Events {
+		for _, t := range types {
+			if ev.Type == t {
+				out = append(out, ev)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Count returns the number of events of type t.
+func (r *Result) Count(t agent.EventType) int {
+	return len(r.Filter(t))
+}
+
+// Texts returns the text of each transcript turn with the given role.
+func (r *Result) Texts(role string) []string {
+	var out []string
+	for _, turn := range r.Transcript {
+		if turn.Role == role {
+			out = append(out, turn.Text)
+		}
+	}
+	return out
+}
+
+// LoadWAV reads a 16-bit PCM WAV file, mixing stereo down to mono, and
+// returns the samples and sample rate.
+func LoadWAV(path string) ([]byte, int, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- test fixture path
+	if err != nil {
+		return nil, 0, err
+	}
+	pcm, format, err := audio.ReadWAV(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	if format.Channels > 1 {
+		pcm = downmix(pcm, format.Channels)
+	}
+	return pcm, format.SampleRate, nil
+}
+
+func downmix(pcm []byte, channels int) []byte {
+	in := audio.BytesToInt16(pcm)
+	out := make([]int16, len(in)/channels)
+	for i := range out {
+		var sum int
+		for c := 0; c < channels; c++ {
+			sum += int(in[i*channels+c])
+		}
+		out[i] = int16(sum / channels) // #nosec G115 -- mean of int16 values
+	}
+	return audio.Int16ToBytes(out)
+}
+
+// Run starts session, replays the audio and actions, waits for the session
+// to settle, and stops it. The session must not have been started. If an
+// action fails, the session is stopped and the error returned with the
+// result so far.
+func Run(ctx context.Context, session agent.Session, replay Replay) (*Result, error) {
+	rate := replay.SampleRate
+	if rate <= 0 {
+		rate = 16000
+	}
+	frameDuration := replay.FrameDuration
+	if frameDuration <= 0 {
+		frameDuration = audio.DefaultFrameDuration
+	}
+	settle := replay.Settle
+	if settle <= 0 {
+		settle = 500 * time.Millisecond
+	}
+	actions := append([]Action(nil), replay.Actions...)
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].At < actions[j].At })
+
+	rec := &recorder{speed: replay.Speed, rate: rate}
+	rec.start(session)
+
+	if err := session.Start(ctx); err != nil {
+		_ = session.Stop(context.Background())
+		return rec.finish(session, false), err
+	}
+	runErr := rec.play(ctx, session, replay.Audio, frameDuration, actions)
+	if runErr == nil {
+		rec.settle(ctx, settle)
+	}
+	result := rec.finish(session, true)
+	if runErr == nil {
+		runErr = ctx.Err()
+	}
+	return result, runErr
+}
+
+// recorder drives a replay and collects its output.
+type recorder struct {
+	speed float64
+	rate  int
+
+	sent      atomic.Int64
+	lastEvent atomic.Int64
+
+	mu         sync.Mutex
+	events     []RecordedEvent
+	agentAudio []byte
+	wg         sync.WaitGroup
+}
+
+func (r *recorder) position() time.Duration {
+	return pcmDuration(int(r.sent.Load()), r.rate)
+}
+
+func (r *recorder) start(session agent.Session) {
+	r.lastEvent.Store(time.Now().UnixNano())
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		for ev := range session.Events() {
+			r.lastEvent.Store(time.Now().UnixNano())
+			r.mu.Lock()
+			r.events = append(r.events, RecordedEvent{Event: ev, At: r.position()})
+			r.mu.Unlock()
+		}
+	}()
+	go func() {
+		// Agent audio is consumed at the replay pace, as a phone line
+		// would, so speech takes as long to play as it lasts.
+		defer r.wg.Done()
+		release, _ := session.(agent.AudioReleaser)
+		for chunk := range session.ReceiveAudio() {
+			r.mu.Lock()
+			r.agentAudio = append(r.agentAudio, chunk...)
+			r.mu.Unlock()
+			n := len(chunk)
+			if release != nil {
+				release.ReleaseAudio(chunk)
+			}
+			if r.speed > 0 {
+				time.Sleep(time.Duration(float64(pcmDuration(n, r.rate)) / r.speed))
+			}
+		}
+	}()
+}
+
+// play sends the audio frame by frame, running each action once its
+// position is reached, then pads with silence up to the last action.
+func (r *recorder) play(ctx context.Context, session agent.Session, pcm []byte, frameDuration time.Duration, actions []Action) error {
+	frame := audio.BytesPerSecond(r.rate, 1) * int(frameDuration) / int(time.Second)
+	frame -= frame % audio.BytesPerSample
+	frame = max(frame, audio.BytesPerSample)
+	silence := make([]byte, frame)
+	began := time.Now()
+	flushed := false
+
+	for len(pcm) > 0 || len(actions) > 0 {
+		pos := r.position()
+		for len(actions) > 0 && actions[0].At <= pos {
+			if err := actions[0].run(ctx, session); err != nil {
+				return fmt.Errorf("agenttest: action at %v: %w", actions[0].At, err)
+			}
+			actions = actions[1:]
+		}
+		if len(pcm) == 0 && len(actions) == 0 {
+			break
+		}
+		if len(pcm) == 0 && !flushed {
+			// End of the recording: don't leave a partial frame behind
+			// the silence padding.
+			flushed = true
+			if err := flushAudio(session); err != nil {
+				return err
+			}
+		}
+
+		chunk := silence
+		if len(pcm) > 0 {
+			n := min(frame, len(pcm))
+			chunk, pcm = pcm[:n], pcm[n:]
+		}
+		if err := session.SendAudio(chunk); err != nil {
+			return err
+		}
+		r.sent.Add(int64(len(chunk)))
+
+		if r.speed > 0 {
+			due := began.Add(time.Duration(float64(r.position()) / r.speed))
+			select {
+			case <-time.After(time.Until(due)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	if !flushed {
+		return flushAudio(session)
+	}
+	return nil
+}
+
+// flushAudio flushes the session's partial frame, if it buffers one.
+func flushAudio(session agent.Session) error {
+	if f, ok := session.(agent.AudioFlusher); ok {
+		return f.FlushAudio()
+	}
+	return nil
+}
+
+// settle waits until no event has arrived for d.
+func (r *recorder) settle(ctx context.Context, d time.Duration) {
+	for {
+		quiet := time.Since(time.Unix(0, r.lastEvent.Load()))
+		if quiet >= d {
+			return
+		}
+		select {
+		case <-time.After(d - quiet):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// finish stops the session and collects the result.
+func (r *recorder) finish(session agent.Session, stop bool) *Result {
+	result := &Result{Transcript: session.Transcript(), Metrics: session.Metrics()}
+	if stop {
+		ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		_ = session.Stop(ctx)
+		cancel()
+	}
+
+	// Sessions close their channels on Stop; don't hang on one that
+	// doesn't.
+	drained := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(stopTimeout):
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result.Events = append([]RecordedEvent(nil), r.events...)
+	result.AgentAudio = append([]byte(nil), r.agentAudio...)
+	return result
+}
@@ -0,0 +1,234 @@
+package agenttest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// Utterance is something the caller says in the replayed audio, as
+// offsets from the start of the stream.
+type Utterance struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+
+	// Speaker is the diarization label reported for the utterance.
+	Speaker string
+}
+
+// ScriptedSTT is a streaming STT provider that "recognizes" Utterances by
+// audio position rather than content: SpeechStart is sent once Start worth
+// of audio has been written, and SpeechEnd and a final transcript once End
+// has. Results therefore depend only on the audio, not on timing.
+type ScriptedSTT struct {
+	Utterances []Utterance
+}
+
+// NewScriptedSTT creates an STT provider recognizing utterances.
+func NewScriptedSTT(utterances ...Utterance) *ScriptedSTT {
+	return &ScriptedSTT{Utterances: utterances}
+}
+
+// Name returns the provider name.
+func (s *ScriptedSTT) Name() string { return "agenttest" }
+
+// Transcribe returns the text of every utterance within the audio.
+func (s *ScriptedSTT) Transcribe(ctx context.Context, pcm []byte, config stt.TranscriptionConfig) (*stt.TranscriptionResult, error) {
+	length := pcmDuration(len(pcm), config.SampleRate)
+	result := &stt.TranscriptionResult{Duration: length}
+	var texts []string
+	for _, u := range s.Utterances {
+		if u.End > length {
+			continue
+		}
+		texts = append(texts, u.Text)
+		result.Segments = append(result.Segments, stt.Segment{Text: u.Text, StartTime: u.Start, EndTime: u.End, Confidence: 1, Speaker: u.Speaker})
+	}
+	result.Text = strings.Join(texts, " ")
+	return result, nil
+}
+
+// TranscribeFile transcribes a WAV file.
+func (s *ScriptedSTT) TranscribeFile(ctx context.Context, filePath string, config stt.TranscriptionConfig) (*stt.TranscriptionResult, error) {
+	pcm, rate, err := LoadWAV(filePath)
+	if err != nil {
+		return nil, err
+	}
+	config.SampleRate = rate
+	return s.Transcribe(ctx, pcm, config)
+}
+
+// TranscribeURL is not supported.
+func (s *ScriptedSTT) TranscribeURL(ctx context.Context, url string, config stt.TranscriptionConfig) (*stt.TranscriptionResult, error) {
+	return nil, ErrNotSupported
+}
+
+// TranscribeStream starts a stream; positions restart at zero for each
+// stream.
+func (s *ScriptedSTT) TranscribeStream(ctx context.Context, config stt.TranscriptionConfig) (io.WriteCloser, <-chan stt.StreamEvent, error) {
+	w := &scriptedStream{
+		utterances: s.Utterances,
+		sampleRate: config.SampleRate,
+		events:     make(chan stt.StreamEvent, 3*len(s.Utterances)),
+		started:    make([]bool, len(s.Utterances)),
+		ended:      make([]bool, len(s.Utterances)),
+	}
+	go func() {
+		<-ctx.Done()
+		_ = w.Close()
+	}()
+	return w, w.events, nil
+}
+
+// scriptedStream emits at most three events per utterance, so its
+// buffered channel never blocks Write.
+type scriptedStream struct {
+	mu         sync.Mutex
+	utterances []Utterance
+	sampleRate int
+	written    int
+	events     chan stt.StreamEvent
+	started    []bool
+	ended      []bool
+	closed     bool
+}
+
+func (w *scriptedStream) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	w.written += len(p)
+	pos := pcmDuration(w.written, w.sampleRate)
+	for i, u := range w.utterances {
+		if !w.started[i] && pos >= u.Start {
+			w.started[i] = true
+			w.events <- stt.StreamEvent{Type: stt.EventSpeechStart, SpeechStarted: true}
+		}
+		if !w.ended[i] && pos >= u.End {
+			w.ended[i] = true
+			w.events <- stt.StreamEvent{Type: stt.EventSpeechEnd, SpeechEnded: true}
+			w.events <- stt.StreamEvent{
+				Type:       stt.EventTranscript,
+				Transcript: u.Text,
+				IsFinal:    true,
+				Segment:    &stt.Segment{Text: u.Text, StartTime: u.Start, EndTime: u.End, Confidence: 1, Speaker: u.Speaker},
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (w *scriptedStream) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.closed = true
+		close(w.events)
+	}
+	return nil
+}
+
+// SilentTTS is a TTS provider producing silence whose length is
+// proportional to the text, and recording what it was asked to say.
+type SilentTTS struct {
+	// WordDuration is the audio length per word. Defaults to 300ms.
+	WordDuration time.Duration
+
+	// ChunkDuration is the length of each streamed chunk. Defaults to 20ms.
+	ChunkDuration time.Duration
+
+	mu     sync.Mutex
+	spoken []string
+}
+
+// Name returns the provider name.
+func (t *SilentTTS) Name() string { return "agenttest" }
+
+// Spoken returns every text synthesized, in order.
+func (t *SilentTTS) Spoken() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.spoken...)
+}
+
+func (t *SilentTTS) render(text string, config tts.SynthesisConfig) ([]byte, int) {
+	t.mu.Lock()
+	t.spoken = append(t.spoken, text)
+	t.mu.Unlock()
+	rate := config.SampleRate
+	if rate <= 0 {
+		rate = 16000
+	}
+	per := t.WordDuration
+	if per <= 0 {
+		per = 300 * time.Millisecond
+	}
+	d := time.Duration(len(strings.Fields(text))) * per
+	n := int(d * time.Duration(rate) / time.Second)
+	return make([]byte, n*audio.BytesPerSample), rate
+}
+
+// Synthesize returns silent PCM.
+func (t *SilentTTS) Synthesize(ctx context.Context, text string, config tts.SynthesisConfig) (*tts.SynthesisResult, error) {
+	pcm, rate := t.render(text, config)
+	return &tts.SynthesisResult{
+		Audio:          pcm,
+		Format:         "pcm",
+		SampleRate:     rate,
+		Channels:       1,
+		DurationMs:     int(pcmDuration(len(pcm), rate).Milliseconds()),
+		CharacterCount: len(text),
+	}, nil
+}
+
+// SynthesizeStream streams silent PCM in ChunkDuration chunks.
+func (t *SilentTTS) SynthesizeStream(ctx context.Context, text string, config tts.SynthesisConfig) (<-chan tts.StreamChunk, error) {
+	pcm, rate := t.render(text, config)
+	chunk := t.ChunkDuration
+	if chunk <= 0 {
+		chunk = 20 * time.Millisecond
+	}
+	size := max(audio.BytesPerSample, int(chunk*time.Duration(audio.BytesPerSecond(rate, 1))/time.Second))
+	size -= size % audio.BytesPerSample
+
+	ch := make(chan tts.StreamChunk)
+	go func() {
+		defer close(ch)
+		for len(pcm) > 0 {
+			n := min(size, len(pcm))
+			select {
+			case ch <- tts.StreamChunk{Audio: pcm[:n], IsFinal: n == len(pcm)}:
+			case <-ctx.Done():
+				return
+			}
+			pcm = pcm[n:]
+		}
+	}()
+	return ch, nil
+}
+
+// ListVoices returns a single placeholder voice.
+func (t *SilentTTS) ListVoices(ctx context.Context) ([]tts.Voice, error) {
+	return []tts.Voice{{ID: "silent", Name: "Silent", Provider: t.Name()}}, nil
+}
+
+// GetVoice returns the placeholder voice.
+func (t *SilentTTS) GetVoice(ctx context.Context, voiceID string) (*tts.Voice, error) {
+	return &tts.Voice{ID: voiceID, Name: "Silent", Provider: t.Name()}, nil
+}
+
+func pcmDuration(n, sampleRate int) time.Duration {
+	if sampleRate <= 0 {
+		sampleRate = 16000
+	}
+	return time.Duration(n) * time.Second / time.Duration(audio.BytesPerSecond(sampleRate, 1))
+}
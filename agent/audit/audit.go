@@ -0,0 +1,281 @@
+// Package audit keeps an append-only log of everything that happened in
+// an agent session, for compliance and for reconstructing a call.
+//
+// A Logger records every session event as a Record: a sequence number,
+// the timestamp, the event type, its data (transcripts, tool calls,
+// interruptions, limits, metrics), and any error. The first record,
+// TypeSessionStarted, also names the STT, TTS, and LLM providers. Records
+// are written by a background worker in batches to a pluggable Sink, so
+// the real-time path never waits on storage; Close flushes them. Redactors
+// mask sensitive data before it is persisted.
+//
+// FileSink and WriterSink store records as JSON Lines; other stores (S3,
+// a database) implement Sink. Read decodes a JSON Lines log and
+// Reconstruct rebuilds the transcript and metrics from its records.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+var (
+	// ErrClosed is returned when logging to a closed Logger.
+	ErrClosed = errors.New("audit: logger closed")
+
+	// ErrGap is reported by Reconstruct when records are missing.
+	ErrGap = errors.New("audit: log has missing records")
+)
+
+// TypeDropped is the type of a record noting that records were dropped
+// because the sink fell behind. Its Data is the number dropped.
+const TypeDropped agent.EventType = "audit_dropped"
+
+// Record is one entry of the audit log.
+type Record struct {
+	// Seq numbers records from 1 in the order events occurred.
+	Seq uint64 `json:"seq"`
+
+	// SessionID is the session the record belongs to.
+	SessionID string `json:"session_id"`
+
+	// Type is the event type.
+	Type agent.EventType `json:"type"`
+
+	// Timestamp is when the event occurred.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Offset is Timestamp in session time (see agent.SessionClock), in
+	// nanoseconds.
+	Offset time.Duration `json:"offset,omitempty"`
+
+	// Data is the event data. Records read back with Read hold the
+	// typed value for known event types: SessionInfo, agent.Turn,
+	// agent.TranscriptUpdate, agent.ToolCall, agent.LimitEvent,
+	// agent.AgentInterruption, agent.RecordingConsent, agent.CallSummary,
+	// or agent.Metrics.
+	// Other data is kept as json.RawMessage.
+	Data any `json:"data,omitempty"`
+
+	// Error is the event's error message, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// SessionInfo is the Data of the session_started record.
+type SessionInfo struct {
+	AgentName   string            `json:"agent_name,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	VoiceID     string            `json:"voice_id,omitempty"`
+	STTProvider string            `json:"stt_provider,omitempty"`
+	TTSProvider string            `json:"tts_provider,omitempty"`
+	LLMProvider string            `json:"llm_provider,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Sink persists audit records. Write is called from one goroutine with
+// batches in order; a failed batch is reported to the error handler and
+// not retried, so sinks that need retries implement them.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// Redactor masks sensitive data in a record before it is persisted. It
+// runs on the logger's worker, not the real-time path. Data is shared
+// with the session's event consumers, so redactors replace values rather
+// than modifying them in place.
+type Redactor func(r *Record)
+
+// Option configures a Logger.
+type Option func(*options)
+
+type options struct {
+	redactors []Redactor
+	queueSize int
+	batchSize int
+	onError   func(error)
+}
+
+// WithRedactor adds a redactor. Redactors run in the order added.
+func WithRedactor(r Redactor) Option {
+	return func(o *options) {
+		o.redactors = append(o.redactors, r)
+	}
+}
+
+// WithQueueSize sets how many records may wait for the sink. When the
+// queue is full, records are dropped and a TypeDropped record notes how
+// many. Defaults to 4096.
+func WithQueueSize(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithBatchSize sets the most records passed to one Sink.Write. Defaults
+// to 256.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithErrorHandler sets the handler for sink errors. By default they are
+// discarded.
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// Logger records the events of one session to a Sink.
+type Logger struct {
+	sessionID string
+	config    agent.Config
+	sink      Sink
+	opts      options
+	queue     chan Record
+	done      chan struct{}
+
+	mu      sync.Mutex
+	seq     uint64
+	dropped int
+	closed  bool
+}
+
+// New creates a logger for a session and starts its worker. config
+// supplies the SessionInfo of the session_started record. Call Close
+// when the session ends.
+func New(sessionID string, config agent.Config, sink Sink, opts ...Option) *Logger {
+	o := options{queueSize: 4096, batchSize: 256}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	l := &Logger{
+		sessionID: sessionID,
+		config:    config,
+		sink:      sink,
+		opts:      o,
+		queue:     make(chan Record, max(o.queueSize, 1)),
+		done:      make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Observe records a session event. It never blocks.
+func (l *Logger) Observe(ev agent.Event) {
+	r := Record{SessionID: l.sessionID, Type: ev.Type, Timestamp: ev.Timestamp, Offset: ev.Offset, Data: ev.Data}
+	if ev.Type == agent.EventSessionStarted && ev.Data == nil {
+		r.Data = SessionInfo{
+			AgentName:   l.config.Name,
+			Language:    l.config.Language,
+			VoiceID:     l.config.VoiceID,
+			STTProvider: l.config.STTProvider,
+			TTSProvider: l.config.TTSProvider,
+			LLMProvider: l.config.LLMProvider,
+			Metadata:    l.config.Metadata,
+		}
+	}
+	if ev.Error != nil {
+		r.Error = ev.Error.Error()
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	if l.dropped > 0 && !l.enqueue(Record{SessionID: l.sessionID, Type: TypeDropped, Timestamp: r.Timestamp, Data: l.dropped}) {
+		l.dropped++
+		return
+	}
+	l.dropped = 0
+	if !l.enqueue(r) {
+		l.dropped++
+	}
+}
+
+// enqueue numbers and queues a record, reporting false if the queue is
+// full. The caller holds l.mu.
+func (l *Logger) enqueue(r Record) bool {
+	r.Seq = l.seq + 1
+	select {
+	case l.queue <- r:
+		l.seq++
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops recording, writes the queued records, and closes the sink.
+// If ctx ends first, unwritten records are abandoned and ctx.Err() is
+// returned.
+func (l *Logger) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+	select {
+	case <-l.done:
+		return l.sink.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	batch := make([]Record, 0, max(l.opts.batchSize, 1))
+	for r := range l.queue {
+		batch = append(batch[:0], l.prepare(r))
+	fill:
+		for len(batch) < cap(batch) {
+			select {
+			case r, ok := <-l.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, l.prepare(r))
+			default:
+				break fill
+			}
+		}
+		if err := l.sink.Write(context.Background(), batch); err != nil && l.opts.onError != nil {
+			l.opts.onError(fmt.Errorf("audit: writing records %d-%d: %w", batch[0].Seq, batch[len(batch)-1].Seq, err))
+		}
+	}
+}
+
+// prepare redacts a record and checks that its data can be encoded.
+func (l *Logger) prepare(r Record) Record {
+	for _, redact := range l.opts.redactors {
+		redact(&r)
+	}
+	if r.Data != nil {
+		if _, err := json.Marshal(r.Data); err != nil {
+			r.Data = nil
+			r.Error = joinError(r.Error, fmt.Sprintf("audit: data not recorded: %v", err))
+		}
+	}
+	return r
+}
+
+func joinError(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "; " + b
+}
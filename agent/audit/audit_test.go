@@ -0,0 +1,232 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+var start = time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+
+// session returns the events of a short call, session_started to
+// session_ended.
+func session() []agent.Event {
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	return []agent.Event{
+		{Type: agent.EventSessionStarted, Timestamp: at(0)},
+		{Type: agent.EventUserTranscript, Timestamp: at(1), Offset: time.Second, Data: agent.Turn{Role: "user", Text: "my card is 4111 1111 1111 1111", Timestamp: at(1)}},
+		{Type: agent.EventToolCall, Timestamp: at(2), Data: agent.ToolCall{Name: "charge", Arguments: map[string]any{"card": "4111111111111111", "amount": 12.5}, Result: "charged 4111111111111111"}},
+		{Type: agent.EventInterruption, Timestamp: at(3)},
+		{Type: agent.EventAgentTranscript, Timestamp: at(4), Data: agent.Turn{Role: "agent", Text: "Done.", Timestamp: at(4)}},
+		{Type: agent.EventError, Timestamp: at(5), Error: errors.New("declined 4111111111111111")},
+		{Type: agent.EventSessionEnded, Timestamp: at(6), Data: agent.Metrics{TurnCount: 2, ToolCallCount: 1, SessionDurationMs: 6000}},
+	}
+}
+
+func logSession(t *testing.T, sink Sink, events []agent.Event, opts ...Option) {
+	t.Helper()
+	config := agent.Config{Name: "billing", STTProvider: "deepgram", TTSProvider: "elevenlabs", LLMProvider: "openai"}
+	l := New("sess-1", config, sink, opts...)
+	for _, ev := range events {
+		l.Observe(ev)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := session()
+	logSession(t, sink, events)
+
+	records, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(events) {
+		t.Fatalf("read %d records, want %d", len(records), len(events))
+	}
+	for i, r := range records {
+		if r.Seq != uint64(i+1) || r.Type != events[i].Type || !r.Timestamp.Equal(events[i].Timestamp) || r.SessionID != "sess-1" {
+			t.Errorf("record %d = seq %d %s at %v in %q", i, r.Seq, r.Type, r.Timestamp, r.SessionID)
+		}
+	}
+	if c, ok := records[2].Data.(agent.ToolCall); !ok || c.Name != "charge" || c.Arguments["amount"] != 12.5 {
+		t.Errorf("tool call read back as %#v", records[2].Data)
+	}
+	if records[5].Error != "declined 4111111111111111" {
+		t.Errorf("error read back as %q", records[5].Error)
+	}
+
+	// Records may arrive out of order from a store that doesn't keep it.
+	records[0], records[4] = records[4], records[0]
+	rec, err := Reconstruct(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SessionInfo{AgentName: "billing", STTProvider: "deepgram", TTSProvider: "elevenlabs", LLMProvider: "openai"}
+	if rec.SessionID != "sess-1" || rec.Session.AgentName != want.AgentName || rec.Session.LLMProvider != want.LLMProvider {
+		t.Errorf("session %q %+v, want %+v", rec.SessionID, rec.Session, want)
+	}
+	if len(rec.Transcript) != 2 || rec.Transcript[0].Text != "my card is 4111 1111 1111 1111" || rec.Transcript[1].Role != "agent" {
+		t.Errorf("transcript %+v", rec.Transcript)
+	}
+	if !rec.Transcript[0].Timestamp.Equal(start.Add(time.Second)) {
+		t.Errorf("first turn at %v", rec.Transcript[0].Timestamp)
+	}
+	if !rec.Complete || rec.Missing != 0 || rec.Metrics.TurnCount != 2 || rec.Metrics.SessionDurationMs != 6000 {
+		t.Errorf("complete %v, missing %d, metrics %+v", rec.Complete, rec.Missing, rec.Metrics)
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	card := regexp.MustCompile(`\b(?:\d[ -]?){13,19}\b`)
+	var buf bytes.Buffer
+	events := session()
+	logSession(t, NewWriterSink(&buf), events, WithRedactor(RedactText(card, "[card]")))
+
+	if strings.Contains(buf.String(), "4111") {
+		t.Fatalf("card number persisted:\n%s", buf.String())
+	}
+	records, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn := records[1].Data.(agent.Turn); turn.Text != "my card is [card]" {
+		t.Errorf("transcript redacted to %q", turn.Text)
+	}
+	c := records[2].Data.(agent.ToolCall)
+	if c.Arguments["card"] != "[card]" || c.Result != "charged [card]" || c.Arguments["amount"] != 12.5 {
+		t.Errorf("tool call redacted to %+v", c)
+	}
+	if records[5].Error != "declined [card]" {
+		t.Errorf("error redacted to %q", records[5].Error)
+	}
+
+	// The session's own events are shared with other consumers and must
+	// keep their values.
+	if events[1].Data.(agent.Turn).Text != "my card is 4111 1111 1111 1111" ||
+		events[2].Data.(agent.ToolCall).Arguments["card"] != "4111111111111111" {
+		t.Error("redactor modified the session's event data")
+	}
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	var first []byte
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logSession(t, sink, session()[:2])
+		if i == 0 {
+			if first, err = os.ReadFile(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	all, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(all, first) {
+		t.Error("reopening the log rewrote earlier records")
+	}
+	records, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Errorf("read %d records, want both sessions' 4", len(records))
+	}
+}
+
+func TestReconstructGap(t *testing.T) {
+	var buf bytes.Buffer
+	logSession(t, NewWriterSink(&buf), session())
+	records, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lose a record and the end of the log.
+	records = append(records[:2], records[3:len(records)-1]...)
+
+	rec, err := Reconstruct(records)
+	if !errors.Is(err, ErrGap) {
+		t.Fatalf("Reconstruct = %v, want ErrGap", err)
+	}
+	if rec.Missing != 1 || rec.Complete {
+		t.Errorf("missing %d, complete %v; want 1, false", rec.Missing, rec.Complete)
+	}
+	// Without session_ended the metrics are counted from the records.
+	if rec.Metrics.TurnCount != 2 || rec.Metrics.ToolCallCount != 0 || rec.Metrics.InterruptionCount != 1 ||
+		rec.Metrics.ErrorCount != 1 || rec.Metrics.SessionDurationMs != 5000 {
+		t.Errorf("counted metrics %+v", rec.Metrics)
+	}
+}
+
+// gateSink holds each Write until released.
+type gateSink struct {
+	WriterSink
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *gateSink) Write(ctx context.Context, records []Record) error {
+	s.entered <- struct{}{}
+	<-s.release
+	return s.WriterSink.Write(ctx, records)
+}
+
+func TestDroppedRecords(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &gateSink{WriterSink: WriterSink{w: &buf}, entered: make(chan struct{}), release: make(chan struct{})}
+	l := New("sess-1", agent.Config{}, sink, WithQueueSize(1), WithBatchSize(1))
+	ev := agent.Event{Type: agent.EventInterruption}
+
+	l.Observe(ev)
+	<-sink.entered // the worker holds record 1
+	l.Observe(ev)  // queued
+	l.Observe(ev)  // dropped
+	l.Observe(ev)  // dropped
+	sink.release <- struct{}{}
+	<-sink.entered // the worker holds record 2; the queue is empty
+	l.Observe(ev)  // queues the drop notice; this one is dropped
+	go func() {
+		for range sink.entered {
+		}
+	}()
+	close(sink.release)
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(sink.entered)
+
+	records, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2].Type != TypeDropped || records[2].Data != 2 {
+		t.Fatalf("records %+v, want two events and a note of 2 dropped", records)
+	}
+	rec, err := Reconstruct(records)
+	if !errors.Is(err, ErrGap) || rec.Missing != 2 {
+		t.Errorf("Reconstruct = missing %d, %v; want 2, ErrGap", rec.Missing, err)
+	}
+}
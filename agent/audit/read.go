@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+// maxLine bounds a JSON Lines record, which may hold a long transcript or
+// tool result.
+const maxLine = 16 << 20
+
+// UnmarshalJSON decodes a record, giving Data its type from Type.
+func (r *Record) UnmarshalJSON(b []byte) error {
+	type plain Record
+	var raw struct {
+		plain
+		Data json.RawMessage `json:"data,omitempty"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*r = Record(raw.plain)
+	if len(raw.Data) == 0 {
+		return nil
+	}
+	var err error
+	switch r.Type {
+	case agent.EventSessionStarted:
+		r.Data, err = decode[SessionInfo](raw.Data)
+	case agent.EventSessionEnded:
+		r.Data, err = decode[agent.Metrics](raw.Data)
+	case agent.EventUserTranscript, agent.EventAgentTranscript:
+		r.Data, err = decode[agent.Turn](raw.Data)
+	case agent.EventUserTranscriptUpdate:
+		r.Data, err = decode[agent.TranscriptUpdate](raw.Data)
+	case agent.EventToolCall:
+		r.Data, err = decode[agent.ToolCall](raw.Data)
+	case agent.EventLimitReached:
+		r.Data, err = decode[agent.LimitEvent](raw.Data)
+	case agent.EventAgentInterrupted:
+		r.Data, err = decode[agent.AgentInterruption](raw.Data)
+	case agent.EventPersonaChanged:
+		r.Data, err = decode[agent.PersonaChange](raw.Data)
+	case agent.EventRecordingConsent:
+		r.Data, err = decode[agent.RecordingConsent](raw.Data)
+	case agent.EventCallSummary:
+		r.Data, err = decode[agent.CallSummary](raw.Data)
+	case TypeDropped:
+		r.Data, err = decode[int](raw.Data)
+	default:
+		r.Data = raw.Data
+	}
+	return err
+}
+
+func decode[T any](b []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// Read decodes a JSON Lines audit log.
+func Read(r io.Reader) ([]Record, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxLine)
+	var records []Record
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return records, fmt.Errorf("audit: line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, sc.Err()
+}
+
+// ReadFile decodes the JSON Lines audit log at path.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path) // #nosec G304 -- the caller chooses the log path
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Reconstruction is a session rebuilt from its audit log.
+type Reconstruction struct {
+	SessionID string
+
+	// Session is the session_started record's data.
+	Session SessionInfo
+
+	// Transcript is the conversation, in order.
+	Transcript []agent.Turn
+
+	// Metrics are the final metrics if the log is complete, and otherwise
+	// counted from the records.
+	Metrics agent.Metrics
+
+	// Complete reports whether the log reaches session_ended.
+	Complete bool
+
+	// Missing is the number of records lost, dropped by the logger or
+	// absent from the log.
+	Missing int
+}
+
+// Reconstruct rebuilds a session from its records, in any order. If
+// records are missing it still returns the reconstruction, with an error
+// wrapping ErrGap.
+func Reconstruct(records []Record) (*Reconstruction, error) {
+	sorted := slices.Clone(records)
+	slices.SortStableFunc(sorted, func(a, b Record) int { return cmp.Compare(a.Seq, b.Seq) })
+
+	rec := &Reconstruction{}
+	var counted agent.Metrics
+	var next uint64 = 1
+	for _, r := range sorted {
+		if r.Seq > next {
+			rec.Missing += int(r.Seq - next) // #nosec G115 -- record counts fit in int
+		}
+		next = r.Seq + 1
+		if rec.SessionID == "" {
+			rec.SessionID = r.SessionID
+		}
+		if r.Error != "" {
+			counted.ErrorCount++
+		}
+		switch d := r.Data.(type) {
+		case SessionInfo:
+			rec.Session = d
+		case agent.Turn:
+			rec.Transcript = append(rec.Transcript, d)
+		case agent.ToolCall:
+			counted.ToolCallCount++
+		case agent.Metrics:
+			rec.Metrics, rec.Complete = d, true
+		case int:
+			if r.Type == TypeDropped {
+				rec.Missing += d
+			}
+		}
+		if r.Type == agent.EventInterruption {
+			counted.InterruptionCount++
+		}
+	}
+	if !rec.Complete {
+		counted.TurnCount = len(rec.Transcript)
+		if n := len(sorted); n > 0 {
+			counted.SessionDurationMs = int(sorted[n-1].Timestamp.Sub(sorted[0].Timestamp).Milliseconds())
+		}
+		rec.Metrics = counted
+	}
+	if rec.Missing > 0 {
+		return rec, fmt.Errorf("%w: %d records", ErrGap, rec.Missing)
+	}
+	return rec, nil
+}
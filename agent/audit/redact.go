@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"maps"
+	"math"
+	"regexp"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/stt"
+)
+
+// RedactText returns a redactor replacing matches of re with mask in
+// transcripts, DTMF input, tool arguments, tool results, and call
+// summaries, e.g. to mask card numbers:
+//
+//	audit.WithRedactor(audit.RedactText(regexp.MustCompile(`\b(?:\d[ -]?){13,19}\b`), "[card]"))
+func RedactText(re *regexp.Regexp, mask string) Redactor {
+	text := func(s string) string { return re.ReplaceAllLiteralString(s, mask) }
+	toolCall := func(c agent.ToolCall) agent.ToolCall {
+		if c.Arguments != nil {
+			args := maps.Clone(c.Arguments)
+			for k, v := range args {
+				if s, ok := v.(string); ok {
+					args[k] = text(s)
+				}
+			}
+			c.Arguments = args
+		}
+		c.Result = text(c.Result)
+		return c
+	}
+	return func(r *Record) {
+		switch d := r.Data.(type) {
+		case agent.Turn:
+			d.Text = text(d.Text)
+			d.DTMF = text(d.DTMF)
+			if d.ToolCalls != nil {
+				calls := make([]agent.ToolCall, len(d.ToolCalls))
+				for i, c := range d.ToolCalls {
+					calls[i] = toolCall(c)
+				}
+				d.ToolCalls = calls
+			}
+			r.Data = d
+		case agent.TranscriptUpdate:
+			d.Text = text(d.Text)
+			// A correction's offsets no longer apply to the masked text,
+			// so it becomes a rewrite of the whole utterance; Apply clamps
+			// the length to the previous text.
+			d.Correction = stt.Correction{Length: math.MaxInt, Text: d.Text}
+			r.Data = d
+		case agent.ToolCall:
+			r.Data = toolCall(d)
+		case agent.RecordingConsent:
+			d.Response = text(d.Response)
+			r.Data = d
+		case agent.CallSummary:
+			d.Summary = text(d.Summary)
+			if d.Fields != nil {
+				fields := maps.Clone(d.Fields)
+				for k, v := range fields {
+					if s, ok := v.(string); ok {
+						fields[k] = text(s)
+					}
+				}
+				d.Fields = fields
+			}
+			r.Data = d
+		}
+		r.Error = text(r.Error)
+	}
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// BufferPolicy controls what a full session output buffer does.
+type BufferPolicy string
+
+const (
+	// BufferBlock makes the session wait for the consumer. Nothing is
+	// lost, but a stalled consumer stalls the session (it still stops
+	// cleanly on Stop). The default for Events.
+	BufferBlock BufferPolicy = "block"
+
+	// BufferDropOldest discards the oldest buffered item to make room,
+	// counting it as dropped. The default for ReceiveAudio, where late
+	// audio is worse than missing audio.
+	BufferDropOldest BufferPolicy = "drop_oldest"
+)
+
+// Default session buffer sizes.
+const (
+	DefaultAudioBuffer          = 64
+	DefaultEventBuffer          = 256
+	DefaultTranscriptSinkBuffer = 64
+
+	// DefaultInputAudioBuffer is in bytes: about 30 seconds of 16 kHz
+	// PCM.
+	DefaultInputAudioBuffer = 1 << 20
+)
+
+// Buffer is a bounded channel with a full-buffer policy, used by session
+// implementations for ReceiveAudio and Events. Send is safe for concurrent
+// use with Close: once closed, sends are discarded and blocked sends
+// return.
+type Buffer[T any] struct {
+	ch      chan T
+	policy  BufferPolicy
+	done    chan struct{}
+	mu      sync.RWMutex
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// NewBuffer creates a buffer holding size items. A size of zero or less
+// uses 1; an empty policy means BufferBlock.
+func NewBuffer[T any](size int, policy BufferPolicy) *Buffer[T] {
+	if policy == "" {
+		policy = BufferBlock
+	}
+	return &Buffer[T]{
+		ch:     make(chan T, max(size, 1)),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+}
+
+// C returns the receive side of the buffer. It is closed by Close.
+func (b *Buffer[T]) C() <-chan T {
+	return b.ch
+}
+
+// Send delivers v according to the policy, reporting whether it was
+// buffered. Blocking sends give up when ctx ends or the buffer closes;
+// while there is room, v is buffered even if ctx has ended.
+func (b *Buffer[T]) Send(ctx context.Context, v T) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	select {
+	case <-b.done:
+		return false
+	default:
+	}
+
+	if b.policy == BufferDropOldest {
+		for {
+			select {
+			case b.ch <- v:
+				return true
+			default:
+			}
+			select {
+			case <-b.ch:
+				b.dropped.Add(1)
+			default:
+			}
+		}
+	}
+
+	// Try first without waiting: a select with ctx already done would
+	// pick at random between the two.
+	select {
+	case b.ch <- v:
+		return true
+	default:
+	}
+	select {
+	case b.ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-b.done:
+		return false
+	}
+}
+
+// TrySend buffers v only if there is room, without evicting anything.
+func (b *Buffer[T]) TrySend(v T) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	select {
+	case <-b.done:
+		return false
+	default:
+	}
+	select {
+	case b.ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+// Dropped returns the number of items discarded by BufferDropOldest.
+func (b *Buffer[T]) Dropped() int {
+	return int(b.dropped.Load())
+}
+
+// Len returns the number of buffered items.
+func (b *Buffer[T]) Len() int {
+	return len(b.ch)
+}
+
+// Discard removes the buffered items, returning how many. They are not
+// counted as dropped.
+func (b *Buffer[T]) Discard() int {
+	n := 0
+	for {
+		select {
+		case _, ok := <-b.ch:
+			if !ok {
+				return n
+			}
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+// Close releases blocked senders and closes the channel returned by C.
+// Buffered items remain readable. Close is idempotent.
+func (b *Buffer[T]) Close() {
+	b.once.Do(func() {
+		close(b.done)
+		b.mu.Lock()
+		close(b.ch)
+		b.mu.Unlock()
+	})
+}
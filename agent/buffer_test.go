@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBufferSendWithRoomAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := NewBuffer[int](100, BufferBlock)
+	for i := range 100 {
+		if !b.Send(ctx, i) {
+			t.Fatalf("send %d with room dropped after ctx ended", i)
+		}
+	}
+	if b.Send(ctx, 100) {
+		t.Error("send to a full buffer succeeded after ctx ended")
+	}
+}
+
+func TestBufferBlockingSend(t *testing.T) {
+	b := NewBuffer[int](1, BufferBlock)
+	b.Send(context.Background(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if b.Send(ctx, 2) {
+		t.Fatal("send to a full buffer succeeded")
+	}
+
+	sent := make(chan bool)
+	go func() { sent <- b.Send(context.Background(), 3) }()
+	if v := <-b.C(); v != 1 {
+		t.Fatalf("received %d, want 1", v)
+	}
+	if !<-sent {
+		t.Fatal("blocked send not delivered once there was room")
+	}
+
+	go func() { sent <- b.Send(context.Background(), 4) }()
+	time.Sleep(10 * time.Millisecond)
+	b.Close()
+	if <-sent {
+		t.Error("blocked send delivered after Close")
+	}
+	if v, ok := <-b.C(); !ok || v != 3 {
+		t.Errorf("received %d, %v after Close, want the buffered 3", v, ok)
+	}
+}
+
+func TestBufferDropOldest(t *testing.T) {
+	b := NewBuffer[int](2, BufferDropOldest)
+	for i := range 5 {
+		if !b.Send(context.Background(), i) {
+			t.Fatalf("send %d dropped", i)
+		}
+	}
+	if n := b.Dropped(); n != 3 {
+		t.Errorf("Dropped = %d, want 3", n)
+	}
+	if a, c := <-b.C(), <-b.C(); a != 3 || c != 4 {
+		t.Errorf("kept %d, %d, want the newest 3, 4", a, c)
+	}
+}
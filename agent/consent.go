@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ConsentMethod is how recording consent was given.
+type ConsentMethod string
+
+const (
+	// ConsentAnnounced indicates the announcement was played and no
+	// acknowledgment was required.
+	ConsentAnnounced ConsentMethod = "announced"
+
+	// ConsentVoice indicates the caller acknowledged by speaking.
+	ConsentVoice ConsentMethod = "voice"
+
+	// ConsentDTMF indicates the caller acknowledged on the keypad.
+	ConsentDTMF ConsentMethod = "dtmf"
+)
+
+// ConsentAnnouncement is a recording announcement.
+type ConsentAnnouncement struct {
+	// Text is the announcement, e.g. "This call is recorded for quality
+	// purposes." It may contain Metadata placeholders, as Greeting.Text.
+	Text string
+
+	// Audio is optional pre-recorded audio in the session's output
+	// format, played instead of synthesizing Text. Text is still recorded
+	// in the transcript and audit log.
+	Audio []byte
+}
+
+// IsZero reports whether no announcement is configured.
+func (a ConsentAnnouncement) IsZero() bool {
+	return a.Text == "" && len(a.Audio) == 0
+}
+
+// Render fills the announcement's placeholders from metadata.
+func (a ConsentAnnouncement) Render(metadata map[string]string) string {
+	return renderPlaceholders(a.Text, metadata)
+}
+
+// ConsentConfig configures the recording announcement a session plays
+// before the conversation begins (Config.RecordingConsent). Set it for
+// calls placed or answered with recording enabled. The announcement is
+// played uninterruptibly before the greeting, and the session does not
+// answer the user until consent is settled. The outcome is emitted as
+// EventRecordingConsent, which audit logs record.
+type ConsentConfig struct {
+	// Text is the default announcement, e.g. "This call is recorded for
+	// quality purposes." It may contain Metadata placeholders, as
+	// Greeting.Text.
+	Text string
+
+	// Audio is optional pre-recorded audio of the default announcement,
+	// as ConsentAnnouncement.Audio.
+	Audio []byte
+
+	// Jurisdictions overrides the announcement by caller location. A key
+	// starting with "+" matches Metadata["caller_number"] by the longest
+	// E.164 prefix (e.g. "+1", "+44", "+1415"); other keys match
+	// Metadata["caller_region"] exactly (e.g. "US-CA", "DE") and take
+	// precedence over number prefixes.
+	Jurisdictions map[string]ConsentAnnouncement
+
+	// RequireAcknowledgment waits, after the announcement, for the caller
+	// to agree by voice or keypad. The session continues either way; an
+	// application that must not record without consent stops recording
+	// or ends the call on an EventRecordingConsent with Given false.
+	RequireAcknowledgment bool
+
+	// AckPhrases are the words that acknowledge the announcement when
+	// spoken, matched case-insensitively as whole words. Defaults to
+	// DefaultAckPhrases.
+	AckPhrases []string
+
+	// AckDigits are the keypad digits that acknowledge the announcement.
+	// Defaults to "1".
+	AckDigits string
+
+	// AckTimeout bounds the wait for an acknowledgment. Defaults to 10
+	// seconds.
+	AckTimeout time.Duration
+}
+
+// DefaultAckPhrases are the default ConsentConfig AckPhrases.
+var DefaultAckPhrases = []string{"yes", "yeah", "yep", "ok", "okay", "sure", "agree", "i agree", "i consent", "fine"}
+
+// RecordingConsent is the Data of an EventRecordingConsent event.
+type RecordingConsent struct {
+	// Region is the Jurisdictions key whose announcement was played, or
+	// empty for the default.
+	Region string `json:"region,omitempty"`
+
+	// Announcement is the announcement text as played.
+	Announcement string `json:"announcement"`
+
+	// Given reports whether consent was given: always, without
+	// RequireAcknowledgment, once the announcement has played.
+	Given bool `json:"given"`
+
+	// Method is how consent was given. It is empty if it was not.
+	Method ConsentMethod `json:"method,omitempty"`
+
+	// Response is what the caller said or keyed in reply, if anything.
+	Response string `json:"response,omitempty"`
+}
+
+// Announcement returns the announcement for a caller described by
+// metadata and the Jurisdictions key it came from.
+func (c ConsentConfig) Announcement(metadata map[string]string) (string, ConsentAnnouncement) {
+	if region := metadata["caller_region"]; region != "" {
+		if a, ok := c.Jurisdictions[region]; ok {
+			return region, a
+		}
+	}
+	number := metadata["caller_number"]
+	var best string
+	for key := range c.Jurisdictions {
+		if strings.HasPrefix(key, "+") && strings.HasPrefix(number, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best != "" {
+		return best, c.Jurisdictions[best]
+	}
+	return "", ConsentAnnouncement{Text: c.Text, Audio: c.Audio}
+}
+
+// consentRefusals are words that make a spoken reply a refusal even if
+// it contains an acknowledgment, as in "no, I don't agree".
+var consentRefusals = []string{"no", "not", "don't", "dont", "nope", "never"}
+
+// Acknowledges reports whether a spoken reply contains one of AckPhrases
+// and no refusal such as "no" or "not".
+func (c ConsentConfig) Acknowledges(text string) bool {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}), " ") + " "
+	for _, w := range consentRefusals {
+		if strings.Contains(words, " "+w+" ") {
+			return false
+		}
+	}
+	phrases := c.AckPhrases
+	if len(phrases) == 0 {
+		phrases = DefaultAckPhrases
+	}
+	for _, p := range phrases {
+		if p = strings.Join(strings.Fields(strings.ToLower(p)), " "); p != "" && strings.Contains(words, " "+p+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// AcknowledgesDigits reports whether keypad digits include one of
+// AckDigits.
+func (c ConsentConfig) AcknowledgesDigits(digits string) bool {
+	ack := c.AckDigits
+	if ack == "" {
+		ack = "1"
+	}
+	return strings.ContainsAny(digits, ack)
+}
@@ -0,0 +1,41 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/agent/audit"
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+func TestAuditSinkFailureLeavesNoGoroutines(t *testing.T) {
+	errOpen := errors.New("disk full")
+	p := New(stt.NewClient(agenttest.NewScriptedSTT()), tts.NewClient(&agenttest.SilentTTS{}), agenttest.NewScriptedLLM(),
+		WithAudit(func(string, agent.Config) (audit.Sink, error) { return nil, errOpen }))
+	config := agent.Config{
+		EchoCancellation:   &audio.EchoConfig{},
+		AudioLevelInterval: time.Second,
+		TranscriptSink:     func(agent.Turn) {},
+	}
+
+	before := runtime.NumGoroutine()
+	for range 20 {
+		if _, err := p.CreateSession(context.Background(), config); !errors.Is(err, errOpen) {
+			t.Fatalf("CreateSession = %v, want the sink's error", err)
+		}
+	}
+	var after int
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if after = runtime.NumGoroutine(); after <= before {
+			return
+		}
+	}
+	t.Errorf("%d goroutines left behind by sessions whose audit sink failed", after-before)
+}
@@ -0,0 +1,115 @@
+package custom
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+// defaultAckTimeout is the ConsentConfig AckTimeout default.
+const defaultAckTimeout = 10 * time.Second
+
+// consentReply is what the caller said or keyed after the recording
+// announcement.
+type consentReply struct {
+	text   string
+	digits string
+}
+
+// startConsent plays the Config.RecordingConsent announcement, once.
+func (s *Session) startConsent() {
+	s.mu.Lock()
+	if s.consentDone == nil || s.consentStarted {
+		s.mu.Unlock()
+		return
+	}
+	s.consentStarted, s.consenting = true, true
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.recordingConsent()
+	}()
+}
+
+// recordingConsent plays the announcement for the caller's jurisdiction,
+// waits for an acknowledgment if one is required, and emits the outcome.
+func (s *Session) recordingConsent() {
+	defer func() {
+		s.mu.Lock()
+		s.consenting, s.awaitingAck = false, false
+		s.mu.Unlock()
+		close(s.consentDone)
+	}()
+	c := *s.config.RecordingConsent
+	region, a := c.Announcement(s.config.Metadata)
+	if a.IsZero() {
+		return
+	}
+	text := a.Render(s.config.Metadata)
+	r, err := s.queueResponse(s.ctx, true, func(r *response) {
+		if len(a.Audio) == 0 {
+			s.say(r, text)
+			return
+		}
+		if spoken := s.playAudio(r, a.Audio, text); spoken != "" {
+			s.recordAgent(agent.Turn{Role: "agent", Text: spoken, Timestamp: time.Now()})
+		}
+	})
+	if err != nil {
+		return
+	}
+	<-r.done
+	if s.stopping.Load() {
+		return
+	}
+
+	consent := agent.RecordingConsent{Region: region, Announcement: text}
+	if !c.RequireAcknowledgment {
+		consent.Given, consent.Method = true, agent.ConsentAnnounced
+		s.emit(agent.EventRecordingConsent, consent, nil)
+		return
+	}
+	// Only what the caller says once the announcement has ended answers it.
+	s.mu.Lock()
+	s.awaitingAck = true
+	s.mu.Unlock()
+	timer := time.NewTimer(cmp.Or(c.AckTimeout, defaultAckTimeout))
+	defer timer.Stop()
+	select {
+	case reply := <-s.consentReplies:
+		switch {
+		case reply.digits != "":
+			consent.Response = reply.digits
+			if c.AcknowledgesDigits(reply.digits) {
+				consent.Given, consent.Method = true, agent.ConsentDTMF
+			}
+		default:
+			consent.Response = reply.text
+			if c.Acknowledges(reply.text) {
+				consent.Given, consent.Method = true, agent.ConsentVoice
+			}
+		}
+	case <-timer.C:
+	case <-s.done:
+		return
+	}
+	s.emit(agent.EventRecordingConsent, consent, nil)
+}
+
+// consentInput takes user input while consent is being settled,
+// reporting whether it did. Input during the announcement is ignored;
+// the first input after it is the acknowledgment, if one is awaited.
+func (s *Session) consentInput(reply consentReply) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.consenting {
+		return false
+	}
+	if s.awaitingAck {
+		s.awaitingAck = false
+		s.consentReplies <- reply
+	}
+	return true
+}
@@ -0,0 +1,315 @@
+// Package custom implements agent.Provider with a local pipeline: STT
+// transcribes caller audio, an agent.LLM generates the reply, and TTS
+// speaks it. LLM output is synthesized clause by clause as it streams, so
+// the agent starts talking before the model finishes.
+package custom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/audit"
+	"github.com/agentplexus/omnivoice/agent/webhook"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// stopEventTimeout bounds how long Stop waits for a stalled consumer to
+// accept the session-ended event.
+const stopEventTimeout = time.Second
+
+// sttDrainTimeout bounds how long Stop waits for STT to deliver its final
+// transcripts once the audio stream is closed.
+const sttDrainTimeout = 5 * time.Second
+
+// auditFlushTimeout bounds how long Stop waits for the audit log to be
+// written, even when its ctx has ended.
+const auditFlushTimeout = 10 * time.Second
+
+var (
+	// ErrNotStarted is returned when audio is sent before Start.
+	ErrNotStarted = errors.New("custom: session not started")
+
+	// ErrSessionClosed is returned when using a stopped session.
+	ErrSessionClosed = errors.New("custom: session closed")
+
+	// ErrTextOnly is returned when audio is sent to a TextOnly session
+	// or to a session of a provider without STT.
+	ErrTextOnly = errors.New("custom: session takes no audio")
+
+	// ErrNotPushToTalk is returned by StartListening and StopListening
+	// for sessions without agent.Config.PushToTalk.
+	ErrNotPushToTalk = errors.New("custom: session is not push-to-talk")
+
+	// ErrPaused is returned when sending input to a paused session.
+	ErrPaused = errors.New("custom: session paused")
+
+	// ErrNoTTS is returned when creating a session that speaks with a
+	// provider without TTS.
+	ErrNoTTS = errors.New("custom: provider has no TTS; sessions must be TextOnly")
+)
+
+// Option configures a Provider.
+type Option func(*options)
+
+type options struct {
+	sampleRate    int
+	transcription stt.TranscriptionConfig
+	synthesis     tts.SynthesisConfig
+	maxToolRounds int
+	webhooks      []webhook.Option
+	auditSink     AuditSinkFunc
+	audit         []audit.Option
+	logger        *slog.Logger
+	maxSessions   int
+	waitSession   bool
+	workers       int
+}
+
+// WithSampleRate sets the default sample rate of session audio, used when
+// agent.Config.SampleRate is zero. Defaults to 16000.
+func WithSampleRate(rate int) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithTranscriptionConfig sets the base STT configuration. Channels is
+// always set from the session audio format, and Encoding to PCM; any other
+// Encoding makes CreateSession fail with agent.ErrAudioFormat. A SampleRate
+// other than the session's makes sessions resample caller audio to it;
+// zero uses the session rate.
+func WithTranscriptionConfig(config stt.TranscriptionConfig) Option {
+	return func(o *options) {
+		o.transcription = config
+	}
+}
+
+// WithSynthesisConfig sets the base TTS configuration. OutputFormat is
+// always PCM; any other OutputFormat makes CreateSession fail with
+// agent.ErrAudioFormat. A SampleRate other than the session's makes sessions request
+// speech at that rate and resample it to the session rate; zero uses the
+// session rate.
+func WithSynthesisConfig(config tts.SynthesisConfig) Option {
+	return func(o *options) {
+		o.synthesis = config
+	}
+}
+
+// WithLogger sets the logger for session diagnostics, such as inserted
+// resampling. Defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMaxToolRounds limits consecutive LLM calls driven by tool results
+// within one turn. Defaults to 5.
+func WithMaxToolRounds(n int) Option {
+	return func(o *options) {
+		o.maxToolRounds = n
+	}
+}
+
+// WithWebhookOptions configures delivery of the webhooks in
+// agent.Config.Webhooks, e.g. with webhook.WithHTTPClient or
+// webhook.WithDeadLetter.
+func WithWebhookOptions(opts ...webhook.Option) Option {
+	return func(o *options) {
+		o.webhooks = append(o.webhooks, opts...)
+	}
+}
+
+// WithMaxConcurrentSessions limits the provider to n sessions at once,
+// counting suspended sessions until they expire. CreateSession then fails
+// with agent.ErrTooManySessions, unless WithSessionWait is set. Zero, the
+// default, means no limit.
+func WithMaxConcurrentSessions(n int) Option {
+	return func(o *options) {
+		o.maxSessions = n
+	}
+}
+
+// WithSessionWait makes CreateSession wait for a session to end when at
+// the WithMaxConcurrentSessions limit, rather than failing at once. It
+// fails with agent.ErrTooManySessions if its ctx ends first, so a ctx
+// deadline bounds the wait.
+func WithSessionWait() Option {
+	return func(o *options) {
+		o.waitSession = true
+	}
+}
+
+// WithWorkerPool sets how many goroutines the provider runs replies on,
+// shared by all of its sessions. Reusing them spares a busy provider from
+// starting a goroutine per turn, and the size bounds how many replies run
+// at once: past it, a new reply waits for one to finish, holding up the
+// call that started it (e.g. SendText). Zero starts a goroutine per
+// reply. Defaults to DefaultWorkerPool.
+func WithWorkerPool(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// AuditSinkFunc opens the audit log sink for a new session.
+type AuditSinkFunc func(sessionID string, config agent.Config) (audit.Sink, error)
+
+// WithAudit records every session event to an audit log in the sink
+// opened by sink, configured by opts (e.g. audit.WithRedactor). Stop
+// flushes the log. A session whose sink cannot be opened is not created.
+func WithAudit(sink AuditSinkFunc, opts ...audit.Option) Option {
+	return func(o *options) {
+		o.auditSink = sink
+		o.audit = append(o.audit, opts...)
+	}
+}
+
+// Provider creates sessions backed by STT, LLM, and TTS clients.
+type Provider struct {
+	stt      *stt.Client
+	tts      *tts.Client
+	llm      agent.LLM
+	opts     options
+	registry *agent.SessionRegistry
+	pool     *workerPool
+}
+
+// New creates a custom agent provider. A nil sttClient makes sessions
+// take input only from SendText, with replies still spoken; a nil
+// ttsClient requires Config.TextOnly sessions.
+func New(sttClient *stt.Client, ttsClient *tts.Client, llm agent.LLM, opts ...Option) *Provider {
+	o := options{sampleRate: 16000, maxToolRounds: 5, workers: DefaultWorkerPool}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = slog.Default()
+	}
+	registry := agent.NewSessionRegistry(0)
+	registry.SetMaxSessions(o.maxSessions)
+	return &Provider{
+		stt:      sttClient,
+		tts:      ttsClient,
+		llm:      llm,
+		opts:     o,
+		registry: registry,
+		pool:     newWorkerPool(o.workers),
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "custom"
+}
+
+// CreateSession creates a session. Tools are validated with
+// agent.ValidateTool, and Config.Summary with its Validate method;
+// Config.AudioEncoding must be a raw encoding (see
+// agent.RawEncoding; agent.MatchTransport sets it from a transport), and
+// the session sample rate must be convertible to the configured STT and
+// TTS rates (see audio.NewResampler). A tenant set on ctx with
+// credentials.WithTenant applies to all of the session's STT and TTS
+// requests. A SystemPrompt template is rendered here, with
+// agent.RenderSystemPrompt. At the WithMaxConcurrentSessions limit it
+// fails with agent.ErrTooManySessions, or waits as WithSessionWait
+// describes.
+func (p *Provider) CreateSession(ctx context.Context, config agent.Config) (agent.Session, error) {
+	for _, tool := range config.Tools {
+		if err := agent.ValidateTool(tool); err != nil {
+			return nil, err
+		}
+	}
+	if config.Summary != nil {
+		if err := config.Summary.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	prompt, err := agent.RenderSystemPrompt(config, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	config.SystemPrompt = prompt
+	release, err := p.registry.Reserve(ctx, p.opts.waitSession)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSession(ctx, p, newID(), config)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	p.registry.Add(s)
+	if err := p.registry.SetGracePeriod(s.id, config.ResumeGracePeriod); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetSession retrieves an existing session by ID.
+func (p *Provider) GetSession(ctx context.Context, sessionID string) (agent.Session, error) {
+	return p.registry.Get(sessionID)
+}
+
+// ListSessions lists active and suspended sessions.
+func (p *Provider) ListSessions(ctx context.Context) ([]string, error) {
+	return p.registry.List(), nil
+}
+
+// ResumeToken returns the token a reconnecting client passes to
+// ResumeSession.
+func (p *Provider) ResumeToken(sessionID string) (string, error) {
+	return p.registry.Token(sessionID)
+}
+
+// Suspend reports that a session's transport was lost. The session is
+// kept for its Config.ResumeGracePeriod, then stopped.
+func (p *Provider) Suspend(sessionID string) error {
+	return p.registry.Suspend(sessionID)
+}
+
+// ResumeSession reattaches a suspended session.
+func (p *Provider) ResumeSession(ctx context.Context, token string) (agent.Session, error) {
+	return p.registry.Resume(ctx, token)
+}
+
+// Warmup warms the STT and TTS providers' connections (see
+// stt.Client.WarmupAll and tts.Client.WarmupAll), so the first session's
+// first turn does not wait for TLS and authentication. Call it ahead of
+// traffic, e.g. as a call rings; Close releases what is still unused.
+// Sessions fall back to cold connections if it fails.
+func (p *Provider) Warmup(ctx context.Context) error {
+	var errs []error
+	if p.stt != nil {
+		errs = append(errs, p.stt.WarmupAll(ctx, stt.WarmupConfig{}))
+	}
+	if p.tts != nil {
+		errs = append(errs, p.tts.WarmupAll(ctx, tts.WarmupConfig{}))
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops every session and releases warm provider connections.
+func (p *Provider) Close() error {
+	p.registry.Close()
+	var errs []error
+	if p.stt != nil {
+		errs = append(errs, p.stt.ReleaseWarm())
+	}
+	if p.tts != nil {
+		errs = append(errs, p.tts.ReleaseWarm())
+	}
+	return errors.Join(errs...)
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
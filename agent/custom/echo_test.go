@@ -0,0 +1,53 @@
+package custom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+func TestEchoReferenceFollowsPlayback(t *testing.T) {
+	p := New(stt.NewClient(agenttest.NewScriptedSTT()), tts.NewClient(&agenttest.SilentTTS{}), agenttest.NewScriptedLLM())
+	session, err := p.CreateSession(context.Background(), agent.Config{
+		FirstSpeaker:     agent.FirstSpeakerAgent,
+		Greeting:         agent.Greeting{Text: "hello there, how can I help"},
+		AudioBuffer:      4,
+		EchoCancellation: &audio.EchoConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := session.(*Session)
+	defer s.Stop(context.Background())
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unread speech fills the buffer, with older chunks dropped.
+	deadline := time.Now().Add(2 * time.Second)
+	for s.audio.Dropped() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("audio buffer never overflowed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := s.echo.Queued(); n != 0 {
+		t.Fatalf("referenced %d samples before playback", n)
+	}
+
+	chunk := <-s.ReceiveAudio()
+	want := len(chunk) / audio.BytesPerSample
+	for deadline := time.Now().Add(time.Second); s.echo.Queued() < want && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if n := s.echo.Queued(); n != want {
+		t.Errorf("referenced %d samples after one chunk, want %d", n, want)
+	}
+	s.ReleaseAudio(chunk)
+}
@@ -0,0 +1,139 @@
+package custom
+
+import (
+	"sync"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// inputQueue holds caller audio, already converted for STT, until the
+// transcription stream takes it. It is bounded in bytes by
+// Config.InputAudioBuffer, so a client sending faster than STT consumes
+// cannot grow it without limit.
+type inputQueue struct {
+	max    int
+	policy agent.BufferPolicy
+
+	mu     sync.Mutex
+	frames [][]byte
+	// size counts queued bytes plus the frame being written, so a wait
+	// for the queue to drain also waits for that write.
+	size      int
+	highWater int
+	dropped   int
+	// full is set once an overflow is reported, until the queue drains.
+	full bool
+	// ready wakes the writer; taken is closed and replaced whenever a
+	// write finishes.
+	ready chan struct{}
+	taken chan struct{}
+}
+
+func newInputQueue(max int, policy agent.BufferPolicy) *inputQueue {
+	return &inputQueue{
+		max:    max,
+		policy: policy,
+		ready:  make(chan struct{}, 1),
+		taken:  make(chan struct{}),
+	}
+}
+
+// push queues a frame. When the queue is full it drops the oldest
+// frames under BufferDropOldest, or else calls overflow, the first time
+// only, and waits for room. It reports false if done closed first.
+func (q *inputQueue) push(frame []byte, done <-chan struct{}, overflow func()) bool {
+	for {
+		q.mu.Lock()
+		drop := q.policy == agent.BufferDropOldest
+		for drop && len(q.frames) > 0 && q.size+len(frame) > q.max {
+			q.size -= len(q.frames[0])
+			q.dropped += len(q.frames[0])
+			audio.PutBuffer(q.frames[0])
+			q.frames = q.frames[1:]
+		}
+		// A frame larger than the buffer, or than the room left beside the
+		// one being written, still goes in rather than waiting forever.
+		if q.size == 0 || q.size+len(frame) <= q.max || drop {
+			q.frames = append(q.frames, frame)
+			q.size += len(frame)
+			q.highWater = max(q.highWater, q.size)
+			q.mu.Unlock()
+			select {
+			case q.ready <- struct{}{}:
+			default:
+			}
+			return true
+		}
+		report := !q.full
+		q.full = true
+		taken := q.taken
+		q.mu.Unlock()
+		if report {
+			overflow()
+		}
+		select {
+		case <-taken:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// pop waits for the next frame, reporting false if done closed first.
+// The caller calls wrote once it has written the frame.
+func (q *inputQueue) pop(done <-chan struct{}) ([]byte, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.frames) > 0 {
+			frame := q.frames[0]
+			q.frames = q.frames[1:]
+			q.mu.Unlock()
+			return frame, true
+		}
+		q.mu.Unlock()
+		select {
+		case <-q.ready:
+		case <-done:
+			return nil, false
+		}
+	}
+}
+
+// wrote releases the room of a frame returned by pop.
+func (q *inputQueue) wrote(frame []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.size -= len(frame)
+	if q.size == 0 {
+		q.full = false
+	}
+	close(q.taken)
+	q.taken = make(chan struct{})
+}
+
+// wait waits until every queued frame is written, reporting false if
+// done closed first.
+func (q *inputQueue) wait(done <-chan struct{}) bool {
+	for {
+		q.mu.Lock()
+		if q.size == 0 {
+			q.mu.Unlock()
+			return true
+		}
+		taken := q.taken
+		q.mu.Unlock()
+		select {
+		case <-taken:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// stats returns the most bytes queued at once and the bytes dropped.
+func (q *inputQueue) stats() (highWater, dropped int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.highWater, q.dropped
+}
@@ -0,0 +1,12 @@
+package custom
+
+import "github.com/agentplexus/omnivoice/agent"
+
+// The optional session interfaces Session implements.
+var (
+	_ agent.DTMFSender     = (*Session)(nil)
+	_ agent.AnswerReporter = (*Session)(nil)
+	_ agent.Styler         = (*Session)(nil)
+	_ agent.AudioFlusher   = (*Session)(nil)
+	_ agent.Pauser         = (*Session)(nil)
+)
@@ -0,0 +1,48 @@
+package custom
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// sayOver sends a second of caller audio while the agent speaks, for the
+// scripted STT to hear utterance in, and returns the session's events.
+func sayOver(t *testing.T, utterance agenttest.Utterance) []agent.EventType {
+	t.Helper()
+	s, events := speakingSession(t, agenttest.NewScriptedSTT(utterance), agent.Config{Interruption: &agent.InterruptionConfig{}})
+	frame := make([]byte, audio.BytesPerSecond(s.rate, 1)/50)
+	for range 50 {
+		if err := s.SendAudio(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return events()
+}
+
+func TestNoiseBurstDoesNotInterrupt(t *testing.T) {
+	for _, u := range []agenttest.Utterance{
+		{Start: 200 * time.Millisecond, End: 320 * time.Millisecond, Text: "uh"},
+		{Start: 200 * time.Millisecond, End: 800 * time.Millisecond, Text: "mhm, okay"},
+	} {
+		if seen := sayOver(t, u); slices.Contains(seen, agent.EventInterruption) {
+			t.Errorf("%q over %v interrupted the agent", u.Text, u.End-u.Start)
+		}
+	}
+}
+
+func TestGenuineInterruption(t *testing.T) {
+	seen := sayOver(t, agenttest.Utterance{Start: 200 * time.Millisecond, End: 900 * time.Millisecond, Text: "wait, that's the wrong address"})
+	if !slices.Contains(seen, agent.EventInterruption) {
+		t.Error("genuine interruption did not interrupt the agent")
+	}
+}
@@ -0,0 +1,81 @@
+package custom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// lingeringTTS sends audio, and the final chunk if final is set, then
+// keeps the stream open until it is canceled.
+type lingeringTTS struct {
+	agenttest.SilentTTS
+	final bool
+}
+
+func (p *lingeringTTS) SynthesizeStream(ctx context.Context, text string, config tts.SynthesisConfig) (<-chan tts.StreamChunk, error) {
+	ch := make(chan tts.StreamChunk, 1)
+	ch <- tts.StreamChunk{Audio: make([]byte, 320), IsFinal: p.final}
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func TestTurnLimit(t *testing.T) {
+	for _, final := range []bool{true, false} {
+		p := New(stt.NewClient(agenttest.NewScriptedSTT()), tts.NewClient(&lingeringTTS{final: final}),
+			agenttest.NewScriptedLLM(agenttest.Response{Text: "Hello."}))
+		session, err := p.CreateSession(context.Background(), agent.Config{MaxTurnDuration: 100 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := session.(*Session)
+		t.Cleanup(func() { _ = s.Stop(context.Background()) })
+		go func() {
+			for chunk := range s.ReceiveAudio() {
+				s.ReleaseAudio(chunk)
+			}
+		}()
+		events := make(chan agent.Event, 64)
+		go func() {
+			for ev := range s.Events() {
+				events <- ev
+			}
+		}()
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SendText("hi"); err != nil {
+			t.Fatal(err)
+		}
+
+		// A reply whose final chunk was sent in time is delivered, even
+		// though its stream outlives the limit; one without is cut.
+		answered, limited := false, false
+		timeout := time.After(time.Second)
+	wait:
+		for {
+			select {
+			case ev := <-events:
+				switch ev.Type {
+				case agent.EventAgentTranscript:
+					answered = true
+				case agent.EventLimitReached:
+					limited = ev.Data.(agent.LimitEvent).Limit == agent.LimitTurnDuration
+				}
+			case <-timeout:
+				break wait
+			}
+		}
+		if answered != final || limited == final {
+			t.Errorf("final chunk %v: reply recorded %v, turn limit reached %v", final, answered, limited)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+func TestResumeDoesNotUnpause(t *testing.T) {
+	s, _ := textTurn(t, agent.Config{ResumeGreeting: agent.Greeting{Text: "Thanks for holding."}}, "Hold on.", "Sure.")
+	ctx := context.Background()
+	if err := s.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendText("Are you there?"); !errors.Is(err, ErrPaused) {
+		t.Errorf("SendText after Resume of a paused session = %v, want ErrPaused", err)
+	}
+	if err := s.Unpause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendText("Are you there?"); err != nil {
+		t.Errorf("SendText after Unpause = %v", err)
+	}
+}
+
+func TestUnpauseDoesNotResume(t *testing.T) {
+	s, _ := textTurn(t, agent.Config{}, "Hold on.", "Sure.")
+	ctx := context.Background()
+	if err := s.Suspend(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unpause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.Snapshot().SuspendedAt.IsZero() {
+		t.Error("Unpause reattached a suspended session")
+	}
+	if err := s.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Snapshot().SuspendedAt.IsZero() {
+		t.Error("Resume left the session suspended")
+	}
+}
@@ -0,0 +1,85 @@
+package custom
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWorkerPool is the number of workers a Provider runs replies on
+// when WithWorkerPool is not set.
+const DefaultWorkerPool = 1024
+
+// workerIdleTimeout is how long an idle worker waits for a task before
+// exiting, so the pool shrinks after a burst of calls.
+const workerIdleTimeout = 30 * time.Second
+
+// workerPool runs the replies of every session of a Provider on at most
+// size reused goroutines, so a busy provider neither starts and grows the
+// stack of a fresh goroutine for each nor runs more at once than it was
+// sized for: run waits for a worker to be free. Tasks a running task
+// waits on, such as a reply's speech, are started with spawn instead,
+// which never waits, so they cannot queue behind their own parent. A nil
+// pool starts a goroutine per task.
+type workerPool struct {
+	// slots holds a token per worker, running or idle.
+	slots chan struct{}
+	tasks chan func()
+}
+
+func newWorkerPool(size int) *workerPool {
+	if size <= 0 {
+		return nil
+	}
+	return &workerPool{slots: make(chan struct{}, size), tasks: make(chan func())}
+}
+
+// run runs task on an idle worker, or a new one while fewer than size
+// are running, waiting for one to be free otherwise. It reports false,
+// without running task, if ctx ends first.
+func (p *workerPool) run(ctx context.Context, task func()) bool {
+	if p == nil {
+		go task()
+		return true
+	}
+	select {
+	case p.tasks <- task:
+		return true
+	default:
+	}
+	select {
+	case p.tasks <- task:
+	case p.slots <- struct{}{}:
+		go p.work(task)
+	case <-ctx.Done():
+		return false
+	}
+	return true
+}
+
+// spawn runs task on an idle worker if there is one, else on a goroutine
+// of its own outside the pool.
+func (p *workerPool) spawn(task func()) {
+	if p != nil {
+		select {
+		case p.tasks <- task:
+			return
+		default:
+		}
+	}
+	go task()
+}
+
+func (p *workerPool) work(task func()) {
+	timer := time.NewTimer(workerIdleTimeout)
+	defer timer.Stop()
+	for {
+		task()
+		timer.Reset(workerIdleTimeout)
+		select {
+		case task = <-p.tasks:
+		case <-timer.C:
+			<-p.slots
+			return
+		}
+	}
+}
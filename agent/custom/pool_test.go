@@ -0,0 +1,133 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+func TestWorkerPoolBoundsConcurrency(t *testing.T) {
+	p := newWorkerPool(2)
+	release := make(chan struct{})
+	var running, most atomic.Int32
+	task := func() {
+		n := running.Add(1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		<-release
+		running.Add(-1)
+	}
+	for range 2 {
+		if !p.run(context.Background(), task) {
+			t.Fatal("run failed with free workers")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if p.run(ctx, task) {
+		t.Fatal("run did not wait for a free worker")
+	}
+
+	done := make(chan bool)
+	go func() { done <- p.run(context.Background(), task) }()
+	release <- struct{}{}
+	if !<-done {
+		t.Fatal("run failed once a worker was free")
+	}
+	close(release)
+	if n := most.Load(); n > 2 {
+		t.Errorf("%d tasks ran at once on a pool of 2", n)
+	}
+}
+
+func TestWorkerPoolSpawnDoesNotWait(t *testing.T) {
+	p := newWorkerPool(1)
+	release := make(chan struct{})
+	defer close(release)
+	p.run(context.Background(), func() { <-release })
+
+	ran := make(chan struct{})
+	p.spawn(func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("spawn waited for the busy pool")
+	}
+}
+
+// repeatLLM replies with the same text to every turn.
+type repeatLLM string
+
+func (l repeatLLM) Stream(ctx context.Context, messages []agent.Message, tools []agent.Tool) (<-chan agent.LLMChunk, error) {
+	ch := make(chan agent.LLMChunk, 1)
+	ch <- agent.LLMChunk{Text: string(l)}
+	close(ch)
+	return ch, nil
+}
+
+// BenchmarkConcurrentSessions measures spoken turns across 500 sessions
+// of one provider, each op being one turn in every session, with replies
+// on the worker pool and on a goroutine each.
+func BenchmarkConcurrentSessions(b *testing.B) {
+	const sessions = 500
+	for _, workers := range []int{DefaultWorkerPool, 0} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			p := New(stt.NewClient(agenttest.NewScriptedSTT()),
+				tts.NewClient(&agenttest.SilentTTS{WordDuration: 20 * time.Millisecond}),
+				repeatLLM("Sure, one moment."), WithWorkerPool(workers))
+			turns := make([]chan struct{}, sessions)
+			all := make([]*Session, sessions)
+			for i := range all {
+				session, err := p.CreateSession(context.Background(), agent.Config{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				s := session.(*Session)
+				if err := s.Start(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+				all[i], turns[i] = s, make(chan struct{}, 1)
+				go func() {
+					for chunk := range s.ReceiveAudio() {
+						s.ReleaseAudio(chunk)
+					}
+				}()
+				go func(turn chan<- struct{}) {
+					for ev := range s.Events() {
+						if ev.Type == agent.EventAgentTranscript {
+							turn <- struct{}{}
+						}
+					}
+				}(turns[i])
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for b.Loop() {
+				var wg sync.WaitGroup
+				for i, s := range all {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_ = s.SendText("what are your hours")
+						<-turns[i]
+					}()
+				}
+				wg.Wait()
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*sessions), "ns/turn")
+			b.StopTimer()
+			for _, s := range all {
+				_ = s.Stop(context.Background())
+			}
+		})
+	}
+}
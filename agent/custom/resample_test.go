@@ -0,0 +1,191 @@
+package custom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// toneSamples returns seconds of a 440 Hz tone at rate.
+func toneSamples(rate int, seconds float64) []int16 {
+	samples := make([]int16, int(float64(rate)*seconds))
+	for i := range samples {
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+	}
+	return samples
+}
+
+// oddChunkTTS streams pcm for any text in chunks of an odd number of
+// bytes, splitting samples between chunks.
+type oddChunkTTS struct {
+	agenttest.SilentTTS
+	pcm []byte
+}
+
+func (p *oddChunkTTS) SynthesizeStream(ctx context.Context, text string, config tts.SynthesisConfig) (<-chan tts.StreamChunk, error) {
+	ch := make(chan tts.StreamChunk)
+	go func() {
+		defer close(ch)
+		for rest := p.pcm; len(rest) > 0; {
+			n := min(333, len(rest))
+			select {
+			case ch <- tts.StreamChunk{Audio: rest[:n]}:
+			case <-ctx.Done():
+				return
+			}
+			rest = rest[n:]
+		}
+		ch <- tts.StreamChunk{IsFinal: true}
+	}()
+	return ch, nil
+}
+
+func TestSpeechResampledAcrossOddChunks(t *testing.T) {
+	src := toneSamples(24000, 0.3)
+	want, err := audio.Resample(src, 24000, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(stt.NewClient(agenttest.NewScriptedSTT()), tts.NewClient(&oddChunkTTS{pcm: audio.Int16ToBytes(src)}),
+		agenttest.NewScriptedLLM(agenttest.Response{Text: "Hello."}),
+		WithSynthesisConfig(tts.SynthesisConfig{SampleRate: 24000}))
+	session, err := p.CreateSession(context.Background(), agent.Config{SampleRate: 16000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := session.(*Session)
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+	var mu sync.Mutex
+	var got []byte
+	go func() {
+		for chunk := range s.ReceiveAudio() {
+			mu.Lock()
+			got = append(got, chunk...)
+			mu.Unlock()
+			s.ReleaseAudio(chunk)
+		}
+	}()
+	go func() {
+		for range s.Events() {
+		}
+	}()
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendText("hi"); err != nil {
+		t.Fatal(err)
+	}
+	wantBytes := audio.Int16ToBytes(want)
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n >= len(wantBytes) {
+			break
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !bytes.Equal(got, wantBytes) {
+		t.Errorf("received %d bytes of speech differing from the %d of the reply resampled at once", len(got), len(wantBytes))
+	}
+}
+
+// speechEndSTT is a streaming STT provider recording the audio written
+// to it, which reports the end of speech once after end bytes.
+type speechEndSTT struct {
+	agenttest.ScriptedSTT
+	end int
+
+	mu    sync.Mutex
+	heard []byte
+}
+
+func (p *speechEndSTT) TranscribeStream(ctx context.Context, config stt.TranscriptionConfig) (io.WriteCloser, <-chan stt.StreamEvent, error) {
+	w := &speechEndStream{p: p, events: make(chan stt.StreamEvent, 1)}
+	return w, w.events, nil
+}
+
+type speechEndStream struct {
+	p      *speechEndSTT
+	events chan stt.StreamEvent
+	ended  bool
+	once   sync.Once
+}
+
+func (w *speechEndStream) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+	w.p.heard = append(w.p.heard, b...)
+	if !w.ended && len(w.p.heard) >= w.p.end {
+		w.ended = true
+		w.events <- stt.StreamEvent{Type: stt.EventSpeechEnd, SpeechEnded: true}
+	}
+	return len(b), nil
+}
+
+func (w *speechEndStream) Close() error {
+	w.once.Do(func() { close(w.events) })
+	return nil
+}
+
+func TestSpeechEndKeepsResamplerHistory(t *testing.T) {
+	src := toneSamples(8000, 1)
+	want, err := audio.Resample(src, 8000, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// End speech once most of the first half has been heard.
+	provider := &speechEndSTT{end: len(want) - 100}
+	p := New(stt.NewClient(provider), tts.NewClient(&agenttest.SilentTTS{}), agenttest.NewScriptedLLM(),
+		WithTranscriptionConfig(stt.TranscriptionConfig{SampleRate: 16000}))
+	session, err := p.CreateSession(context.Background(), agent.Config{SampleRate: 8000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := session.(*Session)
+	ended := make(chan struct{})
+	go func() {
+		for ev := range s.Events() {
+			if ev.Type == agent.EventUserSpeechEnd {
+				close(ended)
+			}
+		}
+	}()
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pcm := audio.Int16ToBytes(src)
+	half := len(pcm) / 2
+	if err := s.SendAudio(pcm[:half]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ended:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no end of speech")
+	}
+	// Let the flush at the end of speech run before more audio arrives.
+	time.Sleep(50 * time.Millisecond)
+	if err := s.SendAudio(pcm[half:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if !bytes.Equal(provider.heard, audio.Int16ToBytes(want)) {
+		t.Errorf("STT heard %d bytes differing from the %d of the audio resampled at once", len(provider.heard), 2*len(want))
+	}
+}
@@ -0,0 +1,67 @@
+package custom
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// BenchmarkSendAudio measures caller audio across 500 telephony sessions
+// of one provider, each op being a 20ms mu-law frame at 8 kHz sent to
+// every session and resampled to 16 kHz for STT. gc/op counts garbage
+// collections.
+func BenchmarkSendAudio(b *testing.B) {
+	const sessions = 500
+	p := New(stt.NewClient(agenttest.NewScriptedSTT()),
+		tts.NewClient(&agenttest.SilentTTS{WordDuration: 20 * time.Millisecond}),
+		repeatLLM("Sure."), WithTranscriptionConfig(stt.TranscriptionConfig{SampleRate: 16000}),
+		WithLogger(slog.New(slog.DiscardHandler)))
+	all := make([]*Session, sessions)
+	for i := range all {
+		session, err := p.CreateSession(context.Background(), agent.Config{AudioEncoding: audio.EncodingMuLaw, SampleRate: 8000})
+		if err != nil {
+			b.Fatal(err)
+		}
+		s := session.(*Session)
+		if err := s.Start(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+		go func() {
+			for range s.Events() {
+			}
+		}()
+		all[i] = s
+	}
+	pcm := make([]int16, 160)
+	for i := range pcm {
+		pcm[i] = int16(3000 * math.Sin(2*math.Pi*440*float64(i)/8000))
+	}
+	frame := audio.EncodeMuLaw(audio.Int16ToBytes(pcm))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame) * sessions))
+	for b.Loop() {
+		for _, s := range all {
+			if err := s.SendAudio(frame); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
+	b.StopTimer()
+	for _, s := range all {
+		_ = s.Stop(context.Background())
+	}
+}
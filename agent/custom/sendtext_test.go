@@ -0,0 +1,96 @@
+package custom
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// textTurn sends text to a new session and returns it once the reply
+// is recorded, with a count of the audio bytes it delivers.
+func textTurn(t *testing.T, config agent.Config, text, reply string) (*Session, *atomic.Int64) {
+	t.Helper()
+	p := New(stt.NewClient(agenttest.NewScriptedSTT()), tts.NewClient(&agenttest.SilentTTS{}), agenttest.NewScriptedLLM(agenttest.Response{Text: reply}))
+	session, err := p.CreateSession(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := session.(*Session)
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+	received := new(atomic.Int64)
+	go func() {
+		for chunk := range s.ReceiveAudio() {
+			received.Add(int64(len(chunk)))
+			s.ReleaseAudio(chunk)
+		}
+	}()
+	answered := make(chan struct{})
+	go func() {
+		for ev := range s.Events() {
+			if ev.Type == agent.EventAgentTranscript {
+				close(answered)
+				break
+			}
+		}
+		for range s.Events() {
+		}
+	}()
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendText(text); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-answered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reply to SendText")
+	}
+	return s, received
+}
+
+func checkTranscript(t *testing.T, s *Session, user, reply string) {
+	t.Helper()
+	turns := s.Transcript()
+	if len(turns) != 2 || turns[0].Role != "user" || turns[0].Text != user || turns[1].Role != "agent" || turns[1].Text != reply {
+		t.Fatalf("transcript %+v, want the user turn then the reply", turns)
+	}
+	m := s.Metrics()
+	if m.TurnCount != 2 {
+		t.Errorf("TurnCount %d, want 2", m.TurnCount)
+	}
+	if m.AvgSTTLatencyMs != 0 || m.UserSpeechDurationMs != 0 {
+		t.Errorf("text input counted STT latency %dms and user speech %dms", m.AvgSTTLatencyMs, m.UserSpeechDurationMs)
+	}
+}
+
+func TestSendTextSpeaksReply(t *testing.T) {
+	s, received := textTurn(t, agent.Config{}, "When do you open?", "We open at nine.")
+	checkTranscript(t, s, "When do you open?", "We open at nine.")
+	for deadline := time.Now().Add(time.Second); received.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if received.Load() == 0 {
+		t.Error("reply delivered no audio")
+	}
+	if m := s.Metrics(); m.AgentSpeechDurationMs == 0 {
+		t.Error("reply counted no agent speech")
+	}
+}
+
+func TestSendTextTextOnly(t *testing.T) {
+	s, received := textTurn(t, agent.Config{TextOnly: true}, "When do you open?", "We open at nine.")
+	checkTranscript(t, s, "When do you open?", "We open at nine.")
+	if n := received.Load(); n != 0 {
+		t.Errorf("text-only session delivered %d bytes of audio", n)
+	}
+	if m := s.Metrics(); m.AgentSpeechDurationMs != 0 {
+		t.Errorf("text-only reply counted %dms of agent speech", m.AgentSpeechDurationMs)
+	}
+}
@@ -0,0 +1,2541 @@
+package custom
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/audit"
+	"github.com/agentplexus/omnivoice/agent/webhook"
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/credentials"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/transport"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// Session is a voice conversation implementing agent.ResumableSession.
+type Session struct {
+	id       string
+	p        *Provider
+	config   agent.Config
+	rate     int
+	encoding string
+
+	events *agent.Buffer[agent.Event]
+	audio  *agent.Buffer[[]byte]
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// clock is the session clock, set by Start.
+	clock atomic.Pointer[agent.SessionClock]
+
+	mu      sync.Mutex
+	started time.Time
+	// sttStart is the session time the current STT stream started at.
+	sttStart    time.Duration
+	sttIn       io.WriteCloser
+	sttCancel   context.CancelFunc
+	sttDone     chan struct{}
+	transcript  []agent.Turn
+	history     []agent.Message
+	toolState   map[string]any
+	prompt      string
+	tools       []agent.Tool
+	style       string
+	styleDegree float64
+	response    *response
+	greeted     bool
+	suspendedAt time.Time
+	dtmf        *agent.DTMFCollector
+	endpoint    *agent.Endpointer
+	igate       *agent.InterruptionGate
+	m           metrics
+
+	// speaker is set by SetSpeaker, and utteranceSpeaker is its value
+	// when the user last started speaking. speakers stabilizes
+	// diarization labels when the transcription config enables them.
+	speaker          string
+	utteranceSpeaker string
+	speakerNames     map[string]string
+	speakers         *stt.SpeakerRegistry
+
+	// heard collects what the user has said while a PushToTalk session
+	// listens, answered as one turn at StopListening.
+	heard *agent.Turn
+
+	// policy is Config.TurnPolicy or the default. quiet, guarded by mu,
+	// is closed and replaced when the user stops speaking or an
+	// utterance completes, waking replies it holds.
+	policy agent.TurnPolicy
+	quiet  chan struct{}
+
+	hooks *webhook.Dispatcher
+	audit *audit.Logger
+
+	// sink queues turns for Config.TranscriptSink.
+	sink *agent.Buffer[agent.Turn]
+
+	// voice keeps each reply with one TTS provider.
+	voice *tts.VoiceContinuity
+
+	// sttRate and ttsRate are the sample rates of STT input and TTS
+	// output. Audio is resampled between them and rate, the session's
+	// native rate.
+	sttRate int
+	ttsRate int
+
+	// sendMu serializes caller audio through framer and resampler.
+	sendMu    sync.Mutex
+	framer    *audio.Framer
+	resampler *audio.Resampler
+
+	// input queues converted caller audio for pumpInput to write to STT.
+	input *inputQueue
+
+	// echo and gate, if set, keep agent speech out of caller audio.
+	echo *audio.EchoCanceller
+	// played relays audio to ReceiveAudio under echo cancellation, so
+	// that a chunk is referenced when the reader takes it.
+	played chan []byte
+	gate   *agent.EchoGate
+
+	// userLevel and agentLevel meter caller audio and agent speech for
+	// Config.AudioLevelInterval.
+	userLevel  *audio.LevelMeter
+	agentLevel *audio.LevelMeter
+
+	// gain is the linear output gain, and ducker follows agent speech.
+	gain   atomic.Uint64
+	ducker *agent.Ducker
+
+	// listening is set between StartListening and StopListening, which
+	// pttMu serializes.
+	pttMu     sync.Mutex
+	listening atomic.Bool
+
+	// paused is set between Pause and Resume. sessionTimer enforces
+	// MaxSessionDuration until sessionEnd, and sessionLeft is its time
+	// remaining while paused; both are guarded by mu.
+	paused       atomic.Bool
+	sessionTimer *time.Timer
+	sessionEnd   time.Time
+	sessionLeft  time.Duration
+
+	// consentDone is closed once Config.RecordingConsent is settled, and
+	// is nil without it. consenting is set from the announcement until
+	// then, and awaitingAck while an acknowledgment is awaited on
+	// consentReplies; the flags are guarded by mu.
+	consentDone    chan struct{}
+	consentReplies chan consentReply
+	consentStarted bool
+	consenting     bool
+	awaitingAck    bool
+
+	// summary is the call summary once Config.Summary has generated it,
+	// guarded by mu.
+	summary *agent.CallSummary
+
+	// ending is set once a session limit is ending the session.
+	ending   atomic.Bool
+	stopping atomic.Bool
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// latency accumulates an average latency.
+type latency struct {
+	total time.Duration
+	count int
+}
+
+// metrics accumulates the totals behind agent.Metrics.
+type metrics struct {
+	userSpeech         time.Duration
+	speechStart        time.Time
+	lastSpeech         time.Duration
+	agentSpeech        time.Duration
+	llmLatency         time.Duration
+	llmCount           int
+	ttsLatency         time.Duration
+	ttsCount           int
+	ttsByProvider      map[string]latency
+	retrievalLatency   time.Duration
+	retrievalCount     int
+	ttfa               time.Duration
+	ttfaTotal          time.Duration
+	ttfaCount          int
+	interruptions      int
+	falseInterruptions int
+	toolCalls          int
+	errors             int
+	usage              agent.Usage
+	quality            transport.QualityStats
+}
+
+// response is one in-flight agent reply.
+type response struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	prev   *response
+
+	// userEnd is when the user turn being answered ended, for
+	// time-to-first-audio.
+	userEnd time.Time
+
+	uninterruptible bool
+	stopAfterClause atomic.Bool
+	speaking        atomic.Bool
+	// speakingSince is when the reply last started speaking, in Unix
+	// nanoseconds, for Config.InterruptionGracePeriod.
+	speakingSince atomic.Int64
+
+	// Under Config.InterruptionResume, resumed is open while the reply is
+	// paused by an interruption; clause is the text being spoken.
+	pauseMu  sync.Mutex
+	resumed  chan struct{}
+	pausedAt time.Time
+	restart  bool
+	clause   string
+
+	// ttfa is the time to the reply's first audio, once there is some.
+	ttfa atomic.Int64
+
+	// completed is set once the reply has been delivered in full, so a
+	// Config.MaxTurnDuration timeout that fires as it finishes is not
+	// reported as cutting it.
+	completed atomic.Bool
+}
+
+func newSession(parent context.Context, p *Provider, id string, config agent.Config) (*Session, error) {
+	rate := config.SampleRate
+	if rate <= 0 {
+		rate = p.opts.sampleRate
+	}
+	encoding := config.AudioEncoding
+	if encoding == "" {
+		encoding = audio.EncodingPCM
+	}
+	if !config.TextOnly && p.tts == nil {
+		return nil, ErrNoTTS
+	}
+	var framer *audio.Framer
+	if !config.TextOnly {
+		var err error
+		if encoding, err = p.checkFormats(encoding); err != nil {
+			return nil, err
+		}
+		if framer, err = audio.NewFramer(encoding, rate, config.FrameDuration); err != nil {
+			return nil, err
+		}
+	}
+
+	// The audit log is opened before any of the session's goroutines
+	// start, so failing to open it leaves none behind.
+	var auditSink audit.Sink
+	if p.opts.auditSink != nil {
+		var err error
+		if auditSink, err = p.opts.auditSink(id, config); err != nil {
+			return nil, fmt.Errorf("custom: opening audit log: %w", err)
+		}
+	}
+
+	// The session outlives the CreateSession call, so only the tenant is
+	// carried over for per-tenant provider credentials.
+	base := context.Background()
+	if tenant := credentials.TenantFrom(parent); tenant != "" {
+		base = credentials.WithTenant(base, tenant)
+	}
+	ctx, cancel := context.WithCancel(base)
+	s := &Session{
+		id:          id,
+		p:           p,
+		config:      config,
+		rate:        rate,
+		encoding:    encoding,
+		framer:      framer,
+		events:      agent.NewBuffer[agent.Event](bufferSize(config.EventBuffer, agent.DefaultEventBuffer), config.EventBufferPolicy),
+		done:        make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		toolState:   make(map[string]any),
+		prompt:      config.SystemPrompt,
+		tools:       config.Tools,
+		style:       config.Style,
+		styleDegree: config.StyleDegree,
+		voice:       tts.NewVoiceContinuity(),
+		policy:      config.TurnPolicy,
+		quiet:       make(chan struct{}),
+	}
+	if s.policy == nil {
+		s.policy = agent.DefaultTurnPolicy{}
+	}
+	s.SetOutputGain(config.OutputGain)
+	if !config.TextOnly {
+		s.audio = agent.NewBuffer[[]byte](bufferSize(config.AudioBuffer, agent.DefaultAudioBuffer), audioPolicy(config.AudioBufferPolicy))
+		if err := s.negotiateRates(); err != nil {
+			cancel()
+			if auditSink != nil {
+				_ = auditSink.Close()
+			}
+			return nil, err
+		}
+		if config.EchoCancellation != nil {
+			s.echo = audio.NewEchoCanceller(rate, *config.EchoCancellation)
+			s.played = make(chan []byte)
+			go s.relayAudio()
+		}
+		if config.EchoGate != nil {
+			s.gate = agent.NewEchoGate(*config.EchoGate)
+		}
+		if config.Ducking != nil {
+			s.ducker = agent.NewDucker(*config.Ducking)
+		}
+	}
+	if config.DTMFAsInput {
+		s.dtmf = agent.NewDTMFCollector(config, s.dtmfInput)
+	}
+	if config.AudioLevelInterval > 0 {
+		s.userLevel, s.agentLevel = &audio.LevelMeter{}, &audio.LevelMeter{}
+		s.wg.Add(1)
+		go s.meterLevels(max(config.AudioLevelInterval, agent.MinAudioLevelInterval))
+	}
+	if config.RecordingConsent != nil {
+		s.consentDone = make(chan struct{})
+		s.consentReplies = make(chan consentReply, 1)
+	}
+	var endpointing agent.EndpointConfig
+	if config.Endpointing != nil {
+		endpointing = *config.Endpointing
+	}
+	s.endpoint = agent.NewEndpointer(endpointing, s.utteranceComplete)
+	if config.Interruption != nil {
+		s.igate = agent.NewInterruptionGate(*config.Interruption)
+	}
+	if config.TranscriptSink != nil {
+		s.sink = agent.NewBuffer[agent.Turn](bufferSize(config.TranscriptSinkBuffer, agent.DefaultTranscriptSinkBuffer), agent.BufferDropOldest)
+		go func() {
+			for turn := range s.sink.C() {
+				config.TranscriptSink(turn)
+			}
+		}()
+	}
+	if p.opts.transcription.EnableSpeakerDiarization {
+		s.speakers = stt.NewSpeakerRegistry(p.opts.transcription.MaxSpeakers)
+	}
+	if auditSink != nil {
+		s.audit = audit.New(id, config, auditSink, p.opts.audit...)
+	}
+	if webhook.Enabled(config.Webhooks) {
+		s.hooks = webhook.New(config, p.opts.webhooks...)
+	}
+	if p.stt != nil && !config.TextOnly {
+		s.input = newInputQueue(bufferSize(config.InputAudioBuffer, agent.DefaultInputAudioBuffer), config.InputAudioBufferPolicy)
+		s.wg.Add(1)
+		go s.pumpInput()
+	}
+	return s, nil
+}
+
+func bufferSize(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
+
+func audioPolicy(p agent.BufferPolicy) agent.BufferPolicy {
+	if p == "" {
+		return agent.BufferDropOldest
+	}
+	return p
+}
+
+// ID returns the session identifier.
+func (s *Session) ID() string { return s.id }
+
+// Start begins transcription and, if configured, plays the recording
+// announcement and speaks the greeting.
+func (s *Session) Start(ctx context.Context) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	clock := agent.NewSessionClock(time.Now())
+	s.clock.Store(&clock)
+	if !s.config.PushToTalk {
+		if err := s.startSTT(); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.started = clock.Start()
+	s.mu.Unlock()
+	if d := s.config.MaxSessionDuration; d > 0 {
+		s.mu.Lock()
+		s.startSessionTimerLocked(d)
+		s.mu.Unlock()
+		go func() {
+			<-s.done
+			s.mu.Lock()
+			s.sessionTimer.Stop()
+			s.mu.Unlock()
+		}()
+	}
+	s.emit(agent.EventSessionStarted, nil, nil)
+	if s.config.FirstSpeaker != agent.FirstSpeakerAgentOnAnswer {
+		s.startConsent()
+	}
+	if agent.ShouldGreet(s.config, "") {
+		s.greet()
+	}
+	return nil
+}
+
+// startSessionTimerLocked ends the session after d, the time left of
+// MaxSessionDuration.
+func (s *Session) startSessionTimerLocked(d time.Duration) {
+	s.sessionEnd = time.Now().Add(d)
+	s.sessionTimer = time.AfterFunc(d, func() {
+		s.endSession(agent.LimitSessionDuration)
+	})
+}
+
+// negotiateRates picks the STT and TTS sample rates, inserting resampling
+// where they differ from the session rate. It fails only if a conversion
+// is impossible.
+func (s *Session) negotiateRates() error {
+	s.sttRate = cmp.Or(s.p.opts.transcription.SampleRate, s.rate)
+	s.ttsRate = cmp.Or(s.p.opts.synthesis.SampleRate, s.rate)
+	if s.sttRate != s.rate {
+		r, err := audio.NewResampler(s.rate, s.sttRate)
+		if err != nil {
+			return fmt.Errorf("custom: STT sample rate: %w", err)
+		}
+		s.resampler = r
+		s.p.opts.logger.Info("resampling caller audio for STT",
+			"session", s.id, "from", s.rate, "to", s.sttRate)
+	}
+	if s.ttsRate != s.rate {
+		if _, err := audio.NewResampler(s.ttsRate, s.rate); err != nil {
+			return fmt.Errorf("custom: TTS sample rate: %w", err)
+		}
+		s.p.opts.logger.Info("resampling agent speech from TTS",
+			"session", s.id, "from", s.ttsRate, "to", s.rate)
+	}
+	return nil
+}
+
+// checkFormats returns the audio package name of a session's
+// AudioEncoding, checking that the session can convert between it and the
+// PCM its STT and TTS exchange.
+func (p *Provider) checkFormats(encoding string) (string, error) {
+	enc, ok := agent.RawEncoding(encoding)
+	if !ok {
+		return "", fmt.Errorf("%w: agent AudioEncoding %q and STT/TTS audio (pcm) disagree: no %s codec; use pcm, mulaw, or alaw",
+			agent.ErrAudioFormat, encoding, encoding)
+	}
+	if f := p.opts.synthesis.OutputFormat; f != "" {
+		if raw, _ := agent.RawEncoding(f); raw != audio.EncodingPCM {
+			return "", fmt.Errorf("%w: TTS OutputFormat %q and agent AudioEncoding %q disagree: sessions decode only pcm speech, with no %s decoder; leave OutputFormat empty for pcm encoded to %q",
+				agent.ErrAudioFormat, f, encoding, f, enc)
+		}
+	}
+	if e := p.opts.transcription.Encoding; e != "" {
+		if raw, _ := agent.RawEncoding(e); raw != audio.EncodingPCM {
+			return "", fmt.Errorf("%w: agent AudioEncoding %q and STT Encoding %q disagree: sessions decode caller audio to pcm, with no %s encoder; leave Encoding empty",
+				agent.ErrAudioFormat, encoding, e, e)
+		}
+	}
+	return enc, nil
+}
+
+// startSTT opens a transcription stream for caller audio, if there is an
+// STT client and the session takes audio.
+func (s *Session) startSTT() error {
+	if s.p.stt == nil || s.config.TextOnly {
+		return nil
+	}
+	config := s.p.opts.transcription
+	if config.Language == "" {
+		config.Language = s.config.Language
+	}
+	config.Encoding, config.SampleRate, config.Channels = "pcm", s.sttRate, 1
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	w, events, err := s.p.stt.TranscribeStream(ctx, config)
+	if err != nil {
+		cancel()
+		return err
+	}
+	done := make(chan struct{})
+	s.mu.Lock()
+	s.sttIn, s.sttCancel, s.sttDone = w, cancel, done
+	s.sttStart = s.Clock().Now()
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer close(done)
+		s.listen(events)
+	}()
+	return nil
+}
+
+func (s *Session) stopSTT() {
+	s.mu.Lock()
+	w, cancel := s.sttIn, s.sttCancel
+	s.sttIn, s.sttCancel = nil, nil
+	s.mu.Unlock()
+	if w != nil {
+		_ = w.Close()
+	}
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Stop ends the session. Input is refused at once; buffered audio is
+// flushed and the STT stream closed so its final transcripts are recorded
+// (but not answered); the reply in progress is cut or, with
+// agent.StopFinishUtterance, allowed to finish. If ctx ends while
+// draining, everything is canceled and ctx.Err() returned.
+func (s *Session) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { s.stopErr = s.drain(ctx) })
+	return s.stopErr
+}
+
+func (s *Session) drain(ctx context.Context) error {
+	s.stopping.Store(true)
+	mode := agent.StopModeFrom(ctx, s.config)
+	if s.dtmf != nil {
+		s.dtmf.Stop()
+	}
+
+	_ = s.FlushAudio()
+	s.mu.Lock()
+	w, sttDone := s.sttIn, s.sttDone
+	s.sttIn = nil
+	s.mu.Unlock()
+	if w != nil {
+		_ = w.Close()
+	}
+	// A provider that never closes its events must not hold Stop up, but
+	// giving up on it leaves the reply to finish under mode.
+	flush, cancel := context.WithTimeout(ctx, sttDrainTimeout)
+	forced := !wait(flush, sttDone) && ctx.Err() != nil
+	cancel()
+	s.endpoint.Stop()
+
+	s.mu.Lock()
+	r := s.response
+	s.mu.Unlock()
+	if r != nil {
+		if forced || mode != agent.StopFinishUtterance {
+			r.cancel()
+		}
+		forced = !wait(ctx, r.done) || forced
+	}
+
+	close(s.done)
+	s.cancel()
+	s.stopSTT()
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(stopEventTimeout):
+	}
+
+	s.mu.Lock()
+	started := !s.started.IsZero()
+	s.mu.Unlock()
+	if started && s.config.Summary != nil {
+		s.summarize(ctx)
+	}
+	if started {
+		// The final event waits a bounded time for a stalled consumer,
+		// even when ctx has already ended.
+		ended, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopEventTimeout)
+		ev := s.event(agent.EventSessionEnded, s.Metrics(), nil)
+		s.events.Send(ended, ev)
+		cancel()
+		if s.hooks != nil {
+			s.hooks.Observe(s.id, ev)
+		}
+		if s.audit != nil {
+			s.audit.Observe(ev)
+		}
+	}
+	if s.audit != nil {
+		flush, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditFlushTimeout)
+		_ = s.audit.Close(flush)
+		cancel()
+	}
+	if s.hooks != nil {
+		// Queued webhooks finish delivery, with retries, in the
+		// background.
+		go func() { _ = s.hooks.Close(context.Background()) }()
+	}
+	s.events.Close()
+	if s.audio != nil {
+		s.audio.Close()
+	}
+	if s.sink != nil {
+		s.sink.Close()
+	}
+	s.p.registry.Remove(s.id)
+	if forced {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// summarize generates the call summary for Config.Summary and emits
+// EventCallSummary. A failure is reported in the event only. Sessions in
+// which the user said nothing are not summarized.
+func (s *Session) summarize(ctx context.Context) {
+	transcript := s.Transcript()
+	if !slices.ContainsFunc(transcript, func(t agent.Turn) bool { return t.Role == "user" }) {
+		return
+	}
+	summary, err := agent.Summarize(ctx, s.p.llm, transcript, *s.config.Summary)
+	ev := s.event(agent.EventCallSummary, nil, err)
+	if err == nil {
+		s.mu.Lock()
+		s.summary = &summary
+		s.mu.Unlock()
+		ev.Data = summary
+	}
+	sent, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopEventTimeout)
+	s.events.Send(sent, ev)
+	cancel()
+	if s.hooks != nil {
+		s.hooks.Observe(s.id, ev)
+	}
+	if s.audit != nil {
+		s.audit.Observe(ev)
+	}
+}
+
+// Summary returns the call summary, implementing agent.Summarizer. It is
+// available once Stop has generated it under Config.Summary.
+func (s *Session) Summary() (agent.CallSummary, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.summary == nil {
+		return agent.CallSummary{}, false
+	}
+	return *s.summary, true
+}
+
+// wait waits for done, reporting false if ctx ended first. A nil done is
+// already finished.
+func wait(ctx context.Context, done <-chan struct{}) bool {
+	if done == nil {
+		return true
+	}
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SendAudio sends caller audio to the agent, repacketized into frames as
+// described by agent.Session. G.711 audio is decoded to PCM for STT.
+// Frames are queued for STT within agent.Config.InputAudioBuffer; errors
+// writing them are reported as EventError.
+func (s *Session) SendAudio(data []byte) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if (s.config.PushToTalk && !s.listening.Load() || s.paused.Load()) && !s.config.TextOnly {
+		return nil
+	}
+	if _, err := s.writer(); err != nil {
+		return err
+	}
+	return s.framer.WriteFunc(data, func(frame []byte) error {
+		return s.queue(s.toSTT(frame))
+	})
+}
+
+// queue hands converted caller audio to pumpInput, under the input
+// buffer policy. The caller holds sendMu.
+func (s *Session) queue(pcm []byte) error {
+	if len(pcm) == 0 {
+		audio.PutBuffer(pcm)
+		return nil
+	}
+	if !s.input.push(pcm, s.done, func() {
+		s.emit(agent.EventError, nil, fmt.Errorf("%w: %d bytes waiting for STT", agent.ErrInputAudioOverflow, s.input.max))
+	}) {
+		return ErrSessionClosed
+	}
+	return nil
+}
+
+// pumpInput writes queued caller audio to the current STT stream.
+// Audio arriving while there is none, e.g. while suspended, is dropped.
+func (s *Session) pumpInput() {
+	defer s.wg.Done()
+	for {
+		pcm, ok := s.input.pop(s.done)
+		if !ok {
+			return
+		}
+		w, err := s.writer()
+		if err == nil {
+			_, err = w.Write(pcm)
+		}
+		s.input.wrote(pcm)
+		audio.PutBuffer(pcm)
+		if err != nil && !errors.Is(err, ErrNotStarted) && !errors.Is(err, ErrSessionClosed) {
+			s.emit(agent.EventError, nil, err)
+		}
+	}
+}
+
+// FlushAudio sends the buffered partial frame to STT, implementing
+// agent.AudioFlusher, and returns once the queued caller audio has been
+// written.
+func (s *Session) FlushAudio() error {
+	return s.flushAudio(true)
+}
+
+// flushAudio sends the buffered partial frame to STT, and at the end of
+// the caller's audio the resampler's look-ahead too, which starts its
+// filter afresh. Within a stream, as at the end of each user utterance,
+// the look-ahead goes with the next audio instead, so the filter history
+// carries over.
+func (s *Session) flushAudio(end bool) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if s.config.PushToTalk && !s.listening.Load() && !s.config.TextOnly {
+		return nil
+	}
+	if _, err := s.writer(); err != nil {
+		return err
+	}
+	rest, ferr := s.framer.Flush()
+	if len(rest) > 0 {
+		if err := s.queue(s.toSTT(rest)); err != nil {
+			return err
+		}
+	}
+	if s.resampler != nil && end {
+		if err := s.queue(s.resampler.FlushBytes()); err != nil {
+			return err
+		}
+	}
+	if !s.input.wait(s.done) {
+		return ErrSessionClosed
+	}
+	return ferr
+}
+
+func (s *Session) writer() (io.Writer, error) {
+	s.mu.Lock()
+	w := s.sttIn
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+		return nil, ErrSessionClosed
+	default:
+	}
+	if w == nil {
+		if s.p.stt == nil || s.config.TextOnly {
+			return nil, ErrTextOnly
+		}
+		return nil, ErrNotStarted
+	}
+	return w, nil
+}
+
+// toSTT converts a frame of session audio to PCM at the STT rate, in a
+// pooled buffer that pumpInput returns once it is written. The frame is
+// not retained. The caller holds sendMu.
+func (s *Session) toSTT(frame []byte) []byte {
+	pcm := s.appendDecode(audio.GetBuffer(len(frame) * audio.BytesPerSample / audio.SampleSize(s.encoding))[:0], frame)
+	if s.userLevel != nil {
+		s.userLevel.AddBytes(pcm)
+	}
+	if s.echo != nil {
+		pcm = s.echo.ProcessBytes(pcm)
+	}
+	if s.gate != nil {
+		var bargeIn bool
+		// With an interruption gate, the transcript decides instead.
+		if pcm, bargeIn = s.gate.Process(pcm, s.rate); bargeIn && s.igate == nil && !s.config.PushToTalk {
+			s.bargeIn(false, "")
+		}
+	}
+	if s.resampler != nil {
+		out := s.resampler.AppendBytes(audio.GetBuffer(len(pcm)*s.sttRate/s.rate + audio.BytesPerSample)[:0], pcm)
+		audio.PutBuffer(pcm)
+		pcm = out
+	}
+	return pcm
+}
+
+// appendDecode appends session audio converted to PCM to dst.
+func (s *Session) appendDecode(dst, frame []byte) []byte {
+	switch s.encoding {
+	case audio.EncodingMuLaw:
+		return audio.AppendDecodeMuLaw(dst, frame)
+	case audio.EncodingALaw:
+		return audio.AppendDecodeALaw(dst, frame)
+	}
+	return append(dst, frame...)
+}
+
+// decode converts session audio to PCM.
+func (s *Session) decode(frame []byte) []byte {
+	switch s.encoding {
+	case audio.EncodingMuLaw:
+		return audio.DecodeMuLaw(frame)
+	case audio.EncodingALaw:
+		return audio.DecodeALaw(frame)
+	}
+	return frame
+}
+
+// appendEncode appends PCM converted to session audio to dst.
+func (s *Session) appendEncode(dst, pcm []byte) []byte {
+	switch s.encoding {
+	case audio.EncodingMuLaw:
+		return audio.AppendEncodeMuLaw(dst, pcm)
+	case audio.EncodingALaw:
+		return audio.AppendEncodeALaw(dst, pcm)
+	}
+	return append(dst, pcm...)
+}
+
+// ReceiveAudio returns agent speech as mono chunks in the session
+// encoding, drawn from a buffer pool; see ReleaseAudio.
+// A TextOnly session returns a closed channel.
+func (s *Session) ReceiveAudio() <-chan []byte {
+	if s.audio == nil {
+		return noAudio
+	}
+	if s.played != nil {
+		return s.played
+	}
+	return s.audio.C()
+}
+
+// relayAudio hands buffered agent audio to ReceiveAudio one chunk at a
+// time, feeding each to the echo canceller as the reader takes it: audio
+// still queued, or dropped by the buffer policy, never reaches the
+// reference. Once the session ends, a reader that stops taking audio for
+// stopEventTimeout forfeits the rest.
+func (s *Session) relayAudio() {
+	defer close(s.played)
+	for chunk := range s.audio.C() {
+		// The reader may release the chunk after taking it, so keep a copy.
+		ref := audio.BytesToInt16(s.decode(chunk))
+		select {
+		case s.played <- chunk:
+			s.echo.Reference(ref)
+			continue
+		case <-s.done:
+		}
+		select {
+		case s.played <- chunk:
+		case <-time.After(stopEventTimeout):
+			audio.PutBuffer(chunk)
+			for chunk := range s.audio.C() {
+				audio.PutBuffer(chunk)
+			}
+			return
+		}
+	}
+}
+
+// ReleaseAudio returns a ReceiveAudio chunk to the session's buffer pool,
+// implementing agent.AudioReleaser.
+func (s *Session) ReleaseAudio(chunk []byte) {
+	audio.PutBuffer(chunk)
+}
+
+// noAudio is the closed ReceiveAudio channel of TextOnly sessions.
+var noAudio = func() chan []byte {
+	ch := make(chan []byte)
+	close(ch)
+	return ch
+}()
+
+// SendText sends a user turn as text, bypassing STT.
+func (s *Session) SendText(text string) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	if s.paused.Load() {
+		return ErrPaused
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	s.interrupt(true)
+	s.userTurn(agent.Turn{Role: "user", Text: text, Timestamp: time.Now()})
+	return nil
+}
+
+// SendDTMF handles keypad digits, implementing agent.DTMFSender:
+// interrupting agent speech and, with Config.DTMFAsInput, collecting them
+// into a user turn.
+func (s *Session) SendDTMF(digits string) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	if s.paused.Load() {
+		return ErrPaused
+	}
+	if s.consentInput(consentReply{digits: digits}) {
+		return nil
+	}
+	if agent.DTMFInterrupts(s.config.InterruptionMode) && !s.config.PushToTalk {
+		s.interrupt(true)
+	}
+	if s.dtmf != nil {
+		s.dtmf.Add(digits)
+	}
+	return nil
+}
+
+// Interrupt interrupts agent speech as if the user barged in.
+func (s *Session) Interrupt() error {
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+	s.interrupt(true)
+	return nil
+}
+
+func (s *Session) dtmfInput(input agent.DTMFInput) {
+	s.emit(agent.EventDTMFInput, input, nil)
+	s.userTurn(agent.DTMFTurn(input))
+}
+
+// InterruptAgent cancels the agent's reply and replaces it, implementing
+// agent.AgentInterrupter. Queued audio is discarded once the canceled
+// reply has stopped, so none of it plays before the replacement.
+func (s *Session) InterruptAgent(replacement agent.AgentInterruption) error {
+	if s.stopping.Load() || s.ending.Load() {
+		return ErrSessionClosed
+	}
+	s.emit(agent.EventAgentInterrupted, replacement, nil)
+	s.startResponse(false, func(r *response) {
+		if s.audio != nil {
+			s.audio.Discard()
+		}
+		switch {
+		case replacement.Say != "":
+			s.say(r, replacement.Say)
+		case replacement.Prompt != "":
+			s.mu.Lock()
+			s.history = append(s.history, agent.Message{Role: agent.RoleSystem, Content: replacement.Prompt})
+			s.mu.Unlock()
+			s.reply(r, nil)
+		}
+	})
+	return nil
+}
+
+// Answered starts the recording announcement and greeting for
+// FirstSpeakerAgentOnAnswer sessions, implementing agent.AnswerReporter.
+func (s *Session) Answered(by agent.AnsweredBy) error {
+	if s.config.FirstSpeaker == agent.FirstSpeakerAgentOnAnswer && by != agent.AnsweredByMachine {
+		s.startConsent()
+	}
+	if agent.ShouldGreet(s.config, by) {
+		s.greet()
+	}
+	return nil
+}
+
+// SetStyle changes the TTS style from the next clause spoken, implementing
+// agent.Styler.
+func (s *Session) SetStyle(style string, degree float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.style, s.styleDegree = style, degree
+	return nil
+}
+
+// RecordQuality records the transport's latest call-quality measurement
+// for Metrics.
+func (s *Session) RecordQuality(stats transport.QualityStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m.quality = stats
+}
+
+// Events returns the session event channel.
+func (s *Session) Events() <-chan agent.Event { return s.events.C() }
+
+// Transcript returns the conversation so far.
+func (s *Session) Transcript() []agent.Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]agent.Turn(nil), s.transcript...)
+}
+
+// Metrics returns session performance metrics.
+func (s *Session) Metrics() agent.Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := agent.Metrics{
+		TurnCount:              len(s.transcript),
+		UserSpeechDurationMs:   int(s.m.userSpeech.Milliseconds()),
+		AgentSpeechDurationMs:  int(s.m.agentSpeech.Milliseconds()),
+		TimeToFirstAudioMs:     int(s.m.ttfa.Milliseconds()),
+		InterruptionCount:      s.m.interruptions,
+		FalseInterruptionCount: s.m.falseInterruptions,
+		VoiceSwitchCount:       s.voice.Switches(),
+		ToolCallCount:          s.m.toolCalls,
+		ErrorCount:             s.m.errors,
+		DroppedEvents:          s.events.Dropped(),
+		Usage:                  s.config.Budget.Price(s.m.usage),
+		AudioQuality:           s.m.quality,
+	}
+	if !s.started.IsZero() {
+		m.SessionDurationMs = int(time.Since(s.started).Milliseconds())
+	}
+	if s.audio != nil {
+		m.DroppedAudioFrames = s.audio.Dropped()
+	}
+	if s.sink != nil {
+		m.DroppedTranscriptTurns = s.sink.Dropped()
+	}
+	if s.input != nil {
+		m.InputAudioHighWater, m.DroppedInputAudioBytes = s.input.stats()
+	}
+	if s.m.llmCount > 0 {
+		m.AvgLLMLatencyMs = int((s.m.llmLatency / time.Duration(s.m.llmCount)).Milliseconds())
+	}
+	if s.m.ttsCount > 0 {
+		m.AvgTTSLatencyMs = int((s.m.ttsLatency / time.Duration(s.m.ttsCount)).Milliseconds())
+		m.AvgTTSLatencyByProviderMs = make(map[string]int, len(s.m.ttsByProvider))
+		for provider, l := range s.m.ttsByProvider {
+			m.AvgTTSLatencyByProviderMs[provider] = int((l.total / time.Duration(l.count)).Milliseconds())
+		}
+	}
+	if s.m.retrievalCount > 0 {
+		m.AvgRetrievalLatencyMs = int((s.m.retrievalLatency / time.Duration(s.m.retrievalCount)).Milliseconds())
+	}
+	if s.m.ttfaCount > 0 {
+		m.AvgTimeToFirstAudioMs = int((s.m.ttfaTotal / time.Duration(s.m.ttfaCount)).Milliseconds())
+		m.AvgTotalLatencyMs = m.AvgTimeToFirstAudioMs
+	}
+	return m
+}
+
+// Snapshot returns the session's resumable state.
+func (s *Session) Snapshot() agent.Snapshot {
+	metrics := s.Metrics()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := make(map[string]any, len(s.toolState))
+	for k, v := range s.toolState {
+		state[k] = v
+	}
+	return agent.Snapshot{
+		SessionID:   s.id,
+		Transcript:  append([]agent.Turn(nil), s.transcript...),
+		Context:     contextTurns(s.history),
+		ToolState:   state,
+		Metrics:     metrics,
+		SuspendedAt: s.suspendedAt,
+	}
+}
+
+// contextTurns returns the messages of history, as sent to the LLM, as
+// turns for Snapshot.Context. Messages without text, such as bare tool
+// call requests, are left out.
+func contextTurns(history []agent.Message) []agent.Turn {
+	turns := make([]agent.Turn, 0, len(history))
+	for _, m := range history {
+		if m.Content == "" {
+			continue
+		}
+		role := m.Role
+		if role == agent.RoleAssistant {
+			role = "agent"
+		}
+		turns = append(turns, agent.Turn{Role: role, Text: m.Content})
+	}
+	return turns
+}
+
+// SetToolState stores state that tools need to survive a reconnect.
+func (s *Session) SetToolState(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolState[key] = value
+}
+
+// Suspend detaches the session from its caller audio, keeping the
+// conversation, and cancels any reply in progress.
+func (s *Session) Suspend() error {
+	s.mu.Lock()
+	if !s.suspendedAt.IsZero() {
+		s.mu.Unlock()
+		return nil
+	}
+	s.suspendedAt = time.Now()
+	s.mu.Unlock()
+	s.detach()
+	s.emit(agent.EventSessionSuspended, nil, nil)
+	return nil
+}
+
+// Pause puts the session on hold, implementing agent.Pauser: like
+// Suspend, but without the transport being lost, and stopping the
+// MaxSessionDuration clock. Input sent while paused is discarded or
+// refused with ErrPaused.
+func (s *Session) Pause(ctx context.Context) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	if s.paused.Swap(true) {
+		return nil
+	}
+	s.mu.Lock()
+	if s.sessionTimer != nil && s.sessionTimer.Stop() {
+		s.sessionLeft = time.Until(s.sessionEnd)
+	}
+	s.mu.Unlock()
+	s.detach()
+	s.emit(agent.EventSessionPaused, nil, nil)
+	return nil
+}
+
+// detach cancels any reply in progress and stops taking caller audio,
+// for Suspend and Pause.
+func (s *Session) detach() {
+	s.mu.Lock()
+	r := s.response
+	s.mu.Unlock()
+	if r != nil {
+		r.cancel()
+	}
+	s.pttMu.Lock()
+	s.listening.Store(false)
+	s.stopSTT()
+	s.pttMu.Unlock()
+	s.sendMu.Lock()
+	if s.framer != nil {
+		s.framer.Reset()
+	}
+	if s.resampler != nil {
+		s.resampler.Reset()
+	}
+	if s.echo != nil {
+		s.echo.Reset()
+	}
+	s.sendMu.Unlock()
+}
+
+// Resume reopens transcription after Suspend, unless the session is also
+// paused; PushToTalk sessions wait for StartListening again. It does not
+// end a Pause: see Unpause.
+func (s *Session) Resume(ctx context.Context) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	s.mu.Lock()
+	suspended := !s.suspendedAt.IsZero()
+	s.mu.Unlock()
+	if !suspended {
+		return nil
+	}
+	if !s.paused.Load() && !s.config.PushToTalk {
+		if err := s.startSTT(); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.suspendedAt = time.Time{}
+	s.mu.Unlock()
+	s.emit(agent.EventSessionResumed, nil, nil)
+	return nil
+}
+
+// Unpause ends a Pause, implementing agent.Pauser: transcription reopens
+// unless the session is also suspended, the MaxSessionDuration clock
+// restarts, and Config.ResumeGreeting is spoken. It does not end a
+// Suspend: see Resume.
+func (s *Session) Unpause(ctx context.Context) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	if !s.paused.Load() {
+		return nil
+	}
+	s.mu.Lock()
+	suspended := !s.suspendedAt.IsZero()
+	s.mu.Unlock()
+	if !suspended && !s.config.PushToTalk {
+		if err := s.startSTT(); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	if s.sessionLeft > 0 {
+		s.startSessionTimerLocked(s.sessionLeft)
+		s.sessionLeft = 0
+	}
+	s.mu.Unlock()
+	s.paused.Store(false)
+	s.emit(agent.EventSessionResumed, nil, nil)
+	if !suspended && !s.config.ResumeGreeting.IsZero() {
+		s.speakGreeting(s.config.ResumeGreeting)
+	}
+	return nil
+}
+
+// emit delivers an event under the event buffer policy, giving up when
+// the session stops.
+func (s *Session) emit(t agent.EventType, data any, err error) {
+	if err != nil {
+		s.mu.Lock()
+		s.m.errors++
+		s.mu.Unlock()
+	}
+	ev := s.event(t, data, err)
+	s.events.Send(s.ctx, ev)
+	if s.hooks != nil {
+		s.hooks.Observe(s.id, ev)
+	}
+	if s.audit != nil {
+		s.audit.Observe(ev)
+	}
+}
+
+// event returns an event occurring now.
+func (s *Session) event(t agent.EventType, data any, err error) agent.Event {
+	now := time.Now()
+	return agent.Event{Type: t, Timestamp: now, Offset: s.Clock().Offset(now), Data: data, Error: err}
+}
+
+// Clock returns the session clock, implementing agent.Clocker. It is zero
+// until Start.
+func (s *Session) Clock() agent.SessionClock {
+	if c := s.clock.Load(); c != nil {
+		return *c
+	}
+	return agent.SessionClock{}
+}
+
+// sendAudio delivers a chunk of agent audio under the audio buffer
+// policy, reporting false if ctx ended first. The chunk is the reader's
+// from then on, to release with ReleaseAudio.
+func (s *Session) sendAudio(ctx context.Context, chunk []byte) bool {
+	if s.agentLevel != nil {
+		s.agentLevel.AddBytes(s.decode(chunk))
+	}
+	if !s.audio.Send(ctx, chunk) {
+		audio.PutBuffer(chunk)
+		return false
+	}
+	return ctx.Err() == nil
+}
+
+// meterLevels emits EventAudioLevel every interval until the session
+// ends. Level events are dropped rather than wait for room in Events.
+func (s *Session) meterLevels(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	meters := []struct {
+		role   string
+		meter  *audio.LevelMeter
+		active bool
+	}{{role: "user", meter: s.userLevel}, {role: "agent", meter: s.agentLevel}}
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+		for i := range meters {
+			m := &meters[i]
+			rms, peak, ok := m.meter.Read()
+			if !ok && !m.active {
+				continue
+			}
+			m.active = ok
+			s.events.TrySend(s.event(agent.EventAudioLevel, agent.AudioLevel{Role: m.role, RMS: rms, Peak: peak}, nil))
+		}
+	}
+}
+
+// listen turns STT events into user turns and interruptions.
+func (s *Session) listen(events <-chan stt.StreamEvent) {
+	defer s.wg.Done()
+	// caption is the interim text last sent in EventUserTranscriptUpdate.
+	var caption string
+	for ev := range events {
+		switch ev.Type {
+		case stt.EventSpeechStart:
+			s.mu.Lock()
+			s.m.speechStart, s.m.lastSpeech = time.Now(), 0
+			s.utteranceSpeaker = s.speaker
+			s.mu.Unlock()
+			s.endpoint.SpeechStarted()
+			s.emit(agent.EventUserSpeechStart, nil, nil)
+			if s.igate == nil && (s.gate == nil || !s.gate.Active()) && !s.config.PushToTalk {
+				s.bargeIn(false, "")
+			}
+		case stt.EventSpeechEnd:
+			s.mu.Lock()
+			if !s.m.speechStart.IsZero() {
+				s.m.lastSpeech = time.Since(s.m.speechStart)
+				s.m.userSpeech += s.m.lastSpeech
+				s.m.speechStart = time.Time{}
+			}
+			s.mu.Unlock()
+			s.wakeHeld()
+			// Flush off this goroutine: SendAudio may be blocked writing
+			// to the stream whose events we are consuming.
+			s.wg.Add(1)
+			s.p.pool.spawn(func() {
+				defer s.wg.Done()
+				if err := s.flushAudio(false); err != nil && !errors.Is(err, ErrNotStarted) && !errors.Is(err, ErrSessionClosed) {
+					s.emit(agent.EventError, nil, err)
+				}
+			})
+			s.emit(agent.EventUserSpeechEnd, nil, nil)
+			s.endpoint.SpeechEnded()
+		case stt.EventTranscript:
+			text := strings.TrimSpace(ev.Transcript)
+			// The agent hearing itself: withdraw any caption of it.
+			echo := s.gate != nil && s.gate.Suppress(text)
+			shown := ev.Transcript
+			if echo {
+				shown = ""
+			}
+			if shown != caption || (ev.IsFinal && shown != "") {
+				s.emit(agent.EventUserTranscriptUpdate, agent.TranscriptUpdate{
+					Text:         shown,
+					Correction:   stt.DiffTranscript(caption, shown),
+					Final:        ev.IsFinal,
+					Stability:    ev.Stability,
+					StableLength: ev.StableLength,
+				}, nil)
+			}
+			caption = shown
+			if ev.IsFinal {
+				caption = ""
+			}
+			if echo || text == "" {
+				continue
+			}
+			if s.config.PushToTalk {
+				if ev.IsFinal {
+					s.hear(s.attribute(agent.Turn{Role: "user", Text: text, Timestamp: time.Now()}, ev.Segment))
+				}
+				continue
+			}
+			if state := s.turnState(true, text); state.AgentSpeaking && !s.policy.Listen(state) {
+				continue
+			}
+			if s.igate != nil && s.agentSpeaking() {
+				if !s.igate.Allow(text, confidence(ev), s.speechDuration(ev)) {
+					// Noise or a backchannel: neither an interruption nor
+					// a turn to answer.
+					continue
+				}
+				s.bargeIn(true, text)
+			}
+			if !ev.IsFinal {
+				continue
+			}
+			s.bargeIn(true, text)
+			s.endpoint.Transcript(s.attribute(agent.Turn{Role: "user", Text: text, Timestamp: time.Now()}, ev.Segment))
+		case stt.EventError:
+			s.emit(agent.EventError, nil, ev.Error)
+		}
+	}
+	// The stream's last words complete the utterance.
+	s.endpoint.Flush()
+}
+
+// utteranceComplete reports a user utterance completed by endpointing
+// and, unless turn-taking is left to the application, answers it.
+func (s *Session) utteranceComplete(turn agent.Turn) {
+	s.wakeHeld()
+	s.emit(agent.EventUtteranceComplete, turn, nil)
+	if s.config.Endpointing != nil && s.config.Endpointing.Manual {
+		return
+	}
+	s.userTurn(turn)
+}
+
+// attribute sets the speaker of a user turn heard by STT: the one set
+// with SetSpeaker when the utterance began, or else the diarized
+// speaker of its segment.
+func (s *Session) attribute(turn agent.Turn, seg *stt.Segment) agent.Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn.Speaker = cmp.Or(s.utteranceSpeaker, s.speaker)
+	s.utteranceSpeaker = ""
+	if s.speakers != nil && seg != nil && seg.Speaker != "" {
+		// Stabilize every segment, so labels stay consistent whichever
+		// source attributes the turn.
+		stable := *seg
+		stable.Words = append([]stt.Word(nil), seg.Words...)
+		s.speakers.Stabilize(&stable)
+		if turn.Speaker == "" {
+			turn.Speaker = stable.Speaker
+		}
+	}
+	turn.SpeakerName = s.speakerNames[turn.Speaker]
+	if seg != nil && seg.EndTime > seg.StartTime {
+		// Move the STT stream's timings to the session's.
+		turn.Offset = s.sttStart + seg.StartTime
+		turn.Words = agent.ShiftWords(seg.Words, s.sttStart)
+	}
+	return turn
+}
+
+// StartListening interrupts the agent and starts transcribing caller
+// audio, implementing agent.PushToTalker. If the user's previous turn is
+// still being transcribed it is answered first.
+func (s *Session) StartListening() error {
+	if !s.config.PushToTalk {
+		return ErrNotPushToTalk
+	}
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	if s.paused.Load() {
+		return ErrPaused
+	}
+	s.pttMu.Lock()
+	defer s.pttMu.Unlock()
+	if s.listening.Load() {
+		return nil
+	}
+	s.mu.Lock()
+	prev := s.sttDone
+	s.mu.Unlock()
+	if !wait(s.ctx, prev) {
+		return ErrSessionClosed
+	}
+	s.interrupt(true)
+	if err := s.startSTT(); err != nil {
+		return err
+	}
+	s.listening.Store(true)
+	return nil
+}
+
+// StopListening stops taking caller audio and closes the transcription
+// stream, implementing agent.PushToTalker. Once its final transcripts
+// arrive, they are answered as one turn.
+func (s *Session) StopListening() error {
+	if !s.config.PushToTalk {
+		return ErrNotPushToTalk
+	}
+	s.pttMu.Lock()
+	defer s.pttMu.Unlock()
+	if !s.listening.Load() {
+		return nil
+	}
+	_ = s.FlushAudio()
+	s.sendMu.Lock()
+	s.listening.Store(false)
+	s.sendMu.Unlock()
+	if s.input != nil {
+		// Audio queued before listening stopped still belongs to the turn.
+		s.input.wait(s.done)
+	}
+	s.mu.Lock()
+	w, cancel, done := s.sttIn, s.sttCancel, s.sttDone
+	s.sttIn, s.sttCancel = nil, nil
+	s.mu.Unlock()
+	if w != nil {
+		_ = w.Close()
+	}
+	s.wg.Add(1)
+	s.p.pool.spawn(func() {
+		defer s.wg.Done()
+		ok := wait(s.ctx, done)
+		if cancel != nil {
+			cancel()
+		}
+		s.mu.Lock()
+		turn := s.heard
+		s.heard = nil
+		s.mu.Unlock()
+		if ok && turn != nil {
+			s.utteranceComplete(*turn)
+		}
+	})
+	return nil
+}
+
+// hear collects a final transcript heard while a PushToTalk session
+// listens.
+func (s *Session) hear(turn agent.Turn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.heard == nil {
+		s.heard = &turn
+		return
+	}
+	s.heard.Text += " " + turn.Text
+	s.heard.Words = append(s.heard.Words, turn.Words...)
+}
+
+// SetPrompt replaces the system prompt from the next user turn,
+// implementing agent.PersonaSwitcher.
+func (s *Session) SetPrompt(prompt string) error {
+	config := s.config
+	config.SystemPrompt = prompt
+	rendered, err := agent.RenderSystemPrompt(config, time.Now())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.prompt = rendered
+	change := s.personaLocked()
+	s.mu.Unlock()
+	s.emit(agent.EventPersonaChanged, change, nil)
+	return nil
+}
+
+// SetTools replaces the tools from the next user turn, implementing
+// agent.PersonaSwitcher.
+func (s *Session) SetTools(tools []agent.Tool) error {
+	for _, tool := range tools {
+		if err := agent.ValidateTool(tool); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.tools = slices.Clone(tools)
+	change := s.personaLocked()
+	s.mu.Unlock()
+	s.emit(agent.EventPersonaChanged, change, nil)
+	return nil
+}
+
+func (s *Session) personaLocked() agent.PersonaChange {
+	change := agent.PersonaChange{Prompt: s.prompt}
+	for _, t := range s.tools {
+		change.Tools = append(change.Tools, t.Name)
+	}
+	return change
+}
+
+// SetSpeaker attributes the user speech that follows to a speaker,
+// implementing agent.SpeakerTracker.
+func (s *Session) SetSpeaker(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.speaker = id
+	if s.m.speechStart.IsZero() {
+		return
+	}
+	// The user is mid-utterance: it belongs to the new speaker.
+	s.utteranceSpeaker = id
+}
+
+// NameSpeaker gives a speaker a display name, implementing
+// agent.SpeakerTracker.
+func (s *Session) NameSpeaker(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.speakerNames == nil {
+		s.speakerNames = make(map[string]string)
+	}
+	s.speakerNames[id] = name
+}
+
+// recordLocked adds a turn to the transcript and queues it for the
+// transcript sink, returning it with its session time set if it had
+// none. The caller holds mu.
+func (s *Session) recordLocked(turn agent.Turn) agent.Turn {
+	if turn.Offset == 0 {
+		turn.Offset = s.Clock().Offset(turn.Timestamp)
+	}
+	s.transcript = append(s.transcript, turn)
+	if s.sink != nil {
+		s.sink.Send(s.ctx, turn)
+	}
+	return turn
+}
+
+// agentSpeaking reports whether an interruptible reply is being spoken,
+// or is paused by an interruption.
+func (s *Session) agentSpeaking() bool {
+	s.mu.Lock()
+	r := s.response
+	s.mu.Unlock()
+	return r != nil && (r.speaking.Load() || r.paused()) && !r.uninterruptible
+}
+
+// speechDuration returns how long the caller has been speaking in a
+// transcript, from its segment or the current speech, or zero if unknown.
+func (s *Session) speechDuration(ev stt.StreamEvent) time.Duration {
+	if seg := ev.Segment; seg != nil && seg.EndTime > seg.StartTime {
+		return seg.EndTime - seg.StartTime
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m.speechStart.IsZero() {
+		return s.m.lastSpeech
+	}
+	return time.Since(s.m.speechStart)
+}
+
+func confidence(ev stt.StreamEvent) float64 {
+	if ev.Segment != nil {
+		return ev.Segment.Confidence
+	}
+	return 0
+}
+
+// bargeIn interrupts for the user speaking over the agent, as text if
+// known, unless Config.TurnPolicy disallows it or the reply started
+// speaking within Config.InterruptionGracePeriod, or the session is
+// stopping: the final transcripts Stop flushes must not cut a reply it
+// lets finish.
+func (s *Session) bargeIn(confirmed bool, text string) {
+	if s.stopping.Load() {
+		return
+	}
+	if state := s.turnState(confirmed, text); state.AgentSpeaking && !s.policy.Interrupt(state) {
+		return
+	}
+	if grace := s.config.InterruptionGracePeriod; grace > 0 {
+		s.mu.Lock()
+		r := s.response
+		s.mu.Unlock()
+		if r != nil && r.speaking.Load() && time.Since(time.Unix(0, r.speakingSince.Load())) < grace {
+			return
+		}
+	}
+	s.interrupt(confirmed)
+}
+
+// turnState describes the conversation for Config.TurnPolicy.
+func (s *Session) turnState(confirmed bool, text string) agent.TurnState {
+	s.mu.Lock()
+	r, start := s.response, s.m.speechStart
+	s.mu.Unlock()
+	state := agent.TurnState{Confirmed: confirmed, Text: text}
+	if r != nil && !r.uninterruptible && (r.speaking.Load() || r.paused()) {
+		state.AgentSpeaking = true
+		state.AgentSpeakingFor = time.Since(time.Unix(0, r.speakingSince.Load()))
+	}
+	if !start.IsZero() {
+		state.UserSpeaking = true
+		state.UserSpeakingFor = time.Since(start)
+	}
+	state.UserPending = s.endpoint.Pending() != ""
+	return state
+}
+
+// holdTurn waits, before an interruptible reply starts speaking, while
+// Config.TurnPolicy holds it for the user speaking. It reports false if
+// the reply was canceled first.
+func (s *Session) holdTurn(r *response) bool {
+	if r.uninterruptible {
+		return true
+	}
+	for {
+		state := s.turnState(false, "")
+		if (!state.UserSpeaking && !state.UserPending) || !s.policy.Hold(state) {
+			return true
+		}
+		s.mu.Lock()
+		quiet := s.quiet
+		s.mu.Unlock()
+		select {
+		case <-quiet:
+		case <-r.ctx.Done():
+			return false
+		}
+	}
+}
+
+// wakeHeld has replies held by holdTurn check again.
+func (s *Session) wakeHeld() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.quiet)
+	s.quiet = make(chan struct{})
+}
+
+// interrupt stops agent speech according to the interruption mode.
+// confirmed reports that the user said something or interrupted
+// explicitly, rather than voice activity alone; under
+// Config.InterruptionResume only a confirmed interruption cuts the reply,
+// and others pause it.
+func (s *Session) interrupt(confirmed bool) {
+	s.mu.Lock()
+	r := s.response
+	s.mu.Unlock()
+	if r == nil || r.uninterruptible {
+		return
+	}
+	if r.paused() {
+		if confirmed && r.ctx.Err() == nil {
+			r.cancel()
+			s.emit(agent.EventInterruptionConfirmed, nil, nil)
+		}
+		return
+	}
+	if !r.speaking.Load() {
+		return
+	}
+	switch s.config.InterruptionMode {
+	case agent.InterruptDisabled:
+		return
+	case agent.InterruptAfterSentence:
+		if r.stopAfterClause.Swap(true) {
+			return
+		}
+	default:
+		if r.ctx.Err() != nil {
+			return
+		}
+		if resume := s.config.InterruptionResume; resume != nil && !confirmed {
+			s.pause(r, *resume)
+			break
+		}
+		r.cancel()
+	}
+	s.mu.Lock()
+	s.m.interruptions++
+	s.mu.Unlock()
+	s.emit(agent.EventInterruption, nil, nil)
+	if s.config.InterruptionResume != nil && r.ctx.Err() != nil {
+		s.emit(agent.EventInterruptionConfirmed, nil, nil)
+	}
+}
+
+// pause silences a reply until its resume window passes without a user
+// utterance.
+func (s *Session) pause(r *response, config agent.InterruptionResumeConfig) {
+	window := config.Window
+	if window <= 0 {
+		window = 1500 * time.Millisecond
+	}
+	r.pauseMu.Lock()
+	r.resumed, r.pausedAt, r.restart = make(chan struct{}), time.Now(), config.RestartClause
+	r.pauseMu.Unlock()
+	s.speechEnded(r)
+
+	var check func()
+	check = func() {
+		if r.ctx.Err() != nil {
+			return
+		}
+		s.mu.Lock()
+		talking := !s.m.speechStart.IsZero()
+		s.mu.Unlock()
+		if talking {
+			time.AfterFunc(window, check)
+			return
+		}
+		r.pauseMu.Lock()
+		resumed := agent.InterruptionResumed{Clause: r.clause, Paused: time.Since(r.pausedAt), Restarted: r.restart}
+		close(r.resumed)
+		r.resumed = nil
+		r.pauseMu.Unlock()
+		s.mu.Lock()
+		s.m.falseInterruptions++
+		s.mu.Unlock()
+		s.emit(agent.EventInterruptionResumed, resumed, nil)
+	}
+	time.AfterFunc(window, check)
+}
+
+// paused reports whether the reply is paused by an interruption.
+func (r *response) paused() bool {
+	r.pauseMu.Lock()
+	defer r.pauseMu.Unlock()
+	return r.resumed != nil
+}
+
+// waitResume blocks while the reply is paused. It reports false if the
+// reply was canceled, and restart if the clause is to be spoken again.
+func (s *Session) waitResume(r *response) (ok, restart bool) {
+	r.pauseMu.Lock()
+	ch, restart := r.resumed, r.restart
+	r.pauseMu.Unlock()
+	if ch == nil {
+		return r.ctx.Err() == nil, false
+	}
+	select {
+	case <-ch:
+		if !restart {
+			s.speechStarted(r)
+		}
+		return true, restart
+	case <-r.ctx.Done():
+		return false, false
+	}
+}
+
+// userTurn records a user turn and starts the agent's reply.
+func (s *Session) userTurn(turn agent.Turn) {
+	text, event := agent.ModerateInput(s.ctx, s.config, turn.Text)
+	if event != nil {
+		s.emit(agent.EventModeration, *event, nil)
+	}
+	if text == "" {
+		return
+	}
+	turn.Text = text
+	if s.config.SpeakerLabels {
+		text = turn.Label()
+	}
+
+	s.mu.Lock()
+	turn = s.recordLocked(turn)
+	s.history = append(s.history, agent.Message{Role: agent.RoleUser, Content: text})
+	s.mu.Unlock()
+	s.emit(agent.EventUserTranscript, turn, nil)
+
+	if s.consentInput(consentReply{text: turn.Text}) {
+		return
+	}
+	if s.stopping.Load() || s.ending.Load() || s.paused.Load() {
+		// Final transcripts flushed by Stop or Pause, or heard while the
+		// closing message plays, are recorded, not answered.
+		return
+	}
+	knowledge := s.retrieve(turn.Text)
+	s.startResponse(false, func(r *response) { s.reply(r, knowledge) })
+}
+
+// startResponse supersedes any reply in progress with a new one. Replies
+// run one at a time, in order.
+func (s *Session) startResponse(uninterruptible bool, run func(*response)) *response {
+	r := s.newResponse(uninterruptible)
+	s.mu.Lock()
+	r.prev = s.response
+	s.response = r
+	s.mu.Unlock()
+	if r.prev != nil {
+		r.prev.cancel()
+	}
+	s.runResponse(r, run)
+	return r
+}
+
+// queueResponse starts a reply once none is in progress, rather than
+// superseding it.
+func (s *Session) queueResponse(ctx context.Context, uninterruptible bool, run func(*response)) (*response, error) {
+	for {
+		s.mu.Lock()
+		cur := s.response
+		if cur == nil {
+			r := s.newResponse(uninterruptible)
+			s.response = r
+			s.mu.Unlock()
+			s.runResponse(r, run)
+			return r, nil
+		}
+		s.mu.Unlock()
+		select {
+		case <-cur.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.done:
+			return nil, ErrSessionClosed
+		}
+	}
+}
+
+func (s *Session) newResponse(uninterruptible bool) *response {
+	ctx, cancel := context.WithCancel(s.ctx)
+	if d := s.config.MaxTurnDuration; d > 0 {
+		ctx, cancel = context.WithTimeoutCause(s.ctx, d, errTurnTimeout)
+	}
+	return &response{
+		ctx:             ctx,
+		cancel:          cancel,
+		done:            make(chan struct{}),
+		userEnd:         time.Now(),
+		uninterruptible: uninterruptible,
+	}
+}
+
+// runResponse runs r, once the reply it superseded has finished. It
+// waits for a worker of the provider's pool; a reply canceled meanwhile
+// ends without running.
+func (s *Session) runResponse(r *response, run func(*response)) {
+	s.wg.Add(1)
+	started := s.p.pool.run(r.ctx, func() {
+		defer s.wg.Done()
+		defer close(r.done)
+		defer r.cancel()
+		if r.prev != nil {
+			<-r.prev.done
+			r.prev = nil
+		}
+		run(r)
+		s.clearResponse(r)
+		if errors.Is(context.Cause(r.ctx), errTurnTimeout) && !r.completed.Load() {
+			s.emit(agent.EventLimitReached, agent.LimitEvent{Limit: agent.LimitTurnDuration, Usage: s.Metrics().Usage}, nil)
+		}
+	})
+	if !started {
+		s.clearResponse(r)
+		r.cancel()
+		close(r.done)
+		s.wg.Done()
+	}
+}
+
+// clearResponse unsets r as the reply in progress.
+func (s *Session) clearResponse(r *response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.response == r {
+		s.response = nil
+	}
+}
+
+// errTurnTimeout is the cancellation cause of a reply over
+// Config.MaxTurnDuration.
+var errTurnTimeout = errors.New("custom: turn duration exceeded")
+
+// closingTimeout bounds how long the closing message may take when a
+// limit ends the session.
+const closingTimeout = 30 * time.Second
+
+// endSession stops the session for a reached limit: the reply in progress
+// is cut, Config.ClosingMessage is spoken, and Stop lets it finish. Only
+// the first call has an effect; it blocks until the session has stopped.
+func (s *Session) endSession(limit agent.Limit) {
+	if s.stopping.Load() || s.ending.Swap(true) {
+		return
+	}
+	s.emit(agent.EventLimitReached, agent.LimitEvent{Limit: limit, Usage: s.Metrics().Usage}, nil)
+	if text := s.config.ClosingMessage; text != "" {
+		s.startResponse(true, func(r *response) { s.say(r, text) })
+	} else {
+		s.mu.Lock()
+		r := s.response
+		s.mu.Unlock()
+		if r != nil {
+			r.cancel()
+		}
+	}
+	ctx, cancel := context.WithTimeout(agent.WithStopMode(context.Background(), agent.StopFinishUtterance), closingTimeout)
+	defer cancel()
+	_ = s.Stop(ctx)
+}
+
+// Say speaks text without the LLM, implementing agent.Sayer, after any
+// reply in progress.
+func (s *Session) Say(ctx context.Context, text string, config agent.SayConfig) error {
+	if s.stopping.Load() {
+		return ErrSessionClosed
+	}
+	if s.paused.Load() {
+		return ErrPaused
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	r, err := s.queueResponse(ctx, config.Uninterruptible, func(r *response) {
+		if len(config.Audio) == 0 {
+			s.say(r, text)
+			return
+		}
+		if spoken := s.playAudio(r, config.Audio, text); spoken != "" {
+			s.recordAgent(agent.Turn{Role: "agent", Text: spoken, Timestamp: time.Now()})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, r.cancel)
+	defer stop()
+	<-r.done
+	return ctx.Err()
+}
+
+// say speaks fixed text as an agent turn.
+func (s *Session) say(r *response, text string) {
+	clauses := make(chan string, 1)
+	clauses <- text
+	close(clauses)
+	if spoken := s.speak(r, clauses); spoken != "" {
+		s.recordAgent(agent.Turn{Role: "agent", Text: spoken, Timestamp: time.Now()})
+	}
+}
+
+// recordAgent adds fixed agent speech to the transcript and history.
+func (s *Session) recordAgent(turn agent.Turn) {
+	s.mu.Lock()
+	turn = s.recordLocked(turn)
+	s.history = append(s.history, agent.Message{Role: agent.RoleAssistant, Content: turn.Text})
+	s.mu.Unlock()
+	s.emit(agent.EventAgentTranscript, turn, nil)
+}
+
+// addUsage records provider spend and ends the session if it exceeds
+// Config.Budget.
+func (s *Session) addUsage(add func(*agent.Usage)) {
+	s.mu.Lock()
+	add(&s.m.usage)
+	exceeded := s.config.Budget.Exceeded(s.config.Budget.Price(s.m.usage))
+	s.mu.Unlock()
+	if exceeded && !s.ending.Load() {
+		// Stop waits for the reply that is spending, so end from
+		// another goroutine.
+		go s.endSession(agent.LimitBudget)
+	}
+}
+
+// greet speaks the configured greeting once, as the first agent turn,
+// after the recording announcement if there is one.
+func (s *Session) greet() {
+	s.mu.Lock()
+	if s.greeted || s.stopping.Load() || s.ending.Load() {
+		s.mu.Unlock()
+		return
+	}
+	s.greeted = true
+	consent := s.consentDone
+	s.mu.Unlock()
+	if consent == nil {
+		s.speakGreeting(s.config.Greeting)
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case <-consent:
+			s.speakGreeting(s.config.Greeting)
+		case <-s.done:
+		}
+	}()
+}
+
+// speakGreeting speaks g as an agent turn.
+func (s *Session) speakGreeting(g agent.Greeting) {
+	text := g.Render(s.config.Metadata)
+	s.startResponse(g.Uninterruptible, func(r *response) {
+		var spoken string
+		if len(g.Audio) > 0 {
+			spoken = s.playAudio(r, g.Audio, text)
+		} else {
+			clauses := make(chan string, 1)
+			clauses <- text
+			close(clauses)
+			spoken = s.speak(r, clauses)
+		}
+		if spoken == "" {
+			return
+		}
+		s.recordAgent(agent.GreetingTurn(spoken))
+	})
+}
+
+// playAudio plays pre-synthesized audio in the session encoding frame by
+// frame, returning text if the audio was played to the end.
+func (s *Session) playAudio(r *response, data []byte, text string) string {
+	if s.config.TextOnly {
+		r.completed.Store(true)
+		return text
+	}
+	framer, _ := audio.NewFramer(s.encoding, s.rate, s.config.FrameDuration)
+	frames, _ := framer.Write(data)
+	if rest, _ := framer.Flush(); len(rest) > 0 {
+		frames = append(frames, rest)
+	}
+	if s.gate != nil {
+		s.gate.AgentText(text)
+	}
+	r.pauseMu.Lock()
+	r.clause = text
+	r.pauseMu.Unlock()
+	if !s.holdTurn(r) {
+		return ""
+	}
+	s.speechStarted(r)
+	defer s.speechEnded(r)
+	for i := 0; i < len(frames); i++ {
+		if r.ctx.Err() != nil || r.stopAfterClause.Load() {
+			return ""
+		}
+		ok, restart := s.waitResume(r)
+		if !ok {
+			return ""
+		}
+		if restart {
+			i = 0
+			s.speechStarted(r)
+		}
+		frame := frames[i]
+		// A restart after a pause sends the frames again, so the reader
+		// gets copies it may release.
+		if !s.sendAudio(r.ctx, append(audio.GetBuffer(len(frame))[:0], frame...)) {
+			return ""
+		}
+		s.addAgentSpeech(len(frame))
+	}
+	r.completed.Store(true)
+	return text
+}
+
+// retrieve starts Config.Retriever for a user turn, returning a channel
+// that delivers its snippets, or none if it fails. It returns nil without
+// a Retriever.
+func (s *Session) retrieve(text string) <-chan []agent.ContextSnippet {
+	if s.config.Retriever == nil {
+		return nil
+	}
+	ch := make(chan []agent.ContextSnippet, 1)
+	s.wg.Add(1)
+	s.p.pool.spawn(func() {
+		defer s.wg.Done()
+		start := time.Now()
+		snippets, err := agent.Retrieve(s.ctx, s.config, text)
+		s.mu.Lock()
+		s.m.retrievalLatency += time.Since(start)
+		s.m.retrievalCount++
+		s.mu.Unlock()
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.emit(agent.EventError, nil, fmt.Errorf("custom: retrieval: %w", err))
+			}
+			snippets = nil
+		}
+		ch <- snippets
+	})
+	return ch
+}
+
+// reply runs LLM rounds for the latest user turn, speaking text as it
+// streams and executing tool calls between rounds. knowledge, if not nil,
+// delivers the snippets retrieved for the turn.
+func (s *Session) reply(r *response, knowledge <-chan []agent.ContextSnippet) {
+	s.emit(agent.EventAgentThinking, nil, nil)
+	turn := agent.Turn{Role: "agent", Timestamp: time.Now()}
+	var spoken strings.Builder
+
+	// The whole reply runs under the persona it started with.
+	s.mu.Lock()
+	prompt, tools := s.prompt, s.tools
+	s.mu.Unlock()
+
+	var snippets []agent.ContextSnippet
+	if knowledge != nil {
+		select {
+		case snippets = <-knowledge:
+		case <-r.ctx.Done():
+		}
+	}
+
+	for round := 0; round < s.p.opts.maxToolRounds && r.ctx.Err() == nil; round++ {
+		s.mu.Lock()
+		messages := make([]agent.Message, 0, len(s.history)+1)
+		if prompt != "" {
+			messages = append(messages, agent.Message{Role: agent.RoleSystem, Content: prompt})
+		}
+		if len(snippets) > 0 {
+			messages = append(messages, agent.KnowledgeMessage(snippets))
+		}
+		messages = append(messages, s.history...)
+		s.mu.Unlock()
+
+		requested := time.Now()
+		chunks, err := s.p.llm.Stream(r.ctx, messages, tools)
+		if err != nil {
+			if r.ctx.Err() == nil {
+				s.emit(agent.EventError, nil, err)
+			}
+			break
+		}
+
+		// Tokens feed the aggregator while earlier clauses are spoken.
+		tokens := make(chan string)
+		language := s.p.opts.synthesis.Language
+		if language == "" {
+			language = s.config.Language
+		}
+		clauses := (&tts.SentenceAggregator{Language: language}).Stream(r.ctx, tokens)
+		said := make(chan string, 1)
+		s.p.pool.spawn(func() { said <- s.speak(r, clauses) })
+
+		var text strings.Builder
+		var calls []agent.LLMToolCall
+		first := true
+		cut := false
+	read:
+		for chunk := range chunks {
+			if chunk.Error != nil {
+				if r.ctx.Err() == nil {
+					s.emit(agent.EventError, nil, chunk.Error)
+				}
+				cut = true
+				break
+			}
+			if first {
+				first = false
+				latency := time.Since(requested)
+				if round == 0 {
+					turn.LLMLatencyMs = int(latency.Milliseconds())
+				}
+				s.mu.Lock()
+				s.m.llmLatency += latency
+				s.m.llmCount++
+				s.mu.Unlock()
+			}
+			if u := chunk.Usage; u != nil {
+				s.addUsage(func(usage *agent.Usage) {
+					usage.LLMInputTokens += u.InputTokens
+					usage.LLMOutputTokens += u.OutputTokens
+				})
+			}
+			calls = append(calls, chunk.ToolCalls...)
+			if chunk.Text == "" {
+				continue
+			}
+			text.WriteString(chunk.Text)
+			select {
+			case tokens <- chunk.Text:
+			case <-r.ctx.Done():
+				cut = true
+				break read
+			}
+		}
+		// A stream closed by the reply's end may have been cut short.
+		cut = cut || r.ctx.Err() != nil
+		close(tokens)
+		s.p.pool.spawn(func() {
+			for range chunks {
+			}
+		})
+		if part := <-said; part != "" {
+			if spoken.Len() > 0 {
+				spoken.WriteByte(' ')
+			}
+			spoken.WriteString(part)
+		}
+		if cut || len(calls) > 0 {
+			// speak delivered what it was given, but not the whole reply.
+			r.completed.Store(false)
+		}
+
+		s.mu.Lock()
+		if text.Len() > 0 || len(calls) > 0 {
+			content := text.String()
+			if r.ctx.Err() != nil {
+				// Keep only what the caller actually heard.
+				content = spoken.String()
+			}
+			s.history = append(s.history, agent.Message{Role: agent.RoleAssistant, Content: content, ToolCalls: calls})
+		}
+		s.mu.Unlock()
+		if len(calls) == 0 || r.ctx.Err() != nil {
+			break
+		}
+		turn.ToolCalls = append(turn.ToolCalls, s.runTools(r.ctx, tools, calls)...)
+	}
+
+	turn.Text = spoken.String()
+	if turn.Text == "" && len(turn.ToolCalls) == 0 {
+		return
+	}
+	turn.DurationMs = int(time.Since(turn.Timestamp).Milliseconds())
+	turn.TimeToFirstAudioMs = int(time.Duration(r.ttfa.Load()).Milliseconds())
+	s.mu.Lock()
+	turn = s.recordLocked(turn)
+	s.mu.Unlock()
+	s.emit(agent.EventAgentTranscript, turn, nil)
+}
+
+// runTools executes tool calls and appends their results to the history.
+func (s *Session) runTools(ctx context.Context, tools []agent.Tool, calls []agent.LLMToolCall) []agent.ToolCall {
+	var records []agent.ToolCall
+	for _, call := range calls {
+		var record agent.ToolCall
+		if tool, ok := findTool(tools, call.Name); ok {
+			record = agent.RunTool(ctx, tool, call.Arguments)
+		} else {
+			record = agent.ToolCall{Name: call.Name, Arguments: call.Arguments, Error: "unknown tool " + call.Name}
+		}
+		content := record.Result
+		if record.Error != "" {
+			content = "error: " + record.Error
+		}
+		s.mu.Lock()
+		s.m.toolCalls++
+		s.history = append(s.history, agent.Message{
+			Role:        agent.RoleTool,
+			Content:     content,
+			ToolCallID:  call.ID,
+			Attachments: record.Attachments,
+		})
+		s.mu.Unlock()
+		s.emit(agent.EventToolCall, record, nil)
+		records = append(records, record)
+	}
+	return records
+}
+
+func findTool(tools []agent.Tool, name string) (agent.Tool, bool) {
+	for _, t := range tools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return agent.Tool{}, false
+}
+
+// speak synthesizes clauses in order and streams the audio, returning the
+// text that was fully spoken. It stops when the reply is canceled or,
+// under InterruptAfterSentence, after the clause being spoken, and marks
+// the reply completed if it spoke every clause.
+func (s *Session) speak(r *response, clauses <-chan string) string {
+	defer func() {
+		for range clauses {
+		}
+	}()
+	defer s.speechEnded(r)
+	s.voice.BeginUtterance()
+	defer s.voice.EndUtterance()
+	r.completed.Store(false)
+	var spoken []string
+	for clause := range clauses {
+		if r.ctx.Err() != nil {
+			return strings.Join(spoken, " ")
+		}
+		text, event := agent.ModerateOutput(r.ctx, s.config, clause)
+		if event != nil {
+			s.emit(agent.EventModeration, *event, nil)
+		}
+		if !s.speakClause(r, text, strings.Join(append(spoken, text), " ")) {
+			return strings.Join(spoken, " ")
+		}
+		spoken = append(spoken, text)
+		if event != nil || r.stopAfterClause.Load() {
+			// Nothing after a blocked clause is spoken, and an
+			// after-sentence interruption ends the reply here.
+			r.cancel()
+			return strings.Join(spoken, " ")
+		}
+	}
+	r.completed.Store(true)
+	return strings.Join(spoken, " ")
+}
+
+// speakClause synthesizes one clause, reporting whether it was played to
+// the end.
+func (s *Session) speakClause(r *response, text, soFar string) bool {
+	if s.config.TextOnly {
+		// The reply is delivered as text in EventAgentTranscript.
+		return r.ctx.Err() == nil
+	}
+	s.mu.Lock()
+	base := s.p.opts.synthesis
+	if s.config.VoiceID != "" {
+		base.VoiceID = s.config.VoiceID
+	}
+	if base.Language == "" {
+		base.Language = s.config.Language
+	}
+	base.Style, base.StyleDegree = s.style, s.styleDegree
+	s.mu.Unlock()
+	base.OutputFormat, base.SampleRate = "pcm", s.ttsRate
+	config := agent.ApplyProsody(base, agent.Turn{Role: "agent", Text: soFar}, s.config.Prosody)
+
+	if s.gate != nil {
+		s.gate.AgentText(text)
+	}
+	s.addUsage(func(u *agent.Usage) { u.TTSCharacters += utf8.RuneCountInString(text) })
+	r.pauseMu.Lock()
+	r.clause = text
+	r.pauseMu.Unlock()
+	requested := time.Now()
+	ctx, cancel := context.WithCancel(tts.WithVoiceContinuity(r.ctx, s.voice))
+	defer cancel()
+	stream, err := s.p.tts.SynthesizeStream(ctx, text, config)
+	if err != nil {
+		if r.ctx.Err() == nil {
+			s.emit(agent.EventError, nil, err)
+		}
+		return false
+	}
+	defer func() {
+		for range stream {
+		}
+	}()
+	var resampler *audio.Resampler
+	if s.ttsRate != s.rate {
+		// Checked by negotiateRates.
+		resampler, _ = audio.NewResampler(s.ttsRate, s.rate)
+	}
+	var carry []byte
+	first, final := true, false
+	for chunk := range stream {
+		if chunk.Error != nil {
+			if r.ctx.Err() == nil {
+				s.emit(agent.EventError, nil, chunk.Error)
+			}
+			return false
+		}
+		// The final chunk a canceled stream ends with is not the
+		// provider's.
+		final = chunk.IsFinal && r.ctx.Err() == nil
+		if len(chunk.Audio) == 0 {
+			continue
+		}
+		ok, restart := s.waitResume(r)
+		if !ok {
+			return false
+		}
+		if restart {
+			cancel()
+			for range stream {
+			}
+			return s.speakClause(r, text, soFar)
+		}
+		if first {
+			first = false
+			if !r.speaking.Load() && !s.holdTurn(r) {
+				return false
+			}
+			d := time.Since(requested)
+			provider := s.voice.Provider()
+			s.mu.Lock()
+			s.m.ttsLatency += d
+			s.m.ttsCount++
+			if s.m.ttsByProvider == nil {
+				s.m.ttsByProvider = make(map[string]latency)
+			}
+			l := s.m.ttsByProvider[provider]
+			s.m.ttsByProvider[provider] = latency{l.total + d, l.count + 1}
+			s.mu.Unlock()
+			s.speechStarted(r)
+		}
+		// Chunks need not end on a sample: carry a trailing odd byte
+		// over to the next.
+		pcm := chunk.Audio
+		if len(carry) > 0 {
+			pcm = slices.Concat(carry, pcm)
+		}
+		odd := len(pcm) % audio.BytesPerSample
+		carry = slices.Clone(pcm[len(pcm)-odd:])
+		pcm = pcm[:len(pcm)-odd]
+		if resampler != nil {
+			pcm = resampler.ProcessBytes(pcm)
+		}
+		if !s.sendSpeech(r, pcm) {
+			return false
+		}
+	}
+	if resampler != nil && r.ctx.Err() == nil {
+		if !s.sendSpeech(r, resampler.FlushBytes()) {
+			return false
+		}
+	}
+	// Once the final chunk is sent, the clause has been played even if
+	// the reply's time ran out just after.
+	return final || r.ctx.Err() == nil
+}
+
+// sendSpeech sends PCM at the session rate as agent speech.
+func (s *Session) sendSpeech(r *response, pcm []byte) bool {
+	if len(pcm) == 0 {
+		return true
+	}
+	if gain := math.Float64frombits(s.gain.Load()); gain != 1 {
+		pcm = audio.ApplyGain(pcm, gain)
+	}
+	out := s.appendEncode(audio.GetBuffer(len(pcm) * audio.SampleSize(s.encoding) / audio.BytesPerSample)[:0], pcm)
+	n := len(out)
+	if !s.sendAudio(r.ctx, out) {
+		return false
+	}
+	s.addAgentSpeech(n)
+	return true
+}
+
+// speechStarted marks the start of agent audio for a reply, recording
+// time-to-first-audio on the reply's first audio.
+func (s *Session) speechStarted(r *response) {
+	if r.speaking.Swap(true) {
+		return
+	}
+	r.speakingSince.Store(time.Now().UnixNano())
+	if s.gate != nil {
+		s.gate.SpeechStarted()
+	}
+	if s.ducker != nil {
+		// A background source that has ended needs no ducking.
+		_ = s.ducker.Duck()
+	}
+	if r.ttfa.Load() == 0 {
+		d := max(time.Since(r.userEnd), 1)
+		r.ttfa.Store(int64(d))
+		s.mu.Lock()
+		s.m.ttfa = d
+		s.m.ttfaTotal += d
+		s.m.ttfaCount++
+		s.mu.Unlock()
+	}
+	s.emit(agent.EventAgentSpeechStart, nil, nil)
+}
+
+func (s *Session) speechEnded(r *response) {
+	if r.speaking.Swap(false) {
+		if s.gate != nil {
+			s.gate.SpeechEnded()
+		}
+		if s.ducker != nil {
+			_ = s.ducker.Restore()
+		}
+		s.emit(agent.EventAgentSpeechEnd, nil, nil)
+	}
+}
+
+// SetOutputGain sets the level of agent speech in dB from the next chunk,
+// implementing agent.OutputGainSetter.
+func (s *Session) SetOutputGain(db float64) {
+	s.gain.Store(math.Float64bits(audio.DBToGain(db)))
+}
+
+// addAgentSpeech counts n bytes of session audio as agent speech.
+func (s *Session) addAgentSpeech(n int) {
+	d := time.Duration(n/audio.SampleSize(s.encoding)) * time.Second / time.Duration(s.rate)
+	s.mu.Lock()
+	s.m.agentSpeech += d
+	s.mu.Unlock()
+}
@@ -0,0 +1,37 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+func TestSnapshotContextFollowsHistory(t *testing.T) {
+	s, _ := textTurn(t, agent.Config{}, "When do you open?", "We open at nine.")
+
+	// Replace the history with a summary, as a truncating LLM context
+	// would.
+	s.mu.Lock()
+	s.history = []agent.Message{
+		{Role: agent.RoleSystem, Content: "The caller asked about opening hours."},
+		s.history[len(s.history)-1],
+	}
+	s.mu.Unlock()
+
+	snap := s.Snapshot()
+	if len(snap.Transcript) != 2 {
+		t.Fatalf("transcript %+v, want both turns", snap.Transcript)
+	}
+	want := []agent.Turn{
+		{Role: "system", Text: "The caller asked about opening hours."},
+		{Role: "agent", Text: "We open at nine."},
+	}
+	if len(snap.Context) != len(want) {
+		t.Fatalf("context %+v, want %+v", snap.Context, want)
+	}
+	for i, turn := range snap.Context {
+		if turn.Role != want[i].Role || turn.Text != want[i].Text {
+			t.Errorf("context turn %d = %s %q, want %s %q", i, turn.Role, turn.Text, want[i].Role, want[i].Text)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package custom
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/agent/agenttest"
+	"github.com/agentplexus/omnivoice/stt"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// flushingSTT is a streaming STT provider that hears nothing until its
+// stream is closed, then delivers final as a last transcript. With hang
+// set, closing the stream delivers nothing and leaves the events open
+// until the stream's context ends.
+type flushingSTT struct {
+	agenttest.ScriptedSTT
+	final string
+	hang  bool
+}
+
+func (p *flushingSTT) TranscribeStream(ctx context.Context, config stt.TranscriptionConfig) (io.WriteCloser, <-chan stt.StreamEvent, error) {
+	w := &flushingStream{final: p.final, hang: p.hang, events: make(chan stt.StreamEvent, 1)}
+	go func() {
+		<-ctx.Done()
+		w.end()
+	}()
+	return w, w.events, nil
+}
+
+type flushingStream struct {
+	final  string
+	hang   bool
+	events chan stt.StreamEvent
+	once   sync.Once
+}
+
+func (w *flushingStream) Write(p []byte) (int, error) { return len(p), nil }
+
+func (w *flushingStream) Close() error {
+	if !w.hang {
+		w.end()
+	}
+	return nil
+}
+
+func (w *flushingStream) end() {
+	w.once.Do(func() {
+		if w.final != "" {
+			w.events <- stt.StreamEvent{Type: stt.EventTranscript, Transcript: w.final, IsFinal: true}
+		}
+		close(w.events)
+	})
+}
+
+// speakingSession starts a session greeting the caller at a real-time
+// pace, returning once the greeting is being spoken, and a function
+// returning the events seen once the session has ended.
+func speakingSession(t *testing.T, provider stt.StreamingProvider, config agent.Config) (*Session, func() []agent.EventType) {
+	t.Helper()
+	p := New(stt.NewClient(provider), tts.NewClient(&agenttest.SilentTTS{}), agenttest.NewScriptedLLM())
+	config.FirstSpeaker = agent.FirstSpeakerAgent
+	config.Greeting = agent.Greeting{Text: "thanks for calling, let me read you our opening hours"}
+	config.AudioBuffer, config.AudioBufferPolicy = 2, agent.BufferBlock
+	session, err := p.CreateSession(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := session.(*Session)
+	go func() {
+		for chunk := range s.ReceiveAudio() {
+			time.Sleep(20 * time.Millisecond)
+			s.ReleaseAudio(chunk)
+		}
+	}()
+	speaking := make(chan struct{})
+	done := make(chan []agent.EventType)
+	go func() {
+		var seen []agent.EventType
+		for ev := range s.Events() {
+			if ev.Type == agent.EventAgentSpeechStart && !slices.Contains(seen, ev.Type) {
+				close(speaking)
+			}
+			seen = append(seen, ev.Type)
+		}
+		done <- seen
+	}()
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-speaking:
+	case <-time.After(2 * time.Second):
+		t.Fatal("greeting never started")
+	}
+	return s, func() []agent.EventType { return <-done }
+}
+
+func TestStopFinishUtteranceIgnoresFlushedTranscript(t *testing.T) {
+	s, events := speakingSession(t, &flushingSTT{final: "wait a second"}, agent.Config{StopMode: agent.StopFinishUtterance})
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	seen := events()
+	for _, typ := range []agent.EventType{agent.EventInterruption, agent.EventAgentInterrupted} {
+		if slices.Contains(seen, typ) {
+			t.Errorf("flushed transcript caused %s", typ)
+		}
+	}
+	if !slices.Contains(seen, agent.EventAgentSpeechEnd) {
+		t.Error("greeting did not finish")
+	}
+}
+
+func TestStopBoundsSTTDrain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the STT drain timeout")
+	}
+	s, events := speakingSession(t, &flushingSTT{hang: true}, agent.Config{})
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(sttDrainTimeout + 3*stopEventTimeout):
+		t.Fatal("Stop hung on an STT stream that never ended")
+	}
+	events()
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DTMFInput is the Data of an EventDTMFInput event.
+type DTMFInput struct {
+	// Digits are the collected digits, without the terminator.
+	Digits string
+
+	// Terminated reports whether entry ended with the terminator, rather
+	// than by timeout or reaching the digit limit.
+	Terminated bool
+}
+
+// DTMFCollector buffers keypad digits into complete entries for
+// Config.DTMFAsInput. It is safe for concurrent use.
+type DTMFCollector struct {
+	terminator string
+	timeout    time.Duration
+	maxDigits  int
+	onInput    func(DTMFInput)
+
+	mu      sync.Mutex
+	digits  strings.Builder
+	timer   *time.Timer
+	gen     int
+	stopped bool
+}
+
+// NewDTMFCollector creates a collector using the DTMF settings of config.
+// onInput is called, from the caller's goroutine or a timer goroutine,
+// each time an entry completes.
+func NewDTMFCollector(config Config, onInput func(DTMFInput)) *DTMFCollector {
+	c := &DTMFCollector{
+		terminator: config.DTMFTerminator,
+		timeout:    config.DTMFTimeout,
+		maxDigits:  config.DTMFMaxDigits,
+		onInput:    onInput,
+	}
+	if c.terminator == "" {
+		c.terminator = "#"
+	}
+	if c.timeout <= 0 {
+		c.timeout = 3 * time.Second
+	}
+	return c
+}
+
+// Add records digits. Entry completes on the terminator, at the digit
+// limit, or after the timeout since the last digit.
+func (c *DTMFCollector) Add(digits string) {
+	for _, d := range digits {
+		if input, ok := c.add(string(d)); ok {
+			c.onInput(input)
+		}
+	}
+}
+
+func (c *DTMFCollector) add(d string) (DTMFInput, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return DTMFInput{}, false
+	}
+	c.digits.WriteString(d)
+	if pending := c.digits.String(); strings.HasSuffix(pending, c.terminator) {
+		c.digits.Reset()
+		c.digits.WriteString(strings.TrimSuffix(pending, c.terminator))
+		return c.takeLocked(true)
+	}
+	if c.maxDigits > 0 && c.digits.Len() >= c.maxDigits {
+		return c.takeLocked(false)
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.gen++
+	gen := c.gen
+	c.timer = time.AfterFunc(c.timeout, func() { c.expire(gen) })
+	return DTMFInput{}, false
+}
+
+func (c *DTMFCollector) expire(gen int) {
+	c.mu.Lock()
+	if gen != c.gen || c.stopped {
+		// A later digit restarted the timeout.
+		c.mu.Unlock()
+		return
+	}
+	input, ok := c.takeLocked(false)
+	c.mu.Unlock()
+	if ok {
+		c.onInput(input)
+	}
+}
+
+// takeLocked completes the pending entry. A bare terminator completes an
+// empty entry so callers can use "#" alone to skip a prompt.
+func (c *DTMFCollector) takeLocked(terminated bool) (DTMFInput, bool) {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.gen++
+	if c.digits.Len() == 0 && !terminated {
+		return DTMFInput{}, false
+	}
+	input := DTMFInput{Digits: c.digits.String(), Terminated: terminated}
+	c.digits.Reset()
+	return input, true
+}
+
+// Pending returns digits collected so far in the current entry.
+func (c *DTMFCollector) Pending() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.digits.String()
+}
+
+// Stop discards pending digits and ignores further input.
+func (c *DTMFCollector) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.digits.Reset()
+}
+
+// DTMFTurn builds the user turn for a completed keypad entry. Text is what
+// the LLM sees; the raw digits are kept in Turn.DTMF.
+func DTMFTurn(input DTMFInput) Turn {
+	text := "The caller entered " + input.Digits + " on the keypad."
+	if input.Digits == "" {
+		text = "The caller pressed the terminator key without entering digits."
+	}
+	return Turn{Role: "user", Text: text, Timestamp: time.Now(), DTMF: input.Digits}
+}
+
+// DTMFInterrupts reports whether a keypress should interrupt agent speech
+// under mode.
+func DTMFInterrupts(mode InterruptionMode) bool {
+	return mode != InterruptDisabled
+}
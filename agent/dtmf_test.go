@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDTMFCollectorTerminator(t *testing.T) {
+	tests := []struct {
+		terminator string
+		keys       string
+		want       []DTMFInput
+	}{
+		{"", "123#", []DTMFInput{{Digits: "123", Terminated: true}}},
+		{"", "#", []DTMFInput{{Terminated: true}}},
+		{"**", "12**", []DTMFInput{{Digits: "12", Terminated: true}}},
+		{"**", "1*2**", []DTMFInput{{Digits: "1*2", Terminated: true}}},
+		{"**", "**", []DTMFInput{{Terminated: true}}},
+		{"*#", "4*#5*#", []DTMFInput{{Digits: "4", Terminated: true}, {Digits: "5", Terminated: true}}},
+	}
+	for _, tt := range tests {
+		var got []DTMFInput
+		c := NewDTMFCollector(Config{DTMFTerminator: tt.terminator, DTMFTimeout: time.Hour}, func(in DTMFInput) {
+			got = append(got, in)
+		})
+		c.Add(tt.keys)
+		c.Stop()
+		if len(got) != len(tt.want) {
+			t.Errorf("terminator %q, keys %q: got %+v, want %+v", tt.terminator, tt.keys, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("terminator %q, keys %q: got %+v, want %+v", tt.terminator, tt.keys, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDTMFCollectorMaxDigits(t *testing.T) {
+	var got []DTMFInput
+	c := NewDTMFCollector(Config{DTMFMaxDigits: 3, DTMFTimeout: time.Hour}, func(in DTMFInput) {
+		got = append(got, in)
+	})
+	c.Add("12345")
+	if len(got) != 1 || got[0] != (DTMFInput{Digits: "123"}) {
+		t.Errorf("got %+v, want one entry of 123", got)
+	}
+	if p := c.Pending(); p != "45" {
+		t.Errorf("Pending() = %q, want 45", p)
+	}
+	c.Stop()
+}
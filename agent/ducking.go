@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// DuckingConfig configures a Ducker. Zero fields use the defaults.
+type DuckingConfig struct {
+	// Mixer plays the background source.
+	Mixer *audio.Mixer
+
+	// Source is the name of the background source in Mixer, such as hold
+	// music or ambience.
+	Source string
+
+	// Level is how far the source is turned down while the agent speaks,
+	// in dB. Defaults to 12.
+	Level float64
+
+	// Fade is how long the source takes to turn down and back up.
+	// Defaults to 150ms.
+	Fade time.Duration
+}
+
+// Ducker turns a background mixer source down while the agent speaks and
+// restores it afterward. Sessions with Config.Ducking drive it from agent
+// speech; it is safe for concurrent use.
+type Ducker struct {
+	config DuckingConfig
+
+	mu     sync.Mutex
+	ducked bool
+	gain   float64
+}
+
+// NewDucker creates a ducker.
+func NewDucker(config DuckingConfig) *Ducker {
+	if config.Level <= 0 {
+		config.Level = 12
+	}
+	if config.Fade <= 0 {
+		config.Fade = 150 * time.Millisecond
+	}
+	return &Ducker{config: config}
+}
+
+// Duck fades the source down by Level from its current gain. Ducking an
+// already ducked source is a no-op. It returns audio.ErrSourceNotFound if
+// the source is not playing.
+func (d *Ducker) Duck() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ducked {
+		return nil
+	}
+	gain, err := d.config.Mixer.Gain(d.config.Source)
+	if err != nil {
+		return err
+	}
+	if err := d.config.Mixer.FadeGain(d.config.Source, gain*audio.DBToGain(-d.config.Level), d.config.Fade); err != nil {
+		return err
+	}
+	d.ducked, d.gain = true, gain
+	return nil
+}
+
+// Restore fades the source back to its gain before Duck. Restoring a
+// source that is not ducked is a no-op.
+func (d *Ducker) Restore() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.ducked {
+		return nil
+	}
+	d.ducked = false
+	return d.config.Mixer.FadeGain(d.config.Source, d.gain, d.config.Fade)
+}
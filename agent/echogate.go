@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// EchoGateConfig configures an EchoGate. Zero fields use the defaults.
+//
+// The gate is a cheap alternative to Config.EchoCancellation: rather than
+// subtracting the agent's speech from caller audio, it distrusts caller
+// audio while the agent talks. That costs nearly no CPU and needs no
+// alignment with playback, but caller speech quieter than
+// BargeInThreshold is lost while the agent speaks, the first
+// BargeInDuration of an interruption reaches STT attenuated, and loud
+// echo can still pass as barge-in. Raising Attenuation and BargeInThreshold makes
+// self-interruption less likely and genuine interruptions harder.
+type EchoGateConfig struct {
+	// Attenuation is how far caller audio below BargeInThreshold is
+	// turned down while the agent speaks, in dB. Defaults to 20.
+	Attenuation float64
+
+	// BargeInThreshold is the RMS level (0.0-1.0) at which caller audio
+	// during agent speech counts as a genuine interruption and passes
+	// unchanged. Defaults to 0.08.
+	BargeInThreshold float64
+
+	// BargeInDuration is how long audio must stay above BargeInThreshold
+	// before it counts as barge-in, so echo peaks do not. Defaults to
+	// 120ms.
+	BargeInDuration time.Duration
+
+	// Tail extends gating past the end of agent speech to cover playback
+	// buffers and room echo. Defaults to 300ms.
+	Tail time.Duration
+
+	// MatchRatio is the fraction of a transcript's words that must appear
+	// in the agent's recent speech for it to be suppressed as echo.
+	// Defaults to 0.6; 1 or more disables transcript suppression.
+	MatchRatio float64
+}
+
+// EchoGate suppresses the agent's own voice in caller audio: while the
+// agent is speaking, and for a short tail after, quiet caller audio is
+// attenuated and transcripts repeating the agent's words are flagged, so
+// the agent does not interrupt or answer itself. Loud, sustained caller
+// speech opens the gate as barge-in. It is safe for concurrent use.
+type EchoGate struct {
+	config EchoGateConfig
+	gain   float64
+
+	mu       sync.Mutex
+	speaking bool
+	ended    time.Time
+	loud     time.Duration
+	bargeIn  bool
+	words    map[string]bool
+}
+
+// echoTranscriptDelay is how much longer than Tail transcripts of agent
+// speech are expected to arrive.
+const echoTranscriptDelay = 2 * time.Second
+
+// NewEchoGate creates a gate.
+func NewEchoGate(config EchoGateConfig) *EchoGate {
+	if config.Attenuation <= 0 {
+		config.Attenuation = 20
+	}
+	if config.BargeInThreshold <= 0 {
+		config.BargeInThreshold = 0.08
+	}
+	if config.BargeInDuration <= 0 {
+		config.BargeInDuration = 120 * time.Millisecond
+	}
+	if config.Tail <= 0 {
+		config.Tail = 300 * time.Millisecond
+	}
+	if config.MatchRatio <= 0 {
+		config.MatchRatio = 0.6
+	}
+	return &EchoGate{
+		config: config,
+		gain:   math.Pow(10, -config.Attenuation/20),
+		words:  make(map[string]bool),
+	}
+}
+
+// SpeechStarted marks the start of agent speech (EventAgentSpeechStart).
+func (g *EchoGate) SpeechStarted() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.activeLocked() {
+		g.bargeIn = false
+		g.loud = 0
+	}
+	g.speaking = true
+}
+
+// SpeechEnded marks the end of agent speech (EventAgentSpeechEnd).
+func (g *EchoGate) SpeechEnded() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.speaking {
+		g.speaking = false
+		g.ended = time.Now()
+	}
+}
+
+// AgentText records text the agent is speaking, for Suppress. It may be
+// called before or after SpeechStarted. Words from earlier speech are
+// forgotten once transcripts of it are no longer expected.
+func (g *EchoGate) AgentText(text string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.recentLocked() {
+		clear(g.words)
+	}
+	for _, w := range echoWords(text) {
+		g.words[w] = true
+	}
+}
+
+// Active reports whether the gate is closed: the agent is speaking, or
+// just stopped, and the caller has not barged in.
+func (g *EchoGate) Active() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.activeLocked() && !g.bargeIn
+}
+
+func (g *EchoGate) activeLocked() bool {
+	return g.speaking || (!g.ended.IsZero() && time.Since(g.ended) < g.config.Tail)
+}
+
+// recentLocked reports whether transcripts of agent speech may still
+// arrive: final transcripts trail the audio, so the agent's words stay
+// matchable a little past the tail.
+func (g *EchoGate) recentLocked() bool {
+	return g.speaking || (!g.ended.IsZero() && time.Since(g.ended) < g.config.Tail+echoTranscriptDelay)
+}
+
+// Process gates a frame of 16-bit PCM caller audio at sampleRate in
+// place, returning it and whether this frame started a barge-in. Audio
+// passes unchanged when the gate is open.
+func (g *EchoGate) Process(pcm []byte, sampleRate int) ([]byte, bool) {
+	samples := audio.BytesToInt16(pcm)
+	level := audio.RMS(samples)
+
+	g.mu.Lock()
+	if !g.activeLocked() {
+		g.mu.Unlock()
+		return pcm, false
+	}
+	if g.bargeIn {
+		g.mu.Unlock()
+		return pcm, false
+	}
+	if level >= g.config.BargeInThreshold {
+		if sampleRate > 0 {
+			g.loud += time.Duration(len(samples)) * time.Second / time.Duration(sampleRate)
+		}
+		g.bargeIn = g.loud >= g.config.BargeInDuration
+	} else {
+		g.loud = 0
+	}
+	started := g.bargeIn
+	g.mu.Unlock()
+
+	if started {
+		return pcm, true
+	}
+	for i, s := range samples {
+		samples[i] = int16(float64(s) * g.gain)
+	}
+	copy(pcm, audio.Int16ToBytes(samples))
+	return pcm, false
+}
+
+// Suppress reports whether a caller transcript should be discarded as
+// the agent's own speech: it arrived during or shortly after agent speech
+// without a barge-in, and enough of its words were spoken by the agent.
+func (g *EchoGate) Suppress(transcript string) bool {
+	if g.config.MatchRatio >= 1 {
+		return false
+	}
+	words := echoWords(transcript)
+	if len(words) == 0 {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.recentLocked() || g.bargeIn {
+		return false
+	}
+	matched := 0
+	for _, w := range words {
+		if g.words[w] {
+			matched++
+		}
+	}
+	return float64(matched) >= g.config.MatchRatio*float64(len(words))
+}
+
+// echoWords splits text into lowercase words for matching.
+func echoWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
@@ -0,0 +1,36 @@
+package agent
+
+import "testing"
+
+func TestEchoGateSuppressesFirstClause(t *testing.T) {
+	g := NewEchoGate(EchoGateConfig{})
+	// Sessions register a clause's text before its first audio starts
+	// speech.
+	g.AgentText("Thanks for calling Acme support")
+	g.SpeechStarted()
+	if !g.Suppress("thanks for calling acme") {
+		t.Error("echo of the first clause was not suppressed")
+	}
+	if g.Suppress("I need to cancel my order") {
+		t.Error("caller speech suppressed as echo")
+	}
+}
+
+func TestEchoGateForgetsEarlierSpeech(t *testing.T) {
+	g := NewEchoGate(EchoGateConfig{})
+	g.AgentText("your order has shipped")
+	g.SpeechStarted()
+	g.SpeechEnded()
+	// Long after, a new reply starts: the old words no longer match.
+	g.mu.Lock()
+	g.ended = g.ended.Add(-g.config.Tail - echoTranscriptDelay)
+	g.mu.Unlock()
+	g.AgentText("anything else")
+	g.SpeechStarted()
+	if g.Suppress("your order has shipped") {
+		t.Error("words from earlier speech still suppressed")
+	}
+	if !g.Suppress("anything else") {
+		t.Error("current speech not suppressed")
+	}
+}
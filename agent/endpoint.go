@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointConfig configures how a session decides that the user has
+// finished an utterance (Config.Endpointing). The user stopping speaking
+// (EventUserSpeechEnd) is only a pause; the utterance is complete once
+// no more speech follows within the delay, and EventUtteranceComplete
+// then carries all of its final transcripts as one turn.
+type EndpointConfig struct {
+	// Delay is how long to wait for more speech after an utterance that
+	// ends a sentence, from when the user stops speaking or, if the STT
+	// does not report it, from the final transcript. Zero completes the
+	// utterance at once.
+	Delay time.Duration
+
+	// IncompleteDelay is the wait after an utterance that does not end
+	// with sentence punctuation, such as one trailing off mid-thought.
+	// Defaults to Delay.
+	IncompleteDelay time.Duration
+
+	// MaxDelay bounds the wait for the user to stop speaking after a
+	// final transcript, for STT that does not reliably report the end of
+	// speech. Defaults to 2 seconds.
+	MaxDelay time.Duration
+
+	// Manual emits EventUtteranceComplete without answering it, leaving
+	// turn-taking to the application, which answers with SendText when
+	// it decides the user is done.
+	Manual bool
+}
+
+// Endpointer joins the final transcripts of an utterance into one turn,
+// completing it as EndpointConfig describes. It is safe for concurrent
+// use.
+type Endpointer struct {
+	delay      time.Duration
+	incomplete time.Duration
+	maxDelay   time.Duration
+	onComplete func(Turn)
+
+	mu       sync.Mutex
+	pending  *Turn
+	speaking bool
+	timer    *time.Timer
+	gen      int
+	stopped  bool
+}
+
+// NewEndpointer creates an endpointer. onComplete is called, from the
+// caller's goroutine or a timer goroutine, with each completed utterance.
+func NewEndpointer(config EndpointConfig, onComplete func(Turn)) *Endpointer {
+	e := &Endpointer{
+		delay:      max(config.Delay, 0),
+		incomplete: config.IncompleteDelay,
+		maxDelay:   config.MaxDelay,
+		onComplete: onComplete,
+	}
+	if e.incomplete <= 0 {
+		e.incomplete = e.delay
+	}
+	if e.maxDelay <= 0 {
+		e.maxDelay = 2 * time.Second
+	}
+	return e
+}
+
+// SpeechStarted reports that the user started speaking, holding back
+// the pending utterance until they stop.
+func (e *Endpointer) SpeechStarted() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.speaking = true
+	if e.pending != nil {
+		e.waitLocked(e.maxDelay)
+	}
+}
+
+// SpeechEnded reports that the user stopped speaking, starting the wait
+// for the pending utterance to complete.
+func (e *Endpointer) SpeechEnded() {
+	e.mu.Lock()
+	e.speaking = false
+	turn, ok := e.armLocked()
+	e.mu.Unlock()
+	if ok {
+		e.onComplete(turn)
+	}
+}
+
+// Transcript adds a final transcript. A transcript from another speaker
+// completes the pending utterance first.
+func (e *Endpointer) Transcript(turn Turn) {
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return
+	}
+	var prev Turn
+	var switched bool
+	if e.pending != nil && e.pending.Speaker != turn.Speaker {
+		prev, switched = e.takeLocked()
+	}
+	if e.pending == nil {
+		e.pending = &turn
+	} else {
+		e.pending.Text += " " + turn.Text
+		e.pending.Words = append(e.pending.Words, turn.Words...)
+	}
+	var done Turn
+	var ok bool
+	if e.speaking {
+		e.waitLocked(e.maxDelay)
+	} else {
+		done, ok = e.armLocked()
+	}
+	e.mu.Unlock()
+	if switched {
+		e.onComplete(prev)
+	}
+	if ok {
+		e.onComplete(done)
+	}
+}
+
+// Pending returns the text of the utterance not yet complete.
+func (e *Endpointer) Pending() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pending == nil {
+		return ""
+	}
+	return e.pending.Text
+}
+
+// Flush completes the pending utterance at once.
+func (e *Endpointer) Flush() {
+	e.mu.Lock()
+	turn, ok := e.takeLocked()
+	e.mu.Unlock()
+	if ok {
+		e.onComplete(turn)
+	}
+}
+
+// Stop discards the pending utterance and ignores further input.
+func (e *Endpointer) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopped = true
+	e.takeLocked()
+}
+
+// armLocked starts the wait for the pending utterance, returning it if
+// it completes at once.
+func (e *Endpointer) armLocked() (Turn, bool) {
+	if e.pending == nil || e.stopped {
+		return Turn{}, false
+	}
+	d := e.incomplete
+	if endsSentence(e.pending.Text) {
+		d = e.delay
+	}
+	if d <= 0 {
+		return e.takeLocked()
+	}
+	e.waitLocked(d)
+	return Turn{}, false
+}
+
+// waitLocked (re)starts the timer completing the pending utterance.
+func (e *Endpointer) waitLocked(d time.Duration) {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.gen++
+	gen := e.gen
+	e.timer = time.AfterFunc(d, func() { e.expire(gen) })
+}
+
+func (e *Endpointer) expire(gen int) {
+	e.mu.Lock()
+	if gen != e.gen || e.stopped {
+		// Later speech restarted the wait.
+		e.mu.Unlock()
+		return
+	}
+	turn, ok := e.takeLocked()
+	e.mu.Unlock()
+	if ok {
+		e.onComplete(turn)
+	}
+}
+
+func (e *Endpointer) takeLocked() (Turn, bool) {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.gen++
+	if e.pending == nil {
+		return Turn{}, false
+	}
+	turn := *e.pending
+	e.pending = nil
+	return turn, true
+}
+
+// endsSentence reports whether text ends with sentence punctuation,
+// ignoring closing quotes and brackets.
+func endsSentence(text string) bool {
+	text = strings.TrimRight(text, " \t\n\"')]”’")
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "!")
+}
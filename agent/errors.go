@@ -0,0 +1,41 @@
+package agent
+
+import "errors"
+
+var (
+	// ErrSessionNotFound is returned when no session matches an ID or
+	// resume token.
+	ErrSessionNotFound = errors.New("agent: session not found")
+
+	// ErrSessionExpired is returned when resuming a session whose grace
+	// period has elapsed.
+	ErrSessionExpired = errors.New("agent: session expired")
+
+	// ErrSessionNotResumable is returned when suspending a session that
+	// does not implement ResumableSession.
+	ErrSessionNotResumable = errors.New("agent: session not resumable")
+
+	// ErrInvalidTool is returned when a tool definition is malformed.
+	ErrInvalidTool = errors.New("agent: invalid tool")
+
+	// ErrToolResultEncoding is returned when a structured tool result
+	// cannot be JSON-encoded.
+	ErrToolResultEncoding = errors.New("agent: cannot encode tool result")
+
+	// ErrInvalidPrompt is returned when a system prompt template is
+	// malformed or uses an undefined variable.
+	ErrInvalidPrompt = errors.New("agent: invalid system prompt")
+
+	// ErrAudioFormat is returned when the audio formats of a session and
+	// the components it connects disagree without a conversion between
+	// them.
+	ErrAudioFormat = errors.New("agent: audio format mismatch")
+
+	// ErrTooManySessions is returned when creating a session would exceed
+	// a provider's concurrent session limit.
+	ErrTooManySessions = errors.New("agent: too many sessions")
+
+	// ErrInputAudioOverflow is reported in an EventError when caller
+	// audio fills Config.InputAudioBuffer under BufferBlock.
+	ErrInputAudioOverflow = errors.New("agent: input audio buffer full")
+)
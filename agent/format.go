@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/transport"
+)
+
+// encodingAliases maps the names transports and providers use for raw
+// encodings to the audio package's.
+var encodingAliases = map[string]string{
+	"":         audio.EncodingPCM,
+	"pcm":      audio.EncodingPCM,
+	"pcm16":    audio.EncodingPCM,
+	"linear16": audio.EncodingPCM,
+	"l16":      audio.EncodingPCM,
+	"s16le":    audio.EncodingPCM,
+	"mulaw":    audio.EncodingMuLaw,
+	"ulaw":     audio.EncodingMuLaw,
+	"pcmu":     audio.EncodingMuLaw,
+	"g711":     audio.EncodingMuLaw,
+	"g711u":    audio.EncodingMuLaw,
+	"alaw":     audio.EncodingALaw,
+	"pcma":     audio.EncodingALaw,
+	"g711a":    audio.EncodingALaw,
+}
+
+// RawEncoding returns the audio package name of a raw encoding given any
+// of its common names ("pcmu", "ulaw", and "g711" are "mulaw"), and false
+// for compressed or unknown encodings such as Opus.
+func RawEncoding(name string) (string, bool) {
+	enc, ok := encodingAliases[strings.ToLower(name)]
+	return enc, ok
+}
+
+// MatchTransport returns config with its audio format taken from the
+// transport carrying the session's audio, and an error wrapping
+// ErrAudioFormat naming the disagreement if they cannot work together.
+//
+// Sessions convert between Config.AudioEncoding and the PCM their STT and
+// TTS use, so any raw transport encoding works once AudioEncoding and
+// SampleRate match it: unset fields are filled in, and set fields must
+// agree. Compressed transport encodings need a decoder between transport
+// and session, and multichannel transports a downmix; MatchTransport
+// reports which is missing rather than letting the session play noise.
+func MatchTransport(config Config, t transport.Config) (Config, error) {
+	enc, ok := RawEncoding(t.Encoding)
+	if !ok {
+		return config, fmt.Errorf("%w: transport encoding %q and agent AudioEncoding %q disagree: no %s decoder; use a pcm, mulaw, or alaw transport, or decode its audio before SendAudio",
+			ErrAudioFormat, t.Encoding, config.AudioEncoding, t.Encoding)
+	}
+	if config.AudioEncoding == "" {
+		config.AudioEncoding = enc
+	} else if want, _ := RawEncoding(config.AudioEncoding); want != enc {
+		return config, fmt.Errorf("%w: transport encoding %q and agent AudioEncoding %q disagree: no %s to %s conversion between transport and session; set AudioEncoding to %q or leave it empty",
+			ErrAudioFormat, t.Encoding, config.AudioEncoding, enc, want, enc)
+	}
+	if t.Channels > 1 {
+		return config, fmt.Errorf("%w: transport has %d channels and agent sessions are mono: no downmix; configure a mono transport",
+			ErrAudioFormat, t.Channels)
+	}
+	if t.SampleRate > 0 {
+		if config.SampleRate <= 0 {
+			config.SampleRate = t.SampleRate
+		} else if config.SampleRate != t.SampleRate {
+			return config, fmt.Errorf("%w: transport sample rate %d Hz and agent SampleRate %d Hz disagree: no resampling between them; set SampleRate to %d or leave it zero",
+				ErrAudioFormat, t.SampleRate, config.SampleRate, t.SampleRate)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FirstSpeaker controls who speaks first in a session.
+type FirstSpeaker string
+
+const (
+	// FirstSpeakerUser waits for the user to speak (the default).
+	FirstSpeakerUser FirstSpeaker = "user"
+
+	// FirstSpeakerAgent greets as soon as the session starts, for
+	// outbound calls.
+	FirstSpeakerAgent FirstSpeaker = "agent"
+
+	// FirstSpeakerAgentOnAnswer waits for AnswerReporter.Answered: it
+	// greets a human immediately and a voicemail after the beep.
+	FirstSpeakerAgentOnAnswer FirstSpeaker = "agent_on_answer"
+)
+
+// AnswerReporter is implemented by sessions that support
+// FirstSpeakerAgentOnAnswer.
+type AnswerReporter interface {
+	// Answered reports who answered an outbound call, from answering
+	// machine detection. With FirstSpeakerAgentOnAnswer it starts the
+	// greeting; see ShouldGreet.
+	Answered(by AnsweredBy) error
+}
+
+// AnsweredBy is the result of answering machine detection.
+type AnsweredBy string
+
+const (
+	// AnsweredByHuman indicates a person answered.
+	AnsweredByHuman AnsweredBy = "human"
+
+	// AnsweredByMachine indicates voicemail answered and the greeting is
+	// still playing.
+	AnsweredByMachine AnsweredBy = "machine"
+
+	// AnsweredByMachineBeep indicates the voicemail beep was heard and a
+	// message can be left.
+	AnsweredByMachineBeep AnsweredBy = "machine_beep"
+
+	// AnsweredByUnknown indicates detection was inconclusive.
+	AnsweredByUnknown AnsweredBy = "unknown"
+)
+
+// Greeting is the agent's opening turn.
+type Greeting struct {
+	// Text is the greeting text. It may contain placeholders for
+	// Config.Metadata, written {key} or {key|default}, e.g.
+	// "Hi {caller_name|there}, this is Acme calling."
+	Text string
+
+	// Audio is optional pre-synthesized greeting audio in the session's
+	// output format, played instead of synthesizing Text. Text is still
+	// recorded in the transcript.
+	Audio []byte
+
+	// Uninterruptible plays the greeting to the end even if the user
+	// speaks. By default the greeting follows Config.InterruptionMode.
+	Uninterruptible bool
+}
+
+// IsZero reports whether no greeting is configured.
+func (g Greeting) IsZero() bool {
+	return g.Text == "" && len(g.Audio) == 0
+}
+
+// Render fills the greeting's placeholders from metadata. Unknown keys
+// without a default render as empty, with doubled spaces collapsed; line
+// breaks and other whitespace are kept.
+func (g Greeting) Render(metadata map[string]string) string {
+	return renderPlaceholders(g.Text, metadata)
+}
+
+// doubledSpaces matches the runs of spaces an empty placeholder can leave.
+var doubledSpaces = regexp.MustCompile(`  +`)
+
+func renderPlaceholders(text string, metadata map[string]string) string {
+	var b strings.Builder
+	for {
+		open := strings.IndexByte(text, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(text[open:], '}')
+		if end < 0 {
+			break
+		}
+		b.WriteString(text[:open])
+		key, def, _ := strings.Cut(text[open+1:open+end], "|")
+		if v := metadata[strings.TrimSpace(key)]; v != "" {
+			b.WriteString(v)
+		} else {
+			b.WriteString(def)
+		}
+		text = text[open+end+1:]
+	}
+	b.WriteString(text)
+	return strings.Trim(doubledSpaces.ReplaceAllString(b.String(), " "), " ")
+}
+
+// ShouldGreet reports whether a session should speak its greeting now.
+// Sessions call it with an empty AnsweredBy at start, and again from
+// AnswerReporter.Answered.
+func ShouldGreet(config Config, by AnsweredBy) bool {
+	if config.Greeting.IsZero() {
+		return false
+	}
+	switch config.FirstSpeaker {
+	case FirstSpeakerAgent:
+		return by == ""
+	case FirstSpeakerAgentOnAnswer:
+		return by == AnsweredByHuman || by == AnsweredByMachineBeep || by == AnsweredByUnknown
+	}
+	return false
+}
+
+// GreetingTurn returns the first agent Turn for a rendered greeting.
+func GreetingTurn(text string) Turn {
+	return Turn{Role: "agent", Text: text, Timestamp: time.Now()}
+}
@@ -0,0 +1,24 @@
+package agent
+
+import "testing"
+
+func TestGreetingRender(t *testing.T) {
+	metadata := map[string]string{"caller_name": "Ana", "company": "Acme"}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hi {caller_name|there}, this is {company}.", "Hi Ana, this is Acme."},
+		{"Hi {missing|there}.", "Hi there."},
+		{"Hi {missing} and welcome.", "Hi and welcome."},
+		{"{missing} Hello.", "Hello."},
+		{"Hello.\n\nPress 1 for {company}.", "Hello.\n\nPress 1 for Acme."},
+		{"Line one\n{missing}\tindented", "Line one\n\tindented"},
+		{"Unclosed {brace", "Unclosed {brace"},
+	}
+	for _, tt := range tests {
+		if got := (Greeting{Text: tt.text}).Render(metadata); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultBackchannels are the words that, alone, do not interrupt the
+// agent when InterruptionConfig.Backchannels is empty.
+var DefaultBackchannels = []string{
+	"yeah", "yes", "yep", "mhm", "mm", "mmhmm", "mm-hmm", "uh-huh", "uh", "um",
+	"hmm", "okay", "ok", "right", "sure", "alright", "got it", "i see",
+}
+
+// InterruptionConfig tunes what counts as an interruption while the agent
+// speaks, so coughs, backchannels, and background noise do not cut it
+// off. Zero fields use the defaults.
+//
+// With it set, voice activity alone no longer interrupts: a transcript
+// must arrive that passes every check. Speech that fails them while the
+// agent speaks is ignored rather than answered.
+type InterruptionConfig struct {
+	// MinSpeechDuration is how long the caller must have been speaking.
+	// Defaults to 300ms. Speech of unknown length passes.
+	MinSpeechDuration time.Duration
+
+	// MinConfidence is the lowest STT confidence (0.0-1.0) that counts.
+	// Defaults to 0.5. Transcripts without a confidence pass.
+	MinConfidence float64
+
+	// Backchannels are words and phrases that acknowledge rather than
+	// interrupt, such as "mhm" and "okay". A transcript made up only of
+	// them does not interrupt. Matching ignores case and punctuation.
+	// Defaults to DefaultBackchannels.
+	Backchannels []string
+}
+
+// InterruptionResumeConfig configures resuming after a false
+// interruption. Zero fields use the defaults.
+//
+// An interruption pauses the agent's speech instead of cutting it, and
+// emits EventInterruption as usual. A user utterance within Window
+// confirms it: the reply is abandoned and EventInterruptionConfirmed
+// emitted. Otherwise the agent resumes, with EventInterruptionResumed.
+// Explicit interruptions (Interrupter, DTMF) are confirmed at once.
+type InterruptionResumeConfig struct {
+	// Window is how long to wait for a user utterance after an
+	// interruption, extended while the user is still speaking. Defaults
+	// to 1.5s.
+	Window time.Duration
+
+	// RestartClause resumes by speaking the interrupted clause again from
+	// its start. By default speech continues where it paused.
+	RestartClause bool
+}
+
+// InterruptionResumed is the Data of an EventInterruptionResumed event.
+type InterruptionResumed struct {
+	// Clause is the text being spoken when the agent paused.
+	Clause string
+
+	// Paused is how long the agent was silent.
+	Paused time.Duration
+
+	// Restarted reports whether the clause was spoken again from its
+	// start.
+	Restarted bool
+}
+
+// InterruptionGate decides whether caller speech during agent speech is a
+// genuine interruption. It is safe for concurrent use.
+type InterruptionGate struct {
+	config  InterruptionConfig
+	phrases [][]string
+}
+
+// NewInterruptionGate creates a gate.
+func NewInterruptionGate(config InterruptionConfig) *InterruptionGate {
+	if config.MinSpeechDuration <= 0 {
+		config.MinSpeechDuration = 300 * time.Millisecond
+	}
+	if config.MinConfidence <= 0 {
+		config.MinConfidence = 0.5
+	}
+	if len(config.Backchannels) == 0 {
+		config.Backchannels = DefaultBackchannels
+	}
+	g := &InterruptionGate{config: config}
+	for _, b := range config.Backchannels {
+		if words := normalizeWords(b); len(words) > 0 {
+			g.phrases = append(g.phrases, words)
+		}
+	}
+	return g
+}
+
+// Allow reports whether a transcript interrupts the agent. confidence and
+// speech (how long the caller has been speaking) are zero when unknown.
+func (g *InterruptionGate) Allow(text string, confidence float64, speech time.Duration) bool {
+	if speech > 0 && speech < g.config.MinSpeechDuration {
+		return false
+	}
+	if confidence > 0 && confidence < g.config.MinConfidence {
+		return false
+	}
+	return !g.IsBackchannel(text)
+}
+
+// IsBackchannel reports whether text consists only of backchannel words,
+// or of no words at all.
+func (g *InterruptionGate) IsBackchannel(text string) bool {
+	words := normalizeWords(text)
+next:
+	for len(words) > 0 {
+		for _, p := range g.phrases {
+			if len(p) <= len(words) && equalWords(p, words[:len(p)]) {
+				words = words[len(p):]
+				continue next
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// normalizeWords lower-cases text and splits it into words, dropping
+// punctuation other than inner hyphens and apostrophes.
+func normalizeWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '\''
+	})
+	out := words[:0]
+	for _, w := range words {
+		if w = strings.Trim(w, "-'"); w != "" {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
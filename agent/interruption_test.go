@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterruptionGate(t *testing.T) {
+	g := NewInterruptionGate(InterruptionConfig{})
+	for _, tc := range []struct {
+		name       string
+		text       string
+		confidence float64
+		speech     time.Duration
+		want       bool
+	}{
+		{"cough", "uh", 0.9, 120 * time.Millisecond, false},
+		{"short burst", "stop", 0.9, 150 * time.Millisecond, false},
+		{"tv noise", "and the weather tomorrow", 0.3, time.Second, false},
+		{"backchannel", "Mm-hmm.", 0.95, 600 * time.Millisecond, false},
+		{"backchannels", "Yeah, okay, got it!", 0.95, 900 * time.Millisecond, false},
+		{"interruption", "Wait, that's the wrong address", 0.9, 800 * time.Millisecond, true},
+		{"backchannel then request", "okay but can you repeat that", 0.9, time.Second, true},
+		{"unknown duration and confidence", "hold on", 0, 0, true},
+	} {
+		if got := g.Allow(tc.text, tc.confidence, tc.speech); got != tc.want {
+			t.Errorf("%s: Allow(%q, %v, %v) = %v, want %v", tc.name, tc.text, tc.confidence, tc.speech, got, tc.want)
+		}
+	}
+}
+
+func TestInterruptionGateCustomBackchannels(t *testing.T) {
+	g := NewInterruptionGate(InterruptionConfig{Backchannels: []string{"vale", "ya veo"}})
+	if !g.IsBackchannel("Vale, ya veo.") {
+		t.Error("custom backchannels interrupted")
+	}
+	if g.IsBackchannel("okay") {
+		t.Error("default backchannels still apply with custom ones set")
+	}
+}
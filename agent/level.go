@@ -0,0 +1,20 @@
+package agent
+
+import "time"
+
+// MinAudioLevelInterval is the shortest Config.AudioLevelInterval, which
+// bounds the rate of EventAudioLevel events.
+const MinAudioLevelInterval = 50 * time.Millisecond
+
+// AudioLevel is the Data of an EventAudioLevel event: the level of one
+// direction of session audio over the last interval.
+type AudioLevel struct {
+	// Role is "user" for caller audio or "agent" for agent speech.
+	Role string
+
+	// RMS is the root-mean-square level, 0.0-1.0.
+	RMS float64
+
+	// Peak is the absolute peak level, 0.0-1.0.
+	Peak float64
+}
@@ -0,0 +1,74 @@
+package agent
+
+// Limit identifies a session limit in a LimitEvent.
+type Limit string
+
+const (
+	// LimitTurnDuration is Config.MaxTurnDuration. The reply is cut and
+	// the session goes on listening.
+	LimitTurnDuration Limit = "turn_duration"
+
+	// LimitSessionDuration is Config.MaxSessionDuration. The session
+	// speaks Config.ClosingMessage and stops.
+	LimitSessionDuration Limit = "session_duration"
+
+	// LimitBudget is Config.Budget. The session speaks
+	// Config.ClosingMessage and stops.
+	LimitBudget Limit = "budget"
+)
+
+// LimitEvent is the Data of an EventLimitReached event.
+type LimitEvent struct {
+	// Limit is the limit reached.
+	Limit Limit
+
+	// Usage is the session's spend when the limit was reached.
+	Usage Usage
+}
+
+// Usage is the provider spend of a session.
+type Usage struct {
+	// LLMInputTokens and LLMOutputTokens are the tokens reported by the
+	// LLM in LLMChunk.Usage.
+	LLMInputTokens  int
+	LLMOutputTokens int
+
+	// TTSCharacters is the number of characters sent to TTS.
+	TTSCharacters int
+
+	// Cost is the spend priced with the Budget rates, in the currency
+	// they are given in.
+	Cost float64
+}
+
+// Budget caps the spend of a session. Zero fields are unlimited.
+type Budget struct {
+	// MaxLLMTokens caps input plus output tokens.
+	MaxLLMTokens int
+
+	// MaxTTSCharacters caps characters sent to TTS.
+	MaxTTSCharacters int
+
+	// MaxCost caps Usage.Cost, priced with the rates below.
+	MaxCost float64
+
+	// Per-unit prices used for Usage.Cost.
+	LLMInputTokenPrice  float64
+	LLMOutputTokenPrice float64
+	TTSCharacterPrice   float64
+}
+
+// Price returns u with Cost computed from the budget's prices.
+func (b Budget) Price(u Usage) Usage {
+	u.Cost = float64(u.LLMInputTokens)*b.LLMInputTokenPrice +
+		float64(u.LLMOutputTokens)*b.LLMOutputTokenPrice +
+		float64(u.TTSCharacters)*b.TTSCharacterPrice
+	return u
+}
+
+// Exceeded reports whether priced usage u is over any cap.
+func (b Budget) Exceeded(u Usage) bool {
+	return (b.MaxLLMTokens > 0 && u.LLMInputTokens+u.LLMOutputTokens > b.MaxLLMTokens) ||
+		(b.MaxTTSCharacters > 0 && u.TTSCharacters > b.MaxTTSCharacters) ||
+		(b.MaxCost > 0 && u.Cost > b.MaxCost)
+}
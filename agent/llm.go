@@ -0,0 +1,71 @@
+package agent
+
+import "context"
+
+// Message roles used in LLM conversations.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is one entry of the conversation sent to an LLM.
+type Message struct {
+	// Role is RoleSystem, RoleUser, RoleAssistant, or RoleTool.
+	Role string
+
+	// Content is the message text.
+	Content string
+
+	// ToolCalls are the calls requested by an assistant message.
+	ToolCalls []LLMToolCall
+
+	// ToolCallID links a RoleTool message to the call it answers.
+	ToolCallID string
+
+	// Attachments are media for multimodal models, e.g. from tool results.
+	Attachments []Attachment
+}
+
+// LLMToolCall is a function call requested by the model.
+type LLMToolCall struct {
+	// ID identifies the call so its result can be matched.
+	ID string
+
+	// Name is the tool name.
+	Name string
+
+	// Arguments are the parsed call arguments.
+	Arguments map[string]any
+}
+
+// LLMChunk is an incremental piece of a streamed LLM response.
+type LLMChunk struct {
+	// Text is the next fragment of response text.
+	Text string
+
+	// ToolCalls are complete tool calls, typically on the last chunk.
+	ToolCalls []LLMToolCall
+
+	// Usage is the token usage of the request, typically on the last
+	// chunk. LLMs that don't report usage leave it nil.
+	Usage *LLMUsage
+
+	// Error contains any error that ended the stream.
+	Error error
+}
+
+// LLMUsage is the token usage of one LLM request.
+type LLMUsage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// LLM is a streaming language model used by session implementations.
+type LLM interface {
+	// Stream generates a response to messages, offering tools for
+	// function calling. The channel is closed when generation ends;
+	// canceling ctx stops generation.
+	Stream(ctx context.Context, messages []Message, tools []Tool) (<-chan LLMChunk, error)
+}
@@ -0,0 +1,73 @@
+package agent
+
+import "context"
+
+// DefaultModerationFallback is spoken when a response is blocked and no
+// other replacement is configured.
+const DefaultModerationFallback = "I'm sorry, I can't help with that."
+
+// Moderator screens text. It returns allowed=false to block the text, with
+// an optional replacement to use instead. Implementations may call a local
+// classifier or a moderation API; they should fail closed or open
+// deliberately when ctx ends.
+type Moderator func(ctx context.Context, text string) (allowed bool, replacement string)
+
+// ModerationDirection says which side of the conversation was moderated.
+type ModerationDirection string
+
+const (
+	// ModerationInput is a user transcript.
+	ModerationInput ModerationDirection = "input"
+
+	// ModerationOutput is an agent response.
+	ModerationOutput ModerationDirection = "output"
+)
+
+// ModerationEvent is the Data of an EventModeration event.
+type ModerationEvent struct {
+	// Direction is the side of the conversation that was moderated.
+	Direction ModerationDirection
+
+	// Original is the blocked text.
+	Original string
+
+	// Replacement is the text used instead, or "" if the input was
+	// dropped.
+	Replacement string
+}
+
+// ModerateInput runs config.InputModerator on a user transcript. It
+// returns the text to send to the LLM, which is the replacement when the
+// input is blocked (an empty result means the turn is dropped), and a
+// non-nil event to emit as EventModeration when the input was blocked.
+func ModerateInput(ctx context.Context, config Config, text string) (string, *ModerationEvent) {
+	if config.InputModerator == nil {
+		return text, nil
+	}
+	allowed, replacement := config.InputModerator(ctx, text)
+	if allowed {
+		return text, nil
+	}
+	return replacement, &ModerationEvent{Direction: ModerationInput, Original: text, Replacement: replacement}
+}
+
+// ModerateOutput runs config.OutputModerator on an agent response before
+// TTS. A blocked response is replaced by the moderator's replacement,
+// config.ModerationFallback, or DefaultModerationFallback, and a non-nil
+// event is returned to emit as EventModeration.
+func ModerateOutput(ctx context.Context, config Config, text string) (string, *ModerationEvent) {
+	if config.OutputModerator == nil {
+		return text, nil
+	}
+	allowed, replacement := config.OutputModerator(ctx, text)
+	if allowed {
+		return text, nil
+	}
+	if replacement == "" {
+		replacement = config.ModerationFallback
+	}
+	if replacement == "" {
+		replacement = DefaultModerationFallback
+	}
+	return replacement, &ModerationEvent{Direction: ModerationOutput, Original: text, Replacement: replacement}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// RenderSystemPrompt renders Config.SystemPrompt as a text/template, as
+// sessions do when they are created, so an integration can fill in call
+// details without building prompts by hand:
+//
+//	You are Acme's assistant. It is {{.time_of_day}}; the caller is
+//	{{.caller_name}}{{if eq .account_status "overdue"}}, whose account is
+//	overdue{{end}}.
+//
+// Variables are PromptVariables, Metadata, and these built-ins, in that
+// order of precedence:
+//
+//	now             the time.Time of rendering, in TimeZone
+//	date            e.g. "Monday, January 2, 2006"
+//	time            e.g. "3:04 PM"
+//	time_of_day     "morning", "afternoon", "evening", or "night"
+//	call_direction  CallDirection
+//	language        Language
+//
+// Every variable a prompt uses must be defined, even in a branch not
+// taken; give optional ones an empty value. Errors, for a malformed
+// template or undefined variables (all of them named), wrap
+// ErrInvalidPrompt. Prompts
+// without "{{" are returned unchanged.
+func RenderSystemPrompt(config Config, now time.Time) (string, error) {
+	if !strings.Contains(config.SystemPrompt, "{{") {
+		return config.SystemPrompt, nil
+	}
+	tmpl, err := template.New("system prompt").Option("missingkey=error").Parse(config.SystemPrompt)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidPrompt, err)
+	}
+	vars, err := promptVariables(config, now)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	undefinedVariables(tmpl.Root, vars, &missing)
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: undefined variables %s", ErrInvalidPrompt, strings.Join(missing, ", "))
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidPrompt, err)
+	}
+	return b.String(), nil
+}
+
+func promptVariables(config Config, now time.Time) (map[string]any, error) {
+	if config.TimeZone != "" {
+		loc, err := time.LoadLocation(config.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: time zone: %w", ErrInvalidPrompt, err)
+		}
+		now = now.In(loc)
+	}
+	vars := map[string]any{
+		"now":            now,
+		"date":           now.Format("Monday, January 2, 2006"),
+		"time":           now.Format("3:04 PM"),
+		"time_of_day":    timeOfDay(now),
+		"call_direction": config.CallDirection,
+		"language":       config.Language,
+	}
+	for k, v := range config.Metadata {
+		vars[k] = v
+	}
+	maps.Copy(vars, config.PromptVariables)
+	return vars, nil
+}
+
+func timeOfDay(t time.Time) string {
+	switch h := t.Hour(); {
+	case h >= 5 && h < 12:
+		return "morning"
+	case h >= 12 && h < 17:
+		return "afternoon"
+	case h >= 17 && h < 22:
+		return "evening"
+	}
+	return "night"
+}
+
+// undefinedVariables appends the variables node uses that vars lacks,
+// quoted. Only fields of the top-level dot are checked, not those within
+// range and with, which change it.
+func undefinedVariables(node parse.Node, vars map[string]any, missing *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			undefinedVariables(c, vars, missing)
+		}
+	case *parse.ActionNode:
+		undefinedVariables(n.Pipe, vars, missing)
+	case *parse.IfNode:
+		undefinedVariables(n.Pipe, vars, missing)
+		undefinedVariables(n.List, vars, missing)
+		undefinedVariables(n.ElseList, vars, missing)
+	case *parse.RangeNode:
+		undefinedVariables(n.Pipe, vars, missing)
+		undefinedVariables(n.ElseList, vars, missing)
+	case *parse.WithNode:
+		undefinedVariables(n.Pipe, vars, missing)
+		undefinedVariables(n.ElseList, vars, missing)
+	case *parse.TemplateNode:
+		undefinedVariables(n.Pipe, vars, missing)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				undefinedVariables(arg, vars, missing)
+			}
+		}
+	case *parse.FieldNode:
+		name := fmt.Sprintf("%q", n.Ident[0])
+		if _, ok := vars[n.Ident[0]]; !ok && !slices.Contains(*missing, name) {
+			*missing = append(*missing, name)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package agent
+
+import "github.com/agentplexus/omnivoice/tts"
+
+// ProsodyFunc maps an agent turn to TTS prosody settings. Only Speed,
+// Pitch, Style, and StyleDegree of the result are used; zero values leave
+// the session's settings unchanged.
+type ProsodyFunc func(turn Turn) tts.SynthesisConfig
+
+// ApplyProsody returns base with the prosody chosen by fn for turn. A nil
+// fn returns base unchanged. Sessions call it before synthesizing each
+// agent turn.
+func ApplyProsody(base tts.SynthesisConfig, turn Turn, fn ProsodyFunc) tts.SynthesisConfig {
+	if fn == nil {
+		return base
+	}
+	p := fn(turn)
+	if p.Speed != 0 {
+		base.Speed = p.Speed
+	}
+	if p.Pitch != 0 {
+		base.Pitch = p.Pitch
+	}
+	if p.Style != "" {
+		base.Style = p.Style
+	}
+	if p.StyleDegree != 0 {
+		base.StyleDegree = p.StyleDegree
+	}
+	return base
+}
+
+// SentimentProsody is a simple ProsodyFunc: it slows down and softens for
+// negative or frustrated turns, brightens positive ones, and raises style
+// intensity with Emphasis.
+func SentimentProsody(turn Turn) tts.SynthesisConfig {
+	var p tts.SynthesisConfig
+	switch turn.Sentiment {
+	case "negative", "frustrated", "sad", "angry":
+		p.Style, p.Speed, p.Pitch = tts.StyleEmpathetic, 0.92, -0.05
+	case "positive", "happy", "excited":
+		p.Style, p.Speed, p.Pitch = tts.StyleCheerful, 1.05, 0.05
+	}
+	if turn.Emphasis > 0 {
+		p.StyleDegree = 1 + min(turn.Emphasis, 1)
+	}
+	return p
+}
@@ -0,0 +1,371 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot is the resumable state of a session.
+type Snapshot struct {
+	// SessionID is the session identifier.
+	SessionID string
+
+	// Transcript is the full conversation so far.
+	Transcript []Turn
+
+	// Context is the turns currently in the LLM context window, which may
+	// differ from Transcript after truncation or summarization. Besides
+	// "user" and "agent", a turn's Role may be "system" or "tool" for the
+	// corresponding messages.
+	Context []Turn
+
+	// ToolState holds provider- or tool-defined state that must survive a
+	// reconnect.
+	ToolState map[string]any
+
+	// Metrics are the session metrics at snapshot time.
+	Metrics Metrics
+
+	// SuspendedAt is when the session was suspended, or zero if active.
+	SuspendedAt time.Time
+}
+
+// ResumableSession is implemented by sessions that can outlive their
+// transport connection.
+type ResumableSession interface {
+	Session
+
+	// Snapshot returns the session's current state.
+	Snapshot() Snapshot
+
+	// Suspend detaches the session from its transport, keeping
+	// conversation state, and emits EventSessionSuspended.
+	Suspend() error
+
+	// Resume reattaches the session after Suspend and emits
+	// EventSessionResumed. Audio sent afterwards continues the
+	// conversation.
+	Resume(ctx context.Context) error
+}
+
+// Pauser is implemented by sessions that can be put on hold, e.g. while
+// a call is held or transferred. Unlike Suspend, the transport stays
+// connected and the session's time limit stops too.
+type Pauser interface {
+	// Pause stops listening and speaking, cancelling any reply in
+	// progress, and emits EventSessionPaused. The conversation is kept;
+	// caller audio sent while paused is discarded, and
+	// Config.MaxSessionDuration does not run.
+	Pause(ctx context.Context) error
+
+	// Unpause ends a pause, emitting EventSessionResumed and speaking
+	// Config.ResumeGreeting, if set. It does not end a Suspend, nor
+	// does ResumableSession.Resume end a pause.
+	Unpause(ctx context.Context) error
+}
+
+// ResumableProvider is implemented by providers that can reattach a new
+// transport connection to a suspended session.
+type ResumableProvider interface {
+	Provider
+
+	// ResumeSession resumes the session identified by a resume token.
+	ResumeSession(ctx context.Context, token string) (Session, error)
+}
+
+// SessionRegistry tracks a provider's sessions and keeps suspended ones
+// alive for a grace period. Providers use it to implement GetSession,
+// ListSessions, and ResumeSession, and to cap concurrent sessions with
+// SetMaxSessions and Reserve. It is safe for concurrent use.
+type SessionRegistry struct {
+	mu       sync.Mutex
+	grace    time.Duration
+	sessions map[string]*registryEntry
+	tokens   map[string]string
+	onExpire func(Session)
+
+	// max caps sessions plus reserved places; freed is closed and
+	// replaced whenever a place is given up, waking Reserve.
+	max      int
+	reserved int
+	freed    chan struct{}
+}
+
+type registryEntry struct {
+	session Session
+	token   string
+	grace   time.Duration
+	expired bool
+
+	// timer expires a suspended session at deadline.
+	timer    *time.Timer
+	deadline time.Time
+}
+
+// NewSessionRegistry creates a registry that keeps suspended sessions for
+// grace before stopping and removing them.
+func NewSessionRegistry(grace time.Duration) *SessionRegistry {
+	return &SessionRegistry{
+		grace:    grace,
+		sessions: make(map[string]*registryEntry),
+		tokens:   make(map[string]string),
+		freed:    make(chan struct{}),
+	}
+}
+
+// SetMaxSessions limits how many sessions, including suspended ones, may
+// be registered or reserved at once. Zero or negative means no limit.
+func (r *SessionRegistry) SetMaxSessions(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.max = n
+	r.freeLocked()
+}
+
+// Reserve claims a place for a session about to be created, so providers
+// can reject a session before building it. At the SetMaxSessions limit it
+// fails with ErrTooManySessions or, if wait is set, waits for a session to
+// be removed, failing with ErrTooManySessions wrapping ctx's error if ctx
+// ends first. Add fills the place; release gives it up if the session
+// will not be added, and must not be called after Add.
+func (r *SessionRegistry) Reserve(ctx context.Context, wait bool) (release func(), err error) {
+	for {
+		r.mu.Lock()
+		if r.max <= 0 || len(r.sessions)+r.reserved < r.max {
+			r.reserved++
+			r.mu.Unlock()
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					r.mu.Lock()
+					defer r.mu.Unlock()
+					if r.reserved > 0 {
+						r.reserved--
+					}
+					r.freeLocked()
+				})
+			}, nil
+		}
+		freed := r.freed
+		r.mu.Unlock()
+		if !wait {
+			return nil, ErrTooManySessions
+		}
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrTooManySessions, ctx.Err())
+		}
+	}
+}
+
+// OnExpire sets a callback run after an abandoned session is stopped and
+// removed.
+func (r *SessionRegistry) OnExpire(fn func(Session)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onExpire = fn
+}
+
+// Add registers a session and returns its resume token, filling a place
+// claimed with Reserve. Sessions added without one are not limited, but
+// still count against the limit.
+func (r *SessionRegistry) Add(s Session) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reserved > 0 {
+		r.reserved--
+	}
+	token := newResumeToken()
+	r.sessions[s.ID()] = &registryEntry{session: s, token: token, grace: r.grace}
+	r.tokens[token] = s.ID()
+	return token
+}
+
+// SetGracePeriod overrides the registry's grace period for one session,
+// e.g. from its Config.ResumeGracePeriod.
+func (r *SessionRegistry) SetGracePeriod(id string, grace time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	e.grace = grace
+	return nil
+}
+
+// Get returns a registered session by ID.
+func (r *SessionRegistry) Get(id string) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return e.session, nil
+}
+
+// Token returns the resume token of a registered session.
+func (r *SessionRegistry) Token(id string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.sessions[id]
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	return e.token, nil
+}
+
+// List returns the IDs of registered sessions, including suspended ones.
+func (r *SessionRegistry) List() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Suspend marks a session's transport as lost. The session's Suspend is
+// called and, unless it is resumed within the grace period, the session is
+// stopped and removed. With a zero grace period the session is stopped
+// immediately. Suspending a session already suspended does nothing, so
+// its grace period runs from the first loss.
+func (r *SessionRegistry) Suspend(id string) error {
+	r.mu.Lock()
+	e, ok := r.sessions[id]
+	if !ok {
+		r.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	rs, ok := e.session.(ResumableSession)
+	if !ok || e.grace <= 0 {
+		r.mu.Unlock()
+		r.expire(id, e)
+		if !ok {
+			return ErrSessionNotResumable
+		}
+		return nil
+	}
+	if e.timer != nil {
+		r.mu.Unlock()
+		return nil
+	}
+	e.deadline = time.Now().Add(e.grace)
+	r.armLocked(id, e)
+	r.mu.Unlock()
+	return rs.Suspend()
+}
+
+// armLocked starts the expiry of a suspended session at its deadline.
+func (r *SessionRegistry) armLocked(id string, e *registryEntry) {
+	e.timer = time.AfterFunc(time.Until(e.deadline), func() { r.expire(id, e) })
+}
+
+// Resume reattaches the session identified by token, cancelling its
+// expiry. It returns ErrSessionNotFound for unknown or already removed
+// sessions and ErrSessionExpired if the grace period ran out concurrently.
+// If the session fails to resume it stays suspended, and still expires at
+// the end of its grace period.
+func (r *SessionRegistry) Resume(ctx context.Context, token string) (Session, error) {
+	r.mu.Lock()
+	id, ok := r.tokens[token]
+	if !ok {
+		r.mu.Unlock()
+		return nil, ErrSessionNotFound
+	}
+	e := r.sessions[id]
+	suspended := e.timer != nil
+	if suspended {
+		if !e.timer.Stop() {
+			// The expiry is already running.
+			r.mu.Unlock()
+			return nil, ErrSessionExpired
+		}
+		e.timer = nil
+	}
+	r.mu.Unlock()
+	if rs, ok := e.session.(ResumableSession); ok {
+		if err := rs.Resume(ctx); err != nil {
+			if suspended {
+				r.mu.Lock()
+				if r.sessions[id] == e && !e.expired && e.timer == nil {
+					r.armLocked(id, e)
+				}
+				r.mu.Unlock()
+			}
+			return nil, err
+		}
+	}
+	return e.session, nil
+}
+
+// Remove unregisters a session without stopping it.
+func (r *SessionRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.sessions[id]; ok {
+		r.removeLocked(id, e)
+	}
+}
+
+// Close stops and removes every session.
+func (r *SessionRegistry) Close() {
+	r.mu.Lock()
+	entries := make(map[string]*registryEntry, len(r.sessions))
+	for id, e := range r.sessions {
+		entries[id] = e
+	}
+	r.mu.Unlock()
+	for id, e := range entries {
+		r.expire(id, e)
+	}
+}
+
+func (r *SessionRegistry) expire(id string, e *registryEntry) {
+	r.mu.Lock()
+	if e.expired || r.sessions[id] != e {
+		r.mu.Unlock()
+		return
+	}
+	e.expired = true
+	r.removeLocked(id, e)
+	onExpire := r.onExpire
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = e.session.Stop(ctx)
+	if onExpire != nil {
+		onExpire(e.session)
+	}
+}
+
+func (r *SessionRegistry) removeLocked(id string, e *registryEntry) {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	delete(r.sessions, id)
+	delete(r.tokens, e.token)
+	r.freeLocked()
+}
+
+// freeLocked wakes callers waiting in Reserve.
+func (r *SessionRegistry) freeLocked() {
+	close(r.freed)
+	r.freed = make(chan struct{})
+}
+
+func newResumeToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
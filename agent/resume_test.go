@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeResumable is a ResumableSession counting its calls. Resume fails
+// with resumeErr if set.
+type fakeResumable struct {
+	Session
+	id        string
+	resumeErr error
+
+	suspends, resumes, stops atomic.Int32
+}
+
+func (s *fakeResumable) ID() string         { return s.id }
+func (s *fakeResumable) Snapshot() Snapshot { return Snapshot{SessionID: s.id} }
+func (s *fakeResumable) Stop(context.Context) error {
+	s.stops.Add(1)
+	return nil
+}
+
+func (s *fakeResumable) Suspend() error {
+	s.suspends.Add(1)
+	return nil
+}
+
+func (s *fakeResumable) Resume(context.Context) error {
+	s.resumes.Add(1)
+	return s.resumeErr
+}
+
+// waitExpired waits for the registry to remove s, reporting whether it
+// did within d.
+func waitExpired(r *SessionRegistry, s *fakeResumable, d time.Duration) bool {
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, err := r.Get(s.id); errors.Is(err, ErrSessionNotFound) && s.stops.Load() == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegistrySuspendIsIdempotent(t *testing.T) {
+	r := NewSessionRegistry(200 * time.Millisecond)
+	s := &fakeResumable{id: "a"}
+	r.Add(s)
+	start := time.Now()
+	if err := r.Suspend("a"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(120 * time.Millisecond)
+	if err := r.Suspend("a"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.suspends.Load(); n != 1 {
+		t.Errorf("session suspended %d times, want 1", n)
+	}
+	if !waitExpired(r, s, time.Second) {
+		t.Fatal("suspended session not expired")
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("expired after %v: the second Suspend restarted the grace period", elapsed)
+	}
+}
+
+func TestRegistryResumeFailureKeepsExpiry(t *testing.T) {
+	r := NewSessionRegistry(50 * time.Millisecond)
+	s := &fakeResumable{id: "a", resumeErr: errors.New("transport gone")}
+	token := r.Add(s)
+	if err := r.Suspend("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Resume(context.Background(), token); !errors.Is(err, s.resumeErr) {
+		t.Fatalf("Resume = %v, want the session's failure", err)
+	}
+	if !waitExpired(r, s, time.Second) {
+		t.Fatal("session that failed to resume was never expired")
+	}
+}
+
+func TestRegistryResume(t *testing.T) {
+	r := NewSessionRegistry(50 * time.Millisecond)
+	s := &fakeResumable{id: "a"}
+	token := r.Add(s)
+	if err := r.Suspend("a"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Resume(context.Background(), token)
+	if err != nil || got != Session(s) {
+		t.Fatalf("Resume = %v, %v", got, err)
+	}
+	if waitExpired(r, s, 100*time.Millisecond) {
+		t.Fatal("resumed session expired")
+	}
+	// Losing the transport again starts a new grace period.
+	if err := r.Suspend("a"); err != nil {
+		t.Fatal(err)
+	}
+	if !waitExpired(r, s, time.Second) {
+		t.Fatal("session suspended again never expired")
+	}
+	if n := s.suspends.Load(); n != 2 {
+		t.Errorf("session suspended %d times, want 2", n)
+	}
+}
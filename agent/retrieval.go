@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// DefaultRetrievalTimeout bounds Config.Retriever when RetrievalTimeout
+// is zero.
+const DefaultRetrievalTimeout = time.Second
+
+// ContextSnippet is a piece of knowledge retrieved for a turn.
+type ContextSnippet struct {
+	// Text is the content given to the LLM.
+	Text string
+
+	// Source optionally names where Text came from, such as a document
+	// title or URL.
+	Source string
+}
+
+// Retriever looks up knowledge relevant to a user turn, e.g. from a
+// vector database, for the LLM to answer from. It should return promptly
+// when ctx ends.
+type Retriever func(ctx context.Context, userText string) ([]ContextSnippet, error)
+
+// Retrieve runs config.Retriever on a user turn, bounded by
+// config.RetrievalTimeout. Sessions start it as soon as the turn is
+// final, so it overlaps whatever precedes the LLM call, and proceed
+// without context if it fails or times out.
+func Retrieve(ctx context.Context, config Config, userText string) ([]ContextSnippet, error) {
+	if config.Retriever == nil {
+		return nil, nil
+	}
+	timeout := config.RetrievalTimeout
+	if timeout <= 0 {
+		timeout = DefaultRetrievalTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return config.Retriever(ctx, userText)
+}
+
+// KnowledgeMessage returns the system message giving snippets to the
+// LLM, placed after the system prompt for the turn they were retrieved
+// for. It is not kept in the conversation history.
+func KnowledgeMessage(snippets []ContextSnippet) Message {
+	var b strings.Builder
+	b.WriteString("Relevant information for the user's latest message:")
+	for _, sn := range snippets {
+		b.WriteString("\n\n")
+		if sn.Source != "" {
+			b.WriteString("[" + sn.Source + "]\n")
+		}
+		b.WriteString(sn.Text)
+	}
+	return Message{Role: RoleSystem, Content: b.String()}
+}
@@ -0,0 +1,36 @@
+package agent
+
+import "context"
+
+// StopMode controls what Session.Stop does with an agent reply in
+// progress.
+type StopMode string
+
+const (
+	// StopImmediate cuts agent speech off at once. The default.
+	StopImmediate StopMode = "immediate"
+
+	// StopFinishUtterance lets the reply being spoken finish before the
+	// session ends. No new replies are started.
+	StopFinishUtterance StopMode = "finish_utterance"
+)
+
+type stopModeKey struct{}
+
+// WithStopMode returns a context that makes Session.Stop use mode instead
+// of Config.StopMode.
+func WithStopMode(ctx context.Context, mode StopMode) context.Context {
+	return context.WithValue(ctx, stopModeKey{}, mode)
+}
+
+// StopModeFrom returns the stop mode for a Stop call: the mode set with
+// WithStopMode, else config.StopMode, else StopImmediate.
+func StopModeFrom(ctx context.Context, config Config) StopMode {
+	if mode, ok := ctx.Value(stopModeKey{}).(StopMode); ok && mode != "" {
+		return mode
+	}
+	if config.StopMode != "" {
+		return config.StopMode
+	}
+	return StopImmediate
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSummary is returned for a SummaryConfig whose Schema is not
+// a well-formed JSON Schema object, and wrapped in the error of an
+// EventCallSummary whose LLM reply could not be used.
+var ErrInvalidSummary = errors.New("agent: invalid call summary")
+
+// DefaultSummaryPrompt is the default SummaryConfig Prompt.
+const DefaultSummaryPrompt = "You summarize customer calls for a CRM. Write a short, factual summary of the call: why the caller called, what was done, and anything left to follow up."
+
+// DefaultSummaryTimeout is the default SummaryConfig Timeout.
+const DefaultSummaryTimeout = 30 * time.Second
+
+// SummaryConfig configures the summary a session generates from its
+// transcript when it ends (Config.Summary), e.g. for CRM logging. Stop
+// waits for it, up to Timeout, before EventSessionEnded; it is delivered
+// as EventCallSummary, and to WebhookConfig.OnCallSummary. A summary
+// that fails is reported in the event's Error and does not affect the
+// session or the error Stop returns.
+type SummaryConfig struct {
+	// Prompt is the system prompt instructing the LLM what to summarize.
+	// Defaults to DefaultSummaryPrompt.
+	Prompt string
+
+	// Schema is the JSON Schema of the fields to extract alongside the
+	// summary, such as caller intent, resolution, and follow-up, as an
+	// object schema like Tool.Parameters. Nil extracts no fields.
+	Schema map[string]any
+
+	// Timeout bounds the LLM request. Defaults to
+	// DefaultSummaryTimeout.
+	Timeout time.Duration
+}
+
+// Validate checks that Schema is a well-formed object schema. Errors wrap
+// ErrInvalidSummary.
+func (c SummaryConfig) Validate() error {
+	if c.Schema == nil {
+		return nil
+	}
+	if t, ok := c.Schema["type"]; ok && t != "object" {
+		return fmt.Errorf("%w: schema must be of type \"object\"", ErrInvalidSummary)
+	}
+	if err := validateSchema(c.Schema, "schema"); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSummary, err)
+	}
+	return nil
+}
+
+// CallSummary is the Data of EventCallSummary.
+type CallSummary struct {
+	// Summary is the prose summary of the call.
+	Summary string `json:"summary"`
+
+	// Fields are the values extracted according to SummaryConfig.Schema.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// Summarizer is implemented by sessions that summarize the call when
+// they end (Config.Summary).
+type Summarizer interface {
+	// Summary returns the call summary, and false until one has been
+	// generated.
+	Summary() (CallSummary, bool)
+}
+
+// Summarize asks llm to summarize transcript as config describes. The
+// reply must be a JSON object with the summary and the extracted fields;
+// one that is not, or whose fields do not match Schema, is an error
+// wrapping ErrInvalidSummary.
+func Summarize(ctx context.Context, llm LLM, transcript []Turn, config SummaryConfig) (CallSummary, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSummaryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	chunks, err := llm.Stream(ctx, summaryMessages(transcript, config), nil)
+	if err != nil {
+		return CallSummary{}, err
+	}
+	var reply strings.Builder
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			return CallSummary{}, chunk.Error
+		}
+		reply.WriteString(chunk.Text)
+	}
+	if err := ctx.Err(); err != nil {
+		return CallSummary{}, err
+	}
+	return parseSummary(reply.String(), config.Schema)
+}
+
+func summaryMessages(transcript []Turn, config SummaryConfig) []Message {
+	prompt := config.Prompt
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+	var format strings.Builder
+	format.WriteString(`Reply with only a JSON object: {"summary": "<the summary>"`)
+	if config.Schema != nil {
+		schema, _ := json.Marshal(config.Schema)
+		format.WriteString(`, "fields": <the fields>}, where the fields match this JSON Schema: `)
+		format.Write(schema)
+	} else {
+		format.WriteString("}")
+	}
+
+	var b strings.Builder
+	b.WriteString("Call transcript:\n")
+	for _, t := range transcript {
+		speaker := "Agent"
+		if t.Role != "agent" {
+			speaker = "Caller"
+			if t.SpeakerName != "" {
+				speaker = t.SpeakerName
+			}
+		}
+		text := t.Text
+		if text == "" && t.DTMF != "" {
+			text = "[keypad: " + t.DTMF + "]"
+		}
+		b.WriteString("\n" + speaker + ": " + text)
+	}
+	return []Message{
+		{Role: RoleSystem, Content: prompt + "\n\n" + format.String()},
+		{Role: RoleUser, Content: b.String()},
+	}
+}
+
+// parseSummary decodes the LLM's reply, allowing a Markdown code fence
+// or text around the JSON object.
+func parseSummary(reply string, schema map[string]any) (CallSummary, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return CallSummary{}, fmt.Errorf("%w: reply is not a JSON object", ErrInvalidSummary)
+	}
+	var s CallSummary
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &s); err != nil {
+		return CallSummary{}, fmt.Errorf("%w: %w", ErrInvalidSummary, err)
+	}
+	if schema == nil {
+		s.Fields = nil
+		return s, nil
+	}
+	var problems []string
+	checkValue(schema, s.Fields, "fields", &problems)
+	if len(problems) > 0 {
+		return s, fmt.Errorf("%w: %s", ErrInvalidSummary, strings.Join(problems, "; "))
+	}
+	return s, nil
+}
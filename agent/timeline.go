@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/agentplexus/omnivoice/stt"
+)
+
+// SessionClock is a session's timeline, shared by its turns, words, and
+// events so they line up with each other and with a recording of the call.
+//
+// Session time is the duration since the session started: the call start
+// is 0. It runs on the monotonic clock, so it is unaffected by wall-clock
+// adjustments during the call. Event.Offset and Turn.Offset are session
+// times, as are the word timings in Turn.Words, which sessions shift from
+// the STT stream's own timeline (relative to the stream's first audio) by
+// the session time the stream started at. Time converts a session time
+// to wall-clock time, and Offset converts back.
+//
+// The zero SessionClock is a session that has not started; its offsets
+// are all zero.
+type SessionClock struct {
+	start time.Time
+}
+
+// NewSessionClock returns the clock of a session started at start, which
+// should come from time.Now so that offsets use its monotonic reading.
+func NewSessionClock(start time.Time) SessionClock {
+	return SessionClock{start: start}
+}
+
+// Start returns the wall-clock time of session time 0.
+func (c SessionClock) Start() time.Time { return c.start }
+
+// IsZero reports whether the session has not started.
+func (c SessionClock) IsZero() bool { return c.start.IsZero() }
+
+// Now returns the current session time.
+func (c SessionClock) Now() time.Duration {
+	if c.IsZero() {
+		return 0
+	}
+	return time.Since(c.start)
+}
+
+// Offset converts a wall-clock time to session time. Times before the
+// start are negative; a zero t, or a zero clock, gives 0.
+func (c SessionClock) Offset(t time.Time) time.Duration {
+	if c.IsZero() || t.IsZero() {
+		return 0
+	}
+	return t.Sub(c.start)
+}
+
+// Time converts a session time to wall-clock time.
+func (c SessionClock) Time(offset time.Duration) time.Time {
+	return c.start.Add(offset)
+}
+
+// ShiftWords returns a copy of words with their timings moved by d, e.g.
+// from an STT stream's timeline to the session's.
+func ShiftWords(words []stt.Word, d time.Duration) []stt.Word {
+	if words == nil {
+		return nil
+	}
+	shifted := make([]stt.Word, len(words))
+	for i, w := range words {
+		w.StartTime += d
+		w.EndTime += d
+		shifted[i] = w
+	}
+	return shifted
+}
+
+// Clocker is implemented by sessions that timestamp on a SessionClock.
+type Clocker interface {
+	// Clock returns the session's clock, zero until it starts.
+	Clock() SessionClock
+}
@@ -0,0 +1,395 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// toolNamePattern is the function name format accepted by the major LLM
+// function-calling APIs.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// ValidateTool checks that a tool can be registered: the name is a valid
+// function name, Handler is set, and Parameters is a well-formed JSON
+// Schema object whose required properties are declared. Either Handler or
+// StructuredHandler may be set. Errors wrap ErrInvalidTool.
+func ValidateTool(tool Tool) error {
+	if !toolNamePattern.MatchString(tool.Name) {
+		return fmt.Errorf("%w: name %q must be 1-64 letters, digits, '_' or '-'", ErrInvalidTool, tool.Name)
+	}
+	if tool.Handler == nil && tool.StructuredHandler == nil {
+		return fmt.Errorf("%w: %s: Handler is nil", ErrInvalidTool, tool.Name)
+	}
+	if tool.Parameters == nil {
+		return nil
+	}
+	if t, ok := tool.Parameters["type"]; ok && t != "object" {
+		return fmt.Errorf("%w: %s: parameters must be a schema of type \"object\"", ErrInvalidTool, tool.Name)
+	}
+	if err := validateSchema(tool.Parameters, "parameters"); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidTool, tool.Name, err)
+	}
+	return nil
+}
+
+func validateSchema(schema map[string]any, path string) error {
+	types, err := schemaTypeList(schema["type"])
+	if err != nil {
+		return fmt.Errorf("%s.type: %w", path, err)
+	}
+	for _, t := range types {
+		if !schemaTypes[t] {
+			return fmt.Errorf("%s.type: unknown type %q", path, t)
+		}
+	}
+
+	var props map[string]any
+	if p, ok := schema["properties"]; ok {
+		if props, ok = p.(map[string]any); !ok {
+			return fmt.Errorf("%s.properties: must be an object", path)
+		}
+		for _, name := range sortedKeys(props) {
+			sub, ok := props[name].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s.properties.%s: must be a schema object", path, name)
+			}
+			if err := validateSchema(sub, path+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if r, ok := schema["required"]; ok {
+		required, ok := stringList(r)
+		if !ok {
+			return fmt.Errorf("%s.required: must be an array of strings", path)
+		}
+		for _, name := range required {
+			if _, ok := props[name]; !ok {
+				return fmt.Errorf("%s.required: %q is not a declared property", path, name)
+			}
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		sub, ok := items.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s.items: must be a schema object", path)
+		}
+		if err := validateSchema(sub, path+".items"); err != nil {
+			return err
+		}
+	}
+	if e, ok := schema["enum"]; ok {
+		if values, ok := anyList(e); !ok || len(values) == 0 {
+			return fmt.Errorf("%s.enum: must be a non-empty array", path)
+		}
+	}
+	if d, ok := schema["description"]; ok {
+		if _, ok := d.(string); !ok {
+			return fmt.Errorf("%s.description: must be a string", path)
+		}
+	}
+	return nil
+}
+
+// ArgumentError reports tool arguments that do not match the tool's
+// parameter schema. Its message is written for the LLM, so it can be
+// returned as the tool result to let the model correct its call.
+type ArgumentError struct {
+	// Tool is the tool name.
+	Tool string
+
+	// Problems lists each mismatch, e.g. `"count": expected integer`.
+	Problems []string
+}
+
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(e.Problems, "; "))
+}
+
+// ValidateArguments checks args against a tool's parameter schema,
+// returning an *ArgumentError listing every mismatch.
+func ValidateArguments(tool Tool, args map[string]any) error {
+	if tool.Parameters == nil {
+		return nil
+	}
+	var problems []string
+	checkValue(tool.Parameters, args, "", &problems)
+	if len(problems) > 0 {
+		return &ArgumentError{Tool: tool.Name, Problems: problems}
+	}
+	return nil
+}
+
+// InvokeTool validates args and calls the tool's StructuredHandler, or its
+// Handler, whose string result is wrapped in a ToolResult. Invalid
+// arguments return an *ArgumentError without calling the handler, and a
+// panicking handler is reported as an error rather than crashing the
+// session.
+func InvokeTool(ctx context.Context, tool Tool, args map[string]any) (result *ToolResult, err error) {
+	if tool.Handler == nil && tool.StructuredHandler == nil {
+		return nil, fmt.Errorf("%w: %s: Handler is nil", ErrInvalidTool, tool.Name)
+	}
+	if err := ValidateArguments(tool, args); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("agent: tool %s panicked: %v", tool.Name, r)
+		}
+	}()
+	if tool.StructuredHandler != nil {
+		result, err = tool.StructuredHandler(ctx, args)
+		if err == nil && result == nil {
+			result = &ToolResult{}
+		}
+		return result, err
+	}
+	text, err := tool.Handler(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return &ToolResult{Data: text}, nil
+}
+
+// Content returns the result as sent to the LLM: strings unchanged, other
+// values JSON-encoded. Encoding failures wrap ErrToolResultEncoding.
+func (r *ToolResult) Content() (string, error) {
+	switch v := r.Data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrToolResultEncoding, err)
+	}
+	return string(b), nil
+}
+
+// RunTool invokes a tool and records the call for the transcript. Handler
+// errors, invalid arguments, and encoding errors are recorded in
+// ToolCall.Error, which sessions return to the LLM as the tool result.
+func RunTool(ctx context.Context, tool Tool, args map[string]any) ToolCall {
+	start := time.Now()
+	call := ToolCall{Name: tool.Name, Arguments: args}
+	result, err := InvokeTool(ctx, tool, args)
+	if err == nil {
+		call.Result, err = result.Content()
+		call.Attachments = result.Attachments
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	call.DurationMs = int(time.Since(start).Milliseconds())
+	return call
+}
+
+func checkValue(schema map[string]any, v any, path string, problems *[]string) {
+	label := path
+	if label == "" {
+		label = "arguments"
+	}
+	fail := func(format string, a ...any) {
+		*problems = append(*problems, fmt.Sprintf("%q: ", label)+fmt.Sprintf(format, a...))
+	}
+
+	types, _ := schemaTypeList(schema["type"])
+	if len(types) > 0 {
+		matched := ""
+		for _, t := range types {
+			if hasType(v, t) {
+				matched = t
+				break
+			}
+		}
+		if matched == "" {
+			fail("expected %s, got %s", strings.Join(types, " or "), jsonType(v))
+			return
+		}
+	}
+	if e, ok := anyList(schema["enum"]); ok && !inEnum(v, e) {
+		fail("must be one of %s", formatEnum(e))
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		required, _ := stringList(schema["required"])
+		for _, name := range required {
+			if _, ok := val[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%q: required", join(path, name)))
+			}
+		}
+		for _, name := range sortedKeys(val) {
+			sub, ok := props[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					*problems = append(*problems, fmt.Sprintf("%q: unknown property", join(path, name)))
+				}
+				continue
+			}
+			checkValue(sub, val[name], join(path, name), problems)
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				checkValue(items, item, fmt.Sprintf("%s[%d]", label, i), problems)
+			}
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "integer":
+		f, ok := toFloat(v)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func jsonType(v any) string {
+	for _, t := range []string{"null", "boolean", "integer", "number", "string", "array", "object"} {
+		if hasType(v, t) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func inEnum(v any, values []any) bool {
+	for _, e := range values {
+		if fv, ok := toFloat(v); ok {
+			if fe, ok := toFloat(e); ok && fv == fe {
+				return true
+			}
+			continue
+		}
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		b, _ := json.Marshal(v)
+		parts[i] = string(b)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func schemaTypeList(t any) ([]string, error) {
+	switch v := t.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	default:
+		if list, ok := stringList(v); ok && len(list) > 0 {
+			return list, nil
+		}
+	}
+	return nil, errors.New("must be a type name or array of type names")
+}
+
+func stringList(v any) ([]string, bool) {
+	switch l := v.(type) {
+	case []string:
+		return l, true
+	case []any:
+		out := make([]string, len(l))
+		for i, s := range l {
+			str, ok := s.(string)
+			if !ok {
+				return nil, false
+			}
+			out[i] = str
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func anyList(v any) ([]any, bool) {
+	switch l := v.(type) {
+	case []any:
+		return l, true
+	case []string:
+		out := make([]any, len(l))
+		for i, s := range l {
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package agent
+
+import "time"
+
+// TurnState describes the conversation when a TurnPolicy is consulted.
+type TurnState struct {
+	// AgentSpeaking reports that the agent is speaking an interruptible
+	// reply, or has paused one for an interruption.
+	AgentSpeaking bool
+
+	// AgentSpeakingFor is how long the agent has been speaking the reply.
+	AgentSpeakingFor time.Duration
+
+	// UserSpeaking reports voice activity from the caller.
+	UserSpeaking bool
+
+	// UserSpeakingFor is how long the caller has been speaking.
+	UserSpeakingFor time.Duration
+
+	// UserPending reports that the caller has said words that endpointing
+	// has not yet completed into a turn.
+	UserPending bool
+
+	// Confirmed reports, to Interrupt, that the caller said words rather
+	// than voice activity alone.
+	Confirmed bool
+
+	// Text is, to Interrupt and Listen, what the caller said, if known.
+	Text string
+}
+
+// TurnPolicy decides who holds the floor (Config.TurnPolicy), so
+// applications can choose between strict alternation, overlap, and an
+// agent that always yields. Sessions consult it at each turn-taking
+// decision; Endpointing still decides when an utterance is complete, and
+// InterruptionMode, Interruption, and InterruptionGracePeriod still apply
+// to interruptions the policy allows. Explicit interruptions
+// (Interrupter, DTMF, SendText) bypass it. Methods may be called
+// concurrently.
+type TurnPolicy interface {
+	// Interrupt reports whether the caller speaking over the agent stops
+	// its reply: on voice activity, and again on each transcript, with
+	// Confirmed set.
+	Interrupt(state TurnState) bool
+
+	// Listen reports whether what the caller says while the agent speaks
+	// is heard. Speech that is not heard is neither recorded nor answered.
+	Listen(state TurnState) bool
+
+	// Hold reports whether a reply about to start speaking waits for the
+	// caller to stop, letting them finish first. A user turn completing
+	// meanwhile supersedes the reply as usual.
+	Hold(state TurnState) bool
+}
+
+// DefaultTurnPolicy is the turn-taking of a session without a
+// TurnPolicy: the caller may interrupt and is always heard, and replies
+// start speaking as soon as they are ready.
+type DefaultTurnPolicy struct{}
+
+// Interrupt always allows the interruption.
+func (DefaultTurnPolicy) Interrupt(TurnState) bool { return true }
+
+// Listen always hears the caller.
+func (DefaultTurnPolicy) Listen(TurnState) bool { return true }
+
+// Hold never holds a reply.
+func (DefaultTurnPolicy) Hold(TurnState) bool { return false }
+
+// StrictTurnPolicy alternates strictly: the agent is not interrupted and
+// does not hear the caller while it speaks, and does not start speaking
+// while the caller does.
+type StrictTurnPolicy struct{}
+
+// Interrupt never allows the interruption.
+func (StrictTurnPolicy) Interrupt(TurnState) bool { return false }
+
+// Listen hears the caller only while the agent is silent.
+func (StrictTurnPolicy) Listen(state TurnState) bool { return !state.AgentSpeaking }
+
+// Hold holds a reply while the caller speaks or has an utterance pending.
+func (StrictTurnPolicy) Hold(state TurnState) bool { return state.UserSpeaking || state.UserPending }
+
+// YieldingTurnPolicy has the agent always yield: the caller may
+// interrupt, and a reply does not start speaking while the caller does.
+type YieldingTurnPolicy struct{}
+
+// Interrupt always allows the interruption.
+func (YieldingTurnPolicy) Interrupt(TurnState) bool { return true }
+
+// Listen always hears the caller.
+func (YieldingTurnPolicy) Listen(TurnState) bool { return true }
+
+// Hold holds a reply while the caller speaks or has an utterance pending.
+func (YieldingTurnPolicy) Hold(state TurnState) bool { return state.UserSpeaking || state.UserPending }
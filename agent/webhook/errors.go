@@ -0,0 +1,24 @@
+package webhook
+
+import "errors"
+
+var (
+	// ErrDeliveryFailed is reported to the dead-letter handler when a
+	// delivery is rejected or its retries are exhausted.
+	ErrDeliveryFailed = errors.New("webhook: delivery failed")
+
+	// ErrQueueFull is reported to the dead-letter handler when a delivery
+	// is discarded because the dispatcher is too far behind.
+	ErrQueueFull = errors.New("webhook: queue full")
+
+	// ErrClosed is reported for deliveries abandoned by Close.
+	ErrClosed = errors.New("webhook: dispatcher closed")
+
+	// ErrInvalidSignature is returned by Verify for a missing or wrong
+	// signature.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+
+	// ErrSignatureExpired is returned by Verify when the signed timestamp
+	// is outside the allowed tolerance.
+	ErrSignatureExpired = errors.New("webhook: signature timestamp outside tolerance")
+)
@@ -0,0 +1,251 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+// SchemaVersion is the payload schema version sent in Payload.Schema. It
+// changes only when a field is removed or changes meaning; new fields may
+// be added within a version.
+const SchemaVersion = "1"
+
+// maxBodySize bounds request bodies read by ParseRequest.
+const maxBodySize = 1 << 20
+
+// Payload is the JSON body of every webhook request: a common envelope
+// with type-specific Data.
+type Payload struct {
+	// Schema is SchemaVersion.
+	Schema string `json:"schema"`
+
+	// ID uniquely identifies the event and is repeated in the
+	// Idempotency-Key header; retries reuse it.
+	ID string `json:"id"`
+
+	// Type is one of the Type constants and determines Data.
+	Type string `json:"type"`
+
+	// SessionID is the session the event belongs to.
+	SessionID string `json:"session_id"`
+
+	// Timestamp is when the event occurred (RFC 3339).
+	Timestamp time.Time `json:"timestamp"`
+
+	// OffsetMs is Timestamp in session time, milliseconds since the
+	// session started (see agent.SessionClock).
+	OffsetMs int64 `json:"offset_ms,omitempty"`
+
+	// Data is a *SessionStart, *SessionEnd, *TurnComplete, *ToolCall, or
+	// *CallSummary.
+	Data any `json:"data"`
+}
+
+// SessionStart is the Data of TypeSessionStart.
+type SessionStart struct {
+	AgentName string            `json:"agent_name,omitempty"`
+	Language  string            `json:"language,omitempty"`
+	VoiceID   string            `json:"voice_id,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// SessionEnd is the Data of TypeSessionEnd: the final session metrics.
+type SessionEnd struct {
+	DurationMs            int `json:"duration_ms"`
+	TurnCount             int `json:"turn_count"`
+	UserSpeechDurationMs  int `json:"user_speech_duration_ms"`
+	AgentSpeechDurationMs int `json:"agent_speech_duration_ms"`
+	AvgSTTLatencyMs       int `json:"avg_stt_latency_ms"`
+	AvgLLMLatencyMs       int `json:"avg_llm_latency_ms"`
+	AvgTTSLatencyMs       int `json:"avg_tts_latency_ms"`
+	AvgTotalLatencyMs     int `json:"avg_total_latency_ms"`
+	AvgTimeToFirstAudioMs int `json:"avg_time_to_first_audio_ms"`
+	InterruptionCount     int `json:"interruption_count"`
+	ToolCallCount         int `json:"tool_call_count"`
+	ErrorCount            int `json:"error_count"`
+	DroppedAudioFrames    int `json:"dropped_audio_frames"`
+	DroppedEvents         int `json:"dropped_events"`
+
+	LLMInputTokens  int     `json:"llm_input_tokens"`
+	LLMOutputTokens int     `json:"llm_output_tokens"`
+	TTSCharacters   int     `json:"tts_characters"`
+	Cost            float64 `json:"cost"`
+
+	// Audio quality, when the transport measured it.
+	JitterMs        float64 `json:"jitter_ms,omitempty"`
+	PacketLossRate  float64 `json:"packet_loss_rate,omitempty"`
+	RoundTripTimeMs int     `json:"round_trip_time_ms,omitempty"`
+	MOS             float64 `json:"mos,omitempty"`
+
+	AvgTTSLatencyByProviderMs map[string]int `json:"avg_tts_latency_by_provider_ms,omitempty"`
+}
+
+// TurnComplete is the Data of TypeTurnComplete.
+type TurnComplete struct {
+	// Role is "user" or "agent".
+	Role      string    `json:"role"`
+	Text      string    `json:"text"`
+	StartedAt time.Time `json:"started_at"`
+	OffsetMs  int64     `json:"offset_ms,omitempty"`
+
+	DurationMs         int `json:"duration_ms,omitempty"`
+	LLMLatencyMs       int `json:"llm_latency_ms,omitempty"`
+	TimeToFirstAudioMs int `json:"time_to_first_audio_ms,omitempty"`
+
+	DTMF      string     `json:"dtmf,omitempty"`
+	Sentiment string     `json:"sentiment,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ToolCall is the Data of TypeToolCall, and an element of
+// TurnComplete.ToolCalls.
+type ToolCall struct {
+	Name       string         `json:"name"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Result     string         `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	DurationMs int            `json:"duration_ms"`
+}
+
+// CallSummary is the Data of TypeCallSummary.
+type CallSummary struct {
+	Summary string         `json:"summary,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+func sessionStart(config agent.Config) *SessionStart {
+	return &SessionStart{
+		AgentName: config.Name,
+		Language:  config.Language,
+		VoiceID:   config.VoiceID,
+		Metadata:  config.Metadata,
+	}
+}
+
+func sessionEnd(m agent.Metrics) *SessionEnd {
+	return &SessionEnd{
+		DurationMs:            m.SessionDurationMs,
+		TurnCount:             m.TurnCount,
+		UserSpeechDurationMs:  m.UserSpeechDurationMs,
+		AgentSpeechDurationMs: m.AgentSpeechDurationMs,
+		AvgSTTLatencyMs:       m.AvgSTTLatencyMs,
+		AvgLLMLatencyMs:       m.AvgLLMLatencyMs,
+		AvgTTSLatencyMs:       m.AvgTTSLatencyMs,
+		AvgTotalLatencyMs:     m.AvgTotalLatencyMs,
+		AvgTimeToFirstAudioMs: m.AvgTimeToFirstAudioMs,
+		InterruptionCount:     m.InterruptionCount,
+		ToolCallCount:         m.ToolCallCount,
+		ErrorCount:            m.ErrorCount,
+		DroppedAudioFrames:    m.DroppedAudioFrames,
+		DroppedEvents:         m.DroppedEvents,
+		LLMInputTokens:        m.Usage.LLMInputTokens,
+		LLMOutputTokens:       m.Usage.LLMOutputTokens,
+		TTSCharacters:         m.Usage.TTSCharacters,
+		Cost:                  m.Usage.Cost,
+		JitterMs:              float64(m.AudioQuality.Jitter) / float64(time.Millisecond),
+		PacketLossRate:        m.AudioQuality.LossRate,
+		RoundTripTimeMs:       int(m.AudioQuality.RoundTripTime.Milliseconds()),
+		MOS:                   m.AudioQuality.MOS,
+
+		AvgTTSLatencyByProviderMs: m.AvgTTSLatencyByProviderMs,
+	}
+}
+
+func turnComplete(t agent.Turn) *TurnComplete {
+	tc := &TurnComplete{
+		Role:               t.Role,
+		Text:               t.Text,
+		StartedAt:          t.Timestamp,
+		OffsetMs:           t.Offset.Milliseconds(),
+		DurationMs:         t.DurationMs,
+		LLMLatencyMs:       t.LLMLatencyMs,
+		TimeToFirstAudioMs: t.TimeToFirstAudioMs,
+		DTMF:               t.DTMF,
+		Sentiment:          t.Sentiment,
+	}
+	for _, c := range t.ToolCalls {
+		tc.ToolCalls = append(tc.ToolCalls, *toolCall(c))
+	}
+	return tc
+}
+
+func callSummary(ev agent.Event) *CallSummary {
+	s, _ := ev.Data.(agent.CallSummary)
+	cs := &CallSummary{Summary: s.Summary, Fields: s.Fields}
+	if ev.Error != nil {
+		cs.Error = ev.Error.Error()
+	}
+	return cs
+}
+
+func toolCall(c agent.ToolCall) *ToolCall {
+	return &ToolCall{
+		Name:       c.Name,
+		Arguments:  c.Arguments,
+		Result:     c.Result,
+		Error:      c.Error,
+		DurationMs: c.DurationMs,
+	}
+}
+
+// Parse decodes a webhook body, setting Data to the concrete type for
+// Type. Payloads of an unknown type keep Data as json.RawMessage; a
+// Schema other than SchemaVersion is an error.
+func Parse(body []byte) (*Payload, error) {
+	var raw struct {
+		Payload
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("webhook: parse payload: %w", err)
+	}
+	p := raw.Payload
+	if p.Schema != SchemaVersion {
+		return nil, fmt.Errorf("webhook: unsupported schema %q", p.Schema)
+	}
+
+	var data any
+	switch p.Type {
+	case TypeSessionStart:
+		data = new(SessionStart)
+	case TypeSessionEnd:
+		data = new(SessionEnd)
+	case TypeTurnComplete:
+		data = new(TurnComplete)
+	case TypeToolCall:
+		data = new(ToolCall)
+	case TypeCallSummary:
+		data = new(CallSummary)
+	default:
+		p.Data = raw.Data
+		return &p, nil
+	}
+	if len(raw.Data) > 0 {
+		if err := json.Unmarshal(raw.Data, data); err != nil {
+			return nil, fmt.Errorf("webhook: parse %s data: %w", p.Type, err)
+		}
+	}
+	p.Data = data
+	return &p, nil
+}
+
+// ParseRequest reads, verifies, and parses a webhook request. With an
+// empty secret the signature is not checked.
+func ParseRequest(r *http.Request, secret string) (*Payload, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if secret != "" {
+		if err := Verify(secret, r.Header.Get(SignatureHeader), body, 0); err != nil {
+			return nil, err
+		}
+	}
+	return Parse(body)
+}
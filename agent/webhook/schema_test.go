@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRequest(t *testing.T) {
+	body := []byte(`{"schema":"1","id":"e1","type":"tool.call","session_id":"s","timestamp":"2026-01-02T03:04:05Z","data":{"name":"lookup","duration_ms":12}}`)
+	r := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+	r.Header.Set(SignatureHeader, Sign(testSecret, time.Now(), body))
+	p, err := ParseRequest(r, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := p.Data.(*ToolCall); !ok || c.Name != "lookup" || c.DurationMs != 12 {
+		t.Errorf("Data %#v, want ToolCall lookup", p.Data)
+	}
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+	if _, err := ParseRequest(unsigned, testSecret); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("unsigned request: %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	unknown, err := Parse([]byte(`{"schema":"1","type":"future.event","data":{"x":1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, ok := unknown.Data.(json.RawMessage); !ok || string(raw) != `{"x":1}` {
+		t.Errorf("unknown type Data %#v, want the raw JSON", unknown.Data)
+	}
+	if _, err := Parse([]byte(`{"schema":"2","type":"tool.call"}`)); err == nil {
+		t.Error("Parse accepted schema 2")
+	}
+	if _, err := Parse([]byte(`{"schema":"1","type":"tool.call","data":[]}`)); err == nil {
+		t.Error("Parse accepted tool.call data of the wrong shape")
+	}
+}
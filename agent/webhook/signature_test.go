@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"schema":"1"}`)
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		want   error
+	}{
+		{"valid", Sign(testSecret, now, body), body, nil},
+		{"rotated secret", "t=" + ts + ",v1=" + mac("old", ts, body) + ",v1=" + mac(testSecret, ts, body), body, nil},
+		{"wrong secret", Sign("other", now, body), body, ErrInvalidSignature},
+		{"tampered body", Sign(testSecret, now, body), []byte(`{"schema":"2"}`), ErrInvalidSignature},
+		{"missing", "", body, ErrInvalidSignature},
+		{"no mac", "t=" + ts, body, ErrInvalidSignature},
+		{"too old", Sign(testSecret, now.Add(-DefaultTolerance-time.Minute), body), body, ErrSignatureExpired},
+		{"too new", Sign(testSecret, now.Add(DefaultTolerance+time.Minute), body), body, ErrSignatureExpired},
+	}
+	for _, tt := range tests {
+		err := Verify(testSecret, tt.header, tt.body, 0)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Package webhook delivers agent session events to the URLs in
+// agent.WebhookConfig.
+//
+// A Dispatcher turns session events into versioned JSON Payloads (see
+// SchemaVersion) and POSTs them from a background worker, so slow
+// receivers never block the session. Each payload carries an ID, also sent
+// as the Idempotency-Key header, that stays the same across retries.
+// Deliveries failing with a network error, timeout, 429, or 5xx are
+// retried with exponential backoff; rejected or exhausted deliveries go to
+// the dead-letter handler. With WebhookConfig.Secret set, requests are
+// signed (see SignatureHeader and Verify).
+//
+// Receiving services decode requests with ParseRequest and switch on the
+// type of Payload.Data.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+// Payload types.
+const (
+	TypeSessionStart = "session.start"
+	TypeSessionEnd   = "session.end"
+	TypeTurnComplete = "turn.complete"
+	TypeToolCall     = "tool.call"
+	TypeCallSummary  = "call.summary"
+)
+
+// Delivery is a payload addressed to a URL.
+type Delivery struct {
+	URL     string
+	Payload Payload
+
+	// Attempts is the number of requests made.
+	Attempts int
+}
+
+// DeadLetterFunc receives deliveries that could not be made. err wraps
+// ErrDeliveryFailed, ErrQueueFull, or ErrClosed.
+type DeadLetterFunc func(d Delivery, err error)
+
+// Option configures a Dispatcher.
+type Option func(*options)
+
+type options struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	queueSize   int
+	deadLetter  DeadLetterFunc
+}
+
+// WithHTTPClient sets the client used for deliveries, e.g. for proxies or
+// timeouts. Defaults to a client with a 10 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithRetries sets the maximum number of attempts per delivery and the
+// backoff before the first retry, which doubles up to maxBackoff.
+// Defaults to 5 attempts, 500ms, and 30s.
+func WithRetries(maxAttempts int, backoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.maxAttempts, o.backoff, o.maxBackoff = maxAttempts, backoff, maxBackoff
+	}
+}
+
+// WithQueueSize sets how many deliveries may wait for the worker.
+// Defaults to 256.
+func WithQueueSize(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithDeadLetter sets the handler for failed deliveries.
+func WithDeadLetter(fn DeadLetterFunc) Option {
+	return func(o *options) {
+		o.deadLetter = fn
+	}
+}
+
+// Dispatcher delivers the webhooks of one session.
+type Dispatcher struct {
+	session agent.Config
+	config  agent.WebhookConfig
+	opts    options
+	queue   chan Delivery
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New creates a dispatcher for a session's config.Webhooks and starts its
+// worker. Call Close when done.
+func New(config agent.Config, opts ...Option) *Dispatcher {
+	o := options{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 5,
+		backoff:     500 * time.Millisecond,
+		maxBackoff:  30 * time.Second,
+		queueSize:   256,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.maxAttempts = max(o.maxAttempts, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		session: config,
+		config:  config.Webhooks,
+		opts:    o,
+		queue:   make(chan Delivery, max(o.queueSize, 1)),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Enabled reports whether config has any webhook URL.
+func Enabled(config agent.WebhookConfig) bool {
+	return config.OnSessionStart != "" || config.OnSessionEnd != "" ||
+		config.OnTurnComplete != "" || config.OnToolCall != "" || config.OnCallSummary != ""
+}
+
+// Observe queues the webhook for a session event, if it has one:
+// EventSessionStarted, EventSessionEnded (with Metrics data),
+// EventUserTranscript and EventAgentTranscript (with Turn data),
+// EventToolCall (with ToolCall data), and EventCallSummary. It never
+// blocks.
+func (d *Dispatcher) Observe(sessionID string, ev agent.Event) {
+	p := Payload{Schema: SchemaVersion, SessionID: sessionID, Timestamp: ev.Timestamp, OffsetMs: ev.Offset.Milliseconds()}
+	var url string
+	switch ev.Type {
+	case agent.EventSessionStarted:
+		p.Type, url = TypeSessionStart, d.config.OnSessionStart
+		p.Data = sessionStart(d.session)
+	case agent.EventSessionEnded:
+		m, _ := ev.Data.(agent.Metrics)
+		p.Type, url = TypeSessionEnd, d.config.OnSessionEnd
+		p.Data = sessionEnd(m)
+	case agent.EventUserTranscript, agent.EventAgentTranscript:
+		t, ok := ev.Data.(agent.Turn)
+		if !ok {
+			return
+		}
+		p.Type, url = TypeTurnComplete, d.config.OnTurnComplete
+		p.Data = turnComplete(t)
+	case agent.EventToolCall:
+		c, ok := ev.Data.(agent.ToolCall)
+		if !ok {
+			return
+		}
+		p.Type, url = TypeToolCall, d.config.OnToolCall
+		p.Data = toolCall(c)
+	case agent.EventCallSummary:
+		p.Type, url = TypeCallSummary, d.config.OnCallSummary
+		p.Data = callSummary(ev)
+	}
+	if url == "" {
+		return
+	}
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now()
+	}
+	p.ID = newID()
+	d.Send(Delivery{URL: url, Payload: p})
+}
+
+// Send queues a delivery without blocking. If the queue is full or the
+// dispatcher closed, the delivery goes to the dead-letter handler.
+func (d *Dispatcher) Send(delivery Delivery) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.fail(delivery, ErrClosed)
+		return
+	}
+	select {
+	case d.queue <- delivery:
+	default:
+		d.fail(delivery, ErrQueueFull)
+	}
+}
+
+// Close stops accepting deliveries and waits for queued ones, including
+// their retries, until ctx ends; anything left is abandoned with
+// ErrClosed.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	defer d.cancel()
+	for delivery := range d.queue {
+		if d.ctx.Err() != nil {
+			d.fail(delivery, ErrClosed)
+			continue
+		}
+		d.deliver(delivery)
+	}
+}
+
+// deliver makes a delivery, retrying transient failures.
+func (d *Dispatcher) deliver(delivery Delivery) {
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		d.fail(delivery, fmt.Errorf("%w: %w", ErrDeliveryFailed, err))
+		return
+	}
+	backoff := d.opts.backoff
+	for {
+		delivery.Attempts++
+		retry, err := d.post(delivery, body)
+		if err == nil {
+			return
+		}
+		if !retry || delivery.Attempts >= d.opts.maxAttempts {
+			d.fail(delivery, fmt.Errorf("%w after %d attempts: %w", ErrDeliveryFailed, delivery.Attempts, err))
+			return
+		}
+		// Jitter keeps retries from many sessions apart.
+		wait := backoff/2 + mrand.N(backoff/2+1) // #nosec G404 -- jitter, not security
+		select {
+		case <-time.After(wait):
+		case <-d.ctx.Done():
+			d.fail(delivery, ErrClosed)
+			return
+		}
+		backoff = min(backoff*2, d.opts.maxBackoff)
+	}
+}
+
+// post makes one request, reporting whether a failure is worth retrying.
+func (d *Dispatcher) post(delivery Delivery, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "omnivoice-webhook")
+	req.Header.Set("Idempotency-Key", delivery.Payload.ID)
+	req.Header.Set("X-Omnivoice-Event", delivery.Payload.Type)
+	if d.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(d.config.Secret, time.Now(), body))
+	}
+
+	resp, err := d.opts.client.Do(req)
+	if err != nil {
+		return d.ctx.Err() == nil, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
+
+func (d *Dispatcher) fail(delivery Delivery, err error) {
+	if d.opts.deadLetter != nil {
+		d.opts.deadLetter(delivery, err)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
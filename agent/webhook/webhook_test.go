@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+const testSecret = "whsec_test"
+
+// request is what a test receiver saw of one delivery attempt.
+type request struct {
+	header  http.Header
+	payload *Payload
+	err     error
+}
+
+// receiver is an httptest.Server answering each attempt with the next
+// status in statuses, then 200.
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []request
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+	rc := &receiver{statuses: statuses}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := ParseRequest(r, testSecret)
+		rc.mu.Lock()
+		rc.requests = append(rc.requests, request{header: r.Header.Clone(), payload: p, err: err})
+		status := http.StatusOK
+		if len(rc.statuses) > 0 {
+			status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+		}
+		rc.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func (rc *receiver) received() []request {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]request(nil), rc.requests...)
+}
+
+// deadLetters records the dead-letter handler's calls.
+type deadLetters struct {
+	mu   sync.Mutex
+	errs []error
+	last Delivery
+}
+
+func (dl *deadLetters) handle(d Delivery, err error) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	dl.errs = append(dl.errs, err)
+	dl.last = d
+}
+
+func (dl *deadLetters) get() ([]error, Delivery) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	return append([]error(nil), dl.errs...), dl.last
+}
+
+func newDispatcher(t *testing.T, url string, dl *deadLetters, opts ...Option) *Dispatcher {
+	t.Helper()
+	config := agent.Config{
+		Name:     "support",
+		Webhooks: agent.WebhookConfig{OnTurnComplete: url, OnSessionStart: url, Secret: testSecret},
+	}
+	opts = append([]Option{WithRetries(3, time.Millisecond, time.Millisecond), WithDeadLetter(dl.handle)}, opts...)
+	return New(config, opts...)
+}
+
+func closeDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestDeliverySigned(t *testing.T) {
+	rc := newReceiver(t)
+	var dl deadLetters
+	d := newDispatcher(t, rc.URL, &dl)
+	d.Observe("sess-1", agent.Event{Type: agent.EventSessionStarted})
+	d.Observe("sess-1", agent.Event{
+		Type:   agent.EventUserTranscript,
+		Offset: 1500 * time.Millisecond,
+		Data:   agent.Turn{Role: "user", Text: "hello"},
+	})
+	d.Observe("sess-1", agent.Event{Type: agent.EventToolCall, Data: agent.ToolCall{Name: "lookup"}}) // no URL
+	closeDispatcher(t, d)
+
+	got := rc.received()
+	if len(got) != 2 {
+		t.Fatalf("received %d requests, want 2", len(got))
+	}
+	for _, r := range got {
+		if r.err != nil {
+			t.Fatalf("ParseRequest: %v", r.err)
+		}
+		if r.header.Get(SignatureHeader) == "" {
+			t.Errorf("%s request missing %s", r.payload.Type, SignatureHeader)
+		}
+		if r.header.Get("Idempotency-Key") != r.payload.ID || r.payload.ID == "" {
+			t.Errorf("Idempotency-Key %q, payload ID %q", r.header.Get("Idempotency-Key"), r.payload.ID)
+		}
+		if r.header.Get("X-Omnivoice-Event") != r.payload.Type {
+			t.Errorf("X-Omnivoice-Event %q, payload type %q", r.header.Get("X-Omnivoice-Event"), r.payload.Type)
+		}
+	}
+	if start, ok := got[0].payload.Data.(*SessionStart); !ok || start.AgentName != "support" {
+		t.Errorf("first delivery data %#v, want SessionStart for agent support", got[0].payload.Data)
+	}
+	turn, ok := got[1].payload.Data.(*TurnComplete)
+	if !ok || turn.Role != "user" || turn.Text != "hello" {
+		t.Errorf("second delivery data %#v, want the user turn", got[1].payload.Data)
+	}
+	if got[1].payload.SessionID != "sess-1" || got[1].payload.OffsetMs != 1500 {
+		t.Errorf("envelope session %q offset %d", got[1].payload.SessionID, got[1].payload.OffsetMs)
+	}
+	if errs, _ := dl.get(); len(errs) != 0 {
+		t.Errorf("dead letters %v", errs)
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	rc := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	var dl deadLetters
+	d := newDispatcher(t, rc.URL, &dl)
+	d.Observe("sess-1", agent.Event{Type: agent.EventSessionStarted})
+	closeDispatcher(t, d)
+
+	got := rc.received()
+	if len(got) != 3 {
+		t.Fatalf("received %d attempts, want 3", len(got))
+	}
+	for i, r := range got {
+		if r.err != nil {
+			t.Errorf("attempt %d: %v", i+1, r.err)
+		}
+		if r.payload.ID != got[0].payload.ID {
+			t.Errorf("attempt %d ID %q, want the first attempt's %q", i+1, r.payload.ID, got[0].payload.ID)
+		}
+	}
+	if errs, _ := dl.get(); len(errs) != 0 {
+		t.Errorf("dead letters %v", errs)
+	}
+}
+
+func TestDeliveryDeadLetters(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+	}{
+		{"exhausted", []int{500, 502, 503, 504}, 3},
+		{"rejected", []int{http.StatusBadRequest}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newReceiver(t, tt.statuses...)
+			var dl deadLetters
+			d := newDispatcher(t, rc.URL, &dl)
+			d.Observe("sess-1", agent.Event{Type: agent.EventSessionStarted})
+			closeDispatcher(t, d)
+
+			if n := len(rc.received()); n != tt.attempts {
+				t.Errorf("received %d attempts, want %d", n, tt.attempts)
+			}
+			errs, last := dl.get()
+			if len(errs) != 1 || !errors.Is(errs[0], ErrDeliveryFailed) {
+				t.Fatalf("dead letters %v, want one ErrDeliveryFailed", errs)
+			}
+			if last.Attempts != tt.attempts || last.URL != rc.URL {
+				t.Errorf("dead letter %d attempts to %q", last.Attempts, last.URL)
+			}
+		})
+	}
+}
+
+func TestDeliveryTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	var dl deadLetters
+	d := newDispatcher(t, srv.URL, &dl, WithHTTPClient(&http.Client{Timeout: 20 * time.Millisecond}))
+	d.Observe("sess-1", agent.Event{Type: agent.EventSessionStarted})
+	closeDispatcher(t, d)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Errorf("server saw %d attempts, want 3 (timeouts are retried)", attempts)
+	}
+	if errs, _ := dl.get(); len(errs) != 1 || !errors.Is(errs[0], ErrDeliveryFailed) {
+		t.Errorf("dead letters %v, want one ErrDeliveryFailed", errs)
+	}
+}
+
+func TestSendAfterClose(t *testing.T) {
+	var dl deadLetters
+	d := newDispatcher(t, "http://127.0.0.1:0", &dl)
+	closeDispatcher(t, d)
+	d.Observe("sess-1", agent.Event{Type: agent.EventSessionStarted})
+	if errs, _ := dl.get(); len(errs) != 1 || !errors.Is(errs[0], ErrClosed) {
+		t.Errorf("dead letters %v, want one ErrClosed", errs)
+	}
+}
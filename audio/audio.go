@@ -0,0 +1,94 @@
+// Package audio provides PCM audio utilities shared across OmniVoice packages.
+//
+// Unless stated otherwise, functions in this package operate on 16-bit
+// signed little-endian linear PCM, the lingua franca between transports,
+// STT providers, and TTS providers.
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// BytesPerSample is the size of a single 16-bit PCM sample.
+const BytesPerSample = 2
+
+// BytesToInt16 decodes little-endian 16-bit PCM bytes into samples.
+// A trailing odd byte is ignored.
+func BytesToInt16(b []byte) []int16 {
+	samples := make([]int16, len(b)/BytesPerSample)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(b[i*2:])) // #nosec G115 -- reinterpreting PCM bits
+	}
+	return samples
+}
+
+// Int16ToBytes encodes samples as little-endian 16-bit PCM bytes.
+func Int16ToBytes(samples []int16) []byte {
+	b := make([]byte, len(samples)*BytesPerSample)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(b[i*2:], uint16(s)) // #nosec G115 -- reinterpreting PCM bits
+	}
+	return b
+}
+
+// BytesPerSecond returns the byte rate of 16-bit PCM audio.
+func BytesPerSecond(sampleRate, channels int) int {
+	if channels <= 0 {
+		channels = 1
+	}
+	return sampleRate * channels * BytesPerSample
+}
+
+// RMS returns the root-mean-square level of samples, normalized to 0.0-1.0.
+func RMS(samples []int16) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		v := float64(s) / math.MaxInt16
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// Peak returns the absolute peak level of samples, normalized to 0.0-1.0.
+func Peak(samples []int16) float64 {
+	var peak int
+	for _, s := range samples {
+		v := int(s)
+		if v < 0 {
+			v = -v
+		}
+		if v > peak {
+			peak = v
+		}
+	}
+	return math.Min(float64(peak)/math.MaxInt16, 1)
+}
+
+// Downmix converts PCM interleaved across channels to mono, keeping the
+// given channel (0 for the left of a stereo pair), or averaging all of
+// them if channel is negative or out of range. A trailing partial frame is
+// ignored.
+func Downmix(pcm []byte, channels, channel int) []byte {
+	if channels <= 1 {
+		return pcm[:len(pcm)-len(pcm)%BytesPerSample]
+	}
+	samples := BytesToInt16(pcm)
+	mono := make([]int16, len(samples)/channels)
+	for i := range mono {
+		frame := samples[i*channels : (i+1)*channels]
+		if channel >= 0 && channel < channels {
+			mono[i] = frame[channel]
+			continue
+		}
+		var sum int
+		for _, s := range frame {
+			sum += int(s)
+		}
+		mono[i] = int16(sum / channels) // #nosec G115 -- the average of int16 samples fits
+	}
+	return Int16ToBytes(mono)
+}
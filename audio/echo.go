@@ -0,0 +1,196 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// EchoConfig configures an EchoCanceller. Zero fields use defaults.
+type EchoConfig struct {
+	// FilterLength is the longest echo tail cancelled. Longer filters
+	// handle more reverberant or delayed echo paths at proportionally
+	// higher CPU cost. Defaults to 64ms.
+	FilterLength time.Duration
+
+	// Delay is the bulk delay between the far-end signal being handed to
+	// Reference and its echo arriving at Process, e.g. transport jitter
+	// buffers. It shifts the filter window so FilterLength covers only the
+	// echo tail.
+	Delay time.Duration
+
+	// StepSize is the NLMS adaptation rate, between 0 and 1. Larger values
+	// converge faster but leave more residual echo. Defaults to 0.3.
+	StepSize float64
+
+	// MaxReferenceBuffer bounds how much far-end audio waits to be matched
+	// with near-end audio; older audio is discarded. Defaults to 2s.
+	MaxReferenceBuffer time.Duration
+}
+
+// EchoCanceller removes the echo of a far-end signal (the agent's speech)
+// from a near-end signal (the caller's microphone) with a normalized LMS
+// adaptive filter. Far-end audio passed to Reference is matched sample for
+// sample with near-end audio passed to Process, in the order each is
+// played and captured. Adaptation pauses during double talk so caller
+// speech over the agent (barge-in) is preserved.
+//
+// Reference and Process may be called from different goroutines.
+type EchoCanceller struct {
+	taps     int
+	step     float64
+	delay    int
+	maxQueue int
+	hold     int
+
+	mu sync.Mutex
+
+	// queue holds far-end samples not yet matched with near-end ones.
+	queue []float64
+
+	// x is the far-end history, stored twice so x[pos:pos+taps] is always
+	// contiguous, newest first; w holds the matching filter weights.
+	x      []float64
+	w      []float64
+	pos    int
+	energy float64
+
+	// farPeak decays over the filter length for double-talk detection;
+	// holding counts down samples of paused adaptation.
+	farPeak float64
+	decay   float64
+	holding int
+}
+
+// echoDoubleTalkHold is how long adaptation stays paused after double talk.
+const echoDoubleTalkHold = 30 * time.Millisecond
+
+// NewEchoCanceller creates an echo canceller for mono audio at sampleRate.
+func NewEchoCanceller(sampleRate int, config EchoConfig) *EchoCanceller {
+	sampleRate = max(sampleRate, 1)
+	samples := func(d time.Duration) int {
+		return int(int64(d) * int64(sampleRate) / int64(time.Second))
+	}
+	if config.FilterLength <= 0 {
+		config.FilterLength = 64 * time.Millisecond
+	}
+	if config.StepSize <= 0 || config.StepSize > 1 {
+		config.StepSize = 0.3
+	}
+	if config.MaxReferenceBuffer <= 0 {
+		config.MaxReferenceBuffer = 2 * time.Second
+	}
+	e := &EchoCanceller{
+		taps:     max(samples(config.FilterLength), 1),
+		step:     config.StepSize,
+		delay:    samples(config.Delay),
+		maxQueue: samples(config.MaxReferenceBuffer),
+		hold:     samples(echoDoubleTalkHold),
+	}
+	e.decay = math.Pow(0.001, 1/float64(e.taps))
+	e.x = make([]float64, 2*e.taps)
+	e.w = make([]float64, e.taps)
+	e.queue = make([]float64, e.delay)
+	return e
+}
+
+// Reference queues far-end samples as they are sent for playback.
+func (e *EchoCanceller) Reference(samples []int16) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range samples {
+		e.queue = append(e.queue, float64(s)/math.MaxInt16)
+	}
+	if over := len(e.queue) - e.maxQueue; over > 0 {
+		e.queue = append(e.queue[:0], e.queue[over:]...)
+	}
+}
+
+// ReferenceBytes is Reference for 16-bit PCM bytes.
+func (e *EchoCanceller) ReferenceBytes(b []byte) {
+	e.Reference(BytesToInt16(b))
+}
+
+// Queued returns the number of far-end samples, including the bulk
+// delay, waiting to be matched against near-end audio.
+func (e *EchoCanceller) Queued() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.queue)
+}
+
+// Process removes echo from near-end samples in place and returns them.
+// While no far-end audio is queued the filter sees silence, so the input
+// passes through unchanged apart from any echo tail still decaying.
+func (e *EchoCanceller) Process(near []int16) []int16 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := min(len(near), len(e.queue))
+	for i, s := range near {
+		var far float64
+		if i < n {
+			far = e.queue[i]
+		}
+		near[i] = e.cancel(float64(s)/math.MaxInt16, far)
+	}
+	e.queue = append(e.queue[:0], e.queue[n:]...)
+	return near
+}
+
+// ProcessBytes is Process for 16-bit PCM bytes, in place.
+func (e *EchoCanceller) ProcessBytes(b []byte) []byte {
+	copy(b, Int16ToBytes(e.Process(BytesToInt16(b))))
+	return b
+}
+
+// Reset discards the reference queue and the adapted filter, e.g. when
+// the echo path changes.
+func (e *EchoCanceller) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// The bulk delay is silence ahead of the first reference sample.
+	e.queue = append(e.queue[:0], make([]float64, e.delay)...)
+	clear(e.x)
+	clear(e.w)
+	e.pos, e.energy, e.farPeak, e.holding = 0, 0, 0, 0
+}
+
+// cancel processes one sample pair, returning the echo-free near sample.
+func (e *EchoCanceller) cancel(near, far float64) int16 {
+	if e.pos == 0 {
+		e.pos = e.taps
+		// Recompute the running energy once per lap to shed rounding
+		// drift.
+		e.energy = 0
+		for _, v := range e.x[:e.taps] {
+			e.energy += v * v
+		}
+	}
+	e.pos--
+	oldest := e.x[e.pos]
+	e.x[e.pos], e.x[e.pos+e.taps] = far, far
+	e.energy = math.Max(e.energy+far*far-oldest*oldest, 0)
+	x := e.x[e.pos : e.pos+e.taps]
+
+	var echo float64
+	for k, v := range x {
+		echo += e.w[k] * v
+	}
+	out := near - echo
+
+	// Geigel double-talk detection: near-end louder than half the recent
+	// far-end peak can't be echo alone.
+	e.farPeak = math.Max(math.Abs(far), e.farPeak*e.decay)
+	if math.Abs(near) > 0.5*e.farPeak {
+		e.holding = e.hold
+	}
+	if e.holding > 0 {
+		e.holding--
+	} else if e.energy > 1e-6 {
+		g := e.step * out / (e.energy + 1e-6)
+		for k, v := range x {
+			e.w[k] += g * v
+		}
+	}
+	return int16(math.Round(math.Max(-1, math.Min(1, out)) * math.MaxInt16))
+}
@@ -0,0 +1,23 @@
+package audio
+
+import "errors"
+
+var (
+	// ErrInvalidWAV is returned when WAV data is malformed.
+	ErrInvalidWAV = errors.New("audio: invalid WAV data")
+
+	// ErrCompressedFormat is returned when a PCM-only operation receives
+	// compressed or non-linear audio.
+	ErrCompressedFormat = errors.New("audio: compressed format not supported, linear PCM required")
+
+	// ErrFormatMismatch is returned when audio streams with different
+	// sample rates or channel counts are combined.
+	ErrFormatMismatch = errors.New("audio: format mismatch")
+
+	// ErrUnsupportedRate is returned when audio cannot be converted
+	// between two sample rates.
+	ErrUnsupportedRate = errors.New("audio: unsupported sample rate conversion")
+
+	// ErrSourceNotFound is returned when a named mixer source does not exist.
+	ErrSourceNotFound = errors.New("audio: source not found")
+)
@@ -0,0 +1,174 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Raw encodings understood by Framer.
+const (
+	EncodingPCM   = "pcm"   // 16-bit signed little-endian linear PCM
+	EncodingMuLaw = "mulaw" // G.711 mu-law, one byte per sample
+	EncodingALaw  = "alaw"  // G.711 A-law, one byte per sample
+)
+
+// DefaultFrameDuration is the frame length used when none is configured,
+// the usual packetization for telephony and realtime STT.
+const DefaultFrameDuration = 20 * time.Millisecond
+
+// containerMagic identifies file formats mistakenly sent as raw audio.
+var containerMagic = []struct {
+	prefix []byte
+	name   string
+}{
+	{[]byte("RIFF"), "WAV"},
+	{[]byte("ID3"), "MP3"},
+	{[]byte("OggS"), "Ogg"},
+	{[]byte("fLaC"), "FLAC"},
+	{[]byte{0x1A, 0x45, 0xDF, 0xA3}, "WebM"},
+}
+
+// SampleSize returns the bytes per sample of a raw encoding, or 0 if the
+// encoding is not a fixed-size raw format.
+func SampleSize(encoding string) int {
+	switch encoding {
+	case "", EncodingPCM:
+		return BytesPerSample
+	case EncodingMuLaw, EncodingALaw:
+		return 1
+	}
+	return 0
+}
+
+// Framer repacketizes a byte stream of arbitrarily sized writes into
+// fixed-duration frames. Partial frames are buffered across calls to Write
+// until they fill or Flush is called. It is not safe for concurrent use.
+type Framer struct {
+	encoding  string
+	frameSize int
+	sampleLen int
+	buf       []byte
+	started   bool
+}
+
+// NewFramer creates a framer for mono audio in a raw encoding ("pcm",
+// "mulaw", or "alaw") at sampleRate. frameDuration defaults to
+// DefaultFrameDuration. Compressed encodings such as Opus return
+// ErrCompressedFormat, since their packets cannot be split.
+func NewFramer(encoding string, sampleRate int, frameDuration time.Duration) (*Framer, error) {
+	size := SampleSize(encoding)
+	if size == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrCompressedFormat, encoding)
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("%w: sample rate %d", ErrFormatMismatch, sampleRate)
+	}
+	if frameDuration <= 0 {
+		frameDuration = DefaultFrameDuration
+	}
+	samples := max(1, int(time.Duration(sampleRate)*frameDuration/time.Second))
+	if encoding == "" {
+		encoding = EncodingPCM
+	}
+	return &Framer{encoding: encoding, frameSize: samples * size, sampleLen: size}, nil
+}
+
+// FrameSize returns the size of each frame in bytes.
+func (f *Framer) FrameSize() int {
+	return f.frameSize
+}
+
+// Buffered returns the number of bytes waiting for a full frame.
+func (f *Framer) Buffered() int {
+	return len(f.buf)
+}
+
+// Write adds audio and returns the complete frames now available. The
+// first write is checked for container headers (WAV, MP3, Ogg, FLAC,
+// WebM), which indicate encoded files rather than raw samples and return
+// ErrFormatMismatch.
+func (f *Framer) Write(p []byte) ([][]byte, error) {
+	if err := f.check(p); err != nil {
+		return nil, err
+	}
+	f.buf = append(f.buf, p...)
+	n := len(f.buf) / f.frameSize
+	if n == 0 {
+		return nil, nil
+	}
+	// The frames share one allocation; the partial frame left over moves
+	// to the front of buf, which is reused by the next Write.
+	all := append([]byte(nil), f.buf[:n*f.frameSize]...)
+	frames := make([][]byte, n)
+	for i := range frames {
+		frames[i] = all[i*f.frameSize : (i+1)*f.frameSize : (i+1)*f.frameSize]
+	}
+	f.buf = append(f.buf[:0], f.buf[n*f.frameSize:]...)
+	return frames, nil
+}
+
+// WriteFunc adds audio like Write but passes each complete frame to fn
+// instead of returning copies. Frames lying wholly within p are slices of
+// it, not copies, and the one completing a buffered partial frame is
+// reused by the next call, so fn must not retain a frame after it
+// returns. If fn fails, WriteFunc returns its error and drops the rest
+// of p.
+func (f *Framer) WriteFunc(p []byte, fn func(frame []byte) error) error {
+	if err := f.check(p); err != nil {
+		return err
+	}
+	if len(f.buf) > 0 {
+		n := min(f.frameSize-len(f.buf), len(p))
+		f.buf = append(f.buf, p[:n]...)
+		p = p[n:]
+		if len(f.buf) < f.frameSize {
+			return nil
+		}
+		err := fn(f.buf)
+		f.buf = f.buf[:0]
+		if err != nil {
+			return err
+		}
+	}
+	for len(p) >= f.frameSize {
+		if err := fn(p[:f.frameSize:f.frameSize]); err != nil {
+			return err
+		}
+		p = p[f.frameSize:]
+	}
+	f.buf = append(f.buf, p...)
+	return nil
+}
+
+// check rejects container headers on the first write.
+func (f *Framer) check(p []byte) error {
+	if f.started || len(p) == 0 {
+		return nil
+	}
+	f.started = true
+	for _, m := range containerMagic {
+		if bytes.HasPrefix(p, m.prefix) {
+			return fmt.Errorf("%w: got %s data, want raw %s", ErrFormatMismatch, m.name, f.encoding)
+		}
+	}
+	return nil
+}
+
+// Flush returns the buffered partial frame, if any, and empties the
+// buffer. A trailing partial sample is dropped and reported as
+// ErrFormatMismatch alongside the whole samples.
+func (f *Framer) Flush() ([]byte, error) {
+	out := f.buf
+	f.buf = nil
+	if extra := len(out) % f.sampleLen; extra != 0 {
+		return out[:len(out)-extra], fmt.Errorf("%w: %d stray bytes, not whole %s samples", ErrFormatMismatch, extra, f.encoding)
+	}
+	return out, nil
+}
+
+// Reset discards buffered audio and re-arms the header check.
+func (f *Framer) Reset() {
+	f.buf = nil
+	f.started = false
+}
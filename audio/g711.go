@@ -0,0 +1,137 @@
+package audio
+
+import "encoding/binary"
+
+// G.711 companding (ITU-T G.711) between 16-bit linear PCM and 8-bit
+// mu-law (PCMU) or A-law (PCMA), as used on telephony RTP streams.
+
+const (
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+// EncodeMuLaw converts 16-bit PCM bytes to mu-law bytes.
+func EncodeMuLaw(pcm []byte) []byte {
+	return AppendEncodeMuLaw(make([]byte, 0, len(pcm)/BytesPerSample), pcm)
+}
+
+// AppendEncodeMuLaw appends 16-bit PCM bytes encoded as mu-law to dst. A
+// trailing odd byte is ignored.
+func AppendEncodeMuLaw(dst, pcm []byte) []byte {
+	for i := 0; i+1 < len(pcm); i += BytesPerSample {
+		dst = append(dst, linearToMuLaw(int16(binary.LittleEndian.Uint16(pcm[i:])))) // #nosec G115 -- reinterpreting PCM bits
+	}
+	return dst
+}
+
+// DecodeMuLaw converts mu-law bytes to 16-bit PCM bytes.
+func DecodeMuLaw(ulaw []byte) []byte {
+	return AppendDecodeMuLaw(make([]byte, 0, len(ulaw)*BytesPerSample), ulaw)
+}
+
+// AppendDecodeMuLaw appends mu-law bytes decoded to 16-bit PCM to dst.
+func AppendDecodeMuLaw(dst, ulaw []byte) []byte {
+	for _, b := range ulaw {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(muLawToLinear(b))) // #nosec G115 -- reinterpreting PCM bits
+	}
+	return dst
+}
+
+// EncodeALaw converts 16-bit PCM bytes to A-law bytes.
+func EncodeALaw(pcm []byte) []byte {
+	return AppendEncodeALaw(make([]byte, 0, len(pcm)/BytesPerSample), pcm)
+}
+
+// AppendEncodeALaw appends 16-bit PCM bytes encoded as A-law to dst. A
+// trailing odd byte is ignored.
+func AppendEncodeALaw(dst, pcm []byte) []byte {
+	for i := 0; i+1 < len(pcm); i += BytesPerSample {
+		dst = append(dst, linearToALaw(int16(binary.LittleEndian.Uint16(pcm[i:])))) // #nosec G115 -- reinterpreting PCM bits
+	}
+	return dst
+}
+
+// DecodeALaw converts A-law bytes to 16-bit PCM bytes.
+func DecodeALaw(alaw []byte) []byte {
+	return AppendDecodeALaw(make([]byte, 0, len(alaw)*BytesPerSample), alaw)
+}
+
+// AppendDecodeALaw appends A-law bytes decoded to 16-bit PCM to dst.
+func AppendDecodeALaw(dst, alaw []byte) []byte {
+	for _, b := range alaw {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(aLawToLinear(b))) // #nosec G115 -- reinterpreting PCM bits
+	}
+	return dst
+}
+
+func linearToMuLaw(sample int16) byte {
+	s := int(sample)
+	sign := 0
+	if s < 0 {
+		s = -s
+		sign = 0x80
+	}
+	if s > ulawClip {
+		s = ulawClip
+	}
+	s += ulawBias
+	exponent := 7
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (s >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+func muLawToLinear(b byte) int16 {
+	b = ^b
+	sign := b & 0x80
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0F)
+	s := ((mantissa << 3) + ulawBias) << exponent
+	s -= ulawBias
+	if sign != 0 {
+		s = -s
+	}
+	return int16(s) // #nosec G115 -- bounded by G.711 range
+}
+
+func linearToALaw(sample int16) byte {
+	s := int(sample)
+	sign := 0x80
+	if s < 0 {
+		s = -s - 1
+		sign = 0
+	}
+	if s > 32767 {
+		s = 32767
+	}
+	var out int
+	if s < 256 {
+		out = s >> 4
+	} else {
+		exponent := 7
+		for mask := 0x4000; s&mask == 0 && exponent > 1; mask >>= 1 {
+			exponent--
+		}
+		out = exponent<<4 | (s>>(exponent+3))&0x0F
+	}
+	return byte((out | sign) ^ 0x55) // #nosec G115 -- 8-bit code
+}
+
+func aLawToLinear(b byte) int16 {
+	b ^= 0x55
+	sign := b & 0x80
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0F)
+	var s int
+	if exponent == 0 {
+		s = mantissa<<4 + 8
+	} else {
+		s = (mantissa<<4 + 0x108) << (exponent - 1)
+	}
+	if sign == 0 {
+		s = -s
+	}
+	return int16(s) // #nosec G115 -- bounded by G.711 range
+}
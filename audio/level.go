@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"math"
+	"sync"
+)
+
+// LevelMeter accumulates the RMS and peak level of audio between reads,
+// for level meters and speaking indicators. Adding samples costs a
+// multiply-add each, so it can run on every frame. It is safe for
+// concurrent use.
+type LevelMeter struct {
+	mu    sync.Mutex
+	sumSq float64
+	n     int
+	peak  int
+}
+
+// Add measures samples.
+func (m *LevelMeter) Add(samples []int16) {
+	var sum float64
+	var peak int
+	for _, s := range samples {
+		v := int(s)
+		sum += float64(v * v)
+		if v < 0 {
+			v = -v
+		}
+		peak = max(peak, v)
+	}
+	m.add(sum, len(samples), peak)
+}
+
+// AddBytes measures 16-bit little-endian PCM without converting it. A
+// trailing odd byte is ignored.
+func (m *LevelMeter) AddBytes(pcm []byte) {
+	var sum float64
+	var peak int
+	n := len(pcm) / BytesPerSample
+	for i := range n {
+		v := int(int16(uint16(pcm[2*i]) | uint16(pcm[2*i+1])<<8))
+		sum += float64(v * v)
+		if v < 0 {
+			v = -v
+		}
+		peak = max(peak, v)
+	}
+	m.add(sum, n, peak)
+}
+
+func (m *LevelMeter) add(sum float64, n, peak int) {
+	if n == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sumSq += sum
+	m.n += n
+	m.peak = max(m.peak, peak)
+}
+
+// Read returns the RMS and peak level, normalized to 0.0-1.0, of the
+// samples added since the last Read, and resets the meter. ok is false
+// if none were added.
+func (m *LevelMeter) Read() (rms, peak float64, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.n == 0 {
+		return 0, 0, false
+	}
+	rms = math.Min(math.Sqrt(m.sumSq/float64(m.n))/math.MaxInt16, 1)
+	peak = math.Min(float64(m.peak)/math.MaxInt16, 1)
+	m.sumSq, m.n, m.peak = 0, 0, 0
+	return rms, peak, true
+}
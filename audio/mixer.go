@@ -0,0 +1,270 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// softClipKnee is the level above which the mixer compresses peaks.
+const softClipKnee = 0.8
+
+// Mixer combines multiple PCM sources into a single stream, for example
+// agent speech over hold music, whisper coaching, or sound effects.
+//
+// Mixer is pull-based: each Read pulls the same number of bytes from every
+// source, applies per-source gain, sums, and soft-clips the result. Sources
+// are read synchronously, so live sources should be buffered (e.g., with
+// io.Pipe fed ahead of playback) to avoid stalling the mix. A source that
+// returns io.EOF is removed automatically; its missing tail is silence.
+// Sources may be added, removed, and re-gained while the mixer is in use,
+// even while a Read waits on a slow source; a source removed or replaced
+// during a Read is left out of that Read's mix.
+type Mixer struct {
+	// readMu serializes Read, which reads the sources outside mu.
+	readMu sync.Mutex
+
+	mu      sync.Mutex
+	format  Format
+	sources map[string]*mixerSource
+	order   []string
+}
+
+type mixerSource struct {
+	reader io.Reader
+	gain   float64
+
+	// buf is read into under the mixer's readMu.
+	buf []byte
+
+	// target is the gain a fade moves towards by step per sample.
+	target float64
+	step   float64
+}
+
+// NewMixer creates a mixer producing audio in the given format.
+func NewMixer(format Format) *Mixer {
+	if format.Channels <= 0 {
+		format.Channels = 1
+	}
+	format.BitsPerSample = 8 * BytesPerSample
+	return &Mixer{format: format, sources: make(map[string]*mixerSource)}
+}
+
+// Format returns the mixer output format.
+func (m *Mixer) Format() Format {
+	return m.format
+}
+
+// AddSource adds or replaces a named source. gain is a linear multiplier
+// (1.0 = unity; see DBToGain). Sources whose sample rate or channel count
+// differ from the mixer format return ErrFormatMismatch.
+func (m *Mixer) AddSource(name string, r io.Reader, format Format, gain float64) error {
+	if format.Channels <= 0 {
+		format.Channels = 1
+	}
+	if format.SampleRate != m.format.SampleRate || format.Channels != m.format.Channels {
+		return fmt.Errorf("%w: source %q is %dHz/%dch, mixer is %dHz/%dch", ErrFormatMismatch,
+			name, format.SampleRate, format.Channels, m.format.SampleRate, m.format.Channels)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sources[name]; !ok {
+		m.order = append(m.order, name)
+	}
+	m.sources[name] = &mixerSource{reader: r, gain: gain, target: gain}
+	return nil
+}
+
+// RemoveSource removes a named source. Removing an unknown source is a no-op.
+func (m *Mixer) RemoveSource(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(name)
+}
+
+func (m *Mixer) removeLocked(name string) {
+	if _, ok := m.sources[name]; !ok {
+		return
+	}
+	delete(m.sources, name)
+	for i, n := range m.order {
+		if n == name {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// SetGain changes the linear gain of a source.
+func (m *Mixer) SetGain(name string, gain float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, ok := m.sources[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+	}
+	src.gain, src.target, src.step = gain, gain, 0
+	return nil
+}
+
+// FadeGain moves the linear gain of a source to gain over d, avoiding the
+// click of an abrupt change. A fade replaces any fade in progress; d <= 0
+// is SetGain.
+func (m *Mixer) FadeGain(name string, gain float64, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, ok := m.sources[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+	}
+	samples := int64(d) * int64(m.format.SampleRate) / int64(time.Second)
+	if samples <= 0 {
+		src.gain, src.target, src.step = gain, gain, 0
+		return nil
+	}
+	src.target, src.step = gain, (gain-src.gain)/float64(samples)
+	return nil
+}
+
+// Gain returns the linear gain of a source, or the gain it is fading to.
+func (m *Mixer) Gain(name string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, ok := m.sources[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+	}
+	return src.target, nil
+}
+
+// Sources returns the names of the current sources in insertion order.
+func (m *Mixer) Sources() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.order...)
+}
+
+// Read fills p with mixed audio. With no sources it returns silence, so the
+// output stream never stalls. len(p) is rounded down to a whole frame.
+func (m *Mixer) Read(p []byte) (int, error) {
+	align := BytesPerSample * m.format.Channels
+	n := len(p) - len(p)%align
+	if n == 0 {
+		return 0, nil
+	}
+
+	m.readMu.Lock()
+	defer m.readMu.Unlock()
+
+	// Read the sources without holding mu, so a slow one does not block
+	// AddSource, SetGain, and the like.
+	m.mu.Lock()
+	names := append([]string(nil), m.order...)
+	sources := make([]*mixerSource, len(names))
+	for i, name := range names {
+		sources[i] = m.sources[name]
+	}
+	m.mu.Unlock()
+	reads := make([]int, len(sources))
+	errs := make([]error, len(sources))
+	for i, src := range sources {
+		if cap(src.buf) < n {
+			src.buf = make([]byte, n)
+		}
+		reads[i], errs[i] = io.ReadFull(src.reader, src.buf[:n])
+		if err := errs[i]; err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, fmt.Errorf("audio: mixer source %q: %w", names[i], err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc := make([]float64, n/BytesPerSample)
+	for i, src := range sources {
+		if m.sources[names[i]] != src {
+			// Removed or replaced while being read.
+			continue
+		}
+		read := reads[i]
+		for j, s := range BytesToInt16(src.buf[:read-read%BytesPerSample]) {
+			if src.step != 0 && j%m.format.Channels == 0 {
+				src.fade()
+			}
+			acc[j] += float64(s) / math.MaxInt16 * src.gain
+		}
+		if errs[i] != nil {
+			m.removeLocked(names[i])
+		}
+	}
+
+	out := make([]int16, len(acc))
+	for i, v := range acc {
+		out[i] = int16(math.Round(SoftClip(v) * math.MaxInt16))
+	}
+	copy(p, Int16ToBytes(out))
+	return n, nil
+}
+
+// fade advances a fade by one sample.
+func (src *mixerSource) fade() {
+	src.gain += src.step
+	if (src.step > 0 && src.gain >= src.target) || (src.step < 0 && src.gain <= src.target) {
+		src.gain, src.step = src.target, 0
+	}
+}
+
+// Run writes mixed audio to w in frame-sized chunks at real-time pace until
+// ctx is done or a write fails. frame is the chunk duration (e.g., 20ms).
+func (m *Mixer) Run(ctx context.Context, w io.Writer, frame time.Duration) error {
+	if frame <= 0 {
+		frame = 20 * time.Millisecond
+	}
+	size := int(int64(BytesPerSecond(m.format.SampleRate, m.format.Channels)) * int64(frame) / int64(time.Second))
+	buf := make([]byte, size)
+	ticker := time.NewTicker(frame)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			n, err := m.Read(buf)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// SoftClip limits a normalized sample to [-1, 1], compressing peaks above
+// the knee smoothly instead of hard clipping.
+func SoftClip(v float64) float64 {
+	a := math.Abs(v)
+	if a <= softClipKnee {
+		return v
+	}
+	y := softClipKnee + (1-softClipKnee)*math.Tanh((a-softClipKnee)/(1-softClipKnee))
+	return math.Copysign(y, v)
+}
+
+// ApplyGain scales 16-bit PCM by a linear gain, soft-clipping peaks.
+func ApplyGain(pcm []byte, gain float64) []byte {
+	samples := BytesToInt16(pcm[:len(pcm)-len(pcm)%BytesPerSample])
+	for i, s := range samples {
+		samples[i] = int16(math.Round(SoftClip(float64(s)/math.MaxInt16*gain) * math.MaxInt16))
+	}
+	return Int16ToBytes(samples)
+}
+
+// DBToGain converts a level in decibels to a linear gain multiplier.
+func DBToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
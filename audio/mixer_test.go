@@ -0,0 +1,83 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestMixerRead(t *testing.T) {
+	format := Format{SampleRate: 8000, Channels: 1}
+	m := NewMixer(format)
+	quarter := int16(8192)
+	a := Int16ToBytes([]int16{quarter, quarter, quarter, quarter})
+	b := Int16ToBytes([]int16{quarter, quarter})
+	if err := m.AddSource("a", bytes.NewReader(a), format, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddSource("b", bytes.NewReader(b), format, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	p := make([]byte, 8)
+	if _, err := m.Read(p); err != nil {
+		t.Fatal(err)
+	}
+	got := BytesToInt16(p)
+	if got[0] <= quarter || got[3] != quarter {
+		t.Errorf("mix = %v, want both sources in the first samples and only a in the last", got)
+	}
+	if names := m.Sources(); len(names) != 1 || names[0] != "a" {
+		t.Errorf("Sources() = %v, want [a] once b reached EOF", names)
+	}
+}
+
+// blockingReader blocks in Read until release is closed.
+type blockingReader struct {
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	close(r.reading)
+	<-r.release
+	return 0, io.EOF
+}
+
+func TestMixerReadDoesNotBlockControl(t *testing.T) {
+	format := Format{SampleRate: 8000, Channels: 1}
+	m := NewMixer(format)
+	slow := &blockingReader{reading: make(chan struct{}), release: make(chan struct{})}
+	if err := m.AddSource("slow", slow, format, 1); err != nil {
+		t.Fatal(err)
+	}
+	read := make(chan error, 1)
+	go func() {
+		_, err := m.Read(make([]byte, 320))
+		read <- err
+	}()
+	<-slow.reading
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = m.SetGain("slow", 0.5)
+		_ = m.AddSource("music", bytes.NewReader(nil), format, 1)
+		m.Sources()
+		m.RemoveSource("slow")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("mixer controls blocked on a source being read")
+	}
+
+	close(slow.release)
+	if err := <-read; err != nil {
+		t.Fatal(err)
+	}
+	if names := m.Sources(); len(names) != 1 || names[0] != "music" {
+		t.Errorf("Sources() = %v, want [music]", names)
+	}
+}
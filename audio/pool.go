@@ -0,0 +1,56 @@
+package audio
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// Buffers smaller than minPoolClass or larger than maxPoolClass bytes are
+// not pooled: small ones are cheap to allocate, and large ones would pin
+// memory after a burst.
+const (
+	minPoolClass = 6  // 64 bytes
+	maxPoolClass = 17 // 128 KiB
+)
+
+// bufferPools holds buffers by size class: class c holds buffers of at
+// least 1<<c bytes capacity.
+var bufferPools [maxPoolClass + 1]sync.Pool
+
+// headers holds the emptied *[]byte of buffers taken from bufferPools, so
+// PutBuffer need not allocate one to pool a buffer.
+var headers sync.Pool
+
+// GetBuffer returns a buffer of length n from a shared pool, for audio
+// frames that are handed on and later returned with PutBuffer. Its
+// contents are undefined.
+func GetBuffer(n int) []byte {
+	c := bits.Len(uint(n - 1)) // the smallest class holding n bytes
+	if n <= 0 || c > maxPoolClass {
+		return make([]byte, n)
+	}
+	c = max(c, minPoolClass)
+	if h, ok := bufferPools[c].Get().(*[]byte); ok {
+		b := (*h)[:n]
+		*h = nil
+		headers.Put(h)
+		return b
+	}
+	return make([]byte, n, 1<<c)
+}
+
+// PutBuffer returns b to the pool for reuse by GetBuffer. b need not have
+// come from GetBuffer, but the caller must own it alone: neither it nor
+// anything it was handed to may use b afterwards.
+func PutBuffer(b []byte) {
+	c := bits.Len(uint(cap(b))) - 1 // the largest class b can serve
+	if c < minPoolClass || c > maxPoolClass {
+		return
+	}
+	h, ok := headers.Get().(*[]byte)
+	if !ok {
+		h = new([]byte)
+	}
+	*h = b[:0]
+	bufferPools[c].Put(h)
+}
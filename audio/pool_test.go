@@ -0,0 +1,71 @@
+package audio
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGetBuffer(t *testing.T) {
+	for _, n := range []int{0, 1, 160, 320, 640, 1 << maxPoolClass, 1<<maxPoolClass + 1} {
+		b := GetBuffer(n)
+		if len(b) != n {
+			t.Errorf("GetBuffer(%d) has length %d", n, len(b))
+		}
+		PutBuffer(b)
+	}
+
+	b := GetBuffer(320)
+	PutBuffer(b)
+	if c := cap(GetBuffer(300)); c < 300 || c > 512 {
+		t.Errorf("GetBuffer(300) has capacity %d, want the 512-byte class", c)
+	}
+}
+
+// BenchmarkFramePath measures converting 20ms mu-law frames at 8 kHz to
+// 16 kHz PCM for STT, over each of many sessions, as a session's
+// SendAudio does: with pooled buffers handed back once a frame is
+// written, and with a fresh allocation at each step. gc/op counts
+// garbage collections.
+func BenchmarkFramePath(b *testing.B) {
+	const sessions = 500
+	frame := EncodeMuLaw(Int16ToBytes(sine(8000, 440, 0.02, 0.5)))
+	newResamplers := func(b *testing.B) []*Resampler {
+		rs := make([]*Resampler, sessions)
+		for i := range rs {
+			r, err := NewResampler(8000, 16000)
+			if err != nil {
+				b.Fatal(err)
+			}
+			rs[i] = r
+		}
+		return rs
+	}
+	run := func(b *testing.B, convert func(r *Resampler)) {
+		rs := newResamplers(b)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		b.ReportAllocs()
+		b.SetBytes(int64(len(frame) * sessions))
+		for b.Loop() {
+			for _, r := range rs {
+				convert(r)
+			}
+		}
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
+	}
+
+	b.Run("pooled", func(b *testing.B) {
+		run(b, func(r *Resampler) {
+			pcm := AppendDecodeMuLaw(GetBuffer(len(frame) * BytesPerSample)[:0], frame)
+			out := r.AppendBytes(GetBuffer(2*len(pcm) + BytesPerSample)[:0], pcm)
+			PutBuffer(pcm)
+			PutBuffer(out)
+		})
+	})
+	b.Run("allocating", func(b *testing.B) {
+		run(b, func(r *Resampler) {
+			r.ProcessBytes(DecodeMuLaw(frame))
+		})
+	})
+}
@@ -0,0 +1,273 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// PreprocessConfig selects the stages of a Preprocessor. Zero numeric
+// fields use the defaults of DefaultPreprocessConfig.
+type PreprocessConfig struct {
+	// HighPass removes rumble and DC offset below HighPassCutoff.
+	HighPass bool
+
+	// HighPassCutoff is the high-pass corner frequency in Hz.
+	HighPassCutoff float64
+
+	// NoiseSuppression attenuates frames near the tracked noise floor.
+	// It is a broadband, energy-based suppressor: cheap enough for every
+	// frame, but it cannot separate noise from speech that overlaps it.
+	NoiseSuppression bool
+
+	// NoiseReduction is the maximum attenuation of noise in dB.
+	NoiseReduction float64
+
+	// AGC adjusts the speech level towards AGCTarget.
+	AGC bool
+
+	// AGCTarget is the RMS level (0.0-1.0) AGC aims for.
+	AGCTarget float64
+
+	// AGCMaxGain is the largest gain factor AGC applies, so quiet noise is
+	// not boosted indefinitely.
+	AGCMaxGain float64
+}
+
+// DefaultPreprocessConfig enables every stage with settings tuned for
+// 8-16 kHz telephony speech: a 100 Hz high-pass, up to 12 dB of noise
+// reduction, and AGC towards -20 dBFS with at most 18 dB of gain.
+func DefaultPreprocessConfig() PreprocessConfig {
+	return PreprocessConfig{
+		HighPass:         true,
+		HighPassCutoff:   100,
+		NoiseSuppression: true,
+		NoiseReduction:   12,
+		AGC:              true,
+		AGCTarget:        0.1,
+		AGCMaxGain:       8,
+	}
+}
+
+// PreprocessStats reports the work done by a Preprocessor.
+type PreprocessStats struct {
+	// Frames is the number of frames processed.
+	Frames int
+
+	// AvgFrameCost and MaxFrameCost are the wall-clock time spent per
+	// frame. Compare them with the frame duration to judge whether
+	// preprocessing fits a real-time budget.
+	AvgFrameCost time.Duration
+	MaxFrameCost time.Duration
+
+	// NoiseFloor is the current noise floor estimate as an RMS level.
+	NoiseFloor float64
+
+	// Gain is the current AGC gain factor.
+	Gain float64
+}
+
+// Preprocessor conditions mono PCM before speech recognition: high-pass
+// filter, then noise suppression, then AGC, each optional. Frames are
+// processed in place, and state carries across frames, so use one
+// Preprocessor per audio stream. It is not safe for concurrent use.
+type Preprocessor struct {
+	config     PreprocessConfig
+	sampleRate int
+
+	// High-pass biquad coefficients and state.
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+
+	noiseFloor float64
+	nsGain     float64
+	agcGain    float64
+	buf        []float64
+
+	frames    int
+	totalCost time.Duration
+	maxCost   time.Duration
+}
+
+// NewPreprocessor creates a preprocessor for audio at sampleRate.
+func NewPreprocessor(sampleRate int, config PreprocessConfig) *Preprocessor {
+	def := DefaultPreprocessConfig()
+	if config.HighPassCutoff <= 0 {
+		config.HighPassCutoff = def.HighPassCutoff
+	}
+	if config.NoiseReduction <= 0 {
+		config.NoiseReduction = def.NoiseReduction
+	}
+	if config.AGCTarget <= 0 {
+		config.AGCTarget = def.AGCTarget
+	}
+	if config.AGCMaxGain <= 0 {
+		config.AGCMaxGain = def.AGCMaxGain
+	}
+	p := &Preprocessor{config: config, sampleRate: max(sampleRate, 1)}
+
+	// RBJ cookbook second-order Butterworth high-pass.
+	cutoff := math.Min(config.HighPassCutoff, float64(p.sampleRate)/2*0.9)
+	w0 := 2 * math.Pi * cutoff / float64(p.sampleRate)
+	cos, alpha := math.Cos(w0), math.Sin(w0)/math.Sqrt2
+	a0 := 1 + alpha
+	p.b0 = (1 + cos) / 2 / a0
+	p.b1 = -(1 + cos) / a0
+	p.b2 = p.b0
+	p.a1 = -2 * cos / a0
+	p.a2 = (1 - alpha) / a0
+
+	p.Reset()
+	return p
+}
+
+// Process conditions a frame of samples in place and returns it.
+func (p *Preprocessor) Process(frame []int16) []int16 {
+	if len(frame) == 0 {
+		return frame
+	}
+	start := time.Now()
+
+	if cap(p.buf) < len(frame) {
+		p.buf = make([]float64, len(frame))
+	}
+	buf := p.buf[:len(frame)]
+	for i, s := range frame {
+		buf[i] = float64(s) / math.MaxInt16
+	}
+	if p.config.HighPass {
+		p.highPass(buf)
+	}
+
+	// The noise floor is tracked even without suppression, so AGC can tell
+	// speech from background.
+	seconds := float64(len(frame)) / float64(p.sampleRate)
+	level := rms(buf)
+	p.trackNoise(level, seconds)
+
+	if p.config.NoiseSuppression {
+		prev := p.nsGain
+		p.nsGain = p.suppression(level)
+		rampGain(buf, prev, p.nsGain)
+		level *= p.nsGain
+	}
+	if p.config.AGC {
+		prev := p.agcGain
+		p.updateAGC(level, seconds)
+		rampGain(buf, prev, p.agcGain)
+	}
+
+	for i, v := range buf {
+		frame[i] = int16(math.Round(math.Max(-1, math.Min(1, v)) * math.MaxInt16))
+	}
+
+	cost := time.Since(start)
+	p.frames++
+	p.totalCost += cost
+	p.maxCost = max(p.maxCost, cost)
+	return frame
+}
+
+// ProcessBytes conditions 16-bit PCM bytes in place and returns them. A
+// trailing odd byte is left untouched.
+func (p *Preprocessor) ProcessBytes(b []byte) []byte {
+	samples := p.Process(BytesToInt16(b))
+	copy(b, Int16ToBytes(samples))
+	return b
+}
+
+// Stats returns processing statistics.
+func (p *Preprocessor) Stats() PreprocessStats {
+	st := PreprocessStats{
+		Frames:       p.frames,
+		MaxFrameCost: p.maxCost,
+		NoiseFloor:   p.noiseFloor,
+		Gain:         p.agcGain,
+	}
+	if p.frames > 0 {
+		st.AvgFrameCost = p.totalCost / time.Duration(p.frames)
+	}
+	return st
+}
+
+// Reset clears filter and level state, e.g. between calls.
+func (p *Preprocessor) Reset() {
+	p.x1, p.x2, p.y1, p.y2 = 0, 0, 0, 0
+	p.noiseFloor = 0
+	p.nsGain = 1
+	p.agcGain = 1
+}
+
+func (p *Preprocessor) highPass(buf []float64) {
+	for i, x := range buf {
+		y := p.b0*x + p.b1*p.x1 + p.b2*p.x2 - p.a1*p.y1 - p.a2*p.y2
+		p.x2, p.x1 = p.x1, x
+		p.y2, p.y1 = p.y1, y
+		buf[i] = y
+	}
+}
+
+// noiseFloorMin keeps the floor estimate away from zero in digital
+// silence.
+const noiseFloorMin = 1e-4
+
+// trackNoise follows the noise floor: it drops to quieter frames almost at
+// once and rises slowly (about 3 dB per second), so speech barely moves it.
+func (p *Preprocessor) trackNoise(level, seconds float64) {
+	level = math.Max(level, noiseFloorMin)
+	switch {
+	case p.noiseFloor == 0:
+		p.noiseFloor = level
+	case level < p.noiseFloor:
+		p.noiseFloor += (level - p.noiseFloor) * 0.5
+	default:
+		p.noiseFloor = math.Min(level, p.noiseFloor*math.Pow(10, 3.0/20*seconds))
+	}
+}
+
+// suppression returns the noise suppression gain for a frame: a Wiener-
+// style gain on the frame's estimated SNR, bounded by NoiseReduction, and
+// released gradually so word endings are not chopped.
+func (p *Preprocessor) suppression(level float64) float64 {
+	floor := math.Pow(10, -p.config.NoiseReduction/20)
+	g := floor
+	if level > 0 {
+		ratio := p.noiseFloor / level
+		g = math.Max(1-ratio*ratio, floor)
+	}
+	if g < p.nsGain {
+		g = p.nsGain + (g-p.nsGain)*0.3
+	}
+	return g
+}
+
+// updateAGC moves the gain towards AGCTarget during speech, quickly when
+// the level is too high and slowly when too low. Frames near the noise
+// floor leave the gain unchanged.
+func (p *Preprocessor) updateAGC(level, seconds float64) {
+	if level < p.noiseFloor*2 || level <= 0 {
+		return
+	}
+	want := math.Max(0.1, math.Min(p.config.AGCTarget/level, p.config.AGCMaxGain))
+	tau := 0.5
+	if want < p.agcGain {
+		tau = 0.01
+	}
+	p.agcGain += (want - p.agcGain) * (1 - math.Exp(-seconds/tau))
+}
+
+// rampGain applies a gain moving linearly from prev to next across buf,
+// avoiding clicks at frame boundaries.
+func rampGain(buf []float64, prev, next float64) {
+	step := (next - prev) / float64(len(buf))
+	for i := range buf {
+		buf[i] *= prev + step*float64(i+1)
+	}
+}
+
+func rms(buf []float64) float64 {
+	var sum float64
+	for _, v := range buf {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(buf)))
+}
@@ -0,0 +1,28 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// BenchmarkPreprocessor measures the cost of one 20ms frame with every
+// stage enabled; compare ns/op with the 20ms real-time budget.
+func BenchmarkPreprocessor(b *testing.B) {
+	for _, rate := range []int{8000, 16000} {
+		b.Run(fmt.Sprintf("%dHz", rate), func(b *testing.B) {
+			p := NewPreprocessor(rate, DefaultPreprocessConfig())
+			frame := make([]int16, rate/50)
+			src := make([]int16, len(frame))
+			for i := range src {
+				src[i] = int16(3000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+			}
+			b.ReportAllocs()
+			b.SetBytes(int64(len(frame) * BytesPerSample))
+			for b.Loop() {
+				copy(frame, src)
+				p.Process(frame)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// MaxResampleRatio is the largest ratio between sample rates a Resampler
+// converts.
+const MaxResampleRatio = 8
+
+// resampleTaps is the filter half-width in samples of the lower rate.
+const resampleTaps = 8
+
+// maxResamplePhases bounds the polyphase filter bank. Rates whose reduced
+// ratio needs more phases, such as coprime rates, compute each filter as
+// it is used instead.
+const maxResamplePhases = 1024
+
+// Resampler converts a stream of mono 16-bit PCM between sample rates
+// using windowed-sinc interpolation, which band-limits the signal when
+// downsampling so it does not alias. Any ratio within MaxResampleRatio
+// is supported, including non-integer ones such as 44100 to 16000 Hz.
+// The filters for each output phase are computed once, so a 20ms frame
+// converts in a few tens of microseconds. Output lags input by a few
+// samples of filter look-ahead; Flush returns them at the end of the
+// stream. It is not safe for concurrent use.
+type Resampler struct {
+	from, to int
+
+	// up and down are to and from divided by their greatest common
+	// divisor: each output sample advances down/up input samples.
+	up, down int
+
+	// cutoff is the filter cutoff relative to the input Nyquist rate, and
+	// half is the filter half-width in input samples.
+	cutoff float64
+	half   int
+
+	// phases holds the 2*half filter taps for each position of an output
+	// sample between input samples, if there are no more than
+	// maxResamplePhases of them. It is shared by resamplers of the same
+	// rates and not modified.
+	phases [][]float64
+
+	hist []float64
+
+	// pos is the position of the next output sample, in input samples
+	// from the start of hist, scaled by up.
+	pos int
+}
+
+// NewResampler creates a resampler from one sample rate to another. Rates
+// must be positive and within MaxResampleRatio of each other.
+func NewResampler(from, to int) (*Resampler, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("%w: %d Hz to %d Hz", ErrUnsupportedRate, from, to)
+	}
+	if from > to*MaxResampleRatio || to > from*MaxResampleRatio {
+		return nil, fmt.Errorf("%w: %d Hz to %d Hz exceeds ratio %d", ErrUnsupportedRate, from, to, MaxResampleRatio)
+	}
+	g := gcd(from, to)
+	r := &Resampler{from: from, to: to, up: to / g, down: from / g, cutoff: math.Min(1, float64(to)/float64(from))}
+	r.half = int(math.Ceil(resampleTaps / r.cutoff))
+	if from != to && r.up <= maxResamplePhases {
+		r.phases = r.filterBank()
+	}
+	r.Reset()
+	return r, nil
+}
+
+// filterBanks caches the phases of each pair of rates, since sessions
+// create a resampler per utterance.
+var filterBanks sync.Map // [2]int -> [][]float64
+
+func (r *Resampler) filterBank() [][]float64 {
+	key := [2]int{r.from, r.to}
+	if bank, ok := filterBanks.Load(key); ok {
+		return bank.([][]float64)
+	}
+	bank := make([][]float64, r.up)
+	for p := range bank {
+		taps := make([]float64, 2*r.half)
+		frac := float64(p) / float64(r.up)
+		for i := range taps {
+			taps[i] = r.tap(float64(i-r.half+1) - frac)
+		}
+		bank[p] = taps
+	}
+	actual, _ := filterBanks.LoadOrStore(key, bank)
+	return actual.([][]float64)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// From returns the input sample rate.
+func (r *Resampler) From() int { return r.from }
+
+// To returns the output sample rate.
+func (r *Resampler) To() int { return r.to }
+
+// Process converts samples, returning the output now available.
+func (r *Resampler) Process(samples []int16) []int16 {
+	if r.from == r.to {
+		return samples
+	}
+	for _, s := range samples {
+		r.hist = append(r.hist, float64(s)/math.MaxInt16)
+	}
+	return r.drain(len(r.hist) - r.half)
+}
+
+// ProcessBytes converts 16-bit PCM bytes. A trailing odd byte is ignored.
+func (r *Resampler) ProcessBytes(b []byte) []byte {
+	if r.from == r.to {
+		return b
+	}
+	return Int16ToBytes(r.Process(BytesToInt16(b)))
+}
+
+// AppendBytes converts 16-bit PCM bytes, appending the output now
+// available to dst, for callers reusing an output buffer. Unlike
+// ProcessBytes it copies the input when the rates are equal. A trailing
+// odd byte is ignored.
+func (r *Resampler) AppendBytes(dst, b []byte) []byte {
+	b = b[:len(b)-len(b)%BytesPerSample]
+	if r.from == r.to {
+		return append(dst, b...)
+	}
+	for i := 0; i < len(b); i += BytesPerSample {
+		r.hist = append(r.hist, float64(int16(binary.LittleEndian.Uint16(b[i:])))/math.MaxInt16) // #nosec G115 -- reinterpreting PCM bits
+	}
+	r.drainFunc(len(r.hist)-r.half, func(s int16) {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(s)) // #nosec G115 -- reinterpreting PCM bits
+	})
+	return dst
+}
+
+// Flush returns the output held back for filter look-ahead and resets the
+// resampler for a new stream.
+func (r *Resampler) Flush() []int16 {
+	if r.from == r.to {
+		return nil
+	}
+	end := len(r.hist)
+	r.hist = append(r.hist, make([]float64, r.half)...)
+	out := r.drain(end)
+	r.Reset()
+	return out
+}
+
+// FlushBytes is Flush for 16-bit PCM bytes.
+func (r *Resampler) FlushBytes() []byte {
+	return Int16ToBytes(r.Flush())
+}
+
+// Reset discards buffered input.
+func (r *Resampler) Reset() {
+	// Leading zeros stand in for the history before the first sample.
+	r.hist = make([]float64, r.half, 4*r.half)
+	r.pos = r.half * r.up
+}
+
+// drain returns the output for positions before limit; see drainFunc.
+func (r *Resampler) drain(limit int) []int16 {
+	if limit <= 0 {
+		return nil
+	}
+	out := make([]int16, 0, (limit*r.up-r.pos)/r.down+1)
+	r.drainFunc(limit, func(s int16) { out = append(out, s) })
+	return out
+}
+
+// drainFunc produces output for positions before limit, in input samples
+// from the start of hist, then discards history no longer needed.
+func (r *Resampler) drainFunc(limit int, emit func(int16)) {
+	for r.pos < limit*r.up {
+		emit(r.sample())
+		r.pos += r.down
+	}
+	if cut := r.pos/r.up - r.half; cut > 0 {
+		r.hist = append(r.hist[:0], r.hist[cut:]...)
+		r.pos -= cut * r.up
+	}
+}
+
+// sample interpolates the output sample at pos. drain keeps pos at least
+// half input samples from either end of hist, so the filter never runs
+// past it.
+func (r *Resampler) sample() int16 {
+	center := r.pos / r.up
+	phase := r.pos % r.up
+	window := r.hist[center-r.half+1 : center+r.half+1]
+	var sum float64
+	if r.phases != nil {
+		for i, c := range r.phases[phase] {
+			sum += window[i] * c
+		}
+	} else {
+		frac := float64(phase) / float64(r.up)
+		for i, v := range window {
+			sum += v * r.tap(float64(i-r.half+1)-frac)
+		}
+	}
+	return int16(math.Round(math.Max(-1, math.Min(1, sum)) * math.MaxInt16))
+}
+
+// tap is the Hann-windowed sinc filter at x input samples from an output
+// sample.
+func (r *Resampler) tap(x float64) float64 {
+	w := 0.5 + 0.5*math.Cos(math.Pi*x/float64(r.half))
+	return r.cutoff * sinc(r.cutoff*x) * w
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Resample converts a complete clip of mono samples between sample rates.
+func Resample(samples []int16, from, to int) ([]int16, error) {
+	r, err := NewResampler(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(r.Process(samples), r.Flush()...), nil
+}
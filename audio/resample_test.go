@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+// sine returns seconds of a tone at freq Hz and amplitude amp (0.0-1.0).
+func sine(rate int, freq, seconds, amp float64) []int16 {
+	samples := make([]int16, int(float64(rate)*seconds))
+	for i := range samples {
+		samples[i] = int16(amp * math.MaxInt16 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return samples
+}
+
+func TestResamplerStreamingMatchesBatch(t *testing.T) {
+	in := sine(44100, 440, 0.5, 0.5)
+	want, err := Resample(in, 44100, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewResampler(44100, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int16
+	for frame := range slices.Chunk(in, 441) {
+		got = append(got, r.Process(frame)...)
+	}
+	got = append(got, r.Flush()...)
+	if !slices.Equal(got, want) {
+		t.Errorf("streamed %d samples differ from the %d converted at once", len(got), len(want))
+	}
+	if n := len(in) * 16000 / 44100; len(got) < n-1 || len(got) > n+1 {
+		t.Errorf("%d samples out, want about %d", len(got), n)
+	}
+}
+
+func TestResamplerRejectsAliases(t *testing.T) {
+	// 10 kHz is above the 8 kHz Nyquist rate of 16 kHz audio, so
+	// decimating would fold it back into the band as a 6 kHz tone.
+	out, err := Resample(sine(44100, 10000, 0.5, 0.5), 44100, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass, err := Resample(sine(44100, 1000, 0.5, 0.5), 44100, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alias, kept := RMS(out), RMS(pass); alias > kept/30 {
+		t.Errorf("out-of-band tone at RMS %.4f, in-band %.4f: want it attenuated by 30 dB", alias, kept)
+	}
+}
+
+// BenchmarkResampler measures converting one 20ms frame between the
+// rates a telephony bridge sees; compare ns/op with the 20ms real-time
+// budget.
+func BenchmarkResampler(b *testing.B) {
+	for _, rates := range [][2]int{{8000, 16000}, {16000, 8000}, {24000, 8000}, {44100, 16000}, {48000, 8000}} {
+		from, to := rates[0], rates[1]
+		frame := sine(from, 440, 0.02, 0.5)
+		pcm := Int16ToBytes(frame)
+		b.Run(fmt.Sprintf("%d-%d/Process", from, to), func(b *testing.B) {
+			r, err := NewResampler(from, to)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.SetBytes(int64(len(pcm)))
+			for b.Loop() {
+				r.Process(frame)
+			}
+		})
+		b.Run(fmt.Sprintf("%d-%d/AppendBytes", from, to), func(b *testing.B) {
+			r, err := NewResampler(from, to)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var dst []byte
+			b.ReportAllocs()
+			b.SetBytes(int64(len(pcm)))
+			for b.Loop() {
+				dst = r.AppendBytes(dst[:0], pcm)
+			}
+		})
+	}
+}
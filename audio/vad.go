@@ -0,0 +1,79 @@
+package audio
+
+import "time"
+
+// VADEvent is a voice activity transition reported by VAD.
+type VADEvent int
+
+const (
+	// VADNone indicates no transition.
+	VADNone VADEvent = iota
+
+	// VADSpeechStart indicates speech began.
+	VADSpeechStart
+
+	// VADSpeechEnd indicates speech ended after the hangover period.
+	VADSpeechEnd
+)
+
+// VAD is a lightweight energy-based voice activity detector.
+// It is not safe for concurrent use.
+type VAD struct {
+	// Threshold is the RMS level (0.0-1.0) above which a frame is speech.
+	Threshold float64
+
+	// Hangover is how long the level must stay below Threshold before
+	// speech is considered ended.
+	Hangover time.Duration
+
+	// SampleRate is the sample rate of the frames passed to Process.
+	SampleRate int
+
+	speaking bool
+	silence  time.Duration
+}
+
+// NewVAD creates a VAD with defaults suited to telephony audio.
+func NewVAD(sampleRate int) *VAD {
+	return &VAD{
+		Threshold:  0.02,
+		Hangover:   500 * time.Millisecond,
+		SampleRate: sampleRate,
+	}
+}
+
+// Speaking reports whether the detector is currently in speech.
+func (v *VAD) Speaking() bool {
+	return v.speaking
+}
+
+// Process analyzes a frame of mono samples and reports any transition.
+func (v *VAD) Process(frame []int16) VADEvent {
+	active := RMS(frame) >= v.Threshold
+	if active {
+		v.silence = 0
+		if !v.speaking {
+			v.speaking = true
+			return VADSpeechStart
+		}
+		return VADNone
+	}
+	if !v.speaking {
+		return VADNone
+	}
+	if v.SampleRate > 0 {
+		v.silence += time.Duration(len(frame)) * time.Second / time.Duration(v.SampleRate)
+	}
+	if v.silence >= v.Hangover {
+		v.speaking = false
+		v.silence = 0
+		return VADSpeechEnd
+	}
+	return VADNone
+}
+
+// Reset clears the detector state.
+func (v *VAD) Reset() {
+	v.speaking = false
+	v.silence = 0
+}
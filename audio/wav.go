@@ -0,0 +1,144 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Format describes a linear PCM stream.
+type Format struct {
+	// SampleRate is the sample rate in Hz.
+	SampleRate int
+
+	// Channels is the number of interleaved channels.
+	Channels int
+
+	// BitsPerSample is the sample width (16 for this package's PCM helpers).
+	BitsPerSample int
+}
+
+const (
+	wavFormatPCM        = 1
+	wavFormatExtensible = 0xFFFE
+	wavHeaderSize       = 44
+)
+
+// WriteWAV writes pcm as a canonical RIFF/WAVE file. pcm must be 16-bit
+// little-endian samples interleaved across channels.
+func WriteWAV(w io.Writer, pcm []byte, sampleRate, channels int) error {
+	if sampleRate <= 0 {
+		return fmt.Errorf("%w: sample rate %d", ErrInvalidWAV, sampleRate)
+	}
+	if channels <= 0 {
+		channels = 1
+	}
+	byteRate := BytesPerSecond(sampleRate, channels)
+	blockAlign := channels * BytesPerSample
+
+	var h [wavHeaderSize]byte
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(36+len(pcm))) // #nosec G115 -- WAV sizes are 32-bit
+	copy(h[8:], "WAVE")
+	copy(h[12:], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], wavFormatPCM)
+	binary.LittleEndian.PutUint16(h[22:], uint16(channels))   // #nosec G115 -- WAV fields are 16-bit
+	binary.LittleEndian.PutUint32(h[24:], uint32(sampleRate)) // #nosec G115 -- WAV fields are 32-bit
+	binary.LittleEndian.PutUint32(h[28:], uint32(byteRate))   // #nosec G115 -- WAV fields are 32-bit
+	binary.LittleEndian.PutUint16(h[32:], uint16(blockAlign)) // #nosec G115 -- WAV fields are 16-bit
+	binary.LittleEndian.PutUint16(h[34:], 8*BytesPerSample)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], uint32(len(pcm))) // #nosec G115 -- WAV sizes are 32-bit
+
+	if _, err := w.Write(h[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(pcm)
+	return err
+}
+
+// ReadWAV reads a RIFF/WAVE stream and returns its raw PCM data and format.
+// Only 16-bit linear PCM is accepted; other encodings (mu-law, A-law,
+// float, ADPCM) return ErrCompressedFormat.
+func ReadWAV(r io.Reader) ([]byte, Format, error) {
+	format, size, err := ReadWAVHeader(r)
+	if err != nil {
+		return nil, format, err
+	}
+	pcm, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, Format{}, err
+	}
+	return pcm, format, nil
+}
+
+// ReadWAVHeader reads a RIFF/WAVE stream up to its PCM data, leaving r
+// positioned at the first sample, and returns the format and the size of
+// the data in bytes, so long recordings can be read incrementally. It
+// accepts the same encodings as ReadWAV.
+func ReadWAVHeader(r io.Reader) (Format, int64, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return Format{}, 0, fmt.Errorf("%w: %v", ErrInvalidWAV, err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return Format{}, 0, fmt.Errorf("%w: missing RIFF/WAVE header", ErrInvalidWAV)
+	}
+
+	var format Format
+	var haveFormat bool
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return Format{}, 0, fmt.Errorf("%w: missing data chunk", ErrInvalidWAV)
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return Format{}, 0, fmt.Errorf("%w: short fmt chunk", ErrInvalidWAV)
+			}
+			// Only the first 40 bytes, those of WAVE_FORMAT_EXTENSIBLE,
+			// are used; the size comes from the file, so the rest is
+			// skipped rather than read into memory.
+			var buf [40]byte
+			body := buf[:min(size, int64(len(buf)))]
+			if _, err := io.ReadFull(r, body); err != nil {
+				return Format{}, 0, fmt.Errorf("%w: %v", ErrInvalidWAV, err)
+			}
+			if rest := size - int64(len(body)) + size%2; rest > 0 {
+				if _, err := io.CopyN(io.Discard, r, rest); err != nil {
+					return Format{}, 0, fmt.Errorf("%w: %v", ErrInvalidWAV, err)
+				}
+			}
+			tag := binary.LittleEndian.Uint16(body[0:])
+			if tag == wavFormatExtensible && len(body) >= 26 {
+				tag = binary.LittleEndian.Uint16(body[24:])
+			}
+			format = Format{
+				Channels:      int(binary.LittleEndian.Uint16(body[2:])),
+				SampleRate:    int(binary.LittleEndian.Uint32(body[4:])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(body[14:])),
+			}
+			if format.Channels == 0 || format.SampleRate == 0 || format.BitsPerSample == 0 {
+				return Format{}, 0, fmt.Errorf("%w: %d channels at %d Hz, %d bits", ErrInvalidWAV, format.Channels, format.SampleRate, format.BitsPerSample)
+			}
+			if tag != wavFormatPCM || format.BitsPerSample != 8*BytesPerSample {
+				return format, 0, fmt.Errorf("%w: format tag %d, %d bits", ErrCompressedFormat, tag, format.BitsPerSample)
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return Format{}, 0, fmt.Errorf("%w: data before fmt chunk", ErrInvalidWAV)
+			}
+			return format, size, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return Format{}, 0, fmt.Errorf("%w: %v", ErrInvalidWAV, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+// fmtChunk returns a "fmt " chunk body of size bytes for tag, with the
+// subformat of WAVE_FORMAT_EXTENSIBLE set to sub if size allows.
+func fmtChunk(size int, tag uint16, sampleRate, channels, bits int, sub uint16) []byte {
+	b := make([]byte, size)
+	binary.LittleEndian.PutUint16(b[0:], tag)
+	binary.LittleEndian.PutUint16(b[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(b[8:], uint32(sampleRate*channels*bits/8))
+	binary.LittleEndian.PutUint16(b[12:], uint16(channels*bits/8))
+	binary.LittleEndian.PutUint16(b[14:], uint16(bits))
+	if size >= 26 {
+		binary.LittleEndian.PutUint16(b[24:], sub)
+	}
+	return b
+}
+
+// chunk is a RIFF chunk; size, if not negative, overrides the size
+// declared for body.
+type chunk struct {
+	id   string
+	body []byte
+	size int64
+}
+
+// riff assembles a RIFF/WAVE file from chunks.
+func riff(chunks ...chunk) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(0))
+	b.WriteString("WAVE")
+	for _, c := range chunks {
+		b.WriteString(c.id)
+		size := uint32(len(c.body))
+		if c.size >= 0 {
+			size = uint32(c.size)
+		}
+		_ = binary.Write(&b, binary.LittleEndian, size)
+		b.Write(c.body)
+		if len(c.body)%2 == 1 {
+			b.WriteByte(0)
+		}
+	}
+	return b.Bytes()
+}
+
+func TestReadWAV(t *testing.T) {
+	pcm := Int16ToBytes([]int16{1, -2, 3, -4})
+	var canonical bytes.Buffer
+	if err := WriteWAV(&canonical, pcm, 16000, 1); err != nil {
+		t.Fatal(err)
+	}
+	data := chunk{"data", pcm, -1}
+	tests := []struct {
+		name string
+		wav  []byte
+		want Format
+		err  error
+	}{
+		{"canonical", canonical.Bytes(), Format{16000, 1, 16}, nil},
+		{"extensible", riff(chunk{"fmt ", fmtChunk(40, wavFormatExtensible, 8000, 2, 16, wavFormatPCM), -1}, data), Format{8000, 2, 16}, nil},
+		{"fmt with extension", riff(chunk{"fmt ", fmtChunk(18, wavFormatPCM, 8000, 1, 16, 0), -1}, data), Format{8000, 1, 16}, nil},
+		{"odd chunk before data", riff(chunk{"fmt ", fmtChunk(16, wavFormatPCM, 8000, 1, 16, 0), -1}, chunk{"LIST", []byte("abc"), -1}, data), Format{8000, 1, 16}, nil},
+		{"extensible float", riff(chunk{"fmt ", fmtChunk(40, wavFormatExtensible, 8000, 1, 16, 3), -1}, data), Format{}, ErrCompressedFormat},
+		{"mu-law", riff(chunk{"fmt ", fmtChunk(16, 7, 8000, 1, 8, 0), -1}, data), Format{}, ErrCompressedFormat},
+		{"truncated header", canonical.Bytes()[:30], Format{}, ErrInvalidWAV},
+		{"no RIFF", []byte("RIFX\x00\x00\x00\x00WAVE"), Format{}, ErrInvalidWAV},
+		{"short fmt", riff(chunk{"fmt ", make([]byte, 8), -1}, data), Format{}, ErrInvalidWAV},
+		{"huge fmt size", riff(chunk{"fmt ", fmtChunk(16, wavFormatPCM, 8000, 1, 16, 0), 0xFFFFFFF0}), Format{}, ErrInvalidWAV},
+		{"missing data", riff(chunk{"fmt ", fmtChunk(16, wavFormatPCM, 8000, 1, 16, 0), -1}), Format{}, ErrInvalidWAV},
+		{"data before fmt", riff(data), Format{}, ErrInvalidWAV},
+		{"zero sample rate", riff(chunk{"fmt ", fmtChunk(16, wavFormatPCM, 0, 1, 16, 0), -1}, data), Format{}, ErrInvalidWAV},
+		{"zero channels", riff(chunk{"fmt ", fmtChunk(16, wavFormatPCM, 8000, 0, 16, 0), -1}, data), Format{}, ErrInvalidWAV},
+		{"zero bits", riff(chunk{"fmt ", fmtChunk(16, wavFormatPCM, 8000, 1, 0, 0), -1}, data), Format{}, ErrInvalidWAV},
+	}
+	for _, tt := range tests {
+		got, format, err := ReadWAV(bytes.NewReader(tt.wav))
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if format != tt.want || !bytes.Equal(got, pcm) {
+			t.Errorf("%s: read %v %v, want %v %v", tt.name, format, got, tt.want, pcm)
+		}
+	}
+}
+
+func TestReadWAVHeaderBoundsFmtChunk(t *testing.T) {
+	wav := riff(chunk{"fmt ", fmtChunk(16, wavFormatPCM, 8000, 1, 16, 0), 0xFFFFFFF0})
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, _, err := ReadWAVHeader(bytes.NewReader(wav)); !errors.Is(err, ErrInvalidWAV) {
+		t.Fatalf("err = %v, want ErrInvalidWAV", err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("reading a header declaring a 4 GiB fmt chunk allocated %d bytes", n)
+	}
+}
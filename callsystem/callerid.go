@@ -0,0 +1,138 @@
+package callsystem
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unicode"
+)
+
+// maxCallerNameLength is the longest caller name (CNAM) carriers display.
+const maxCallerNameLength = 15
+
+var (
+	// ErrInvalidNumber is returned for a phone number not in E.164 format.
+	ErrInvalidNumber = errors.New("callsystem: invalid E.164 number")
+
+	// ErrInvalidCallerName is returned for a caller name carriers cannot
+	// display.
+	ErrInvalidCallerName = errors.New("callsystem: invalid caller name")
+)
+
+// ValidateE164 returns an error wrapping ErrInvalidNumber unless number
+// is in E.164 format: "+" followed by up to 15 digits, the first not 0.
+func ValidateE164(number string) error {
+	if len(number) < 3 || len(number) > 16 || number[0] != '+' || number[1] == '0' {
+		return fmt.Errorf("%w: %q", ErrInvalidNumber, number)
+	}
+	for _, r := range number[1:] {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("%w: %q", ErrInvalidNumber, number)
+		}
+	}
+	return nil
+}
+
+// WithCallerName sets the outbound caller name (CNAM), up to 15
+// printable characters. Carriers and destinations may ignore it.
+func WithCallerName(name string) CallOption {
+	return func(o *CallOptions) {
+		o.CallerName = name
+	}
+}
+
+// WithNumberPool dials from a number in pool chosen for the destination,
+// unless WithFrom sets one. See NumberPool.Select.
+func WithNumberPool(pool *NumberPool) CallOption {
+	return func(o *CallOptions) {
+		o.NumberPool = pool
+	}
+}
+
+// NumberPool is a set of outbound caller IDs for local presence dialing.
+// It is safe for concurrent use.
+type NumberPool struct {
+	numbers []string
+
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// NewNumberPool creates a pool of E.164 numbers.
+func NewNumberPool(numbers ...string) (*NumberPool, error) {
+	if len(numbers) == 0 {
+		return nil, errors.New("callsystem: empty number pool")
+	}
+	for _, n := range numbers {
+		if err := ValidateE164(n); err != nil {
+			return nil, err
+		}
+	}
+	return &NumberPool{numbers: append([]string(nil), numbers...), next: make(map[string]int)}, nil
+}
+
+// Numbers returns the pool's numbers.
+func (p *NumberPool) Numbers() []string {
+	return append([]string(nil), p.numbers...)
+}
+
+// Select returns the number to dial to from: of the numbers sharing the
+// longest leading digits with to (country code, then area code and
+// exchange), the next in rotation.
+func (p *NumberPool) Select(to string) string {
+	best, bestLen := []string(nil), -1
+	for _, n := range p.numbers {
+		l := commonPrefix(n, to)
+		switch {
+		case l > bestLen:
+			best, bestLen = []string{n}, l
+		case l == bestLen:
+			best = append(best, n)
+		}
+	}
+	key := to[:min(bestLen, len(to))]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.next[key]
+	p.next[key] = (i + 1) % len(best)
+	return best[i%len(best)]
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// ResolveCallOptions applies opts for an outbound call to to, choosing
+// From from the number pool if needed, and validates the result: to and
+// From must be E.164 numbers and CallerName displayable. CallSystem
+// implementations call it from MakeCall.
+func ResolveCallOptions(to string, opts ...CallOption) (CallOptions, error) {
+	var o CallOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if ValidateE164(to) != nil {
+		return o, fmt.Errorf("%w: destination %q", ErrInvalidNumber, to)
+	}
+	if o.From == "" && o.NumberPool != nil {
+		o.From = o.NumberPool.Select(to)
+	}
+	if o.From != "" {
+		if ValidateE164(o.From) != nil {
+			return o, fmt.Errorf("%w: caller ID %q", ErrInvalidNumber, o.From)
+		}
+	}
+	if len([]rune(o.CallerName)) > maxCallerNameLength {
+		return o, fmt.Errorf("%w: %q is over %d characters", ErrInvalidCallerName, o.CallerName, maxCallerNameLength)
+	}
+	for _, r := range o.CallerName {
+		if !unicode.IsPrint(r) {
+			return o, fmt.Errorf("%w: %q", ErrInvalidCallerName, o.CallerName)
+		}
+	}
+	return o, nil
+}
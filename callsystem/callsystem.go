@@ -54,7 +54,8 @@ type Call interface {
 	// Status returns the current call status.
 	Status() CallStatus
 
-	// From returns the caller ID.
+	// From returns the caller ID. For outbound calls it is the number
+	// actually dialed from, e.g. the one chosen from a NumberPool.
 	From() string
 
 	// To returns the called number.
@@ -107,6 +108,14 @@ type CallSystemConfig struct {
 
 	// Region is the service region.
 	Region string
+
+	// FailoverRegions are alternate regions for outbound calls when
+	// Region fails, in order of preference. See RegionalCallSystem.
+	FailoverRegions []string
+
+	// RegionCooldown is how long RegionalCallSystem avoids a region after
+	// a failed call. Defaults to 30 seconds.
+	RegionCooldown time.Duration
 }
 
 // CallSystem defines the interface for telephony/meeting integrations.
@@ -137,15 +146,21 @@ type CallSystem interface {
 type CallOption func(*CallOptions)
 
 // CallOptions holds parsed options for MakeCall.
-// Exported so provider implementations can access option values.
+// Exported so provider implementations can access option values; see
+// ResolveCallOptions.
 type CallOptions struct {
-	From           string
-	Timeout        time.Duration
-	MachineDetect  bool
-	Record         bool
-	Whisper        string
-	AgentConfig    *agent.Config
-	StatusCallback string
+	From              string
+	CallerName        string
+	NumberPool        *NumberPool
+	Timeout           time.Duration
+	MachineDetect     bool
+	Record            bool
+	Whisper           string
+	AgentConfig       *agent.Config
+	StatusCallback    string
+	Voicemail         *VoicemailConfig
+	SMSFallback       *SMSFallbackConfig
+	OutcomeClassifier OutcomeClassifier
 }
 
 // WithFrom sets the outbound caller ID.
@@ -169,7 +184,8 @@ func WithMachineDetection() CallOption {
 	}
 }
 
-// WithRecording enables call recording.
+// WithRecording enables call recording. Where callers must be told,
+// set agent.Config.RecordingConsent on the call's agent session.
 func WithRecording() CallOption {
 	return func(o *CallOptions) {
 		o.Record = true
@@ -251,6 +267,22 @@ type Participant struct {
 	IsBot bool
 }
 
+// NameParticipants names each meeting participant as a speaker of the
+// session, if it implements agent.SpeakerTracker, so user turns
+// attributed to a participant ID carry its display name. Call it again
+// as participants join.
+func NameParticipants(session agent.Session, participants []Participant) {
+	tracker, ok := session.(agent.SpeakerTracker)
+	if !ok {
+		return
+	}
+	for _, p := range participants {
+		if !p.IsBot && p.Name != "" {
+			tracker.NameSpeaker(p.ID, p.Name)
+		}
+	}
+}
+
 // MeetingOption configures meeting join behavior.
 type MeetingOption func(*meetingOptions)
 
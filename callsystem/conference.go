@@ -0,0 +1,492 @@
+package callsystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// Conference errors.
+var (
+	// ErrLegExists is returned when adding a leg whose ID is in use.
+	ErrLegExists = errors.New("callsystem: conference leg already exists")
+
+	// ErrLegNotFound is returned for an unknown conference leg.
+	ErrLegNotFound = errors.New("callsystem: conference leg not found")
+
+	// ErrConferenceClosed is returned when using a closed conference.
+	ErrConferenceClosed = errors.New("callsystem: conference closed")
+)
+
+const (
+	// EventLegJoined indicates a leg joined a conference. Data is a
+	// LegEvent.
+	EventLegJoined EventType = "leg_joined"
+
+	// EventLegLeft indicates a leg left a conference, removed or because
+	// its audio ended. Data is a LegEvent.
+	EventLegLeft EventType = "leg_left"
+)
+
+// LegRole is the part a leg plays in a conference.
+type LegRole string
+
+const (
+	// RoleParticipant is a call leg that hears and is heard by everyone
+	// except coaches.
+	RoleParticipant LegRole = "participant"
+
+	// RoleAgent is the voice agent. It hears everyone, coaches included.
+	RoleAgent LegRole = "agent"
+
+	// RoleCoach is a call leg heard only by the agent, e.g. a supervisor
+	// coaching during an escalation. It hears everyone.
+	RoleCoach LegRole = "coach"
+)
+
+// LegEvent is the Data of EventLegJoined and EventLegLeft.
+type LegEvent struct {
+	// Conference is the conference ID.
+	Conference string
+
+	// Leg is the leg ID: the call ID, or the session ID for the agent.
+	Leg string
+
+	// Role is the leg's role.
+	Role LegRole
+
+	// Err is why the leg's audio ended, if it left on its own.
+	Err error
+}
+
+// LegInfo describes a conference leg.
+type LegInfo struct {
+	ID    string
+	Role  LegRole
+	Muted bool
+}
+
+// ConferenceConfig configures a Conference. Zero fields use defaults.
+type ConferenceConfig struct {
+	// SampleRate is the rate of all leg audio, in Hz. Defaults to 8000.
+	SampleRate int
+
+	// FrameDuration is the mixing interval. Defaults to 20ms.
+	FrameDuration time.Duration
+
+	// MaxJitter bounds how much audio a call leg may queue ahead of the
+	// mix; older audio is dropped to keep latency low. Agent audio, which
+	// arrives faster than real time, is never dropped. Defaults to 200ms.
+	MaxJitter time.Duration
+}
+
+// LegOption configures a conference leg.
+type LegOption func(*legOptions)
+
+type legOptions struct {
+	encoding string
+	muted    bool
+	coach    bool
+}
+
+// WithLegEncoding sets the leg's audio encoding: "pcm" (the default),
+// "mulaw", or "alaw". For the agent it must match Config.AudioEncoding.
+func WithLegEncoding(encoding string) LegOption {
+	return func(o *legOptions) {
+		o.encoding = encoding
+	}
+}
+
+// WithLegMuted adds the leg muted.
+func WithLegMuted() LegOption {
+	return func(o *legOptions) {
+		o.muted = true
+	}
+}
+
+// AsCoach adds a call leg as a coach, heard only by the agent.
+func AsCoach() LegOption {
+	return func(o *legOptions) {
+		o.coach = true
+	}
+}
+
+// Conference bridges call legs and a voice agent: every frame, each leg's
+// audio is mixed into what the others hear. Muted legs are heard by
+// nobody, and coach legs only by the agent. Legs may be added and removed
+// while the conference runs. All legs carry mono audio at the conference
+// sample rate. It is safe for concurrent use.
+type Conference struct {
+	id        string
+	frame     time.Duration
+	rate      int
+	maxJitter int
+
+	mu      sync.Mutex
+	legs    map[string]*leg
+	order   []string
+	handler EventHandler
+	closed  bool
+}
+
+type leg struct {
+	id       string
+	role     LegRole
+	muted    bool
+	encoding string
+	write    func([]byte) error
+	cancel   context.CancelFunc
+
+	// queue holds decoded PCM samples awaiting the mix; limit caps it,
+	// or is 0 for no cap. carry is a trailing odd PCM byte.
+	queue []int16
+	limit int
+	carry []byte
+}
+
+// NewConference creates a conference. Call Run to start mixing.
+func NewConference(id string, config ConferenceConfig) *Conference {
+	if config.SampleRate <= 0 {
+		config.SampleRate = 8000
+	}
+	if config.FrameDuration <= 0 {
+		config.FrameDuration = audio.DefaultFrameDuration
+	}
+	if config.MaxJitter <= 0 {
+		config.MaxJitter = 200 * time.Millisecond
+	}
+	return &Conference{
+		id:        id,
+		frame:     config.FrameDuration,
+		rate:      config.SampleRate,
+		maxJitter: int(int64(config.SampleRate) * int64(config.MaxJitter) / int64(time.Second)),
+		legs:      make(map[string]*leg),
+	}
+}
+
+// ID returns the conference identifier.
+func (c *Conference) ID() string {
+	return c.id
+}
+
+// OnEvent sets the handler for leg events. Handlers must not block.
+func (c *Conference) OnEvent(handler EventHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handler = handler
+}
+
+// AddCall adds a call leg, identified by the call ID, reading and writing
+// audio through its Transport. The leg leaves when its audio ends, ctx
+// ends, or it is removed; leaving does not hang up the call.
+func (c *Conference) AddCall(ctx context.Context, call Call, opts ...LegOption) error {
+	conn := call.Transport()
+	if conn == nil {
+		return fmt.Errorf("callsystem: call %s has no transport", call.ID())
+	}
+	o := legOpts(opts)
+	role := RoleParticipant
+	if o.coach {
+		role = RoleCoach
+	}
+	in := conn.AudioIn()
+	l := &leg{id: call.ID(), role: role, muted: o.muted, encoding: o.encoding, limit: c.maxJitter}
+	l.write = func(b []byte) error {
+		_, err := in.Write(b)
+		return err
+	}
+	return c.add(ctx, l, func(ctx context.Context) error {
+		out := conn.AudioOut()
+		buf := make([]byte, max(audio.SampleSize(l.encoding)*c.rate*int(c.frame/time.Millisecond)/1000, 2))
+		for ctx.Err() == nil {
+			n, err := out.Read(buf)
+			if n > 0 {
+				c.queue(l, buf[:n])
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddAgent adds the voice agent, identified by its session ID. The agent
+// hears the mix as caller audio and its speech is heard by the other
+// legs. The leg leaves when the session's audio closes, ctx ends, or it
+// is removed; leaving does not stop the session.
+func (c *Conference) AddAgent(ctx context.Context, session agent.Session, opts ...LegOption) error {
+	o := legOpts(opts)
+	l := &leg{id: session.ID(), role: RoleAgent, muted: o.muted, encoding: o.encoding}
+	l.write = session.SendAudio
+	release, _ := session.(agent.AudioReleaser)
+	return c.add(ctx, l, func(ctx context.Context) error {
+		speech := session.ReceiveAudio()
+		for {
+			select {
+			case b, ok := <-speech:
+				if !ok {
+					return io.EOF
+				}
+				// queue copies the samples, so the chunk can be reused.
+				c.queue(l, b)
+				if release != nil {
+					release.ReleaseAudio(b)
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+}
+
+func legOpts(opts []LegOption) legOptions {
+	o := legOptions{encoding: audio.EncodingPCM}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// add registers a leg and starts pump, which feeds its audio until it
+// fails or the leg is removed.
+func (c *Conference) add(ctx context.Context, l *leg, pump func(context.Context) error) error {
+	if audio.SampleSize(l.encoding) == 0 {
+		return fmt.Errorf("%w: conference leg encoding %q", audio.ErrFormatMismatch, l.encoding)
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrConferenceClosed
+	}
+	if _, ok := c.legs[l.id]; ok {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrLegExists, l.id)
+	}
+	ctx, l.cancel = context.WithCancel(ctx)
+	c.legs[l.id] = l
+	c.order = append(c.order, l.id)
+	c.mu.Unlock()
+	c.emit(EventLegJoined, l, nil)
+
+	go func() {
+		err := pump(ctx)
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			err = nil
+		}
+		c.remove(l, err)
+	}()
+	return nil
+}
+
+// Remove removes a leg.
+func (c *Conference) Remove(legID string) error {
+	c.mu.Lock()
+	l, ok := c.legs[legID]
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrLegNotFound, legID)
+	}
+	c.remove(l, nil)
+	return nil
+}
+
+// remove drops l if it is still present, emitting EventLegLeft.
+func (c *Conference) remove(l *leg, err error) {
+	c.mu.Lock()
+	if c.legs[l.id] != l {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.legs, l.id)
+	for i, id := range c.order {
+		if id == l.id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.mu.Unlock()
+	l.cancel()
+	c.emit(EventLegLeft, l, err)
+}
+
+// SetMuted mutes or unmutes a leg. A muted leg still hears the others.
+func (c *Conference) SetMuted(legID string, muted bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.legs[legID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrLegNotFound, legID)
+	}
+	l.muted = muted
+	return nil
+}
+
+// SetCoach makes a call leg a coach, heard only by the agent, or returns
+// it to a regular participant.
+func (c *Conference) SetCoach(legID string, coach bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.legs[legID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrLegNotFound, legID)
+	}
+	if l.role == RoleAgent {
+		return fmt.Errorf("callsystem: the agent leg cannot be a coach")
+	}
+	l.role = RoleParticipant
+	if coach {
+		l.role = RoleCoach
+	}
+	return nil
+}
+
+// Legs returns the current legs in join order.
+func (c *Conference) Legs() []LegInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	legs := make([]LegInfo, 0, len(c.order))
+	for _, id := range c.order {
+		l := c.legs[id]
+		legs = append(legs, LegInfo{ID: l.id, Role: l.role, Muted: l.muted})
+	}
+	return legs
+}
+
+// Run mixes audio every frame until ctx is done or Close is called. A leg
+// whose write fails is removed.
+func (c *Conference) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.frame)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if !c.mix() {
+				return ErrConferenceClosed
+			}
+		}
+	}
+}
+
+// Close removes all legs and stops Run.
+func (c *Conference) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	legs := make([]*leg, 0, len(c.order))
+	for _, id := range c.order {
+		legs = append(legs, c.legs[id])
+	}
+	c.mu.Unlock()
+	for _, l := range legs {
+		c.remove(l, nil)
+	}
+	return nil
+}
+
+// queue appends a leg's audio to its queue, dropping the oldest beyond
+// its limit.
+func (c *Conference) queue(l *leg, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch l.encoding {
+	case audio.EncodingMuLaw:
+		b = audio.DecodeMuLaw(b)
+	case audio.EncodingALaw:
+		b = audio.DecodeALaw(b)
+	default:
+		b = slices.Concat(l.carry, b)
+		odd := len(b) % audio.BytesPerSample
+		l.carry = slices.Clone(b[len(b)-odd:])
+		b = b[:len(b)-odd]
+	}
+	l.queue = append(l.queue, audio.BytesToInt16(b)...)
+	if over := len(l.queue) - l.limit; l.limit > 0 && over > 0 {
+		l.queue = append(l.queue[:0], l.queue[over:]...)
+	}
+}
+
+// mix takes one frame from every leg and writes each leg the sum of the
+// legs it hears. It reports false once the conference is closed.
+func (c *Conference) mix() bool {
+	n := int(int64(c.rate) * int64(c.frame) / int64(time.Second))
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	legs := make([]*leg, 0, len(c.order))
+	frames := make([][]int16, 0, len(c.order))
+	for _, id := range c.order {
+		l := c.legs[id]
+		f := make([]int16, n)
+		copy(f, l.queue)
+		l.queue = append(l.queue[:0], l.queue[min(n, len(l.queue)):]...)
+		if l.muted {
+			clear(f)
+		}
+		legs = append(legs, l)
+		frames = append(frames, f)
+	}
+	roles := make([]LegRole, len(legs))
+	for i, l := range legs {
+		roles[i] = l.role
+	}
+	c.mu.Unlock()
+
+	acc := make([]float64, n)
+	out := make([]int16, n)
+	for i, l := range legs {
+		clear(acc)
+		for j, f := range frames {
+			if j == i || (roles[j] == RoleCoach && roles[i] != RoleAgent) {
+				continue
+			}
+			for k, s := range f {
+				acc[k] += float64(s) / math.MaxInt16
+			}
+		}
+		for k, v := range acc {
+			out[k] = int16(math.Round(audio.SoftClip(v) * math.MaxInt16))
+		}
+		if err := l.write(encodeLeg(l.encoding, audio.Int16ToBytes(out))); err != nil {
+			c.remove(l, err)
+		}
+	}
+	return true
+}
+
+func (c *Conference) emit(t EventType, l *leg, err error) {
+	c.mu.Lock()
+	h := c.handler
+	role := l.role
+	c.mu.Unlock()
+	if h != nil {
+		h(Event{
+			Type:      t,
+			CallID:    l.id,
+			Timestamp: time.Now(),
+			Data:      LegEvent{Conference: c.id, Leg: l.id, Role: role, Err: err},
+			Error:     err,
+		})
+	}
+}
+
+func encodeLeg(encoding string, pcm []byte) []byte {
+	switch encoding {
+	case audio.EncodingMuLaw:
+		return audio.EncodeMuLaw(pcm)
+	case audio.EncodingALaw:
+		return audio.EncodeALaw(pcm)
+	}
+	return pcm
+}
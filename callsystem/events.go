@@ -0,0 +1,45 @@
+package callsystem
+
+import "time"
+
+// EventSource is implemented by call systems that report events such as
+// EventWhisper.
+type EventSource interface {
+	// OnEvent sets the handler for call system events.
+	OnEvent(handler EventHandler)
+}
+
+// Event represents a call system event, delivered to the handler set with
+// EventSource.OnEvent.
+type Event struct {
+	// Type is the event type.
+	Type EventType
+
+	// CallID is the call the event relates to.
+	CallID string
+
+	// Timestamp is when the event occurred.
+	Timestamp time.Time
+
+	// Data contains event-specific data.
+	Data any
+
+	// Error contains any error details.
+	Error error
+}
+
+// EventType identifies the type of call system event.
+type EventType string
+
+const (
+	// EventWhisper records that whisper audio was injected to the agent.
+	// Data is a WhisperRecord.
+	EventWhisper EventType = "whisper"
+
+	// EventRegionFailover records that an outbound call failed in one
+	// region and is being retried in another. Data is a RegionFailover.
+	EventRegionFailover EventType = "region_failover"
+)
+
+// EventHandler is called for call system events. Handlers must not block.
+type EventHandler func(event Event)
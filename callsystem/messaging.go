@@ -0,0 +1,201 @@
+package callsystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrMessagingUnsupported is returned when a call system cannot send
+// text messages.
+var ErrMessagingUnsupported = errors.New("callsystem: messaging not supported")
+
+// EventSMSFallback indicates that an outbound call was not answered and a
+// fallback message was sent, or failed to send. Data is an SMSFallbackResult.
+const EventSMSFallback EventType = "sms_fallback"
+
+// Messenger is implemented by call systems that also send text messages,
+// such as Twilio, using the account and PhoneNumber of their
+// CallSystemConfig. It is kept apart from Call: a message is not part of a
+// call and needs no connection.
+type Messenger interface {
+	// SendSMS sends a text message to an E.164 number.
+	SendSMS(ctx context.Context, to, body string, opts ...MessageOption) (*Message, error)
+
+	// SendMMS sends a multimedia message: body and the media at
+	// mediaURLs, which the provider fetches.
+	SendMMS(ctx context.Context, to, body string, mediaURLs []string, opts ...MessageOption) (*Message, error)
+}
+
+// MessageStatus is the delivery state of a message.
+type MessageStatus string
+
+const (
+	// MessageQueued indicates the provider accepted the message.
+	MessageQueued MessageStatus = "queued"
+
+	// MessageSent indicates the message was handed to the carrier.
+	MessageSent MessageStatus = "sent"
+
+	// MessageDelivered indicates the carrier confirmed delivery.
+	MessageDelivered MessageStatus = "delivered"
+
+	// MessageFailed indicates the message could not be sent.
+	MessageFailed MessageStatus = "failed"
+)
+
+// Message is a sent text message.
+type Message struct {
+	// ID is the provider's message identifier.
+	ID string
+
+	// From is the number the message was sent from.
+	From string
+
+	// To is the recipient.
+	To string
+
+	// Body is the message text.
+	Body string
+
+	// MediaURLs are the media of an MMS.
+	MediaURLs []string
+
+	// Status is the delivery state when sending returned.
+	Status MessageStatus
+
+	// SentAt is when the message was accepted.
+	SentAt time.Time
+}
+
+// MessageOption configures a message.
+type MessageOption func(*MessageOptions)
+
+// MessageOptions holds parsed options for SendSMS and SendMMS.
+// Exported so provider implementations can access option values; see
+// ResolveMessageOptions.
+type MessageOptions struct {
+	From           string
+	NumberPool     *NumberPool
+	StatusCallback string
+}
+
+// WithMessageFrom sets the sending number. Defaults to the call system's
+// PhoneNumber.
+func WithMessageFrom(from string) MessageOption {
+	return func(o *MessageOptions) {
+		o.From = from
+	}
+}
+
+// WithMessageNumberPool sends from a number in pool chosen for the
+// recipient, unless WithMessageFrom sets one.
+func WithMessageNumberPool(pool *NumberPool) MessageOption {
+	return func(o *MessageOptions) {
+		o.NumberPool = pool
+	}
+}
+
+// WithMessageStatusCallback sets a webhook URL for delivery updates.
+func WithMessageStatusCallback(url string) MessageOption {
+	return func(o *MessageOptions) {
+		o.StatusCallback = url
+	}
+}
+
+// ResolveMessageOptions applies opts for a message to to, choosing From
+// from the number pool if needed, and validates the numbers as
+// ResolveCallOptions does. Messenger implementations call it from SendSMS
+// and SendMMS.
+func ResolveMessageOptions(to string, opts ...MessageOption) (MessageOptions, error) {
+	var o MessageOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if ValidateE164(to) != nil {
+		return o, fmt.Errorf("%w: recipient %q", ErrInvalidNumber, to)
+	}
+	if o.From == "" && o.NumberPool != nil {
+		o.From = o.NumberPool.Select(to)
+	}
+	if o.From != "" && ValidateE164(o.From) != nil {
+		return o, fmt.Errorf("%w: sender %q", ErrInvalidNumber, o.From)
+	}
+	return o, nil
+}
+
+// SMSFallbackConfig configures the message sent when an outbound call is
+// not answered. See WithSMSFallback.
+type SMSFallbackConfig struct {
+	// Text is the message body.
+	Text string
+
+	// MediaURLs, if set, send the message as an MMS.
+	MediaURLs []string
+
+	// Statuses are the call outcomes that send the message. Defaults to
+	// StatusNoAnswer and StatusBusy.
+	Statuses []CallStatus
+
+	// Options configure the message. It is sent from the number the call
+	// was placed from unless they set another.
+	Options []MessageOption
+}
+
+// FallsBack reports whether a call that ended with status sends the
+// fallback message.
+func (c SMSFallbackConfig) FallsBack(status CallStatus) bool {
+	if len(c.Statuses) == 0 {
+		return status == StatusNoAnswer || status == StatusBusy
+	}
+	return slices.Contains(c.Statuses, status)
+}
+
+// SMSFallbackResult is the Data of EventSMSFallback.
+type SMSFallbackResult struct {
+	// CallID is the call that was not answered.
+	CallID string
+
+	// Status is how the call ended.
+	Status CallStatus
+
+	// Message is the message sent, or nil if sending failed.
+	Message *Message
+}
+
+// WithSMSFallback sends a text message to the callee when the call ends
+// unanswered: with StatusNoAnswer or StatusBusy, or config.Statuses.
+// Implementations that are also Messengers run SendSMSFallback once the
+// call's final status is known and emit EventSMSFallback.
+func WithSMSFallback(config SMSFallbackConfig) CallOption {
+	return func(o *CallOptions) {
+		o.SMSFallback = &config
+	}
+}
+
+// SendSMSFallback sends the fallback message for a call that ended with
+// one of config's statuses, from the number the call was placed from. It
+// returns nil and no error if the call's status does not fall back, and
+// ErrMessagingUnsupported if m is nil.
+func SendSMSFallback(ctx context.Context, m Messenger, call Call, config SMSFallbackConfig) (*Message, error) {
+	if !config.FallsBack(call.Status()) {
+		return nil, nil
+	}
+	if m == nil {
+		return nil, ErrMessagingUnsupported
+	}
+	if config.Text == "" && len(config.MediaURLs) == 0 {
+		return nil, fmt.Errorf("callsystem: SMS fallback has neither text nor media")
+	}
+	var opts []MessageOption
+	if from := call.From(); from != "" {
+		opts = append(opts, WithMessageFrom(from))
+	}
+	opts = append(opts, config.Options...)
+	if len(config.MediaURLs) > 0 {
+		return m.SendMMS(ctx, call.To(), config.Text, config.MediaURLs, opts...)
+	}
+	return m.SendSMS(ctx, call.To(), config.Text, opts...)
+}
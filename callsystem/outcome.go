@@ -0,0 +1,268 @@
+package callsystem
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+)
+
+// EventCallOutcome indicates a call ended and was classified. Data is a
+// CallResult.
+const EventCallOutcome EventType = "call_outcome"
+
+// CallOutcome is how a call ended, for analytics.
+type CallOutcome string
+
+const (
+	// OutcomeCompleted indicates the call was answered and the
+	// conversation ran its course.
+	OutcomeCompleted CallOutcome = "completed"
+
+	// OutcomeAbandoned indicates the caller hung up before the agent
+	// answered anything they said, or an inbound call was never
+	// answered.
+	OutcomeAbandoned CallOutcome = "abandoned"
+
+	// OutcomeTransferred indicates the call was transferred elsewhere.
+	OutcomeTransferred CallOutcome = "transferred"
+
+	// OutcomeVoicemailLeft indicates a machine answered and a voicemail
+	// message was left.
+	OutcomeVoicemailLeft CallOutcome = "voicemail_left"
+
+	// OutcomeFailed indicates the call could not be placed or connected.
+	OutcomeFailed CallOutcome = "failed"
+
+	// OutcomeNoAnswer indicates an outbound call was not answered or the
+	// line was busy.
+	OutcomeNoAnswer CallOutcome = "no_answer"
+)
+
+// CallRecord is what is known about a call once it has ended, from its
+// status and its agent session's events, for classifying its outcome.
+type CallRecord struct {
+	// CallID is the call.
+	CallID string
+
+	// Direction is inbound or outbound.
+	Direction CallDirection
+
+	// Status is the call's final status.
+	Status CallStatus
+
+	// Duration is the call duration.
+	Duration time.Duration
+
+	// Answered reports whether the call was connected.
+	Answered bool
+
+	// RemoteHangup reports whether the other party ended the call.
+	RemoteHangup bool
+
+	// TransferTarget is where the call was transferred, if it was.
+	TransferTarget string
+
+	// Voicemail is the result of the voicemail workflow, if it ran.
+	Voicemail *VoicemailResult
+
+	// UserTurns and AgentTurns count the session's user transcripts and
+	// agent replies.
+	UserTurns  int
+	AgentTurns int
+
+	// Answers counts the user turns the agent replied to.
+	Answers int
+
+	// Metrics are the final metrics of the agent session, from
+	// EventSessionEnded, or nil if it has none.
+	Metrics *agent.Metrics
+
+	// Err is why the call failed, if it did.
+	Err error
+}
+
+// CallResult is the classified outcome of a call, and the Data of
+// EventCallOutcome.
+type CallResult struct {
+	// CallID is the call.
+	CallID string
+
+	// Outcome is how the call ended.
+	Outcome CallOutcome
+
+	// Reason explains Outcome, e.g. "busy" or "caller hung up before an
+	// answer".
+	Reason string
+
+	// Status is the call's final status.
+	Status CallStatus
+
+	// Duration is the call duration.
+	Duration time.Duration
+
+	// Metrics are the final metrics of the agent session, or nil.
+	Metrics *agent.Metrics
+}
+
+// OutcomeClassifier classifies an ended call, returning its outcome and
+// the reason for it. ClassifyOutcome is the default; see
+// WithOutcomeClassifier.
+type OutcomeClassifier func(CallRecord) (CallOutcome, string)
+
+// ClassifyOutcome is the default OutcomeClassifier. Its rules apply in
+// order:
+//
+//   - A voicemail left is OutcomeVoicemailLeft.
+//   - A transferred call is OutcomeTransferred.
+//   - StatusNoAnswer and StatusBusy are OutcomeNoAnswer.
+//   - StatusFailed is OutcomeFailed.
+//   - An outbound call never answered is OutcomeNoAnswer; an inbound one
+//     is OutcomeAbandoned.
+//   - A call the other party hung up before the agent replied to
+//     anything they said is OutcomeAbandoned.
+//   - Anything else is OutcomeCompleted.
+func ClassifyOutcome(r CallRecord) (CallOutcome, string) {
+	switch {
+	case r.Voicemail != nil && r.Voicemail.Outcome == OutcomeVoicemailLeft:
+		return OutcomeVoicemailLeft, "voicemail left"
+	case r.TransferTarget != "":
+		return OutcomeTransferred, "transferred to " + r.TransferTarget
+	case r.Status == StatusNoAnswer:
+		return OutcomeNoAnswer, "no answer"
+	case r.Status == StatusBusy:
+		return OutcomeNoAnswer, "busy"
+	case r.Status == StatusFailed:
+		if r.Err != nil {
+			return OutcomeFailed, fmt.Sprintf("call failed: %v", r.Err)
+		}
+		return OutcomeFailed, "call failed"
+	case !r.Answered && r.Direction == Outbound:
+		return OutcomeNoAnswer, "not answered"
+	case !r.Answered:
+		return OutcomeAbandoned, "caller hung up before answer"
+	case r.RemoteHangup && r.Answers == 0:
+		if r.UserTurns == 0 {
+			return OutcomeAbandoned, "caller hung up without speaking"
+		}
+		return OutcomeAbandoned, "caller hung up before an answer"
+	}
+	return OutcomeCompleted, "conversation completed"
+}
+
+// WithOutcomeClassifier classifies the call's outcome with classify
+// instead of ClassifyOutcome.
+func WithOutcomeClassifier(classify OutcomeClassifier) CallOption {
+	return func(o *CallOptions) {
+		o.OutcomeClassifier = classify
+	}
+}
+
+// OutcomeReporter is implemented by calls that classify how they ended.
+type OutcomeReporter interface {
+	// Outcome returns the call's classified outcome, and false until the
+	// call has ended.
+	Outcome() (CallResult, bool)
+}
+
+// OutcomeTracker collects what a Call implementation learns about a
+// call, from its agent session's events and its status, and classifies
+// the outcome once it ends. Implementations feed it, end it on hangup,
+// emit EventCallOutcome with the result, and implement OutcomeReporter
+// with Result. It is safe for concurrent use.
+type OutcomeTracker struct {
+	classify OutcomeClassifier
+
+	mu      sync.Mutex
+	record  CallRecord
+	pending bool
+	result  *CallResult
+}
+
+// NewOutcomeTracker creates a tracker for a call. A nil classify uses
+// ClassifyOutcome.
+func NewOutcomeTracker(callID string, direction CallDirection, classify OutcomeClassifier) *OutcomeTracker {
+	if classify == nil {
+		classify = ClassifyOutcome
+	}
+	return &OutcomeTracker{
+		classify: classify,
+		record:   CallRecord{CallID: callID, Direction: direction},
+	}
+}
+
+// Event records an event of the call's agent session.
+func (t *OutcomeTracker) Event(ev agent.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch ev.Type {
+	case agent.EventUserTranscript:
+		t.record.UserTurns++
+		t.pending = true
+	case agent.EventAgentTranscript:
+		t.record.AgentTurns++
+		if t.pending {
+			t.record.Answers++
+			t.pending = false
+		}
+	case agent.EventSessionEnded:
+		if m, ok := ev.Data.(agent.Metrics); ok {
+			t.record.Metrics = &m
+		}
+	}
+}
+
+// Answered records that the call connected.
+func (t *OutcomeTracker) Answered() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.record.Answered = true
+}
+
+// Transferred records that the call was transferred to target.
+func (t *OutcomeTracker) Transferred(target string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.record.TransferTarget = target
+}
+
+// Voicemail records the result of the voicemail workflow.
+func (t *OutcomeTracker) Voicemail(result VoicemailResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.record.Voicemail = &result
+}
+
+// End classifies the call, which ended with status after duration, and
+// returns the result. remote reports that the other party hung up; err is
+// why the call failed, if it did. Only the first End counts.
+func (t *OutcomeTracker) End(status CallStatus, duration time.Duration, remote bool, err error) CallResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.result != nil {
+		return *t.result
+	}
+	r := t.record
+	r.Status, r.Duration, r.RemoteHangup, r.Err = status, duration, remote, err
+	outcome, reason := t.classify(r)
+	t.result = &CallResult{
+		CallID:   r.CallID,
+		Outcome:  outcome,
+		Reason:   reason,
+		Status:   status,
+		Duration: duration,
+		Metrics:  r.Metrics,
+	}
+	return *t.result
+}
+
+// Result returns the classified outcome, and false until End.
+func (t *OutcomeTracker) Result() (CallResult, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.result == nil {
+		return CallResult{}, false
+	}
+	return *t.result, true
+}
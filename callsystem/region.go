@@ -0,0 +1,324 @@
+package callsystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultRegionCooldown is how long a failed region is avoided when
+// CallSystemConfig.RegionCooldown is zero.
+const defaultRegionCooldown = 30 * time.Second
+
+// ErrRegionsUnavailable is returned when an outbound call or message
+// failed in every region. It wraps each region's error.
+var ErrRegionsUnavailable = errors.New("callsystem: call failed in every region")
+
+// RegionFailover is the Data of an EventRegionFailover event.
+type RegionFailover struct {
+	// From is the region that failed.
+	From string
+
+	// To is the region tried next.
+	To string
+
+	// Err is the failure in From.
+	Err error
+}
+
+// RegionStatus is the health of one region of a RegionalCallSystem.
+type RegionStatus struct {
+	// Region is the region name.
+	Region string
+
+	// Healthy reports that the region has not failed within the cooldown.
+	Healthy bool
+
+	// Failures counts consecutive failed calls, reset by a success.
+	Failures int
+
+	// LastError is the region's most recent failure, if any.
+	LastError error
+}
+
+// RegionalCallSystem runs one instance of a call system per region and
+// places outbound calls in a healthy one, failing over as the STT and
+// TTS clients do between providers. Configure creates an instance for
+// CallSystemConfig.Region and each of FailoverRegions. A region whose
+// call fails is tried last until RegionCooldown passes, and
+// EventRegionFailover is emitted each time MakeCall moves on to another
+// region. Incoming calls and events of every region are delivered to the
+// handlers. It is a Messenger if the underlying call system is. It is
+// safe for concurrent use.
+type RegionalCallSystem struct {
+	newSystem func() CallSystem
+
+	mu       sync.Mutex
+	regions  []*region
+	cooldown time.Duration
+	incoming CallHandler
+	events   EventHandler
+}
+
+type region struct {
+	name      string
+	system    CallSystem
+	failures  int
+	lastErr   error
+	downUntil time.Time
+}
+
+// NewRegionalCallSystem creates a regional call system. newSystem returns
+// a new, unconfigured instance of the underlying call system.
+func NewRegionalCallSystem(newSystem func() CallSystem) *RegionalCallSystem {
+	return &RegionalCallSystem{newSystem: newSystem}
+}
+
+// Name returns the name of the underlying call system.
+func (r *RegionalCallSystem) Name() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.regions) == 0 {
+		return r.newSystem().Name()
+	}
+	return r.regions[0].system.Name()
+}
+
+// Configure creates and configures an instance per region, each with
+// config.Region set to its region. It replaces, without closing, any
+// instances from an earlier Configure.
+func (r *RegionalCallSystem) Configure(config CallSystemConfig) error {
+	names := []string{config.Region}
+	for _, name := range config.FailoverRegions {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	regions := make([]*region, 0, len(names))
+	for _, name := range names {
+		sys := r.newSystem()
+		c := config
+		c.Region = name
+		if err := sys.Configure(c); err != nil {
+			return fmt.Errorf("callsystem: region %q: %w", name, err)
+		}
+		sys.OnIncomingCall(r.handleCall)
+		if events, ok := sys.(EventSource); ok {
+			events.OnEvent(r.emit)
+		}
+		regions = append(regions, &region{name: name, system: sys})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.regions = regions
+	r.cooldown = config.RegionCooldown
+	if r.cooldown <= 0 {
+		r.cooldown = defaultRegionCooldown
+	}
+	return nil
+}
+
+// OnIncomingCall sets the handler for incoming calls in any region.
+func (r *RegionalCallSystem) OnIncomingCall(handler CallHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.incoming = handler
+}
+
+// OnEvent sets the handler for EventRegionFailover and the events of
+// every region whose call system is an EventSource, implementing
+// EventSource.
+func (r *RegionalCallSystem) OnEvent(handler EventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = handler
+}
+
+func (r *RegionalCallSystem) handleCall(call Call) error {
+	r.mu.Lock()
+	handler := r.incoming
+	r.mu.Unlock()
+	if handler == nil {
+		return nil
+	}
+	return handler(call)
+}
+
+func (r *RegionalCallSystem) emit(ev Event) {
+	r.mu.Lock()
+	handler := r.events
+	r.mu.Unlock()
+	if handler != nil {
+		handler(ev)
+	}
+}
+
+// MakeCall places an outbound call in the first healthy region, in
+// configuration order, trying the others in turn if it fails. Errors
+// from invalid options (ErrInvalidNumber, ErrInvalidCallerName) and from
+// ctx are returned without failing over. If every region fails, the
+// error wraps ErrRegionsUnavailable and each region's error.
+func (r *RegionalCallSystem) MakeCall(ctx context.Context, to string, opts ...CallOption) (Call, error) {
+	order := r.order()
+	if len(order) == 0 {
+		return nil, fmt.Errorf("%w: not configured", ErrRegionsUnavailable)
+	}
+	var errs []error
+	for i, reg := range order {
+		call, err := reg.system.MakeCall(ctx, to, opts...)
+		if err == nil {
+			r.succeeded(reg)
+			return call, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ErrInvalidNumber) || errors.Is(err, ErrInvalidCallerName) {
+			return nil, err
+		}
+		r.failed(reg, err)
+		errs = append(errs, fmt.Errorf("region %q: %w", reg.name, err))
+		if i+1 < len(order) {
+			r.emit(Event{
+				Type:      EventRegionFailover,
+				Timestamp: time.Now(),
+				Data:      RegionFailover{From: reg.name, To: order[i+1].name, Err: err},
+				Error:     err,
+			})
+		}
+	}
+	return nil, fmt.Errorf("%w: %w", ErrRegionsUnavailable, errors.Join(errs...))
+}
+
+// order returns the regions to try: healthy ones in configuration order,
+// then failed ones soonest to recover first.
+func (r *RegionalCallSystem) order() []*region {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var healthy, down []*region
+	for _, reg := range r.regions {
+		if now.Before(reg.downUntil) {
+			down = append(down, reg)
+		} else {
+			healthy = append(healthy, reg)
+		}
+	}
+	slices.SortStableFunc(down, func(a, b *region) int { return a.downUntil.Compare(b.downUntil) })
+	return append(healthy, down...)
+}
+
+func (r *RegionalCallSystem) succeeded(reg *region) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reg.failures, reg.lastErr, reg.downUntil = 0, nil, time.Time{}
+}
+
+func (r *RegionalCallSystem) failed(reg *region, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reg.failures++
+	reg.lastErr = err
+	reg.downUntil = time.Now().Add(r.cooldown)
+}
+
+// Regions returns the health of each region, in configuration order.
+func (r *RegionalCallSystem) Regions() []RegionStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	status := make([]RegionStatus, len(r.regions))
+	for i, reg := range r.regions {
+		status[i] = RegionStatus{
+			Region:    reg.name,
+			Healthy:   !now.Before(reg.downUntil),
+			Failures:  reg.failures,
+			LastError: reg.lastErr,
+		}
+	}
+	return status
+}
+
+// SendSMS sends a text message through the first healthy region whose
+// call system is a Messenger, trying the others in turn if it fails, as
+// MakeCall does. It returns ErrMessagingUnsupported if none is.
+func (r *RegionalCallSystem) SendSMS(ctx context.Context, to, body string, opts ...MessageOption) (*Message, error) {
+	return r.message(ctx, func(m Messenger) (*Message, error) { return m.SendSMS(ctx, to, body, opts...) })
+}
+
+// SendMMS sends a multimedia message as SendSMS does.
+func (r *RegionalCallSystem) SendMMS(ctx context.Context, to, body string, mediaURLs []string, opts ...MessageOption) (*Message, error) {
+	return r.message(ctx, func(m Messenger) (*Message, error) { return m.SendMMS(ctx, to, body, mediaURLs, opts...) })
+}
+
+func (r *RegionalCallSystem) message(ctx context.Context, send func(Messenger) (*Message, error)) (*Message, error) {
+	var errs []error
+	for _, reg := range r.order() {
+		m, ok := reg.system.(Messenger)
+		if !ok {
+			continue
+		}
+		msg, err := send(m)
+		if err == nil {
+			return msg, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ErrInvalidNumber) {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("region %q: %w", reg.name, err))
+	}
+	if len(errs) == 0 {
+		return nil, ErrMessagingUnsupported
+	}
+	return nil, fmt.Errorf("%w: %w", ErrRegionsUnavailable, errors.Join(errs...))
+}
+
+// GetCall retrieves a call by ID from whichever region has it.
+func (r *RegionalCallSystem) GetCall(ctx context.Context, callID string) (Call, error) {
+	var errs []error
+	for _, reg := range r.snapshot() {
+		call, err := reg.system.GetCall(ctx, callID)
+		if err == nil {
+			return call, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("callsystem: call %s not found: %w", callID, errors.Join(errs...))
+}
+
+// ListCalls lists the active calls of every region.
+func (r *RegionalCallSystem) ListCalls(ctx context.Context) ([]Call, error) {
+	var calls []Call
+	for _, reg := range r.snapshot() {
+		c, err := reg.system.ListCalls(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("callsystem: region %q: %w", reg.name, err)
+		}
+		calls = append(calls, c...)
+	}
+	return calls, nil
+}
+
+// Close shuts down every region.
+func (r *RegionalCallSystem) Close() error {
+	var errs []error
+	for _, reg := range r.snapshot() {
+		if err := reg.system.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("region %q: %w", reg.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *RegionalCallSystem) snapshot() []*region {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.regions)
+}
@@ -0,0 +1,333 @@
+package callsystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// EventVoicemail indicates a voicemail workflow finished. Data is a
+// VoicemailResult; Error is set if no message was left.
+const EventVoicemail EventType = "voicemail"
+
+// VoicemailConfig configures the voicemail workflow. See LeaveVoicemail.
+type VoicemailConfig struct {
+	// Text is the message, synthesized with TTS when Audio is empty.
+	Text string
+
+	// Audio is the pre-recorded message as 16-bit PCM at SampleRate.
+	Audio []byte
+
+	// TTS synthesizes Text. Synthesis configures it; OutputFormat and
+	// SampleRate are overridden to match the call.
+	TTS       *tts.Client
+	Synthesis tts.SynthesisConfig
+
+	// SampleRate is the call audio sample rate, in Hz. Defaults to 8000.
+	SampleRate int
+
+	// Encoding is the call audio encoding: "pcm" (the default), "mulaw",
+	// or "alaw".
+	Encoding string
+
+	// BeepTimeout is how long to wait for the beep before leaving the
+	// message anyway. Defaults to 30s.
+	BeepTimeout time.Duration
+
+	// PostBeepDelay is the pause between the end of the beep and the
+	// message. Defaults to 300ms.
+	PostBeepDelay time.Duration
+}
+
+func (c VoicemailConfig) withDefaults() VoicemailConfig {
+	if c.SampleRate <= 0 {
+		c.SampleRate = 8000
+	}
+	if c.Encoding == "" {
+		c.Encoding = audio.EncodingPCM
+	}
+	if c.BeepTimeout <= 0 {
+		c.BeepTimeout = 30 * time.Second
+	}
+	if c.PostBeepDelay <= 0 {
+		c.PostBeepDelay = 300 * time.Millisecond
+	}
+	return c
+}
+
+// VoicemailResult is the Data of EventVoicemail.
+type VoicemailResult struct {
+	// CallID is the call the message was left on.
+	CallID string
+
+	// Outcome is OutcomeVoicemailLeft once the message was played.
+	Outcome CallOutcome
+
+	// BeepDetected reports whether the beep was heard; if not, the
+	// message was left after BeepTimeout.
+	BeepDetected bool
+
+	// Duration is the length of the message played.
+	Duration time.Duration
+}
+
+// WithVoicemail leaves a voicemail when answering machine detection finds
+// a machine, instead of attaching the agent. It implies
+// WithMachineDetection. Implementations run LeaveVoicemail and emit
+// EventVoicemail.
+func WithVoicemail(config VoicemailConfig) CallOption {
+	return func(o *CallOptions) {
+		o.MachineDetect = true
+		o.Voicemail = &config
+	}
+}
+
+// LeaveVoicemail leaves a message on a call answered by a machine: it
+// listens to the greeting for the beep, waits PostBeepDelay, plays the
+// message, and hangs up. If no beep is heard within BeepTimeout the
+// message is left anyway. No agent may be attached to the call, as the
+// workflow reads the call's audio itself.
+func LeaveVoicemail(ctx context.Context, call Call, config VoicemailConfig) (VoicemailResult, error) {
+	config = config.withDefaults()
+	result := VoicemailResult{CallID: call.ID()}
+	size := audio.SampleSize(config.Encoding)
+	if size == 0 {
+		return result, fmt.Errorf("%w: voicemail encoding %q", audio.ErrFormatMismatch, config.Encoding)
+	}
+	pcm, err := voicemailAudio(ctx, config)
+	if err != nil {
+		return result, err
+	}
+
+	conn := call.Transport()
+	beep := make(chan struct{}, 1)
+	readErr := make(chan error, 1)
+	go func() {
+		detector := NewBeepDetector(config.SampleRate)
+		buf := make([]byte, size*config.SampleRate/50)
+		var tail []byte
+		for {
+			n, err := conn.AudioOut().Read(buf)
+			if n > 0 {
+				b := decodeLeg(config.Encoding, buf[:n])
+				b = append(tail, b...)
+				whole := len(b) - len(b)%audio.BytesPerSample
+				if detector.Process(audio.BytesToInt16(b[:whole])) {
+					select {
+					case beep <- struct{}{}:
+					default:
+					}
+				}
+				tail = append(tail[:0:0], b[whole:]...)
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	timeout := time.NewTimer(config.BeepTimeout)
+	defer timeout.Stop()
+	select {
+	case <-beep:
+		result.BeepDetected = true
+	case <-timeout.C:
+	case err := <-readErr:
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("callsystem: call ended before the voicemail was left: %w", err)
+		}
+		return result, err
+	case <-ctx.Done():
+		return result, ctx.Err()
+	}
+	if result.BeepDetected {
+		delay := time.NewTimer(config.PostBeepDelay)
+		select {
+		case <-delay.C:
+		case <-ctx.Done():
+			delay.Stop()
+			return result, ctx.Err()
+		}
+	}
+
+	if err := playPaced(ctx, conn.AudioIn(), pcm, config); err != nil {
+		return result, err
+	}
+	result.Duration = time.Duration(len(pcm)) * time.Second / time.Duration(audio.BytesPerSecond(config.SampleRate, 1))
+	result.Outcome = OutcomeVoicemailLeft
+	return result, call.Hangup(ctx)
+}
+
+// voicemailAudio resolves the message to PCM at the call's sample rate.
+func voicemailAudio(ctx context.Context, config VoicemailConfig) ([]byte, error) {
+	if len(config.Audio) > 0 {
+		return config.Audio, nil
+	}
+	if config.Text == "" {
+		return nil, fmt.Errorf("callsystem: voicemail has neither text nor audio")
+	}
+	if config.TTS == nil {
+		return nil, fmt.Errorf("callsystem: text voicemail requires a TTS client")
+	}
+	cfg := config.Synthesis
+	cfg.OutputFormat = "pcm"
+	cfg.SampleRate = config.SampleRate
+	result, err := config.TTS.Synthesize(ctx, config.Text, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if result.Format != "pcm" || result.SampleRate != config.SampleRate {
+		return nil, fmt.Errorf("%w: voicemail synthesized as %s/%dHz, call expects pcm/%dHz",
+			audio.ErrFormatMismatch, result.Format, result.SampleRate, config.SampleRate)
+	}
+	return result.Audio, nil
+}
+
+// playPaced writes pcm to w in real time, 20ms at a time, so the far end
+// is not flooded.
+func playPaced(ctx context.Context, w io.Writer, pcm []byte, config VoicemailConfig) error {
+	const frame = 20 * time.Millisecond
+	size := max(audio.BytesPerSecond(config.SampleRate, 1)*int(frame/time.Millisecond)/1000&^1, 2)
+	ticker := time.NewTicker(frame)
+	defer ticker.Stop()
+	for len(pcm) > 0 {
+		n := min(size, len(pcm))
+		if _, err := w.Write(encodeLeg(config.Encoding, pcm[:n])); err != nil {
+			return err
+		}
+		pcm = pcm[n:]
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Beep detection parameters: blocks are 20ms, giving 50Hz bins, which are
+// searched in 25Hz steps so no tone falls far from a bin centre.
+const (
+	beepBlock   = 20 * time.Millisecond
+	beepMinFreq = 300
+	beepMaxFreq = 3000
+	beepStep    = 25
+)
+
+// BeepDetector recognizes the beep that ends a voicemail greeting: a
+// single steady tone between 300Hz and 3kHz. It is not safe for
+// concurrent use.
+type BeepDetector struct {
+	// MinLevel is the minimum RMS level (0.0-1.0) of a beep.
+	MinLevel float64
+
+	// MinDuration is the shortest tone taken for a beep. Shorter tones,
+	// and speech, are ignored.
+	MinDuration time.Duration
+
+	blockSize int
+	coeffs    []float64
+	pending   []int16
+
+	freq int
+	tone time.Duration
+}
+
+// NewBeepDetector creates a detector for mono audio at sampleRate.
+func NewBeepDetector(sampleRate int) *BeepDetector {
+	if sampleRate <= 0 {
+		sampleRate = 8000
+	}
+	d := &BeepDetector{
+		MinLevel:    0.01,
+		MinDuration: 150 * time.Millisecond,
+		blockSize:   sampleRate * int(beepBlock/time.Millisecond) / 1000,
+	}
+	for f := beepMinFreq; f <= min(beepMaxFreq, sampleRate/2-beepStep); f += beepStep {
+		d.coeffs = append(d.coeffs, 2*math.Cos(2*math.Pi*float64(f)/float64(sampleRate)))
+	}
+	return d
+}
+
+// Process analyzes mono samples and reports whether a beep ended within
+// them.
+func (d *BeepDetector) Process(frame []int16) bool {
+	ended := false
+	d.pending = append(d.pending, frame...)
+	for len(d.pending) >= d.blockSize {
+		bin := d.classify(d.pending[:d.blockSize])
+		d.pending = d.pending[d.blockSize:]
+		// Allow the tone to drift by one 50Hz bin.
+		if bin >= 0 && (d.tone == 0 || abs(bin-d.freq) <= 2) {
+			if d.tone == 0 {
+				d.freq = bin
+			}
+			d.tone += beepBlock
+			continue
+		}
+		if d.tone >= d.MinDuration {
+			ended = true
+		}
+		d.tone = 0
+		if bin >= 0 {
+			d.freq, d.tone = bin, beepBlock
+		}
+	}
+	d.pending = append(d.pending[:0:0], d.pending...)
+	return ended
+}
+
+// classify returns the index of the tone that dominates the block, or -1.
+func (d *BeepDetector) classify(block []int16) int {
+	var energy float64
+	for _, v := range block {
+		x := float64(v) / 32768
+		energy += x * x
+	}
+	n := float64(len(block))
+	if energy/n < d.MinLevel*d.MinLevel {
+		return -1
+	}
+	best, power := -1, 0.0
+	for i, c := range d.coeffs {
+		var s1, s2 float64
+		for _, v := range block {
+			s0 := float64(v)/32768 + c*s1 - s2
+			s2, s1 = s1, s0
+		}
+		if p := s1*s1 + s2*s2 - c*s1*s2; p > power {
+			best, power = i, p
+		}
+	}
+	// A pure tone puts nearly all of the block's energy in one bin;
+	// speech and noise spread it.
+	if 2*power/(n*energy) < 0.6 {
+		return -1
+	}
+	return best
+}
+
+// decodeLeg converts call audio in encoding to 16-bit PCM.
+func decodeLeg(encoding string, b []byte) []byte {
+	switch encoding {
+	case audio.EncodingMuLaw:
+		return audio.DecodeMuLaw(b)
+	case audio.EncodingALaw:
+		return audio.DecodeALaw(b)
+	}
+	return b
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
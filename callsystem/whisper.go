@@ -0,0 +1,143 @@
+package callsystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/agent"
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/tts"
+)
+
+// Whisperer is implemented by calls that can inject audio audible only to
+// the agent.
+type Whisperer interface {
+	// Whisper injects audio audible only to the agent (e.g., supervisor
+	// coaching during a warm transfer). Text messages are synthesized via
+	// TTS. Implementations mix the audio into the agent's inbound path
+	// (see AgentInput) and emit EventWhisper for audit.
+	Whisper(ctx context.Context, msg WhisperMessage) error
+}
+
+// WhisperMessage is coaching audio delivered only to the agent side of a
+// call; the caller never hears it.
+type WhisperMessage struct {
+	// Text is synthesized through TTS when Audio is empty.
+	Text string
+
+	// Audio is pre-synthesized 16-bit PCM in the agent's input format.
+	Audio []byte
+
+	// Synthesis configures TTS when Text is used. OutputFormat and
+	// SampleRate are overridden to match the agent input.
+	Synthesis tts.SynthesisConfig
+
+	// From identifies the supervisor or system sending the whisper.
+	From string
+
+	// Gain is the linear gain applied to the whisper (0 = unity).
+	Gain float64
+}
+
+// WhisperRecord is the audit record emitted with EventWhisper.
+type WhisperRecord struct {
+	// From identifies who sent the whisper.
+	From string
+
+	// Text is the whisper text, if it was text-based.
+	Text string
+
+	// Duration is the duration of the injected audio.
+	Duration time.Duration
+}
+
+// WhisperAudio resolves a whisper message to PCM in the given format,
+// synthesizing Text with client when no pre-synthesized Audio is supplied.
+func WhisperAudio(ctx context.Context, client *tts.Client, msg WhisperMessage, format audio.Format) ([]byte, error) {
+	if len(msg.Audio) > 0 {
+		return msg.Audio, nil
+	}
+	if msg.Text == "" {
+		return nil, fmt.Errorf("callsystem: whisper has neither text nor audio")
+	}
+	if client == nil {
+		return nil, fmt.Errorf("callsystem: text whisper requires a TTS client")
+	}
+	cfg := msg.Synthesis
+	cfg.OutputFormat = "pcm"
+	cfg.SampleRate = format.SampleRate
+	result, err := client.Synthesize(ctx, msg.Text, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if result.Format != "pcm" || result.SampleRate != format.SampleRate {
+		return nil, fmt.Errorf("%w: whisper synthesized as %s/%dHz, agent expects pcm/%dHz",
+			audio.ErrFormatMismatch, result.Format, result.SampleRate, format.SampleRate)
+	}
+	return result.Audio, nil
+}
+
+// AgentInput builds the agent's inbound audio path: caller audio mixed with
+// any injected whisper audio. Because the mix is only ever delivered to the
+// agent session, injected audio never leaks to the caller. Call
+// implementations use it to implement Whisperer.
+type AgentInput struct {
+	mixer  *audio.Mixer
+	format audio.Format
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewAgentInput creates an agent input path reading caller audio from
+// caller (typically Connection.AudioOut) in the given format.
+func NewAgentInput(caller io.Reader, format audio.Format) (*AgentInput, error) {
+	m := audio.NewMixer(format)
+	if err := m.AddSource("caller", caller, format, 1); err != nil {
+		return nil, err
+	}
+	return &AgentInput{mixer: m, format: m.Format()}, nil
+}
+
+// Inject mixes pcm into the agent's inbound audio and returns its duration.
+// The whisper source is removed once fully played.
+func (a *AgentInput) Inject(pcm []byte, gain float64) time.Duration {
+	if gain == 0 {
+		gain = 1
+	}
+	a.mu.Lock()
+	a.seq++
+	name := fmt.Sprintf("whisper-%d", a.seq)
+	a.mu.Unlock()
+	_ = a.mixer.AddSource(name, bytes.NewReader(pcm), a.format, gain)
+	return time.Duration(len(pcm)) * time.Second / time.Duration(audio.BytesPerSecond(a.format.SampleRate, a.format.Channels))
+}
+
+// Run pumps mixed audio into the session until ctx is done or the caller
+// stream ends. Reads are paced by the caller stream.
+func (a *AgentInput) Run(ctx context.Context, session agent.Session, frame time.Duration) error {
+	if frame <= 0 {
+		frame = 20 * time.Millisecond
+	}
+	size := int(int64(audio.BytesPerSecond(a.format.SampleRate, a.format.Channels)) * int64(frame) / int64(time.Second))
+	buf := make([]byte, size)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(a.mixer.Sources()) == 0 {
+			return io.EOF
+		}
+		n, err := a.mixer.Read(buf)
+		if err != nil {
+			return err
+		}
+		if err := session.SendAudio(append([]byte(nil), buf[:n]...)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package clock abstracts time for the STT and TTS clients, so that
+// retry backoff, attempt timeouts, and latency measurement can be driven
+// deterministically in tests. Production code uses Real, the default.
+//
+// A test swaps in a Fake and moves it forward explicitly, here through a
+// batch transcription's retry backoff:
+//
+//	c := clock.NewFake(time.Unix(0, 0))
+//	client.SetClock(c)
+//	progress, _ := client.TranscribeBatch(ctx, items, config)
+//	c.BlockUntil(1)                // a failed item waits to retry
+//	c.Advance(config.RetryBackoff) // retry it now
+//
+// The clients' Timeouts run on the clock too, through WithTimeout; a
+// deadline ctx carries from the caller still runs on real time.
+package clock
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer returns a timer that fires once after d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer, as time.Timer.
+type Timer interface {
+	// C returns the channel the time is delivered on when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, reporting whether it was
+	// pending.
+	Stop() bool
+}
+
+// Real returns the system clock.
+func Real() Clock {
+	return realClock{}
+}
+
+// Or returns c, or Real if c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real()
+	}
+	return c
+}
+
+// Since returns the time elapsed on c since t.
+func Since(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// WithTimeout is context.WithTimeout timed by c: the returned context
+// ends with context.DeadlineExceeded once d has passed on c. On the real
+// clock it also carries the deadline.
+func WithTimeout(ctx context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(realClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	t := &timeoutCtx{Context: ctx, done: make(chan struct{})}
+	stop := context.AfterFunc(ctx, func() { t.end(ctx.Err()) })
+	timer := c.NewTimer(d)
+	go func() {
+		select {
+		case <-timer.C():
+			t.end(context.DeadlineExceeded)
+		case <-t.done:
+		}
+	}()
+	return t, func() {
+		stop()
+		timer.Stop()
+		t.end(context.Canceled)
+	}
+}
+
+// timeoutCtx is the context of WithTimeout on a clock other than Real.
+type timeoutCtx struct {
+	context.Context
+	done chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (t *timeoutCtx) Done() <-chan struct{} { return t.done }
+
+func (t *timeoutCtx) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *timeoutCtx) end(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err == nil {
+		t.err = err
+		close(t.done)
+	}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+// Fake is a Clock that only moves when told to. It is safe for concurrent
+// use.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	added  chan struct{}
+}
+
+// NewFake creates a fake clock reading start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, added: make(chan struct{})}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer returns a timer that fires once Advance moves the clock d past
+// now. A timer of d <= 0 fires at once.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{f: f, at: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	close(f.added)
+	f.added = make(chan struct{})
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers due by then in
+// order.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	slices.SortStableFunc(f.timers, func(a, b *fakeTimer) int { return a.at.Compare(b.at) })
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.at.After(f.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- t.at
+	}
+	f.timers = pending
+}
+
+// Timers returns the number of timers waiting to fire.
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil waits until at least n timers are waiting to fire, so a test
+// can Advance once the code under test is waiting.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		waiting, added := len(f.timers), f.added
+		f.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		<-added
+	}
+}
+
+type fakeTimer struct {
+	f  *Fake
+	at time.Time
+	c  chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	for i, p := range t.f.timers {
+		if p == t {
+			t.f.timers = append(t.f.timers[:i], t.f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
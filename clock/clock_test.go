@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeoutOnFake(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+	ctx, cancel := WithTimeout(context.Background(), clk, time.Second)
+	defer cancel()
+	child, stop := context.WithCancel(ctx)
+	defer stop()
+
+	clk.Advance(time.Second - 1)
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("ended early: %v", err)
+	}
+	clk.Advance(1)
+	for _, c := range []context.Context{ctx, child} {
+		select {
+		case <-c.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("not done once the clock passed the timeout")
+		}
+		if err := c.Err(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Err() = %v, want DeadlineExceeded", err)
+		}
+	}
+}
+
+func TestWithTimeoutOnFakeCancel(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithTimeout(parent, clk, time.Second)
+	defer cancel()
+
+	cancelParent()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("not done when its parent was canceled")
+	}
+	if err := ctx.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err() = %v, want Canceled", err)
+	}
+	ctx, cancel = WithTimeout(context.Background(), clk, time.Second)
+	cancel()
+	if err := ctx.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err() after cancel = %v, want Canceled", err)
+	}
+}
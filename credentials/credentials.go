@@ -0,0 +1,180 @@
+// Package credentials lets STT and TTS clients pick provider credentials
+// per request, e.g. per tenant, instead of using one key baked into each
+// provider.
+//
+// A Resolver is consulted by the client before every provider attempt and
+// its result is attached to that attempt's context only. Providers read it
+// with FromContext and fall back to their constructor key when none is
+// set. Because nothing is cached, a key rotated in the Resolver takes
+// effect on the next request, and a request can only see the credentials
+// resolved for its own context.
+package credentials
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned by a Resolver that has no credentials for a
+	// tenant and provider.
+	ErrNotFound = errors.New("credentials: not found")
+
+	// ErrNoTenant is returned by Store when the context carries no tenant.
+	ErrNoTenant = errors.New("credentials: no tenant in context")
+)
+
+// Credentials authenticate requests to one provider account. Which
+// fields are used depends on the provider.
+type Credentials struct {
+	// APIKey is the provider API key or token.
+	APIKey string
+
+	// Secret is a secondary secret, for providers that use a key pair.
+	Secret string
+
+	// Endpoint overrides the provider's API base URL, e.g. for a
+	// regional or dedicated deployment.
+	Endpoint string
+
+	// Extra holds provider-specific values such as a project or region.
+	Extra map[string]string
+}
+
+// IsZero reports whether c has no values set.
+func (c Credentials) IsZero() bool {
+	return c.APIKey == "" && c.Secret == "" && c.Endpoint == "" && len(c.Extra) == 0
+}
+
+// String redacts the secrets so credentials can't leak into logs.
+func (c Credentials) String() string {
+	if c.IsZero() {
+		return "credentials{}"
+	}
+	return "credentials{redacted}"
+}
+
+// GoString redacts the secrets for %#v.
+func (c Credentials) GoString() string {
+	return c.String()
+}
+
+// Resolver returns the credentials to use for a request to the named
+// provider. ctx is the request context, so resolvers typically key on
+// TenantFrom(ctx). Implementations must be safe for concurrent use.
+type Resolver interface {
+	Credentials(ctx context.Context, provider string) (Credentials, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(ctx context.Context, provider string) (Credentials, error)
+
+// Credentials calls f.
+func (f ResolverFunc) Credentials(ctx context.Context, provider string) (Credentials, error) {
+	return f(ctx, provider)
+}
+
+type tenantKey struct{}
+
+type credentialsKey struct{}
+
+// WithTenant returns a context identifying the tenant a request is for.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set with WithTenant, or "".
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// NewContext returns a context carrying c for a provider request. Clients
+// call it; providers read the value with FromContext.
+func NewContext(ctx context.Context, c Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, c)
+}
+
+// Resolve returns ctx carrying the credentials r returns for the named
+// provider, for a client about to call it. Credentials already in ctx are
+// replaced, so a request never uses another tenant's. A nil r returns ctx
+// unchanged.
+func Resolve(ctx context.Context, r Resolver, provider string) (context.Context, error) {
+	if r == nil {
+		return ctx, nil
+	}
+	c, err := r.Credentials(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	return NewContext(ctx, c), nil
+}
+
+// FromContext returns the credentials attached by NewContext, if any.
+// Providers should prefer them over their constructor key.
+func FromContext(ctx context.Context) (Credentials, bool) {
+	c, ok := ctx.Value(credentialsKey{}).(Credentials)
+	return c, ok && !c.IsZero()
+}
+
+// Store is an in-memory Resolver keyed by tenant and provider name. It is
+// safe for concurrent use; Set replaces credentials for later requests
+// without affecting requests already under way.
+type Store struct {
+	mu      sync.RWMutex
+	tenants map[string]map[string]Credentials
+}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{tenants: make(map[string]map[string]Credentials)}
+}
+
+// Set stores the credentials of a tenant for the named provider.
+func (s *Store) Set(tenant, provider string, c Credentials) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.tenants[tenant]
+	if !ok {
+		m = make(map[string]Credentials)
+		s.tenants[tenant] = m
+	}
+	c.Extra = maps.Clone(c.Extra)
+	m[provider] = c
+}
+
+// Delete removes the credentials of a tenant for the named provider.
+func (s *Store) Delete(tenant, provider string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tenants[tenant], provider)
+	if len(s.tenants[tenant]) == 0 {
+		delete(s.tenants, tenant)
+	}
+}
+
+// DeleteTenant removes all credentials of a tenant.
+func (s *Store) DeleteTenant(tenant string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tenants, tenant)
+}
+
+// Credentials returns the credentials of the context's tenant for the
+// named provider.
+func (s *Store) Credentials(ctx context.Context, provider string) (Credentials, error) {
+	tenant := TenantFrom(ctx)
+	if tenant == "" {
+		return Credentials{}, ErrNoTenant
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.tenants[tenant][provider]
+	if !ok {
+		return Credentials{}, ErrNotFound
+	}
+	c.Extra = maps.Clone(c.Extra)
+	return c, nil
+}
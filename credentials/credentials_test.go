@@ -0,0 +1,144 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestResolveReplacesContextCredentials(t *testing.T) {
+	ctx := NewContext(context.Background(), Credentials{APIKey: "tenant-a"})
+	r := ResolverFunc(func(ctx context.Context, provider string) (Credentials, error) {
+		return Credentials{APIKey: provider + "-key"}, nil
+	})
+	ctx, err := Resolve(ctx, r, "deepgram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := FromContext(ctx); !ok || c.APIKey != "deepgram-key" {
+		t.Errorf("FromContext = %v %q, want the resolver's key", ok, c.APIKey)
+	}
+
+	// A resolver returning nothing must not leave the earlier key visible.
+	empty := ResolverFunc(func(context.Context, string) (Credentials, error) { return Credentials{}, nil })
+	ctx, err = Resolve(ctx, empty, "deepgram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := FromContext(ctx); ok {
+		t.Errorf("FromContext after an empty resolve = %q, want none", c.APIKey)
+	}
+}
+
+func TestResolveNilResolver(t *testing.T) {
+	ctx := NewContext(context.Background(), Credentials{APIKey: "k"})
+	got, err := Resolve(ctx, nil, "deepgram")
+	if err != nil || got != ctx {
+		t.Errorf("Resolve with a nil resolver = %v, %v; want ctx unchanged", got, err)
+	}
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext found credentials in an empty context")
+	}
+}
+
+func TestResolveError(t *testing.T) {
+	r := ResolverFunc(func(context.Context, string) (Credentials, error) { return Credentials{}, ErrNotFound })
+	if _, err := Resolve(context.Background(), r, "deepgram"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreResolution(t *testing.T) {
+	s := NewStore()
+	s.Set("a", "deepgram", Credentials{APIKey: "a-deepgram"})
+	s.Set("a", "elevenlabs", Credentials{APIKey: "a-elevenlabs"})
+	s.Set("b", "deepgram", Credentials{APIKey: "b-deepgram"})
+
+	tests := []struct {
+		tenant, provider string
+		want             string
+		err              error
+	}{
+		{"a", "deepgram", "a-deepgram", nil},
+		{"a", "elevenlabs", "a-elevenlabs", nil},
+		{"b", "deepgram", "b-deepgram", nil},
+		{"b", "elevenlabs", "", ErrNotFound},
+		{"c", "deepgram", "", ErrNotFound},
+		{"", "deepgram", "", ErrNoTenant},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.tenant != "" {
+			ctx = WithTenant(ctx, tt.tenant)
+		}
+		c, err := s.Credentials(ctx, tt.provider)
+		if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) || c.APIKey != tt.want {
+			t.Errorf("tenant %q %s = %q, %v; want %q, %v", tt.tenant, tt.provider, c.APIKey, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestStoreRotationAndRemoval(t *testing.T) {
+	s := NewStore()
+	ctx := WithTenant(context.Background(), "a")
+	s.Set("a", "deepgram", Credentials{APIKey: "old"})
+	inFlight, err := Resolve(ctx, s, "deepgram")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Set("a", "deepgram", Credentials{APIKey: "new"})
+	next, err := Resolve(ctx, s, "deepgram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := FromContext(next); c.APIKey != "new" {
+		t.Errorf("request after rotation used %q, want new", c.APIKey)
+	}
+	if c, _ := FromContext(inFlight); c.APIKey != "old" {
+		t.Errorf("request under way switched to %q, want old", c.APIKey)
+	}
+
+	s.Delete("a", "deepgram")
+	if _, err := Resolve(ctx, s, "deepgram"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve after Delete = %v, want ErrNotFound", err)
+	}
+	s.Set("a", "deepgram", Credentials{APIKey: "k"})
+	s.Set("a", "elevenlabs", Credentials{APIKey: "k"})
+	s.DeleteTenant("a")
+	for _, provider := range []string{"deepgram", "elevenlabs"} {
+		if _, err := s.Credentials(ctx, provider); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s after DeleteTenant = %v, want ErrNotFound", provider, err)
+		}
+	}
+}
+
+func TestStoreCopiesExtra(t *testing.T) {
+	s := NewStore()
+	extra := map[string]string{"region": "eu"}
+	s.Set("a", "azure", Credentials{APIKey: "k", Extra: extra})
+	extra["region"] = "us"
+
+	ctx := WithTenant(context.Background(), "a")
+	c, err := s.Credentials(ctx, "azure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Extra["region"] = "ap"
+	if again, _ := s.Credentials(ctx, "azure"); again.Extra["region"] != "eu" {
+		t.Errorf("stored region %q, want eu", again.Extra["region"])
+	}
+}
+
+func TestCredentialsRedacted(t *testing.T) {
+	c := Credentials{APIKey: "sk-live-123", Secret: "s3cret"}
+	for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+		if got := fmt.Sprintf(format, c); got != "credentials{redacted}" {
+			t.Errorf("%s formatted as %q", format, got)
+		}
+	}
+	if got := fmt.Sprint(Credentials{}); got != "credentials{}" {
+		t.Errorf("zero credentials formatted as %q", got)
+	}
+}
@@ -27,25 +27,41 @@ func main() {
 	//
 	// client := tts.NewClient(elevenLabs, awsPolly, googleTTS)
 
+	// Register one logical voice so fallback keeps the same voice
+	// identity on every provider
+	//
+	// err := client.RegisterVoice(ctx, "support-female-en", map[string]string{
+	//     "elevenlabs": "rachel",
+	//     "polly":      "Joanna",
+	//     "google":     "en-US-Neural2-F",
+	// })
+	// if err != nil {
+	//     log.Fatalf("Voice alias: %v", err)
+	// }
+
 	// Example synthesis
 	text := "Hello! This is a demonstration of OmniVoice text-to-speech capabilities."
 
 	config := tts.SynthesisConfig{
-		VoiceID:      "rachel", // ElevenLabs voice
+		VoiceID:      "support-female-en", // resolved per provider
 		OutputFormat: "mp3",
 		SampleRate:   44100,
 	}
 
 	// Synthesize with automatic fallback
-	// If ElevenLabs fails, tries AWS Polly, then Google TTS
+	// If ElevenLabs fails, tries AWS Polly as Joanna, then Google TTS
 	//
 	// result, err := client.Synthesize(ctx, text, config)
 	// if err != nil {
 	//     log.Fatalf("All providers failed: %v", err)
 	// }
 	//
-	// // Save to file
-	// os.WriteFile("output.mp3", result.Audio, 0644)
+	// // Save to file (use result.WriteWAV for "pcm" output)
+	// f, _ := os.Create("output.mp3")
+	// defer f.Close()
+	// if err := result.WriteEncoded(f); err != nil {
+	//     log.Fatalf("Failed to save audio: %v", err)
+	// }
 	// fmt.Printf("Generated %d bytes, duration: %dms\n",
 	//     len(result.Audio), result.DurationMs)
 
@@ -0,0 +1,85 @@
+// Example: Voice agent on a raw SIP trunk
+//
+// This example registers with a SIP registrar (e.g., a local Asterisk or
+// FreeSWITCH test PBX) and answers inbound calls, echoing caller audio back
+// as a stand-in for an OmniVoice agent session.
+//
+// Architecture:
+//
+//	┌──────────┐  SIP/RTP  ┌─────────────┐  SIP/RTP  ┌───────────────────┐
+//	│  Phone   │◄─────────►│  Test PBX   │◄─────────►│   OmniVoice       │
+//	│ (softphone)          │ (Asterisk)  │           │   transport/sip   │
+//	└──────────┘           └─────────────┘           └───────────────────┘
+//
+// Usage:
+//
+//	SIP_SERVER=pbx.local SIP_USER=agent SIP_PASSWORD=secret go run ./examples/sip-agent
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/agentplexus/omnivoice/transport"
+	"github.com/agentplexus/omnivoice/transport/sip"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	sipTransport := sip.New(sip.Config{
+		Audio: transport.Config{SampleRate: 8000, Channels: 1, Encoding: "pcm"},
+	})
+	defer sipTransport.Close()
+
+	calls, err := sipTransport.Listen(ctx, ":5070")
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := os.Getenv("SIP_SERVER")
+	if err := sipTransport.Register(ctx, server, os.Getenv("SIP_USER"), os.Getenv("SIP_PASSWORD")); err != nil {
+		log.Fatalf("Failed to register with %s: %v", server, err)
+	}
+	log.Printf("Registered with %s", server)
+
+	sipTransport.OnInvite(func(conn transport.Connection, from string) bool {
+		log.Printf("Incoming call from %s", from)
+		return true
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case conn, ok := <-calls:
+			if !ok {
+				return
+			}
+			go handleCall(conn)
+		}
+	}
+}
+
+// handleCall echoes audio back to the caller until hangup.
+//
+// With OmniVoice (pseudo-code), attach an agent session instead:
+//
+//	session, _ := agentProvider.CreateSession(ctx, agent.Config{...})
+//	go io.Copy(sessionAudioWriter, conn.AudioOut())
+//	for audio := range session.ReceiveAudio() { conn.AudioIn().Write(audio) }
+func handleCall(conn transport.Connection) {
+	log.Printf("Call %s connected", conn.ID())
+	go func() {
+		_, _ = io.Copy(conn.AudioIn(), conn.AudioOut())
+	}()
+	for ev := range conn.Events() {
+		log.Printf("Call %s event: %s", conn.ID(), ev.Type)
+	}
+	log.Printf("Call %s ended", conn.ID())
+}
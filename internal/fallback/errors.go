@@ -0,0 +1,118 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Kinds are the sentinel errors a client package classifies provider
+// failures by.
+type Kinds struct {
+	Unauthorized  error
+	BadRequest    error
+	RateLimited   error
+	QuotaExceeded error
+	ServerError   error
+	Timeout       error
+	NetworkError  error
+}
+
+// Classify returns the kind of a provider failure: by its HTTP status if
+// statusCode is an error status, and otherwise by err, where deadlines
+// and network timeouts are Timeout and other network failures
+// NetworkError. It returns nil if neither tells.
+func (k Kinds) Classify(statusCode int, err error) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return k.Unauthorized
+	case statusCode == http.StatusPaymentRequired:
+		return k.QuotaExceeded
+	case statusCode == http.StatusTooManyRequests:
+		return k.RateLimited
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return k.Timeout
+	case statusCode >= 500:
+		return k.ServerError
+	case statusCode >= 400:
+		return k.BadRequest
+	}
+	var ne net.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &ne) && ne.Timeout():
+		return k.Timeout
+	case errors.As(err, &ne), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return k.NetworkError
+	}
+	return nil
+}
+
+// Retryable reports whether a request that failed with err might succeed
+// if retried later: true if err wraps ServerError, Timeout, NetworkError,
+// or RateLimited, and otherwise false if it wraps Unauthorized,
+// BadRequest, QuotaExceeded, or one of permanent.
+func (k Kinds) Retryable(err error, permanent ...error) bool {
+	for _, transient := range []error{k.ServerError, k.Timeout, k.NetworkError, k.RateLimited} {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	for _, p := range append([]error{k.Unauthorized, k.BadRequest, k.QuotaExceeded}, permanent...) {
+		if errors.Is(err, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Unavailable builds the error for a request no provider completed: err,
+// wrapping RateLimited if providers were skipped for rate limits, and
+// the non-nil causes.
+func (k Kinds) Unavailable(err error, limited bool, causes ...error) error {
+	if limited {
+		err = fmt.Errorf("%w: %w", err, k.RateLimited)
+	}
+	for _, e := range causes {
+		if e != nil {
+			err = fmt.Errorf("%w: %w", err, e)
+		}
+	}
+	return err
+}
+
+// ErrorString formats a provider error: its kind, or "<scope>: provider
+// error" if it has none, then the provider, HTTP status, and err.
+func ErrorString(scope, provider string, statusCode int, kind, err error) string {
+	var b strings.Builder
+	if kind != nil {
+		b.WriteString(kind.Error())
+	} else {
+		b.WriteString(scope + ": provider error")
+	}
+	b.WriteString(": " + provider)
+	if statusCode != 0 {
+		fmt.Fprintf(&b, ": HTTP %d", statusCode)
+	}
+	if err != nil {
+		b.WriteString(": " + err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns those of kind and err that are not nil, for a provider
+// error's Unwrap.
+func Unwrap(kind, err error) []error {
+	var errs []error
+	for _, e := range []error{kind, err} {
+		if e != nil {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
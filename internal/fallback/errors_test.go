@@ -0,0 +1,132 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	errUnauthorized  = errors.New("unauthorized")
+	errBadRequest    = errors.New("bad request")
+	errRateLimited   = errors.New("rate limited")
+	errQuotaExceeded = errors.New("quota exceeded")
+	errServerError   = errors.New("server error")
+	errTimeout       = errors.New("timeout")
+	errNetworkError  = errors.New("network error")
+)
+
+var testKinds = Kinds{
+	Unauthorized:  errUnauthorized,
+	BadRequest:    errBadRequest,
+	RateLimited:   errRateLimited,
+	QuotaExceeded: errQuotaExceeded,
+	ServerError:   errServerError,
+	Timeout:       errTimeout,
+	NetworkError:  errNetworkError,
+}
+
+// timeoutError is a net.Error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		err    error
+		want   error
+	}{
+		{401, nil, errUnauthorized},
+		{403, nil, errUnauthorized},
+		{402, nil, errQuotaExceeded},
+		{429, nil, errRateLimited},
+		{408, nil, errTimeout},
+		{504, nil, errTimeout},
+		{503, nil, errServerError},
+		{422, nil, errBadRequest},
+		// The status decides over the error.
+		{500, context.DeadlineExceeded, errServerError},
+		{0, fmt.Errorf("dial: %w", context.DeadlineExceeded), errTimeout},
+		{0, timeoutError{}, errTimeout},
+		{0, &net.OpError{Op: "dial", Err: errors.New("refused")}, errNetworkError},
+		{0, io.ErrUnexpectedEOF, errNetworkError},
+		{0, errors.New("odd"), nil},
+		{200, nil, nil},
+	} {
+		if got := testKinds.Classify(tc.status, tc.err); got != tc.want {
+			t.Errorf("Classify(%d, %v) = %v, want %v", tc.status, tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{errServerError, true},
+		{fmt.Errorf("a: %w", errRateLimited), true},
+		{errUnauthorized, false},
+		{errPermanent, false},
+		// A transient failure of one provider outweighs a permanent one
+		// of another.
+		{errors.Join(errUnauthorized, errTimeout), true},
+		{errors.New("unknown"), true},
+	} {
+		if got := testKinds.Retryable(tc.err, errPermanent); got != tc.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	errNone := errors.New("no provider")
+	cause := errors.New("cause")
+	err := testKinds.Unavailable(errNone, true, nil, cause)
+	for _, want := range []error{errNone, errRateLimited, cause} {
+		if !errors.Is(err, want) {
+			t.Errorf("%v does not wrap %v", err, want)
+		}
+	}
+	if err := testKinds.Unavailable(errNone, false); err != errNone {
+		t.Errorf("Unavailable without causes = %v, want %v", err, errNone)
+	}
+}
+
+func TestEach(t *testing.T) {
+	errFailed := errors.New("failed")
+	providers := map[string]int{"a": 1, "b": 2, "c": 3}
+	var started sync.WaitGroup
+	started.Add(len(providers))
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err = Each(providers, func(name string, n int) error {
+			// Every call waits for the others, so they must run at once.
+			started.Done()
+			started.Wait()
+			if n == 2 {
+				return fmt.Errorf("%s: %w", name, errFailed)
+			}
+			return nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Each did not run the calls concurrently")
+	}
+	if !errors.Is(err, errFailed) || err.Error() != "b: failed" {
+		t.Errorf("Each = %v, want the one failure", err)
+	}
+}
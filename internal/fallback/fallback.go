@@ -0,0 +1,31 @@
+// Package fallback holds the machinery the STT and TTS clients share for
+// running a request across their chain of providers: attempt timeouts,
+// failure classification, and fanning out to every provider. Each client
+// wraps it in its own exported API and documentation.
+package fallback
+
+import (
+	"errors"
+	"sync"
+)
+
+// Each calls fn for every provider concurrently and joins the errors it
+// returns.
+func Each[P any](providers map[string]P, fn func(name string, p P) error) error {
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for name, p := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(name, p); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,41 @@
+package fallback
+
+import (
+	"context"
+	"time"
+
+	"github.com/agentplexus/omnivoice/clock"
+)
+
+// Timeouts has the fields of the clients' Timeouts, so theirs convert to
+// it.
+type Timeouts struct {
+	Attempt   time.Duration
+	Providers map[string]time.Duration
+	Overall   time.Duration
+}
+
+// AttemptFor returns the attempt bound for the named provider.
+func (t Timeouts) AttemptFor(provider string) time.Duration {
+	if d, ok := t.Providers[provider]; ok {
+		return d
+	}
+	return t.Attempt
+}
+
+// WithOverall bounds ctx by the overall timeout on clk.
+func (t Timeouts) WithOverall(ctx context.Context, clk clock.Clock) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, clk, t.Overall)
+}
+
+// WithAttempt bounds ctx by the named provider's attempt timeout on clk.
+func (t Timeouts) WithAttempt(ctx context.Context, clk clock.Clock, provider string) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, clk, t.AttemptFor(provider))
+}
+
+func withTimeout(ctx context.Context, clk clock.Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return clock.WithTimeout(ctx, clk, d)
+}
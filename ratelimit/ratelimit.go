@@ -0,0 +1,247 @@
+// Package ratelimit provides per-provider request limiting: a token bucket
+// for request rate combined with a cap on concurrent requests.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentplexus/omnivoice/clock"
+)
+
+// ErrLimited is returned when a request cannot be admitted within its wait
+// budget.
+var ErrLimited = errors.New("ratelimit: limit exceeded")
+
+// Limit configures a Limiter. Zero fields disable the corresponding limit.
+type Limit struct {
+	// RequestsPerSecond is the sustained request rate.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that may start at once after idle
+	// time. Values below 1 are treated as 1.
+	Burst int
+
+	// MaxInFlight is the maximum number of concurrent requests.
+	MaxInFlight int
+
+	// MaxWait bounds how long a request queues for admission. Zero waits
+	// until the context ends. Under a context deadline a request waits at
+	// most half the time left, and fails at once if its rate slot falls
+	// later, so callers have time to try elsewhere.
+	MaxWait time.Duration
+}
+
+// deadlineShare is the share of the time left before a context deadline
+// a request may spend queueing; the rest is left for a fallback.
+const deadlineShare = 0.5
+
+// Limiter admits requests according to a Limit. It is safe for concurrent
+// use.
+type Limiter struct {
+	limit Limit
+	slots chan struct{}
+	clock clock.Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	waiting  atomic.Int64
+	inFlight atomic.Int64
+}
+
+// New creates a limiter.
+func New(limit Limit) *Limiter {
+	return newLimiter(limit, clock.Real())
+}
+
+func newLimiter(limit Limit, clk clock.Clock) *Limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	l := &Limiter{
+		limit:  limit,
+		clock:  clk,
+		tokens: float64(limit.Burst),
+		last:   clk.Now(),
+	}
+	if limit.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, limit.MaxInFlight)
+	}
+	return l
+}
+
+// Limit returns the limiter's configuration.
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// QueueDepth returns the number of requests waiting for admission.
+func (l *Limiter) QueueDepth() int {
+	return int(l.waiting.Load())
+}
+
+// InFlight returns the number of admitted requests not yet released.
+func (l *Limiter) InFlight() int {
+	return int(l.inFlight.Load())
+}
+
+// Acquire waits for admission and returns a function that must be called
+// when the request completes. It returns ErrLimited if admission is not
+// possible within MaxWait or its share of the time before the context
+// deadline, and ctx.Err() if the context ends first.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+
+	// A context deadline runs on real time, the wait on the limiter's
+	// clock: the budget carries over as a duration.
+	budget, hasDeadline := l.limit.MaxWait, l.limit.MaxWait > 0
+	if d, ok := ctx.Deadline(); ok {
+		if share := time.Duration(float64(time.Until(d)) * deadlineShare); !hasDeadline || share < budget {
+			budget, hasDeadline = share, true
+		}
+	}
+	deadline := l.clock.Now().Add(budget)
+
+	if err := l.waitToken(ctx, deadline, hasDeadline); err != nil {
+		return nil, err
+	}
+	if err := l.waitSlot(ctx, deadline, hasDeadline); err != nil {
+		l.returnToken()
+		return nil, err
+	}
+
+	l.inFlight.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.inFlight.Add(-1)
+			if l.slots != nil {
+				<-l.slots
+			}
+		})
+	}, nil
+}
+
+// waitToken reserves a rate token, sleeping until it is due.
+func (l *Limiter) waitToken(ctx context.Context, deadline time.Time, hasDeadline bool) error {
+	if l.limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := l.clock.Now()
+	l.tokens = min(float64(l.limit.Burst), l.tokens+now.Sub(l.last).Seconds()*l.limit.RequestsPerSecond)
+	l.last = now
+	var wait time.Duration
+	if l.tokens < 1 {
+		wait = time.Duration((1 - l.tokens) / l.limit.RequestsPerSecond * float64(time.Second))
+	}
+	if hasDeadline && now.Add(wait).After(deadline) {
+		l.mu.Unlock()
+		return ErrLimited
+	}
+	l.tokens--
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := l.clock.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		l.returnToken()
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) returnToken() {
+	if l.limit.RequestsPerSecond <= 0 {
+		return
+	}
+	l.mu.Lock()
+	l.tokens = min(float64(l.limit.Burst), l.tokens+1)
+	l.mu.Unlock()
+}
+
+// waitSlot takes a concurrency slot.
+func (l *Limiter) waitSlot(ctx context.Context, deadline time.Time, hasDeadline bool) error {
+	if l.slots == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	var expired <-chan time.Time
+	if hasDeadline {
+		t := l.clock.NewTimer(deadline.Sub(l.clock.Now()))
+		defer t.Stop()
+		expired = t.C()
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-expired:
+		return ErrLimited
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Group holds a Limiter per provider name, for a client choosing among
+// providers. The zero Group limits nothing, on the real clock. Set and
+// SetClock must not be called concurrently with other methods.
+type Group struct {
+	limiters map[string]*Limiter
+	clock    clock.Clock
+}
+
+// SetClock sets the clock the group's limiters wait on. A nil clock is
+// Real. Limiters already set start over with full buckets.
+func (g *Group) SetClock(clk clock.Clock) {
+	g.clock = clock.Or(clk)
+	for provider, l := range g.limiters {
+		g.limiters[provider] = newLimiter(l.limit, g.clock)
+	}
+}
+
+// Set limits requests to the named provider. A zero Limit removes the
+// limiter.
+func (g *Group) Set(provider string, limit Limit) {
+	if limit == (Limit{}) {
+		delete(g.limiters, provider)
+		return
+	}
+	if g.limiters == nil {
+		g.limiters = make(map[string]*Limiter)
+	}
+	g.limiters[provider] = newLimiter(limit, clock.Or(g.clock))
+}
+
+// QueueDepth returns the number of requests waiting for admission to the
+// named provider.
+func (g *Group) QueueDepth(provider string) int {
+	if l, ok := g.limiters[provider]; ok {
+		return l.QueueDepth()
+	}
+	return 0
+}
+
+// Acquire waits for admission to the named provider, as Limiter.Acquire.
+// A provider without a limit is admitted at once.
+func (g *Group) Acquire(ctx context.Context, provider string) (release func(), err error) {
+	l, ok := g.limiters[provider]
+	if !ok {
+		return func() {}, nil
+	}
+	return l.Acquire(ctx)
+}
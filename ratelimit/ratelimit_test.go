@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/clock"
+)
+
+func TestGroup(t *testing.T) {
+	var g Group
+	release, err := g.Acquire(context.Background(), "open")
+	if err != nil {
+		t.Fatalf("unlimited provider not admitted: %v", err)
+	}
+	release()
+
+	g.Set("busy", Limit{MaxInFlight: 1, MaxWait: 20 * time.Millisecond})
+	release, err = g.Acquire(context.Background(), "busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Acquire(context.Background(), "busy"); !errors.Is(err, ErrLimited) {
+		t.Errorf("second request = %v, want ErrLimited", err)
+	}
+	if _, err := g.Acquire(context.Background(), "open"); err != nil {
+		t.Errorf("another provider's limit applied: %v", err)
+	}
+	release()
+
+	g.Set("busy", Limit{})
+	if n := g.QueueDepth("busy"); n != 0 {
+		t.Errorf("QueueDepth = %d after removing the limit", n)
+	}
+	for range 3 {
+		if _, err := g.Acquire(context.Background(), "busy"); err != nil {
+			t.Fatalf("removed limit still applied: %v", err)
+		}
+	}
+}
+
+func TestDeadlineLeavesTimeForFallback(t *testing.T) {
+	l := New(Limit{MaxInFlight: 1})
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := l.Acquire(ctx); !errors.Is(err, ErrLimited) {
+		t.Fatalf("Acquire = %v, want ErrLimited", err)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("gave up after %v, at the context deadline", time.Since(start))
+	}
+}
+
+func TestGroupWaitsOnClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	var g Group
+	g.SetClock(clk)
+	g.Set("busy", Limit{MaxInFlight: 1, MaxWait: time.Minute})
+	release, err := g.Acquire(context.Background(), "busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.Acquire(context.Background(), "busy")
+		done <- err
+	}()
+	clk.BlockUntil(1)
+	select {
+	case err := <-done:
+		t.Fatalf("Acquire returned %v before the clock reached MaxWait", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(time.Minute)
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrLimited) {
+			t.Errorf("Acquire = %v, want ErrLimited", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Acquire still waiting after the clock passed MaxWait")
+	}
+}
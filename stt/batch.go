@@ -0,0 +1,417 @@
+package stt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// BatchItem is a recording for TranscribeBatch.
+type BatchItem struct {
+	// ID names the item in the manifest and its output file, and must be
+	// unique within a batch. Defaults to Source's base name without its
+	// extension.
+	ID string
+
+	// Source is a file path or an http(s) URL. URLs are passed to the
+	// provider with Client.TranscribeURL.
+	Source string
+}
+
+// BatchConfig configures TranscribeBatch.
+type BatchConfig struct {
+	// Transcription configures each request. For WAV files SampleRate,
+	// Channels, and Encoding come from the file header, except that a
+	// Channels of 1 downmixes stereo files; for other files an empty
+	// Encoding is taken from the file extension.
+	Transcription TranscriptionConfig
+
+	// OutputDir receives each result as <ID>.json. Required.
+	OutputDir string
+
+	// Manifest is the path of the file recording completed items, so a
+	// rerun skips them. Defaults to manifest.json in OutputDir.
+	Manifest string
+
+	// Concurrency is the number of items transcribed at once. Defaults
+	// to 4.
+	Concurrency int
+
+	// Retries is how many times a failed item is retried, with backoff.
+	// Defaults to 2; negative disables retries. Errors that cannot succeed
+	// on retry, such as missing files or ErrNoCapableProvider, are not
+	// retried.
+	Retries int
+
+	// RetryBackoff is the wait before the first retry, doubling after
+	// each. Defaults to 1s.
+	RetryBackoff time.Duration
+}
+
+func (c BatchConfig) withDefaults() BatchConfig {
+	if c.Manifest == "" {
+		c.Manifest = filepath.Join(c.OutputDir, "manifest.json")
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 4
+	}
+	if c.Retries == 0 {
+		c.Retries = 2
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = time.Second
+	}
+	return c
+}
+
+// BatchStatus is the outcome of a batch item.
+type BatchStatus string
+
+const (
+	// BatchSucceeded means the item was transcribed and its result written.
+	BatchSucceeded BatchStatus = "succeeded"
+
+	// BatchFailed means the item failed after all retries. It is retried
+	// on the next run.
+	BatchFailed BatchStatus = "failed"
+
+	// BatchResumed means the manifest showed the item already succeeded
+	// in an earlier run, so it was skipped.
+	BatchResumed BatchStatus = "resumed"
+)
+
+// BatchProgress reports a completed batch item.
+type BatchProgress struct {
+	// Item is the item.
+	Item BatchItem
+
+	// Status is the outcome.
+	Status BatchStatus
+
+	// Output is the path of the result file, unless the item failed.
+	Output string
+
+	// Result is the transcription, unless the item failed or was resumed.
+	Result *TranscriptionResult
+
+	// Attempts is the number of transcription attempts made this run.
+	Attempts int
+
+	// Err is the last error of a failed item.
+	Err error
+
+	// Stats are the batch totals so far.
+	Stats BatchStats
+}
+
+// BatchStats are the totals of a batch.
+type BatchStats struct {
+	// Total is the number of items in the batch.
+	Total int
+
+	// Succeeded counts items with a result, including Resumed ones.
+	Succeeded int
+
+	// Failed counts items that failed after all retries.
+	Failed int
+
+	// Resumed counts items completed by an earlier run.
+	Resumed int
+
+	// AudioDuration is the total audio transcribed by succeeded items.
+	AudioDuration time.Duration
+}
+
+// batchManifest is the on-disk record of completed items.
+type batchManifest struct {
+	Items map[string]batchEntry `json:"items"`
+}
+
+type batchEntry struct {
+	Source       string      `json:"source"`
+	Status       BatchStatus `json:"status"`
+	Output       string      `json:"output,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	Attempts     int         `json:"attempts"`
+	AudioSeconds float64     `json:"audio_seconds,omitempty"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// TranscribeBatch transcribes recordings offline with bounded
+// concurrency and retries, writing each result to config.OutputDir and
+// reporting each completed item on the returned channel, which closes
+// when the batch is done. The channel must be drained.
+//
+// Completed items are recorded in a manifest as they finish; running the
+// same batch again skips items that already succeeded, so a crash only
+// loses items in flight. If ctx ends, items in flight are abandoned
+// without being recorded and the channel closes.
+func (c *Client) TranscribeBatch(ctx context.Context, items []BatchItem, config BatchConfig) (<-chan BatchProgress, error) {
+	if config.OutputDir == "" {
+		return nil, fmt.Errorf("%w: batch output directory required", ErrInvalidConfig)
+	}
+	config = config.withDefaults()
+	items, err := batchItems(items)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(config.OutputDir, 0o750); err != nil {
+		return nil, err
+	}
+	manifest, err := readBatchManifest(config.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &batch{
+		client:   c,
+		config:   config,
+		manifest: manifest,
+		stats:    BatchStats{Total: len(items)},
+		out:      make(chan BatchProgress),
+	}
+	go b.run(ctx, items)
+	return b.out, nil
+}
+
+// batchItems fills in default IDs and checks they are unique file names.
+func batchItems(items []BatchItem) ([]BatchItem, error) {
+	items = append([]BatchItem(nil), items...)
+	seen := make(map[string]bool, len(items))
+	for i, it := range items {
+		if it.ID == "" {
+			base := filepath.Base(it.Source)
+			if isURL(it.Source) {
+				base = it.Source[strings.LastIndex(it.Source, "/")+1:]
+				base, _, _ = strings.Cut(base, "?")
+			}
+			it.ID = strings.TrimSuffix(base, filepath.Ext(base))
+			items[i] = it
+		}
+		if it.ID == "" || it.ID == "." || it.ID == ".." || strings.ContainsAny(it.ID, `/\`) {
+			return nil, fmt.Errorf("%w: invalid batch item ID %q for %s", ErrInvalidConfig, it.ID, it.Source)
+		}
+		if seen[it.ID] {
+			return nil, fmt.Errorf("%w: duplicate batch item ID %q", ErrInvalidConfig, it.ID)
+		}
+		seen[it.ID] = true
+	}
+	return items, nil
+}
+
+func readBatchManifest(path string) (*batchManifest, error) {
+	m := &batchManifest{Items: make(map[string]batchEntry)}
+	data, err := os.ReadFile(path) // #nosec G304 -- caller-supplied manifest path
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("stt: batch manifest %s: %w", path, err)
+	}
+	if m.Items == nil {
+		m.Items = make(map[string]batchEntry)
+	}
+	return m, nil
+}
+
+type batch struct {
+	client *Client
+	config BatchConfig
+	out    chan BatchProgress
+
+	mu       sync.Mutex
+	manifest *batchManifest
+	stats    BatchStats
+}
+
+func (b *batch) run(ctx context.Context, items []BatchItem) {
+	defer close(b.out)
+	work := make(chan BatchItem)
+	var wg sync.WaitGroup
+	for range b.config.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range work {
+				if p, ok := b.process(ctx, it); ok {
+					b.send(ctx, p)
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, it := range items {
+		if p, ok := b.resume(it); ok {
+			b.send(ctx, p)
+			continue
+		}
+		select {
+		case work <- it:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+}
+
+func (b *batch) send(ctx context.Context, p BatchProgress) {
+	select {
+	case b.out <- p:
+	case <-ctx.Done():
+	}
+}
+
+// resume reports an item that succeeded in an earlier run and whose
+// output is still present.
+func (b *batch) resume(it BatchItem) (BatchProgress, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.manifest.Items[it.ID]
+	if !ok || e.Status != BatchSucceeded || e.Source != it.Source {
+		return BatchProgress{}, false
+	}
+	if _, err := os.Stat(e.Output); err != nil {
+		return BatchProgress{}, false
+	}
+	b.stats.Succeeded++
+	b.stats.Resumed++
+	b.stats.AudioDuration += time.Duration(e.AudioSeconds * float64(time.Second))
+	return BatchProgress{Item: it, Status: BatchResumed, Output: e.Output, Stats: b.stats}, true
+}
+
+// process transcribes an item with retries and records the outcome. It
+// reports false if ctx ended first.
+func (b *batch) process(ctx context.Context, it BatchItem) (BatchProgress, bool) {
+	p := BatchProgress{Item: it}
+	backoff := b.config.RetryBackoff
+	for {
+		p.Attempts++
+		p.Result, p.Err = b.transcribe(ctx, it)
+		if ctx.Err() != nil {
+			return p, false
+		}
+		if p.Err == nil || !retryable(p.Err) || p.Attempts > b.config.Retries {
+			break
+		}
+		t := b.client.clock.NewTimer(backoff)
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			t.Stop()
+			return p, false
+		}
+		backoff *= 2
+	}
+
+	if p.Err == nil {
+		p.Output = filepath.Join(b.config.OutputDir, it.ID+".json")
+		p.Err = writeJSON(p.Output, p.Result)
+	}
+	p.Status = BatchSucceeded
+	if p.Err != nil {
+		p.Status = BatchFailed
+		p.Output = ""
+	}
+	return b.record(p), true
+}
+
+func (b *batch) transcribe(ctx context.Context, it BatchItem) (*TranscriptionResult, error) {
+	config := b.config.Transcription
+	if isURL(it.Source) {
+		return b.client.TranscribeURL(ctx, it.Source, config)
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(it.Source), "."))
+	if ext == "wav" {
+		data, wav, err := ReadWAVFile(it.Source)
+		if err != nil {
+			return nil, err
+		}
+		config = withWAVFormat(config, audio.Format{SampleRate: wav.SampleRate, Channels: wav.Channels})
+		return b.client.Transcribe(ctx, data, config)
+	}
+	data, err := os.ReadFile(it.Source) // #nosec G304 -- caller-supplied recording path
+	if err != nil {
+		return nil, err
+	}
+	if config.Encoding == "" {
+		config.Encoding = ext
+	}
+	return b.client.Transcribe(ctx, data, config)
+}
+
+// record updates the stats and manifest with a finished item.
+func (b *batch) record(p BatchProgress) BatchProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e := batchEntry{
+		Source:    p.Item.Source,
+		Status:    p.Status,
+		Output:    p.Output,
+		Attempts:  p.Attempts,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if p.Status == BatchSucceeded {
+		b.stats.Succeeded++
+		if p.Result != nil {
+			b.stats.AudioDuration += p.Result.Duration
+			e.AudioSeconds = p.Result.Duration.Seconds()
+		}
+	} else {
+		b.stats.Failed++
+		e.Error = p.Err.Error()
+	}
+	b.manifest.Items[p.Item.ID] = e
+	if err := writeJSON(b.config.Manifest, b.manifest); err != nil && p.Err == nil {
+		// The result is written but would be redone on resume.
+		p.Err = fmt.Errorf("stt: batch manifest: %w", err)
+	}
+	p.Stats = b.stats
+	return p
+}
+
+// retryable reports whether a failed transcription might succeed if
+// retried.
+func retryable(err error) bool {
+	return Retryable(err) && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrPermission)
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// writeJSON writes v to path atomically, so a crash never leaves a
+// truncated file.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// Cleans up after a failure; a renamed file is already gone.
+	defer os.Remove(f.Name())
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
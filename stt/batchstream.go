@@ -0,0 +1,280 @@
+package stt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// BatchStreamConfig configures the batch-to-stream adapter.
+type BatchStreamConfig struct {
+	// Window is the maximum audio duration sent in a single batch request.
+	// Defaults to 5 seconds.
+	Window time.Duration
+
+	// SilenceThreshold is the RMS level (0.0-1.0) below which audio is
+	// treated as silence. Defaults to 0.02.
+	SilenceThreshold float64
+
+	// SilenceDuration is how long silence must last to end an utterance
+	// and flush the current window early. Defaults to 500ms.
+	SilenceDuration time.Duration
+
+	// MaxPendingWindows is the number of windows that may wait for
+	// transcription before Write blocks. Defaults to 2.
+	MaxPendingWindows int
+}
+
+func (c BatchStreamConfig) withDefaults() BatchStreamConfig {
+	if c.Window <= 0 {
+		c.Window = 5 * time.Second
+	}
+	if c.SilenceThreshold <= 0 {
+		c.SilenceThreshold = 0.02
+	}
+	if c.SilenceDuration <= 0 {
+		c.SilenceDuration = 500 * time.Millisecond
+	}
+	if c.MaxPendingWindows <= 0 {
+		c.MaxPendingWindows = 2
+	}
+	return c
+}
+
+// StreamFromBatch adapts a batch-only Provider into a StreamingProvider.
+//
+// Incoming PCM audio is split into windows at utterance boundaries (detected
+// by an energy VAD) or when a window reaches config.Window, and each window
+// containing speech is sent to provider.Transcribe. Every window produces a
+// final EventTranscript; the VAD also emits EventSpeechStart and
+// EventSpeechEnd. Write blocks when MaxPendingWindows windows are waiting,
+// applying backpressure to the audio source instead of buffering without
+// bound.
+//
+// Only linear 16-bit PCM ("pcm" or empty Encoding) is supported;
+// TranscribeStream returns ErrUnsupportedFormat for other encodings.
+func StreamFromBatch(provider Provider, config BatchStreamConfig) StreamingProvider {
+	return &batchStreamer{Provider: provider, config: config.withDefaults()}
+}
+
+type batchStreamer struct {
+	Provider
+	config BatchStreamConfig
+}
+
+type batchWindow struct {
+	audio       []byte
+	offset      time.Duration
+	speechStart bool
+	speechEnd   bool
+}
+
+// TranscribeStream implements StreamingProvider.
+func (b *batchStreamer) TranscribeStream(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, error) {
+	if config.Encoding != "" && config.Encoding != "pcm" {
+		return nil, nil, ErrUnsupportedFormat
+	}
+	if config.SampleRate <= 0 {
+		return nil, nil, ErrInvalidConfig
+	}
+
+	vad := audio.NewVAD(config.SampleRate)
+	vad.Threshold = b.config.SilenceThreshold
+	vad.Hangover = b.config.SilenceDuration
+
+	bps := audio.BytesPerSecond(config.SampleRate, config.Channels)
+	frameAlign := audio.BytesPerSample * max(config.Channels, 1)
+	frameBytes := max(bps/50/frameAlign, 1) * frameAlign // ~20ms frames
+	w := &batchStreamWriter{
+		ctx:         ctx,
+		vad:         vad,
+		channels:    max(config.Channels, 1),
+		bps:         bps,
+		frameBytes:  frameBytes,
+		windowBytes: int(int64(bps) * int64(b.config.Window) / int64(time.Second)),
+		windows:     make(chan batchWindow, b.config.MaxPendingWindows),
+	}
+
+	events := make(chan StreamEvent)
+	go b.run(ctx, config, w.windows, events)
+	return w, events, nil
+}
+
+func (b *batchStreamer) run(ctx context.Context, config TranscriptionConfig, windows <-chan batchWindow, events chan<- StreamEvent) {
+	defer close(events)
+	emit := func(ev StreamEvent) bool {
+		return sendEvent(ctx, events, ev) && ctx.Err() == nil
+	}
+
+	for win := range windows {
+		if win.speechStart && !emit(StreamEvent{Type: EventSpeechStart, SpeechStarted: true}) {
+			return
+		}
+		if len(win.audio) > 0 {
+			result, err := b.Transcribe(ctx, win.audio, config)
+			switch {
+			case err != nil && result != nil && result.Partial && result.Text != "":
+				// Flush what the provider managed before ctx ended.
+				seg := windowSegment(result, win.offset)
+				sendEvent(ctx, events, StreamEvent{Type: EventTranscript, Transcript: result.Text, IsFinal: true, Partial: true, Segment: &seg})
+				return
+			case errors.Is(err, ErrAudioTooShort) && ctx.Err() == nil:
+				// A blip too short to transcribe holds no words, like a
+				// window without speech.
+			case err != nil:
+				if !emit(StreamEvent{Type: EventError, Error: err}) {
+					return
+				}
+			case result.Text != "":
+				seg := windowSegment(result, win.offset)
+				if !emit(StreamEvent{Type: EventTranscript, Transcript: result.Text, IsFinal: true, Segment: &seg}) {
+					return
+				}
+			}
+		}
+		if win.speechEnd && !emit(StreamEvent{Type: EventSpeechEnd, SpeechEnded: true}) {
+			return
+		}
+	}
+}
+
+// windowSegment collapses a window's result into a single segment with
+// timings shifted to be relative to the start of the stream.
+func windowSegment(result *TranscriptionResult, offset time.Duration) Segment {
+	seg := Segment{
+		Text:      result.Text,
+		StartTime: offset,
+		EndTime:   offset + result.Duration,
+		Language:  result.Language,
+	}
+	var confidence float64
+	for i, s := range result.Segments {
+		if i == 0 {
+			seg.StartTime = offset + s.StartTime
+			seg.Speaker = s.Speaker
+		}
+		seg.EndTime = offset + s.EndTime
+		confidence += s.Confidence
+		for _, w := range s.Words {
+			w.StartTime += offset
+			w.EndTime += offset
+			seg.Words = append(seg.Words, w)
+		}
+	}
+	if n := len(result.Segments); n > 0 {
+		seg.Confidence = confidence / float64(n)
+	}
+	return seg
+}
+
+type batchStreamWriter struct {
+	mu          sync.Mutex
+	ctx         context.Context
+	vad         *audio.VAD
+	channels    int
+	bps         int
+	frameBytes  int
+	windowBytes int
+	windows     chan batchWindow
+
+	pending     []byte
+	buf         []byte
+	offset      time.Duration
+	written     int64
+	hasSpeech   bool
+	speechStart bool
+	closed      bool
+}
+
+// Write buffers audio, blocking while the transcription queue is full.
+func (w *batchStreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrStreamClosed
+	}
+
+	w.pending = append(w.pending, p...)
+	for len(w.pending) >= w.frameBytes {
+		frame := w.pending[:w.frameBytes]
+		w.pending = w.pending[w.frameBytes:]
+		if err := w.processFrame(frame); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *batchStreamWriter) processFrame(frame []byte) error {
+	if len(w.buf) == 0 {
+		w.offset = time.Duration(w.written) * time.Second / time.Duration(w.bps)
+	}
+	w.written += int64(len(frame))
+	w.buf = append(w.buf, frame...)
+
+	switch w.vad.Process(monoSamples(frame, w.channels)) {
+	case audio.VADSpeechStart:
+		w.hasSpeech = true
+		w.speechStart = true
+	case audio.VADSpeechEnd:
+		return w.flush(true)
+	}
+	if w.vad.Speaking() {
+		w.hasSpeech = true
+	}
+	if len(w.buf) >= w.windowBytes {
+		return w.flush(false)
+	}
+	return nil
+}
+
+func (w *batchStreamWriter) flush(speechEnd bool) error {
+	win := batchWindow{offset: w.offset, speechStart: w.speechStart, speechEnd: speechEnd}
+	if w.hasSpeech {
+		win.audio = w.buf
+	}
+	w.buf, w.hasSpeech, w.speechStart = nil, false, false
+	if win.audio == nil && !win.speechStart && !win.speechEnd {
+		return nil
+	}
+	select {
+	case w.windows <- win:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
+
+// Close flushes buffered audio and ends the stream.
+func (w *batchStreamWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer close(w.windows)
+	w.buf = append(w.buf, w.pending...)
+	w.pending = nil
+	if len(w.buf) == 0 && !w.vad.Speaking() {
+		return nil
+	}
+	return w.flush(w.vad.Speaking())
+}
+
+// monoSamples decodes a PCM frame, keeping only the first channel.
+func monoSamples(frame []byte, channels int) []int16 {
+	samples := audio.BytesToInt16(frame)
+	if channels <= 1 {
+		return samples
+	}
+	mono := make([]int16, 0, len(samples)/channels)
+	for i := 0; i+channels <= len(samples); i += channels {
+		mono = append(mono, samples[i])
+	}
+	return mono
+}
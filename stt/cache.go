@@ -0,0 +1,177 @@
+package stt
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/credentials"
+)
+
+// Cache stores batch transcription results for Client.Transcribe, keyed by
+// a hash of the audio and the TranscriptionConfig. Implementations must be
+// safe for concurrent use and may evict entries at any time.
+type Cache interface {
+	// Get returns the unexpired result stored under key.
+	Get(key string) (*TranscriptionResult, bool)
+
+	// Set stores a result under key. A ttl of zero means no expiry.
+	Set(key string, result *TranscriptionResult, ttl time.Duration)
+}
+
+// SetCache makes Transcribe return cached results for audio it has already
+// transcribed with the same config, and store complete results for ttl
+// (zero for no expiry). Partial results and errors are never cached, and
+// TranscribeStream is not affected. A nil cache disables caching.
+func (c *Client) SetCache(cache Cache, ttl time.Duration) {
+	c.cache, c.cacheTTL = cache, ttl
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache returns a context whose Transcribe calls skip the cache
+// lookup, e.g. after a provider model change. The fresh result still
+// replaces the cached one.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// cacheKey hashes the audio together with every config field that can
+// change the transcript. The tenant is included so results are never
+// shared between tenants. SHA-256 is hardware accelerated on common CPUs
+// and keeps keys stable across processes, for caches shared between them.
+func cacheKey(ctx context.Context, audio []byte, config TranscriptionConfig) string {
+	h := sha256.New()
+	num := func(v int) {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(v)) // #nosec G115 -- hashed bit pattern
+		h.Write(n[:])
+	}
+	field := func(s string) {
+		num(len(s))
+		h.Write([]byte(s))
+	}
+	flag := func(b bool) {
+		if b {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+	}
+
+	field(credentials.TenantFrom(ctx))
+	field(config.Language)
+	field(config.Model)
+	num(config.SampleRate)
+	num(config.Channels)
+	field(config.Encoding)
+	flag(config.EnablePunctuation)
+	flag(config.EnableWordTimestamps)
+	flag(config.EstimateWordTimestamps)
+	flag(config.EnableSpeakerDiarization)
+	num(config.MaxSpeakers)
+	num(len(config.Keywords))
+	for _, k := range config.Keywords {
+		field(k)
+	}
+	field(config.VocabularyID)
+	// fmt prints maps in key order.
+	field(fmt.Sprint(config.ProviderOptions))
+	num(len(audio))
+	h.Write(audio)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cloneResult deep-copies a result so callers can't modify cached data.
+func cloneResult(r *TranscriptionResult) *TranscriptionResult {
+	out := *r
+	out.Segments = slices.Clone(r.Segments)
+	for i := range out.Segments {
+		out.Segments[i].Words = slices.Clone(out.Segments[i].Words)
+	}
+	return &out
+}
+
+// LRUCache is an in-memory Cache holding at most a fixed number of
+// results, evicting the least recently used.
+type LRUCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	result  *TranscriptionResult
+	expires time.Time
+}
+
+// NewLRUCache creates a cache of up to size results (at least 1).
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    max(size, 1),
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the result stored under key unless it has expired.
+func (c *LRUCache) Get(key string) (*TranscriptionResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.result, true
+}
+
+// Set stores a result under key, evicting the least recently used entry
+// if the cache is full.
+func (c *LRUCache) Set(key string, result *TranscriptionResult, ttl time.Duration) {
+	e := &lruEntry{key: key, result: result}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of stored results, including expired ones not
+// yet evicted.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
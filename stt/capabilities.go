@@ -0,0 +1,149 @@
+package stt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Capabilities describes what a provider supports.
+type Capabilities struct {
+	// Streaming reports real-time streaming (StreamingProvider).
+	Streaming bool
+
+	// Diarization reports speaker diarization.
+	Diarization bool
+
+	// WordTimestamps reports word-level timestamps.
+	WordTimestamps bool
+
+	// Punctuation reports automatic punctuation.
+	Punctuation bool
+
+	// Languages are the supported BCP-47 codes. A bare language ("en")
+	// covers all its regions. Empty means any language.
+	Languages []string
+
+	// Encodings are the supported audio encodings. Empty means any.
+	Encodings []string
+
+	// MaxAudioDuration is the longest audio accepted in one batch
+	// request; 0 means no limit.
+	MaxAudioDuration time.Duration
+
+	// MinAudioDuration is the shortest audio accepted in one batch
+	// request; shorter audio fails with ErrAudioTooShort. 0 means no
+	// minimum.
+	MinAudioDuration time.Duration
+}
+
+// CapabilityProvider is implemented by providers that describe their
+// capabilities. The client skips such providers for requests they cannot
+// serve; providers without it are always tried.
+type CapabilityProvider interface {
+	Provider
+
+	// Capabilities returns the provider's capabilities.
+	Capabilities() Capabilities
+}
+
+// Check reports whether the capabilities cover config, returning an error
+// wrapping ErrUnsupportedFeature, ErrUnsupportedLanguage, or
+// ErrUnsupportedFormat if not.
+func (c Capabilities) Check(config TranscriptionConfig) error {
+	switch {
+	case config.EnableSpeakerDiarization && !c.Diarization:
+		return fmt.Errorf("%w: speaker diarization", ErrUnsupportedFeature)
+	case config.EnableWordTimestamps && !c.WordTimestamps && !config.EstimateWordTimestamps:
+		return fmt.Errorf("%w: word timestamps", ErrUnsupportedFeature)
+	case !supportsLanguage(c.Languages, config.Language):
+		return fmt.Errorf("%w: %s", ErrUnsupportedLanguage, config.Language)
+	case config.Encoding != "" && len(c.Encodings) > 0 && !slices.Contains(c.Encodings, config.Encoding):
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, config.Encoding)
+	}
+	return nil
+}
+
+// checkAudio is Check plus MaxAudioDuration and MinAudioDuration for a
+// batch request. Only PCM audio has a duration known without decoding.
+func (c Capabilities) checkAudio(audio []byte, config TranscriptionConfig) error {
+	if err := c.Check(config); err != nil {
+		return err
+	}
+	if audio == nil || config.SampleRate <= 0 || (config.Encoding != "" && config.Encoding != "pcm") {
+		return nil
+	}
+	bytesPerSecond := 2 * max(config.Channels, 1) * config.SampleRate
+	d := time.Duration(len(audio)) * time.Second / time.Duration(bytesPerSecond)
+	switch {
+	case c.MaxAudioDuration > 0 && d > c.MaxAudioDuration:
+		return fmt.Errorf("%w: %v exceeds %v", ErrAudioTooLong, d, c.MaxAudioDuration)
+	case c.MinAudioDuration > 0 && d < c.MinAudioDuration:
+		return fmt.Errorf("%w: %v is under %v", ErrAudioTooShort, d, c.MinAudioDuration)
+	}
+	return nil
+}
+
+// supportsLanguage reports whether lang is in langs, matching a bare
+// language against any of its regions. Empty lang (auto-detect) and empty
+// langs match anything.
+func supportsLanguage(langs []string, lang string) bool {
+	if lang == "" || len(langs) == 0 {
+		return true
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	for _, l := range langs {
+		lb, _, _ := strings.Cut(l, "-")
+		if strings.EqualFold(l, lang) || strings.EqualFold(l, base) || (base == lang && strings.EqualFold(lb, base)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns the capabilities of each provider implementing
+// CapabilityProvider, by provider name.
+func (c *Client) Capabilities() map[string]Capabilities {
+	caps := make(map[string]Capabilities)
+	for name, p := range c.providers {
+		if cp, ok := p.(CapabilityProvider); ok {
+			caps[name] = cp.Capabilities()
+		}
+	}
+	return caps
+}
+
+// skips records providers passed over because their capabilities do not
+// cover a request.
+type skips struct {
+	errs    []error
+	capable bool
+}
+
+// check reports whether p can serve config, recording why not. audio
+// is nil for streams. Providers that do not describe their capabilities qualify.
+func (s *skips) check(p Provider, audio []byte, config TranscriptionConfig) bool {
+	if cp, ok := p.(CapabilityProvider); ok {
+		if err := cp.Capabilities().checkAudio(audio, config); err != nil {
+			s.errs = append(s.errs, fmt.Errorf("%w for %s", err, p.Name()))
+			return false
+		}
+	}
+	s.capable = true
+	return true
+}
+
+// wrap returns err for a request no provider took. If providers were
+// skipped and none qualified, it is ErrNoCapableProvider wrapping each
+// provider's reason instead.
+func (s *skips) wrap(err error) error {
+	if s.capable || len(s.errs) == 0 {
+		return err
+	}
+	args := []any{ErrNoCapableProvider}
+	for _, e := range s.errs {
+		args = append(args, e)
+	}
+	return fmt.Errorf("%w: "+strings.Repeat("%w; ", len(s.errs)-1)+"%w", args...)
+}
@@ -0,0 +1,102 @@
+package stt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a batch and streaming provider that transcribes any
+// audio as its own name, after delay.
+type fakeProvider struct {
+	name  string
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Transcribe(ctx context.Context, audio []byte, config TranscriptionConfig) (*TranscriptionResult, error) {
+	p.calls.Add(1)
+	select {
+	case <-time.After(p.delay):
+		return &TranscriptionResult{Text: p.name}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *fakeProvider) TranscribeFile(ctx context.Context, path string, config TranscriptionConfig) (*TranscriptionResult, error) {
+	return nil, ErrUnsupportedFormat
+}
+
+func (p *fakeProvider) TranscribeURL(ctx context.Context, url string, config TranscriptionConfig) (*TranscriptionResult, error) {
+	return nil, ErrUnsupportedFormat
+}
+
+func (p *fakeProvider) TranscribeStream(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, error) {
+	p.calls.Add(1)
+	events := make(chan StreamEvent)
+	close(events)
+	return &nopWriteCloser{}, events, nil
+}
+
+// capableProvider is a fakeProvider describing its capabilities.
+type capableProvider struct {
+	fakeProvider
+	caps Capabilities
+}
+
+func (p *capableProvider) Capabilities() Capabilities { return p.caps }
+
+func TestTranscribeRoutesToCapableFallback(t *testing.T) {
+	primary := &capableProvider{fakeProvider: fakeProvider{name: "primary"}, caps: Capabilities{Languages: []string{"en"}}}
+	fallback := &capableProvider{fakeProvider: fakeProvider{name: "fallback"}, caps: Capabilities{Languages: []string{"en", "es"}, Diarization: true}}
+	c := NewClient(primary, fallback)
+
+	for _, config := range []TranscriptionConfig{
+		{Language: "es-MX"},
+		{Language: "en", EnableSpeakerDiarization: true},
+	} {
+		result, err := c.Transcribe(context.Background(), make([]byte, 320), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Text != "fallback" {
+			t.Errorf("%+v transcribed by %q, want fallback", config, result.Text)
+		}
+	}
+	if n := primary.calls.Load(); n != 0 {
+		t.Errorf("incapable primary called %d times", n)
+	}
+}
+
+func TestTranscribeStreamRoutesToCapableFallback(t *testing.T) {
+	primary := &capableProvider{fakeProvider: fakeProvider{name: "primary"}, caps: Capabilities{Streaming: true, Encodings: []string{"pcm"}}}
+	fallback := &capableProvider{fakeProvider: fakeProvider{name: "fallback"}, caps: Capabilities{Streaming: true, Encodings: []string{"pcm", "mulaw"}}}
+	c := NewClient(primary, fallback)
+
+	if _, _, err := c.TranscribeStream(context.Background(), TranscriptionConfig{Encoding: "mulaw", SampleRate: 8000}); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls.Load() != 0 || fallback.calls.Load() != 1 {
+		t.Errorf("streams opened: primary %d, fallback %d; want only the fallback", primary.calls.Load(), fallback.calls.Load())
+	}
+}
+
+func TestTranscribeNoCapableProvider(t *testing.T) {
+	primary := &capableProvider{fakeProvider: fakeProvider{name: "primary"}, caps: Capabilities{Languages: []string{"en"}}}
+	fallback := &capableProvider{fakeProvider: fakeProvider{name: "fallback"}, caps: Capabilities{Languages: []string{"fr"}}}
+	c := NewClient(primary, fallback)
+
+	_, err := c.Transcribe(context.Background(), make([]byte, 320), TranscriptionConfig{Language: "ja"})
+	if !errors.Is(err, ErrNoCapableProvider) || !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Fatalf("got %v, want ErrNoCapableProvider wrapping ErrUnsupportedLanguage", err)
+	}
+	if primary.calls.Load()+fallback.calls.Load() != 0 {
+		t.Error("incapable providers were called")
+	}
+}
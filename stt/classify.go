@@ -0,0 +1,92 @@
+package stt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/agentplexus/omnivoice/internal/fallback"
+)
+
+// ProviderError is a provider failure classified by Kind, one of
+// ErrUnauthorized, ErrBadRequest, ErrRateLimited, ErrQuotaExceeded,
+// ErrServerError, ErrTimeout, and ErrNetworkError, so callers can tell
+// failures that retrying may fix from those it will not. Providers should
+// return their HTTP and network failures as ProviderErrors, built with
+// NewProviderError; the Client classifies the others as best it can.
+//
+// errors.Is matches both Kind and the wrapped error:
+//
+//	if errors.Is(err, stt.ErrUnauthorized) { ... }
+//
+//	var pe *stt.ProviderError
+//	if errors.As(err, &pe) { log.Print(pe.Provider, pe.StatusCode) }
+type ProviderError struct {
+	// Provider is the provider's name.
+	Provider string
+
+	// StatusCode is the HTTP status of the failure, or 0 if there was no
+	// response.
+	StatusCode int
+
+	// Kind classifies the failure; nil if it could not be classified.
+	Kind error
+
+	// Err is the original error, if any.
+	Err error
+}
+
+// NewProviderError classifies a provider's failure by its HTTP status if
+// statusCode is an error status, and otherwise by err: deadlines and
+// network timeouts are ErrTimeout, and other network failures
+// ErrNetworkError. An err already classified is returned unchanged.
+func NewProviderError(provider string, statusCode int, err error) error {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &ProviderError{Provider: provider, StatusCode: statusCode, Kind: kinds.Classify(statusCode, err), Err: err}
+}
+
+func (e *ProviderError) Error() string {
+	return fallback.ErrorString("stt", e.Provider, e.StatusCode, e.Kind, e.Err)
+}
+
+// Unwrap returns Kind and Err, for errors.Is and errors.As.
+func (e *ProviderError) Unwrap() []error {
+	return fallback.Unwrap(e.Kind, e.Err)
+}
+
+// Retryable reports whether a failed request might succeed if retried
+// later: true if any failure it wraps is transient (ErrServerError,
+// ErrTimeout, ErrNetworkError, or ErrRateLimited), and otherwise false
+// for ErrUnauthorized, ErrBadRequest, ErrQuotaExceeded, and requests no
+// provider could serve.
+func Retryable(err error) bool {
+	return kinds.Retryable(err,
+		ErrNoCapableProvider, ErrInvalidConfig, ErrUnsupportedFormat,
+		ErrInvalidAudio, ErrAudioTooLong, ErrAudioTooShort, ErrUnsupportedLanguage, ErrUnsupportedFeature)
+}
+
+// kinds are the errors provider failures are classified by.
+var kinds = fallback.Kinds{
+	Unauthorized:  ErrUnauthorized,
+	BadRequest:    ErrBadRequest,
+	RateLimited:   ErrRateLimited,
+	QuotaExceeded: ErrQuotaExceeded,
+	ServerError:   ErrServerError,
+	Timeout:       ErrTimeout,
+	NetworkError:  ErrNetworkError,
+}
+
+// failure returns a provider attempt's error classified and naming the
+// provider, for the error of a request no provider completed.
+func failure(provider string, err error) error {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		if pe.Provider == provider {
+			return err
+		}
+		return fmt.Errorf("%s: %w", provider, err)
+	}
+	return NewProviderError(provider, 0, err)
+}
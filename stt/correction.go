@@ -0,0 +1,106 @@
+package stt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Correction describes how a transcript differs from the previous
+// transcript of the same utterance: Length bytes at Offset were replaced
+// by Text. Appending words has Length zero; a provider revising earlier
+// words has Length above zero.
+type Correction struct {
+	// Offset is the byte offset of the change in the previous transcript.
+	Offset int
+
+	// Length is the number of bytes replaced.
+	Length int
+
+	// Text is the replacement text.
+	Text string
+}
+
+// Revises reports whether the correction changes previously emitted text,
+// rather than only appending to it.
+func (c Correction) Revises() bool {
+	return c.Length > 0
+}
+
+// Apply returns prev with the correction applied. An offset or length out
+// of range for prev is clamped.
+func (c Correction) Apply(prev string) string {
+	start := min(max(c.Offset, 0), len(prev))
+	end := min(start+max(c.Length, 0), len(prev))
+	return prev[:start] + c.Text + prev[end:]
+}
+
+// DiffTranscript returns the correction turning prev into next. The
+// changed span starts at the first differing character and runs to the end.
+func DiffTranscript(prev, next string) Correction {
+	n := 0
+	for n < len(prev) && n < len(next) && prev[n] == next[n] {
+		n++
+	}
+	// Don't split a multi-byte character.
+	for n > 0 && ((n < len(prev) && !utf8.RuneStart(prev[n])) || (n < len(next) && !utf8.RuneStart(next[n]))) {
+		n--
+	}
+	return Correction{Offset: n, Length: len(prev) - n, Text: next[n:]}
+}
+
+// TranscriptBuilder maintains the current transcript of a stream from its
+// corrections: the final text of completed utterances and the interim text
+// of the one in progress. Caption renderers can apply the same corrections
+// to update text in place. It is not safe for concurrent use.
+type TranscriptBuilder struct {
+	finals  []string
+	interim string
+}
+
+// Apply updates the interim text with a correction, completing the
+// utterance if final is set.
+func (b *TranscriptBuilder) Apply(c Correction, final bool) {
+	b.interim = c.Apply(b.interim)
+	if final {
+		if text := strings.TrimSpace(b.interim); text != "" {
+			b.finals = append(b.finals, text)
+		}
+		b.interim = ""
+	}
+}
+
+// ApplyEvent updates the transcript from a stream event. Events without
+// a Correction replace the interim text.
+func (b *TranscriptBuilder) ApplyEvent(ev StreamEvent) {
+	if ev.Type != EventTranscript {
+		return
+	}
+	c := Correction{Length: len(b.interim), Text: ev.Transcript}
+	if ev.Correction != nil {
+		c = *ev.Correction
+	}
+	b.Apply(c, ev.IsFinal)
+}
+
+// Text returns the final text followed by the interim text.
+func (b *TranscriptBuilder) Text() string {
+	if b.interim == "" {
+		return b.Final()
+	}
+	return strings.TrimSpace(b.Final() + " " + b.interim)
+}
+
+// Final returns the text of completed utterances.
+func (b *TranscriptBuilder) Final() string {
+	return strings.Join(b.finals, " ")
+}
+
+// Interim returns the text of the utterance in progress.
+func (b *TranscriptBuilder) Interim() string {
+	return b.interim
+}
+
+// Reset clears the transcript.
+func (b *TranscriptBuilder) Reset() {
+	b.finals, b.interim = nil, ""
+}
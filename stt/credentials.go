@@ -0,0 +1,34 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/agentplexus/omnivoice/credentials"
+)
+
+// SetCredentials makes the client resolve credentials before each provider
+// attempt and pass them to the provider in the request context (see
+// credentials.FromContext). Providers whose credentials cannot be resolved
+// are skipped. A nil resolver leaves providers on their own keys.
+func (c *Client) SetCredentials(r credentials.Resolver) {
+	c.credentials = r
+}
+
+// withCredentials returns ctx carrying the named provider's credentials.
+// Credentials already in ctx are replaced, so a request never uses another
+// tenant's.
+func (c *Client) withCredentials(ctx context.Context, provider string) (context.Context, error) {
+	ctx, err := credentials.Resolve(ctx, c.credentials, provider)
+	if err != nil {
+		return nil, fmt.Errorf("%w for %s: %w", ErrCredentials, provider, err)
+	}
+	return ctx, nil
+}
+
+// unavailable builds the error for a request no provider completed,
+// noting providers skipped for rate limits, credentials, or capabilities,
+// and wrapping the failures of those tried.
+func unavailable(err error, limited bool, causes ...error) error {
+	return kinds.Unavailable(err, limited, causes...)
+}
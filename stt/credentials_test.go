@@ -0,0 +1,66 @@
+package stt
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentplexus/omnivoice/credentials"
+)
+
+// keyProvider transcribes to the API key it was given, or "default" when
+// the request carries none.
+type keyProvider struct {
+	fakeProvider
+}
+
+func (p *keyProvider) Transcribe(ctx context.Context, audio []byte, config TranscriptionConfig) (*TranscriptionResult, error) {
+	p.calls.Add(1)
+	if c, ok := credentials.FromContext(ctx); ok {
+		return &TranscriptionResult{Text: c.APIKey}, nil
+	}
+	return &TranscriptionResult{Text: "default"}, nil
+}
+
+func TestCredentialsPerTenant(t *testing.T) {
+	store := credentials.NewStore()
+	store.Set("a", "primary", credentials.Credentials{APIKey: "a-key"})
+	store.Set("b", "primary", credentials.Credentials{APIKey: "b-key"})
+	c := NewClient(&keyProvider{fakeProvider{name: "primary"}})
+	c.SetCredentials(store)
+
+	for _, tenant := range []string{"a", "b", "a"} {
+		ctx := credentials.WithTenant(context.Background(), tenant)
+		// A key already in the context is never used over the resolver's.
+		ctx = credentials.NewContext(ctx, credentials.Credentials{APIKey: "stale"})
+		result, err := c.Transcribe(ctx, make([]byte, 320), TranscriptionConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Text != tenant+"-key" {
+			t.Errorf("tenant %s transcribed with %q", tenant, result.Text)
+		}
+	}
+}
+
+func TestCredentialsSkipUnresolvedProvider(t *testing.T) {
+	store := credentials.NewStore()
+	store.Set("a", "fallback", credentials.Credentials{APIKey: "fallback-key"})
+	primary := &keyProvider{fakeProvider{name: "primary"}}
+	c := NewClient(primary, &keyProvider{fakeProvider{name: "fallback"}})
+	c.SetCredentials(store)
+
+	ctx := credentials.WithTenant(context.Background(), "a")
+	result, err := c.Transcribe(ctx, make([]byte, 320), TranscriptionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "fallback-key" || primary.calls.Load() != 0 {
+		t.Errorf("got %q with %d primary calls, want the fallback's key and none", result.Text, primary.calls.Load())
+	}
+
+	store.Delete("a", "fallback")
+	if _, err := c.Transcribe(ctx, make([]byte, 321), TranscriptionConfig{}); !errors.Is(err, ErrCredentials) {
+		t.Errorf("no credentials at all: %v, want ErrCredentials", err)
+	}
+}
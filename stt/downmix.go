@@ -0,0 +1,110 @@
+package stt
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// DownmixMode selects how multichannel input is converted to mono. See
+// TranscriptionConfig.Downmix.
+type DownmixMode string
+
+const (
+	// DownmixMix averages the channels.
+	DownmixMix DownmixMode = "mix"
+
+	// DownmixLeft keeps the first channel.
+	DownmixLeft DownmixMode = "left"
+
+	// DownmixRight keeps the second channel.
+	DownmixRight DownmixMode = "right"
+)
+
+// channel returns the channel to keep, or -1 to average them.
+func (m DownmixMode) channel() int {
+	switch m {
+	case DownmixLeft:
+		return 0
+	case DownmixRight:
+		return 1
+	}
+	return -1
+}
+
+// downmixes reports whether config describes multichannel PCM input for
+// a mono request.
+func downmixes(config TranscriptionConfig) bool {
+	return config.Channels == 1 && config.InputChannels > 1 &&
+		(config.Encoding == "" || config.Encoding == "pcm")
+}
+
+// withWAVFormat returns config for the PCM data of a WAV recording in
+// format, keeping a requested mono Channels so that stereo recordings are
+// downmixed.
+func withWAVFormat(config TranscriptionConfig, format audio.Format) TranscriptionConfig {
+	config.SampleRate, config.Encoding, config.InputChannels = format.SampleRate, "pcm", 0
+	if config.Channels == 1 && format.Channels > 1 {
+		config.InputChannels = format.Channels
+	} else {
+		config.Channels = format.Channels
+	}
+	return config
+}
+
+// downmixAudio returns batch audio and its config converted to the mono
+// the config requests: multichannel PCM per InputChannels, or a WAV
+// recording whose header shows more than one channel. Other audio is
+// returned as is.
+func downmixAudio(data []byte, config TranscriptionConfig) ([]byte, TranscriptionConfig) {
+	if config.Channels == 1 && config.Encoding == "wav" && bytes.HasPrefix(data, []byte("RIFF")) {
+		if pcm, format, err := audio.ReadWAV(bytes.NewReader(data)); err == nil && format.Channels > 1 {
+			data, config = pcm, withWAVFormat(config, format)
+		}
+	}
+	if !downmixes(config) {
+		return data, config
+	}
+	data = audio.Downmix(data, config.InputChannels, config.Downmix.channel())
+	config.InputChannels = 0
+	return data, config
+}
+
+// downmixStream wraps a stream writer so written multichannel audio is
+// downmixed as config requests.
+func downmixStream(w io.WriteCloser, config TranscriptionConfig) io.WriteCloser {
+	if w == nil || !downmixes(config) {
+		return w
+	}
+	return &downmixWriter{w: w, channels: config.InputChannels, channel: config.Downmix.channel()}
+}
+
+// downmixWriter downmixes each write, carrying a trailing partial frame
+// over to the next.
+type downmixWriter struct {
+	w        io.WriteCloser
+	channels int
+	channel  int
+	pending  []byte
+}
+
+func (dw *downmixWriter) Write(b []byte) (int, error) {
+	buf := append(dw.pending, b...)
+	frame := audio.BytesPerSample * dw.channels
+	n := len(buf) - len(buf)%frame
+	dw.pending = append([]byte(nil), buf[n:]...)
+	if n == 0 {
+		return len(b), nil
+	}
+	if _, err := dw.w.Write(audio.Downmix(buf[:n], dw.channels, dw.channel)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the underlying writer; a trailing partial frame is
+// dropped.
+func (dw *downmixWriter) Close() error {
+	return dw.w.Close()
+}
@@ -0,0 +1,158 @@
+package stt
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// DryRunConfig configures a client's dry-run mode. See Client.SetDryRun.
+type DryRunConfig struct {
+	// Transcript is the text of every stub result. Defaults to
+	// "dry run transcript".
+	Transcript string
+
+	// Errors makes the named providers fail with the given error, to
+	// exercise fallback.
+	Errors map[string]error
+
+	// Result, if set, builds each batch result instead of Transcript.
+	// audio is nil for TranscribeURL.
+	Result func(provider string, audio []byte, config TranscriptionConfig) *TranscriptionResult
+}
+
+// SetDryRun puts the client in dry-run mode: requests go through provider
+// selection, capabilities, credentials, rate limits, caching, and
+// preprocessing as usual, but the chosen provider is never called and
+// returns a deterministic stub result instead. Streams emit one final
+// transcript when closed. A nil config turns dry-run mode off.
+func (c *Client) SetDryRun(config *DryRunConfig) {
+	if config == nil {
+		c.dryRun = nil
+		return
+	}
+	cfg := *config
+	if cfg.Transcript == "" {
+		cfg.Transcript = "dry run transcript"
+	}
+	c.dryRun = &cfg
+}
+
+// call returns the provider to send a request to: p, or its stub in
+// dry-run mode.
+func (c *Client) call(p Provider) Provider {
+	if c.dryRun == nil {
+		return p
+	}
+	return &dryRunProvider{name: p.Name(), config: c.dryRun}
+}
+
+// callStream is call for streaming providers.
+func (c *Client) callStream(sp StreamingProvider) StreamingProvider {
+	if c.dryRun == nil {
+		return sp
+	}
+	return &dryRunProvider{name: sp.Name(), config: c.dryRun}
+}
+
+// dryRunProvider stands in for a provider in dry-run mode.
+type dryRunProvider struct {
+	name   string
+	config *DryRunConfig
+}
+
+func (d *dryRunProvider) Name() string { return d.name }
+
+func (d *dryRunProvider) Transcribe(ctx context.Context, data []byte, config TranscriptionConfig) (*TranscriptionResult, error) {
+	if err := d.config.Errors[d.name]; err != nil {
+		return nil, err
+	}
+	if d.config.Result != nil {
+		return d.config.Result(d.name, data, config), nil
+	}
+	return d.result(pcmLength(len(data), config), config), nil
+}
+
+func (d *dryRunProvider) TranscribeFile(ctx context.Context, filePath string, config TranscriptionConfig) (*TranscriptionResult, error) {
+	return d.Transcribe(ctx, nil, config)
+}
+
+func (d *dryRunProvider) TranscribeURL(ctx context.Context, url string, config TranscriptionConfig) (*TranscriptionResult, error) {
+	return d.Transcribe(ctx, nil, config)
+}
+
+func (d *dryRunProvider) TranscribeStream(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, error) {
+	if err := d.config.Errors[d.name]; err != nil {
+		return nil, nil, err
+	}
+	w := &dryRunWriter{closed: make(chan struct{})}
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		select {
+		case <-w.closed:
+		case <-ctx.Done():
+			return
+		}
+		w.mu.Lock()
+		n := w.n
+		w.mu.Unlock()
+		result := d.result(pcmLength(n, config), config)
+		sendEvent(ctx, events, StreamEvent{
+			Type:       EventTranscript,
+			Transcript: result.Text,
+			IsFinal:    true,
+			Segment:    &result.Segments[0],
+		})
+	}()
+	return w, events, nil
+}
+
+func (d *dryRunProvider) result(duration time.Duration, config TranscriptionConfig) *TranscriptionResult {
+	return &TranscriptionResult{
+		Text:     d.config.Transcript,
+		Segments: []Segment{{Text: d.config.Transcript, EndTime: duration, Confidence: 1, Language: config.Language}},
+		Language: config.Language,
+		Duration: duration,
+	}
+}
+
+// pcmLength returns the duration of n bytes of PCM audio, or zero for
+// other encodings.
+func pcmLength(n int, config TranscriptionConfig) time.Duration {
+	if (config.Encoding != "" && config.Encoding != "pcm") || config.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(audio.BytesPerSecond(config.SampleRate, config.Channels))
+}
+
+// dryRunWriter counts the audio of a dry-run stream.
+type dryRunWriter struct {
+	mu     sync.Mutex
+	n      int
+	done   bool
+	closed chan struct{}
+}
+
+func (w *dryRunWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return 0, ErrStreamClosed
+	}
+	w.n += len(p)
+	return len(p), nil
+}
+
+func (w *dryRunWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.done = true
+		close(w.closed)
+	}
+	return nil
+}
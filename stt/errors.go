@@ -6,6 +6,10 @@ var (
 	// ErrNoAvailableProvider is returned when no provider is available.
 	ErrNoAvailableProvider = errors.New("stt: no available provider")
 
+	// ErrNoCapableProvider is returned when no provider's capabilities
+	// cover the request. It wraps each provider's reason.
+	ErrNoCapableProvider = errors.New("stt: no provider supports the request")
+
 	// ErrStreamingNotSupported is returned when streaming is not supported.
 	ErrStreamingNotSupported = errors.New("stt: streaming not supported by any provider")
 
@@ -18,7 +22,10 @@ var (
 	// ErrAudioTooLong is returned when audio exceeds provider limits.
 	ErrAudioTooLong = errors.New("stt: audio too long")
 
-	// ErrAudioTooShort is returned when audio is too short to transcribe.
+	// ErrAudioTooShort is returned when audio is too short to transcribe,
+	// below the provider's minimum length (see
+	// Capabilities.MinAudioDuration). Audio long enough but without speech
+	// is not an error: it gives a result with NoSpeech set.
 	ErrAudioTooShort = errors.New("stt: audio too short")
 
 	// ErrRateLimited is returned when the provider rate limits the request.
@@ -27,12 +34,43 @@ var (
 	// ErrQuotaExceeded is returned when the provider quota is exceeded.
 	ErrQuotaExceeded = errors.New("stt: quota exceeded")
 
+	// ErrCredentials is wrapped in request errors when credentials could
+	// not be resolved for a provider.
+	ErrCredentials = errors.New("stt: credentials unavailable")
+
 	// ErrUnsupportedLanguage is returned when the language is not supported.
 	ErrUnsupportedLanguage = errors.New("stt: unsupported language")
 
+	// ErrUnsupportedFeature is returned when a requested feature, such as
+	// speaker diarization, is not supported.
+	ErrUnsupportedFeature = errors.New("stt: unsupported feature")
+
 	// ErrUnsupportedFormat is returned when the audio format is not supported.
 	ErrUnsupportedFormat = errors.New("stt: unsupported audio format")
 
+	// ErrVocabularyNotSupported is returned when a provider cannot manage
+	// custom vocabularies. See VocabularyManager.
+	ErrVocabularyNotSupported = errors.New("stt: custom vocabularies not supported")
+
 	// ErrStreamClosed is returned when attempting to use a closed stream.
 	ErrStreamClosed = errors.New("stt: stream closed")
+
+	// ErrUnauthorized classifies a provider rejecting its credentials or
+	// their permissions (HTTP 401 and 403). See ProviderError.
+	ErrUnauthorized = errors.New("stt: unauthorized")
+
+	// ErrBadRequest classifies a provider rejecting a request as malformed
+	// or unprocessable (other HTTP 4xx).
+	ErrBadRequest = errors.New("stt: bad request")
+
+	// ErrServerError classifies a provider failing internally (HTTP 5xx).
+	ErrServerError = errors.New("stt: provider server error")
+
+	// ErrTimeout classifies a provider request that timed out, as HTTP 408
+	// and 504 or a deadline.
+	ErrTimeout = errors.New("stt: provider timeout")
+
+	// ErrNetworkError classifies a provider request that failed to reach
+	// the provider or lost its connection.
+	ErrNetworkError = errors.New("stt: network error")
 )
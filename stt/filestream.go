@@ -0,0 +1,215 @@
+package stt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// FileStreamConfig configures TranscribeFileStream and
+// TranscribeURLStream.
+type FileStreamConfig struct {
+	// Transcription configures the stream. For WAV recordings
+	// SampleRate, Channels, and Encoding come from the header, as for
+	// BatchConfig.Transcription; for others an empty Encoding is taken
+	// from the file extension. Batch-only providers, adapted with
+	// EnableBatchStreaming, need PCM.
+	Transcription TranscriptionConfig
+
+	// ChunkSize is the number of bytes read and written to the stream at
+	// a time, bounding memory use. Defaults to 32 KiB.
+	ChunkSize int
+
+	// HTTPClient fetches URLs. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// StreamProgress is the Progress of an EventProgress.
+type StreamProgress struct {
+	// Bytes is the audio sent to the provider so far.
+	Bytes int64
+
+	// TotalBytes is the size of the audio, or 0 if it is not known, as
+	// for a URL served without a Content-Length.
+	TotalBytes int64
+
+	// Audio is the duration of the audio sent so far. It is only known
+	// for PCM recordings, and 0 for others.
+	Audio time.Duration
+}
+
+// Fraction returns the share of the audio sent so far, from 0 to 1, or 0
+// if TotalBytes is not known.
+func (p StreamProgress) Fraction() float64 {
+	if p.TotalBytes <= 0 {
+		return 0
+	}
+	return min(float64(p.Bytes)/float64(p.TotalBytes), 1)
+}
+
+// TranscribeFileStream transcribes a recording incrementally, reading it
+// in chunks into TranscribeStream rather than loading it whole, so long
+// recordings such as meetings get early transcripts and bounded memory
+// use. Events are as TranscribeStream's, with an EventProgress after each
+// chunk; the channel closes once the provider has finished the
+// recording. A read error is delivered as an EventError and ends the
+// recording there. The channel must be drained, or ctx canceled.
+func (c *Client) TranscribeFileStream(ctx context.Context, path string, config FileStreamConfig) (<-chan StreamEvent, error) {
+	f, err := os.Open(path) // #nosec G304 -- caller-supplied recording path
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+	events, err := c.transcribeReader(ctx, f, filepath.Ext(path), size, config)
+	if err != nil {
+		f.Close()
+	}
+	return events, err
+}
+
+// TranscribeURLStream is like TranscribeFileStream but reads the
+// recording from an http(s) URL.
+func (c *Client) TranscribeURLStream(ctx context.Context, url string, config FileStreamConfig) (<-chan StreamEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrNetworkError, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("stt: fetching %s: %s", url, resp.Status)
+	}
+	name, _, _ := strings.Cut(url[strings.LastIndex(url, "/")+1:], "?")
+	events, err := c.transcribeReader(ctx, resp.Body, filepath.Ext(name), max(resp.ContentLength, 0), config)
+	if err != nil {
+		resp.Body.Close()
+	}
+	return events, err
+}
+
+// transcribeReader streams the recording in r, of size bytes if known,
+// closing r when done.
+func (c *Client) transcribeReader(ctx context.Context, r io.ReadCloser, ext string, size int64, config FileStreamConfig) (<-chan StreamEvent, error) {
+	tc := config.Transcription
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if ext == "wav" {
+		format, dataSize, err := audio.ReadWAVHeader(r)
+		if err != nil {
+			if errors.Is(err, audio.ErrCompressedFormat) {
+				return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+			}
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAudio, err)
+		}
+		tc = withWAVFormat(tc, format)
+		size = dataSize
+		r = readCloser{io.LimitReader(r, dataSize), r}
+	} else if tc.Encoding == "" {
+		tc.Encoding = ext
+	}
+
+	w, events, err := c.TranscribeStream(ctx, tc)
+	if err != nil {
+		return nil, err
+	}
+	chunk := config.ChunkSize
+	if chunk <= 0 {
+		chunk = 32 << 10
+	}
+	var bps int
+	if (tc.Encoding == "" || tc.Encoding == "pcm") && tc.SampleRate > 0 {
+		bps = audio.BytesPerSecond(tc.SampleRate, max(tc.Channels, tc.InputChannels))
+	}
+
+	out := make(chan StreamEvent)
+	side := make(chan StreamEvent)
+	stop := make(chan struct{})
+	go func() {
+		defer r.Close()
+		defer w.Close()
+		send := func(ev StreamEvent) bool {
+			select {
+			case side <- ev:
+				return true
+			case <-stop:
+				return false
+			}
+		}
+		buf := make([]byte, chunk)
+		progress := StreamProgress{TotalBytes: size}
+		for ctx.Err() == nil {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					if ctx.Err() == nil {
+						send(StreamEvent{Type: EventError, Error: werr})
+					}
+					return
+				}
+				progress.Bytes += int64(n)
+				if bps > 0 {
+					progress.Audio = time.Duration(progress.Bytes) * time.Second / time.Duration(bps)
+				}
+				p := progress
+				if !send(StreamEvent{Type: EventProgress, Progress: &p}) {
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return
+			}
+			if err != nil {
+				send(StreamEvent{Type: EventError, Error: fmt.Errorf("%w: %v", ErrInvalidAudio, err)})
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(out)
+		defer close(stop)
+		for {
+			var ev StreamEvent
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				ev = e
+			case ev = <-side:
+			}
+			if !sendEvent(ctx, out, ev) {
+				// Let the provider finish closing the stream.
+				for range events {
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// readCloser reads from one reader and closes another.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,69 @@
+package stt
+
+import "strings"
+
+// ModelMap selects a provider's model by language.
+type ModelMap struct {
+	// Models maps BCP-47 codes to models. A bare language ("en") covers
+	// all its regions; a region ("en-GB") takes precedence over it.
+	Models map[string]string
+
+	// Default is the model for other languages and for automatic
+	// detection. Empty leaves the provider's own default.
+	Default string
+}
+
+// Model returns the model for language, "" for automatic detection.
+func (m ModelMap) Model(language string) string {
+	if language == "" {
+		return m.Default
+	}
+	if model, ok := m.Models[language]; ok {
+		return model
+	}
+	base, _, _ := strings.Cut(language, "-")
+	if model, ok := m.Models[base]; ok {
+		return model
+	}
+	return m.Default
+}
+
+// DefaultModelMaps are the model maps the client uses for providers
+// without one set by SetModelMap, by provider name:
+//
+//	deepgram: "nova-2" for English, "whisper-large" (multilingual) otherwise
+var DefaultModelMaps = map[string]ModelMap{
+	"deepgram": {Models: map[string]string{"en": "nova-2"}, Default: "whisper-large"},
+}
+
+// SetModelMap sets the model map for the named provider, overriding
+// DefaultModelMaps. Requests to the provider that leave
+// TranscriptionConfig.Model empty use the map's model for their Language.
+// A zero ModelMap turns selection off for the provider.
+func (c *Client) SetModelMap(provider string, m ModelMap) {
+	if c.models == nil {
+		c.models = make(map[string]ModelMap)
+	}
+	c.models[provider] = m
+}
+
+// ModelMap returns the model map used for the named provider.
+func (c *Client) ModelMap(provider string) (ModelMap, bool) {
+	if m, ok := c.models[provider]; ok {
+		return m, m.Default != "" || len(m.Models) > 0
+	}
+	m, ok := DefaultModelMaps[provider]
+	return m, ok
+}
+
+// withModel returns config with the named provider's model for its
+// language, unless config names a model.
+func (c *Client) withModel(provider string, config TranscriptionConfig) TranscriptionConfig {
+	if config.Model != "" {
+		return config
+	}
+	if m, ok := c.ModelMap(provider); ok {
+		config.Model = m.Model(config.Language)
+	}
+	return config
+}
@@ -0,0 +1,95 @@
+package stt
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// streamFlushTimeout bounds how long a canceled stream keeps delivering
+// flushed events before closing its channel.
+const streamFlushTimeout = time.Second
+
+// sendEvent delivers ev, blocking until the consumer accepts it. Once ctx
+// has ended, delivery is abandoned after streamFlushTimeout so a departed
+// consumer cannot leak the sending goroutine.
+func sendEvent(ctx context.Context, ch chan<- StreamEvent, ev StreamEvent) bool {
+	select {
+	case ch <- ev:
+		return true
+	case <-ctx.Done():
+	}
+	t := time.NewTimer(streamFlushTimeout)
+	defer t.Stop()
+	select {
+	case ch <- ev:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// startStream starts a provider stream and wraps its events so that
+// cancellation of ctx delivers remaining provider events, and promotes a
+// trailing interim transcript to a Partial final result, before the
+// channel closes. Transcript events get a Correction against the previous
+// transcript of the utterance. release is called once the stream has ended.
+func startStream(ctx context.Context, sp StreamingProvider, config TranscriptionConfig, release func()) (io.WriteCloser, <-chan StreamEvent, error) {
+	w, in, err := sp.TranscribeStream(ctx, config)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		defer release()
+		var interim *StreamEvent
+		var prev string
+		var grace <-chan time.Time
+		done := ctx.Done()
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					if interim != nil && ctx.Err() != nil {
+						sendEvent(ctx, out, partialFinal(*interim))
+					}
+					return
+				}
+				if ev.Type == EventTranscript {
+					fillSegmentWords(&ev, config)
+					c := DiffTranscript(prev, ev.Transcript)
+					ev.Correction = &c
+					if ev.IsFinal {
+						interim, prev = nil, ""
+					} else {
+						interim, prev = &ev, ev.Transcript
+					}
+				}
+				if !sendEvent(ctx, out, ev) {
+					return
+				}
+			case <-done:
+				done = nil
+				t := time.NewTimer(streamFlushTimeout)
+				defer t.Stop()
+				grace = t.C
+			case <-grace:
+				if interim != nil {
+					sendEvent(ctx, out, partialFinal(*interim))
+				}
+				return
+			}
+		}
+	}()
+	return w, out, nil
+}
+
+func partialFinal(ev StreamEvent) StreamEvent {
+	ev.IsFinal = true
+	ev.Partial = true
+	// The text is unchanged from the interim already delivered.
+	ev.Correction = &Correction{Offset: len(ev.Transcript)}
+	return ev
+}
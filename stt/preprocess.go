@@ -0,0 +1,100 @@
+package stt
+
+import (
+	"io"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// preprocessFrame is the frame length audio is preprocessed in, so noise
+// tracking and AGC follow the level through the recording.
+const preprocessFrame = 20 * time.Millisecond
+
+// SetPreprocessing runs caller audio through an audio.Preprocessor before
+// it reaches a provider, with a fresh Preprocessor per Transcribe call and
+// per stream. It only applies to mono linear PCM, that is, Encoding "pcm"
+// (or empty) with a SampleRate and at most one channel; other audio is
+// passed through. A nil config disables preprocessing.
+func (c *Client) SetPreprocessing(config *audio.PreprocessConfig) {
+	c.preprocess = config
+}
+
+// preprocessor returns a Preprocessor for audio described by config, or
+// nil if preprocessing is off or does not apply.
+func (c *Client) preprocessor(config TranscriptionConfig) *audio.Preprocessor {
+	if c.preprocess == nil || config.SampleRate <= 0 || config.Channels > 1 {
+		return nil
+	}
+	if config.Encoding != "" && config.Encoding != "pcm" {
+		return nil
+	}
+	return audio.NewPreprocessor(config.SampleRate, *c.preprocess)
+}
+
+// frameBytes returns the size of a preprocessFrame of mono PCM at
+// sampleRate.
+func frameBytes(sampleRate int) int {
+	return max(int(int64(audio.BytesPerSecond(sampleRate, 1))*int64(preprocessFrame)/int64(time.Second))&^1, audio.BytesPerSample)
+}
+
+// preprocessAudio returns a preprocessed copy of data, or data itself.
+func (c *Client) preprocessAudio(data []byte, config TranscriptionConfig) []byte {
+	p := c.preprocessor(config)
+	if p == nil {
+		return data
+	}
+	out := append([]byte(nil), data...)
+	frame := frameBytes(config.SampleRate)
+	for i := 0; i < len(out); i += frame {
+		p.ProcessBytes(out[i:min(i+frame, len(out))])
+	}
+	return out
+}
+
+// preprocessStream wraps a stream writer so written audio is preprocessed.
+func (c *Client) preprocessStream(w io.WriteCloser, config TranscriptionConfig) io.WriteCloser {
+	p := c.preprocessor(config)
+	if w == nil || p == nil {
+		return w
+	}
+	return &preprocessWriter{w: w, p: p, frame: frameBytes(config.SampleRate)}
+}
+
+// preprocessWriter preprocesses written audio a frame at a time, carrying
+// a partial frame over to the next write.
+type preprocessWriter struct {
+	w       io.WriteCloser
+	p       *audio.Preprocessor
+	frame   int
+	pending []byte
+}
+
+func (pw *preprocessWriter) Write(b []byte) (int, error) {
+	buf := append(pw.pending, b...)
+	n := len(buf) - len(buf)%pw.frame
+	pw.pending = append([]byte(nil), buf[n:]...)
+	if n == 0 {
+		return len(b), nil
+	}
+	for i := 0; i < n; i += pw.frame {
+		pw.p.ProcessBytes(buf[i : i+pw.frame])
+	}
+	if _, err := pw.w.Write(buf[:n]); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close writes the last partial frame and closes the underlying writer;
+// an unpaired trailing byte is dropped.
+func (pw *preprocessWriter) Close() error {
+	if n := len(pw.pending) &^ 1; n > 0 {
+		if _, err := pw.w.Write(pw.p.ProcessBytes(pw.pending[:n])); err != nil {
+			pw.w.Close()
+			return err
+		}
+	}
+	pw.pending = nil
+	return pw.w.Close()
+}
@@ -0,0 +1,102 @@
+package stt
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// captureProvider records the audio it is asked to transcribe.
+type captureProvider struct {
+	audio []byte
+}
+
+func (p *captureProvider) Name() string { return "capture" }
+
+func (p *captureProvider) Transcribe(ctx context.Context, data []byte, config TranscriptionConfig) (*TranscriptionResult, error) {
+	p.audio = append([]byte(nil), data...)
+	return &TranscriptionResult{Text: "ok"}, nil
+}
+
+func (p *captureProvider) TranscribeFile(ctx context.Context, path string, config TranscriptionConfig) (*TranscriptionResult, error) {
+	return nil, ErrUnsupportedFormat
+}
+
+func (p *captureProvider) TranscribeURL(ctx context.Context, url string, config TranscriptionConfig) (*TranscriptionResult, error) {
+	return nil, ErrUnsupportedFormat
+}
+
+// tone returns d seconds of a 440 Hz tone at amplitude amp (0.0-1.0).
+func tone(rate int, seconds, amp float64) []byte {
+	samples := make([]int16, int(float64(rate)*seconds))
+	for i := range samples {
+		samples[i] = int16(amp * math.MaxInt16 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+	}
+	return audio.Int16ToBytes(samples)
+}
+
+func TestPreprocessLevelsPerFrame(t *testing.T) {
+	const rate = 16000
+	// A second of near-silence lets the noise floor settle below the
+	// quiet speech that follows.
+	in := append(tone(rate, 1, 0.0005), tone(rate, 3, 0.014)...)
+
+	p := &captureProvider{}
+	c := NewClient(p)
+	cfg := audio.DefaultPreprocessConfig()
+	c.SetPreprocessing(&cfg)
+	if _, err := c.Transcribe(context.Background(), in, TranscriptionConfig{SampleRate: rate, Encoding: "pcm"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.audio) != len(in) {
+		t.Fatalf("got %d bytes, want %d", len(p.audio), len(in))
+	}
+	tail := len(in) - audio.BytesPerSecond(rate, 1)
+	before := audio.RMS(audio.BytesToInt16(in[tail:]))
+	after := audio.RMS(audio.BytesToInt16(p.audio[tail:]))
+	if after < 3*before {
+		t.Errorf("AGC did not raise quiet speech: RMS %.4f -> %.4f", before, after)
+	}
+}
+
+type nopWriteCloser struct {
+	data   []byte
+	closed bool
+}
+
+func (w *nopWriteCloser) Write(b []byte) (int, error) {
+	w.data = append(w.data, b...)
+	return len(b), nil
+}
+
+func (w *nopWriteCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestPreprocessStreamCarriesPartialFrames(t *testing.T) {
+	const rate = 8000
+	in := tone(rate, 0.5, 0.1)
+	c := NewClient(&captureProvider{})
+	cfg := audio.DefaultPreprocessConfig()
+	c.SetPreprocessing(&cfg)
+
+	out := &nopWriteCloser{}
+	w := c.preprocessStream(out, TranscriptionConfig{SampleRate: rate})
+	for i := 0; i < len(in); i += 77 {
+		if _, err := w.Write(in[i:min(i+77, len(in))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !out.closed {
+		t.Error("underlying writer not closed")
+	}
+	if len(out.data) != len(in) {
+		t.Errorf("got %d bytes, want %d", len(out.data), len(in))
+	}
+}
@@ -0,0 +1,32 @@
+package stt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/ratelimit"
+)
+
+func TestSaturatedPrimaryFallsThroughUnderDeadline(t *testing.T) {
+	primary := &fakeProvider{name: "primary", delay: time.Hour}
+	c := NewClient(primary, &fakeProvider{name: "fallback"})
+	c.SetRateLimit("primary", ratelimit.Limit{MaxInFlight: 1})
+
+	busy, cancelBusy := context.WithCancel(context.Background())
+	defer cancelBusy()
+	go func() { _, _ = c.Transcribe(busy, make([]byte, 320), TranscriptionConfig{}) }()
+	for primary.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	result, err := c.Transcribe(ctx, make([]byte, 320), TranscriptionConfig{})
+	if err != nil {
+		t.Fatalf("saturated primary did not fall through: %v", err)
+	}
+	if result.Text != "fallback" {
+		t.Errorf("transcribed by %q, want fallback", result.Text)
+	}
+}
@@ -0,0 +1,453 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/clock"
+)
+
+// ReconnectConfig configures WithReconnect.
+type ReconnectConfig struct {
+	// MaxReconnects is how many reconnect attempts a stream makes before
+	// the failure is surfaced as a final EventError. Defaults to 3.
+	MaxReconnects int
+
+	// ReplayWindow caps how much audio of the current utterance is kept
+	// for replay to a new connection. Defaults to 10s.
+	ReplayWindow time.Duration
+
+	// Backoff is the wait before the first reconnect attempt of a stream,
+	// doubling after each. Defaults to 250ms.
+	Backoff time.Duration
+
+	// Models, if set, switches models on reconnect: once a stream with
+	// automatic language detection has detected a language (from
+	// Segment.Language), new connections use Models' model for it.
+	Models *ModelMap
+
+	// Clock times the backoff, for deterministic tests. Defaults to
+	// clock.Real.
+	Clock clock.Clock
+}
+
+func (c ReconnectConfig) withDefaults() ReconnectConfig {
+	if c.MaxReconnects <= 0 {
+		c.MaxReconnects = 3
+	}
+	if c.ReplayWindow <= 0 {
+		c.ReplayWindow = 10 * time.Second
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = 250 * time.Millisecond
+	}
+	c.Clock = clock.Or(c.Clock)
+	return c
+}
+
+// ReconnectingProvider is a StreamingProvider that reconnects dropped
+// streams. See WithReconnect.
+type ReconnectingProvider struct {
+	StreamingProvider
+	config     ReconnectConfig
+	reconnects atomic.Int64
+}
+
+// WithReconnect wraps provider so that a stream which fails, by emitting
+// an EventError, closing its events early, or rejecting a write, is
+// transparently replaced by a new one.
+//
+// Audio written since the last final transcript, up to ReplayWindow, is
+// replayed to the new stream so the utterance in progress is not lost.
+// Segment timestamps stay relative to the start of the original stream.
+// Replay needs linear PCM ("pcm" or empty Encoding) and a SampleRate;
+// other streams reconnect without it.
+//
+// Events keep their order across reconnects: a connection's events are
+// relayed only after every event of the connection it replaced, and
+// none of the old connection's follow. Final transcripts are delivered
+// in time order without repeats. After a reconnect, a final segment
+// ending no later than the last final delivered re-transcribes audio
+// already heard and is dropped; one overlapping it loses the words that
+// end by then, if it has word timings, and otherwise the leading words
+// repeating the last final transcript.
+func WithReconnect(provider StreamingProvider, config ReconnectConfig) *ReconnectingProvider {
+	return &ReconnectingProvider{StreamingProvider: provider, config: config.withDefaults()}
+}
+
+// Reconnects returns the number of reconnections made across all streams.
+func (p *ReconnectingProvider) Reconnects() int64 {
+	return p.reconnects.Load()
+}
+
+// TranscribeStream implements StreamingProvider.
+func (p *ReconnectingProvider) TranscribeStream(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, error) {
+	w, events, err := p.StreamingProvider.TranscribeStream(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &reconnectStream{
+		p:       p,
+		ctx:     ctx,
+		config:  config,
+		w:       w,
+		events:  events,
+		dropped: make(chan error, 1),
+		out:     make(chan StreamEvent),
+	}
+	if (config.Encoding == "" || config.Encoding == "pcm") && config.SampleRate > 0 {
+		s.bps = audio.BytesPerSecond(config.SampleRate, config.Channels)
+		s.align = int64(audio.BytesPerSample * max(config.Channels, 1))
+		s.maxBuf = int(int64(s.bps) * int64(p.config.ReplayWindow) / int64(time.Second))
+	}
+	go s.run()
+	return s, s.out, nil
+}
+
+// reconnectStream is one stream of a ReconnectingProvider. Offsets are in
+// bytes of audio written since the stream started.
+type reconnectStream struct {
+	p      *ReconnectingProvider
+	ctx    context.Context
+	config TranscriptionConfig
+
+	// bps is the PCM byte rate, zero when audio is not replayed.
+	bps    int
+	align  int64
+	maxBuf int
+
+	mu      sync.Mutex
+	w       io.WriteCloser
+	events  <-chan StreamEvent
+	closing bool
+
+	// buf holds audio from offset bufStart to written for replay; base
+	// is the offset the current connection's audio starts at.
+	buf      []byte
+	bufStart int64
+	written  int64
+	base     int64
+
+	dropped chan error
+	out     chan StreamEvent
+
+	// Owned by run: lastFinal is the last final transcript and lastEnd
+	// the end of its segment, in stream time, and dedup is set from a
+	// reconnect until the new connection's first new final.
+	lastFinal string
+	lastEnd   time.Duration
+	dedup     bool
+
+	// detected is the language last detected, under s.mu.
+	detected string
+}
+
+// Write sends audio to the current connection and keeps it for replay. A
+// failed write starts a reconnect instead of failing.
+func (s *reconnectStream) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return 0, ErrStreamClosed
+	}
+	s.written += int64(len(b))
+	if s.bps > 0 {
+		s.buf = append(s.buf, b...)
+		if over := len(s.buf) - s.maxBuf; over > 0 {
+			over = int((int64(over) + s.align - 1) / s.align * s.align)
+			s.buf = append(s.buf[:0], s.buf[min(over, len(s.buf)):]...)
+			s.bufStart = s.written - int64(len(s.buf))
+		}
+	}
+	if _, err := s.w.Write(b); err != nil {
+		select {
+		case s.dropped <- err:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// Close ends the stream; the current connection flushes and closes its
+// events as usual.
+func (s *reconnectStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return nil
+	}
+	s.closing = true
+	return s.w.Close()
+}
+
+func (s *reconnectStream) run() {
+	defer close(s.out)
+	attempts := 0
+	for {
+		err := s.forward()
+		if err == nil || s.ctx.Err() != nil {
+			return
+		}
+		for {
+			if attempts >= s.p.config.MaxReconnects {
+				sendEvent(s.ctx, s.out, StreamEvent{
+					Type:  EventError,
+					Error: fmt.Errorf("%w: gave up after %d reconnects: %w", ErrStreamClosed, attempts, err),
+				})
+				return
+			}
+			backoff := s.p.config.Backoff << attempts
+			attempts++
+			t := s.p.config.Clock.NewTimer(backoff)
+			select {
+			case <-t.C():
+			case <-s.ctx.Done():
+				t.Stop()
+				return
+			}
+			var done bool
+			if done, err = s.reconnect(); done {
+				return
+			}
+			if err == nil {
+				break
+			}
+		}
+	}
+}
+
+// forward relays the current connection's events until it ends, returning
+// nil for a normal end and the failure otherwise.
+func (s *reconnectStream) forward() error {
+	s.mu.Lock()
+	events := s.events
+	s.mu.Unlock()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				s.mu.Lock()
+				closing := s.closing
+				s.mu.Unlock()
+				if closing {
+					return nil
+				}
+				return ErrStreamClosed
+			}
+			if ev.Type == EventError {
+				s.mu.Lock()
+				closing := s.closing
+				s.mu.Unlock()
+				if !closing {
+					go drain(events)
+					return ev.Error
+				}
+			}
+			if !s.relay(ev) {
+				go drain(events)
+				return nil
+			}
+		case err := <-s.dropped:
+			go drain(events)
+			return err
+		}
+	}
+}
+
+// relay adjusts and delivers an event, reporting false if the consumer
+// is gone.
+func (s *reconnectStream) relay(ev StreamEvent) bool {
+	if ev.Type == EventTranscript {
+		s.mu.Lock()
+		base := s.base
+		s.mu.Unlock()
+		if ev.Segment != nil && s.bps > 0 && base > 0 {
+			seg := shiftSegment(*ev.Segment, time.Duration(base)*time.Second/time.Duration(s.bps))
+			ev.Segment = &seg
+		}
+		timed := false
+		if s.dedup && ev.IsFinal && ev.Segment != nil && ev.Segment.EndTime > 0 && s.lastEnd > 0 {
+			if ev.Segment.EndTime <= s.lastEnd {
+				// Replayed audio heard before the reconnect.
+				s.utteranceEnded(ev.Segment)
+				return true
+			}
+			if ev, timed = trimBefore(ev, s.lastEnd); timed && ev.Transcript == "" {
+				s.utteranceEnded(ev.Segment)
+				return true
+			}
+		}
+		if s.dedup && !timed {
+			var dup bool
+			ev, dup = trimRepeat(s.lastFinal, ev)
+			if dup && ev.IsFinal {
+				s.dedup = false
+				s.utteranceEnded(ev.Segment)
+				return true
+			}
+		}
+		if ev.Segment != nil && ev.Segment.Language != "" {
+			s.mu.Lock()
+			s.detected = ev.Segment.Language
+			s.mu.Unlock()
+		}
+		if ev.IsFinal {
+			s.dedup = false
+			if strings.TrimSpace(ev.Transcript) != "" {
+				s.lastFinal = ev.Transcript
+			}
+			if ev.Segment != nil {
+				s.lastEnd = max(s.lastEnd, ev.Segment.EndTime)
+			}
+			s.utteranceEnded(ev.Segment)
+		}
+	}
+	return sendEvent(s.ctx, s.out, ev)
+}
+
+// utteranceEnded drops replay audio up to the end of a final segment, or
+// all of it if the segment has no timing.
+func (s *reconnectStream) utteranceEnded(seg *Segment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cut := s.written
+	if seg != nil && seg.EndTime > 0 && s.bps > 0 {
+		end := int64(seg.EndTime) * int64(s.bps) / int64(time.Second) / s.align * s.align
+		cut = min(cut, end)
+	}
+	if n := cut - s.bufStart; n > 0 {
+		s.buf = append(s.buf[:0], s.buf[min(int(n), len(s.buf)):]...)
+		s.bufStart = cut
+	}
+}
+
+// reconnect opens a new connection and replays buffered audio. done
+// reports that the stream was closed meanwhile.
+func (s *reconnectStream) reconnect() (done bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return true, nil
+	}
+	_ = s.w.Close()
+	config := s.config
+	if m := s.p.config.Models; m != nil && config.Language == "" && s.detected != "" {
+		if model := m.Model(s.detected); model != "" {
+			config.Model = model
+		}
+	}
+	w, events, err := s.p.StreamingProvider.TranscribeStream(s.ctx, config)
+	if err != nil {
+		return false, err
+	}
+	s.p.reconnects.Add(1)
+	s.w, s.events = w, events
+	s.base = s.bufStart
+	s.dedup = true
+	// Discard a drop reported by the old connection.
+	select {
+	case <-s.dropped:
+	default:
+	}
+	if len(s.buf) > 0 {
+		if _, err := w.Write(s.buf); err != nil {
+			go drain(events)
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func drain(events <-chan StreamEvent) {
+	for range events {
+	}
+}
+
+// shiftSegment offsets a segment's timestamps by d.
+func shiftSegment(seg Segment, d time.Duration) Segment {
+	seg.StartTime += d
+	seg.EndTime += d
+	seg.Words = append([]Word(nil), seg.Words...)
+	for i := range seg.Words {
+		seg.Words[i].StartTime += d
+		seg.Words[i].EndTime += d
+	}
+	return seg
+}
+
+// trimBefore removes the words of a final segment that end by t, as a
+// reconnected stream re-transcribes replayed audio, and moves the
+// segment's start past them. It reports false, changing nothing, if the
+// segment has no word timings.
+func trimBefore(ev StreamEvent, t time.Duration) (StreamEvent, bool) {
+	seg := *ev.Segment
+	if len(seg.Words) == 0 {
+		return ev, false
+	}
+	k := 0
+	for k < len(seg.Words) && seg.Words[k].EndTime <= t {
+		k++
+	}
+	if k == 0 {
+		return ev, true
+	}
+	seg.Words = seg.Words[k:]
+	texts := make([]string, len(seg.Words))
+	for i, w := range seg.Words {
+		texts[i] = w.Text
+	}
+	seg.Text = strings.Join(texts, " ")
+	if len(seg.Words) > 0 {
+		seg.StartTime = seg.Words[0].StartTime
+	}
+	ev.Transcript, ev.Segment = seg.Text, &seg
+	return ev, true
+}
+
+// trimRepeat removes leading words of ev's transcript that repeat the end
+// of prev, as a reconnected stream re-transcribes replayed audio. At least
+// two words must overlap unless the whole transcript is repeated. It
+// reports whether nothing new remains.
+func trimRepeat(prev string, ev StreamEvent) (StreamEvent, bool) {
+	words := strings.Fields(ev.Transcript)
+	if prev == "" || len(words) == 0 {
+		return ev, false
+	}
+	prevWords := strings.Fields(prev)
+	k := 0
+	for n := min(len(prevWords), len(words)); n > 0; n-- {
+		if wordsEqual(prevWords[len(prevWords)-n:], words[:n]) {
+			k = n
+			break
+		}
+	}
+	if k < 2 && k < len(words) {
+		return ev, false
+	}
+	ev.Transcript = strings.Join(words[k:], " ")
+	if ev.Segment != nil {
+		seg := *ev.Segment
+		seg.Text = ev.Transcript
+		if len(seg.Words) >= k {
+			seg.Words = seg.Words[k:]
+		}
+		ev.Segment = &seg
+	}
+	return ev, ev.Transcript == ""
+}
+
+// wordsEqual compares words ignoring case and punctuation.
+func wordsEqual(a, b []string) bool {
+	for i := range a {
+		if !strings.EqualFold(strings.Trim(a[i], ".,!?;:"), strings.Trim(b[i], ".,!?;:")) {
+			return false
+		}
+	}
+	return true
+}
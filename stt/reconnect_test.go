@@ -0,0 +1,152 @@
+package stt
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedProvider is a streaming provider whose nth connection sends
+// scripts[n] and then drops, except the last, which stays open until its
+// writer is closed.
+type scriptedProvider struct {
+	fakeProvider
+	scripts [][]StreamEvent
+	conns   atomic.Int32
+}
+
+func (p *scriptedProvider) TranscribeStream(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, error) {
+	n := int(p.conns.Add(1)) - 1
+	w := &closeWriter{closed: make(chan struct{})}
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		for _, ev := range p.scripts[n] {
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if n == len(p.scripts)-1 {
+			<-w.closed
+		}
+	}()
+	return w, events, nil
+}
+
+// closeWriter discards audio and signals when it is closed.
+type closeWriter struct {
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (w *closeWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+func (w *closeWriter) Close() error {
+	w.once.Do(func() { close(w.closed) })
+	return nil
+}
+
+// final is a final transcript of words, each given as text, start and end
+// in milliseconds; without words it has no segment.
+func final(text string, words ...any) StreamEvent {
+	ev := StreamEvent{Type: EventTranscript, Transcript: text, IsFinal: true}
+	if len(words) == 0 {
+		return ev
+	}
+	seg := Segment{Text: text}
+	for i := 0; i < len(words); i += 3 {
+		seg.Words = append(seg.Words, Word{
+			Text:      words[i].(string),
+			StartTime: time.Duration(words[i+1].(int)) * time.Millisecond,
+			EndTime:   time.Duration(words[i+2].(int)) * time.Millisecond,
+		})
+	}
+	seg.StartTime, seg.EndTime = seg.Words[0].StartTime, seg.Words[len(seg.Words)-1].EndTime
+	ev.Segment = &seg
+	return ev
+}
+
+// checkReconnects streams through scripts, reconnecting after each
+// drop, and checks that the transcripts delivered are want, in order,
+// and that their final segments run forward in time.
+func checkReconnects(t *testing.T, want []string, scripts ...[]StreamEvent) {
+	t.Helper()
+	p := &scriptedProvider{fakeProvider: fakeProvider{name: "scripted"}, scripts: scripts}
+	rp := WithReconnect(p, ReconnectConfig{Backoff: time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	w, events, err := rp.TranscribeStream(ctx, TranscriptionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	var last time.Duration
+	for ev := range events {
+		switch ev.Type {
+		case EventError:
+			t.Fatalf("stream failed: %v", ev.Error)
+		case EventTranscript:
+			got = append(got, ev.Transcript)
+			if ev.IsFinal && ev.Segment != nil {
+				if ev.Segment.StartTime < last {
+					t.Errorf("final %q starts at %v, before the last final ended at %v", ev.Transcript, ev.Segment.StartTime, last)
+				}
+				last = ev.Segment.EndTime
+			}
+		}
+		if len(got) == len(want) {
+			// The last connection has sent its script; end the stream
+			// and make sure nothing else follows.
+			_ = w.Close()
+		}
+	}
+	if ctx.Err() != nil {
+		t.Fatal("stream did not end")
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("transcripts %q, want %q", got, want)
+	}
+	if n := rp.Reconnects(); n != int64(len(scripts)-1) {
+		t.Errorf("%d reconnects, want %d", n, len(scripts)-1)
+	}
+}
+
+func TestReconnectDropsReplayedFinalsByTiming(t *testing.T) {
+	checkReconnects(t,
+		[]string{"hello there", "how", "how are", "you today"},
+		[]StreamEvent{
+			final("hello there", "hello", 0, 400, "there", 400, 900),
+			{Type: EventTranscript, Transcript: "how"},
+			final("how are", "how", 1000, 1300, "are", 1300, 1600),
+		},
+		[]StreamEvent{
+			// Replayed audio, re-transcribed.
+			final("how are", "how", 1000, 1300, "are", 1300, 1600),
+			final("are you today", "are", 1300, 1600, "you", 1700, 1900, "today", 1900, 2400),
+		},
+	)
+}
+
+func TestReconnectDropsReplayedFinalsByText(t *testing.T) {
+	checkReconnects(t,
+		[]string{"turn on the lights", "please"},
+		[]StreamEvent{final("turn on the lights")},
+		[]StreamEvent{final("on the lights"), final("please")},
+	)
+}
+
+func TestReconnectKeepsOrderAcrossSeveralDrops(t *testing.T) {
+	checkReconnects(t,
+		[]string{"one", "two", "three"},
+		[]StreamEvent{final("one", "one", 0, 500)},
+		[]StreamEvent{final("one", "one", 0, 500), final("two", "two", 600, 900)},
+		[]StreamEvent{final("two", "two", 600, 900), final("three", "three", 1000, 1400)},
+	)
+}
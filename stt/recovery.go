@@ -0,0 +1,206 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// BatchRecoveryConfig configures Client.EnableBatchRecovery.
+type BatchRecoveryConfig struct {
+	// MaxUtterance caps how much audio of the current utterance is kept
+	// for recovery. Defaults to 30s.
+	MaxUtterance time.Duration
+}
+
+// EnableBatchRecovery makes streams from TranscribeStream recover the
+// utterance in progress when their streaming provider fails: the audio
+// written since the last final transcript is sent to Transcribe, with the
+// client's usual fallbacks, and its text delivered as a final
+// EventTranscript with Recovered set before the stream's EventError. This
+// keeps the turn even when no other provider streams, at the cost of a
+// batch request's latency on failure. The stream still ends with the
+// error; recovery needs linear PCM ("pcm" or empty Encoding) and a
+// SampleRate, and other streams fail as before.
+func (c *Client) EnableBatchRecovery(config BatchRecoveryConfig) {
+	if config.MaxUtterance <= 0 {
+		config.MaxUtterance = 30 * time.Second
+	}
+	c.recovery = &config
+}
+
+// recoverStream wraps a native stream for batch recovery, if enabled.
+func (c *Client) recoverStream(ctx context.Context, w io.WriteCloser, events <-chan StreamEvent, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent) {
+	if c.recovery == nil || (config.Encoding != "" && config.Encoding != "pcm") || config.SampleRate <= 0 {
+		return w, events
+	}
+	bps := audio.BytesPerSecond(config.SampleRate, config.Channels)
+	s := &recoveryStream{
+		c:       c,
+		ctx:     ctx,
+		config:  config,
+		bps:     bps,
+		align:   int64(audio.BytesPerSample * max(config.Channels, 1)),
+		maxBuf:  int(int64(bps) * int64(c.recovery.MaxUtterance) / int64(time.Second)),
+		w:       w,
+		dropped: make(chan error, 1),
+		out:     make(chan StreamEvent),
+	}
+	go s.run(events)
+	return s, s.out
+}
+
+// recoveryStream keeps the audio of the utterance in progress for batch
+// recovery. Offsets are in bytes of audio written since the stream
+// started.
+type recoveryStream struct {
+	c      *Client
+	ctx    context.Context
+	config TranscriptionConfig
+	bps    int
+	align  int64
+	maxBuf int
+
+	mu      sync.Mutex
+	w       io.WriteCloser
+	closing bool
+	failed  bool
+
+	// buf holds audio from offset bufStart to written.
+	buf      []byte
+	bufStart int64
+	written  int64
+
+	dropped chan error
+	out     chan StreamEvent
+
+	// interim is the utterance's last interim transcript, owned by run.
+	interim string
+}
+
+// Write sends audio to the stream and keeps it for recovery. A failed
+// write starts recovery instead of failing; writes after a failure
+// return ErrStreamClosed.
+func (s *recoveryStream) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing || s.failed {
+		return 0, ErrStreamClosed
+	}
+	s.written += int64(len(b))
+	s.buf = append(s.buf, b...)
+	if over := len(s.buf) - s.maxBuf; over > 0 {
+		over = int((int64(over) + s.align - 1) / s.align * s.align)
+		s.buf = append(s.buf[:0], s.buf[min(over, len(s.buf)):]...)
+		s.bufStart = s.written - int64(len(s.buf))
+	}
+	if _, err := s.w.Write(b); err != nil {
+		select {
+		case s.dropped <- err:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// Close ends the stream.
+func (s *recoveryStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return nil
+	}
+	s.closing = true
+	return s.w.Close()
+}
+
+func (s *recoveryStream) run(events <-chan StreamEvent) {
+	defer close(s.out)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				if !s.isClosing() {
+					s.recover(ErrStreamClosed)
+				}
+				return
+			}
+			if ev.Type == EventError && !s.isClosing() {
+				go drain(events)
+				s.recover(ev.Error)
+				return
+			}
+			if ev.Type == EventTranscript {
+				if ev.IsFinal {
+					s.interim = ""
+					s.utteranceEnded(ev.Segment)
+				} else {
+					s.interim = ev.Transcript
+				}
+			}
+			if !sendEvent(s.ctx, s.out, ev) {
+				go drain(events)
+				return
+			}
+		case err := <-s.dropped:
+			go drain(events)
+			s.recover(err)
+			return
+		}
+	}
+}
+
+func (s *recoveryStream) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// utteranceEnded drops audio up to the end of a final segment, or all of
+// it if the segment has no timing.
+func (s *recoveryStream) utteranceEnded(seg *Segment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cut := s.written
+	if seg != nil && seg.EndTime > 0 {
+		end := int64(seg.EndTime) * int64(s.bps) / int64(time.Second) / s.align * s.align
+		cut = min(cut, end)
+	}
+	if n := cut - s.bufStart; n > 0 {
+		s.buf = append(s.buf[:0], s.buf[min(int(n), len(s.buf)):]...)
+		s.bufStart = cut
+	}
+}
+
+// recover transcribes the utterance in progress after the stream failed
+// with err, then delivers the failure.
+func (s *recoveryStream) recover(err error) {
+	s.mu.Lock()
+	s.failed = true
+	pcm, start := s.buf, s.bufStart
+	s.buf = nil
+	s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	if len(pcm) > 0 {
+		result, rerr := s.c.Transcribe(s.ctx, pcm, s.config)
+		switch {
+		case rerr != nil:
+			err = fmt.Errorf("%w; batch recovery: %w", err, rerr)
+		case result.Text != "":
+			seg := windowSegment(result, time.Duration(start)*time.Second/time.Duration(s.bps))
+			c := DiffTranscript(s.interim, result.Text)
+			ev := StreamEvent{Type: EventTranscript, Transcript: result.Text, IsFinal: true, Recovered: true, Correction: &c, Segment: &seg}
+			if !sendEvent(s.ctx, s.out, ev) {
+				return
+			}
+		}
+	}
+	sendEvent(s.ctx, s.out, StreamEvent{Type: EventError, Error: err})
+}
@@ -0,0 +1,204 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// SpeakerRegistry maps volatile provider speaker labels to stable,
+// session-scoped speaker IDs.
+//
+// Streaming providers frequently reassign diarization labels ("0", "1")
+// between events, so the same person can flip labels mid-call. The registry
+// applies a simple heuristic to keep Segment.Speaker and Word.Speaker
+// consistent for the lifetime of a stream:
+//
+//   - Each provider label is bound to a stable ID ("speaker_1", ...) the
+//     first time it is seen, and keeps that binding thereafter.
+//   - Short single-word label flips inside a segment are smoothed to the
+//     surrounding speaker, and the segment speaker is the majority label.
+//   - When MaxSpeakers is set and the provider introduces a label beyond
+//     that count, the new label is treated as a relabel and bound to the
+//     stable speaker that has been silent the longest.
+//
+// Limitations: the registry only sees labels and timings, not audio, so it
+// cannot truly re-identify a voice. If a provider swaps two labels at once,
+// or restarts numbering after a reconnect, the mapping will follow the new
+// labels. Callers with voiceprint data can correct the mapping with Bind.
+type SpeakerRegistry struct {
+	mu          sync.Mutex
+	maxSpeakers int
+	labels      map[string]string
+	lastActive  map[string]time.Duration
+	names       map[string]string
+	order       []string
+}
+
+// NewSpeakerRegistry creates a registry. maxSpeakers caps the number of
+// stable speakers (0 = unlimited), typically TranscriptionConfig.MaxSpeakers.
+func NewSpeakerRegistry(maxSpeakers int) *SpeakerRegistry {
+	return &SpeakerRegistry{
+		maxSpeakers: maxSpeakers,
+		labels:      make(map[string]string),
+		lastActive:  make(map[string]time.Duration),
+		names:       make(map[string]string),
+	}
+}
+
+// Rename assigns a display name (e.g., "Agent", "Caller") to a stable
+// speaker ID. Subsequent stabilized segments carry the name in Speaker.
+func (r *SpeakerRegistry) Rename(id, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[id] = name
+}
+
+// Name returns the display name for a stable speaker ID, or the ID itself
+// if no name has been assigned.
+func (r *SpeakerRegistry) Name(id string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.displayLocked(id)
+}
+
+// Bind forces a provider label to map to a stable speaker ID, overriding
+// the heuristic.
+func (r *SpeakerRegistry) Bind(label, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.labels[label] = id
+	if _, ok := r.lastActive[id]; !ok {
+		r.lastActive[id] = 0
+		r.order = append(r.order, id)
+	}
+}
+
+// Speakers returns the stable speaker IDs in the order they were first seen.
+func (r *SpeakerRegistry) Speakers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+// Stabilize rewrites the Speaker fields of seg and its words in place,
+// replacing provider labels with stable speaker IDs or display names.
+func (r *SpeakerRegistry) Stabilize(seg *Segment) {
+	if seg == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	smoothWordLabels(seg.Words)
+
+	counts := make(map[string]int)
+	for i := range seg.Words {
+		w := &seg.Words[i]
+		if w.Speaker == "" {
+			continue
+		}
+		id := r.resolveLocked(w.Speaker, w.EndTime)
+		counts[id]++
+		w.Speaker = r.displayLocked(id)
+	}
+
+	switch {
+	case len(counts) > 0:
+		best, bestCount := "", 0
+		for _, id := range r.order {
+			if counts[id] > bestCount {
+				best, bestCount = id, counts[id]
+			}
+		}
+		seg.Speaker = r.displayLocked(best)
+	case seg.Speaker != "":
+		seg.Speaker = r.displayLocked(r.resolveLocked(seg.Speaker, seg.EndTime))
+	}
+}
+
+func (r *SpeakerRegistry) resolveLocked(label string, at time.Duration) string {
+	id, ok := r.labels[label]
+	if !ok {
+		if r.maxSpeakers > 0 && len(r.order) >= r.maxSpeakers {
+			id = r.stalestLocked()
+		} else {
+			id = fmt.Sprintf("speaker_%d", len(r.order)+1)
+			r.order = append(r.order, id)
+		}
+		r.labels[label] = id
+	}
+	if at > r.lastActive[id] {
+		r.lastActive[id] = at
+	}
+	return id
+}
+
+func (r *SpeakerRegistry) stalestLocked() string {
+	stalest := r.order[0]
+	for _, id := range r.order[1:] {
+		if r.lastActive[id] < r.lastActive[stalest] {
+			stalest = id
+		}
+	}
+	return stalest
+}
+
+func (r *SpeakerRegistry) displayLocked(id string) string {
+	if name, ok := r.names[id]; ok {
+		return name
+	}
+	return id
+}
+
+// smoothWordLabels relabels isolated single-word flips to the speaker of
+// the surrounding words.
+func smoothWordLabels(words []Word) {
+	for i := 1; i < len(words)-1; i++ {
+		prev, next := words[i-1].Speaker, words[i+1].Speaker
+		if prev != "" && prev == next && words[i].Speaker != prev {
+			words[i].Speaker = prev
+		}
+	}
+}
+
+// StabilizeSpeakers wraps a stream event channel, passing every segment
+// through the registry so speaker labels stay consistent across events.
+// The returned channel is closed when events is closed. Once ctx ends,
+// events the consumer does not take within a short grace period are
+// dropped, and the rest of events drained, so a consumer that stopped
+// reading does not leave the stream blocked.
+func StabilizeSpeakers(ctx context.Context, events <-chan StreamEvent, registry *SpeakerRegistry) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if ev.Segment != nil {
+				seg := *ev.Segment
+				seg.Words = append([]Word(nil), seg.Words...)
+				registry.Stabilize(&seg)
+				ev.Segment = &seg
+			}
+			if !sendEvent(ctx, out, ev) {
+				go drain(events)
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// TranscribeStreamWithSpeakers starts a streaming transcription like
+// TranscribeStream and, when diarization is enabled, stabilizes speaker
+// labels through a new SpeakerRegistry returned to the caller.
+// The registry is nil when EnableSpeakerDiarization is false.
+func (c *Client) TranscribeStreamWithSpeakers(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, *SpeakerRegistry, error) {
+	w, events, err := c.TranscribeStream(ctx, config)
+	if err != nil || !config.EnableSpeakerDiarization {
+		return w, events, nil, err
+	}
+	registry := NewSpeakerRegistry(config.MaxSpeakers)
+	return w, StabilizeSpeakers(ctx, events, registry), registry, nil
+}
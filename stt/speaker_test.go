@@ -0,0 +1,72 @@
+package stt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// labeled returns a transcript event whose words are spoken by labels.
+func labeled(labels ...string) StreamEvent {
+	seg := &Segment{}
+	for i, l := range labels {
+		seg.Words = append(seg.Words, Word{Text: "w", Speaker: l, EndTime: time.Duration(i+1) * time.Second})
+	}
+	return StreamEvent{Type: EventTranscript, IsFinal: true, Segment: seg}
+}
+
+func TestStabilizeSpeakers(t *testing.T) {
+	in := make(chan StreamEvent, 3)
+	in <- labeled("0", "0", "1", "0")
+	in <- labeled("1", "1")
+	close(in)
+	registry := NewSpeakerRegistry(0)
+	registry.Rename("speaker_2", "Caller")
+
+	var got []string
+	for ev := range StabilizeSpeakers(context.Background(), in, registry) {
+		got = append(got, ev.Segment.Speaker)
+		for _, w := range ev.Segment.Words {
+			if w.Speaker == "0" || w.Speaker == "1" {
+				t.Errorf("word label %q not rewritten", w.Speaker)
+			}
+		}
+	}
+	if len(got) != 2 || got[0] != "speaker_1" || got[1] != "Caller" {
+		t.Errorf("segment speakers %q, want speaker_1 then Caller", got)
+	}
+}
+
+func TestStabilizeSpeakersStopsWithContext(t *testing.T) {
+	in := make(chan StreamEvent)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		defer close(in)
+		for range 10 {
+			in <- labeled("0")
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := StabilizeSpeakers(ctx, in, NewSpeakerRegistry(0))
+	<-out
+	cancel()
+
+	// The consumer stops reading: the stream must still finish.
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("source blocked after ctx ended")
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-out:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("output not closed after ctx ended")
+		}
+	}
+}
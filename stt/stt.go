@@ -4,7 +4,13 @@ package stt
 import (
 	"context"
 	"io"
+	"strings"
 	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/clock"
+	"github.com/agentplexus/omnivoice/credentials"
+	"github.com/agentplexus/omnivoice/ratelimit"
 )
 
 // TranscriptionConfig configures a STT transcription request.
@@ -20,8 +26,20 @@ type TranscriptionConfig struct {
 	SampleRate int
 
 	// Channels is the number of audio channels (1 = mono, 2 = stereo).
+	// With 1, the Client downmixes multichannel input for the provider:
+	// PCM per InputChannels, and WAV audio, files, and streamed recordings
+	// whose header shows more channels. See Downmix.
 	Channels int
 
+	// InputChannels is the number of channels of PCM input that differs
+	// from a mono Channels, such as a stereo transport or recording
+	// without a header. 0 means the input matches Channels.
+	InputChannels int
+
+	// Downmix selects how multichannel input is converted to mono.
+	// Defaults to DownmixMix.
+	Downmix DownmixMode
+
 	// Encoding is the audio encoding ("pcm", "mp3", "wav", "opus", "flac").
 	Encoding string
 
@@ -31,6 +49,12 @@ type TranscriptionConfig struct {
 	// EnableWordTimestamps includes word-level timestamps.
 	EnableWordTimestamps bool
 
+	// EstimateWordTimestamps, with EnableWordTimestamps, has the Client
+	// estimate word timings for segments the provider returns without
+	// them (see EstimateWords), marking them Word.Estimated, and no longer
+	// skips providers whose Capabilities lack WordTimestamps.
+	EstimateWordTimestamps bool
+
 	// EnableSpeakerDiarization identifies different speakers.
 	EnableSpeakerDiarization bool
 
@@ -40,8 +64,30 @@ type TranscriptionConfig struct {
 	// Keywords are words/phrases to boost recognition accuracy.
 	Keywords []string
 
-	// VocabularyID is a provider-specific custom vocabulary ID.
+	// VocabularyID is a provider-specific custom vocabulary ID, such as
+	// one from Client.CreateVocabulary. It is the primary provider's,
+	// and may not be valid for fallbacks.
 	VocabularyID string
+
+	// ProviderOptions holds settings specific to one provider, keyed by
+	// provider name (as Provider.Name returns) and then option name, for
+	// knobs the common fields do not cover:
+	//
+	//	ProviderOptions: map[string]map[string]any{
+	//		"deepgram": {"smart_format": true},
+	//	}
+	//
+	// Each provider reads only its own entry, with ProviderOption, and
+	// ignores option names it does not know; other providers ignore the
+	// entry. Values are as the provider's API takes them.
+	ProviderOptions map[string]map[string]any
+}
+
+// ProviderOption returns the option named key in provider's entry of
+// ProviderOptions.
+func (c TranscriptionConfig) ProviderOption(provider, key string) (any, bool) {
+	v, ok := c.ProviderOptions[provider][key]
+	return v, ok
 }
 
 // Word represents a single transcribed word with timing.
@@ -60,6 +106,11 @@ type Word struct {
 
 	// Speaker is the speaker identifier (if diarization enabled).
 	Speaker string
+
+	// Estimated reports that the timings were estimated from the
+	// segment's timing rather than reported by the provider. See
+	// TranscriptionConfig.EstimateWordTimestamps.
+	Estimated bool
 }
 
 // Segment represents a segment of transcription (sentence, phrase).
@@ -102,6 +153,15 @@ type TranscriptionResult struct {
 
 	// Duration is the audio duration.
 	Duration time.Duration
+
+	// Partial indicates the result is incomplete because the context was
+	// canceled or its deadline expired before the provider finished.
+	// See Provider.Transcribe for the partial-result contract.
+	Partial bool
+
+	// NoSpeech reports that the audio was transcribed but held no speech,
+	// such as silence or noise a VAD let through. Text is then empty.
+	NoSpeech bool
 }
 
 // StreamEvent represents an event from streaming transcription.
@@ -115,6 +175,20 @@ type StreamEvent struct {
 	// IsFinal indicates if this is a final (non-interim) result.
 	IsFinal bool
 
+	// Stability is the provider's confidence (0.0-1.0) that an interim
+	// transcript will not change; 0 if not reported.
+	Stability float64
+
+	// StableLength is the byte length of the transcript prefix the
+	// provider marks as stable; 0 if not reported.
+	StableLength int
+
+	// Correction is set by Client.TranscribeStream on transcript events
+	// and describes how Transcript differs from the previous transcript of
+	// the same utterance, so captions can be revised in place. See
+	// TranscriptBuilder.
+	Correction *Correction
+
 	// Segment contains segment details for final results.
 	Segment *Segment
 
@@ -126,6 +200,18 @@ type StreamEvent struct {
 
 	// Error contains any error that occurred.
 	Error error
+
+	// Partial indicates a final transcript that was flushed because the
+	// stream's context ended, and may be missing trailing words.
+	Partial bool
+
+	// Recovered indicates a final transcript recovered by batch
+	// transcription after the stream failed. See
+	// Client.EnableBatchRecovery.
+	Recovered bool
+
+	// Progress is set on EventProgress events.
+	Progress *StreamProgress
 }
 
 // StreamEventType identifies the type of stream event.
@@ -143,14 +229,31 @@ const (
 
 	// EventError indicates an error occurred.
 	EventError StreamEventType = "error"
+
+	// EventProgress reports how much of a recording has been sent, from
+	// Client.TranscribeFileStream and TranscribeURLStream.
+	EventProgress StreamEventType = "progress"
 )
 
-// Provider defines the interface for STT providers.
+// Provider defines the interface for STT providers. Request failures,
+// including stream errors, should be classified with NewProviderError.
 type Provider interface {
 	// Name returns the provider name.
 	Name() string
 
 	// Transcribe converts audio to text (batch mode).
+	//
+	// Partial-result contract: if ctx is canceled or its deadline expires
+	// after the provider has produced some output, providers that support
+	// early results return a non-nil result with Partial set to true
+	// together with ctx.Err(). Callers must check the error first and treat
+	// such a result as possibly incomplete. Providers without early results
+	// return a nil result with ctx.Err().
+	//
+	// No-speech contract: audio the provider can process but in which it
+	// detects no speech is not an error. Providers return a result with
+	// empty Text, and the Client sets NoSpeech on it. ErrAudioTooShort is
+	// only for audio below the provider's minimum length.
 	Transcribe(ctx context.Context, audio []byte, config TranscriptionConfig) (*TranscriptionResult, error)
 
 	// TranscribeFile transcribes audio from a file path.
@@ -166,6 +269,10 @@ type StreamingProvider interface {
 
 	// TranscribeStream starts a streaming transcription session.
 	// Returns a writer for sending audio and a channel for receiving events.
+	//
+	// When ctx is canceled, implementations flush any finalized segments as
+	// a last EventTranscript with IsFinal (and Partial) set before closing
+	// the channel, rather than dropping them.
 	TranscribeStream(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, error)
 }
 
@@ -174,12 +281,25 @@ type Client struct {
 	providers map[string]Provider
 	primary   string
 	fallbacks []string
+
+	batchStream *BatchStreamConfig
+	limits      ratelimit.Group
+	credentials credentials.Resolver
+	cache       Cache
+	cacheTTL    time.Duration
+	preprocess  *audio.PreprocessConfig
+	dryRun      *DryRunConfig
+	models      map[string]ModelMap
+	timeouts    Timeouts
+	recovery    *BatchRecoveryConfig
+	clock       clock.Clock
 }
 
 // NewClient creates a new STT client with the specified providers.
 func NewClient(providers ...Provider) *Client {
 	c := &Client{
 		providers: make(map[string]Provider),
+		clock:     clock.Real(),
 	}
 	for i, p := range providers {
 		c.providers[p.Name()] = p
@@ -192,6 +312,14 @@ func NewClient(providers ...Provider) *Client {
 	return c
 }
 
+// SetClock sets the clock timing provider timeouts (see SetTimeouts),
+// rate limit queueing (see SetRateLimit), and TranscribeBatch's retry
+// backoff, for deterministic tests. A nil clock restores the system clock.
+func (c *Client) SetClock(clk clock.Clock) {
+	c.clock = clock.Or(clk)
+	c.limits.SetClock(c.clock)
+}
+
 // SetPrimary sets the primary provider by name.
 func (c *Client) SetPrimary(name string) {
 	c.primary = name
@@ -202,6 +330,12 @@ func (c *Client) SetFallbacks(names ...string) {
 	c.fallbacks = names
 }
 
+// EnableBatchStreaming lets TranscribeStream fall back to batch providers,
+// adapted with StreamFromBatch, when no configured provider streams natively.
+func (c *Client) EnableBatchStreaming(config BatchStreamConfig) {
+	c.batchStream = &config
+}
+
 // Provider returns a specific provider by name.
 func (c *Client) Provider(name string) (Provider, bool) {
 	p, ok := c.providers[name]
@@ -209,46 +343,227 @@ func (c *Client) Provider(name string) (Provider, bool) {
 }
 
 // Transcribe uses the primary provider with automatic fallback.
+// If ctx ends during an attempt, fallbacks are not tried and any partial
+// result from that attempt is returned alongside ctx.Err().
+// Providers with a rate limit that cannot admit the request in time are
+// skipped; if nothing else succeeds the error also wraps ErrRateLimited.
+// Providers whose Capabilities cannot serve config are skipped too,
+// and if none qualify the error is ErrNoCapableProvider.
+// With a cache set (see SetCache), repeated requests are served from it.
+// Without a Model in config, each provider's is picked by language (see
+// SetModelMap). Attempts are bounded by SetTimeouts as well as ctx; an
+// attempt that times out falls through to the next provider. If every
+// attempt fails, the error wraps each as a ProviderError.
+// Audio without speech gives a result with NoSpeech set, not an error.
 func (c *Client) Transcribe(ctx context.Context, audio []byte, config TranscriptionConfig) (*TranscriptionResult, error) {
-	// Try primary provider
-	if p, ok := c.providers[c.primary]; ok {
-		result, err := p.Transcribe(ctx, audio, config)
+	ctx, cancel := c.withOverall(ctx)
+	defer cancel()
+	audio, config = downmixAudio(audio, config)
+	audio = c.preprocessAudio(audio, config)
+
+	var key string
+	if c.cache != nil {
+		key = cacheKey(ctx, audio, config)
+		if c.dryRun != nil {
+			// Keep stub results apart from real ones.
+			key = "dryrun:" + key
+		}
+		if !cacheBypassed(ctx) {
+			if result, ok := c.cache.Get(key); ok {
+				return cloneResult(result), nil
+			}
+		}
+	}
+
+	limited := false
+	var credErr error
+	var skipped skips
+	var failures []error
+	for _, name := range append([]string{c.primary}, c.fallbacks...) {
+		p, ok := c.providers[name]
+		if !ok {
+			continue
+		}
+		if !skipped.check(p, audio, config) {
+			continue
+		}
+		pctx, err := c.withCredentials(ctx, name)
+		if err != nil {
+			credErr = err
+			continue
+		}
+		release, err := c.acquire(ctx, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			limited = true
+			continue
+		}
+		actx, stop := c.withAttempt(pctx, name)
+		result, err := c.call(p).Transcribe(actx, audio, c.withModel(name, config))
+		stop()
+		release()
 		if err == nil {
+			markNoSpeech(result)
+			fillWords(result, config)
+			if c.cache != nil && result != nil && !result.Partial {
+				c.cache.Set(key, cloneResult(result), c.cacheTTL)
+			}
 			return result, nil
 		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		failures = append(failures, failure(name, err))
 	}
 
-	// Try fallbacks
-	for _, name := range c.fallbacks {
-		if p, ok := c.providers[name]; ok {
-			result, err := p.Transcribe(ctx, audio, config)
-			if err == nil {
-				return result, nil
+	return nil, unavailable(skipped.wrap(ErrNoAvailableProvider), limited, append(failures, credErr)...)
+}
+
+// TranscribeURL transcribes audio at a URL with automatic fallback, like
+// Transcribe but without caching or preprocessing, since the audio is
+// fetched by the provider.
+func (c *Client) TranscribeURL(ctx context.Context, url string, config TranscriptionConfig) (*TranscriptionResult, error) {
+	ctx, cancel := c.withOverall(ctx)
+	defer cancel()
+	limited := false
+	var credErr error
+	var skipped skips
+	var failures []error
+	for _, name := range append([]string{c.primary}, c.fallbacks...) {
+		p, ok := c.providers[name]
+		if !ok {
+			continue
+		}
+		if !skipped.check(p, nil, config) {
+			continue
+		}
+		pctx, err := c.withCredentials(ctx, name)
+		if err != nil {
+			credErr = err
+			continue
+		}
+		release, err := c.acquire(ctx, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
 			}
+			limited = true
+			continue
+		}
+		actx, stop := c.withAttempt(pctx, name)
+		result, err := c.call(p).TranscribeURL(actx, url, c.withModel(name, config))
+		stop()
+		release()
+		if err == nil {
+			markNoSpeech(result)
+			fillWords(result, config)
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
 		}
+		failures = append(failures, failure(name, err))
 	}
 
-	return nil, ErrNoAvailableProvider
+	return nil, unavailable(skipped.wrap(ErrNoAvailableProvider), limited, append(failures, credErr)...)
+}
+
+// markNoSpeech applies the no-speech contract of Provider.Transcribe to a
+// successful result: whitespace-only text becomes empty, with NoSpeech
+// set.
+func markNoSpeech(result *TranscriptionResult) {
+	if result == nil || result.NoSpeech || strings.TrimSpace(result.Text) != "" {
+		return
+	}
+	for _, seg := range result.Segments {
+		if strings.TrimSpace(seg.Text) != "" {
+			return
+		}
+	}
+	result.Text = ""
+	result.NoSpeech = true
 }
 
 // TranscribeStream attempts streaming transcription with the primary provider.
-// Falls back to batch transcription if streaming is not available.
+// If no provider streams natively and EnableBatchStreaming was called, the
+// first available batch provider is adapted with StreamFromBatch.
+// A rate-limited provider's slot is held until the event channel closes.
+// Providers whose Capabilities cannot serve config are skipped,
+// and if none qualify the error is ErrNoCapableProvider.
+//
+// The returned channel honors the cancellation contract of
+// StreamingProvider: after ctx ends, events already produced by the
+// provider are still delivered for a short grace period, and a pending
+// interim transcript is flushed as a Partial final result.
 func (c *Client) TranscribeStream(ctx context.Context, config TranscriptionConfig) (io.WriteCloser, <-chan StreamEvent, error) {
-	// Try primary provider
-	if p, ok := c.providers[c.primary]; ok {
-		if sp, ok := p.(StreamingProvider); ok {
-			return sp.TranscribeStream(ctx, config)
+	names := append([]string{c.primary}, c.fallbacks...)
+	limited := false
+	var credErr error
+	var skipped skips
+
+	// Try providers that stream natively
+	for _, name := range names {
+		p, ok := c.providers[name]
+		if !ok {
+			continue
+		}
+		sp, ok := p.(StreamingProvider)
+		if !ok {
+			continue
+		}
+		if !skipped.check(p, nil, config) {
+			continue
 		}
+		pctx, err := c.withCredentials(ctx, name)
+		if err != nil {
+			credErr = err
+			continue
+		}
+		release, err := c.acquire(ctx, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			limited = true
+			continue
+		}
+		w, events, err := startStream(pctx, c.callStream(sp), c.withModel(name, config), release)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, events = c.recoverStream(ctx, downmixStream(c.preprocessStream(w, config), config), events, config)
+		return w, events, nil
 	}
 
-	// Try fallbacks
-	for _, name := range c.fallbacks {
-		if p, ok := c.providers[name]; ok {
-			if sp, ok := p.(StreamingProvider); ok {
-				return sp.TranscribeStream(ctx, config)
+	// Adapt a batch provider
+	if c.batchStream != nil {
+		for _, name := range names {
+			p, ok := c.providers[name]
+			if !ok {
+				continue
+			}
+			if !skipped.check(p, nil, config) {
+				continue
+			}
+			pctx, err := c.withCredentials(ctx, name)
+			if err != nil {
+				credErr = err
+				continue
+			}
+			release, err := c.acquire(ctx, name)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				limited = true
+				continue
 			}
+			w, events, err := startStream(pctx, StreamFromBatch(c.call(p), *c.batchStream), c.withModel(name, config), release)
+			return downmixStream(c.preprocessStream(w, config), config), events, err
 		}
 	}
 
-	return nil, nil, ErrStreamingNotSupported
+	return nil, nil, unavailable(skipped.wrap(ErrStreamingNotSupported), limited, credErr)
 }
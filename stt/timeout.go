@@ -0,0 +1,39 @@
+package stt
+
+import (
+	"context"
+	"time"
+
+	"github.com/agentplexus/omnivoice/internal/fallback"
+)
+
+// Timeouts bounds Transcribe and TranscribeURL on each provider so a
+// slow primary is given up on while the fallbacks can still answer, rather
+// than spending all of ctx's deadline. Zero fields set no bound. Streams
+// are not bounded; they run for as long as ctx allows. The timeouts run
+// on the Client's clock.
+type Timeouts struct {
+	// Attempt bounds each provider attempt.
+	Attempt time.Duration
+
+	// Providers overrides Attempt by provider name.
+	Providers map[string]time.Duration
+
+	// Overall bounds a request across all its attempts.
+	Overall time.Duration
+}
+
+// SetTimeouts sets the provider timeouts. A zero Timeouts removes them.
+func (c *Client) SetTimeouts(t Timeouts) {
+	c.timeouts = t
+}
+
+// withOverall bounds ctx by the overall timeout.
+func (c *Client) withOverall(ctx context.Context) (context.Context, context.CancelFunc) {
+	return fallback.Timeouts(c.timeouts).WithOverall(ctx, c.clock)
+}
+
+// withAttempt bounds ctx by the named provider's attempt timeout.
+func (c *Client) withAttempt(ctx context.Context, provider string) (context.Context, context.CancelFunc) {
+	return fallback.Timeouts(c.timeouts).WithAttempt(ctx, c.clock, provider)
+}
@@ -0,0 +1,80 @@
+package stt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/clock"
+)
+
+func TestSlowPrimaryLeavesTimeForFallback(t *testing.T) {
+	primary := &fakeProvider{name: "primary", delay: time.Hour}
+	c := NewClient(primary, &fakeProvider{name: "fallback"})
+	c.SetTimeouts(Timeouts{Attempt: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	result, err := c.Transcribe(ctx, make([]byte, 320), TranscriptionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "fallback" {
+		t.Errorf("transcribed by %q, want fallback", result.Text)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fell back after %v, want about the 50ms attempt bound", elapsed)
+	}
+	if n := primary.calls.Load(); n != 1 {
+		t.Errorf("primary called %d times, want 1", n)
+	}
+}
+
+func TestProviderTimeoutOverridesAttempt(t *testing.T) {
+	c := NewClient(&fakeProvider{name: "primary", delay: 50 * time.Millisecond}, &fakeProvider{name: "fallback"})
+	c.SetTimeouts(Timeouts{
+		Attempt:   10 * time.Millisecond,
+		Providers: map[string]time.Duration{"primary": 5 * time.Second},
+	})
+
+	result, err := c.Transcribe(context.Background(), make([]byte, 320), TranscriptionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "primary" {
+		t.Errorf("transcribed by %q, want primary within its own bound", result.Text)
+	}
+}
+
+func TestAttemptTimeoutRunsOnClientClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewClient(&fakeProvider{name: "primary", delay: time.Hour}, &fakeProvider{name: "fallback"})
+	c.SetClock(clk)
+	c.SetTimeouts(Timeouts{Attempt: time.Minute})
+
+	done := make(chan *TranscriptionResult, 1)
+	go func() {
+		result, err := c.Transcribe(context.Background(), make([]byte, 320), TranscriptionConfig{})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- result
+	}()
+
+	clk.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("attempt ended before the clock reached its bound")
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(time.Minute)
+	select {
+	case result := <-done:
+		if result == nil || result.Text != "fallback" {
+			t.Errorf("got %+v, want the fallback's result", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("attempt not abandoned when the clock passed its bound")
+	}
+}
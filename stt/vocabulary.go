@@ -0,0 +1,158 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// VocabularyTerm is a word or phrase of a custom vocabulary.
+type VocabularyTerm struct {
+	// Phrase is the word or phrase, as it should be transcribed, e.g. a
+	// product name.
+	Phrase string
+
+	// SoundsLike are pronunciations spelled as ordinary words, e.g.
+	// "omni voice", for providers that accept them.
+	SoundsLike []string
+
+	// Boost weights the term's recognition, for providers that accept
+	// it; 0 uses the provider's default. Scales differ by provider.
+	Boost float64
+}
+
+// VocabularyConfig describes a custom vocabulary to create.
+type VocabularyConfig struct {
+	// Name names the vocabulary. Providers that require unique names
+	// reject duplicates.
+	Name string
+
+	// Language is the BCP-47 language of the terms, for providers that
+	// keep vocabularies per language.
+	Language string
+
+	// Terms are the words and phrases to recognize.
+	Terms []VocabularyTerm
+}
+
+// VocabularyStatus is the state of a custom vocabulary.
+type VocabularyStatus string
+
+const (
+	// VocabularyPending indicates the provider is still preparing the
+	// vocabulary; it cannot be used yet.
+	VocabularyPending VocabularyStatus = "pending"
+
+	// VocabularyReady indicates the vocabulary can be used.
+	VocabularyReady VocabularyStatus = "ready"
+
+	// VocabularyFailed indicates the provider could not prepare the
+	// vocabulary.
+	VocabularyFailed VocabularyStatus = "failed"
+)
+
+// Vocabulary is a custom vocabulary held by a provider.
+type Vocabulary struct {
+	// ID identifies the vocabulary in TranscriptionConfig.VocabularyID.
+	ID string
+
+	// Name is the vocabulary's name.
+	Name string
+
+	// Language is the vocabulary's language, if it has one.
+	Language string
+
+	// Status is the vocabulary's state.
+	Status VocabularyStatus
+
+	// Terms are the words and phrases, when the provider returns them.
+	Terms []VocabularyTerm
+
+	// CreatedAt is when the vocabulary was created, if known.
+	CreatedAt time.Time
+}
+
+// VocabularyManager is implemented by providers that manage custom
+// vocabularies, so product names and jargon can be pushed to them from
+// code. A created vocabulary is used by setting its ID as
+// TranscriptionConfig.VocabularyID; some providers prepare it
+// asynchronously, returning it VocabularyPending.
+type VocabularyManager interface {
+	Provider
+
+	// CreateVocabulary creates a vocabulary.
+	CreateVocabulary(ctx context.Context, config VocabularyConfig) (*Vocabulary, error)
+
+	// DeleteVocabulary deletes a vocabulary by ID.
+	DeleteVocabulary(ctx context.Context, id string) error
+
+	// ListVocabularies lists the account's vocabularies.
+	ListVocabularies(ctx context.Context) ([]Vocabulary, error)
+}
+
+// CreateVocabulary creates a custom vocabulary with the named provider,
+// or the primary provider if provider is empty, with the client's
+// credentials. It returns ErrVocabularyNotSupported if the provider is
+// not a VocabularyManager.
+func (c *Client) CreateVocabulary(ctx context.Context, provider string, config VocabularyConfig) (*Vocabulary, error) {
+	if len(config.Terms) == 0 {
+		return nil, fmt.Errorf("%w: vocabulary has no terms", ErrInvalidConfig)
+	}
+	vm, ctx, err := c.vocabularies(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	v, err := vm.CreateVocabulary(ctx, config)
+	if err != nil {
+		return nil, failure(vm.Name(), err)
+	}
+	return v, nil
+}
+
+// DeleteVocabulary deletes a custom vocabulary from the named provider,
+// as CreateVocabulary.
+func (c *Client) DeleteVocabulary(ctx context.Context, provider, id string) error {
+	vm, ctx, err := c.vocabularies(ctx, provider)
+	if err != nil {
+		return err
+	}
+	if err := vm.DeleteVocabulary(ctx, id); err != nil {
+		return failure(vm.Name(), err)
+	}
+	return nil
+}
+
+// ListVocabularies lists the custom vocabularies of the named provider,
+// as CreateVocabulary.
+func (c *Client) ListVocabularies(ctx context.Context, provider string) ([]Vocabulary, error) {
+	vm, ctx, err := c.vocabularies(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	vs, err := vm.ListVocabularies(ctx)
+	if err != nil {
+		return nil, failure(vm.Name(), err)
+	}
+	return vs, nil
+}
+
+// vocabularies returns the named provider, or the primary, as a
+// VocabularyManager, and ctx carrying its credentials.
+func (c *Client) vocabularies(ctx context.Context, provider string) (VocabularyManager, context.Context, error) {
+	if provider == "" {
+		provider = c.primary
+	}
+	p, ok := c.providers[provider]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNoAvailableProvider, provider)
+	}
+	vm, ok := p.(VocabularyManager)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrVocabularyNotSupported, provider)
+	}
+	ctx, err := c.withCredentials(ctx, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	return vm, ctx, nil
+}
@@ -0,0 +1,38 @@
+package stt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// ReadWAV loads a 16-bit PCM WAV stream and returns its PCM samples and a
+// TranscriptionConfig populated with the matching SampleRate, Channels, and
+// Encoding. Compressed WAV encodings return ErrUnsupportedFormat.
+func ReadWAV(r io.Reader) ([]byte, TranscriptionConfig, error) {
+	pcm, format, err := audio.ReadWAV(r)
+	if err != nil {
+		if errors.Is(err, audio.ErrCompressedFormat) {
+			return nil, TranscriptionConfig{}, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+		}
+		return nil, TranscriptionConfig{}, fmt.Errorf("%w: %v", ErrInvalidAudio, err)
+	}
+	return pcm, TranscriptionConfig{
+		SampleRate: format.SampleRate,
+		Channels:   format.Channels,
+		Encoding:   "pcm",
+	}, nil
+}
+
+// ReadWAVFile is like ReadWAV but reads from a file path.
+func ReadWAVFile(path string) ([]byte, TranscriptionConfig, error) {
+	f, err := os.Open(path) // #nosec G304 -- caller-supplied path is intended
+	if err != nil {
+		return nil, TranscriptionConfig{}, err
+	}
+	defer f.Close()
+	return ReadWAV(f)
+}
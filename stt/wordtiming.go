@@ -0,0 +1,69 @@
+package stt
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// EstimateWords returns word timings for a segment without them, spreading
+// the words of its text across its duration in proportion to their length
+// in characters. The words are marked Estimated and take the segment's
+// confidence and speaker. It returns nil for a segment without text or
+// without a duration.
+func EstimateWords(seg Segment) []Word {
+	fields := strings.Fields(seg.Text)
+	span := seg.EndTime - seg.StartTime
+	if len(fields) == 0 || span <= 0 {
+		return nil
+	}
+	total := 0
+	for _, f := range fields {
+		total += utf8.RuneCountInString(f)
+	}
+	words := make([]Word, len(fields))
+	start, chars := seg.StartTime, 0
+	for i, f := range fields {
+		chars += utf8.RuneCountInString(f)
+		end := seg.StartTime + time.Duration(int64(span)*int64(chars)/int64(total))
+		words[i] = Word{
+			Text:       f,
+			StartTime:  start,
+			EndTime:    end,
+			Confidence: seg.Confidence,
+			Speaker:    seg.Speaker,
+			Estimated:  true,
+		}
+		start = end
+	}
+	return words
+}
+
+// estimatesWords reports whether config asks for word timings to be
+// estimated where the provider omits them.
+func estimatesWords(config TranscriptionConfig) bool {
+	return config.EnableWordTimestamps && config.EstimateWordTimestamps
+}
+
+// fillWords applies EstimateWordTimestamps to a successful result.
+func fillWords(result *TranscriptionResult, config TranscriptionConfig) {
+	if result == nil || !estimatesWords(config) {
+		return
+	}
+	for i, seg := range result.Segments {
+		if len(seg.Words) == 0 {
+			result.Segments[i].Words = EstimateWords(seg)
+		}
+	}
+}
+
+// fillSegmentWords applies EstimateWordTimestamps to a stream event's
+// segment, copying it rather than modifying the provider's.
+func fillSegmentWords(ev *StreamEvent, config TranscriptionConfig) {
+	if ev.Segment == nil || len(ev.Segment.Words) > 0 || !estimatesWords(config) {
+		return
+	}
+	seg := *ev.Segment
+	seg.Words = EstimateWords(seg)
+	ev.Segment = &seg
+}
@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"slices"
+	"time"
+)
+
+// EventCodecChanged indicates AdaptOpus changed the Opus encoder
+// parameters. Data is a CodecChange.
+const EventCodecChanged EventType = "codec_changed"
+
+// OpusParams are the Opus encoder settings AdaptOpus controls.
+type OpusParams struct {
+	// Bitrate is the target bitrate in bits per second.
+	Bitrate int
+
+	// FEC enables in-band forward error correction.
+	FEC bool
+
+	// DTX enables discontinuous transmission during silence.
+	DTX bool
+
+	// PacketLossPercent is the expected loss the encoder tunes FEC for.
+	PacketLossPercent int
+}
+
+// OpusTuner is implemented by connections that encode Opus themselves,
+// such as WebRTC connections, and can apply new encoder parameters.
+type OpusTuner interface {
+	SetOpusParams(params OpusParams) error
+}
+
+// CodecChange is the Data of EventCodecChanged.
+type CodecChange struct {
+	// Previous and Current are the parameters before and after.
+	Previous OpusParams
+	Current  OpusParams
+
+	// Stats is the measurement that caused the change.
+	Stats QualityStats
+
+	// Err is set if applying Current failed; the previous parameters then
+	// stay in effect at the encoder.
+	Err error
+}
+
+// AdaptivePolicy sets when AdaptOpus degrades and recovers. Zero fields
+// use defaults.
+type AdaptivePolicy struct {
+	// Bitrates is the bitrate ladder, highest first. Defaults to 32, 24,
+	// 16, and 12 kbps.
+	Bitrates []int
+
+	// DegradeLoss and DegradeJitter step the bitrate down one rung when
+	// either is reached. Default to 3% and 40ms.
+	DegradeLoss   float64
+	DegradeJitter time.Duration
+
+	// RecoverLoss and RecoverJitter step the bitrate up one rung once loss
+	// and jitter have stayed below both for RecoverAfter consecutive
+	// measurements. Default to 1%, 20ms, and 3.
+	RecoverLoss   float64
+	RecoverJitter time.Duration
+	RecoverAfter  int
+
+	// FECLoss enables FEC when loss reaches it, until loss falls below
+	// RecoverLoss again. Defaults to 2%.
+	FECLoss float64
+
+	// DisableDTX keeps DTX off. Otherwise it is enabled whenever the
+	// bitrate is below the top rung.
+	DisableDTX bool
+}
+
+func (p AdaptivePolicy) withDefaults() AdaptivePolicy {
+	if len(p.Bitrates) == 0 {
+		p.Bitrates = []int{32000, 24000, 16000, 12000}
+	}
+	p.Bitrates = slices.Clone(p.Bitrates)
+	if p.DegradeLoss <= 0 {
+		p.DegradeLoss = 0.03
+	}
+	if p.DegradeJitter <= 0 {
+		p.DegradeJitter = 40 * time.Millisecond
+	}
+	if p.RecoverLoss <= 0 {
+		p.RecoverLoss = 0.01
+	}
+	if p.RecoverJitter <= 0 {
+		p.RecoverJitter = 20 * time.Millisecond
+	}
+	if p.RecoverAfter <= 0 {
+		p.RecoverAfter = 3
+	}
+	if p.FECLoss <= 0 {
+		p.FECLoss = 0.02
+	}
+	return p
+}
+
+// BitrateAdapter is the control loop behind AdaptOpus: it turns quality
+// measurements into Opus parameters. It is not safe for concurrent use.
+type BitrateAdapter struct {
+	policy AdaptivePolicy
+	rung   int
+	fec    bool
+	good   int
+	loss   float64
+	prev   QualityStats
+}
+
+// NewBitrateAdapter creates an adapter starting at the top bitrate.
+func NewBitrateAdapter(policy AdaptivePolicy) *BitrateAdapter {
+	return &BitrateAdapter{policy: policy.withDefaults()}
+}
+
+// Params returns the current parameters.
+func (a *BitrateAdapter) Params() OpusParams {
+	return OpusParams{
+		Bitrate:           a.policy.Bitrates[a.rung],
+		FEC:               a.fec,
+		DTX:               !a.policy.DisableDTX && a.rung > 0,
+		PacketLossPercent: int(a.loss*100 + 0.5),
+	}
+}
+
+// Update feeds a measurement and reports whether the parameters changed.
+// Loss is measured over the interval since the previous measurement, so a
+// spike is not diluted by a long clean call.
+func (a *BitrateAdapter) Update(stats QualityStats) (OpusParams, bool) {
+	before := a.Params()
+	loss := stats.LossRate
+	if stats.PacketsReceived > a.prev.PacketsReceived && stats.PacketsLost >= a.prev.PacketsLost {
+		lost := stats.PacketsLost - a.prev.PacketsLost
+		loss = float64(lost) / float64(stats.PacketsReceived-a.prev.PacketsReceived+lost)
+	}
+	a.prev = stats
+	a.loss = loss
+
+	p := a.policy
+	switch {
+	case loss >= p.DegradeLoss || stats.Jitter >= p.DegradeJitter:
+		a.good = 0
+		a.rung = min(a.rung+1, len(p.Bitrates)-1)
+	case loss < p.RecoverLoss && stats.Jitter < p.RecoverJitter:
+		a.good++
+		if a.good >= p.RecoverAfter {
+			a.good = 0
+			a.rung = max(a.rung-1, 0)
+			a.fec = false
+		}
+	default:
+		a.good = 0
+	}
+	if loss >= p.FECLoss {
+		a.fec = true
+	}
+	after := a.Params()
+	changed := after.Bitrate != before.Bitrate || after.FEC != before.FEC || after.DTX != before.DTX
+	return after, changed
+}
+
+// AdaptOpus wraps a connection so that each EventQuality it emits drives a
+// BitrateAdapter: when loss or jitter degrade, the Opus bitrate steps down
+// and FEC and DTX are enabled, and they recover as the network improves.
+// New parameters go to apply, or to the connection's SetOpusParams if
+// apply is nil and it is an OpusTuner, and each change is reported as
+// EventCodecChanged. Events are delivered through a goroutine that ends
+// when the wrapped connection's Events channel closes.
+func AdaptOpus(conn Connection, policy AdaptivePolicy, apply func(OpusParams) error) Connection {
+	if apply == nil {
+		if t, ok := conn.(OpusTuner); ok {
+			apply = t.SetOpusParams
+		}
+	}
+	c := &adaptiveConn{
+		Connection: conn,
+		adapter:    NewBitrateAdapter(policy),
+		apply:      apply,
+		events:     make(chan Event, 32),
+	}
+	go c.forward()
+	return c
+}
+
+type adaptiveConn struct {
+	Connection
+	adapter *BitrateAdapter
+	apply   func(OpusParams) error
+	events  chan Event
+}
+
+func (c *adaptiveConn) Events() <-chan Event { return c.events }
+
+func (c *adaptiveConn) forward() {
+	defer close(c.events)
+	for ev := range c.Connection.Events() {
+		c.events <- ev
+		stats, ok := ev.Data.(QualityStats)
+		if ev.Type != EventQuality || !ok {
+			continue
+		}
+		prev := c.adapter.Params()
+		params, changed := c.adapter.Update(stats)
+		if !changed {
+			continue
+		}
+		change := CodecChange{Previous: prev, Current: params, Stats: stats}
+		if c.apply != nil {
+			change.Err = c.apply(params)
+		}
+		c.events <- Event{Type: EventCodecChanged, Data: change, Error: change.Err}
+	}
+}
@@ -0,0 +1,321 @@
+package transport
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/transport/rtp"
+)
+
+// DTMF sources reported in DTMF.Source.
+const (
+	// DTMFSourceRFC2833 indicates an RTP telephone-event (RFC 2833/4733).
+	DTMFSourceRFC2833 = "rfc2833"
+
+	// DTMFSourceInband indicates a tone detected in the audio stream.
+	DTMFSourceInband = "inband"
+)
+
+// DTMF is the Data of an EventDTMF event.
+type DTMF struct {
+	// Digit is the digit pressed: 0-9, *, #, or A-D.
+	Digit string
+
+	// Source is how the digit was detected (DTMFSourceRFC2833 or
+	// DTMFSourceInband).
+	Source string
+
+	// Gap is the time between the end of the previous digit and the start
+	// of this one. It is zero for the first digit.
+	Gap time.Duration
+}
+
+// dtmfBlock is the Goertzel block length at 8kHz. 205 samples puts every
+// DTMF frequency close to a bin centre.
+const dtmfBlock = 205
+
+// dtmfConfirm is the number of consecutive blocks that start or end a tone.
+const dtmfConfirm = 2
+
+var (
+	dtmfRows = [4]float64{697, 770, 852, 941}
+	dtmfCols = [4]float64{1209, 1336, 1477, 1633}
+	dtmfKeys = [4]string{"123A", "456B", "789C", "*0#D"}
+)
+
+// DTMFDetector recognizes DTMF digits from RTP telephone-events and from
+// in-band tones. Each key press is reported once, however long it is held.
+// Once a telephone-event has been seen, in-band detection is disabled so
+// digits sent both ways are not reported twice.
+// It is not safe for concurrent use.
+type DTMFDetector struct {
+	// MinLevel is the minimum RMS level (0.0-1.0) for an in-band tone.
+	MinLevel float64
+
+	sampleRate int
+	blockSize  int
+	coeffs     [8]float64
+	pending    []int16
+	position   time.Duration
+
+	candidate string
+	count     int
+	active    string
+	misses    int
+	activeEnd time.Duration
+	lastEnd   time.Duration
+	hasLast   bool
+
+	eventsSeen bool
+	event      rtp.TelephoneEvent
+	eventTS    uint32
+	eventEnd   uint32
+	hasEvent   bool
+}
+
+// NewDTMFDetector creates a detector for mono audio at sampleRate.
+func NewDTMFDetector(sampleRate int) *DTMFDetector {
+	if sampleRate <= 0 {
+		sampleRate = 8000
+	}
+	d := &DTMFDetector{
+		MinLevel:   0.005,
+		sampleRate: sampleRate,
+		blockSize:  dtmfBlock * sampleRate / 8000,
+	}
+	for i, f := range append(dtmfRows[:], dtmfCols[:]...) {
+		d.coeffs[i] = 2 * math.Cos(2*math.Pi*f/float64(sampleRate))
+	}
+	return d
+}
+
+// Process analyzes mono samples for in-band tones and returns the digits
+// whose key press was confirmed within them.
+func (d *DTMFDetector) Process(frame []int16) []DTMF {
+	if d.eventsSeen {
+		d.position += d.duration(len(frame))
+		return nil
+	}
+	var digits []DTMF
+	d.pending = append(d.pending, frame...)
+	for len(d.pending) >= d.blockSize {
+		block := d.pending[:d.blockSize]
+		start := d.position
+		d.position += d.duration(d.blockSize)
+		if digit, ok := d.update(d.classify(block), start); ok {
+			digits = append(digits, digit)
+		}
+		d.pending = d.pending[d.blockSize:]
+	}
+	d.pending = append(d.pending[:0:0], d.pending...)
+	return digits
+}
+
+// ProcessTelephoneEvent handles a telephone-event RTP payload, returning a
+// digit at the start of each new event. Retransmitted and continuation
+// packets of the same event are ignored.
+func (d *DTMFDetector) ProcessTelephoneEvent(timestamp uint32, payload []byte) (DTMF, bool) {
+	var ev rtp.TelephoneEvent
+	if ev.Unmarshal(payload) != nil || ev.Digit() == "" {
+		return DTMF{}, false
+	}
+	d.eventsSeen = true
+	end := timestamp + uint32(ev.Duration)
+	if d.hasEvent {
+		// Same event, or a long event continued under a new timestamp
+		// after its duration field overflowed.
+		same := timestamp == d.eventTS
+		continued := !d.event.End && ev.Event == d.event.Event && timestamp == d.eventEnd
+		if same || continued {
+			d.event.End = ev.End
+			if int32(end-d.eventEnd) > 0 { // #nosec G115 -- RTP timestamp arithmetic wraps
+				d.eventEnd = end
+			}
+			return DTMF{}, false
+		}
+	}
+	digit := DTMF{Digit: ev.Digit(), Source: DTMFSourceRFC2833}
+	if d.hasEvent {
+		if gap := int32(timestamp - d.eventEnd); gap > 0 { // #nosec G115 -- RTP timestamp arithmetic wraps
+			digit.Gap = time.Duration(gap) * time.Second / rtp.TelephoneEventClockRate
+		}
+	}
+	d.event, d.eventTS, d.eventEnd, d.hasEvent = ev, timestamp, end, true
+	return digit, true
+}
+
+// Reset clears the detector state.
+func (d *DTMFDetector) Reset() {
+	*d = DTMFDetector{
+		MinLevel:   d.MinLevel,
+		sampleRate: d.sampleRate,
+		blockSize:  d.blockSize,
+		coeffs:     d.coeffs,
+	}
+}
+
+func (d *DTMFDetector) duration(samples int) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(d.sampleRate)
+}
+
+// update advances the debounce state with one block's classification.
+func (d *DTMFDetector) update(key string, start time.Duration) (DTMF, bool) {
+	if key != "" && key == d.active {
+		d.misses = 0
+		d.activeEnd = d.position
+		d.candidate, d.count = "", 0
+		return DTMF{}, false
+	}
+	if d.active != "" {
+		d.misses++
+		if d.misses >= dtmfConfirm {
+			d.active = ""
+			d.lastEnd, d.hasLast = d.activeEnd, true
+		}
+	}
+
+	if key == "" || key != d.candidate {
+		d.candidate, d.count = key, 0
+	}
+	if key == "" {
+		return DTMF{}, false
+	}
+	d.count++
+	if d.count < dtmfConfirm {
+		return DTMF{}, false
+	}
+
+	if d.active != "" {
+		// A different key without an intervening silence.
+		d.lastEnd, d.hasLast = d.activeEnd, true
+	}
+	toneStart := start - d.duration(d.blockSize*(dtmfConfirm-1))
+	digit := DTMF{Digit: key, Source: DTMFSourceInband}
+	if d.hasLast && toneStart > d.lastEnd {
+		digit.Gap = toneStart - d.lastEnd
+	}
+	d.active, d.misses, d.activeEnd = key, 0, d.position
+	d.candidate, d.count = "", 0
+	return digit, true
+}
+
+// classify returns the key whose tone pair dominates the block, or "".
+func (d *DTMFDetector) classify(block []int16) string {
+	var s1, s2 [8]float64
+	var energy float64
+	for _, v := range block {
+		x := float64(v) / 32768
+		energy += x * x
+		for i, c := range d.coeffs {
+			s0 := x + c*s1[i] - s2[i]
+			s2[i], s1[i] = s1[i], s0
+		}
+	}
+	n := float64(len(block))
+	if energy/n < d.MinLevel*d.MinLevel {
+		return ""
+	}
+	var power [8]float64
+	for i, c := range d.coeffs {
+		power[i] = s1[i]*s1[i] + s2[i]*s2[i] - c*s1[i]*s2[i]
+	}
+
+	row, col := strongest(power[:4]), strongest(power[4:])
+	pr, pc := power[row], power[4+col]
+	// Twist: up to 8dB with the column tone weaker, 4dB with it stronger.
+	if pc*6.3 < pr || pr*2.5 < pc {
+		return ""
+	}
+	// Each tone must stand 6dB clear of the others in its group.
+	for i := range 4 {
+		if (i != row && power[i]*4 > pr) || (i != col && power[4+i]*4 > pc) {
+			return ""
+		}
+	}
+	// The tone pair must carry most of the block's energy, which rejects
+	// speech and music that happen to contain the frequencies.
+	if 2*(pr+pc)/(n*energy) < 0.6 {
+		return ""
+	}
+	return dtmfKeys[row][col : col+1]
+}
+
+func strongest(p []float64) int {
+	best := 0
+	for i := range p {
+		if p[i] > p[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// DetectDTMF wraps a connection so in-band tones in its 16-bit PCM AudioOut
+// are reported as EventDTMF on Events, for transports that do not deliver
+// DTMF as events. Audio passes through unchanged. Events are delivered
+// through a goroutine that ends when the wrapped connection's Events
+// channel closes.
+func DetectDTMF(conn Connection, sampleRate int) Connection {
+	c := &dtmfConn{
+		Connection: conn,
+		detector:   NewDTMFDetector(sampleRate),
+		digits:     make(chan DTMF, 16),
+		events:     make(chan Event, 32),
+	}
+	go c.forward()
+	return c
+}
+
+type dtmfConn struct {
+	Connection
+	detector *DTMFDetector
+	digits   chan DTMF
+	events   chan Event
+
+	mu   sync.Mutex
+	tail []byte
+}
+
+func (c *dtmfConn) AudioOut() io.Reader { return dtmfReader{c} }
+
+func (c *dtmfConn) Events() <-chan Event { return c.events }
+
+func (c *dtmfConn) forward() {
+	defer close(c.events)
+	in := c.Connection.Events()
+	for {
+		select {
+		case ev, ok := <-in:
+			if !ok {
+				return
+			}
+			c.events <- ev
+		case d := <-c.digits:
+			c.events <- Event{Type: EventDTMF, Data: d}
+		}
+	}
+}
+
+type dtmfReader struct{ c *dtmfConn }
+
+func (r dtmfReader) Read(p []byte) (int, error) {
+	n, err := r.c.Connection.AudioOut().Read(p)
+	if n > 0 {
+		r.c.mu.Lock()
+		buf := append(r.c.tail, p[:n]...)
+		whole := len(buf) - len(buf)%audio.BytesPerSample
+		digits := r.c.detector.Process(audio.BytesToInt16(buf[:whole]))
+		r.c.tail = append(r.c.tail[:0], buf[whole:]...)
+		r.c.mu.Unlock()
+		for _, d := range digits {
+			select {
+			case r.c.digits <- d:
+			default:
+			}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,537 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// JSON WebSocket protocol
+//
+// The OmniVoice JSON WebSocket protocol connects a client, such as a web
+// page or a telephony platform's media stream, to an agent over one
+// WebSocket. Every message is a JSON object in a text frame with a "type"
+// field; audio may instead travel in binary frames.
+//
+// The client opens with "setup", naming the audio format, and the server
+// answers "ready":
+//
+//	→ {"type":"setup","session_id":"abc","sample_rate":16000,"encoding":"pcm","channels":1,"audio_framings":["binary","base64"],"metadata":{"user":"42"}}
+//	← {"type":"ready","session_id":"abc","sample_rate":16000,"encoding":"pcm","channels":1,"audio_framing":"binary"}
+//
+// All fields of setup are optional; encoding is "pcm" (16-bit
+// little-endian), "mulaw", or "alaw" as in agent.Config.AudioEncoding.
+//
+// audio_framings lists the audio framings the client supports, in order
+// of preference, and ready names the one chosen: the first the server
+// knows, or "binary" if the list is absent or names none. With "binary"
+// both sides send audio as raw binary frames; with "base64", for peers
+// that only handle text frames, as messages:
+//
+//	{"type":"audio","audio":"<base64>"}
+//
+// Binary framing is the default because base64 costs bandwidth and time.
+// As BenchmarkJSONWebSocketAudio measures, a 20ms frame of 16kHz PCM is
+// 640 bytes binary and 883 as an audio message (+38%); of 8kHz mu-law,
+// 160 bytes and 243 (+52%). Encoding and decoding the message took about
+// 5µs for the PCM frame and 2-4µs for the mu-law one on a server core,
+// with five allocations, against under 0.1µs and none for a binary frame:
+// negligible next to the frame's 20ms, but multiplied across every stream
+// a server carries.
+//
+// The server also sends "clear" when the client should discard audio it
+// has buffered but not yet played, as when the agent is interrupted:
+//
+//	← {"type":"clear"}
+//
+// The client sends text input, keypad digits, and explicit interruptions:
+//
+//	→ {"type":"text","text":"What's my balance?"}
+//	→ {"type":"dtmf","digits":"1#"}
+//	→ {"type":"interrupt"}
+//
+// and, for push-to-talk, when the talk button is pressed and released:
+//
+//	→ {"type":"listen_start"}
+//	→ {"type":"listen_stop"}
+//
+// The server sends session events, such as transcripts, named by event
+// with any JSON data:
+//
+//	← {"type":"event","event":"agent_transcript","data":{...}}
+//
+// Either side may send "error" with a message, and "close" before closing
+// the WebSocket:
+//
+//	{"type":"error","error":"unsupported encoding"}
+//	{"type":"close"}
+//
+// Receivers ignore unknown fields, so messages may gain fields.
+
+// AudioFraming is how the JSON WebSocket protocol carries audio.
+type AudioFraming string
+
+// Audio framings.
+const (
+	// FramingBinary sends audio as raw binary WebSocket frames.
+	FramingBinary AudioFraming = "binary"
+
+	// FramingBase64 sends audio base64 in audio messages.
+	FramingBase64 AudioFraming = "base64"
+)
+
+// MessageType identifies a JSON WebSocket protocol message.
+type MessageType string
+
+// JSON WebSocket protocol message types.
+const (
+	MessageSetup       MessageType = "setup"
+	MessageReady       MessageType = "ready"
+	MessageAudio       MessageType = "audio"
+	MessageClear       MessageType = "clear"
+	MessageText        MessageType = "text"
+	MessageDTMF        MessageType = "dtmf"
+	MessageInterrupt   MessageType = "interrupt"
+	MessageListenStart MessageType = "listen_start"
+	MessageListenStop  MessageType = "listen_stop"
+	MessageEvent       MessageType = "event"
+	MessageError       MessageType = "error"
+	MessageClose       MessageType = "close"
+)
+
+// Connection events of the JSON WebSocket protocol.
+const (
+	// EventText indicates text input from the remote. Data is the text.
+	EventText EventType = "text"
+
+	// EventInterrupt indicates the remote asked to interrupt the agent.
+	EventInterrupt EventType = "interrupt"
+)
+
+// DTMFSourceMessage indicates a digit sent in a signaling message, such
+// as a JSON WebSocket "dtmf" message.
+const DTMFSourceMessage = "message"
+
+// ErrInvalidMessage is returned when decoding a malformed protocol
+// message.
+var ErrInvalidMessage = errors.New("transport: invalid protocol message")
+
+// Message is a JSON WebSocket protocol message. Which fields apply
+// depends on Type.
+type Message struct {
+	Type MessageType `json:"type"`
+
+	// Setup and ready fields.
+	SessionID  string            `json:"session_id,omitempty"`
+	SampleRate int               `json:"sample_rate,omitempty"`
+	Encoding   string            `json:"encoding,omitempty"`
+	Channels   int               `json:"channels,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+
+	// AudioFramings are the framings a setup's client supports, in order
+	// of preference.
+	AudioFramings []AudioFraming `json:"audio_framings,omitempty"`
+
+	// AudioFraming is the framing chosen in ready.
+	AudioFraming AudioFraming `json:"audio_framing,omitempty"`
+
+	// Audio is the audio of an audio message, base64 in JSON.
+	Audio []byte `json:"audio,omitempty"`
+
+	// Text is the input of a text message.
+	Text string `json:"text,omitempty"`
+
+	// Digits are the keys of a dtmf message.
+	Digits string `json:"digits,omitempty"`
+
+	// Event and Data are the name and data of an event message.
+	Event string          `json:"event,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+
+	// Error is the message of an error message.
+	Error string `json:"error,omitempty"`
+}
+
+// EncodeMessage encodes a protocol message as JSON.
+func EncodeMessage(m Message) ([]byte, error) {
+	if m.Type == "" {
+		return nil, fmt.Errorf("%w: no type", ErrInvalidMessage)
+	}
+	return json.Marshal(m)
+}
+
+// DecodeMessage decodes a protocol message from JSON. Unknown types are
+// decoded, for the caller to ignore.
+func DecodeMessage(data []byte) (Message, error) {
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Message{}, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	if m.Type == "" {
+		return Message{}, fmt.Errorf("%w: no type", ErrInvalidMessage)
+	}
+	return m, nil
+}
+
+// WebSocket message types, as numbered by RFC 6455 opcodes and
+// github.com/gorilla/websocket.
+const (
+	WebSocketText   = 1
+	WebSocketBinary = 2
+)
+
+// WebSocketConn is the WebSocket connection NewJSONWebSocket speaks over.
+// *websocket.Conn from github.com/gorilla/websocket implements it; other
+// libraries need a small adapter.
+type WebSocketConn interface {
+	// ReadMessage returns the next message and its type, WebSocketText
+	// or WebSocketBinary.
+	ReadMessage() (messageType int, data []byte, err error)
+
+	// WriteMessage sends a message. It is not called concurrently.
+	WriteMessage(messageType int, data []byte) error
+
+	// Close closes the connection.
+	Close() error
+
+	// RemoteAddr returns the remote address.
+	RemoteAddr() net.Addr
+}
+
+// JSONWebSocket is a Connection speaking the JSON WebSocket protocol to
+// a client.
+//
+// Audio written to AudioIn is sent to the client and audio the client
+// sends is read from AudioOut, in the format of its setup. The setup is
+// reported as EventConnected with the setup Message as Data; text input
+// as EventText, digits as EventDTMF (one per digit), interrupt requests
+// as EventInterrupt, and push-to-talk as EventListenStart and
+// EventListenStop. SendEvent and Clear send session events and
+// clear requests to the client.
+type JSONWebSocket struct {
+	conn WebSocketConn
+
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	id     string
+	setup  *Message
+	ready  chan struct{}
+	events chan Event
+	closed chan struct{}
+
+	// audioQ holds client audio on its way to AudioOut, so the read loop
+	// keeps handling control messages while AudioOut is not read.
+	audioQ   chan []byte
+	outR     *io.PipeReader
+	outW     *io.PipeWriter
+	shutOnce sync.Once
+}
+
+// jsonAudioBuffer is the number of client audio frames a JSONWebSocket
+// holds for AudioOut, about 1.3s of 20ms frames.
+const jsonAudioBuffer = 64
+
+// NewJSONWebSocket starts speaking the protocol over conn, which must be
+// an accepted WebSocket; the connection reads from it until Close or the
+// client closes it.
+func NewJSONWebSocket(conn WebSocketConn) *JSONWebSocket {
+	r, w := io.Pipe()
+	c := &JSONWebSocket{
+		conn:   conn,
+		id:     newConnectionID(),
+		ready:  make(chan struct{}),
+		events: make(chan Event, 32),
+		closed: make(chan struct{}),
+		audioQ: make(chan []byte, jsonAudioBuffer),
+		outR:   r,
+		outW:   w,
+	}
+	go c.read()
+	go c.relayAudio()
+	return c
+}
+
+// newConnectionID returns a random connection ID.
+func newConnectionID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ID returns the setup's session_id, or a random ID if it had none.
+func (c *JSONWebSocket) ID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id
+}
+
+// WaitSetup waits for the client's setup message.
+func (c *JSONWebSocket) WaitSetup(ctx context.Context) (Message, error) {
+	select {
+	case <-c.ready:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return *c.setup, nil
+	case <-c.closed:
+		return Message{}, io.ErrClosedPipe
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// AudioIn returns a writer sending audio to the client in the negotiated
+// framing, one frame or message per Write.
+func (c *JSONWebSocket) AudioIn() io.WriteCloser { return (*jsonAudioWriter)(c) }
+
+// AudioOut returns a reader for audio received from the client. Audio is
+// buffered, so control messages are still handled while it is not read;
+// once the buffer is full the oldest audio is dropped.
+func (c *JSONWebSocket) AudioOut() io.Reader { return c.outR }
+
+// Events returns the connection event channel. Events are dropped rather
+// than blocking the connection if the consumer falls behind.
+func (c *JSONWebSocket) Events() <-chan Event { return c.events }
+
+// RemoteAddr returns the client's address.
+func (c *JSONWebSocket) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// SendEvent sends a session event to the client; data is encoded as JSON.
+func (c *JSONWebSocket) SendEvent(event string, data any) error {
+	m := Message{Type: MessageEvent, Event: event}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		m.Data = b
+	}
+	return c.Send(m)
+}
+
+// Clear asks the client to discard audio it has not yet played.
+func (c *JSONWebSocket) Clear() error {
+	return c.Send(Message{Type: MessageClear})
+}
+
+// Send sends a protocol message to the client.
+func (c *JSONWebSocket) Send(m Message) error {
+	b, err := EncodeMessage(m)
+	if err != nil {
+		return err
+	}
+	return c.write(WebSocketText, b)
+}
+
+func (c *JSONWebSocket) write(messageType int, data []byte) error {
+	select {
+	case <-c.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// Close sends a close message and closes the WebSocket.
+func (c *JSONWebSocket) Close() error {
+	_ = c.Send(Message{Type: MessageClose})
+	c.shutdown()
+	return nil
+}
+
+func (c *JSONWebSocket) emit(ev Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	select {
+	case c.events <- ev:
+	default:
+	}
+}
+
+func (c *JSONWebSocket) shutdown() {
+	c.shutOnce.Do(func() {
+		_ = c.conn.Close()
+		_ = c.outW.Close()
+		c.mu.Lock()
+		select {
+		case c.events <- Event{Type: EventDisconnected}:
+		default:
+		}
+		close(c.closed)
+		close(c.events)
+		c.mu.Unlock()
+	})
+}
+
+// read handles client messages until the WebSocket closes.
+func (c *JSONWebSocket) read() {
+	defer c.shutdown()
+	for {
+		kind, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if kind == WebSocketBinary {
+			if !c.audio(data) {
+				return
+			}
+			continue
+		}
+		m, err := DecodeMessage(data)
+		if err != nil {
+			c.emit(Event{Type: EventError, Error: err})
+			_ = c.Send(Message{Type: MessageError, Error: err.Error()})
+			continue
+		}
+		switch m.Type {
+		case MessageSetup:
+			c.handleSetup(m)
+		case MessageAudio:
+			if !c.audio(m.Audio) {
+				return
+			}
+		case MessageText:
+			c.emit(Event{Type: EventText, Data: m.Text})
+		case MessageDTMF:
+			for _, d := range m.Digits {
+				c.emit(Event{Type: EventDTMF, Data: DTMF{Digit: string(d), Source: DTMFSourceMessage}})
+			}
+		case MessageInterrupt:
+			c.emit(Event{Type: EventInterrupt})
+		case MessageListenStart:
+			c.emit(Event{Type: EventListenStart})
+		case MessageListenStop:
+			c.emit(Event{Type: EventListenStop})
+		case MessageClose:
+			return
+		}
+	}
+}
+
+// handleSetup records the client's setup and answers ready. A repeated
+// setup is an error.
+func (c *JSONWebSocket) handleSetup(m Message) {
+	c.mu.Lock()
+	if c.setup != nil {
+		c.mu.Unlock()
+		_ = c.Send(Message{Type: MessageError, Error: "setup already received"})
+		return
+	}
+	if m.Encoding == "" {
+		m.Encoding = "pcm"
+	}
+	if m.Channels == 0 {
+		m.Channels = 1
+	}
+	if m.SessionID != "" {
+		c.id = m.SessionID
+	}
+	m.SessionID = c.id
+	m.AudioFraming = negotiateFraming(m.AudioFramings)
+	c.setup = &m
+	close(c.ready)
+	c.mu.Unlock()
+
+	_ = c.Send(Message{
+		Type:         MessageReady,
+		SessionID:    m.SessionID,
+		SampleRate:   m.SampleRate,
+		Encoding:     m.Encoding,
+		Channels:     m.Channels,
+		AudioFraming: m.AudioFraming,
+	})
+	c.emit(Event{Type: EventConnected, Data: m})
+}
+
+// audio queues client audio for AudioOut, dropping the oldest if the
+// queue is full, and reports false once the connection is closed.
+func (c *JSONWebSocket) audio(data []byte) bool {
+	if len(data) == 0 {
+		return true
+	}
+	for {
+		select {
+		case c.audioQ <- data:
+			return true
+		case <-c.closed:
+			return false
+		default:
+		}
+		select {
+		case <-c.audioQ:
+		default:
+		}
+	}
+}
+
+// relayAudio writes queued client audio to AudioOut until the connection
+// closes.
+func (c *JSONWebSocket) relayAudio() {
+	for {
+		select {
+		case data := <-c.audioQ:
+			if _, err := c.outW.Write(data); err != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// negotiateFraming returns the first framing in offered the server
+// supports, or FramingBinary.
+func negotiateFraming(offered []AudioFraming) AudioFraming {
+	for _, f := range offered {
+		if f == FramingBinary || f == FramingBase64 {
+			return f
+		}
+	}
+	return FramingBinary
+}
+
+// AudioFraming returns the audio framing negotiated in setup, or
+// FramingBinary before it.
+func (c *JSONWebSocket) AudioFraming() AudioFraming {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.setup == nil {
+		return FramingBinary
+	}
+	return c.setup.AudioFraming
+}
+
+type jsonAudioWriter JSONWebSocket
+
+// Write sends p to the client as one audio message.
+func (w *jsonAudioWriter) Write(p []byte) (int, error) {
+	c := (*JSONWebSocket)(w)
+	var err error
+	if c.AudioFraming() == FramingBinary {
+		err = c.write(WebSocketBinary, p)
+	} else {
+		err = c.Send(Message{Type: MessageAudio, Audio: p})
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection.
+func (w *jsonAudioWriter) Close() error {
+	return (*JSONWebSocket)(w).Close()
+}
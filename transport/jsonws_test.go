@@ -0,0 +1,380 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wsFrame is a WebSocket message of fakeWebSocket.
+type wsFrame struct {
+	kind int
+	data []byte
+}
+
+// fakeWebSocket is a WebSocketConn fed by send and recording what is
+// written to it.
+type fakeWebSocket struct {
+	in     chan wsFrame
+	closed chan struct{}
+	once   sync.Once
+
+	mu  sync.Mutex
+	out []wsFrame
+}
+
+func newFakeWebSocket() *fakeWebSocket {
+	return &fakeWebSocket{in: make(chan wsFrame), closed: make(chan struct{})}
+}
+
+func (f *fakeWebSocket) ReadMessage() (int, []byte, error) {
+	select {
+	case m := <-f.in:
+		return m.kind, m.data, nil
+	case <-f.closed:
+		return 0, nil, io.EOF
+	}
+}
+
+func (f *fakeWebSocket) WriteMessage(kind int, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.out = append(f.out, wsFrame{kind, slices.Clone(data)})
+	return nil
+}
+
+func (f *fakeWebSocket) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func (f *fakeWebSocket) RemoteAddr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+
+// send delivers a client message.
+func (f *fakeWebSocket) send(t *testing.T, m Message) {
+	t.Helper()
+	b, err := EncodeMessage(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.sendFrame(t, WebSocketText, b)
+}
+
+func (f *fakeWebSocket) sendFrame(t *testing.T, kind int, data []byte) {
+	t.Helper()
+	select {
+	case f.in <- wsFrame{kind, data}:
+	case <-time.After(time.Second):
+		t.Fatal("connection stopped reading client messages")
+	}
+}
+
+// written returns the frames sent to the client, decoding text frames.
+func (f *fakeWebSocket) written(t *testing.T) (messages []Message, binary [][]byte) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, w := range f.out {
+		if w.kind == WebSocketBinary {
+			binary = append(binary, w.data)
+			continue
+		}
+		m, err := DecodeMessage(w.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, binary
+}
+
+// nextEvent returns the next connection event.
+func nextEvent(t *testing.T, c *JSONWebSocket) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-c.Events():
+		if !ok {
+			t.Fatal("events closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no connection event")
+	}
+	return Event{}
+}
+
+// connect starts a connection over a fake WebSocket and completes setup.
+func connect(t *testing.T, setup Message) (*JSONWebSocket, *fakeWebSocket) {
+	t.Helper()
+	ws := newFakeWebSocket()
+	c := NewJSONWebSocket(ws)
+	t.Cleanup(func() { _ = c.Close() })
+	setup.Type = MessageSetup
+	ws.send(t, setup)
+	if ev := nextEvent(t, c); ev.Type != EventConnected {
+		t.Fatalf("first event %+v, want connected", ev)
+	}
+	return c, ws
+}
+
+func TestEncodeDecodeMessage(t *testing.T) {
+	in := Message{Type: MessageAudio, Audio: []byte{0, 1, 0xff}}
+	b, err := EncodeMessage(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := DecodeMessage(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Type != in.Type || !bytes.Equal(out.Audio, in.Audio) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	if m, err := DecodeMessage([]byte(`{"type":"future","extra":1}`)); err != nil || m.Type != "future" {
+		t.Errorf("unknown type decoded as %+v, %v", m, err)
+	}
+	if _, err := EncodeMessage(Message{}); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("EncodeMessage without type = %v, want ErrInvalidMessage", err)
+	}
+	for _, data := range []string{`{}`, `not json`, `{"type":1}`} {
+		if _, err := DecodeMessage([]byte(data)); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("DecodeMessage(%s) = %v, want ErrInvalidMessage", data, err)
+		}
+	}
+}
+
+func TestJSONWebSocketSetup(t *testing.T) {
+	c, ws := connect(t, Message{SessionID: "abc", SampleRate: 8000, Encoding: "mulaw", Metadata: map[string]string{"user": "42"}})
+
+	setup, err := c.WaitSetup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID() != "abc" || setup.Encoding != "mulaw" || setup.Channels != 1 || setup.Metadata["user"] != "42" {
+		t.Errorf("setup %+v with ID %q", setup, c.ID())
+	}
+	messages, _ := ws.written(t)
+	want := Message{Type: MessageReady, SessionID: "abc", SampleRate: 8000, Encoding: "mulaw", Channels: 1, AudioFraming: FramingBinary}
+	if len(messages) != 1 || messages[0].Type != want.Type || messages[0].SessionID != want.SessionID ||
+		messages[0].SampleRate != want.SampleRate || messages[0].Encoding != want.Encoding ||
+		messages[0].Channels != want.Channels || messages[0].AudioFraming != want.AudioFraming {
+		t.Fatalf("sent %+v, want %+v", messages, want)
+	}
+
+	ws.send(t, Message{Type: MessageSetup, SessionID: "other"})
+	ws.send(t, Message{Type: MessageText, Text: "sync"})
+	if ev := nextEvent(t, c); ev.Type != EventText {
+		t.Fatalf("event %+v after a repeated setup, want only the text", ev)
+	}
+	messages, _ = ws.written(t)
+	if len(messages) != 2 || messages[1].Type != MessageError {
+		t.Errorf("repeated setup answered %+v, want an error", messages[1:])
+	}
+	if c.ID() != "abc" {
+		t.Errorf("repeated setup changed the ID to %q", c.ID())
+	}
+}
+
+func TestJSONWebSocketDefaultID(t *testing.T) {
+	c, ws := connect(t, Message{})
+	messages, _ := ws.written(t)
+	if c.ID() == "" || len(messages) != 1 || messages[0].SessionID != c.ID() || messages[0].Encoding != "pcm" {
+		t.Errorf("ready %+v for connection %q, want its random ID and pcm", messages, c.ID())
+	}
+}
+
+func TestJSONWebSocketControlMessages(t *testing.T) {
+	c, ws := connect(t, Message{})
+	ws.send(t, Message{Type: MessageText, Text: "What's my balance?"})
+	ws.send(t, Message{Type: MessageDTMF, Digits: "1#"})
+	ws.send(t, Message{Type: MessageInterrupt})
+	ws.send(t, Message{Type: MessageListenStart})
+	ws.send(t, Message{Type: MessageListenStop})
+	ws.sendFrame(t, WebSocketText, []byte("garbage"))
+
+	want := []Event{
+		{Type: EventText, Data: "What's my balance?"},
+		{Type: EventDTMF, Data: DTMF{Digit: "1", Source: DTMFSourceMessage}},
+		{Type: EventDTMF, Data: DTMF{Digit: "#", Source: DTMFSourceMessage}},
+		{Type: EventInterrupt},
+		{Type: EventListenStart},
+		{Type: EventListenStop},
+	}
+	for _, w := range want {
+		if ev := nextEvent(t, c); ev.Type != w.Type || ev.Data != w.Data {
+			t.Errorf("event %+v, want %+v", ev, w)
+		}
+	}
+	if ev := nextEvent(t, c); ev.Type != EventError || !errors.Is(ev.Error, ErrInvalidMessage) {
+		t.Errorf("malformed message reported as %+v", ev)
+	}
+}
+
+func TestJSONWebSocketControlWhileAudioUnread(t *testing.T) {
+	c, ws := connect(t, Message{})
+	// More audio than is buffered, with AudioOut never read.
+	for range 2 * jsonAudioBuffer {
+		ws.sendFrame(t, WebSocketBinary, make([]byte, 320))
+	}
+	ws.send(t, Message{Type: MessageInterrupt})
+	if ev := nextEvent(t, c); ev.Type != EventInterrupt {
+		t.Errorf("event %+v, want interrupt", ev)
+	}
+}
+
+func TestJSONWebSocketAudioOut(t *testing.T) {
+	c, ws := connect(t, Message{AudioFramings: []AudioFraming{FramingBase64}})
+	ws.sendFrame(t, WebSocketBinary, []byte("bin"))
+	ws.send(t, Message{Type: MessageAudio, Audio: []byte("b64")})
+	got := make([]byte, 6)
+	if _, err := io.ReadFull(c.AudioOut(), got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "binb64" {
+		t.Errorf("AudioOut read %q, want binb64", got)
+	}
+}
+
+func TestJSONWebSocketClose(t *testing.T) {
+	c, ws := connect(t, Message{})
+	ws.send(t, Message{Type: MessageClose})
+	if ev := nextEvent(t, c); ev.Type != EventDisconnected {
+		t.Errorf("event %+v, want disconnected", ev)
+	}
+	if _, ok := <-c.Events(); ok {
+		t.Error("events not closed after the client's close")
+	}
+	if _, err := c.AudioOut().Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("AudioOut after close = %v, want EOF", err)
+	}
+	if _, err := c.AudioIn().Write([]byte{1}); err == nil {
+		t.Error("AudioIn accepted audio after close")
+	}
+	select {
+	case <-ws.closed:
+	default:
+		t.Error("WebSocket left open")
+	}
+
+	c, ws = connect(t, Message{})
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	messages, _ := ws.written(t)
+	if last := messages[len(messages)-1]; last.Type != MessageClose {
+		t.Errorf("Close sent %+v last, want close", last)
+	}
+}
+func TestNegotiateFraming(t *testing.T) {
+	tests := []struct {
+		offered []AudioFraming
+		want    AudioFraming
+	}{
+		{nil, FramingBinary},
+		{[]AudioFraming{FramingBase64}, FramingBase64},
+		{[]AudioFraming{FramingBase64, FramingBinary}, FramingBase64},
+		{[]AudioFraming{FramingBinary, FramingBase64}, FramingBinary},
+		{[]AudioFraming{"opus_frames", FramingBase64}, FramingBase64},
+		{[]AudioFraming{"opus_frames"}, FramingBinary},
+	}
+	for _, tt := range tests {
+		if got := negotiateFraming(tt.offered); got != tt.want {
+			t.Errorf("negotiateFraming(%v) = %s, want %s", tt.offered, got, tt.want)
+		}
+	}
+}
+
+func TestJSONWebSocketAudioIn(t *testing.T) {
+	for _, framing := range []AudioFraming{FramingBinary, FramingBase64} {
+		c, ws := connect(t, Message{AudioFramings: []AudioFraming{framing}})
+		if c.AudioFraming() != framing {
+			t.Fatalf("negotiated %s, want %s", c.AudioFraming(), framing)
+		}
+		if _, err := c.AudioIn().Write([]byte("frame")); err != nil {
+			t.Fatal(err)
+		}
+		messages, binary := ws.written(t)
+		switch framing {
+		case FramingBinary:
+			if len(binary) != 1 || string(binary[0]) != "frame" || len(messages) != 1 {
+				t.Errorf("binary framing sent %+v and frames %q", messages, binary)
+			}
+		case FramingBase64:
+			if len(binary) != 0 || len(messages) != 2 || messages[1].Type != MessageAudio || string(messages[1].Audio) != "frame" {
+				t.Errorf("base64 framing sent %+v and frames %q", messages, binary)
+			}
+		}
+	}
+}
+
+// discardWebSocket is a WebSocketConn that counts the bytes written to
+// it and never delivers a message.
+type discardWebSocket struct {
+	closed chan struct{}
+	last   []byte
+	bytes  int
+}
+
+func (d *discardWebSocket) ReadMessage() (int, []byte, error) {
+	<-d.closed
+	return 0, nil, io.EOF
+}
+
+func (d *discardWebSocket) WriteMessage(kind int, data []byte) error {
+	d.last = data
+	d.bytes += len(data)
+	return nil
+}
+
+func (d *discardWebSocket) Close() error         { return nil }
+func (d *discardWebSocket) RemoteAddr() net.Addr { return nil }
+
+// BenchmarkJSONWebSocketAudio measures sending a 20ms frame to the client
+// under each audio framing, for 16 kHz PCM and 8 kHz mu-law, and for
+// base64 decoding the audio message as a client would. wire-B/frame is
+// the size of the frame on the WebSocket.
+func BenchmarkJSONWebSocketAudio(b *testing.B) {
+	for _, format := range []struct {
+		name  string
+		frame int
+	}{
+		{"pcm16k", 640},
+		{"mulaw8k", 160},
+	} {
+		frame := make([]byte, format.frame)
+		for i := range frame {
+			frame[i] = byte(i * 7)
+		}
+		for _, framing := range []AudioFraming{FramingBinary, FramingBase64} {
+			b.Run(format.name+"/"+string(framing), func(b *testing.B) {
+				ws := &discardWebSocket{closed: make(chan struct{})}
+				c := NewJSONWebSocket(ws)
+				c.mu.Lock()
+				c.setup = &Message{AudioFraming: framing}
+				c.mu.Unlock()
+				defer close(ws.closed)
+				w := c.AudioIn()
+				b.ReportAllocs()
+				b.SetBytes(int64(len(frame)))
+				for b.Loop() {
+					if _, err := w.Write(frame); err != nil {
+						b.Fatal(err)
+					}
+					if framing == FramingBase64 {
+						if _, err := DecodeMessage(ws.last); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+				b.ReportMetric(float64(ws.bytes)/float64(b.N), "wire-B/frame")
+			})
+		}
+	}
+}
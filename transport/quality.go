@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/transport/rtp"
+)
+
+// QualityStats are objective call-quality measurements of a connection's
+// received audio.
+type QualityStats struct {
+	// Jitter is the RTP interarrival jitter (RFC 3550).
+	Jitter time.Duration
+
+	// PacketsReceived and PacketsLost count audio packets so far.
+	PacketsReceived uint64
+	PacketsLost     uint64
+
+	// LossRate is the fraction (0.0-1.0) of expected packets lost.
+	LossRate float64
+
+	// RoundTripTime is the network round-trip time, or zero when unknown.
+	RoundTripTime time.Duration
+
+	// MOS is the mean opinion score (1.0-4.5) estimated from the other
+	// figures with EstimateMOS.
+	MOS float64
+
+	// Updated is when the stats were computed.
+	Updated time.Time
+}
+
+// QualityReporter is implemented by connections that measure call
+// quality. They also emit EventQuality periodically.
+type QualityReporter interface {
+	QualityStats() QualityStats
+}
+
+// QualityMonitor computes QualityStats from received RTP packets and RTCP
+// reception reports. It is safe for concurrent use.
+type QualityMonitor struct {
+	clockRate int
+
+	mu          sync.Mutex
+	seq         rtp.SequenceTracker
+	jitter      float64
+	lastTransit int64
+	hasTransit  bool
+	start       time.Time
+	rtt         time.Duration
+	remote      *rtp.ReceptionReport
+}
+
+// NewQualityMonitor creates a monitor for a stream with the given RTP
+// clock rate.
+func NewQualityMonitor(clockRate int) *QualityMonitor {
+	if clockRate <= 0 {
+		clockRate = 8000
+	}
+	return &QualityMonitor{clockRate: clockRate}
+}
+
+// Packet records a received audio packet.
+func (m *QualityMonitor) Packet(seq uint16, timestamp uint32, arrival time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq.Update(seq)
+	if m.start.IsZero() {
+		m.start = arrival
+	}
+	// Transit time in clock-rate units; only differences matter, so the
+	// reference point is arbitrary.
+	transit := int64(arrival.Sub(m.start))*int64(m.clockRate)/int64(time.Second) - int64(timestamp)
+	if m.hasTransit {
+		d := float64(int32(transit - m.lastTransit)) // #nosec G115 -- timestamp differences wrap at 32 bits
+		m.jitter += (math.Abs(d) - m.jitter) / 16
+	}
+	m.lastTransit, m.hasTransit = transit, true
+}
+
+// ReceptionReport records an RTCP reception report from the remote party
+// that arrived at arrival. It provides the round-trip time, and the loss
+// and jitter figures when no packets are recorded locally, as for WebRTC
+// stacks that only expose RTCP.
+func (m *QualityMonitor) ReceptionReport(r rtp.ReceptionReport, arrival time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rtt := r.RoundTripTime(arrival); rtt > 0 {
+		m.rtt = rtt
+	}
+	m.remote = &r
+}
+
+// SetRoundTripTime records a round-trip time measured another way.
+func (m *QualityMonitor) SetRoundTripTime(rtt time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rtt = rtt
+}
+
+// Stats returns the current measurements.
+func (m *QualityMonitor) Stats() QualityStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := QualityStats{RoundTripTime: m.rtt, Updated: time.Now()}
+	clock := time.Duration(m.clockRate)
+	if m.seq.Received() > 0 {
+		s.Jitter = time.Duration(m.jitter * float64(time.Second/clock))
+		s.PacketsReceived = m.seq.Received()
+		s.PacketsLost = m.seq.Lost()
+		if exp := m.seq.Expected(); exp > 0 {
+			s.LossRate = float64(s.PacketsLost) / float64(exp)
+		}
+	} else if r := m.remote; r != nil {
+		s.Jitter = time.Duration(r.Jitter) * time.Second / clock
+		s.PacketsLost = uint64(max(r.CumulativeLost, 0))
+		s.LossRate = float64(r.FractionLost) / 256
+	}
+	s.MOS = EstimateMOS(s.RoundTripTime, s.Jitter, s.LossRate)
+	return s
+}
+
+// EstimateMOS estimates the mean opinion score of a narrowband call from
+// its round-trip time, jitter, and loss rate (0.0-1.0), using a simplified
+// ITU-T G.107 E-model. It ranges from 1.0 (bad) to about 4.4 (excellent).
+func EstimateMOS(rtt, jitter time.Duration, loss float64) float64 {
+	// Effective one-way latency, counting jitter buffering and codec delay.
+	latency := float64(rtt/2+2*jitter)/float64(time.Millisecond) + 10
+	r := 93.2
+	if latency < 160 {
+		r -= latency / 40
+	} else {
+		r -= (latency - 120) / 10
+	}
+	r -= 2.5 * 100 * min(max(loss, 0), 1)
+	if r <= 0 {
+		return 1
+	}
+	r = min(r, 100)
+	return 1 + 0.035*r + 7e-6*r*(r-60)*(100-r)
+}
@@ -0,0 +1,59 @@
+package rtp
+
+import "encoding/binary"
+
+// TelephoneEventClockRate is the clock rate of RFC 4733 telephone-event
+// payloads as negotiated by the SIP transport.
+const TelephoneEventClockRate = 8000
+
+// TelephoneEvent is an RFC 4733 (formerly RFC 2833) named telephone event.
+// Events 0-15 are the DTMF digits 0-9, *, #, and A-D.
+type TelephoneEvent struct {
+	// Event is the event code.
+	Event uint8
+
+	// End is set on the final packets of the event.
+	End bool
+
+	// Volume is the power level in -dBm0 (0-63).
+	Volume uint8
+
+	// Duration is the event duration so far, in clock-rate units from the
+	// packet's RTP timestamp.
+	Duration uint16
+}
+
+// dtmfDigits maps DTMF event codes to digits.
+const dtmfDigits = "0123456789*#ABCD"
+
+// Digit returns the DTMF digit for the event, or "" for non-DTMF events.
+func (e TelephoneEvent) Digit() string {
+	if int(e.Event) >= len(dtmfDigits) {
+		return ""
+	}
+	return dtmfDigits[e.Event : e.Event+1]
+}
+
+// Marshal encodes the event as a 4-byte payload.
+func (e TelephoneEvent) Marshal() []byte {
+	b := make([]byte, 4)
+	b[0] = e.Event
+	b[1] = e.Volume & 0x3f
+	if e.End {
+		b[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(b[2:], e.Duration)
+	return b
+}
+
+// Unmarshal decodes a telephone-event payload.
+func (e *TelephoneEvent) Unmarshal(b []byte) error {
+	if len(b) < 4 {
+		return ErrShortPacket
+	}
+	e.Event = b[0]
+	e.End = b[1]&0x80 != 0
+	e.Volume = b[1] & 0x3f
+	e.Duration = binary.BigEndian.Uint16(b[2:])
+	return nil
+}
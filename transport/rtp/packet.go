@@ -0,0 +1,176 @@
+// Package rtp implements RTP packetization (RFC 3550) and SRTP protection
+// (RFC 3711) shared by the SIP and WebRTC transports.
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Version is the RTP protocol version.
+const Version = 2
+
+// HeaderSize is the size of a fixed RTP header without CSRCs or extensions.
+const HeaderSize = 12
+
+var (
+	// ErrShortPacket is returned when a packet is too short to parse.
+	ErrShortPacket = errors.New("rtp: packet too short")
+
+	// ErrBadVersion is returned when a packet is not RTP version 2.
+	ErrBadVersion = errors.New("rtp: unsupported version")
+
+	// ErrAuthFailed is returned when SRTP authentication fails.
+	ErrAuthFailed = errors.New("rtp: SRTP authentication failed")
+
+	// ErrReplayed is returned when an SRTP packet was already received,
+	// or is too old to tell.
+	ErrReplayed = errors.New("rtp: SRTP packet replayed")
+
+	// ErrNoTranscoding is returned by codecs whose payloads must be supplied
+	// pre-encoded (e.g., Opus, which requires an external encoder).
+	ErrNoTranscoding = errors.New("rtp: codec does not support PCM transcoding")
+
+	// ErrInvalidKey is returned when SRTP key material has the wrong size.
+	ErrInvalidKey = errors.New("rtp: invalid SRTP key")
+)
+
+// Header is the RTP packet header.
+type Header struct {
+	// Marker is the M bit, set on the first packet of a talkspurt.
+	Marker bool
+
+	// PayloadType identifies the payload format.
+	PayloadType uint8
+
+	// SequenceNumber increments by one per packet and wraps at 2^16.
+	SequenceNumber uint16
+
+	// Timestamp is the sampling instant in payload clock-rate units.
+	Timestamp uint32
+
+	// SSRC identifies the synchronization source.
+	SSRC uint32
+
+	// CSRC lists contributing sources.
+	CSRC []uint32
+
+	// ExtensionProfile and Extension hold a header extension, if present.
+	ExtensionProfile uint16
+	Extension        []byte
+	HasExtension     bool
+}
+
+// Packet is an RTP packet.
+type Packet struct {
+	Header
+
+	// Payload is the packet payload, excluding padding.
+	Payload []byte
+}
+
+// Marshal serializes the packet.
+func (p *Packet) Marshal() []byte {
+	size := HeaderSize + 4*len(p.CSRC) + len(p.Payload)
+	if p.HasExtension {
+		size += 4 + len(p.Extension) + (4-len(p.Extension)%4)%4
+	}
+	b := make([]byte, size)
+
+	b[0] = Version<<6 | byte(len(p.CSRC)&0x0F) // #nosec G115 -- CSRC count is 4-bit
+	if p.HasExtension {
+		b[0] |= 0x10
+	}
+	b[1] = p.PayloadType & 0x7F
+	if p.Marker {
+		b[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(b[2:], p.SequenceNumber)
+	binary.BigEndian.PutUint32(b[4:], p.Timestamp)
+	binary.BigEndian.PutUint32(b[8:], p.SSRC)
+	offset := HeaderSize
+	for _, csrc := range p.CSRC {
+		binary.BigEndian.PutUint32(b[offset:], csrc)
+		offset += 4
+	}
+	if p.HasExtension {
+		words := (len(p.Extension) + 3) / 4
+		binary.BigEndian.PutUint16(b[offset:], p.ExtensionProfile)
+		binary.BigEndian.PutUint16(b[offset+2:], uint16(words)) // #nosec G115 -- extension length is 16-bit
+		copy(b[offset+4:], p.Extension)
+		offset += 4 + 4*words
+	}
+	copy(b[offset:], p.Payload)
+	return b
+}
+
+// Unmarshal parses b into the packet. Payload and Extension alias b.
+func (p *Packet) Unmarshal(b []byte) error {
+	if len(b) < HeaderSize {
+		return ErrShortPacket
+	}
+	if b[0]>>6 != Version {
+		return ErrBadVersion
+	}
+	cc := int(b[0] & 0x0F)
+	offset := HeaderSize + 4*cc
+	if len(b) < offset {
+		return ErrShortPacket
+	}
+
+	p.Marker = b[1]&0x80 != 0
+	p.PayloadType = b[1] & 0x7F
+	p.SequenceNumber = binary.BigEndian.Uint16(b[2:])
+	p.Timestamp = binary.BigEndian.Uint32(b[4:])
+	p.SSRC = binary.BigEndian.Uint32(b[8:])
+	p.CSRC = p.CSRC[:0]
+	for i := 0; i < cc; i++ {
+		p.CSRC = append(p.CSRC, binary.BigEndian.Uint32(b[HeaderSize+4*i:]))
+	}
+
+	p.HasExtension = b[0]&0x10 != 0
+	p.Extension = nil
+	if p.HasExtension {
+		if len(b) < offset+4 {
+			return ErrShortPacket
+		}
+		p.ExtensionProfile = binary.BigEndian.Uint16(b[offset:])
+		length := 4 * int(binary.BigEndian.Uint16(b[offset+2:]))
+		if len(b) < offset+4+length {
+			return ErrShortPacket
+		}
+		p.Extension = b[offset+4 : offset+4+length]
+		offset += 4 + length
+	}
+
+	end := len(b)
+	if b[0]&0x20 != 0 {
+		pad := int(b[end-1])
+		if pad == 0 || offset > end-pad {
+			return fmt.Errorf("%w: bad padding", ErrShortPacket)
+		}
+		end -= pad
+	}
+	p.Payload = b[offset:end]
+	return nil
+}
+
+// headerLen returns the length of the RTP header in b, including CSRCs and
+// extension, without fully parsing it.
+func headerLen(b []byte) (int, error) {
+	if len(b) < HeaderSize {
+		return 0, ErrShortPacket
+	}
+	offset := HeaderSize + 4*int(b[0]&0x0F)
+	if b[0]&0x10 != 0 {
+		if len(b) < offset+4 {
+			return 0, ErrShortPacket
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(b[offset+2:]))
+	}
+	if len(b) < offset {
+		return 0, ErrShortPacket
+	}
+	return offset, nil
+}
@@ -0,0 +1,87 @@
+package rtp
+
+import (
+	"strings"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// Static and conventional dynamic payload types.
+const (
+	PayloadPCMU           uint8 = 0
+	PayloadPCMA           uint8 = 8
+	PayloadTelephoneEvent uint8 = 101
+	PayloadOpus           uint8 = 111
+)
+
+// Codec converts between 16-bit linear PCM and an RTP payload format.
+type Codec interface {
+	// Name returns the lower-case codec name ("pcmu", "pcma", "opus").
+	Name() string
+
+	// PayloadType returns the default RTP payload type.
+	PayloadType() uint8
+
+	// ClockRate returns the RTP timestamp clock rate in Hz.
+	ClockRate() int
+
+	// Encode converts PCM to a payload. Passthrough codecs return
+	// ErrNoTranscoding.
+	Encode(pcm []byte) ([]byte, error)
+
+	// Decode converts a payload to PCM. Passthrough codecs return
+	// ErrNoTranscoding.
+	Decode(payload []byte) ([]byte, error)
+}
+
+type g711 struct {
+	name   string
+	pt     uint8
+	encode func([]byte) []byte
+	decode func([]byte) []byte
+}
+
+func (c g711) Name() string                      { return c.name }
+func (c g711) PayloadType() uint8                { return c.pt }
+func (c g711) ClockRate() int                    { return 8000 }
+func (c g711) Encode(pcm []byte) ([]byte, error) { return c.encode(pcm), nil }
+func (c g711) Decode(p []byte) ([]byte, error)   { return c.decode(p), nil }
+
+type opus struct{}
+
+func (opus) Name() string                  { return "opus" }
+func (opus) PayloadType() uint8            { return PayloadOpus }
+func (opus) ClockRate() int                { return 48000 }
+func (opus) Encode([]byte) ([]byte, error) { return nil, ErrNoTranscoding }
+func (opus) Decode([]byte) ([]byte, error) { return nil, ErrNoTranscoding }
+
+var codecs = map[string]Codec{
+	"pcmu": g711{name: "pcmu", pt: PayloadPCMU, encode: audio.EncodeMuLaw, decode: audio.DecodeMuLaw},
+	"pcma": g711{name: "pcma", pt: PayloadPCMA, encode: audio.EncodeALaw, decode: audio.DecodeALaw},
+	"opus": opus{},
+}
+
+// CodecByName returns the codec for a name such as "PCMU" or "opus".
+func CodecByName(name string) (Codec, bool) {
+	c, ok := codecs[strings.ToLower(name)]
+	return c, ok
+}
+
+// CodecByPayloadType returns the codec for a static or conventional
+// payload type.
+func CodecByPayloadType(pt uint8) (Codec, bool) {
+	for _, c := range codecs {
+		if c.PayloadType() == pt {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// RTPMap returns the SDP rtpmap encoding for a codec, e.g. "PCMU/8000".
+func RTPMap(c Codec) string {
+	if c.Name() == "opus" {
+		return "opus/48000/2"
+	}
+	return strings.ToUpper(c.Name()) + "/8000"
+}
@@ -0,0 +1,115 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// RTCP packet types carrying reception reports.
+const (
+	RTCPSenderReport   = 200
+	RTCPReceiverReport = 201
+)
+
+// ntpEpochOffset is the number of seconds from 1900 (the NTP epoch) to
+// 1970.
+const ntpEpochOffset = 2208988800
+
+// ReceptionReport is an RTCP reception report block (RFC 3550 section
+// 6.4.1): how the remote party is receiving one of our streams.
+type ReceptionReport struct {
+	// SSRC is the source the report is about.
+	SSRC uint32
+
+	// FractionLost is the fraction of packets lost since the previous
+	// report, in 1/256 units.
+	FractionLost uint8
+
+	// CumulativeLost is the number of packets lost since the stream began.
+	CumulativeLost int32
+
+	// HighestSequence is the extended highest sequence number received.
+	HighestSequence uint32
+
+	// Jitter is the interarrival jitter, in clock-rate units.
+	Jitter uint32
+
+	// LastSR is the middle 32 bits of the NTP timestamp of the last sender
+	// report received from us, or zero.
+	LastSR uint32
+
+	// DelaySinceLastSR is the time between receiving that sender report
+	// and sending this report, in 1/65536 seconds.
+	DelaySinceLastSR uint32
+}
+
+// RoundTripTime computes the round-trip time from a report that arrived
+// at arrival, or zero when the report does not echo a sender report.
+func (r ReceptionReport) RoundTripTime(arrival time.Time) time.Duration {
+	if r.LastSR == 0 {
+		return 0
+	}
+	rtt := NTPCompact(arrival) - r.LastSR - r.DelaySinceLastSR
+	if rtt > 1<<31 {
+		return 0
+	}
+	return time.Duration(rtt) * time.Second >> 16
+}
+
+// NTPCompact returns the middle 32 bits of the NTP timestamp of t, as used
+// by LastSR.
+func NTPCompact(t time.Time) uint32 {
+	secs := uint64(t.Unix()) + ntpEpochOffset // #nosec G115 -- times before 1970 are not used
+	frac := uint64(t.Nanosecond()) << 16 / uint64(time.Second)
+	return uint32(secs<<16 | frac) // #nosec G115 -- truncation is the compact format
+}
+
+// ParseReceptionReports returns the reception report blocks of the sender
+// and receiver reports in a compound RTCP packet. Other packet types are
+// skipped.
+func ParseReceptionReports(b []byte) ([]ReceptionReport, error) {
+	var reports []ReceptionReport
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return reports, ErrShortPacket
+		}
+		if b[0]>>6 != Version {
+			return reports, ErrBadVersion
+		}
+		count, pt := int(b[0]&0x1f), b[1]
+		size := (int(binary.BigEndian.Uint16(b[2:])) + 1) * 4
+		if len(b) < size {
+			return reports, ErrShortPacket
+		}
+		var blocks []byte
+		switch pt {
+		case RTCPSenderReport:
+			blocks = b[min(28, size):size]
+		case RTCPReceiverReport:
+			blocks = b[min(8, size):size]
+		}
+		if blocks != nil {
+			if len(blocks) < count*24 {
+				return reports, ErrShortPacket
+			}
+			for i := range count {
+				reports = append(reports, parseReportBlock(blocks[i*24:]))
+			}
+		}
+		b = b[size:]
+	}
+	return reports, nil
+}
+
+func parseReportBlock(b []byte) ReceptionReport {
+	lost := int32(binary.BigEndian.Uint32(b[4:])<<8) >> 8 // #nosec G115 -- sign-extends the 24-bit field
+	return ReceptionReport{
+		SSRC:             binary.BigEndian.Uint32(b),
+		FractionLost:     b[4],
+		CumulativeLost:   lost,
+		HighestSequence:  binary.BigEndian.Uint32(b[8:]),
+		Jitter:           binary.BigEndian.Uint32(b[12:]),
+		LastSR:           binary.BigEndian.Uint32(b[16:]),
+		DelaySinceLastSR: binary.BigEndian.Uint32(b[20:]),
+	}
+}
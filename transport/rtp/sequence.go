@@ -0,0 +1,128 @@
+package rtp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// Sender assigns sequence numbers, timestamps, and the SSRC for an
+// outgoing RTP stream. It is not safe for concurrent use.
+type Sender struct {
+	payloadType uint8
+	ssrc        uint32
+	seq         uint16
+	timestamp   uint32
+	marker      bool
+}
+
+// NewSender creates a sender with a random SSRC, initial sequence number,
+// and initial timestamp, as recommended by RFC 3550.
+func NewSender(payloadType uint8) *Sender {
+	var b [10]byte
+	_, _ = rand.Read(b[:])
+	return &Sender{
+		payloadType: payloadType,
+		ssrc:        binary.BigEndian.Uint32(b[0:]),
+		seq:         binary.BigEndian.Uint16(b[4:]),
+		timestamp:   binary.BigEndian.Uint32(b[6:]),
+		marker:      true,
+	}
+}
+
+// SSRC returns the stream's synchronization source.
+func (s *Sender) SSRC() uint32 { return s.ssrc }
+
+// SetPayloadType changes the payload type of subsequent packets.
+func (s *Sender) SetPayloadType(pt uint8) { s.payloadType = pt }
+
+// Packetize wraps payload in the next RTP packet and advances the
+// timestamp by samples (in clock-rate units).
+func (s *Sender) Packetize(payload []byte, samples uint32) *Packet {
+	p := &Packet{
+		Header: Header{
+			Marker:         s.marker,
+			PayloadType:    s.payloadType,
+			SequenceNumber: s.seq,
+			Timestamp:      s.timestamp,
+			SSRC:           s.ssrc,
+		},
+		Payload: payload,
+	}
+	s.marker = false
+	s.seq++
+	s.timestamp += samples
+	return p
+}
+
+// Skip advances the timestamp without sending, e.g. during silence or hold,
+// and marks the next packet as the start of a talkspurt.
+func (s *Sender) Skip(samples uint32) {
+	s.timestamp += samples
+	s.marker = true
+}
+
+// SequenceTracker extends 16-bit RTP sequence numbers to monotonically
+// increasing 64-bit values across wraparound, suitable for ordering in a
+// jitter buffer, and counts loss and reordering. It is not safe for
+// concurrent use.
+type SequenceTracker struct {
+	started  bool
+	cycles   uint64
+	maxSeq   uint16
+	baseSeq  uint64
+	received uint64
+	reorder  uint64
+}
+
+// Update records a received sequence number and returns its extended value.
+func (t *SequenceTracker) Update(seq uint16) uint64 {
+	t.received++
+	if !t.started {
+		t.started = true
+		t.maxSeq = seq
+		t.baseSeq = uint64(seq)
+		return uint64(seq)
+	}
+	delta := seq - t.maxSeq
+	switch {
+	case delta == 0:
+		return t.cycles | uint64(seq)
+	case delta < 0x8000:
+		// In order (possibly with a gap).
+		if seq < t.maxSeq {
+			t.cycles += 1 << 16
+		}
+		t.maxSeq = seq
+		return t.cycles | uint64(seq)
+	default:
+		// Late or reordered packet from before maxSeq.
+		t.reorder++
+		cycles := t.cycles
+		if seq > t.maxSeq && cycles > 0 {
+			cycles -= 1 << 16
+		}
+		return cycles | uint64(seq)
+	}
+}
+
+// Expected returns the number of packets expected so far.
+func (t *SequenceTracker) Expected() uint64 {
+	if !t.started {
+		return 0
+	}
+	return (t.cycles | uint64(t.maxSeq)) - t.baseSeq + 1
+}
+
+// Lost returns the number of packets not received so far.
+func (t *SequenceTracker) Lost() uint64 {
+	if exp := t.Expected(); exp > t.received {
+		return exp - t.received
+	}
+	return 0
+}
+
+// Reordered returns the number of packets that arrived out of order.
+func (t *SequenceTracker) Reordered() uint64 { return t.reorder }
+
+// Received returns the number of packets received.
+func (t *SequenceTracker) Received() uint64 { return t.received }
@@ -0,0 +1,237 @@
+package rtp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 -- HMAC-SHA1 is mandated by the SRTP profile
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// SRTP key material sizes for the AES_CM_128_HMAC_SHA1_80 profile.
+const (
+	SRTPMasterKeyLen  = 16
+	SRTPMasterSaltLen = 14
+	srtpAuthKeyLen    = 20
+	srtpAuthTagLen    = 10
+
+	// srtpReplayWindow is the number of packet indices, up to the highest
+	// received, that Unprotect remembers (RFC 3711 section 3.3.2).
+	srtpReplayWindow = 64
+)
+
+// SRTP key derivation labels (RFC 3711 section 4.3.1).
+const (
+	labelEncryption = 0x00
+	labelAuth       = 0x01
+	labelSalt       = 0x02
+)
+
+// SRTP protects or unprotects RTP packets using the
+// AES_CM_128_HMAC_SHA1_80 profile (RFC 3711), the default SDES-keyed
+// profile for SIP and WebRTC. Use one SRTP per direction: a context created
+// for sending tracks rollover counters for its own SSRCs and must not be
+// shared with the receive path. Unprotect rejects packets it has already
+// accepted, or older than its replay window, with ErrReplayed. SRTCP is
+// not implemented.
+type SRTP struct {
+	mu      sync.Mutex
+	block   cipher.Block
+	salt    []byte
+	authKey []byte
+	streams map[uint32]*srtpStream
+}
+
+type srtpStream struct {
+	started bool
+	roc     uint32
+	lastSeq uint16
+
+	// window has bit i set if the packet i indices before the highest
+	// received, roc and lastSeq, was accepted.
+	window uint64
+}
+
+// NewSRTP derives session keys from a 16-byte master key and 14-byte
+// master salt (as exchanged in SDP a=crypto inline parameters).
+func NewSRTP(masterKey, masterSalt []byte) (*SRTP, error) {
+	if len(masterKey) != SRTPMasterKeyLen || len(masterSalt) != SRTPMasterSaltLen {
+		return nil, fmt.Errorf("%w: need %d-byte key and %d-byte salt", ErrInvalidKey, SRTPMasterKeyLen, SRTPMasterSaltLen)
+	}
+	master, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(deriveKey(master, masterSalt, labelEncryption, SRTPMasterKeyLen))
+	if err != nil {
+		return nil, err
+	}
+	return &SRTP{
+		block:   block,
+		salt:    deriveKey(master, masterSalt, labelSalt, SRTPMasterSaltLen),
+		authKey: deriveKey(master, masterSalt, labelAuth, srtpAuthKeyLen),
+		streams: make(map[uint32]*srtpStream),
+	}, nil
+}
+
+// deriveKey implements the AES-CM key derivation function with a key
+// derivation rate of zero.
+func deriveKey(master cipher.Block, salt []byte, label byte, n int) []byte {
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, salt)
+	iv[7] ^= label
+	out := make([]byte, n)
+	cipher.NewCTR(master, iv).XORKeyStream(out, out)
+	return out
+}
+
+// Protect encrypts and authenticates a marshaled RTP packet, returning a
+// new SRTP packet.
+func (s *SRTP) Protect(packet []byte) ([]byte, error) {
+	hl, err := headerLen(packet)
+	if err != nil {
+		return nil, err
+	}
+	seq := binary.BigEndian.Uint16(packet[2:])
+	ssrc := binary.BigEndian.Uint32(packet[8:])
+
+	s.mu.Lock()
+	st := s.stream(ssrc)
+	if st.started && seq < st.lastSeq && st.lastSeq-seq > 0x8000 {
+		st.roc++
+	}
+	st.started, st.lastSeq = true, seq
+	roc := st.roc
+	s.mu.Unlock()
+
+	out := make([]byte, len(packet), len(packet)+srtpAuthTagLen)
+	copy(out, packet)
+	s.crypt(out[hl:], ssrc, roc, seq)
+	return append(out, s.tag(out, roc)...), nil
+}
+
+// Unprotect authenticates and decrypts an SRTP packet, returning the plain
+// RTP packet. Packets failing authentication return ErrAuthFailed, and
+// replayed ones ErrReplayed.
+func (s *SRTP) Unprotect(packet []byte) ([]byte, error) {
+	if len(packet) < HeaderSize+srtpAuthTagLen {
+		return nil, ErrShortPacket
+	}
+	body, tag := packet[:len(packet)-srtpAuthTagLen], packet[len(packet)-srtpAuthTagLen:]
+	hl, err := headerLen(body)
+	if err != nil {
+		return nil, err
+	}
+	seq := binary.BigEndian.Uint16(body[2:])
+	ssrc := binary.BigEndian.Uint32(body[8:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.stream(ssrc)
+	roc := st.estimateROC(seq)
+	index := packetIndex(roc, seq)
+	if st.replayed(index) {
+		return nil, ErrReplayed
+	}
+	if !hmac.Equal(tag, s.tag(body, roc)) {
+		return nil, ErrAuthFailed
+	}
+
+	out := append([]byte(nil), body...)
+	s.crypt(out[hl:], ssrc, roc, seq)
+	st.accept(index)
+	return out, nil
+}
+
+// packetIndex returns the 48-bit SRTP packet index.
+func packetIndex(roc uint32, seq uint16) uint64 {
+	return uint64(roc)<<16 | uint64(seq)
+}
+
+// replayed reports whether the packet at index was already accepted or
+// is older than the replay window.
+func (st *srtpStream) replayed(index uint64) bool {
+	if !st.started {
+		return false
+	}
+	highest := packetIndex(st.roc, st.lastSeq)
+	if index > highest {
+		return false
+	}
+	d := highest - index
+	return d >= srtpReplayWindow || st.window&(1<<d) != 0
+}
+
+// accept records an authenticated packet at index.
+func (st *srtpStream) accept(index uint64) {
+	highest := packetIndex(st.roc, st.lastSeq)
+	switch {
+	case !st.started:
+		st.started, st.window = true, 1
+	case index > highest:
+		if d := index - highest; d < srtpReplayWindow {
+			st.window = st.window<<d | 1
+		} else {
+			st.window = 1
+		}
+	default:
+		st.window |= 1 << (highest - index)
+		return
+	}
+	st.roc, st.lastSeq = uint32(index>>16), uint16(index) // #nosec G115 -- index is roc<<16 | seq
+}
+
+func (s *SRTP) stream(ssrc uint32) *srtpStream {
+	st, ok := s.streams[ssrc]
+	if !ok {
+		st = &srtpStream{}
+		s.streams[ssrc] = st
+	}
+	return st
+}
+
+// estimateROC guesses the rollover counter for seq (RFC 3711 appendix A).
+func (st *srtpStream) estimateROC(seq uint16) uint32 {
+	if !st.started {
+		return st.roc
+	}
+	if st.lastSeq < 0x8000 {
+		if seq > st.lastSeq && seq-st.lastSeq > 0x8000 && st.roc > 0 {
+			return st.roc - 1
+		}
+		return st.roc
+	}
+	if st.lastSeq-0x8000 > seq {
+		return st.roc + 1
+	}
+	return st.roc
+}
+
+// crypt applies the AES-CM keystream for a packet index to payload.
+func (s *SRTP) crypt(payload []byte, ssrc, roc uint32, seq uint16) {
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, s.salt)
+	var ssrcBytes [4]byte
+	binary.BigEndian.PutUint32(ssrcBytes[:], ssrc)
+	for i := range ssrcBytes {
+		iv[4+i] ^= ssrcBytes[i]
+	}
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], uint64(roc)<<16|uint64(seq))
+	for i := 0; i < 6; i++ {
+		iv[8+i] ^= index[2+i]
+	}
+	cipher.NewCTR(s.block, iv).XORKeyStream(payload, payload)
+}
+
+// tag computes the truncated HMAC-SHA1 authentication tag.
+func (s *SRTP) tag(authenticated []byte, roc uint32) []byte {
+	mac := hmac.New(sha1.New, s.authKey)
+	mac.Write(authenticated)
+	var rocBytes [4]byte
+	binary.BigEndian.PutUint32(rocBytes[:], roc)
+	mac.Write(rocBytes[:])
+	return mac.Sum(nil)[:srtpAuthTagLen]
+}
@@ -0,0 +1,175 @@
+package rtp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestSRTPKeystream checks the AES-CM keystream against RFC 3711
+// appendix B.2.
+func TestSRTPKeystream(t *testing.T) {
+	block, err := aes.NewCipher(unhex(t, "2B7E151628AED2A6ABF7158809CF4F3C"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &SRTP{block: block, salt: unhex(t, "F0F1F2F3F4F5F6F7F8F9FAFBFCFD")}
+	keystream := make([]byte, 3*aes.BlockSize)
+	s.crypt(keystream, 0, 0, 0)
+	want := unhex(t, "E03EAD0935C95E80E166B16DD92B4EB4"+
+		"D23513162B02D0F72A43A2FE4A5F97AB"+
+		"41E95B3BB0A2E8DD477901E4FCA894C0")
+	if !bytes.Equal(keystream, want) {
+		t.Errorf("keystream %X, want %X", keystream, want)
+	}
+}
+
+// TestSRTPKeyDerivation checks session key derivation against RFC 3711
+// appendix B.3.
+func TestSRTPKeyDerivation(t *testing.T) {
+	masterKey := unhex(t, "E1F97A0D3E018BE0D64FA32C06DE4139")
+	masterSalt := unhex(t, "0EC675AD498AFEEBB6960B3AABE6")
+	master, err := aes.NewCipher(masterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		label byte
+		want  string
+	}{
+		{"cipher key", labelEncryption, "C61E7A93744F39EE10734AFE3FF7A087"},
+		{"cipher salt", labelSalt, "30CBBC08863D8C85D49DB34A9AE1"},
+		{"auth key", labelAuth, "CEBE321F6FF7716B6FD4AB49AF256A156D38BAA4"},
+	}
+	for _, tt := range tests {
+		want := unhex(t, tt.want)
+		if got := deriveKey(master, masterSalt, tt.label, len(want)); !bytes.Equal(got, want) {
+			t.Errorf("%s %X, want %X", tt.name, got, want)
+		}
+	}
+
+	s, err := NewSRTP(masterKey, masterSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.salt, unhex(t, tests[1].want)) || !bytes.Equal(s.authKey, unhex(t, tests[2].want)) {
+		t.Errorf("NewSRTP derived salt %X and auth key %X", s.salt, s.authKey)
+	}
+	if _, err := NewSRTP(masterKey[:15], masterSalt); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("NewSRTP with a short key = %v, want ErrInvalidKey", err)
+	}
+}
+
+// srtpPair returns the sending and receiving contexts of one stream.
+func srtpPair(t *testing.T) (send, recv *SRTP) {
+	t.Helper()
+	key := bytes.Repeat([]byte{0x11}, SRTPMasterKeyLen)
+	salt := bytes.Repeat([]byte{0x22}, SRTPMasterSaltLen)
+	send, err := NewSRTP(key, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recv, err = NewSRTP(key, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return send, recv
+}
+
+func rtpPacket(seq uint16, payload string) []byte {
+	p := Packet{Header: Header{PayloadType: 0, SequenceNumber: seq, Timestamp: uint32(seq) * 160, SSRC: 0xCAFEBABE}, Payload: []byte(payload)}
+	return p.Marshal()
+}
+
+func TestSRTPRoundTrip(t *testing.T) {
+	send, recv := srtpPair(t)
+	// Across a sequence number wrap, so the rollover counter advances.
+	for _, seq := range []uint16{65533, 65534, 65535, 0, 1} {
+		plain := rtpPacket(seq, "hello, caller")
+		protected, err := send.Protect(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(protected) != len(plain)+srtpAuthTagLen {
+			t.Fatalf("protected %d bytes, want %d", len(protected), len(plain)+srtpAuthTagLen)
+		}
+		if bytes.Contains(protected, []byte("hello")) {
+			t.Fatalf("payload of packet %d not encrypted", seq)
+		}
+		got, err := recv.Unprotect(protected)
+		if err != nil {
+			t.Fatalf("packet %d: %v", seq, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("packet %d unprotected to %X, want %X", seq, got, plain)
+		}
+	}
+}
+
+func TestSRTPRejectsTampered(t *testing.T) {
+	send, recv := srtpPair(t)
+	protected, err := send.Protect(rtpPacket(7, "hello, caller"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{2, HeaderSize, len(protected) - 1} {
+		tampered := bytes.Clone(protected)
+		tampered[i] ^= 0x01
+		if _, err := recv.Unprotect(tampered); !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("byte %d flipped: %v, want ErrAuthFailed", i, err)
+		}
+	}
+	if _, err := recv.Unprotect(protected[:HeaderSize]); !errors.Is(err, ErrShortPacket) {
+		t.Errorf("short packet: %v, want ErrShortPacket", err)
+	}
+	if _, err := recv.Unprotect(protected); err != nil {
+		t.Errorf("genuine packet rejected after tampered copies: %v", err)
+	}
+}
+
+func TestSRTPRejectsReplayed(t *testing.T) {
+	send, recv := srtpPair(t)
+	packets := make(map[uint16][]byte)
+	for seq := uint16(1); seq <= 100; seq++ {
+		p, err := send.Protect(rtpPacket(seq, "audio"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets[seq] = p
+	}
+
+	steps := []struct {
+		seq uint16
+		err error
+	}{
+		{1, nil},
+		{1, ErrReplayed},
+		{5, nil},
+		{3, nil}, // late, within the window
+		{3, ErrReplayed},
+		{5, ErrReplayed},
+		{100, nil},
+		{40, nil},
+		{36, ErrReplayed}, // older than the window
+		{2, ErrReplayed},
+		{100, ErrReplayed},
+	}
+	for _, step := range steps {
+		_, err := recv.Unprotect(packets[step.seq])
+		if !errors.Is(err, step.err) || (step.err == nil && err != nil) {
+			t.Errorf("packet %d: %v, want %v", step.seq, err, step.err)
+		}
+	}
+}
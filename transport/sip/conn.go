@@ -0,0 +1,563 @@
+package sip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+	"github.com/agentplexus/omnivoice/transport"
+	"github.com/agentplexus/omnivoice/transport/rtp"
+)
+
+// g711FrameSamples is 20ms of 8kHz audio, the PCMU/PCMA packetization.
+const g711FrameSamples = 160
+
+// qualityInterval is how often EventQuality is emitted while audio
+// flows.
+const qualityInterval = 5 * time.Second
+
+// opusFrameTicks is the RTP timestamp increment for a 20ms Opus packet.
+const opusFrameTicks = 960
+
+// dialog holds the SIP dialog state of a call.
+type dialog struct {
+	localURI  string
+	remoteURI string
+	localTag  string
+	remoteTag string
+	target    string
+	routes    []string
+	signal    *net.UDPAddr
+	cseq      uint32
+	remoteSeq uint32
+	inbound   bool
+}
+
+// inviteTx is an INVITE server transaction: its request, the last
+// response sent, repeated when the request is retransmitted, and whether
+// that response is final. acked closes on the ACK of the final response.
+type inviteTx struct {
+	req      *message
+	seq      uint32
+	response *message
+	final    bool
+	acked    chan struct{}
+}
+
+func newInviteTx(req *message) *inviteTx {
+	seq, _ := req.cseq()
+	return &inviteTx{req: req, seq: seq, acked: make(chan struct{})}
+}
+
+// Connection is an established SIP call implementing transport.Connection.
+type Connection struct {
+	t        *Transport
+	id       string
+	rtp      *net.UDPConn
+	encoding string
+
+	mu          sync.Mutex
+	dialog      dialog
+	remoteRTP   *net.UDPAddr
+	codec       string
+	payloadType int
+	eventType   int
+	held        bool
+	sender      *rtp.Sender
+	pendingPCM  []byte
+	audioActive bool
+	dtmf        *transport.DTMFDetector
+	quality     *transport.QualityMonitor
+	invite      *inviteTx
+	// unacked holds the INVITE transactions awaiting the ACK of their
+	// final response, by CSeq.
+	unacked map[uint32]*inviteTx
+
+	events   chan transport.Event
+	outR     *io.PipeReader
+	outW     *io.PipeWriter
+	closed   chan struct{}
+	shutOnce sync.Once
+}
+
+func newConnection(t *Transport, id string, media *net.UDPConn, config transport.Config) *Connection {
+	r, w := io.Pipe()
+	return &Connection{
+		t:         t,
+		id:        id,
+		rtp:       media,
+		encoding:  config.Encoding,
+		sender:    rtp.NewSender(0),
+		eventType: -1,
+		dtmf:      transport.NewDTMFDetector(8000),
+		quality:   transport.NewQualityMonitor(8000),
+		events:    make(chan transport.Event, 32),
+		outR:      r,
+		outW:      w,
+		closed:    make(chan struct{}),
+	}
+}
+
+// ID returns the SIP Call-ID.
+func (c *Connection) ID() string { return c.id }
+
+// AudioIn returns a writer for sending audio to the remote party.
+func (c *Connection) AudioIn() io.WriteCloser { return (*rtpWriter)(c) }
+
+// AudioOut returns a reader for audio received from the remote party.
+func (c *Connection) AudioOut() io.Reader { return c.outR }
+
+// Events returns the connection event channel. Events are dropped rather
+// than blocking signaling if the consumer falls behind.
+func (c *Connection) Events() <-chan transport.Event { return c.events }
+
+// QualityStats returns call-quality measurements of the received audio,
+// derived from RTP sequence numbers and timestamps. RoundTripTime is not
+// measured.
+func (c *Connection) QualityStats() transport.QualityStats {
+	c.mu.Lock()
+	q := c.quality
+	c.mu.Unlock()
+	return q.Stats()
+}
+
+// RemoteAddr returns the remote RTP address.
+func (c *Connection) RemoteAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remoteRTP
+}
+
+// Close hangs up the call with BYE and releases media resources.
+func (c *Connection) Close() error {
+	c.shutdown(true)
+	return nil
+}
+
+func (c *Connection) emit(ev transport.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	select {
+	case c.events <- ev:
+	default:
+	}
+}
+
+// setMedia applies a remote SDP description, negotiating the codec.
+func (c *Connection) setMedia(remote media, preferred []string) error {
+	codec, pt, eventPT, err := negotiate(remote, preferred)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if codec != c.codec {
+		if cd, ok := rtp.CodecByName(codec); ok {
+			c.quality = transport.NewQualityMonitor(cd.ClockRate())
+		}
+	}
+	c.codec, c.payloadType, c.eventType = codec, pt, eventPT
+	c.sender.SetPayloadType(uint8(pt)) // #nosec G115 -- payload types are 7-bit
+	if !remote.addr.IP.IsUnspecified() {
+		c.remoteRTP = remote.addr
+	}
+	return nil
+}
+
+func (c *Connection) start() {
+	go c.readRTP()
+	c.emit(transport.Event{Type: transport.EventConnected, Data: c.dialog.remoteURI})
+}
+
+// inDialogRequest builds a request within the call's dialog.
+func (c *Connection) inDialogRequest(method string, cseq uint32) *message {
+	d := c.dialog
+	req := newRequest(method, d.target)
+	req.add("Max-Forwards", "70")
+	for _, r := range d.routes {
+		req.add("Route", r)
+	}
+	req.add("From", fmt.Sprintf("<%s>;tag=%s", d.localURI, d.localTag))
+	to := fmt.Sprintf("<%s>", d.remoteURI)
+	if d.remoteTag != "" {
+		to += ";tag=" + d.remoteTag
+	}
+	req.add("To", to)
+	req.add("Call-ID", c.id)
+	req.add("CSeq", fmt.Sprintf("%d %s", cseq, method))
+	return req
+}
+
+// respond sends a response in an INVITE transaction, remembering it for
+// retransmissions of the request. Once a final response has been sent,
+// respond sends nothing more and reports false.
+func (c *Connection) respond(tx *inviteTx, resp *message, src *net.UDPAddr) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.respondLocked(tx, resp, src)
+}
+
+func (c *Connection) respondLocked(tx *inviteTx, resp *message, src *net.UDPAddr) bool {
+	if tx.final {
+		return false
+	}
+	tx.response, tx.final = resp, resp.status >= 200
+	if tx.final {
+		if c.unacked == nil {
+			c.unacked = make(map[uint32]*inviteTx)
+		}
+		c.unacked[tx.seq] = tx
+	}
+	_ = c.t.send(resp, src)
+	return true
+}
+
+// answer sends 200 OK with an SDP answer to an inbound INVITE, returning
+// nil if the transaction already ended, e.g. on CANCEL.
+func (c *Connection) answer(tx *inviteTx, src *net.UDPAddr) *message {
+	ip, port := c.t.localAddr(src)
+	rtpPort := c.rtp.LocalAddr().(*net.UDPAddr).Port
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req := tx.req
+	ok := newResponse(req, 200, "OK")
+	ok.set("To", c.localTo(req))
+	for _, rr := range req.getAll("Record-Route") {
+		ok.add("Record-Route", rr)
+	}
+	ok.add("Contact", fmt.Sprintf("<sip:omnivoice@%s:%d>", ip, port))
+	ok.add("User-Agent", c.t.config.UserAgent)
+	ok.add("Content-Type", "application/sdp")
+	direction := "sendrecv"
+	if c.held {
+		direction = "recvonly"
+	}
+	ok.body = buildSDP(ip, rtpPort, []string{c.codec}, true, direction)
+	if !c.respondLocked(tx, ok, src) {
+		return nil
+	}
+	return ok
+}
+
+// reject ends a call still being set up with a final error response to
+// its INVITE, keeping it until the response is acknowledged so that
+// retransmissions of the INVITE get the response again.
+func (c *Connection) reject(tx *inviteTx, resp *message, src *net.UDPAddr) {
+	if c.respond(tx, resp, src) {
+		go c.closeAfterAck(tx, resp, src)
+	}
+}
+
+func (c *Connection) closeAfterAck(tx *inviteTx, resp *message, src *net.UDPAddr) {
+	c.retransmitUntilAck(tx, resp, src)
+	c.shutdown(false)
+}
+
+// localTo returns the To header of a response to req, carrying the
+// dialog's local tag. c.mu must be held.
+func (c *Connection) localTo(req *message) string {
+	to := req.get("To")
+	if headerParam(to, "tag") != "" {
+		return to
+	}
+	return to + ";tag=" + c.dialog.localTag
+}
+
+// retransmitUntilAck retransmits a final response to INVITE until the ACK
+// arrives. A call whose 2xx goes unacknowledged is hung up.
+func (c *Connection) retransmitUntilAck(tx *inviteTx, resp *message, src *net.UDPAddr) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.unacked, tx.seq)
+		c.mu.Unlock()
+	}()
+	interval := timerT1
+	deadline := time.After(timerB)
+	for {
+		select {
+		case <-tx.acked:
+			return
+		case <-c.closed:
+			return
+		case <-deadline:
+			c.shutdown(resp.status < 300)
+			return
+		case <-time.After(interval):
+			_ = c.t.send(resp, src)
+			interval = min(interval*2, timerT2)
+		}
+	}
+}
+
+// acknowledge handles an ACK, ending the INVITE transaction it
+// acknowledges.
+func (c *Connection) acknowledge(req *message) {
+	seq, _ := req.cseq()
+	c.mu.Lock()
+	tx := c.unacked[seq]
+	delete(c.unacked, seq)
+	c.mu.Unlock()
+	if tx != nil {
+		close(tx.acked)
+	}
+}
+
+// handleInvite processes an INVITE within the call: a retransmission of
+// the current transaction's request gets its last response again, and a
+// new CSeq is a re-INVITE.
+func (c *Connection) handleInvite(req *message, src *net.UDPAddr) {
+	seq, _ := req.cseq()
+	c.mu.Lock()
+	tx, last := c.invite, c.dialog.remoteSeq
+	if tx != nil && seq == tx.seq {
+		resp := tx.response
+		c.mu.Unlock()
+		if resp != nil {
+			_ = c.t.send(resp, src)
+		}
+		return
+	}
+	if seq <= last {
+		c.mu.Unlock()
+		_ = c.t.send(newResponse(req, 500, "Server Internal Error"), src)
+		return
+	}
+	tx = newInviteTx(req)
+	c.invite, c.dialog.remoteSeq = tx, seq
+	c.mu.Unlock()
+	c.handleReinvite(tx, src)
+}
+
+// handleCancel handles CANCEL of the call's INVITE. A transaction still
+// without a final response is answered 487, ending the call; otherwise
+// CANCEL has no effect.
+func (c *Connection) handleCancel(req *message, src *net.UDPAddr) {
+	seq, _ := req.cseq()
+	c.mu.Lock()
+	tx := c.invite
+	if tx == nil || tx.seq != seq {
+		c.mu.Unlock()
+		_ = c.t.send(newResponse(req, 481, "Call/Transaction Does Not Exist"), src)
+		return
+	}
+	terminated := newResponse(tx.req, 487, "Request Terminated")
+	terminated.set("To", c.localTo(tx.req))
+	c.mu.Unlock()
+	_ = c.t.send(newResponse(req, 200, "OK"), src)
+	c.reject(tx, terminated, src)
+}
+
+// handleReinvite processes a mid-call INVITE, mapping hold and resume to
+// transport events.
+func (c *Connection) handleReinvite(tx *inviteTx, src *net.UDPAddr) {
+	remote, err := parseSDP(tx.req.body)
+	if err != nil {
+		_ = c.respond(tx, newResponse(tx.req, 488, "Not Acceptable Here"), src)
+		return
+	}
+
+	c.mu.Lock()
+	wasHeld := c.held
+	c.held = remote.onHold()
+	if !remote.addr.IP.IsUnspecified() {
+		c.remoteRTP = remote.addr
+	}
+	held := c.held
+	c.mu.Unlock()
+
+	ok := c.answer(tx, src)
+	if ok == nil {
+		return
+	}
+	go c.retransmitUntilAck(tx, ok, src)
+
+	switch {
+	case held && !wasHeld:
+		c.emit(transport.Event{Type: transport.EventHold})
+	case !held && wasHeld:
+		c.emit(transport.Event{Type: transport.EventResume})
+	}
+}
+
+// remoteHangup ends the call after BYE from the remote party.
+func (c *Connection) remoteHangup() {
+	c.shutdown(false)
+}
+
+// shutdown releases the call, sending BYE first when sendBye is true.
+func (c *Connection) shutdown(sendBye bool) {
+	c.shutOnce.Do(func() {
+		if sendBye && c.dialog.signal != nil {
+			c.mu.Lock()
+			c.dialog.cseq++
+			bye := c.inDialogRequest("BYE", c.dialog.cseq)
+			dst := c.dialog.signal
+			c.mu.Unlock()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_, _ = c.t.transact(ctx, bye, dst)
+			cancel()
+		}
+		c.t.untrack(c)
+		_ = c.rtp.Close()
+		_ = c.outW.Close()
+		c.mu.Lock()
+		select {
+		case c.events <- transport.Event{Type: transport.EventDisconnected}:
+		default:
+		}
+		close(c.closed)
+		close(c.events)
+		c.mu.Unlock()
+	})
+}
+
+// readRTP receives RTP packets and feeds decoded audio to AudioOut.
+func (c *Connection) readRTP() {
+	buf := make([]byte, 1500)
+	var pkt rtp.Packet
+	var reported time.Time
+	for {
+		n, src, err := c.rtp.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if pkt.Unmarshal(buf[:n]) != nil {
+			continue
+		}
+		pt, payload := int(pkt.PayloadType), pkt.Payload
+
+		c.mu.Lock()
+		expected, eventType, codec, quality := c.payloadType, c.eventType, c.codec, c.quality
+		// Symmetric RTP: follow the address media actually arrives from.
+		if c.remoteRTP == nil || !c.remoteRTP.IP.Equal(src.IP) || c.remoteRTP.Port != src.Port {
+			c.remoteRTP = src
+		}
+		first := !c.audioActive
+		c.audioActive = true
+		c.mu.Unlock()
+
+		// Accept events under the remote's payload type or the one we
+		// declared, since peers differ in which they send.
+		if eventType >= 0 && (pt == eventType || pt == int(rtp.PayloadTelephoneEvent)) {
+			if d, ok := c.dtmf.ProcessTelephoneEvent(pkt.Timestamp, payload); ok {
+				c.emit(transport.Event{Type: transport.EventDTMF, Data: d})
+			}
+			continue
+		}
+		if pt != expected {
+			continue
+		}
+		if first {
+			c.emit(transport.Event{Type: transport.EventAudioStarted})
+		}
+		now := time.Now()
+		quality.Packet(pkt.SequenceNumber, pkt.Timestamp, now)
+		if reported.IsZero() {
+			reported = now
+		} else if now.Sub(reported) >= qualityInterval {
+			reported = now
+			c.emit(transport.Event{Type: transport.EventQuality, Data: quality.Stats()})
+		}
+		if _, err := c.outW.Write(c.decode(codec, payload)); err != nil {
+			return
+		}
+	}
+}
+
+// decode converts a payload for AudioOut, running in-band DTMF detection
+// on G.711 audio along the way.
+func (c *Connection) decode(name string, payload []byte) []byte {
+	codec, ok := rtp.CodecByName(name)
+	if !ok {
+		return append([]byte(nil), payload...)
+	}
+	pcm, err := codec.Decode(payload)
+	if err != nil {
+		return append([]byte(nil), payload...)
+	}
+	for _, d := range c.dtmf.Process(audio.BytesToInt16(pcm)) {
+		c.emit(transport.Event{Type: transport.EventDTMF, Data: d})
+	}
+	if c.encoding == "pcm" {
+		return pcm
+	}
+	return append([]byte(nil), payload...)
+}
+
+// rtpWriter packetizes audio written to AudioIn into RTP.
+type rtpWriter Connection
+
+var errOpusPCM = errors.New("sip: opus payloads must be pre-encoded; use a non-pcm Encoding")
+
+// Write sends audio to the remote party. With "pcm" encoding, audio is
+// buffered into 20ms frames and G.711 encoded; otherwise each Write of
+// Opus is one packet and G.711 payloads are framed at 160 bytes.
+func (w *rtpWriter) Write(p []byte) (int, error) {
+	c := (*Connection)(w)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	if c.codec == CodecOpus {
+		if c.encoding == "pcm" {
+			return 0, errOpusPCM
+		}
+		return len(p), c.sendLocked(p, opusFrameTicks)
+	}
+
+	frameBytes := g711FrameSamples
+	if c.encoding == "pcm" {
+		frameBytes *= audio.BytesPerSample
+	}
+	c.pendingPCM = append(c.pendingPCM, p...)
+	for len(c.pendingPCM) >= frameBytes {
+		frame := c.pendingPCM[:frameBytes]
+		c.pendingPCM = c.pendingPCM[frameBytes:]
+		payload := frame
+		if c.encoding == "pcm" {
+			codec, _ := rtp.CodecByName(c.codec)
+			var err error
+			if payload, err = codec.Encode(frame); err != nil {
+				return 0, err
+			}
+		}
+		if err := c.sendLocked(payload, g711FrameSamples); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes nothing; partial frames are discarded. The call stays up
+// until Connection.Close.
+func (w *rtpWriter) Close() error {
+	c := (*Connection)(w)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pendingPCM = nil
+	return nil
+}
+
+func (c *Connection) sendLocked(payload []byte, ticks uint32) error {
+	if c.held || c.remoteRTP == nil {
+		c.sender.Skip(ticks)
+		return nil
+	}
+	_, err := c.rtp.WriteToUDP(c.sender.Packetize(payload, ticks).Marshal(), c.remoteRTP)
+	return err
+}
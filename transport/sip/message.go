@@ -0,0 +1,236 @@
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errMalformed is returned when a SIP message cannot be parsed.
+var errMalformed = errors.New("sip: malformed message")
+
+// compactHeaders maps RFC 3261 compact header forms to their full names.
+var compactHeaders = map[string]string{
+	"v": "Via",
+	"f": "From",
+	"t": "To",
+	"i": "Call-ID",
+	"m": "Contact",
+	"l": "Content-Length",
+	"c": "Content-Type",
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+// message is a SIP request or response.
+type message struct {
+	// method and uri are set for requests.
+	method string
+	uri    string
+
+	// status and reason are set for responses.
+	status int
+	reason string
+
+	headers []header
+	body    []byte
+}
+
+func newRequest(method, uri string) *message {
+	return &message{method: method, uri: uri}
+}
+
+func (m *message) isRequest() bool {
+	return m.method != ""
+}
+
+func canonicalHeader(name string) string {
+	if full, ok := compactHeaders[strings.ToLower(name)]; ok {
+		return full
+	}
+	return name
+}
+
+// get returns the first value of the named header.
+func (m *message) get(name string) string {
+	name = canonicalHeader(name)
+	for _, h := range m.headers {
+		if strings.EqualFold(h.name, name) {
+			return h.value
+		}
+	}
+	return ""
+}
+
+// getAll returns every value of the named header.
+func (m *message) getAll(name string) []string {
+	name = canonicalHeader(name)
+	var values []string
+	for _, h := range m.headers {
+		if strings.EqualFold(h.name, name) {
+			values = append(values, h.value)
+		}
+	}
+	return values
+}
+
+// add appends a header.
+func (m *message) add(name, value string) {
+	m.headers = append(m.headers, header{name: name, value: value})
+}
+
+// set replaces all values of the named header.
+func (m *message) set(name, value string) {
+	m.del(name)
+	m.add(name, value)
+}
+
+// del removes all values of the named header.
+func (m *message) del(name string) {
+	kept := m.headers[:0]
+	for _, h := range m.headers {
+		if !strings.EqualFold(h.name, name) {
+			kept = append(kept, h)
+		}
+	}
+	m.headers = kept
+}
+
+// cseq returns the CSeq number and method.
+func (m *message) cseq() (uint32, string) {
+	fields := strings.Fields(m.get("CSeq"))
+	if len(fields) != 2 {
+		return 0, ""
+	}
+	n, _ := strconv.ParseUint(fields[0], 10, 32)
+	return uint32(n), fields[1]
+}
+
+// bytes serializes the message, setting Content-Length.
+func (m *message) bytes() []byte {
+	var b bytes.Buffer
+	if m.isRequest() {
+		fmt.Fprintf(&b, "%s %s SIP/2.0\r\n", m.method, m.uri)
+	} else {
+		fmt.Fprintf(&b, "SIP/2.0 %d %s\r\n", m.status, m.reason)
+	}
+	for _, h := range m.headers {
+		if strings.EqualFold(h.name, "Content-Length") {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\r\n", h.name, h.value)
+	}
+	fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n", len(m.body))
+	b.Write(m.body)
+	return b.Bytes()
+}
+
+// parseMessage parses a datagram into a SIP message.
+func parseMessage(data []byte) (*message, error) {
+	head, body, _ := bytes.Cut(data, []byte("\r\n\r\n"))
+	lines := strings.Split(string(head), "\r\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return nil, errMalformed
+	}
+
+	m := &message{}
+	start := strings.SplitN(lines[0], " ", 3)
+	if len(start) < 3 {
+		return nil, fmt.Errorf("%w: bad start line %q", errMalformed, lines[0])
+	}
+	if strings.HasPrefix(start[0], "SIP/") {
+		status, err := strconv.Atoi(start[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad status %q", errMalformed, start[1])
+		}
+		m.status, m.reason = status, start[2]
+	} else {
+		m.method, m.uri = start[0], start[1]
+	}
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		// Header folding: continuation lines start with whitespace.
+		if (line[0] == ' ' || line[0] == '\t') && len(m.headers) > 0 {
+			m.headers[len(m.headers)-1].value += " " + strings.TrimSpace(line)
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: bad header %q", errMalformed, line)
+		}
+		m.add(canonicalHeader(strings.TrimSpace(name)), strings.TrimSpace(value))
+	}
+
+	if cl := m.get("Content-Length"); cl != "" {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 || n > len(body) {
+			return nil, fmt.Errorf("%w: bad Content-Length %q", errMalformed, cl)
+		}
+		body = body[:n]
+	}
+	m.body = append([]byte(nil), body...)
+	return m, nil
+}
+
+// newResponse builds a response to req, copying the dialog headers.
+func newResponse(req *message, status int, reason string) *message {
+	resp := &message{status: status, reason: reason}
+	for _, v := range req.getAll("Via") {
+		resp.add("Via", v)
+	}
+	resp.add("From", req.get("From"))
+	resp.add("To", req.get("To"))
+	resp.add("Call-ID", req.get("Call-ID"))
+	resp.add("CSeq", req.get("CSeq"))
+	return resp
+}
+
+// headerParam returns a ;-separated parameter from a header value,
+// e.g. headerParam(`<sip:a@b>;tag=xyz`, "tag") returns "xyz".
+func headerParam(value, name string) string {
+	// Skip any bracketed URI so URI parameters are not matched.
+	if i := strings.IndexByte(value, '>'); i >= 0 {
+		value = value[i+1:]
+	}
+	for _, p := range strings.Split(value, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if strings.EqualFold(k, name) {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// headerURI extracts the URI from a name-addr header value such as
+// `"Alice" <sip:alice@example.com>;tag=1`.
+func headerURI(value string) string {
+	if i := strings.IndexByte(value, '<'); i >= 0 {
+		if j := strings.IndexByte(value[i:], '>'); j >= 0 {
+			return value[i+1 : i+j]
+		}
+	}
+	uri, _, _ := strings.Cut(value, ";")
+	return strings.TrimSpace(uri)
+}
+
+// uriHostPort returns the host:port of a SIP URI, defaulting to port 5060.
+func uriHostPort(uri string) string {
+	uri = strings.TrimPrefix(strings.TrimPrefix(uri, "sips:"), "sip:")
+	if i := strings.IndexByte(uri, '@'); i >= 0 {
+		uri = uri[i+1:]
+	}
+	uri, _, _ = strings.Cut(uri, ";")
+	uri, _, _ = strings.Cut(uri, "?")
+	if !strings.Contains(uri, ":") {
+		uri += ":5060"
+	}
+	return uri
+}
@@ -0,0 +1,164 @@
+package sip
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentplexus/omnivoice/transport/rtp"
+)
+
+// Codec names understood by the SIP transport.
+const (
+	CodecPCMU = "pcmu"
+	CodecPCMA = "pcma"
+	CodecOpus = "opus"
+)
+
+// media is the negotiated audio description of one side of a call.
+type media struct {
+	addr      *net.UDPAddr
+	payloads  []int
+	rtpmap    map[int]string
+	direction string
+}
+
+// codec returns the codec name for a payload type in this description.
+func (m media) codec(pt int) string {
+	if name, ok := m.rtpmap[pt]; ok {
+		enc, _, _ := strings.Cut(name, "/")
+		return strings.ToLower(enc)
+	}
+	if c, ok := rtp.CodecByPayloadType(uint8(pt)); ok && pt < 96 { // #nosec G115 -- payload types are 7-bit
+		return c.Name()
+	}
+	return ""
+}
+
+// onHold reports whether the description places the stream on hold.
+func (m media) onHold() bool {
+	return m.direction == "sendonly" || m.direction == "inactive" ||
+		(m.addr != nil && m.addr.IP.IsUnspecified())
+}
+
+// buildSDP creates an SDP body offering or answering with codecs.
+func buildSDP(ip net.IP, port int, codecs []string, telephoneEvent bool, direction string) []byte {
+	id := strconv.FormatInt(time.Now().Unix(), 10)
+	var pts []string
+	var attrs []string
+	for _, name := range codecs {
+		c, ok := rtp.CodecByName(name)
+		if !ok {
+			continue
+		}
+		pts = append(pts, strconv.Itoa(int(c.PayloadType())))
+		attrs = append(attrs, fmt.Sprintf("a=rtpmap:%d %s", c.PayloadType(), rtp.RTPMap(c)))
+	}
+	if telephoneEvent {
+		pts = append(pts, strconv.Itoa(int(rtp.PayloadTelephoneEvent)))
+		attrs = append(attrs,
+			fmt.Sprintf("a=rtpmap:%d telephone-event/8000", rtp.PayloadTelephoneEvent),
+			fmt.Sprintf("a=fmtp:%d 0-16", rtp.PayloadTelephoneEvent))
+	}
+	if direction == "" {
+		direction = "sendrecv"
+	}
+	lines := []string{
+		"v=0",
+		fmt.Sprintf("o=omnivoice %s %s IN IP4 %s", id, id, ip),
+		"s=omnivoice",
+		fmt.Sprintf("c=IN IP4 %s", ip),
+		"t=0 0",
+		fmt.Sprintf("m=audio %d RTP/AVP %s", port, strings.Join(pts, " ")),
+	}
+	lines = append(lines, attrs...)
+	lines = append(lines, "a=ptime:20", "a="+direction, "")
+	return []byte(strings.Join(lines, "\r\n"))
+}
+
+// parseSDP extracts the first audio stream from an SDP body.
+func parseSDP(body []byte) (media, error) {
+	m := media{rtpmap: make(map[int]string), direction: "sendrecv"}
+	var ip net.IP
+	var port int
+	inAudio := false
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+		value := line[2:]
+		switch line[0] {
+		case 'c':
+			if fields := strings.Fields(value); len(fields) == 3 {
+				ip = net.ParseIP(fields[2])
+			}
+		case 'm':
+			fields := strings.Fields(value)
+			inAudio = len(fields) >= 4 && fields[0] == "audio"
+			if !inAudio {
+				continue
+			}
+			port, _ = strconv.Atoi(fields[1])
+			for _, f := range fields[3:] {
+				if pt, err := strconv.Atoi(f); err == nil {
+					m.payloads = append(m.payloads, pt)
+				}
+			}
+		case 'a':
+			if !inAudio && !isDirection(value) {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(value, "rtpmap:"):
+				ptStr, name, _ := strings.Cut(strings.TrimPrefix(value, "rtpmap:"), " ")
+				if pt, err := strconv.Atoi(ptStr); err == nil {
+					m.rtpmap[pt] = name
+				}
+			case isDirection(value):
+				m.direction = value
+			}
+		}
+	}
+	if ip == nil || port == 0 || len(m.payloads) == 0 {
+		return m, fmt.Errorf("sip: SDP has no usable audio stream")
+	}
+	m.addr = &net.UDPAddr{IP: ip, Port: port}
+	return m, nil
+}
+
+func isDirection(v string) bool {
+	switch v {
+	case "sendrecv", "sendonly", "recvonly", "inactive":
+		return true
+	}
+	return false
+}
+
+// negotiate picks the first preferred codec the remote offers, returning
+// its name and payload type, and the telephone-event payload type or -1 if
+// telephone-events are not offered.
+func negotiate(remote media, preferred []string) (string, int, int, error) {
+	offered := make(map[string]int)
+	telephoneEvent := -1
+	for _, pt := range remote.payloads {
+		name := remote.codec(pt)
+		if name == "telephone-event" {
+			if telephoneEvent < 0 {
+				telephoneEvent = pt
+			}
+			continue
+		}
+		if _, ok := offered[name]; !ok && name != "" {
+			offered[name] = pt
+		}
+	}
+	for _, c := range preferred {
+		if pt, ok := offered[c]; ok {
+			return c, pt, telephoneEvent, nil
+		}
+	}
+	return "", 0, -1, fmt.Errorf("sip: no common codec (remote offered %v)", remote.payloads)
+}
@@ -0,0 +1,705 @@
+// Package sip implements transport.SIPTransport over UDP.
+//
+// It provides a minimal SIP user agent sufficient for connecting voice
+// agents to SIP trunks and PBXs: REGISTER with digest authentication,
+// inbound INVITE handling via OnInvite, outbound INVITE, SDP negotiation of
+// G.711 (PCMU/PCMA) and Opus, re-INVITE hold/resume, and BYE. Negotiated
+// RTP audio is exposed through transport.Connection.
+//
+// When the audio Encoding is "pcm", G.711 payloads are transcoded to and
+// from 16-bit 8kHz linear PCM. Any other Encoding passes codec payloads
+// through unchanged (Opus always passes through, one packet per Write).
+//
+// Limitations: UDP only (no TCP/TLS), no ICE, and no forking of outbound
+// INVITEs.
+package sip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/transport"
+)
+
+// SIP timer values from RFC 3261.
+const (
+	timerT1 = 500 * time.Millisecond
+	timerT2 = 4 * time.Second
+	timerB  = 64 * timerT1
+)
+
+var (
+	// ErrCallRejected is returned when the remote party rejects an INVITE.
+	ErrCallRejected = errors.New("sip: call rejected")
+
+	// ErrTimeout is returned when a SIP transaction receives no response.
+	ErrTimeout = errors.New("sip: transaction timed out")
+
+	// ErrClosed is returned when the transport has been closed.
+	ErrClosed = errors.New("sip: transport closed")
+)
+
+// Config configures a SIP transport.
+type Config struct {
+	// ListenAddr is the local signaling address bound by Register or Invite
+	// when Listen has not been called. Defaults to ":0".
+	ListenAddr string
+
+	// LocalIP is the address advertised in Contact and SDP. Detected from
+	// the route to the peer when empty.
+	LocalIP string
+
+	// UserAgent is the User-Agent header value. Defaults to "omnivoice".
+	UserAgent string
+
+	// Codecs is the codec preference order. Defaults to PCMU, PCMA, Opus.
+	Codecs []string
+
+	// Audio is the default audio configuration for connections.
+	Audio transport.Config
+
+	// RegisterExpires is the requested registration lifetime.
+	// Defaults to one hour.
+	RegisterExpires time.Duration
+}
+
+// Transport is a SIP user agent implementing transport.SIPTransport.
+type Transport struct {
+	config Config
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	pending  map[string]chan *message
+	calls    map[string]*Connection
+	onInvite func(conn transport.Connection, from string) bool
+	incoming chan transport.Connection
+	reg      *registration
+
+	deliveries sync.WaitGroup
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+type registration struct {
+	server   *net.UDPAddr
+	domain   string
+	aor      string
+	username string
+	password string
+	callID   string
+	cseq     uint32
+}
+
+// New creates a SIP transport.
+func New(config Config) *Transport {
+	if config.ListenAddr == "" {
+		config.ListenAddr = ":0"
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "omnivoice"
+	}
+	if len(config.Codecs) == 0 {
+		config.Codecs = []string{CodecPCMU, CodecPCMA, CodecOpus}
+	}
+	if config.RegisterExpires <= 0 {
+		config.RegisterExpires = time.Hour
+	}
+	return &Transport{
+		config:  config,
+		pending: make(map[string]chan *message),
+		calls:   make(map[string]*Connection),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Name returns the transport name.
+func (t *Transport) Name() string { return "sip" }
+
+// Protocol returns the protocol type.
+func (t *Transport) Protocol() string { return "sip" }
+
+// Listen binds the signaling socket to addr and returns a channel of
+// inbound calls accepted by the OnInvite handler (all calls are accepted
+// when no handler is set).
+func (t *Transport) Listen(ctx context.Context, addr string) (<-chan transport.Connection, error) {
+	if err := t.bind(addr); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.incoming == nil {
+		t.incoming = make(chan transport.Connection, 8)
+	}
+	return t.incoming, nil
+}
+
+// Connect places an outbound call to a SIP URI.
+func (t *Transport) Connect(ctx context.Context, addr string, config transport.Config) (transport.Connection, error) {
+	return t.invite(ctx, addr, config)
+}
+
+// OnInvite sets the incoming INVITE handler. Returning false rejects the
+// call with 603 Decline.
+func (t *Transport) OnInvite(handler func(conn transport.Connection, from string) bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onInvite = handler
+}
+
+// Close hangs up all calls, unregisters, and closes the signaling socket.
+func (t *Transport) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		calls := make([]*Connection, 0, len(t.calls))
+		for _, c := range t.calls {
+			calls = append(calls, c)
+		}
+		reg := t.reg
+		t.mu.Unlock()
+
+		for _, c := range calls {
+			_ = c.Close()
+		}
+		if reg != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			_, _ = t.register(ctx, reg, 0)
+			cancel()
+		}
+
+		t.mu.Lock()
+		close(t.closed)
+		t.mu.Unlock()
+		t.deliveries.Wait()
+
+		t.mu.Lock()
+		if t.conn != nil {
+			err = t.conn.Close()
+		}
+		if t.incoming != nil {
+			close(t.incoming)
+		}
+		t.mu.Unlock()
+	})
+	return err
+}
+
+// bind opens the signaling socket if it is not already open.
+func (t *Transport) bind(addr string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.closed:
+		return ErrClosed
+	default:
+	}
+	if t.conn != nil {
+		return nil
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	go t.readLoop(conn)
+	return nil
+}
+
+func (t *Transport) readLoop(conn *net.UDPConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		msg, err := parseMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		if msg.isRequest() {
+			go t.handleRequest(msg, src)
+			continue
+		}
+		branch := headerParam(msg.get("Via"), "branch")
+		t.mu.Lock()
+		ch := t.pending[branch]
+		t.mu.Unlock()
+		if ch != nil {
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+	}
+}
+
+func (t *Transport) send(m *message, dst *net.UDPAddr) error {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return ErrClosed
+	}
+	_, err := conn.WriteToUDP(m.bytes(), dst)
+	return err
+}
+
+// localAddr returns the address to advertise to dst.
+func (t *Transport) localAddr(dst *net.UDPAddr) (net.IP, int) {
+	t.mu.Lock()
+	port := t.conn.LocalAddr().(*net.UDPAddr).Port
+	t.mu.Unlock()
+	if ip := net.ParseIP(t.config.LocalIP); ip != nil {
+		return ip, port
+	}
+	if c, err := net.DialUDP("udp", nil, dst); err == nil {
+		defer c.Close()
+		return c.LocalAddr().(*net.UDPAddr).IP, port
+	}
+	return net.IPv4(127, 0, 0, 1), port
+}
+
+// setVia sets the top Via header with a fresh branch and returns it.
+func (t *Transport) setVia(req *message, dst *net.UDPAddr) string {
+	ip, port := t.localAddr(dst)
+	branch := "z9hG4bK" + randomHex(8)
+	via := fmt.Sprintf("SIP/2.0/UDP %s:%d;branch=%s;rport", ip, port, branch)
+	for i, h := range req.headers {
+		if strings.EqualFold(h.name, "Via") {
+			req.headers[i].value = via
+			return branch
+		}
+	}
+	req.headers = append([]header{{name: "Via", value: via}}, req.headers...)
+	return branch
+}
+
+// transact sends a request and waits for its final response, retransmitting
+// per RFC 3261 over UDP. Non-2xx final responses to INVITE are ACKed.
+func (t *Transport) transact(ctx context.Context, req *message, dst *net.UDPAddr) (*message, error) {
+	branch := t.setVia(req, dst)
+	ch := make(chan *message, 8)
+	t.mu.Lock()
+	t.pending[branch] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, branch)
+		t.mu.Unlock()
+	}()
+
+	if err := t.send(req, dst); err != nil {
+		return nil, err
+	}
+
+	interval := timerT1
+	retransmit := time.NewTimer(interval)
+	defer retransmit.Stop()
+	timeout := time.NewTimer(timerB)
+	defer timeout.Stop()
+	provisional := false
+
+	for {
+		select {
+		case resp := <-ch:
+			if resp.status < 200 {
+				provisional = true
+				if req.method == "INVITE" {
+					// Ringing may last arbitrarily long; rely on ctx.
+					timeout.Stop()
+				}
+				continue
+			}
+			if req.method == "INVITE" && resp.status >= 300 {
+				_ = t.send(ackFor(req, resp), dst)
+			}
+			return resp, nil
+		case <-retransmit.C:
+			if !provisional {
+				_ = t.send(req, dst)
+				interval = min(interval*2, timerT2)
+			}
+			retransmit.Reset(interval)
+		case <-timeout.C:
+			return nil, ErrTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.closed:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// authTransact runs transact and, on a 401/407 challenge, retries once with
+// digest credentials.
+func (t *Transport) authTransact(ctx context.Context, req *message, dst *net.UDPAddr, username, password string) (*message, error) {
+	resp, err := t.transact(ctx, req, dst)
+	if err != nil || username == "" || (resp.status != 401 && resp.status != 407) {
+		return resp, err
+	}
+	challengeHeader, authHeader := "WWW-Authenticate", "Authorization"
+	if resp.status == 407 {
+		challengeHeader, authHeader = "Proxy-Authenticate", "Proxy-Authorization"
+	}
+	challenge, err := parseChallenge(resp.get(challengeHeader))
+	if err != nil {
+		return nil, err
+	}
+	n, method := req.cseq()
+	req.set("CSeq", fmt.Sprintf("%d %s", n+1, method))
+	req.set(authHeader, challenge.authorization(req.method, req.uri, username, password))
+	return t.transact(ctx, req, dst)
+}
+
+// ackFor builds the ACK for a non-2xx final response (same transaction).
+func ackFor(req, resp *message) *message {
+	ack := newRequest("ACK", req.uri)
+	ack.add("Via", req.get("Via"))
+	ack.add("Max-Forwards", "70")
+	ack.add("From", req.get("From"))
+	ack.add("To", resp.get("To"))
+	ack.add("Call-ID", req.get("Call-ID"))
+	n, _ := req.cseq()
+	ack.add("CSeq", fmt.Sprintf("%d ACK", n))
+	return ack
+}
+
+// Register registers with a SIP registrar using digest authentication and
+// keeps the registration refreshed until Close.
+func (t *Transport) Register(ctx context.Context, server, username, password string) error {
+	if err := t.bind(t.config.ListenAddr); err != nil {
+		return err
+	}
+	dst, err := net.ResolveUDPAddr("udp", uriHostPort(server))
+	if err != nil {
+		return err
+	}
+	domain, _, _ := strings.Cut(uriHostPort(server), ":")
+	reg := &registration{
+		server:   dst,
+		domain:   domain,
+		aor:      fmt.Sprintf("sip:%s@%s", username, domain),
+		username: username,
+		password: password,
+		callID:   randomHex(16),
+	}
+	expires, err := t.register(ctx, reg, t.config.RegisterExpires)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.reg = reg
+	t.mu.Unlock()
+	go t.refreshRegistration(reg, expires)
+	return nil
+}
+
+// register sends a REGISTER and returns the granted expiry.
+func (t *Transport) register(ctx context.Context, reg *registration, expires time.Duration) (time.Duration, error) {
+	ip, port := t.localAddr(reg.server)
+	reg.cseq++
+	req := newRequest("REGISTER", "sip:"+reg.domain)
+	req.add("Max-Forwards", "70")
+	req.add("From", fmt.Sprintf("<%s>;tag=%s", reg.aor, randomHex(4)))
+	req.add("To", fmt.Sprintf("<%s>", reg.aor))
+	req.add("Call-ID", reg.callID)
+	req.add("CSeq", fmt.Sprintf("%d REGISTER", reg.cseq))
+	req.add("Contact", fmt.Sprintf("<sip:%s@%s:%d>", reg.username, ip, port))
+	req.add("Expires", fmt.Sprintf("%d", int(expires.Seconds())))
+	req.add("User-Agent", t.config.UserAgent)
+
+	resp, err := t.authTransact(ctx, req, reg.server, reg.username, reg.password)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := req.cseq()
+	reg.cseq = n
+	if resp.status < 200 || resp.status >= 300 {
+		return 0, fmt.Errorf("sip: register failed: %d %s", resp.status, resp.reason)
+	}
+	granted := expires
+	if v := headerParam(resp.get("Contact"), "expires"); v != "" {
+		if d, err := time.ParseDuration(v + "s"); err == nil {
+			granted = d
+		}
+	} else if v := resp.get("Expires"); v != "" {
+		if d, err := time.ParseDuration(v + "s"); err == nil {
+			granted = d
+		}
+	}
+	return granted, nil
+}
+
+func (t *Transport) refreshRegistration(reg *registration, expires time.Duration) {
+	for {
+		wait := max(expires*4/5, time.Second)
+		select {
+		case <-t.closed:
+			return
+		case <-time.After(wait):
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timerB)
+		granted, err := t.register(ctx, reg, t.config.RegisterExpires)
+		cancel()
+		if err == nil {
+			expires = granted
+		} else {
+			expires = 30 * time.Second
+		}
+	}
+}
+
+// Invite places an outbound call to a SIP URI using the default audio
+// configuration.
+func (t *Transport) Invite(ctx context.Context, uri string) (transport.Connection, error) {
+	return t.invite(ctx, uri, t.config.Audio)
+}
+
+func (t *Transport) invite(ctx context.Context, uri string, audioConfig transport.Config) (*Connection, error) {
+	if err := t.bind(t.config.ListenAddr); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(uri, "sip:") && !strings.HasPrefix(uri, "sips:") {
+		uri = "sip:" + uri
+	}
+
+	t.mu.Lock()
+	reg := t.reg
+	t.mu.Unlock()
+
+	var dst *net.UDPAddr
+	var err error
+	var username, password string
+	if reg != nil {
+		dst, username, password = reg.server, reg.username, reg.password
+	} else if dst, err = net.ResolveUDPAddr("udp", uriHostPort(uri)); err != nil {
+		return nil, err
+	}
+
+	ip, port := t.localAddr(dst)
+	rtpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: 0})
+	if err != nil {
+		return nil, err
+	}
+	rtpPort := rtpConn.LocalAddr().(*net.UDPAddr).Port
+
+	localURI := fmt.Sprintf("sip:omnivoice@%s", ip)
+	if reg != nil {
+		localURI = reg.aor
+	}
+	c := newConnection(t, randomHex(16), rtpConn, audioConfig)
+	c.dialog = dialog{
+		localURI:  localURI,
+		remoteURI: uri,
+		localTag:  randomHex(4),
+		target:    uri,
+		signal:    dst,
+		cseq:      1,
+	}
+
+	req := newRequest("INVITE", uri)
+	req.add("Max-Forwards", "70")
+	req.add("From", fmt.Sprintf("<%s>;tag=%s", localURI, c.dialog.localTag))
+	req.add("To", fmt.Sprintf("<%s>", uri))
+	req.add("Call-ID", c.id)
+	req.add("CSeq", "1 INVITE")
+	req.add("Contact", fmt.Sprintf("<sip:omnivoice@%s:%d>", ip, port))
+	req.add("User-Agent", t.config.UserAgent)
+	req.add("Allow", "INVITE, ACK, BYE, CANCEL, OPTIONS")
+	req.add("Content-Type", "application/sdp")
+	req.body = buildSDP(ip, rtpPort, t.config.Codecs, true, "")
+
+	resp, err := t.authTransact(ctx, req, dst, username, password)
+	if err != nil {
+		rtpConn.Close()
+		return nil, err
+	}
+	if resp.status >= 300 {
+		rtpConn.Close()
+		return nil, fmt.Errorf("%w: %d %s", ErrCallRejected, resp.status, resp.reason)
+	}
+
+	remote, err := parseSDP(resp.body)
+	if err == nil {
+		err = c.setMedia(remote, t.config.Codecs)
+	}
+	c.dialog.remoteTag = headerParam(resp.get("To"), "tag")
+	if contact := headerURI(resp.get("Contact")); contact != "" {
+		c.dialog.target = contact
+	}
+	rr := resp.getAll("Record-Route")
+	for i := len(rr) - 1; i >= 0; i-- {
+		c.dialog.routes = append(c.dialog.routes, rr[i])
+	}
+	n, _ := req.cseq()
+	c.dialog.cseq = n
+	_ = t.send(c.inDialogRequest("ACK", n), dst)
+
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	t.track(c)
+	c.start()
+	return c, nil
+}
+
+func (t *Transport) track(c *Connection) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls[c.id] = c
+}
+
+// trackNew tracks c unless a call with its Call-ID already is, returning
+// that call instead.
+func (t *Transport) trackNew(c *Connection) *Connection {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if other := t.calls[c.id]; other != nil {
+		return other
+	}
+	t.calls[c.id] = c
+	return nil
+}
+
+func (t *Transport) untrack(c *Connection) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.calls, c.id)
+}
+
+func (t *Transport) handleRequest(req *message, src *net.UDPAddr) {
+	t.mu.Lock()
+	c := t.calls[req.get("Call-ID")]
+	t.mu.Unlock()
+
+	switch req.method {
+	case "INVITE":
+		if c != nil {
+			c.handleInvite(req, src)
+			return
+		}
+		t.handleInvite(req, src)
+	case "ACK":
+		if c != nil {
+			c.acknowledge(req)
+		}
+	case "BYE":
+		if c == nil {
+			_ = t.send(newResponse(req, 481, "Call/Transaction Does Not Exist"), src)
+			return
+		}
+		_ = t.send(newResponse(req, 200, "OK"), src)
+		c.remoteHangup()
+	case "CANCEL":
+		if c == nil {
+			_ = t.send(newResponse(req, 481, "Call/Transaction Does Not Exist"), src)
+			return
+		}
+		c.handleCancel(req, src)
+	case "OPTIONS":
+		resp := newResponse(req, 200, "OK")
+		resp.add("Allow", "INVITE, ACK, BYE, CANCEL, OPTIONS")
+		_ = t.send(resp, src)
+	default:
+		_ = t.send(newResponse(req, 501, "Not Implemented"), src)
+	}
+}
+
+// handleInvite answers an INVITE starting a call. The call is tracked
+// from the start, so that retransmissions of the INVITE reach its
+// transaction rather than starting another call.
+func (t *Transport) handleInvite(req *message, src *net.UDPAddr) {
+	rtpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: 0})
+	if err != nil {
+		_ = t.send(newResponse(req, 500, "Server Internal Error"), src)
+		return
+	}
+	c := newConnection(t, req.get("Call-ID"), rtpConn, t.config.Audio)
+	n, _ := req.cseq()
+	tx := newInviteTx(req)
+	c.invite = tx
+	c.dialog = dialog{
+		localURI:  headerURI(req.get("To")),
+		remoteURI: headerURI(req.get("From")),
+		localTag:  randomHex(4),
+		remoteTag: headerParam(req.get("From"), "tag"),
+		target:    headerURI(req.get("Contact")),
+		signal:    src,
+		remoteSeq: n,
+		inbound:   true,
+	}
+	c.dialog.routes = req.getAll("Record-Route")
+	if c.dialog.target == "" {
+		c.dialog.target = c.dialog.remoteURI
+	}
+	if other := t.trackNew(c); other != nil {
+		// A retransmission raced the first copy here.
+		rtpConn.Close()
+		other.handleInvite(req, src)
+		return
+	}
+	c.respond(tx, newResponse(req, 100, "Trying"), src)
+
+	remote, err := parseSDP(req.body)
+	if err == nil {
+		err = c.setMedia(remote, t.config.Codecs)
+	}
+	if err != nil {
+		c.reject(tx, newResponse(req, 488, "Not Acceptable Here"), src)
+		return
+	}
+
+	ringing := newResponse(req, 180, "Ringing")
+	ringing.set("To", req.get("To")+";tag="+c.dialog.localTag)
+	if !c.respond(tx, ringing, src) {
+		return
+	}
+
+	t.mu.Lock()
+	handler := t.onInvite
+	t.mu.Unlock()
+	if handler != nil && !handler(c, c.dialog.remoteURI) {
+		decline := newResponse(req, 603, "Decline")
+		decline.set("To", ringing.get("To"))
+		c.reject(tx, decline, src)
+		return
+	}
+
+	ok := c.answer(tx, src)
+	if ok == nil {
+		// Canceled while the handler decided.
+		return
+	}
+	c.start()
+	go c.retransmitUntilAck(tx, ok, src)
+
+	t.mu.Lock()
+	incoming := t.incoming
+	select {
+	case <-t.closed:
+		incoming = nil
+	default:
+		if incoming != nil {
+			t.deliveries.Add(1)
+		}
+	}
+	t.mu.Unlock()
+	if incoming != nil {
+		defer t.deliveries.Done()
+		select {
+		case incoming <- c:
+		case <-t.closed:
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package sip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/transport"
+)
+
+// peer is the remote user agent of a test call, speaking raw SIP.
+type peer struct {
+	t      *testing.T
+	conn   *net.UDPConn
+	server *net.UDPAddr
+}
+
+// listen starts a transport with handler and a peer to call it.
+func listen(t *testing.T, handler func(transport.Connection, string) bool) (*Transport, *peer) {
+	t.Helper()
+	tr := New(Config{LocalIP: "127.0.0.1"})
+	tr.OnInvite(handler)
+	incoming, err := tr.Listen(context.Background(), "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range incoming {
+		}
+	}()
+	t.Cleanup(func() { _ = tr.Close() })
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	tr.mu.Lock()
+	server := tr.conn.LocalAddr().(*net.UDPAddr)
+	tr.mu.Unlock()
+	return tr, &peer{t: t, conn: conn, server: server}
+}
+
+func (p *peer) request(method string, seq uint32, callID string) *message {
+	addr := p.conn.LocalAddr().(*net.UDPAddr)
+	req := newRequest(method, "sip:agent@127.0.0.1")
+	req.add("Via", fmt.Sprintf("SIP/2.0/UDP %s;branch=z9hG4bK%s%d", addr, callID, seq))
+	req.add("From", "<sip:caller@127.0.0.1>;tag=caller")
+	req.add("To", "<sip:agent@127.0.0.1>")
+	req.add("Call-ID", callID)
+	req.add("CSeq", fmt.Sprintf("%d %s", seq, method))
+	if method == "INVITE" {
+		req.add("Contact", fmt.Sprintf("<sip:caller@%s>", addr))
+		req.add("Content-Type", "application/sdp")
+		req.body = buildSDP(addr.IP, 40000, []string{"PCMU"}, true, "")
+	}
+	return req
+}
+
+func (p *peer) send(m *message) {
+	if _, err := p.conn.WriteToUDP(m.bytes(), p.server); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// responses collects the responses received within d.
+func (p *peer) responses(d time.Duration) []*message {
+	var got []*message
+	buf := make([]byte, 65535)
+	_ = p.conn.SetReadDeadline(time.Now().Add(d))
+	for {
+		n, err := p.conn.Read(buf)
+		if err != nil {
+			return got
+		}
+		if m, err := parseMessage(buf[:n]); err == nil && !m.isRequest() {
+			got = append(got, m)
+		}
+	}
+}
+
+// count returns how many responses have status to the given CSeq method.
+func count(responses []*message, status int, method string) int {
+	n := 0
+	for _, m := range responses {
+		if _, mth := m.cseq(); m.status == status && mth == method {
+			n++
+		}
+	}
+	return n
+}
+
+func TestInviteRetransmissionJoinsCall(t *testing.T) {
+	calls := make(chan struct{}, 4)
+	decide := make(chan struct{})
+	_, p := listen(t, func(transport.Connection, string) bool {
+		calls <- struct{}{}
+		<-decide
+		return true
+	})
+	invite := p.request("INVITE", 1, "retransmit")
+	p.send(invite)
+	<-calls
+	p.send(invite)
+	pending := p.responses(100 * time.Millisecond)
+	close(decide)
+	answered := p.responses(100 * time.Millisecond)
+	p.send(p.request("ACK", 1, "retransmit"))
+	p.send(p.request("BYE", 2, "retransmit"))
+	p.responses(50 * time.Millisecond)
+
+	if n := len(calls); n != 0 {
+		t.Errorf("handler called %d more times for the retransmission", n)
+	}
+	if count(pending, 200, "INVITE") != 0 {
+		t.Error("retransmission answered 200 OK before the handler accepted")
+	}
+	if n := count(pending, 180, "INVITE"); n != 2 {
+		t.Errorf("got %d 180 Ringing, want one per INVITE copy", n)
+	}
+	if count(answered, 200, "INVITE") != 1 {
+		t.Error("accepted INVITE not answered 200 OK")
+	}
+}
+
+func TestReinviteRetransmitsUntilAck(t *testing.T) {
+	_, p := listen(t, nil)
+	p.send(p.request("INVITE", 1, "reinvite"))
+	p.responses(100 * time.Millisecond)
+	p.send(p.request("ACK", 1, "reinvite"))
+
+	p.send(p.request("INVITE", 2, "reinvite"))
+	got := p.responses(timerT1 + 200*time.Millisecond)
+	if n := count(got, 200, "INVITE"); n < 2 {
+		t.Fatalf("re-INVITE 200 OK sent %d times before the ACK, want retransmissions", n)
+	}
+	p.send(p.request("ACK", 2, "reinvite"))
+	p.responses(50 * time.Millisecond)
+	if got := p.responses(2 * timerT1); count(got, 200, "INVITE") != 0 {
+		t.Error("200 OK retransmitted after the ACK")
+	}
+	p.send(p.request("BYE", 3, "reinvite"))
+	p.responses(50 * time.Millisecond)
+}
+
+func TestCancelTerminatesInvite(t *testing.T) {
+	deciding, decide := make(chan struct{}), make(chan struct{})
+	_, p := listen(t, func(transport.Connection, string) bool {
+		close(deciding)
+		<-decide
+		return true
+	})
+	p.send(p.request("INVITE", 1, "cancel"))
+	<-deciding
+	p.send(p.request("CANCEL", 1, "cancel"))
+	got := p.responses(100 * time.Millisecond)
+	close(decide)
+	got = append(got, p.responses(100*time.Millisecond)...)
+	p.send(p.request("ACK", 1, "cancel"))
+	p.responses(50 * time.Millisecond)
+
+	if count(got, 200, "CANCEL") != 1 {
+		t.Error("CANCEL not answered 200 OK")
+	}
+	if count(got, 487, "INVITE") == 0 {
+		t.Error("INVITE not answered 487 Request Terminated")
+	}
+	if count(got, 200, "INVITE") != 0 {
+		t.Error("canceled INVITE answered 200 OK")
+	}
+}
@@ -76,6 +76,21 @@ const (
 
 	// EventDTMF indicates DTMF tone received (telephony).
 	EventDTMF EventType = "dtmf"
+
+	// EventHold indicates the remote party placed the call on hold.
+	EventHold EventType = "hold"
+
+	// EventResume indicates the remote party resumed a held call.
+	EventResume EventType = "resume"
+
+	// EventQuality reports call-quality measurements periodically. Data
+	// is a QualityStats.
+	EventQuality EventType = "quality"
+
+	// EventListenStart and EventListenStop indicate the remote pressed
+	// and released push-to-talk; see agent.PushToTalker.
+	EventListenStart EventType = "listen_start"
+	EventListenStop  EventType = "listen_stop"
 )
 
 // Transport defines the interface for audio transport protocols.
@@ -0,0 +1,289 @@
+package tts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Abbreviation is one entry of an Abbreviations dictionary.
+type Abbreviation struct {
+	// Expansion is the text spoken in place of the abbreviation.
+	Expansion string
+
+	// AfterNumber expands the abbreviation only when it follows a number,
+	// as for units ("5 mg" but not "mg" on its own).
+	AfterNumber bool
+
+	// IgnoreCase matches regardless of letter case. By default matching
+	// is case-sensitive so that "BP" does not match "bp" in a URL.
+	IgnoreCase bool
+}
+
+// Abbreviations expands abbreviations and acronyms in TTS input. Entries
+// match whole words only, never inside a longer word. It changes what is
+// said, not how a word is pronounced, and runs before Normalize so that
+// expanded units are read with their numbers.
+type Abbreviations struct {
+	entries map[string]Abbreviation
+	keys    []string // longest first
+}
+
+// NewAbbreviations creates a dictionary from simple abbreviation to
+// expansion pairs.
+func NewAbbreviations(entries map[string]string) *Abbreviations {
+	a := &Abbreviations{entries: make(map[string]Abbreviation, len(entries))}
+	for k, v := range entries {
+		a.entries[k] = Abbreviation{Expansion: v}
+	}
+	a.index()
+	return a
+}
+
+// Set adds or replaces an entry.
+func (a *Abbreviations) Set(abbr string, entry Abbreviation) {
+	if a.entries == nil {
+		a.entries = make(map[string]Abbreviation)
+	}
+	a.entries[abbr] = entry
+	a.index()
+}
+
+// Merge adds every entry of other, replacing existing entries, and
+// returns a.
+func (a *Abbreviations) Merge(other *Abbreviations) *Abbreviations {
+	if a.entries == nil {
+		a.entries = make(map[string]Abbreviation)
+	}
+	for k, v := range other.entries {
+		a.entries[k] = v
+	}
+	a.index()
+	return a
+}
+
+// Len returns the number of entries.
+func (a *Abbreviations) Len() int {
+	return len(a.entries)
+}
+
+func (a *Abbreviations) index() {
+	a.keys = a.keys[:0]
+	for k := range a.entries {
+		a.keys = append(a.keys, k)
+	}
+	sort.Slice(a.keys, func(i, j int) bool {
+		if len(a.keys[i]) != len(a.keys[j]) {
+			return len(a.keys[i]) > len(a.keys[j])
+		}
+		return a.keys[i] < a.keys[j]
+	})
+}
+
+// Expand replaces abbreviations in text with their expansions. SSML
+// documents are returned unchanged.
+func (a *Abbreviations) Expand(text string) string {
+	if a == nil || len(a.keys) == 0 || isSSML(text) {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(text); {
+		// Units may attach directly to a number ("5mg"); anything else
+		// must start a word.
+		attached := i > 0 && unicode.IsDigit(lastRune(text[:i]))
+		if i > 0 && isWordRune(lastRune(text[:i])) && !attached {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		key, entry, ok := a.match(text, i)
+		if !ok || (attached && !entry.AfterNumber) {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		b.WriteString(text[last:i])
+		if attached {
+			b.WriteByte(' ')
+		}
+		b.WriteString(entry.Expansion)
+		i += len(key)
+		last = i
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// match returns the longest entry matching text at i as a whole word.
+func (a *Abbreviations) match(text string, i int) (string, Abbreviation, bool) {
+	for _, key := range a.keys {
+		if len(text)-i < len(key) {
+			continue
+		}
+		candidate := text[i : i+len(key)]
+		entry := a.entries[key]
+		if candidate != key && !(entry.IgnoreCase && strings.EqualFold(candidate, key)) {
+			continue
+		}
+		rest := text[i+len(key):]
+		if r, _ := utf8.DecodeRuneInString(rest); rest != "" && isWordRune(r) {
+			continue
+		}
+		// "B.P" should not match the start of "B.P.M."
+		if !strings.HasSuffix(key, ".") && strings.HasPrefix(rest, ".") && len(rest) > 1 && isWordRune(rune(rest[1])) {
+			continue
+		}
+		if entry.AfterNumber && !followsNumber(text[:i]) {
+			continue
+		}
+		return candidate, entry, true
+	}
+	return "", Abbreviation{}, false
+}
+
+func followsNumber(before string) bool {
+	before = strings.TrimRight(before, " \u00a0")
+	return before != "" && unicode.IsDigit(lastRune(before))
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ReadAbbreviations parses a dictionary, one entry per line:
+//
+//	# comment
+//	BP = blood pressure
+//	mg = milligrams @number
+//	stat = immediately @nocase
+//
+// The @number flag sets AfterNumber and @nocase sets IgnoreCase.
+func ReadAbbreviations(r io.Reader) (*Abbreviations, error) {
+	a := &Abbreviations{entries: make(map[string]Abbreviation)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		abbr, expansion, ok := strings.Cut(text, "=")
+		abbr, expansion = strings.TrimSpace(abbr), strings.TrimSpace(expansion)
+		if !ok || abbr == "" || expansion == "" {
+			return nil, fmt.Errorf("tts: abbreviations line %d: expected \"abbr = expansion\"", line)
+		}
+		var entry Abbreviation
+		for {
+			switch {
+			case strings.HasSuffix(expansion, "@number"):
+				entry.AfterNumber = true
+				expansion = strings.TrimSpace(strings.TrimSuffix(expansion, "@number"))
+				continue
+			case strings.HasSuffix(expansion, "@nocase"):
+				entry.IgnoreCase = true
+				expansion = strings.TrimSpace(strings.TrimSuffix(expansion, "@nocase"))
+				continue
+			}
+			break
+		}
+		entry.Expansion = expansion
+		a.entries[abbr] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	a.index()
+	return a, nil
+}
+
+// LoadAbbreviations reads a dictionary file in the ReadAbbreviations format.
+func LoadAbbreviations(path string) (*Abbreviations, error) {
+	f, err := os.Open(path) // #nosec G304 -- caller-supplied dictionary path
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadAbbreviations(f)
+}
+
+// MedicalAbbreviations returns a starter dictionary of common clinical
+// abbreviations and dosing units.
+func MedicalAbbreviations() *Abbreviations {
+	a := NewAbbreviations(map[string]string{
+		"BP":     "blood pressure",
+		"HR":     "heart rate",
+		"ECG":    "E C G",
+		"EKG":    "E K G",
+		"MRI":    "M R I",
+		"ER":     "emergency room",
+		"ICU":    "intensive care unit",
+		"Rx":     "prescription",
+		"Dx":     "diagnosis",
+		"Hx":     "history",
+		"PRN":    "as needed",
+		"prn":    "as needed",
+		"q.d.":   "once daily",
+		"b.i.d.": "twice daily",
+		"t.i.d.": "three times daily",
+		"q.i.d.": "four times daily",
+		"q.h.s.": "at bedtime",
+		"p.o.":   "by mouth",
+		"NPO":    "nothing by mouth",
+		"IV":     "intravenous",
+		"IM":     "intramuscular",
+	})
+	for abbr, unit := range map[string]string{
+		"mg":    "milligrams",
+		"mcg":   "micrograms",
+		"g":     "grams",
+		"kg":    "kilograms",
+		"mL":    "milliliters",
+		"ml":    "milliliters",
+		"L":     "liters",
+		"mmHg":  "millimeters of mercury",
+		"bpm":   "beats per minute",
+		"IU":    "international units",
+		"mg/dL": "milligrams per deciliter",
+	} {
+		a.Set(abbr, Abbreviation{Expansion: unit, AfterNumber: true})
+	}
+	return a
+}
+
+// FinanceAbbreviations returns a starter dictionary of common financial
+// abbreviations.
+func FinanceAbbreviations() *Abbreviations {
+	a := NewAbbreviations(map[string]string{
+		"APR":  "annual percentage rate",
+		"APY":  "annual percentage yield",
+		"ROI":  "return on investment",
+		"YTD":  "year to date",
+		"QoQ":  "quarter over quarter",
+		"YoY":  "year over year",
+		"EPS":  "earnings per share",
+		"P/E":  "price to earnings",
+		"IRA":  "I R A",
+		"ETF":  "E T F",
+		"CD":   "certificate of deposit",
+		"ACH":  "A C H",
+		"FDIC": "F D I C",
+		"acct": "account",
+		"bal":  "balance",
+		"pmt":  "payment",
+	})
+	a.Set("bps", Abbreviation{Expansion: "basis points", AfterNumber: true})
+	return a
+}
@@ -0,0 +1,395 @@
+package tts
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SentenceAggregator groups streamed LLM tokens into clauses worth sending
+// to TTS, so synthesis can start before the full response is generated.
+// Text is split after sentence-ending punctuation and, once MinClauseChars
+// have accumulated, after clause punctuation such as commas. Decimal
+// points and common abbreviations do not end a sentence.
+//
+// Sentence and clause marks of other scripts, such as 。！？、 in Chinese
+// and Japanese, ؟ and ، in Arabic, and । in Hindi, split text whatever the
+// Language, and need no space after them; closing quotes and brackets
+// after a sentence end stay with it. Language selects the rest: its
+// abbreviations, whether words are written without spaces, and, for a
+// language without sentence punctuation this aggregator knows, a split
+// by length (MaxClauseChars) as well as by time (MaxDelay).
+// It is not safe for concurrent use.
+type SentenceAggregator struct {
+	// Language is the BCP-47 language of the text (e.g., "ja-JP"), as
+	// SynthesisConfig.Language. Empty selects English.
+	Language string
+
+	// MinClauseChars is the minimum clause length, in characters, before
+	// splitting on clause punctuation. Defaults to 40, or 20 for a
+	// language written without spaces, such as Japanese.
+	MinClauseChars int
+
+	// ClausePunctuation are the ASCII characters that end a clause of at
+	// least MinClauseChars; a dash only counts with spaces around it.
+	// Defaults to DefaultClausePunctuation.
+	ClausePunctuation string
+
+	// SentencesOnly splits only at sentence ends and newlines, ignoring
+	// clause punctuation.
+	SentencesOnly bool
+
+	// MaxClauseChars, if positive, splits text that has run this many
+	// characters without a boundary at its last space, or where it is in
+	// a language written without spaces, so a run-on sentence does not
+	// hold back speech. Defaults to DefaultFallbackClauseChars for a
+	// language without known sentence punctuation.
+	MaxClauseChars int
+
+	// MaxDelay bounds how long Stream holds text waiting for a boundary:
+	// when no token completes a clause within it, the complete words so
+	// far are sent, keeping audio flowing while the LLM pauses. Defaults
+	// to DefaultMaxClauseDelay; negative waits for a boundary.
+	MaxDelay time.Duration
+
+	buf strings.Builder
+}
+
+// DefaultClausePunctuation is the default SentenceAggregator
+// ClausePunctuation.
+const DefaultClausePunctuation = ",;:-"
+
+// DefaultMaxClauseDelay is the default SentenceAggregator MaxDelay.
+const DefaultMaxClauseDelay = 300 * time.Millisecond
+
+// DefaultFallbackClauseChars is the SentenceAggregator MaxClauseChars
+// for a language without known sentence punctuation, such as Thai.
+const DefaultFallbackClauseChars = 100
+
+// abbreviations that end with a period without ending a sentence.
+var sentenceAbbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"jr": true, "sr": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"no": true, "approx": true, "inc": true, "ltd": true,
+}
+
+// languageAbbreviations are sentenceAbbreviations by primary language
+// subtag. Other languages only skip single-letter initials.
+var languageAbbreviations = map[string]map[string]bool{
+	"en": sentenceAbbreviations,
+	"es": {
+		"sr": true, "sra": true, "srta": true, "dr": true, "dra": true, "ud": true,
+		"uds": true, "prof": true, "lic": true, "ing": true, "etc": true, "av": true,
+		"aprox": true, "pág": true, "núm": true,
+	},
+	"pt": {
+		"sr": true, "sra": true, "dr": true, "dra": true, "prof": true, "etc": true,
+		"av": true, "pág": true,
+	},
+	"fr": {
+		"mme": true, "mlle": true, "dr": true, "me": true, "st": true, "ste": true,
+		"etc": true, "av": true, "env": true,
+	},
+	"de": {
+		"dr": true, "prof": true, "nr": true, "str": true, "bzw": true, "usw": true,
+		"ca": true, "z.b": true, "d.h": true, "evtl": true, "vgl": true,
+	},
+	"it": {
+		"sig": true, "sigg": true, "dott": true, "prof": true, "dr": true, "ecc": true,
+	},
+}
+
+// punctuatedLanguages are the primary language subtags, besides those of
+// languageAbbreviations and spacelessLanguages, whose sentences end with
+// punctuation this aggregator knows.
+var punctuatedLanguages = map[string]bool{
+	// Latin sentence punctuation.
+	"af": true, "az": true, "be": true, "bg": true, "bs": true, "ca": true,
+	"cs": true, "cy": true, "da": true, "el": true, "eo": true, "et": true,
+	"eu": true, "fi": true, "fil": true, "ga": true, "gl": true, "he": true,
+	"hr": true, "hu": true, "id": true, "is": true, "ka": true, "kk": true,
+	"ko": true, "lt": true, "lv": true, "mk": true, "mn": true, "ms": true,
+	"mt": true, "nb": true, "nl": true, "nn": true, "no": true, "pl": true,
+	"ro": true, "ru": true, "sk": true, "sl": true, "sq": true, "sr": true,
+	"sv": true, "sw": true, "tl": true, "tr": true, "uk": true, "uz": true,
+	"vi": true, "zu": true,
+	// Arabic script.
+	"ar": true, "fa": true, "ps": true, "sd": true, "ug": true, "ur": true,
+	// Danda.
+	"as": true, "bn": true, "hi": true, "mr": true, "ne": true, "pa": true, "sa": true,
+	// Other scripts with their own full stop.
+	"am": true, "hy": true, "my": true, "ti": true,
+}
+
+// spacelessLanguages are written without spaces between words.
+var spacelessLanguages = map[string]bool{"ja": true, "zh": true, "yue": true}
+
+// sentenceMarks end a sentence without a following space.
+const sentenceMarks = "。！？．｡।॥؟۔።፧։။"
+
+// clauseMarks end a clause of at least MinClauseChars without a following
+// space.
+const clauseMarks = "，、；：､،؛"
+
+// sentenceClosers are closing quotes and brackets that stay with the
+// sentence they follow.
+const sentenceClosers = "\"')]}»”’」』）】〕〉》"
+
+// splitRules are the language-dependent parts of splitting.
+type splitRules struct {
+	abbreviations map[string]bool
+	// spaceless languages have no spaces to split words at.
+	spaceless bool
+	// fallback languages have no sentence punctuation known to split at,
+	// so text is split by length.
+	fallback bool
+}
+
+func splitRulesFor(language string) splitRules {
+	base := baseLanguage(language)
+	if base == "" {
+		base = "en"
+	}
+	abbreviations, ok := languageAbbreviations[base]
+	return splitRules{
+		abbreviations: abbreviations,
+		spaceless:     spacelessLanguages[base],
+		fallback:      !ok && !spacelessLanguages[base] && !punctuatedLanguages[base],
+	}
+}
+
+// Add appends a token and returns any clauses it completed.
+func (a *SentenceAggregator) Add(token string) []string {
+	a.buf.WriteString(token)
+	text := a.buf.String()
+	rules := splitRulesFor(a.Language)
+	minChars := a.MinClauseChars
+	if minChars <= 0 {
+		minChars = 40
+		if rules.spaceless {
+			minChars = 20
+		}
+	}
+	punct := a.ClausePunctuation
+	if punct == "" {
+		punct = DefaultClausePunctuation
+	}
+	if a.SentencesOnly {
+		punct = ""
+	}
+	maxChars := a.MaxClauseChars
+	if maxChars <= 0 && rules.fallback {
+		maxChars = DefaultFallbackClauseChars
+	}
+
+	var clauses []string
+	add := func(clause string) {
+		if clause = strings.TrimSpace(clause); clause != "" {
+			clauses = append(clauses, clause)
+		}
+	}
+	// n counts the characters from start through text[i].
+	start, space, n := 0, -1, 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == ' ' {
+			space = i
+		}
+		n++
+		end, boundary := a.boundary(text, start, i, n, minChars, punct, rules)
+		switch {
+		case boundary:
+			add(text[start:end])
+			start, n = end, 0
+			i = end
+			continue
+		case maxChars > 0 && n > maxChars && space > start:
+			// Split the run-on text at its last space instead.
+			add(text[start:space])
+			start = space + 1
+			n = utf8.RuneCountInString(text[start : i+size])
+		case maxChars > 0 && n > maxChars && (rules.spaceless || rules.fallback) && i > start:
+			// With no space to split at, split before this character.
+			add(text[start:i])
+			start, n = i, 1
+		}
+		i += size
+	}
+	if start > 0 {
+		a.buf.Reset()
+		a.buf.WriteString(text[start:])
+	}
+	return clauses
+}
+
+// FlushWords returns the complete words buffered, keeping a word that may
+// still be growing, for a flush that does not cut a word in two. It
+// returns "" if there is no complete word. In a language written without
+// spaces, where words cannot be told apart, it returns all the text.
+func (a *SentenceAggregator) FlushWords() string {
+	text := a.buf.String()
+	if splitRulesFor(a.Language).spaceless {
+		return a.Flush()
+	}
+	i := strings.LastIndexFunc(text, unicode.IsSpace)
+	if i < 0 {
+		return ""
+	}
+	words := strings.TrimSpace(text[:i])
+	a.buf.Reset()
+	a.buf.WriteString(text[i+1:])
+	return words
+}
+
+// Flush returns the remaining buffered text, if any, and resets the
+// aggregator.
+func (a *SentenceAggregator) Flush() string {
+	text := strings.TrimSpace(a.buf.String())
+	a.buf.Reset()
+	return text
+}
+
+// boundary reports whether the character at text[i], the nth of a clause
+// begun at start, ends the clause, and where the clause ends: after the
+// character and any closing quotes and brackets. A boundary needs the
+// character following those to be known, so punctuation at the end of the
+// buffer waits for the next token.
+func (a *SentenceAggregator) boundary(text string, start, i, n, minChars int, punct string, rules splitRules) (int, bool) {
+	r, size := utf8.DecodeRuneInString(text[i:])
+	if r == '\n' {
+		return i + 1, true
+	}
+	if strings.ContainsRune(clauseMarks, r) {
+		return i + size, punct != "" && n >= minChars && i+size < len(text)
+	}
+	end := i + size
+	if r == '.' || r == '!' || r == '?' || strings.ContainsRune(sentenceMarks, r) {
+		// Closing quotes, brackets, and bidirectional marks stay with
+		// the sentence.
+		for end < len(text) {
+			c, size := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(sentenceClosers, c) && !unicode.Is(unicode.Cf, c) {
+				break
+			}
+			end += size
+		}
+	}
+	if end >= len(text) {
+		return 0, false
+	}
+	if strings.ContainsRune(sentenceMarks, r) {
+		return end, true
+	}
+	next, _ := utf8.DecodeRuneInString(text[end:])
+	// A new sentence may follow without a space if it opens with inverted
+	// punctuation, as in "¡Hola!¿Qué tal?", or, in a language written
+	// without spaces, with a word.
+	opens := unicode.IsSpace(next) || next == '¿' || next == '¡' ||
+		rules.spaceless && r != '.' && next >= utf8.RuneSelf && unicode.IsLetter(next)
+	if !opens {
+		return 0, false
+	}
+	switch r {
+	case '!', '?':
+		return end, true
+	case '.':
+		word := text[start:i]
+		if j := strings.LastIndexFunc(word, unicode.IsSpace); j >= 0 {
+			word = word[j+1:]
+		}
+		word = strings.ToLower(strings.TrimLeft(word, "(\"'¿¡«“‘"))
+		if rules.abbreviations[word] {
+			return 0, false
+		}
+		if c, size := utf8.DecodeRuneInString(word); size == len(word) && unicode.IsLetter(c) {
+			return 0, false
+		}
+		return end, true
+	}
+	if r >= utf8.RuneSelf || !strings.ContainsRune(punct, r) {
+		return 0, false
+	}
+	if r == '-' {
+		// Spaced dash used as a clause break.
+		return end, i > 0 && text[i-1] == ' ' && n >= minChars
+	}
+	return end, n >= minChars
+}
+
+// AggregateStream reads tokens and emits clauses with a default
+// SentenceAggregator; see SentenceAggregator.Stream.
+func AggregateStream(ctx context.Context, tokens <-chan string) <-chan string {
+	return (&SentenceAggregator{}).Stream(ctx, tokens)
+}
+
+// Stream reads tokens and emits clauses, sending the complete words
+// buffered when MaxDelay passes without a boundary, and flushing the
+// remainder when tokens closes. The output channel closes after the last
+// clause or when ctx ends. The aggregator must not be used otherwise
+// until then.
+func (a *SentenceAggregator) Stream(ctx context.Context, tokens <-chan string) <-chan string {
+	delay := a.MaxDelay
+	if delay == 0 {
+		delay = DefaultMaxClauseDelay
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		send := func(s string) bool {
+			select {
+			case out <- s:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		// timeout runs while text waits for a boundary.
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+		wait := func() {
+			if delay < 0 || timeout != nil || strings.TrimSpace(a.buf.String()) == "" {
+				return
+			}
+			timer = time.NewTimer(delay)
+			timeout = timer.C
+		}
+		for {
+			select {
+			case tok, ok := <-tokens:
+				if !ok {
+					if rest := a.Flush(); rest != "" {
+						send(rest)
+					}
+					return
+				}
+				clauses := a.Add(tok)
+				if len(clauses) > 0 && timer != nil {
+					timer.Stop()
+					timeout = nil
+				}
+				for _, clause := range clauses {
+					if !send(clause) {
+						return
+					}
+				}
+				wait()
+			case <-timeout:
+				timeout = nil
+				if words := a.FlushWords(); words != "" && !send(words) {
+					return
+				}
+				wait()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
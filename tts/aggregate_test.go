@@ -0,0 +1,83 @@
+package tts
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// aggregate feeds text to a SentenceAggregator for language, whole and
+// then a character per token, and returns the clauses of each, flushed.
+func aggregate(language, text string) (whole, streamed []string) {
+	a := &SentenceAggregator{Language: language}
+	whole = append(a.Add(text), a.Flush())
+	for _, r := range text {
+		streamed = append(streamed, a.Add(string(r))...)
+	}
+	streamed = append(streamed, a.Flush())
+	return whole, streamed
+}
+
+func TestAggregatorSplitsBySentence(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		language string
+		text     string
+		want     []string
+	}{
+		{"japanese", "ja-JP", "こんにちは。元気ですか？はい、元気です！", []string{"こんにちは。", "元気ですか？", "はい、元気です！"}},
+		{"japanese quotes", "ja", "「はい。」そうです。", []string{"「はい。」", "そうです。"}},
+		{"chinese", "zh-CN", "今天天气很好。我们去公园吧！好的。", []string{"今天天气很好。", "我们去公园吧！", "好的。"}},
+		{"chinese with ascii", "zh", "价格是3.5元。太贵了!", []string{"价格是3.5元。", "太贵了!"}},
+		{"arabic", "ar", "مرحبا بك. كيف حالك؟ أنا بخير.", []string{"مرحبا بك.", "كيف حالك؟", "أنا بخير."}},
+		{"arabic without spaces", "ar-EG", "كيف حالك؟أنا بخير.", []string{"كيف حالك؟", "أنا بخير."}},
+		{"hebrew", "he-IL", "שלום. מה שלומך? טוב מאוד.", []string{"שלום.", "מה שלומך?", "טוב מאוד."}},
+		{"hebrew with bidi mark", "he", "שלום.‏ מה שלומך?", []string{"שלום.‏", "מה שלומך?"}},
+		{"spanish inverted", "es", "¡Hola!¿Qué tal? Bien.", []string{"¡Hola!", "¿Qué tal?", "Bien."}},
+		{"spanish abbreviation", "es-MX", "La Sra. García llegó. Bien.", []string{"La Sra. García llegó.", "Bien."}},
+		{"hindi", "hi", "नमस्ते। आप कैसे हैं?", []string{"नमस्ते।", "आप कैसे हैं?"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			whole, streamed := aggregate(tc.language, tc.text)
+			if !slices.Equal(whole, tc.want) {
+				t.Errorf("whole: %q, want %q", whole, tc.want)
+			}
+			if !slices.Equal(streamed, tc.want) {
+				t.Errorf("streamed: %q, want %q", streamed, tc.want)
+			}
+		})
+	}
+}
+
+func TestAggregatorFallsBackToLength(t *testing.T) {
+	// Thai marks no sentence ends, so only length splits it.
+	text := strings.Repeat("สวัสดีครับ ", 30)
+	whole, streamed := aggregate("th-TH", text)
+	for _, clauses := range [][]string{whole, streamed} {
+		if len(clauses) < 3 {
+			t.Fatalf("%d clauses, want the text split by length: %q", len(clauses), clauses)
+		}
+		for _, c := range clauses {
+			if n := utf8.RuneCountInString(c); n > DefaultFallbackClauseChars {
+				t.Errorf("clause of %d characters, want at most %d", n, DefaultFallbackClauseChars)
+			}
+		}
+		if got := strings.Join(clauses, " "); got != strings.TrimSpace(text) {
+			t.Errorf("clauses rejoin to %q, want the input", got)
+		}
+	}
+}
+
+func TestAggregatorFlushWordsSpaceless(t *testing.T) {
+	a := &SentenceAggregator{Language: "ja"}
+	a.Add("今日は晴れ")
+	if got := a.FlushWords(); got != "今日は晴れ" {
+		t.Errorf("FlushWords = %q, want all the text", got)
+	}
+	a = &SentenceAggregator{}
+	a.Add("hello wor")
+	if got := a.FlushWords(); got != "hello" {
+		t.Errorf("FlushWords = %q, want the complete words", got)
+	}
+}
@@ -0,0 +1,100 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+// RegisterVoice registers alias as a logical voice spoken by a different
+// voice with each provider, so one SynthesisConfig.VoiceID keeps its
+// identity across fallback:
+//
+//	client.RegisterVoice(ctx, "support-female-en", map[string]string{
+//		"elevenlabs": "rachel",
+//		"polly":      "Joanna",
+//		"google":     "en-US-Neural2-F",
+//	})
+//
+// Each voice is checked with its provider's GetVoice, and an error
+// wrapping ErrVoiceNotFound is returned, registering nothing, if one is
+// missing. Providers must be configured on the client. Registering an
+// alias again replaces it.
+//
+// A request whose VoiceID is a registered alias uses each provider's
+// voice for it, and skips providers the alias has no voice for. A
+// request naming one of the alias's provider voices directly falls back
+// to the alias's voices too.
+func (c *Client) RegisterVoice(ctx context.Context, alias string, voices map[string]string) error {
+	if alias == "" || len(voices) == 0 {
+		return fmt.Errorf("%w: voice alias %q has no voices", ErrInvalidConfig, alias)
+	}
+	for name, id := range voices {
+		p, ok := c.providers[name]
+		if !ok {
+			return fmt.Errorf("%w: voice alias %q names unknown provider %s", ErrInvalidConfig, alias, name)
+		}
+		pctx, err := c.withCredentials(ctx, name)
+		if err != nil {
+			return err
+		}
+		if _, err := c.call(p).GetVoice(pctx, id); err != nil {
+			return fmt.Errorf("%w: voice alias %q: %s voice %s: %w", ErrVoiceNotFound, alias, name, id, err)
+		}
+	}
+	c.aliasMu.Lock()
+	defer c.aliasMu.Unlock()
+	if c.aliases == nil {
+		c.aliases = make(map[string]map[string]string)
+	}
+	c.aliases[alias] = maps.Clone(voices)
+	return nil
+}
+
+// UnregisterVoice removes a voice alias.
+func (c *Client) UnregisterVoice(alias string) {
+	c.aliasMu.Lock()
+	defer c.aliasMu.Unlock()
+	delete(c.aliases, alias)
+}
+
+// ResolveVoice returns the named provider's voice for alias. ok is false
+// if alias is not registered or has no voice for the provider.
+func (c *Client) ResolveVoice(alias, provider string) (voiceID string, ok bool) {
+	c.aliasMu.RLock()
+	defer c.aliasMu.RUnlock()
+	voiceID, ok = c.aliases[alias][provider]
+	return voiceID, ok
+}
+
+// withVoice returns config with its VoiceID resolved for the named
+// provider, reporting false if it is an alias without a voice for it.
+// A provider voice of an alias resolves to the alias's voice for the
+// named provider; other voice IDs are left as they are.
+func (c *Client) withVoice(provider string, config SynthesisConfig) (SynthesisConfig, bool) {
+	c.aliasMu.RLock()
+	defer c.aliasMu.RUnlock()
+	if voices, ok := c.aliases[config.VoiceID]; ok {
+		id, ok := voices[provider]
+		config.VoiceID = id
+		return config, ok
+	}
+	for _, voices := range c.aliases {
+		id, ok := voices[provider]
+		if !ok {
+			continue
+		}
+		for _, v := range voices {
+			if v == config.VoiceID {
+				config.VoiceID = id
+				return config, true
+			}
+		}
+	}
+	return config, true
+}
+
+// noVoice records a provider skipped for lacking a voice for an alias.
+func (s *skips) noVoice(provider, alias string) {
+	s.errs = append(s.errs, fmt.Errorf("%w: voice alias %q for %s", ErrVoiceNotFound, alias, provider))
+}
@@ -0,0 +1,78 @@
+package tts
+
+import "context"
+
+// CloseOnCancel forwards a stream until it ends or ctx does, enforcing the
+// cancellation contract of Provider.SynthesizeStream for callers that use
+// a provider directly; Client applies it to every stream. Once ctx ends
+// the returned channel is closed, after a final StreamChunk with IsFinal
+// set if the stream had not ended, and in is drained so the provider's
+// goroutine can finish.
+func CloseOnCancel(ctx context.Context, in <-chan StreamChunk) <-chan StreamChunk {
+	return forward(ctx, in, nil, func() {})
+}
+
+// forward sends first and then the chunks of in until either ends or ctx
+// does, as described for CloseOnCancel, and calls done once in has been
+// drained.
+func forward(ctx context.Context, in <-chan StreamChunk, first []StreamChunk, done func()) <-chan StreamChunk {
+	// One slot lets the final chunk be left for a consumer that has
+	// stopped reading, without blocking.
+	out := make(chan StreamChunk, 1)
+	go func() {
+		defer done()
+		final := false
+		send := func(c StreamChunk) bool {
+			select {
+			case out <- c:
+				final = final || c.IsFinal
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, c := range first {
+			if !send(c) {
+				cancelled(out, final)
+				drain(in)
+				return
+			}
+		}
+		for {
+			select {
+			case c, ok := <-in:
+				if !ok {
+					close(out)
+					return
+				}
+				if send(c) {
+					continue
+				}
+			case <-ctx.Done():
+			}
+			cancelled(out, final)
+			drain(in)
+			return
+		}
+	}()
+	return out
+}
+
+// cancelled ends a forwarded stream whose context has ended, replacing
+// any chunk the consumer has not taken with the final one.
+func cancelled(out chan StreamChunk, final bool) {
+	if !final {
+		select {
+		case <-out:
+		default:
+		}
+		out <- StreamChunk{IsFinal: true}
+	}
+	close(out)
+}
+
+// drain discards a stream's chunks so the provider's goroutine can finish.
+func drain(in <-chan StreamChunk) {
+	for range in {
+	}
+}
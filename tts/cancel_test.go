@@ -0,0 +1,99 @@
+package tts
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// endlessProvider is a fakeProvider whose streams send chunks until their
+// ctx ends, as a provider holding a connection open does, and then close.
+type endlessProvider struct {
+	fakeProvider
+	closed chan struct{}
+}
+
+func (p *endlessProvider) SynthesizeStream(ctx context.Context, text string, config SynthesisConfig) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(p.closed)
+		defer close(ch)
+		for {
+			select {
+			case ch <- StreamChunk{Audio: make([]byte, 320)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// readAfterCancel reads two chunks of stream, calls cancel, and returns
+// the chunks that followed until stream closed.
+func readAfterCancel(t *testing.T, stream <-chan StreamChunk, cancel func()) []StreamChunk {
+	t.Helper()
+	for range 2 {
+		if _, ok := <-stream; !ok {
+			t.Fatal("stream closed before cancel")
+		}
+	}
+	cancel()
+	var rest []StreamChunk
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case c, ok := <-stream:
+			if !ok {
+				return rest
+			}
+			rest = append(rest, c)
+		case <-timeout:
+			t.Fatal("stream not closed after cancel")
+		}
+	}
+}
+
+func TestSynthesizeStreamCancelClosesProviderStream(t *testing.T) {
+	p := &endlessProvider{fakeProvider: fakeProvider{name: "endless"}, closed: make(chan struct{})}
+	c := NewClient(p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.SynthesizeStream(ctx, "hello", SynthesisConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest := readAfterCancel(t, stream, cancel)
+	if len(rest) == 0 || !rest[len(rest)-1].IsFinal {
+		t.Errorf("stream ended with %d chunks after cancel and no final one", len(rest))
+	}
+	select {
+	case <-p.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider stream still open after cancel")
+	}
+}
+
+func TestCloseOnCancelDrainsStream(t *testing.T) {
+	// The provider ignores ctx, so only draining lets it finish.
+	in := make(chan StreamChunk)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer close(in)
+		for range 100 {
+			in <- StreamChunk{Audio: make([]byte, 320)}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rest := readAfterCancel(t, CloseOnCancel(ctx, in), cancel)
+	if len(rest) == 0 || !rest[len(rest)-1].IsFinal {
+		t.Errorf("stream ended with %d chunks after cancel and no final one", len(rest))
+	}
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider goroutine blocked after cancel")
+	}
+}
@@ -0,0 +1,137 @@
+package tts
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Capabilities describes what a provider supports.
+type Capabilities struct {
+	// Streaming reports streaming text input (StreamingProvider).
+	Streaming bool
+
+	// SSML reports SSML input.
+	SSML bool
+
+	// Styles reports SynthesisConfig.Style support.
+	Styles bool
+
+	// Languages are the supported BCP-47 codes. A bare language ("en")
+	// covers all its regions. Empty means any language.
+	Languages []string
+
+	// Scripts are the ISO 15924 scripts the provider can be asked to read
+	// text in (SynthesisConfig.Script). Empty means script selection is
+	// not supported and the hint is ignored.
+	Scripts []string
+
+	// OutputFormats are the supported output formats. Empty means any.
+	OutputFormats []string
+
+	// SampleRates are the supported output sample rates. Empty means any.
+	SampleRates []int
+
+	// MaxTextLength is the most characters accepted in one request; 0
+	// means no limit.
+	MaxTextLength int
+}
+
+// CapabilityProvider is implemented by providers that describe their
+// capabilities. The client skips such providers for requests they cannot
+// serve; providers without it are always tried.
+type CapabilityProvider interface {
+	Provider
+
+	// Capabilities returns the provider's capabilities.
+	Capabilities() Capabilities
+}
+
+// Check reports whether the capabilities cover synthesizing text with
+// config, returning an error wrapping ErrUnsupportedFeature,
+// ErrUnsupportedLanguage, ErrUnsupportedFormat, or ErrTextTooLong if not.
+// Styles are not checked, as providers ignore styles they cannot express.
+func (c Capabilities) Check(text string, config SynthesisConfig) error {
+	switch {
+	case isSSML(text) && !c.SSML:
+		return fmt.Errorf("%w: SSML", ErrUnsupportedFeature)
+	case !supportsLanguage(c.Languages, config.Language):
+		return fmt.Errorf("%w: %s", ErrUnsupportedLanguage, config.Language)
+	case config.Script != "" && len(c.Scripts) > 0 && !slices.ContainsFunc(c.Scripts, func(sc string) bool { return strings.EqualFold(sc, config.Script) }):
+		// Only an explicit Script is checked; a script subtag in Language
+		// is left for the provider to interpret.
+		return fmt.Errorf("%w: script %s", ErrUnsupportedLanguage, config.Script)
+	case config.OutputFormat != "" && len(c.OutputFormats) > 0 && !slices.Contains(c.OutputFormats, config.OutputFormat):
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, config.OutputFormat)
+	case config.SampleRate > 0 && len(c.SampleRates) > 0 && !slices.Contains(c.SampleRates, config.SampleRate):
+		return fmt.Errorf("%w: %d Hz", ErrUnsupportedFormat, config.SampleRate)
+	case c.MaxTextLength > 0 && utf8.RuneCountInString(text) > c.MaxTextLength:
+		return fmt.Errorf("%w: over %d characters", ErrTextTooLong, c.MaxTextLength)
+	}
+	return nil
+}
+
+// supportsLanguage reports whether lang is in langs, matching a bare
+// language against any of its regions. Empty lang and empty langs match
+// anything.
+func supportsLanguage(langs []string, lang string) bool {
+	if lang == "" || len(langs) == 0 {
+		return true
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	for _, l := range langs {
+		lb, _, _ := strings.Cut(l, "-")
+		if strings.EqualFold(l, lang) || strings.EqualFold(l, base) || (base == lang && strings.EqualFold(lb, base)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns the capabilities of each provider implementing
+// CapabilityProvider, by provider name. See Latency for how responsive
+// they have been.
+func (c *Client) Capabilities() map[string]Capabilities {
+	caps := make(map[string]Capabilities)
+	for name, p := range c.providers {
+		if cp, ok := p.(CapabilityProvider); ok {
+			caps[name] = cp.Capabilities()
+		}
+	}
+	return caps
+}
+
+// skips records providers passed over because their capabilities do not
+// cover a request.
+type skips struct {
+	errs    []error
+	capable bool
+}
+
+// check reports whether p can synthesize text with config, recording
+// why not. Providers that do not describe their capabilities qualify.
+func (s *skips) check(p Provider, text string, config SynthesisConfig) bool {
+	if cp, ok := p.(CapabilityProvider); ok {
+		if err := cp.Capabilities().Check(text, config); err != nil {
+			s.errs = append(s.errs, fmt.Errorf("%w for %s", err, p.Name()))
+			return false
+		}
+	}
+	s.capable = true
+	return true
+}
+
+// wrap returns err for a request no provider took. If providers were
+// skipped and none qualified, it is ErrNoCapableProvider wrapping each
+// provider's reason instead.
+func (s *skips) wrap(err error) error {
+	if s.capable || len(s.errs) == 0 {
+		return err
+	}
+	args := []any{ErrNoCapableProvider}
+	for _, e := range s.errs {
+		args = append(args, e)
+	}
+	return fmt.Errorf("%w: "+strings.Repeat("%w; ", len(s.errs)-1)+"%w", args...)
+}
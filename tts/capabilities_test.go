@@ -0,0 +1,107 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a provider that synthesizes its own name as audio,
+// after delay.
+type fakeProvider struct {
+	name  string
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Synthesize(ctx context.Context, text string, config SynthesisConfig) (*SynthesisResult, error) {
+	p.calls.Add(1)
+	select {
+	case <-time.After(p.delay):
+		return &SynthesisResult{Audio: []byte(p.name), Format: "pcm", SampleRate: 16000}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *fakeProvider) SynthesizeStream(ctx context.Context, text string, config SynthesisConfig) (<-chan StreamChunk, error) {
+	p.calls.Add(1)
+	ch := make(chan StreamChunk, 1)
+	ch <- StreamChunk{Audio: []byte(p.name), IsFinal: true}
+	close(ch)
+	return ch, nil
+}
+
+func (p *fakeProvider) ListVoices(ctx context.Context) ([]Voice, error) { return nil, nil }
+
+func (p *fakeProvider) GetVoice(ctx context.Context, voiceID string) (*Voice, error) {
+	return nil, ErrVoiceNotFound
+}
+
+// capableProvider is a fakeProvider describing its capabilities.
+type capableProvider struct {
+	fakeProvider
+	caps Capabilities
+}
+
+func (p *capableProvider) Capabilities() Capabilities { return p.caps }
+
+func TestSynthesizeRoutesToCapableFallback(t *testing.T) {
+	primary := &capableProvider{fakeProvider: fakeProvider{name: "primary"}, caps: Capabilities{Languages: []string{"en"}, SampleRates: []int{24000}}}
+	fallback := &capableProvider{fakeProvider: fakeProvider{name: "fallback"}, caps: Capabilities{Languages: []string{"en", "es"}, SSML: true}}
+	c := NewClient(primary, fallback)
+
+	for _, tc := range []struct {
+		text   string
+		config SynthesisConfig
+	}{
+		{"Hola", SynthesisConfig{Language: "es-ES"}},
+		{"<speak>Hello</speak>", SynthesisConfig{Language: "en"}},
+		{"Hello", SynthesisConfig{Language: "en", SampleRate: 8000}},
+	} {
+		result, err := c.Synthesize(context.Background(), tc.text, tc.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(result.Audio); got != "fallback" {
+			t.Errorf("%q %+v synthesized by %q, want fallback", tc.text, tc.config, got)
+		}
+	}
+	if n := primary.calls.Load(); n != 0 {
+		t.Errorf("incapable primary called %d times", n)
+	}
+}
+
+func TestSynthesizeStreamRoutesToCapableFallback(t *testing.T) {
+	primary := &capableProvider{fakeProvider: fakeProvider{name: "primary"}, caps: Capabilities{MaxTextLength: 5}}
+	fallback := &capableProvider{fakeProvider: fakeProvider{name: "fallback"}}
+	c := NewClient(primary, fallback)
+
+	ch, err := c.SynthesizeStream(context.Background(), "Hello there", SynthesisConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+	if primary.calls.Load() != 0 || fallback.calls.Load() != 1 {
+		t.Errorf("streams opened: primary %d, fallback %d; want only the fallback", primary.calls.Load(), fallback.calls.Load())
+	}
+}
+
+func TestSynthesizeNoCapableProvider(t *testing.T) {
+	primary := &capableProvider{fakeProvider: fakeProvider{name: "primary"}, caps: Capabilities{OutputFormats: []string{"mp3"}}}
+	fallback := &capableProvider{fakeProvider: fakeProvider{name: "fallback"}, caps: Capabilities{OutputFormats: []string{"wav"}}}
+	c := NewClient(primary, fallback)
+
+	_, err := c.Synthesize(context.Background(), "Hello", SynthesisConfig{OutputFormat: "opus"})
+	if !errors.Is(err, ErrNoCapableProvider) || !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got %v, want ErrNoCapableProvider wrapping ErrUnsupportedFormat", err)
+	}
+	if primary.calls.Load()+fallback.calls.Load() != 0 {
+		t.Error("incapable providers were called")
+	}
+}
@@ -0,0 +1,46 @@
+package tts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNewProviderError(t *testing.T) {
+	cause := errors.New("invalid api key")
+	err := NewProviderError("elevenlabs", http.StatusUnauthorized, cause)
+	if !errors.Is(err, ErrUnauthorized) || !errors.Is(err, cause) {
+		t.Errorf("%v does not wrap ErrUnauthorized and its cause", err)
+	}
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.Provider != "elevenlabs" || pe.StatusCode != http.StatusUnauthorized {
+		t.Errorf("errors.As gave %+v", pe)
+	}
+	if want := "tts: unauthorized: elevenlabs: HTTP 401: invalid api key"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+	if again := NewProviderError("other", 0, fmt.Errorf("wrapped: %w", err)); !errors.As(again, &pe) || pe.Provider != "elevenlabs" {
+		t.Errorf("an already classified error was reclassified: %v", again)
+	}
+	if got := (&ProviderError{Provider: "x"}).Error(); got != "tts: provider error: x" {
+		t.Errorf("unclassified Error() = %q", got)
+	}
+}
+
+func TestRetryableKinds(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{NewProviderError("p", http.StatusServiceUnavailable, nil), true},
+		{NewProviderError("p", http.StatusTooManyRequests, nil), true},
+		{NewProviderError("p", http.StatusBadRequest, nil), false},
+		{ErrTextTooLong, false},
+		{ErrNoCapableProvider, false},
+	} {
+		if got := Retryable(tc.err); got != tc.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package tts
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// VoiceContinuity keeps a session's voice steady across provider
+// fallback. Attach it to request contexts with WithVoiceContinuity and
+// bracket each utterance, the requests making up one reply, with
+// BeginUtterance and EndUtterance.
+//
+// Within an utterance the client only uses the provider that started
+// it: if that provider fails, the request fails rather than finishing
+// the sentence in another voice. Between utterances the client tries the
+// current provider first and falls back as usual, resolving the voice
+// through the alias registry (see RegisterVoice). The tradeoff is that
+// an outage costs the rest of the utterance, heard as the agent stopping
+// mid-reply, in exchange for never switching voices mid-sentence; without
+// VoiceContinuity each request falls back independently.
+type VoiceContinuity struct {
+	mu          sync.Mutex
+	provider    string
+	inUtterance bool
+	switches    int
+}
+
+// NewVoiceContinuity creates a VoiceContinuity for one session.
+func NewVoiceContinuity() *VoiceContinuity {
+	return &VoiceContinuity{}
+}
+
+// BeginUtterance starts an utterance; requests until EndUtterance stay
+// with the provider that serves the first of them.
+func (v *VoiceContinuity) BeginUtterance() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.inUtterance = true
+}
+
+// EndUtterance ends the current utterance, allowing fallback again.
+func (v *VoiceContinuity) EndUtterance() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.inUtterance = false
+}
+
+// Provider returns the name of the provider currently speaking, or ""
+// before the first request.
+func (v *VoiceContinuity) Provider() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.provider
+}
+
+// Switches returns how many times the session's voice moved to another
+// provider.
+func (v *VoiceContinuity) Switches() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.switches
+}
+
+// order returns the providers to try, given the client's order: only the
+// current provider within an utterance, otherwise it first.
+func (v *VoiceContinuity) order(names []string) []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.provider == "" || !slices.Contains(names, v.provider) {
+		return names
+	}
+	if v.inUtterance {
+		return []string{v.provider}
+	}
+	out := []string{v.provider}
+	for _, n := range names {
+		if n != v.provider {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// served records the provider that served a request.
+func (v *VoiceContinuity) served(provider string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.provider != "" && v.provider != provider {
+		v.switches++
+	}
+	v.provider = provider
+}
+
+type continuityKey struct{}
+
+// WithVoiceContinuity returns a context whose TTS requests keep the voice
+// of v.
+func WithVoiceContinuity(ctx context.Context, v *VoiceContinuity) context.Context {
+	return context.WithValue(ctx, continuityKey{}, v)
+}
+
+// voiceContinuity returns the VoiceContinuity of ctx, or nil.
+func voiceContinuity(ctx context.Context) *VoiceContinuity {
+	v, _ := ctx.Value(continuityKey{}).(*VoiceContinuity)
+	return v
+}
+
+// providerOrder returns the providers to try for a request.
+func (c *Client) providerOrder(ctx context.Context) []string {
+	names := append([]string{c.primary}, c.fallbacks...)
+	if v := voiceContinuity(ctx); v != nil {
+		return v.order(names)
+	}
+	return names
+}
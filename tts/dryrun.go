@@ -0,0 +1,148 @@
+package tts
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// DryRunConfig configures a client's dry-run mode. See Client.SetDryRun.
+type DryRunConfig struct {
+	// SampleRate is the stub audio sample rate when the request does not
+	// set one. Defaults to 16000.
+	SampleRate int
+
+	// CharactersPerSecond sizes the stub audio: text lasts its length
+	// over this rate, divided by SynthesisConfig.Speed. Defaults to 15.
+	CharactersPerSecond float64
+
+	// Errors makes the named providers fail with the given error, to
+	// exercise fallback.
+	Errors map[string]error
+
+	// Voices are returned by the stub's ListVoices and GetVoice, so voice
+	// language checks can be exercised.
+	Voices []Voice
+
+	// Result, if set, builds each result instead of silent audio.
+	Result func(provider, text string, config SynthesisConfig) *SynthesisResult
+}
+
+// dryRunChunk is the duration of each stub stream chunk.
+const dryRunChunk = 100 * time.Millisecond
+
+// SetDryRun puts the client in dry-run mode: requests go through text
+// preparation, provider selection, capabilities, credentials, and rate
+// limits as usual, but the chosen provider is never called and returns
+// silence of the expected duration instead, as 16-bit PCM (or G.711 when
+// OutputFormat is "mulaw" or "alaw"). A nil config turns dry-run mode
+// off.
+func (c *Client) SetDryRun(config *DryRunConfig) {
+	if config == nil {
+		c.dryRun = nil
+		return
+	}
+	cfg := *config
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 16000
+	}
+	if cfg.CharactersPerSecond <= 0 {
+		cfg.CharactersPerSecond = 15
+	}
+	c.dryRun = &cfg
+}
+
+// call returns the provider to send a request to: p, or its stub in
+// dry-run mode.
+func (c *Client) call(p Provider) Provider {
+	if c.dryRun == nil {
+		return p
+	}
+	return &dryRunProvider{name: p.Name(), config: c.dryRun}
+}
+
+// dryRunProvider stands in for a provider in dry-run mode.
+type dryRunProvider struct {
+	name   string
+	config *DryRunConfig
+}
+
+func (d *dryRunProvider) Name() string { return d.name }
+
+func (d *dryRunProvider) Synthesize(ctx context.Context, text string, config SynthesisConfig) (*SynthesisResult, error) {
+	if err := d.config.Errors[d.name]; err != nil {
+		return nil, err
+	}
+	if d.config.Result != nil {
+		return d.config.Result(d.name, text, config), nil
+	}
+	rate := config.SampleRate
+	if rate <= 0 {
+		rate = d.config.SampleRate
+	}
+	speed := config.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	chars := utf8.RuneCountInString(text)
+	duration := time.Duration(float64(chars) / d.config.CharactersPerSecond / speed * float64(time.Second))
+	pcm := make([]byte, int(int64(audio.BytesPerSecond(rate, 1))*int64(duration)/int64(time.Second))&^1)
+
+	result := &SynthesisResult{Audio: pcm, Format: "pcm", SampleRate: rate, Channels: 1, CharacterCount: chars}
+	switch config.OutputFormat {
+	case "mulaw":
+		result.Audio, result.Format = audio.EncodeMuLaw(pcm), "mulaw"
+	case "alaw":
+		result.Audio, result.Format = audio.EncodeALaw(pcm), "alaw"
+	}
+	result.DurationMs = int(duration.Milliseconds())
+	return result, nil
+}
+
+func (d *dryRunProvider) SynthesizeStream(ctx context.Context, text string, config SynthesisConfig) (<-chan StreamChunk, error) {
+	result, err := d.Synthesize(ctx, text, config)
+	if err != nil {
+		return nil, err
+	}
+	chunk := len(result.Audio)
+	if result.SampleRate > 0 {
+		bytesPerSample := 2
+		if result.Format != "pcm" {
+			bytesPerSample = 1
+		}
+		chunk = max(result.SampleRate*bytesPerSample*int(dryRunChunk/time.Millisecond)/1000, 1)
+	}
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		data := result.Audio
+		for {
+			n := min(chunk, len(data))
+			select {
+			case out <- StreamChunk{Audio: data[:n], IsFinal: n == len(data)}:
+			case <-ctx.Done():
+				return
+			}
+			data = data[n:]
+			if len(data) == 0 {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (d *dryRunProvider) ListVoices(ctx context.Context) ([]Voice, error) {
+	return append([]Voice(nil), d.config.Voices...), nil
+}
+
+func (d *dryRunProvider) GetVoice(ctx context.Context, voiceID string) (*Voice, error) {
+	for _, v := range d.config.Voices {
+		if v.ID == voiceID {
+			return &v, nil
+		}
+	}
+	return nil, ErrVoiceNotFound
+}
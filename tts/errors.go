@@ -6,6 +6,10 @@ var (
 	// ErrNoAvailableProvider is returned when no provider is available.
 	ErrNoAvailableProvider = errors.New("tts: no available provider")
 
+	// ErrNoCapableProvider is returned when no provider's capabilities
+	// cover the request. It wraps each provider's reason.
+	ErrNoCapableProvider = errors.New("tts: no provider supports the request")
+
 	// ErrVoiceNotFound is returned when a voice ID is not found.
 	ErrVoiceNotFound = errors.New("tts: voice not found")
 
@@ -18,6 +22,43 @@ var (
 	// ErrQuotaExceeded is returned when the provider quota is exceeded.
 	ErrQuotaExceeded = errors.New("tts: quota exceeded")
 
+	// ErrCredentials is wrapped in request errors when credentials could
+	// not be resolved for a provider.
+	ErrCredentials = errors.New("tts: credentials unavailable")
+
+	// ErrUnsupportedFeature is returned when a requested feature, such as
+	// SSML input, is not supported.
+	ErrUnsupportedFeature = errors.New("tts: unsupported feature")
+
+	// ErrUnsupportedLanguage is returned when the language is not supported.
+	ErrUnsupportedLanguage = errors.New("tts: unsupported language")
+
+	// ErrTextTooLong is returned when text exceeds provider limits.
+	ErrTextTooLong = errors.New("tts: text too long")
+
+	// ErrUnsupportedFormat is returned when an operation does not support
+	// the audio format of a result.
+	ErrUnsupportedFormat = errors.New("tts: unsupported audio format")
+
 	// ErrStreamClosed is returned when attempting to use a closed stream.
 	ErrStreamClosed = errors.New("tts: stream closed")
+
+	// ErrUnauthorized classifies a provider rejecting its credentials or
+	// their permissions (HTTP 401 and 403). See ProviderError.
+	ErrUnauthorized = errors.New("tts: unauthorized")
+
+	// ErrBadRequest classifies a provider rejecting a request as malformed
+	// or unprocessable (other HTTP 4xx).
+	ErrBadRequest = errors.New("tts: bad request")
+
+	// ErrServerError classifies a provider failing internally (HTTP 5xx).
+	ErrServerError = errors.New("tts: provider server error")
+
+	// ErrTimeout classifies a provider request that timed out, as HTTP 408
+	// and 504 or a deadline.
+	ErrTimeout = errors.New("tts: provider timeout")
+
+	// ErrNetworkError classifies a provider request that failed to reach
+	// the provider or lost its connection.
+	ErrNetworkError = errors.New("tts: network error")
 )
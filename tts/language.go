@@ -0,0 +1,76 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TextScript returns the script of the text: Script if set, otherwise
+// the script subtag of Language (e.g., "Latn" for "sr-Latn-RS"), or
+// empty if neither names one.
+func (c SynthesisConfig) TextScript() string {
+	if c.Script != "" {
+		return c.Script
+	}
+	return LanguageScript(c.Language)
+}
+
+// LanguageScript returns the ISO 15924 script subtag of a BCP-47 language
+// tag in title case, or empty if it has none.
+func LanguageScript(tag string) string {
+	subtags := strings.FieldsFunc(tag, func(r rune) bool { return r == '-' || r == '_' })
+	if len(subtags) < 2 {
+		return ""
+	}
+	s := subtags[1]
+	if len(s) != 4 || strings.ContainsFunc(s, func(r rune) bool { return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') }) {
+		return ""
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
+
+// baseLanguage returns the primary language subtag of a BCP-47 tag in
+// lower case.
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return strings.ToLower(base)
+}
+
+// checkVoice returns ErrInvalidConfig if the voice's language, as
+// reported by p, is a different language from config.Language. Voices
+// whose language cannot be looked up are not checked.
+func (c *Client) checkVoice(ctx context.Context, p Provider, config SynthesisConfig) error {
+	if config.VoiceID == "" || config.Language == "" {
+		return nil
+	}
+	voiceLang := c.voiceLanguage(ctx, p, config.VoiceID)
+	if voiceLang != "" && baseLanguage(voiceLang) != baseLanguage(config.Language) {
+		return fmt.Errorf("%w: voice %s speaks %s, not %s", ErrInvalidConfig, config.VoiceID, voiceLang, config.Language)
+	}
+	return nil
+}
+
+// voiceLanguage returns the language of a voice, or empty if unknown.
+// Lookups, failed ones included, are cached so they are not repeated on
+// every request; dry-run stub voices are not.
+func (c *Client) voiceLanguage(ctx context.Context, p Provider, voiceID string) string {
+	key := p.Name() + "\x00" + voiceID
+	if c.dryRun == nil {
+		if lang, ok := c.voiceLanguages.Load(key); ok {
+			return lang.(string)
+		}
+	}
+	voice, err := p.GetVoice(ctx, voiceID)
+	if ctx.Err() != nil {
+		return ""
+	}
+	var lang string
+	if err == nil && voice != nil {
+		lang = voice.Language
+	}
+	if c.dryRun == nil {
+		c.voiceLanguages.Store(key, lang)
+	}
+	return lang
+}
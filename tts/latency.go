@@ -0,0 +1,91 @@
+package tts
+
+import (
+	"time"
+
+	"github.com/agentplexus/omnivoice/clock"
+)
+
+// LatencyStats summarizes a provider's measured responsiveness.
+type LatencyStats struct {
+	// Streams is the number of SynthesizeStream requests that produced
+	// audio, and AvgFirstChunk and MaxFirstChunk the time from request to
+	// their first audio chunk: the provider's contribution to the delay
+	// before an agent starts speaking.
+	Streams       int
+	AvgFirstChunk time.Duration
+	MaxFirstChunk time.Duration
+
+	// Requests is the number of successful Synthesize requests, and
+	// AvgSynthesize their average duration.
+	Requests      int
+	AvgSynthesize time.Duration
+}
+
+// latencyTotals accumulates a provider's LatencyStats.
+type latencyTotals struct {
+	streams    int
+	firstChunk time.Duration
+	maxFirst   time.Duration
+	requests   int
+	synthesize time.Duration
+}
+
+// Latency returns the measured responsiveness of each provider that has
+// served a request, by provider name, for comparing providers and
+// spotting a slow one. Unlike Capabilities it reflects this client's
+// requests so far.
+func (c *Client) Latency() map[string]LatencyStats {
+	c.latencyMu.Lock()
+	defer c.latencyMu.Unlock()
+	stats := make(map[string]LatencyStats, len(c.latency))
+	for name, t := range c.latency {
+		s := LatencyStats{Streams: t.streams, MaxFirstChunk: t.maxFirst, Requests: t.requests}
+		if t.streams > 0 {
+			s.AvgFirstChunk = t.firstChunk / time.Duration(t.streams)
+		}
+		if t.requests > 0 {
+			s.AvgSynthesize = t.synthesize / time.Duration(t.requests)
+		}
+		stats[name] = s
+	}
+	return stats
+}
+
+// record updates the named provider's totals.
+func (c *Client) record(provider string, update func(t *latencyTotals)) {
+	c.latencyMu.Lock()
+	defer c.latencyMu.Unlock()
+	if c.latency == nil {
+		c.latency = make(map[string]*latencyTotals)
+	}
+	t, ok := c.latency[provider]
+	if !ok {
+		t = &latencyTotals{}
+		c.latency[provider] = t
+	}
+	update(t)
+}
+
+// timeFirstChunk relays a provider's stream, recording the time from
+// start to its first audio chunk.
+func (c *Client) timeFirstChunk(provider string, start time.Time, in <-chan StreamChunk) <-chan StreamChunk {
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		first := true
+		for chunk := range in {
+			if first && len(chunk.Audio) > 0 {
+				first = false
+				d := clock.Since(c.clock, start)
+				c.record(provider, func(t *latencyTotals) {
+					t.streams++
+					t.firstChunk += d
+					t.maxFirst = max(t.maxFirst, d)
+				})
+			}
+			out <- chunk
+		}
+	}()
+	return out
+}
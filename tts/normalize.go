@@ -0,0 +1,76 @@
+package tts
+
+import (
+	"strings"
+	"sync"
+)
+
+// Normalizer rewrites text into the words a TTS voice should speak for one
+// language.
+type Normalizer interface {
+	// Normalize expands numbers, currencies, dates, and similar tokens.
+	Normalize(text string) string
+}
+
+// NormalizerFunc adapts a function to the Normalizer interface.
+type NormalizerFunc func(text string) string
+
+// Normalize calls f(text).
+func (f NormalizerFunc) Normalize(text string) string { return f(text) }
+
+var (
+	normalizersMu sync.RWMutex
+	normalizers   = map[string]Normalizer{
+		"en": EnglishNormalizer{},
+	}
+)
+
+// RegisterNormalizer sets the normalizer for a language. The language may
+// be a base tag ("de") or a full BCP-47 tag ("en-GB"); full tags take
+// precedence.
+func RegisterNormalizer(language string, n Normalizer) {
+	normalizersMu.Lock()
+	defer normalizersMu.Unlock()
+	normalizers[strings.ToLower(language)] = n
+}
+
+// Normalize expands numbers, currencies, dates, times, and ordinals in text
+// into spoken form for language, falling back to English when no
+// normalizer is registered for it. SSML documents are returned unchanged,
+// since they carry their own say-as markup.
+func Normalize(text, language string) string {
+	if isSSML(text) {
+		return text
+	}
+	return normalizerFor(language).Normalize(text)
+}
+
+func normalizerFor(language string) Normalizer {
+	language = strings.ToLower(language)
+	normalizersMu.RLock()
+	defer normalizersMu.RUnlock()
+	if n, ok := normalizers[language]; ok {
+		return n
+	}
+	base, _, _ := strings.Cut(language, "-")
+	if n, ok := normalizers[base]; ok {
+		return n
+	}
+	return normalizers["en"]
+}
+
+func isSSML(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "<speak")
+}
+
+// prepareText applies the text processing enabled in config.
+func prepareText(text string, config SynthesisConfig) string {
+	text = ExpandSpelling(text, config.Language)
+	if config.Abbreviations != nil {
+		text = config.Abbreviations.Expand(text)
+	}
+	if config.Normalize {
+		text = Normalize(text, config.Language)
+	}
+	return text
+}
@@ -0,0 +1,270 @@
+package tts
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EnglishNormalizer is the default Normalizer. It expands:
+//
+//   - currency: "$1,234.56" → "one thousand two hundred thirty-four dollars and fifty-six cents"
+//   - ISO dates: "2024-03-01" → "March first, twenty twenty-four"
+//   - times: "14:30" → "two thirty p.m.", "9:00" → "nine o'clock"
+//   - ordinals: "22nd" → "twenty-second"
+//   - percentages: "50%" → "fifty percent"
+//   - numbers: "1,234.5" → "one thousand two hundred thirty-four point five",
+//     with bare four-digit numbers from 1100 to 2099 read as years
+type EnglishNormalizer struct{}
+
+var enPattern = regexp.MustCompile(
+	`\b(\d{4})-(\d{2})-(\d{2})\b` + // 1-3 date
+		`|\b(\d{1,2}):(\d{2})(?:\s*([aApP])\.?[mM]\b\.?)?` + // 4-6 time
+		`|([$€£])(\d{1,3}(?:,\d{3})+|\d+)(?:\.(\d{1,2}))?\b` + // 7-9 currency
+		`|\b(\d+)(st|nd|rd|th)\b` + // 10-11 ordinal
+		`|(-?)\b(\d{1,3}(?:,\d{3})+|\d+)(?:\.(\d+))?\b(%?)`) // 12-15 number
+
+// Normalize implements Normalizer.
+func (EnglishNormalizer) Normalize(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range enPattern.FindAllStringSubmatchIndex(text, -1) {
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return text[m[2*i]:m[2*i+1]]
+		}
+		start := m[0]
+		var spoken string
+		switch {
+		case m[2] >= 0:
+			spoken = enDate(group(1), group(2), group(3))
+		case m[8] >= 0:
+			spoken = enTime(group(4), group(5), group(6))
+		case m[14] >= 0:
+			spoken = enCurrency(group(7), group(8), group(9))
+		case m[20] >= 0:
+			n, err := strconv.ParseInt(group(10), 10, 64)
+			if err == nil {
+				spoken = enOrdinal(n)
+			}
+		default:
+			// A hyphen is a minus sign only at the start of a word.
+			negative := group(12) != "" && (start == 0 || strings.ContainsRune(" \t\n(", rune(text[start-1])))
+			if group(12) != "" && !negative {
+				start = m[26]
+			}
+			spoken = enNumber(group(13), group(14), negative)
+			if spoken != "" && group(15) != "" {
+				spoken += " percent"
+			}
+		}
+		if spoken == "" {
+			continue
+		}
+		if strings.HasSuffix(spoken, ".") && strings.HasPrefix(text[m[1]:], ".") {
+			spoken = strings.TrimSuffix(spoken, ".")
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(spoken)
+		last = m[1]
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+var enMonths = [...]string{"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+func enDate(year, month, day string) string {
+	y, _ := strconv.Atoi(year)
+	mo, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	if mo < 1 || mo > 12 || d < 1 || d > 31 {
+		return ""
+	}
+	return enMonths[mo-1] + " " + enOrdinal(int64(d)) + ", " + enYear(y)
+}
+
+func enTime(hour, minute, meridiem string) string {
+	h, _ := strconv.Atoi(hour)
+	m, _ := strconv.Atoi(minute)
+	if h > 23 || m > 59 || (meridiem != "" && (h < 1 || h > 12)) {
+		return ""
+	}
+	suffix := ""
+	switch strings.ToLower(meridiem) {
+	case "a":
+		suffix = " a.m."
+	case "p":
+		suffix = " p.m."
+	default:
+		if h > 12 {
+			h -= 12
+			suffix = " p.m."
+		} else if h == 0 {
+			h = 12
+			suffix = " a.m."
+		}
+	}
+	spoken := enCardinal(int64(h))
+	switch {
+	case m == 0 && suffix == "":
+		spoken += " o'clock"
+	case m == 0:
+	case m < 10:
+		spoken += " oh " + enCardinal(int64(m))
+	default:
+		spoken += " " + enCardinal(int64(m))
+	}
+	return spoken + suffix
+}
+
+var enCurrencies = map[string][2]string{
+	"$": {"dollar", "cent"},
+	"€": {"euro", "cent"},
+	"£": {"pound", "penny"},
+}
+
+func enCurrency(symbol, whole, fraction string) string {
+	n, err := strconv.ParseInt(strings.ReplaceAll(whole, ",", ""), 10, 64)
+	if err != nil {
+		return ""
+	}
+	names := enCurrencies[symbol]
+	spoken := enCardinal(n) + " " + enPlural(names[0], n)
+	if fraction != "" {
+		if len(fraction) == 1 {
+			fraction += "0"
+		}
+		c, _ := strconv.ParseInt(fraction, 10, 64)
+		if c > 0 {
+			sub := enPlural(names[1], c)
+			if names[1] == "penny" && c != 1 {
+				sub = "pence"
+			}
+			spoken += " and " + enCardinal(c) + " " + sub
+		}
+	}
+	return spoken
+}
+
+func enPlural(word string, n int64) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func enNumber(whole, fraction string, negative bool) string {
+	digits := strings.ReplaceAll(whole, ",", "")
+	if len(digits) > 15 {
+		return ""
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return ""
+	}
+	var spoken string
+	if len(digits) == 4 && whole == digits && fraction == "" && !negative && n >= 1100 && n < 2100 {
+		spoken = enYear(int(n))
+	} else {
+		spoken = enCardinal(n)
+	}
+	if fraction != "" {
+		words := make([]string, len(fraction))
+		for i, d := range fraction {
+			words[i] = enOnes[d-'0']
+		}
+		spoken += " point " + strings.Join(words, " ")
+	}
+	if negative {
+		spoken = "minus " + spoken
+	}
+	return spoken
+}
+
+// enYear reads a year the way it is spoken: 1999 → "nineteen ninety-nine",
+// 2005 → "two thousand five", 1900 → "nineteen hundred".
+func enYear(y int) string {
+	hi, lo := y/100, y%100
+	switch {
+	case y < 1000 || y >= 10000:
+		return enCardinal(int64(y))
+	case y >= 2000 && y < 2010:
+		return enCardinal(int64(y))
+	case lo == 0:
+		return enCardinal(int64(hi)) + " hundred"
+	case lo < 10:
+		return enCardinal(int64(hi)) + " oh " + enCardinal(int64(lo))
+	default:
+		return enCardinal(int64(hi)) + " " + enCardinal(int64(lo))
+	}
+}
+
+var (
+	enOnes = [...]string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	enTens   = [...]string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	enScales = [...]string{"", " thousand", " million", " billion", " trillion"}
+)
+
+func enCardinal(n int64) string {
+	if n < 0 {
+		return "minus " + enCardinal(-n)
+	}
+	if n < 20 {
+		return enOnes[n]
+	}
+	var parts []string
+	for scale := 0; n > 0; scale++ {
+		if group := n % 1000; group > 0 {
+			parts = append([]string{enHundreds(int(group)) + enScales[scale]}, parts...)
+		}
+		n /= 1000
+	}
+	return strings.Join(parts, " ")
+}
+
+func enHundreds(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, enOnes[n/100]+" hundred")
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		parts = append(parts, enOnes[n])
+	case n%10 == 0:
+		parts = append(parts, enTens[n/10])
+	default:
+		parts = append(parts, enTens[n/10]+"-"+enOnes[n%10])
+	}
+	return strings.Join(parts, " ")
+}
+
+var enOrdinalWords = map[string]string{
+	"one": "first", "two": "second", "three": "third", "five": "fifth",
+	"eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+}
+
+func enOrdinal(n int64) string {
+	words := enCardinal(n)
+	// Replace the final word, which may follow a hyphen or space.
+	i := strings.LastIndexAny(words, " -") + 1
+	head, tail := words[:i], words[i:]
+	switch {
+	case enOrdinalWords[tail] != "":
+		tail = enOrdinalWords[tail]
+	case strings.HasSuffix(tail, "y"):
+		tail = strings.TrimSuffix(tail, "y") + "ieth"
+	default:
+		tail += "th"
+	}
+	return head + tail
+}
@@ -0,0 +1,266 @@
+package tts
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// PrebufferConfig configures Prebuffer.
+type PrebufferConfig struct {
+	// SampleRate is the sample rate of the PCM stream in Hz. Required:
+	// without it the stream ends with an error chunk wrapping
+	// ErrInvalidConfig.
+	SampleRate int
+
+	// Channels is the number of interleaved channels. Defaults to 1.
+	Channels int
+
+	// Prebuffer is how much audio is accumulated before playback begins.
+	// Defaults to 200ms.
+	Prebuffer time.Duration
+
+	// TargetDepth is the buffer depth restored after an underrun before
+	// normal playback resumes. Defaults to Prebuffer.
+	TargetDepth time.Duration
+
+	// FrameDuration is the duration of each output chunk. Defaults to 20ms.
+	FrameDuration time.Duration
+
+	// MaxGap is the longest underrun filled with comfort noise. Beyond it,
+	// output pauses until TargetDepth is buffered again. Defaults to 300ms.
+	MaxGap time.Duration
+
+	// ComfortNoiseLevel is the peak amplitude (0.0-1.0) of the noise
+	// inserted during underruns. Defaults to 0.001 (about -60 dBFS).
+	ComfortNoiseLevel float64
+}
+
+func (c PrebufferConfig) withDefaults() PrebufferConfig {
+	if c.Channels <= 0 {
+		c.Channels = 1
+	}
+	if c.Prebuffer <= 0 {
+		c.Prebuffer = 200 * time.Millisecond
+	}
+	if c.TargetDepth <= 0 {
+		c.TargetDepth = c.Prebuffer
+	}
+	if c.FrameDuration <= 0 {
+		c.FrameDuration = 20 * time.Millisecond
+	}
+	if c.MaxGap <= 0 {
+		c.MaxGap = 300 * time.Millisecond
+	}
+	if c.ComfortNoiseLevel <= 0 {
+		c.ComfortNoiseLevel = 0.001
+	}
+	return c
+}
+
+// BufferStats reports the playback health of a BufferedStream.
+type BufferStats struct {
+	// Underruns is the number of times the buffer ran dry mid-stream.
+	Underruns int
+
+	// UnderrunDuration is the total time spent without source audio,
+	// including time filled with comfort noise.
+	UnderrunDuration time.Duration
+
+	// Depth is the audio currently buffered.
+	Depth time.Duration
+
+	// Played is the duration of source audio delivered.
+	Played time.Duration
+}
+
+// BufferedStream is a paced, prebuffered view of a PCM StreamChunk
+// channel created by Prebuffer.
+type BufferedStream struct {
+	config PrebufferConfig
+	out    chan StreamChunk
+
+	mu    sync.Mutex
+	stats BufferStats
+}
+
+// Prebuffer wraps a 16-bit PCM stream, such as the channel returned by
+// Client.SynthesizeStream, for real-time playback. Output starts once
+// config.Prebuffer of audio has arrived (or the source finishes) and is
+// then delivered in FrameDuration chunks at playback speed. When the
+// source falls behind, comfort noise is inserted until TargetDepth has
+// been rebuffered; underruns longer than MaxGap pause output instead.
+// Error chunks are forwarded immediately and end the stream.
+func Prebuffer(ctx context.Context, in <-chan StreamChunk, config PrebufferConfig) *BufferedStream {
+	b := &BufferedStream{
+		config: config.withDefaults(),
+		out:    make(chan StreamChunk),
+	}
+	go b.run(ctx, in)
+	return b
+}
+
+// Chunks returns the paced output stream.
+func (b *BufferedStream) Chunks() <-chan StreamChunk {
+	return b.out
+}
+
+// Stats returns a snapshot of the playback statistics.
+func (b *BufferedStream) Stats() BufferStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stats
+}
+
+func (b *BufferedStream) bytesFor(d time.Duration) int {
+	n := int(d * time.Duration(audio.BytesPerSecond(b.config.SampleRate, b.config.Channels)) / time.Second)
+	align := audio.BytesPerSample * b.config.Channels
+	return n - n%align
+}
+
+func (b *BufferedStream) durationOf(n int) time.Duration {
+	return time.Duration(n) * time.Second / time.Duration(audio.BytesPerSecond(b.config.SampleRate, b.config.Channels))
+}
+
+func (b *BufferedStream) run(ctx context.Context, in <-chan StreamChunk) {
+	defer close(b.out)
+	cfg := b.config
+	if cfg.SampleRate <= 0 {
+		go drain(in)
+		select {
+		case b.out <- StreamChunk{Error: fmt.Errorf("%w: prebuffer needs the stream's sample rate", ErrInvalidConfig), IsFinal: true}:
+		case <-ctx.Done():
+		}
+		return
+	}
+	frame := b.bytesFor(cfg.FrameDuration)
+	startDepth, targetDepth := b.bytesFor(cfg.Prebuffer), b.bytesFor(cfg.TargetDepth)
+
+	var (
+		buf        []byte
+		done       bool
+		recovering bool
+		gap        time.Duration
+		ticker     *time.Ticker
+		tick       <-chan time.Time
+	)
+	play := func() {
+		if ticker == nil {
+			ticker = time.NewTicker(cfg.FrameDuration)
+			tick = ticker.C
+		}
+	}
+	pause := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+	}
+	defer pause()
+	send := func(c StreamChunk) bool {
+		select {
+		case b.out <- c:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case chunk, ok := <-in:
+			if !ok {
+				in, done = nil, true
+				play()
+				continue
+			}
+			if chunk.Error != nil {
+				send(chunk)
+				return
+			}
+			buf = append(buf, chunk.Audio...)
+			done = done || chunk.IsFinal
+			b.setDepth(len(buf))
+			need := startDepth
+			if recovering {
+				need = targetDepth
+			}
+			if len(buf) >= need || done {
+				play()
+			}
+
+		case <-tick:
+			if recovering && (len(buf) >= targetDepth || done) {
+				recovering, gap = false, 0
+			}
+			if !recovering && (len(buf) >= frame || (done && len(buf) > 0)) {
+				n := min(frame, len(buf))
+				chunk := StreamChunk{Audio: append([]byte(nil), buf[:n]...)}
+				buf = buf[n:]
+				chunk.IsFinal = done && len(buf) == 0
+				b.played(n, len(buf))
+				if !send(chunk) || chunk.IsFinal {
+					return
+				}
+				continue
+			}
+			if done && len(buf) == 0 {
+				send(StreamChunk{IsFinal: true})
+				return
+			}
+
+			// Underrun: rebuffer to the target depth, covering brief gaps
+			// with comfort noise.
+			if !recovering {
+				recovering = true
+				b.mu.Lock()
+				b.stats.Underruns++
+				b.mu.Unlock()
+			}
+			gap += cfg.FrameDuration
+			b.mu.Lock()
+			b.stats.UnderrunDuration += cfg.FrameDuration
+			b.mu.Unlock()
+			if gap > cfg.MaxGap {
+				pause()
+				continue
+			}
+			if !send(StreamChunk{Audio: b.comfortNoise(frame)}) {
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *BufferedStream) setDepth(n int) {
+	b.mu.Lock()
+	b.stats.Depth = b.durationOf(n)
+	b.mu.Unlock()
+}
+
+func (b *BufferedStream) played(n, remaining int) {
+	b.mu.Lock()
+	b.stats.Played += b.durationOf(n)
+	b.stats.Depth = b.durationOf(remaining)
+	b.mu.Unlock()
+}
+
+// comfortNoise returns n bytes of low-level white noise, which sounds
+// less jarring than digital silence on telephone lines.
+func (b *BufferedStream) comfortNoise(n int) []byte {
+	out := make([]byte, n)
+	peak := b.config.ComfortNoiseLevel * 32767
+	for i := 0; i+1 < n; i += audio.BytesPerSample {
+		v := int16((rand.Float64()*2 - 1) * peak)         // #nosec G404 -- noise, not security
+		binary.LittleEndian.PutUint16(out[i:], uint16(v)) // #nosec G115 -- two's complement reinterpretation
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPrebufferWithoutSampleRate(t *testing.T) {
+	in := make(chan StreamChunk)
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		defer close(in)
+		for range 3 {
+			in <- StreamChunk{Audio: make([]byte, 320)}
+		}
+	}()
+
+	var chunks []StreamChunk
+	for c := range Prebuffer(context.Background(), in, PrebufferConfig{}).Chunks() {
+		chunks = append(chunks, c)
+	}
+	if len(chunks) != 1 || !errors.Is(chunks[0].Error, ErrInvalidConfig) {
+		t.Errorf("got %+v, want a single ErrInvalidConfig chunk", chunks)
+	}
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("source not drained")
+	}
+}
+
+func TestPrebufferPacesFrames(t *testing.T) {
+	const rate = 16000
+	src := make([]byte, rate*2/10) // 100ms
+	for i := range src {
+		src[i] = byte(i)
+	}
+	in := make(chan StreamChunk, 1)
+	in <- StreamChunk{Audio: src, IsFinal: true}
+	close(in)
+
+	b := Prebuffer(context.Background(), in, PrebufferConfig{SampleRate: rate, Prebuffer: 40 * time.Millisecond})
+	var got []byte
+	var last StreamChunk
+	start := time.Now()
+	for c := range b.Chunks() {
+		if c.Error != nil {
+			t.Fatal(c.Error)
+		}
+		if len(c.Audio) > 640 {
+			t.Errorf("chunk of %d bytes, want 20ms frames of 640", len(c.Audio))
+		}
+		got = append(got, c.Audio...)
+		last = c
+	}
+	if !bytes.Equal(got, src) {
+		t.Errorf("played %d bytes differing from the %d streamed", len(got), len(src))
+	}
+	if !last.IsFinal {
+		t.Error("last chunk not final")
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("100ms of audio played in %v, want playback speed", elapsed)
+	}
+	if s := b.Stats(); s.Played != 100*time.Millisecond || s.Underruns != 0 {
+		t.Errorf("stats %+v, want 100ms played without underruns", s)
+	}
+}
@@ -0,0 +1,25 @@
+package tts
+
+import (
+	"context"
+
+	"github.com/agentplexus/omnivoice/ratelimit"
+)
+
+// SetRateLimit limits requests to the named provider. Requests queue for
+// admission and, if not admitted in time, fall through to the next
+// provider. A zero Limit removes the limiter.
+func (c *Client) SetRateLimit(provider string, limit ratelimit.Limit) {
+	c.limits.Set(provider, limit)
+}
+
+// QueueDepth returns the number of requests waiting for admission to the
+// named provider.
+func (c *Client) QueueDepth(provider string) int {
+	return c.limits.QueueDepth(provider)
+}
+
+// acquire waits for admission to the named provider.
+func (c *Client) acquire(ctx context.Context, provider string) (func(), error) {
+	return c.limits.Acquire(ctx, provider)
+}
@@ -0,0 +1,212 @@
+package tts
+
+import (
+	"cmp"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// spellPause separates the groups of spelled-out text.
+const spellPause = "... "
+
+// spellBreak separates the groups of spelled-out SSML.
+const spellBreak = `<break time="400ms"/>`
+
+// spellSymbols are the spoken names of symbols in spelled-out text, by
+// base language. Other languages use English.
+var spellSymbols = map[string]map[rune]string{
+	"en": {'@': "at", '.': "dot", '-': "dash", '_': "underscore", '+': "plus", '/': "slash", '#': "hash"},
+	"de": {'@': "at", '.': "Punkt", '-': "Bindestrich", '_': "Unterstrich", '+': "plus", '/': "Schrägstrich", '#': "Raute"},
+	"fr": {'@': "arobase", '.': "point", '-': "tiret", '_': "tiret bas", '+': "plus", '/': "barre oblique", '#': "dièse"},
+	"es": {'@': "arroba", '.': "punto", '-': "guion", '_': "guion bajo", '+': "más", '/': "barra", '#': "almohadilla"},
+}
+
+// phoneGroups are the digit groupings of phone numbers by language and
+// number length. Numbers without an entry are read in threes.
+var phoneGroups = map[string]map[int][]int{
+	"en":    {10: {3, 3, 4}, 11: {1, 3, 3, 4}, 7: {3, 4}},
+	"en-gb": {11: {5, 3, 3}, 10: {4, 3, 3}},
+	"en-au": {10: {4, 3, 3}},
+	"fr":    {10: {2, 2, 2, 2, 2}},
+	"es":    {9: {3, 3, 3}},
+	"de":    {11: {4, 3, 4}, 10: {4, 3, 3}},
+}
+
+// SpellOut returns text as TTS should read it back character by character,
+// as for confirmation codes, email addresses, and phone numbers. Letters
+// and digits are read singly, in short groups separated by pauses, and
+// symbols by name in language:
+//
+//	SpellOut("X7K2QZ", "en")        → "X 7 K... 2 Q Z"
+//	SpellOut("jo.doe@x.com", "en")  → "J O... dot... D O E... at... X... dot... C O M"
+//	SpellOut("5551234567", "en")    → "5 5 5... 1 2 3... 4 5 6 7"
+//	SpellOut("0612345678", "fr")    → "0 6... 1 2... 3 4... 5 6... 7 8"
+//
+// Text made only of digits and phone punctuation is read as a phone
+// number: in the groups it is written in, or if it is written without
+// separators, in the usual grouping for its length in language. See
+// SpellOutSSML for providers with SSML support.
+func SpellOut(text, language string) string {
+	groups := spellGroups(text, language)
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		if g.word != "" {
+			parts[i] = g.word
+			continue
+		}
+		chars := make([]string, 0, len(g.chars))
+		for _, r := range g.chars {
+			chars = append(chars, string(unicode.ToUpper(r)))
+		}
+		parts[i] = strings.Join(chars, " ")
+	}
+	return strings.Join(parts, spellPause)
+}
+
+// SpellOutSSML is SpellOut as an SSML fragment, to embed in a <speak>
+// document: each group is a say-as "characters" element, and groups are
+// separated by breaks.
+func SpellOutSSML(text, language string) string {
+	groups := spellGroups(text, language)
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		if g.word != "" {
+			parts[i] = escapeXML(g.word)
+			continue
+		}
+		parts[i] = `<say-as interpret-as="characters">` + escapeXML(string(g.chars)) + `</say-as>`
+	}
+	return strings.Join(parts, spellBreak)
+}
+
+// spellMarkup matches <spell>...</spell> spans in plain text.
+var spellMarkup = regexp.MustCompile(`(?s)<spell>(.*?)</spell>`)
+
+// ExpandSpelling replaces each <spell>...</spell> span in text with its
+// SpellOut, so a prompt can have the LLM mark the fields to read back
+// ("Your code is <spell>X7K2</spell>."). Synthesis does this for every
+// request before other text processing. SSML documents are returned
+// unchanged; use SpellOutSSML in them.
+func ExpandSpelling(text, language string) string {
+	if isSSML(text) || !strings.Contains(text, "<spell>") {
+		return text
+	}
+	return spellMarkup.ReplaceAllStringFunc(text, func(m string) string {
+		return SpellOut(spellMarkup.FindStringSubmatch(m)[1], language)
+	})
+}
+
+// spellGroup is a run of characters to read singly, or a symbol's name.
+type spellGroup struct {
+	chars []rune
+	word  string
+}
+
+func spellGroups(text, language string) []spellGroup {
+	var groups []spellGroup
+	if written, ok := phoneDigits(text); ok {
+		if strings.HasPrefix(strings.TrimSpace(text), "+") {
+			groups = append(groups, spellGroup{word: symbolName('+', language)})
+		}
+		for _, digits := range written {
+			rest := []rune(digits)
+			sizes := threes(len(rest))
+			if len(written) == 1 {
+				sizes = phoneGrouping(len(rest), language)
+			}
+			for _, n := range sizes {
+				groups = append(groups, spellGroup{chars: rest[:n]})
+				rest = rest[n:]
+			}
+		}
+		return groups
+	}
+
+	var run []rune
+	flush := func() {
+		rest := run
+		for _, n := range threes(len(rest)) {
+			groups = append(groups, spellGroup{chars: rest[:n]})
+			rest = rest[n:]
+		}
+		run = nil
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			run = append(run, r)
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			groups = append(groups, spellGroup{word: symbolName(r, language)})
+		}
+	}
+	flush()
+	return groups
+}
+
+// phoneDigits returns the digit groups of text as written if it looks
+// like a phone number: at least seven digits, with only spaces, dashes,
+// dots, parentheses, and a leading plus besides.
+func phoneDigits(text string) ([]string, bool) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "+")
+	n := 0
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			n++
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return nil, false
+		}
+	}
+	return strings.FieldsFunc(text, func(r rune) bool { return r < '0' || r > '9' }), n >= 7
+}
+
+// phoneGrouping returns the group sizes for a phone number of n digits.
+func phoneGrouping(n int, language string) []int {
+	language = strings.ToLower(cmp.Or(language, "en"))
+	base, _, _ := strings.Cut(language, "-")
+	for _, tag := range []string{language, base} {
+		if g, ok := phoneGroups[tag][n]; ok {
+			return g
+		}
+		if _, ok := phoneGroups[tag]; ok {
+			break
+		}
+	}
+	return threes(n)
+}
+
+// threes splits n characters into groups of three, with a last group of
+// four rather than a lone character.
+func threes(n int) []int {
+	var sizes []int
+	for n > 4 {
+		sizes = append(sizes, 3)
+		n -= 3
+	}
+	if n > 0 {
+		sizes = append(sizes, n)
+	}
+	return sizes
+}
+
+// symbolName returns the spoken name of a symbol in language, or the
+// symbol itself if it has none.
+func symbolName(r rune, language string) string {
+	names, ok := spellSymbols[baseLanguage(language)]
+	if !ok {
+		names = spellSymbols["en"]
+	}
+	if name, ok := names[r]; ok {
+		return name
+	}
+	return string(r)
+}
+
+func escapeXML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;").Replace(s)
+}
@@ -0,0 +1,38 @@
+package tts
+
+import "context"
+
+// Speaking styles for SynthesisConfig.Style. Providers map them to their
+// own controls as follows, and ignore styles they cannot express:
+//
+//	Provider     Mechanism                         Styles honored
+//	Azure        mstts:express-as style/styledegree all (voice-dependent)
+//	ElevenLabs   style exaggeration + stability    degree only; style as a hint
+//	Cartesia     emotion controls                  cheerful, sad, angry, excited
+//	Google       none                              ignored
+//	AWS Polly    newscaster (neural voices)        newscaster only
+//
+// Other values are passed through unchanged to providers that accept
+// free-form styles.
+const (
+	StyleNeutral         = "neutral"
+	StyleCheerful        = "cheerful"
+	StyleEmpathetic      = "empathetic"
+	StyleCalm            = "calm"
+	StyleSad             = "sad"
+	StyleAngry           = "angry"
+	StyleExcited         = "excited"
+	StyleWhispering      = "whispering"
+	StyleNewscaster      = "newscaster"
+	StyleCustomerService = "customerservice"
+)
+
+// StyleProvider is implemented by providers that can report which styles
+// a voice supports.
+type StyleProvider interface {
+	Provider
+
+	// SupportedStyles returns the styles the voice honors, or nil if the
+	// provider does not support styles for it.
+	SupportedStyles(ctx context.Context, voiceID string) ([]string, error)
+}
@@ -0,0 +1,95 @@
+package tts
+
+import (
+	"context"
+	"time"
+
+	"github.com/agentplexus/omnivoice/clock"
+	"github.com/agentplexus/omnivoice/internal/fallback"
+)
+
+// Timeouts bounds how long Synthesize and the start of SynthesizeStream
+// wait on each provider before moving to the next, so one that stalls
+// cannot hold up the audio a caller is waiting to play. Zero fields set
+// no bound. The timeouts run on the Client's clock.
+type Timeouts struct {
+	// Attempt bounds each provider attempt. For SynthesizeStream it bounds
+	// the wait for the first chunk; once audio flows the stream runs on.
+	Attempt time.Duration
+
+	// Providers overrides Attempt by provider name.
+	Providers map[string]time.Duration
+
+	// Overall bounds a Synthesize request across all its attempts, and
+	// the start of a SynthesizeStream (up to its first chunk).
+	Overall time.Duration
+}
+
+// SetTimeouts sets the provider timeouts. A zero Timeouts removes them.
+func (c *Client) SetTimeouts(t Timeouts) {
+	c.timeouts = t
+}
+
+// withOverall bounds ctx by the overall timeout.
+func (c *Client) withOverall(ctx context.Context) (context.Context, context.CancelFunc) {
+	return fallback.Timeouts(c.timeouts).WithOverall(ctx, c.clock)
+}
+
+// withAttempt bounds ctx by the named provider's attempt timeout.
+func (c *Client) withAttempt(ctx context.Context, provider string) (context.Context, context.CancelFunc) {
+	return fallback.Timeouts(c.timeouts).WithAttempt(ctx, c.clock, provider)
+}
+
+// awaitFirst waits up to d on clk (without bound if zero) and until wait ends
+// for the first chunk of a stream. If it arrives the returned stream
+// replays it and forwards the rest as forward does, calling stop at the
+// end; otherwise stop is called, the stream is drained, and ok is false.
+func awaitFirst(wait, ctx context.Context, in <-chan StreamChunk, clk clock.Clock, d time.Duration, stop func()) (<-chan StreamChunk, bool) {
+	var timeout <-chan time.Time
+	if d > 0 {
+		timer := clk.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	var first StreamChunk
+	var open bool
+	select {
+	case first, open = <-in:
+	case <-timeout:
+		stop()
+		go drain(in)
+		return nil, false
+	case <-wait.Done():
+		stop()
+		go drain(in)
+		return nil, false
+	}
+
+	if !open {
+		return forward(ctx, in, nil, stop), true
+	}
+	return forward(ctx, in, []StreamChunk{first}, stop), true
+}
+
+// startStream starts a provider's stream, bounded by the named provider's
+// attempt timeout and by wait up to its first chunk.
+func (c *Client) startStream(wait, ctx context.Context, p Provider, name, text string, config SynthesisConfig) (<-chan StreamChunk, error) {
+	d := fallback.Timeouts(c.timeouts).AttemptFor(name)
+	if d <= 0 && c.timeouts.Overall <= 0 {
+		return p.SynthesizeStream(ctx, text, config)
+	}
+	ctx, stop := context.WithCancel(ctx)
+	stream, err := p.SynthesizeStream(ctx, text, config)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	stream, ok := awaitFirst(wait, ctx, stream, c.clock, d, stop)
+	if !ok {
+		if err := wait.Err(); err != nil {
+			return nil, err
+		}
+		return nil, context.DeadlineExceeded
+	}
+	return stream, nil
+}
@@ -0,0 +1,107 @@
+package tts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivoice/clock"
+)
+
+// stallingProvider is a fakeProvider whose streams send nothing until
+// their ctx ends.
+type stallingProvider struct {
+	fakeProvider
+	closed chan struct{}
+}
+
+func (p *stallingProvider) SynthesizeStream(ctx context.Context, text string, config SynthesisConfig) (<-chan StreamChunk, error) {
+	p.calls.Add(1)
+	ch := make(chan StreamChunk)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+		close(p.closed)
+	}()
+	return ch, nil
+}
+
+func TestSlowPrimaryLeavesTimeForFallback(t *testing.T) {
+	primary := &fakeProvider{name: "primary", delay: time.Hour}
+	c := NewClient(primary, &fakeProvider{name: "fallback"})
+	c.SetTimeouts(Timeouts{Attempt: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	result, err := c.Synthesize(ctx, "hello", SynthesisConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Audio) != "fallback" {
+		t.Errorf("synthesized by %q, want fallback", result.Audio)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fell back after %v, want about the 50ms attempt bound", elapsed)
+	}
+}
+
+func TestProviderTimeoutOverridesAttempt(t *testing.T) {
+	c := NewClient(&fakeProvider{name: "primary", delay: 50 * time.Millisecond}, &fakeProvider{name: "fallback"})
+	c.SetTimeouts(Timeouts{
+		Attempt:   10 * time.Millisecond,
+		Providers: map[string]time.Duration{"primary": 5 * time.Second},
+	})
+
+	result, err := c.Synthesize(context.Background(), "hello", SynthesisConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Audio) != "primary" {
+		t.Errorf("synthesized by %q, want primary within its own bound", result.Audio)
+	}
+}
+
+func TestStalledStreamFallsBackOnClientClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	primary := &stallingProvider{fakeProvider: fakeProvider{name: "primary"}, closed: make(chan struct{})}
+	c := NewClient(primary, &fakeProvider{name: "fallback"})
+	c.SetClock(clk)
+	c.SetTimeouts(Timeouts{Attempt: time.Minute})
+
+	done := make(chan []byte, 1)
+	go func() {
+		stream, err := c.SynthesizeStream(context.Background(), "hello", SynthesisConfig{})
+		if err != nil {
+			t.Error(err)
+			done <- nil
+			return
+		}
+		var audio []byte
+		for chunk := range stream {
+			audio = append(audio, chunk.Audio...)
+		}
+		done <- audio
+	}()
+
+	clk.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("stream started before the clock reached the first-chunk bound")
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(time.Minute)
+	select {
+	case audio := <-done:
+		if string(audio) != "fallback" {
+			t.Errorf("streamed %q, want the fallback's audio", audio)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stalled stream not abandoned when the clock passed its bound")
+	}
+	select {
+	case <-primary.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("abandoned stream's context not canceled")
+	}
+}
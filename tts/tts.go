@@ -4,6 +4,11 @@ package tts
 import (
 	"context"
 	"io"
+	"sync"
+
+	"github.com/agentplexus/omnivoice/clock"
+	"github.com/agentplexus/omnivoice/credentials"
+	"github.com/agentplexus/omnivoice/ratelimit"
 )
 
 // Voice represents a voice configuration for TTS.
@@ -52,6 +57,57 @@ type SynthesisConfig struct {
 
 	// SimilarityBoost enhances voice similarity (0.0 to 1.0, provider-specific).
 	SimilarityBoost float64
+
+	// Style is the speaking style or emotion (e.g., StyleCheerful). See
+	// the Style constants for provider support; providers without style
+	// support ignore it.
+	Style string
+
+	// StyleDegree is the style intensity (0.0 to 2.0, 1.0 = default,
+	// 0 = provider default).
+	StyleDegree float64
+
+	// Language is the BCP-47 language of the text (e.g., "en-US"), used
+	// by text normalization and passed to providers. Empty selects
+	// English. If the voice's language is known and clearly differs,
+	// synthesis fails with ErrInvalidConfig.
+	Language string
+
+	// Script is the ISO 15924 script of the text (e.g., "Latn", "Cyrl",
+	// "Hira"), for languages written in several scripts or romanized,
+	// such as Serbian or Japanese. Empty uses the script subtag of
+	// Language, if any; see SynthesisConfig.TextScript. Providers that
+	// support script selection pass it through; others ignore it.
+	Script string
+
+	// Normalize expands numbers, currencies, dates, times, and ordinals
+	// into spoken words with Normalize before synthesis. SSML input is
+	// left untouched.
+	Normalize bool
+
+	// Abbreviations, if set, expands abbreviations and acronyms before
+	// synthesis (and before Normalize).
+	Abbreviations *Abbreviations
+
+	// ProviderOptions holds settings specific to one provider, keyed by
+	// provider name (as Provider.Name returns) and then option name, for
+	// knobs the common fields do not cover:
+	//
+	//	ProviderOptions: map[string]map[string]any{
+	//		"elevenlabs": {"optimize_streaming_latency": 3},
+	//	}
+	//
+	// Each provider reads only its own entry, with ProviderOption, and
+	// ignores option names it does not know; other providers ignore the
+	// entry. Values are as the provider's API takes them.
+	ProviderOptions map[string]map[string]any
+}
+
+// ProviderOption returns the option named key in provider's entry of
+// ProviderOptions.
+func (c SynthesisConfig) ProviderOption(provider, key string) (any, bool) {
+	v, ok := c.ProviderOptions[provider][key]
+	return v, ok
 }
 
 // SynthesisResult contains the result of a TTS synthesis.
@@ -65,6 +121,9 @@ type SynthesisResult struct {
 	// SampleRate is the sample rate of the audio.
 	SampleRate int
 
+	// Channels is the number of audio channels (0 is treated as mono).
+	Channels int
+
 	// DurationMs is the duration of the audio in milliseconds.
 	DurationMs int
 
@@ -84,7 +143,8 @@ type StreamChunk struct {
 	Error error
 }
 
-// Provider defines the interface for TTS providers.
+// Provider defines the interface for TTS providers. Request failures,
+// including stream errors, should be classified with NewProviderError.
 type Provider interface {
 	// Name returns the provider name.
 	Name() string
@@ -93,6 +153,11 @@ type Provider interface {
 	Synthesize(ctx context.Context, text string, config SynthesisConfig) (*SynthesisResult, error)
 
 	// SynthesizeStream converts text to speech with streaming output.
+	//
+	// Canceling ctx must promptly stop synthesis: close the upstream
+	// connection or request, so the provider stops billing, and close the
+	// channel. Client.SynthesizeStream and CloseOnCancel also end the
+	// stream they return with a StreamChunk with IsFinal set.
 	SynthesizeStream(ctx context.Context, text string, config SynthesisConfig) (<-chan StreamChunk, error)
 
 	// ListVoices returns available voices from this provider.
@@ -108,6 +173,8 @@ type StreamingProvider interface {
 
 	// SynthesizeFromReader reads text from a reader and streams audio output.
 	// Useful for streaming LLM output directly to TTS.
+	// Canceling ctx stops synthesis as for SynthesizeStream, even while
+	// reader still has text.
 	SynthesizeFromReader(ctx context.Context, reader io.Reader, config SynthesisConfig) (<-chan StreamChunk, error)
 }
 
@@ -116,12 +183,31 @@ type Client struct {
 	providers map[string]Provider
 	primary   string
 	fallbacks []string
+	limits    ratelimit.Group
+
+	credentials credentials.Resolver
+	dryRun      *DryRunConfig
+	timeouts    Timeouts
+
+	// voiceLanguages caches voice languages by provider and voice ID.
+	voiceLanguages sync.Map
+
+	// aliases maps voice aliases to voice IDs by provider.
+	aliasMu sync.RWMutex
+	aliases map[string]map[string]string
+
+	clock clock.Clock
+
+	// latency holds measured responsiveness by provider.
+	latencyMu sync.Mutex
+	latency   map[string]*latencyTotals
 }
 
 // NewClient creates a new TTS client with the specified providers.
 func NewClient(providers ...Provider) *Client {
 	c := &Client{
 		providers: make(map[string]Provider),
+		clock:     clock.Real(),
 	}
 	for i, p := range providers {
 		c.providers[p.Name()] = p
@@ -134,6 +220,14 @@ func NewClient(providers ...Provider) *Client {
 	return c
 }
 
+// SetClock sets the clock timing provider timeouts (see SetTimeouts), rate
+// limit queueing (see SetRateLimit), and Latency, for deterministic tests.
+// A nil clock restores the system clock.
+func (c *Client) SetClock(clk clock.Clock) {
+	c.clock = clock.Or(clk)
+	c.limits.SetClock(c.clock)
+}
+
 // SetPrimary sets the primary provider by name.
 func (c *Client) SetPrimary(name string) {
 	c.primary = name
@@ -151,48 +245,138 @@ func (c *Client) Provider(name string) (Provider, bool) {
 }
 
 // Synthesize uses the primary provider with automatic fallback.
+// Providers with a rate limit that cannot admit the request in time are
+// skipped; if nothing else succeeds the error also wraps ErrRateLimited.
+// Providers whose Capabilities cannot serve the request are skipped
+// too, and if none qualify the error is ErrNoCapableProvider.
+// A VoiceID registered with RegisterVoice is resolved for each provider.
+// Requests with a VoiceContinuity defer to it for the provider order.
+// Attempts are bounded by SetTimeouts as well as ctx. If every attempt
+// fails, the error wraps each as a ProviderError.
 func (c *Client) Synthesize(ctx context.Context, text string, config SynthesisConfig) (*SynthesisResult, error) {
-	// Try primary provider
-	if p, ok := c.providers[c.primary]; ok {
-		result, err := p.Synthesize(ctx, text, config)
+	ctx, cancel := c.withOverall(ctx)
+	defer cancel()
+	text = prepareText(text, config)
+	voice := config.VoiceID
+	limited := false
+	var credErr error
+	var skipped skips
+	var failures []error
+	for _, name := range c.providerOrder(ctx) {
+		p, ok := c.providers[name]
+		if !ok {
+			continue
+		}
+		config, ok := c.withVoice(name, config)
+		if !ok {
+			skipped.noVoice(name, voice)
+			continue
+		}
+		if !skipped.check(p, text, config) {
+			continue
+		}
+		pctx, err := c.withCredentials(ctx, name)
+		if err != nil {
+			credErr = err
+			continue
+		}
+		if err := c.checkVoice(pctx, c.call(p), config); err != nil {
+			return nil, err
+		}
+		release, err := c.acquire(ctx, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			limited = true
+			continue
+		}
+		actx, stop := c.withAttempt(pctx, name)
+		start := c.clock.Now()
+		result, err := c.call(p).Synthesize(actx, text, config)
+		stop()
+		release()
 		if err == nil {
+			d := clock.Since(c.clock, start)
+			c.record(name, func(t *latencyTotals) {
+				t.requests++
+				t.synthesize += d
+			})
+			if v := voiceContinuity(ctx); v != nil {
+				v.served(name)
+			}
 			return result, nil
 		}
-		// Log error and try fallbacks
-	}
-
-	// Try fallbacks
-	for _, name := range c.fallbacks {
-		if p, ok := c.providers[name]; ok {
-			result, err := p.Synthesize(ctx, text, config)
-			if err == nil {
-				return result, nil
-			}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
+		failures = append(failures, failure(name, err))
 	}
 
-	return nil, ErrNoAvailableProvider
+	return nil, unavailable(skipped.wrap(ErrNoAvailableProvider), limited, append(failures, credErr)...)
 }
 
 // SynthesizeStream uses the primary provider with automatic fallback.
+// A rate-limited provider's slot is held until the stream ends.
+// Canceling ctx closes the provider's stream, and the returned channel
+// closes after a final StreamChunk with IsFinal set.
+// Providers whose Capabilities cannot serve the request are skipped,
+// and if none qualify the error is ErrNoCapableProvider.
+// SetTimeouts bounds the wait for each attempt's first chunk, and Latency
+// reports how long it took.
 func (c *Client) SynthesizeStream(ctx context.Context, text string, config SynthesisConfig) (<-chan StreamChunk, error) {
-	// Try primary provider
-	if p, ok := c.providers[c.primary]; ok {
-		stream, err := p.SynthesizeStream(ctx, text, config)
+	wait, cancel := c.withOverall(ctx)
+	defer cancel()
+	text = prepareText(text, config)
+	voice := config.VoiceID
+	limited := false
+	var credErr error
+	var skipped skips
+	var failures []error
+	for _, name := range c.providerOrder(ctx) {
+		p, ok := c.providers[name]
+		if !ok {
+			continue
+		}
+		config, ok := c.withVoice(name, config)
+		if !ok {
+			skipped.noVoice(name, voice)
+			continue
+		}
+		if !skipped.check(p, text, config) {
+			continue
+		}
+		pctx, err := c.withCredentials(ctx, name)
+		if err != nil {
+			credErr = err
+			continue
+		}
+		if err := c.checkVoice(pctx, c.call(p), config); err != nil {
+			return nil, err
+		}
+		release, err := c.acquire(wait, name)
+		if err != nil {
+			if wait.Err() != nil {
+				return nil, wait.Err()
+			}
+			limited = true
+			continue
+		}
+		start := c.clock.Now()
+		stream, err := c.startStream(wait, pctx, c.call(p), name, text, config)
 		if err == nil {
+			stream = forward(ctx, c.timeFirstChunk(name, start, stream), nil, release)
+			if v := voiceContinuity(ctx); v != nil {
+				v.served(name)
+			}
 			return stream, nil
 		}
-	}
-
-	// Try fallbacks
-	for _, name := range c.fallbacks {
-		if p, ok := c.providers[name]; ok {
-			stream, err := p.SynthesizeStream(ctx, text, config)
-			if err == nil {
-				return stream, nil
-			}
+		release()
+		if wait.Err() != nil {
+			return nil, wait.Err()
 		}
+		failures = append(failures, failure(name, err))
 	}
 
-	return nil, ErrNoAvailableProvider
+	return nil, unavailable(skipped.wrap(ErrNoAvailableProvider), limited, append(failures, credErr)...)
 }
@@ -0,0 +1,83 @@
+package tts
+
+import (
+	"context"
+	"time"
+
+	"github.com/agentplexus/omnivoice/internal/fallback"
+)
+
+// WarmupConfig configures Warmer.Warmup.
+type WarmupConfig struct {
+	// IdleStreams is the number of streaming connections, such as
+	// WebSockets, a provider opens and holds ready, each handed to the
+	// next SynthesizeStream or SynthesizeFromReader whose config matches
+	// Synthesis. An idle stream costs what an open one does: a socket,
+	// and usually a slot of the account's concurrent-connection limit.
+	// 0 only warms the connection pool.
+	IdleStreams int
+
+	// Synthesis configures the idle streams.
+	Synthesis SynthesisConfig
+
+	// IdleTimeout releases warm connections still unused after it. Zero
+	// keeps them until ReleaseWarm, or until the provider's server closes
+	// them, which many do after some seconds without audio.
+	IdleTimeout time.Duration
+}
+
+// Warmer is implemented by providers that can prepare for requests ahead
+// of time, so the first one, such as the greeting of a call, does not
+// pay for DNS, TLS, and authentication.
+type Warmer interface {
+	Provider
+
+	// Warmup establishes an authenticated connection and leaves it idle in
+	// the provider's pool, and opens config.IdleStreams streams. A warm
+	// connection holds a socket and, for idle streams, provider capacity
+	// until used, released, or timed out.
+	Warmup(ctx context.Context, config WarmupConfig) error
+
+	// ReleaseWarm closes the connections and streams opened by Warmup
+	// that have not been used.
+	ReleaseWarm() error
+}
+
+// WarmupAll warms every provider implementing Warmer, concurrently, with
+// the credentials the client would use for requests (see SetCredentials).
+// Call it ahead of traffic, e.g. as a call rings, and ReleaseWarm when
+// the warm connections will not be used. It does nothing in dry-run mode.
+// The error joins the failures of each provider, as ProviderErrors; a
+// provider that failed to warm up is still used for requests.
+func (c *Client) WarmupAll(ctx context.Context, config WarmupConfig) error {
+	if c.dryRun != nil {
+		return nil
+	}
+	return fallback.Each(c.providers, func(name string, p Provider) error {
+		w, ok := p.(Warmer)
+		if !ok {
+			return nil
+		}
+		pctx, err := c.withCredentials(ctx, name)
+		if err == nil {
+			err = w.Warmup(pctx, config)
+		}
+		if err != nil {
+			return failure(name, err)
+		}
+		return nil
+	})
+}
+
+// ReleaseWarm releases the warm connections of every provider implementing
+// Warmer, concurrently.
+func (c *Client) ReleaseWarm() error {
+	return fallback.Each(c.providers, func(name string, p Provider) error {
+		if w, ok := p.(Warmer); ok {
+			if err := w.ReleaseWarm(); err != nil {
+				return failure(name, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package tts
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/agentplexus/omnivoice/audio"
+)
+
+// WriteWAV writes the result as a WAV file. Raw PCM is wrapped with a RIFF
+// header built from SampleRate and Channels; audio that is already WAV is
+// written unchanged. Compressed formats return ErrUnsupportedFormat.
+func (r *SynthesisResult) WriteWAV(w io.Writer) error {
+	switch r.Format {
+	case "pcm":
+		if r.SampleRate <= 0 {
+			return fmt.Errorf("%w: pcm result has no sample rate", ErrInvalidConfig)
+		}
+		return audio.WriteWAV(w, r.Audio, r.SampleRate, r.Channels)
+	case "wav":
+		_, err := w.Write(r.Audio)
+		return err
+	default:
+		return fmt.Errorf("%w: cannot write %q as WAV", ErrUnsupportedFormat, r.Format)
+	}
+}
+
+// WriteEncoded writes the audio bytes unchanged for self-describing formats
+// ("mp3", "opus", "ogg", "flac", "wav"). Headerless PCM returns
+// ErrUnsupportedFormat; use WriteWAV instead.
+func (r *SynthesisResult) WriteEncoded(w io.Writer) error {
+	switch r.Format {
+	case "mp3", "opus", "ogg", "flac", "wav":
+		_, err := w.Write(r.Audio)
+		return err
+	default:
+		return fmt.Errorf("%w: %q is not self-describing", ErrUnsupportedFormat, r.Format)
+	}
+}